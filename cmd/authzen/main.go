@@ -0,0 +1,137 @@
+// Command authzen runs an OpenID AuthZEN Access Evaluation API policy decision point for IDQL policies.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/authzen"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
+	"github.com/hexa-org/policy-mapper/pkg/keysupport"
+	"github.com/hexa-org/policy-mapper/pkg/oauth2support"
+	"github.com/hexa-org/policy-mapper/pkg/websupport"
+	"github.com/hexa-org/policy-mapper/sdk"
+	log "golang.org/x/exp/slog"
+)
+
+const (
+	EnvListen          string = "HEXA_AUTHZEN_LISTEN"           // The address to listen on (default 0.0.0.0:8080)
+	EnvPolicyFile      string = "HEXA_AUTHZEN_POLICY_FILE"      // An IDQL policy file
+	EnvProvider        string = "HEXA_AUTHZEN_PROVIDER"         // The provider type of an integration (e.g. opa, avp) used instead of a policy file
+	EnvIntegrationFile string = "HEXA_AUTHZEN_INTEGRATION_FILE" // The integration key file (e.g. from `hexa export`)
+	EnvPap             string = "HEXA_AUTHZEN_PAP"              // The policy application point object id of the integration
+	EnvEntitiesFile    string = "HEXA_AUTHZEN_ENTITIES_FILE"    // Optional entities (see decision.ParseEntities)
+	EnvUsersFile       string = "HEXA_AUTHZEN_USERS_FILE"       // Optional users keyed by subject id (see authzen.ParseUsers)
+	EnvSubjectType     string = "HEXA_AUTHZEN_SUBJECT_TYPE"     // The subject type of users (default user)
+	EnvRefresh         string = "HEXA_AUTHZEN_REFRESH"          // Seconds between policy reloads (default 0, no reload)
+)
+
+func main() {
+	listen := os.Getenv(EnvListen)
+	if listen == "" {
+		listen = "0.0.0.0:8080"
+	}
+
+	pdp, err := newPdp()
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	refresh, _ := strconv.Atoi(os.Getenv(EnvRefresh))
+	if refresh > 0 {
+		go reload(pdp, time.Duration(refresh)*time.Second)
+	}
+
+	authorizer, err := oauth2support.NewResourceJwtAuthorizer()
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	var scopes []string
+	if scope := os.Getenv(oauth2support.EnvJwtScope); scope != "" {
+		scopes = strings.Split(scope, ",")
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	server := authzen.NewAuthZenServer(listen, &authzen.AuthZenHandler{Pdp: pdp, Authorizer: authorizer, Scopes: scopes})
+	if websupport.IsTlsEnabled() {
+		keyConfig := keysupport.GetKeyConfig()
+		if !keyConfig.ServerCertExists() {
+			if err = keyConfig.InitializeKeys(); err != nil {
+				log.Error(err.Error())
+				os.Exit(1)
+			}
+		}
+		websupport.WithTransportLayerSecurity(keyConfig.ServerCertPath, keyConfig.ServerKeyPath, server)
+	}
+	websupport.Start(server, listener)
+}
+
+func newPdp() (*authzen.PolicyDecisionPoint, error) {
+	loader, err := newPolicyLoader()
+	if err != nil {
+		return nil, err
+	}
+
+	directory := decision.NewEntityDirectory(nil)
+	if entitiesFile := os.Getenv(EnvEntitiesFile); entitiesFile != "" {
+		if directory, err = decision.ParseEntityFile(entitiesFile); err != nil {
+			return nil, err
+		}
+	}
+	if usersFile := os.Getenv(EnvUsersFile); usersFile != "" {
+		subjectType := os.Getenv(EnvSubjectType)
+		if subjectType == "" {
+			subjectType = "user"
+		}
+		users, err := authzen.ParseUserFile(usersFile, subjectType)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			directory.Add(user)
+		}
+	}
+	return authzen.NewPolicyDecisionPoint(loader, directory)
+}
+
+func newPolicyLoader() (authzen.PolicyLoader, error) {
+	if policyFile := os.Getenv(EnvPolicyFile); policyFile != "" {
+		log.Info("Loading policies", "file", policyFile)
+		return authzen.FilePolicyLoader(policyFile), nil
+	}
+
+	provider := os.Getenv(EnvProvider)
+	integrationFile := os.Getenv(EnvIntegrationFile)
+	if provider == "" || integrationFile == "" {
+		return nil, errors.New(fmt.Sprintf("either %s or %s and %s must be set", EnvPolicyFile, EnvProvider, EnvIntegrationFile))
+	}
+	key, err := os.ReadFile(integrationFile)
+	if err != nil {
+		return nil, err
+	}
+	integration, err := sdk.OpenIntegration(sdk.WithIntegrationInfo(policyprovider.IntegrationInfo{Name: provider, Key: key}))
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Loading policies", "provider", provider, "pap", os.Getenv(EnvPap))
+	return authzen.IntegrationPolicyLoader(integration, os.Getenv(EnvPap)), nil
+}
+
+func reload(pdp *authzen.PolicyDecisionPoint, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := pdp.Reload(); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
@@ -16,15 +16,25 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/models/formats/awsIam"
+	"github.com/hexa-org/policy-mapper/models/formats/casbin"
 	"github.com/hexa-org/policy-mapper/models/formats/cedar"
 	"github.com/hexa-org/policy-mapper/models/formats/gcpBind"
+	"github.com/hexa-org/policy-mapper/models/formats/k8srbac"
+	"github.com/hexa-org/policy-mapper/models/formats/rego"
+	"github.com/hexa-org/policy-mapper/models/formats/xacml"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/hexa-org/policy-mapper/providers/fileProvider"
+	"github.com/hexa-org/policy-mapper/providers/keycloak/kcClient"
+	"github.com/hexa-org/policy-mapper/providers/okta/oktaClient"
+	"github.com/hexa-org/policy-mapper/providers/openfga/openfgaClient"
 	"github.com/hexa-org/policy-mapper/sdk"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
-var MapFormats = []string{"gcp", "cedar"}
+var MapFormats = []string{"gcp", "cedar", "rego", "iam", "xacml", "casbin", "k8s"}
 
 var seperatorline = "==============================================================================="
 
@@ -140,6 +150,67 @@ func (a *AddGcpIntegrationCmd) Run(cli *CLI) error {
 	return err
 }
 
+type AddFileIntegrationCmd struct {
+	Alias      string `arg:"" optional:"" help:"A new local alias that will be used to refer to the integration in subsequent operations. Defaults to an auto-generated alias"`
+	Directory  string `short:"d" required:"" help:"Directory containing a subdirectory (with a policy file) for each application"`
+	Format     string `default:"idql" enum:"idql,cedar,rego,gcp" help:"Policy format used for new applications (idql, cedar, rego, or gcp)"`
+	AutoCommit bool   `name:"autocommit" help:"Commit each policy update to the Git repository containing the directory"`
+	GitUser    string `name:"gituser" help:"Git user name used for commits (defaults to the git configuration)"`
+	GitEmail   string `name:"gitemail" help:"Git user email used for commits (defaults to the git configuration)"`
+}
+
+func (a *AddFileIntegrationCmd) Help() string {
+	return `To add a local file integration, specify a --directory. Each subdirectory is an application containing one policy
+file whose format is determined by its extension: .json (IDQL), .cedar (Cedar), .rego (Rego), or .bindings.json
+(Google IAM bindings). When --autocommit is set, each policy update is committed to the Git repository containing
+the directory (a repository is initialized if needed).
+`
+}
+
+func (a *AddFileIntegrationCmd) Run(cli *CLI) error {
+	alias := a.Alias
+	if alias == "" {
+		alias = generateAliasOfSize(3)
+	}
+
+	if cli.Data.GetIntegration(alias) != nil {
+		errMsg := fmt.Sprintf("Alias \"%s\" exists", alias)
+		if !ConfirmProceed(errMsg + ", overwrite Y[n]") {
+			return errors.New(errMsg)
+		}
+	}
+	directory, err := filepath.Abs(a.Directory)
+	if err != nil {
+		return err
+	}
+	if stat, err := os.Stat(directory); err != nil || !stat.IsDir() {
+		return fmt.Errorf("invalid directory: %s", a.Directory)
+	}
+
+	keyBytes, err := json.Marshal(fileProvider.Credentials{
+		Directory:     directory,
+		DefaultFormat: a.Format,
+		AutoCommit:    a.AutoCommit,
+		GitUser:       a.GitUser,
+		GitEmail:      a.GitEmail,
+	})
+	if err != nil {
+		return err
+	}
+	info := policyprovider.IntegrationInfo{
+		Name: sdk.ProviderTypeFile,
+		Key:  keyBytes,
+	}
+
+	integration, err := openIntegration(alias, sdk.WithIntegrationInfo(info))
+	if err != nil {
+		return err
+	}
+	cli.Data.Integrations[alias] = integration
+	err = cli.Data.Save(&cli.Globals)
+	return err
+}
+
 type AddOpaIntegrationCmd struct {
 	Type         string   `arg:"" required:"" help:"Type of OPA integration: aws, gcp, github, or http"`
 	Alias        string   `arg:"" optional:"" help:"A new local alias that will be used to refer to the integration in subsequent operations. Defaults to an auto-generated alias"`
@@ -511,19 +582,293 @@ func (a *AddAzureIntegrationCmd) Run(cli *CLI) error {
 	return err
 }
 
+type AddKeycloakIntegrationCmd struct {
+	Alias    string `arg:"" optional:"" help:"A new local alias that will be used to refer to the integration in subsequent operations. Defaults to an auto-generated alias"`
+	Url      string `short:"u" xor:"file" help:"The base url of the Keycloak server (e.g. https://keycloak.example.com)"`
+	Realm    string `short:"r" help:"The Keycloak realm whose clients are managed"`
+	Clientid string `short:"c" help:"The client id of a confidential client with a service account able to manage clients"`
+	Secret   string `short:"s" help:"The client secret"`
+	File     string `short:"f" xor:"file" help:"File containing the Keycloak integration information"`
+}
+
+func (a *AddKeycloakIntegrationCmd) Help() string {
+	return `To add a Keycloak Authorization Services integration specify --url, --realm, --clientid and --secret, or a file
+(--file) containing:
+{
+  "url": "https://keycloak.example.com",
+  "realm": "hexa",
+  "clientId": "hexa-admin",
+  "clientSecret": "client-secret",
+  "tokenRealm": "master"
+}
+
+The client service account requires the realm-management manage-clients, view-users and view-realm roles. The optional
+tokenRealm is the realm used to obtain admin tokens (defaults to realm).
+`
+}
+
+func (a *AddKeycloakIntegrationCmd) Run(cli *CLI) error {
+	alias := a.Alias
+	if alias == "" {
+		alias = generateAliasOfSize(3)
+	}
+
+	if cli.Data.GetIntegration(alias) != nil {
+		errMsg := fmt.Sprintf("Alias \"%s\" exists", alias)
+		if !ConfirmProceed(errMsg + ", overwrite Y[n]") {
+			return errors.New(errMsg)
+		}
+	}
+
+	var keyBytes []byte
+	if a.File != "" {
+		if err := checkFile(a.File); err != nil {
+			return err
+		}
+		keyBytes = getFile(a.File)
+	} else {
+		var err error
+		keyBytes, err = json.Marshal(kcClient.Credentials{
+			Url:          a.Url,
+			Realm:        a.Realm,
+			ClientId:     a.Clientid,
+			ClientSecret: a.Secret,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	info := policyprovider.IntegrationInfo{
+		Name: sdk.ProviderTypeKeycloak,
+		Key:  keyBytes,
+	}
+
+	integration, err := openIntegration(alias, sdk.WithIntegrationInfo(info))
+	if err != nil {
+		return err
+	}
+
+	cli.Data.Integrations[alias] = integration
+	err = cli.Data.Save(&cli.Globals)
+	return err
+}
+
+type AddOktaIntegrationCmd struct {
+	Alias         string `arg:"" optional:"" help:"A new local alias that will be used to refer to the integration in subsequent operations. Defaults to an auto-generated alias"`
+	Orgurl        string `short:"u" xor:"file" help:"The Okta org url (e.g. https://dev-123456.okta.com)"`
+	Token         string `short:"t" help:"An Okta API token able to manage application assignments"`
+	Roleattribute string `short:"r" help:"The app assignment profile attribute holding app roles (default role)"`
+	Multivalued   bool   `short:"m" help:"The role attribute is an array of roles"`
+	File          string `short:"f" xor:"file" help:"File containing the Okta integration information"`
+}
+
+func (a *AddOktaIntegrationCmd) Help() string {
+	return `To add an Okta application assignment integration specify --orgurl and --token, or a file (--file) containing:
+{
+  "orgUrl": "https://dev-123456.okta.com",
+  "apiToken": "00abc...",
+  "roleAttribute": "role",
+  "multiValuedRoles": false
+}
+
+Applications are the active OIDC and SAML apps of the org. Group and user assignments map to the okta:access action and
+the values of the assignment profile role attribute map to okta:role:<role> actions.
+`
+}
+
+func (a *AddOktaIntegrationCmd) Run(cli *CLI) error {
+	alias := a.Alias
+	if alias == "" {
+		alias = generateAliasOfSize(3)
+	}
+
+	if cli.Data.GetIntegration(alias) != nil {
+		errMsg := fmt.Sprintf("Alias \"%s\" exists", alias)
+		if !ConfirmProceed(errMsg + ", overwrite Y[n]") {
+			return errors.New(errMsg)
+		}
+	}
+
+	var keyBytes []byte
+	if a.File != "" {
+		if err := checkFile(a.File); err != nil {
+			return err
+		}
+		keyBytes = getFile(a.File)
+	} else {
+		var err error
+		keyBytes, err = json.Marshal(oktaClient.Credentials{
+			OrgUrl:           a.Orgurl,
+			ApiToken:         a.Token,
+			RoleAttribute:    a.Roleattribute,
+			MultiValuedRoles: a.Multivalued,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	info := policyprovider.IntegrationInfo{
+		Name: sdk.ProviderTypeOkta,
+		Key:  keyBytes,
+	}
+
+	integration, err := openIntegration(alias, sdk.WithIntegrationInfo(info))
+	if err != nil {
+		return err
+	}
+
+	cli.Data.Integrations[alias] = integration
+	err = cli.Data.Save(&cli.Globals)
+	return err
+}
+
+type AddOpenFgaIntegrationCmd struct {
+	Alias     string `arg:"" optional:"" help:"A new local alias that will be used to refer to the integration in subsequent operations. Defaults to an auto-generated alias"`
+	Url       string `short:"u" xor:"file" help:"The url of the OpenFGA API (e.g. https://openfga.example.com)"`
+	Token     string `short:"t" help:"An OpenFGA pre-shared key (optional)"`
+	Namespace string `short:"n" help:"A namespace used to qualify IDQL entities mapped from tuples (e.g. PhotoApp)"`
+	File      string `short:"f" xor:"file" help:"File containing the OpenFGA integration information"`
+}
+
+func (a *AddOpenFgaIntegrationCmd) Help() string {
+	return `To add an OpenFGA integration specify --url (and optionally --token and --namespace), or a file (--file) containing:
+{
+  "apiUrl": "https://openfga.example.com",
+  "apiToken": "preshared-key",
+  "namespace": "PhotoApp"
+}
+
+To authenticate with a client credentials grant, use a file with clientId, clientSecret, tokenUrl and audience instead
+of apiToken. Each OpenFGA store is discovered as an application.
+`
+}
+
+func (a *AddOpenFgaIntegrationCmd) Run(cli *CLI) error {
+	alias := a.Alias
+	if alias == "" {
+		alias = generateAliasOfSize(3)
+	}
+
+	if cli.Data.GetIntegration(alias) != nil {
+		errMsg := fmt.Sprintf("Alias \"%s\" exists", alias)
+		if !ConfirmProceed(errMsg + ", overwrite Y[n]") {
+			return errors.New(errMsg)
+		}
+	}
+
+	var keyBytes []byte
+	if a.File != "" {
+		if err := checkFile(a.File); err != nil {
+			return err
+		}
+		keyBytes = getFile(a.File)
+	} else {
+		var err error
+		keyBytes, err = json.Marshal(openfgaClient.Credentials{
+			ApiUrl:    a.Url,
+			ApiToken:  a.Token,
+			Namespace: a.Namespace,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	info := policyprovider.IntegrationInfo{
+		Name: sdk.ProviderTypeOpenFga,
+		Key:  keyBytes,
+	}
+
+	integration, err := openIntegration(alias, sdk.WithIntegrationInfo(info))
+	if err != nil {
+		return err
+	}
+
+	cli.Data.Integrations[alias] = integration
+	err = cli.Data.Save(&cli.Globals)
+	return err
+}
+
+type AddRestIntegrationCmd struct {
+	Alias string `arg:"" optional:"" help:"A new local alias that will be used to refer to the integration in subsequent operations. Defaults to an auto-generated alias"`
+	File  string `short:"f" required:"" help:"File containing the REST integration configuration (endpoints, auth, pagination and mapping)"`
+}
+
+func (a *AddRestIntegrationCmd) Help() string {
+	return `To add a generic REST integration specify a file (--file) describing the application permissions API, e.g.:
+{
+  "baseUrl": "https://app.example.com/api",
+  "auth": {"type": "bearer", "token": "..."},
+  "apps": [{"id": "photos", "name": "Photo App"}],
+  "list": {"path": "/apps/{{.App.ObjectID}}/permissions", "items": "items",
+           "pagination": {"type": "cursor", "next": "next_cursor"}},
+  "create": {"path": "/apps/{{.App.ObjectID}}/permissions", "body": "{\"members\": {{json .Subjects}}, ...}"},
+  "update": {"path": "/apps/{{.App.ObjectID}}/permissions/{{pathEscape .Id}}", "body": "..."},
+  "delete": {"path": "/apps/{{.App.ObjectID}}/permissions/{{pathEscape .Id}}"},
+  "mapping": {"id": "id", "subjects": "members[*].email", "subjectPrefix": "user:", "actions": "scopes", "object": "resource"}
+}
+
+Auth types are bearer, header, basic and oauth2 (clientId, clientSecret, tokenUrl). See providers/rest/README.md.
+`
+}
+
+func (a *AddRestIntegrationCmd) Run(cli *CLI) error {
+	alias := a.Alias
+	if alias == "" {
+		alias = generateAliasOfSize(3)
+	}
+
+	if cli.Data.GetIntegration(alias) != nil {
+		errMsg := fmt.Sprintf("Alias \"%s\" exists", alias)
+		if !ConfirmProceed(errMsg + ", overwrite Y[n]") {
+			return errors.New(errMsg)
+		}
+	}
+
+	if err := checkFile(a.File); err != nil {
+		return err
+	}
+
+	info := policyprovider.IntegrationInfo{
+		Name: sdk.ProviderTypeRest,
+		Key:  getFile(a.File),
+	}
+
+	integration, err := openIntegration(alias, sdk.WithIntegrationInfo(info))
+	if err != nil {
+		return err
+	}
+
+	cli.Data.Integrations[alias] = integration
+	err = cli.Data.Save(&cli.Globals)
+	return err
+}
+
 type AddCmd struct {
-	Aws   AddAwsIntegrationCmd   `cmd:"" aliases:"amazon" help:"Add AWS Api Gateway, Cognito, or AVP integration"`
-	Gcp   AddGcpIntegrationCmd   `cmd:"" aliases:"google" help:"Add a Google Cloud GCP integration"`
-	Azure AddAzureIntegrationCmd `cmd:"" aliases:"ms,microsoft" help:"Add an Azure RBAC integration"`
-	Opa   AddOpaIntegrationCmd   `cmd:"" help:"Add an Open Policy Agent (OPA) integration"`
+	Aws      AddAwsIntegrationCmd      `cmd:"" aliases:"amazon" help:"Add AWS Api Gateway, Cognito, or AVP integration"`
+	Gcp      AddGcpIntegrationCmd      `cmd:"" aliases:"google" help:"Add a Google Cloud GCP integration"`
+	Azure    AddAzureIntegrationCmd    `cmd:"" aliases:"ms,microsoft" help:"Add an Azure RBAC integration"`
+	Opa      AddOpaIntegrationCmd      `cmd:"" help:"Add an Open Policy Agent (OPA) integration"`
+	File     AddFileIntegrationCmd     `cmd:"" aliases:"dir" help:"Add a local directory (or Git working tree) integration"`
+	Keycloak AddKeycloakIntegrationCmd `cmd:"" aliases:"kc" help:"Add a Keycloak Authorization Services integration"`
+	Okta     AddOktaIntegrationCmd     `cmd:"" help:"Add an Okta application group and user assignment integration"`
+	Openfga  AddOpenFgaIntegrationCmd  `cmd:"" aliases:"fga" help:"Add an OpenFGA integration"`
+	Rest     AddRestIntegrationCmd     `cmd:"" help:"Add a generic REST permissions API integration"`
 }
 
 type ExportCmd struct {
+	Integration ExportIntegrationCmd `cmd:"" default:"withargs" help:"Export an integration configuration (e.g. export <alias> <file>)"`
+	Model       ExportModelCmd       `cmd:"" help:"Export a previously loaded Policy Model namespace as Cedar schema, JSON Schema or OpenAPI components"`
+}
+
+type ExportIntegrationCmd struct {
 	Alias string `arg:"" required:"" help:"Alias for a previously defined integration to export"`
 	File  string `arg:"" required:"" help:"Filename to export to (e.g. integration.json)"`
 }
 
-func (e *ExportCmd) Run(cli *CLI) error {
+func (e *ExportIntegrationCmd) Run(cli *CLI) error {
 	integration := cli.Data.GetIntegration(e.Alias)
 	if integration == nil {
 		return errors.New(fmt.Sprintf("alias %s not found", e.Alias))
@@ -595,7 +940,8 @@ type GetCmd struct {
 type SetPoliciesCmd struct {
 	Alias       string `arg:"" required:"" help:"The alias or object id of a PAP (application) where policies are to be set/reconciled with specified policies"`
 	File        string `short:"f" required:"" type:"path" help:"A file containing IDQL policy to be applied (REQUIRED)"`
-	Differences bool   `optional:"" default:"false" short:"d" help:"When specified, the list of changes to be applied will be shown before confirming change (if supported by provider)"`
+	Differences bool   `optional:"" default:"false" short:"d" help:"When specified, the list of changes to be applied and the resulting access impact will be shown before confirming change (if supported by provider)"`
+	Entities    string `optional:"" short:"e" type:"path" help:"A file containing entities (IDQL or Cedar JSON format) used when analyzing access impact"`
 }
 
 func (s *SetPoliciesCmd) Run(cli *CLI) error {
@@ -622,6 +968,17 @@ func (s *SetPoliciesCmd) Run(cli *CLI) error {
 			output, _ := json.MarshalIndent(diffs, "", "  ")
 			cli.GetOutputWriter().WriteBytes(output, true)
 		}
+
+		current, err := integration.GetPolicies(s.Alias)
+		if err != nil {
+			return err
+		}
+		directory, err := loadEntityDirectory(s.Entities)
+		if err != nil {
+			return err
+		}
+		impacts := decision.AnalyzeImpact(current.Policies, policies, directory)
+		printImpacts(impacts)
 	}
 
 	msg := fmt.Sprintf("Applying %d policies to %s", len(policies), s.Alias)
@@ -729,8 +1086,10 @@ type ShowCmd struct {
 }
 
 type MapToCmd struct {
-	Format string `arg:"" required:"" help:"Target format: gcp, or cedar"`
-	File   string `arg:"" type:"path" help:"A file containing IDQL policy to be mapped"`
+	Format   string `arg:"" required:"" help:"Target format: gcp, cedar, rego, iam, xacml, casbin, or k8s"`
+	File     string `arg:"" type:"path" help:"A file containing IDQL policy to be mapped"`
+	Entities string `optional:"" short:"e" type:"path" help:"casbin: A file containing entities (IDQL or Cedar JSON format) whose membership is mapped to g role lines"`
+	Model    string `optional:"" type:"path" help:"casbin: A file where the generated model.conf is written (default is to display it)"`
 }
 
 func (m *MapToCmd) AfterApply(_ *kong.Context) error {
@@ -767,12 +1126,81 @@ func (m *MapToCmd) Run(cli *CLI) error {
 		fmt.Println(cedarPoliciesString)
 		cli.GetOutputWriter().WriteString(cedarPoliciesString, false)
 		cli.GetOutputWriter().Close()
+	case "rego":
+		rMapper := rego.NewRegoMapper(map[string]string{})
+
+		regoString, err := rMapper.MapHexaPolicies(policies)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(regoString)
+		cli.GetOutputWriter().WriteString(regoString, false)
+		cli.GetOutputWriter().Close()
+	case "iam":
+		iamMapper := awsIam.NewIamMapper(map[string]string{})
+		iamDoc, err := iamMapper.MapPoliciesToIam(policies)
+		if err != nil {
+			return err
+		}
+		_ = MarshalJsonNoEscape(iamDoc, os.Stdout)
+		outWriter := cli.GetOutputWriter()
+		_ = MarshalJsonNoEscape(iamDoc, outWriter.GetOutput())
+		outWriter.Close()
+	case "xacml":
+		xMapper := xacml.NewXacmlMapper(map[string]string{})
+		xacmlBytes, err := xMapper.MapPoliciesToXacmlBytes(policies)
+		if err != nil {
+			return err
+		}
+
+		xacmlString := string(xacmlBytes)
+		fmt.Println(xacmlString)
+		cli.GetOutputWriter().WriteString(xacmlString, false)
+		cli.GetOutputWriter().Close()
+	case "casbin":
+		directory, err := loadEntityDirectory(m.Entities)
+		if err != nil {
+			return err
+		}
+		cMapper := casbin.NewCasbinMapper(map[string]string{})
+		lines, err := cMapper.MapPoliciesToCasbin(policies, directory)
+		if err != nil {
+			return err
+		}
+
+		if m.Model != "" {
+			if err = os.WriteFile(m.Model, []byte(casbin.ModelConf), 0644); err != nil {
+				return err
+			}
+			fmt.Println(fmt.Sprintf("Casbin model written to %s", m.Model))
+		} else {
+			fmt.Print(casbin.ModelConf)
+		}
+		policyString := casbin.FormatPolicy(lines)
+		fmt.Println(policyString)
+		cli.GetOutputWriter().WriteString(policyString, false)
+		cli.GetOutputWriter().Close()
+	case "k8s":
+		manifests, err := k8srbac.MapPoliciesToManifests(policies)
+		if err != nil {
+			return err
+		}
+		manifestBytes, err := manifests.Marshal()
+		if err != nil {
+			return err
+		}
+
+		manifestString := string(manifestBytes)
+		fmt.Println(manifestString)
+		cli.GetOutputWriter().WriteString(manifestString, false)
+		cli.GetOutputWriter().Close()
 	}
 	return nil
 }
 
 type MapFromCmd struct {
-	Format string `arg:"" required:"" help:"Input format: gcp, or cedar"`
+	Format string `arg:"" required:"" help:"Input format: gcp, cedar, rego, iam, xacml, casbin, or k8s"`
 	File   string `arg:"" type:"path" help:"A file containing policy to be mapped into IDQL"`
 }
 
@@ -806,10 +1234,73 @@ func (m *MapFromCmd) Run(cli *CLI) error {
 			return err
 		}
 		pols, err := cMapper.MapCedarPolicyBytes(m.File, policyBytes)
+		var report cedar.MappingReport
+		if errors.As(err, &report) {
+			// report the policies that could not be mapped and continue with the rest
+			for _, issue := range report {
+				fmt.Println(fmt.Sprintf("Warning: %s", issue))
+			}
+		} else if err != nil {
+			return err
+		}
+		policies = pols.Policies
+
+	case "rego":
+		rMapper := rego.NewRegoMapper(map[string]string{})
+		policyBytes, err := os.ReadFile(m.File)
+		if err != nil {
+			return err
+		}
+		pols, err := rMapper.MapRegoPolicyBytes(policyBytes)
 		if err != nil {
 			return err
 		}
 		policies = pols.Policies
+
+	case "iam":
+		iamMapper := awsIam.NewIamMapper(map[string]string{})
+		iamDoc, err := awsIam.ParseFile(m.File)
+		if err != nil {
+			return err
+		}
+		policies, err = iamMapper.MapIamToPolicies(iamDoc)
+		if err != nil {
+			return err
+		}
+
+	case "xacml":
+		xMapper := xacml.NewXacmlMapper(map[string]string{})
+		policySet, err := xacml.ParseFile(m.File)
+		if err != nil {
+			return err
+		}
+		var issues []xacml.Issue
+		policies, issues = xMapper.MapPolicySet(policySet)
+		for _, issue := range issues {
+			fmt.Println("WARNING: " + issue.String())
+		}
+
+	case "casbin":
+		cMapper := casbin.NewCasbinMapper(map[string]string{})
+		policyBytes, err := os.ReadFile(m.File)
+		if err != nil {
+			return err
+		}
+		pols, err := cMapper.MapCasbinPolicyBytes(policyBytes)
+		if err != nil {
+			return err
+		}
+		policies = pols.Policies
+
+	case "k8s":
+		manifests, err := k8srbac.ParseFile(m.File)
+		if err != nil {
+			return err
+		}
+		policies, err = k8srbac.MapManifestsToPolicies(manifests)
+		if err != nil {
+			return err
+		}
 	}
 
 	_ = MarshalJsonNoEscape(policies, os.Stdout)
@@ -891,6 +1382,67 @@ func (r *ReconcileCmd) Run(cli *CLI) error {
 	return nil
 }
 
+type ImpactCmd struct {
+	AliasOld string `arg:"" required:"" help:"The alias of a Policy Application, or a file path to a file containing the current IDQL policies."`
+	AliasNew string `arg:"" required:"" help:"The alias of a Policy Application, or a file path to a file containing the proposed IDQL policies."`
+	Entities string `optional:"" short:"e" type:"path" help:"A file containing entities (IDQL or Cedar JSON format) whose membership and attributes are used to enumerate requests"`
+}
+
+func (i *ImpactCmd) Run(cli *CLI) error {
+	oldPolicies, err := loadPolicySource(cli, i.AliasOld)
+	if err != nil {
+		return err
+	}
+	newPolicies, err := loadPolicySource(cli, i.AliasNew)
+	if err != nil {
+		return err
+	}
+	directory, err := loadEntityDirectory(i.Entities)
+	if err != nil {
+		return err
+	}
+
+	impacts := decision.AnalyzeImpact(oldPolicies, newPolicies, directory)
+	printImpacts(impacts)
+
+	// Write to output if specified
+	output, _ := json.MarshalIndent(impacts, "", "  ")
+	cli.GetOutputWriter().WriteBytes(output, true)
+	return nil
+}
+
+// loadPolicySource returns the policies of a PAP alias, or if the alias is not found, the policies in the file named
+func loadPolicySource(cli *CLI, aliasOrFile string) ([]hexapolicy.PolicyInfo, error) {
+	integration, app := cli.Data.GetApplicationInfo(aliasOrFile)
+	if app == nil {
+		return hexapolicysupport.ParsePolicyFile(aliasOrFile)
+	}
+	policies, err := integration.GetPolicies(aliasOrFile)
+	if err != nil {
+		return nil, err
+	}
+	return policies.Policies, nil
+}
+
+func loadEntityDirectory(entityFile string) (*decision.EntityDirectory, error) {
+	if entityFile == "" {
+		return nil, nil
+	}
+	return decision.ParseEntityFile(entityFile)
+}
+
+func printImpacts(impacts []decision.ImpactDif) {
+	if len(impacts) == 0 {
+		fmt.Println("No change in access detected.")
+		fmt.Println()
+		return
+	}
+	for i, impact := range impacts {
+		fmt.Println(fmt.Sprintf("%d: %s", i, impact.Report()))
+	}
+	fmt.Println()
+}
+
 func ConfirmProceed(msg string) bool {
 	if msg != "" {
 		fmt.Print(msg)
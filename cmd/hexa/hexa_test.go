@@ -13,7 +13,9 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/models/policyInfoModel"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
 	"github.com/hexa-org/policy-mapper/providers/test"
 	"github.com/hexa-org/policy-mapper/sdk"
@@ -451,6 +453,39 @@ func (suite *testSuite) Test06_Reconcile() {
 	assert.Len(suite.T(), difs4, 3, "Should be 3 difs")
 }
 
+func (suite *testSuite) Test06a_Impact() {
+	oldPath := filepath.Join(suite.testDir, "impact-old.json")
+	newPath := filepath.Join(suite.testDir, "impact-new.json")
+	outputFile := filepath.Join(suite.testDir, "impact-out.json")
+	err := os.WriteFile(oldPath, []byte(`{"policies":[
+  {"meta": {"policyId": "read"}, "subjects": ["User:alice", "User:bob"], "actions": ["read"], "object": "Doc:1"}
+]}`), 0660)
+	assert.NoError(suite.T(), err)
+	err = os.WriteFile(newPath, []byte(`{"policies":[
+  {"meta": {"policyId": "read"}, "subjects": ["User:alice"], "actions": ["read"], "object": "Doc:1"}
+]}`), 0660)
+	assert.NoError(suite.T(), err)
+
+	command := fmt.Sprintf("impact %s %s --output %s", oldPath, newPath, outputFile)
+	res, err := suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Check no error on impact")
+	assert.Contains(suite.T(), string(res), "IMPACT: LOST subject: User:bob, action: read, object: Doc:1")
+
+	var impacts []decision.ImpactDif
+	impactBytes, err := os.ReadFile(outputFile)
+	assert.NoError(suite.T(), err)
+	err = json.Unmarshal(impactBytes, &impacts)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), impacts, 1, "Only bob's access changed")
+
+	res, err = suite.executeCommand(fmt.Sprintf("impact %s %s", oldPath, oldPath), 0)
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), string(res), "No change in access detected.")
+
+	_, err = suite.executeCommand(fmt.Sprintf("impact %s %s -e %s", oldPath, newPath, "notexist.json"), 0)
+	assert.Error(suite.T(), err, "Entity file should not be found")
+}
+
 func (suite *testSuite) Test07_MapToCmd() {
 	command := "map to abc"
 	_, err := suite.executeCommand(command, 0)
@@ -471,6 +506,44 @@ func (suite *testSuite) Test07_MapToCmd() {
 	res, err = suite.executeCommand(command, 0)
 	assert.NoError(suite.T(), err, "Should be successful map of gcp")
 	assert.Contains(suite.T(), string(res), "bindings")
+
+	command = "map to rego ../../examples/policyExamples/example_idql.json"
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of rego")
+	assert.Contains(suite.T(), string(res), "allow_set contains \"policy-0\" if {")
+
+	command = "map to iam ../../examples/policyExamples/example_idql.json"
+	_, err = suite.executeCommand(command, 0)
+	assert.Error(suite.T(), err, "IDQL user subjects have no IAM principal type")
+
+	idqlFile := filepath.Join(suite.T().TempDir(), "idql.json")
+	_ = os.WriteFile(idqlFile, []byte(`{"policies":[{"meta":{"policyId":"read"},"subjects":["anyAuthenticated"],"actions":["s3:GetObject"],"object":"arn:aws:s3:::reports/*","condition":{"rule":"req.ip eq \"192.0.2.0/24\"","action":"allow"}}]}`), 0644)
+	command = "map to iam " + idqlFile
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of iam")
+	assert.Contains(suite.T(), string(res), "\"IpAddress\": {")
+
+	command = "map to xacml " + idqlFile
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of xacml")
+	assert.Contains(suite.T(), string(res), "<Rule RuleId=\"read\" Effect=\"Permit\">")
+
+	modelFile := filepath.Join(suite.T().TempDir(), "model.conf")
+	command = "map to casbin ../../examples/authZen/data.json --model " + modelFile
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of casbin")
+	assert.Contains(suite.T(), string(res), "p, anyAuthenticated, User:*, can_read_user, allow, true, GetUsers")
+	modelBytes, err := os.ReadFile(modelFile)
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), string(modelBytes), "[policy_definition]")
+
+	k8sFile := filepath.Join(suite.T().TempDir(), "k8s.json")
+	_ = os.WriteFile(k8sFile, []byte(`{"policies":[{"meta":{"policyId":"ReadPods"},"subjects":["User:\"jane\""],"actions":["get"],"object":"default:pods:"}]}`), 0644)
+	command = "map to k8s " + k8sFile
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of k8s")
+	assert.Contains(suite.T(), string(res), "kind: RoleBinding")
+	assert.Contains(suite.T(), string(res), "name: readpods")
 }
 
 func (suite *testSuite) Test08_MapFromCmd() {
@@ -490,10 +563,48 @@ func (suite *testSuite) Test08_MapFromCmd() {
 	assert.Contains(suite.T(), string(res), "\"Photo:\\\"VacationPhoto94.jpg\\\"")
 	assert.Contains(suite.T(), string(res), " \"Rule\": \"resource in Account:\\\"stacey\\\"\",")
 
+	// policies that cannot be mapped are reported and the remainder are mapped
+	command = "map from cedar ./test/cedarPartial.cedar"
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should map cedar with unsupported policies")
+	assert.Contains(suite.T(), string(res), "context.ip in \\\"10.0.0.0/8\\\"")
+	assert.NotContains(suite.T(), string(res), "User:\\\"stacey\\\"")
+
 	command = "map from gcp ../../examples/policyExamples/example_bindings.json"
 	res, err = suite.executeCommand(command, 0)
 	assert.NoError(suite.T(), err, "Should be successful map of gcp")
 	assert.Contains(suite.T(), string(res), "req.ip sw \\\"127\\\" and req.method eq \\\"POST\\\"", "Check contains condition")
+
+	regoFile := filepath.Join(suite.T().TempDir(), "policies.rego")
+	_ = os.WriteFile(regoFile, []byte("allow_set contains \"admins\" if {\n    hexa_subjects([\"role:admin\"])\n    hexa_actions([\"read\"])\n    input.req.ip == \"127.0.0.1\"\n}\n"), 0644)
+	command = "map from rego " + regoFile
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of rego")
+	assert.Contains(suite.T(), string(res), "\"Rule\": \"req.ip eq \\\"127.0.0.1\\\"\"")
+
+	command = "map from iam ../../examples/policyExamples/example_iam.json"
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of iam")
+	assert.Contains(suite.T(), string(res), "\"Rule\": \"req.secure eq false\"")
+	assert.Contains(suite.T(), string(res), "\"Action\": \"deny\"")
+
+	command = "map from xacml ../../examples/policyExamples/example_xacml.xml"
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of xacml")
+	assert.Contains(suite.T(), string(res), "\"Rule\": \"resource.department eq subject.department\"")
+	assert.Contains(suite.T(), string(res), "WARNING: policy urn:example:policy:documents, rule RegexRule: rule skipped")
+
+	command = "map from casbin ../../examples/policyExamples/example_casbin.csv"
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of casbin")
+	assert.Contains(suite.T(), string(res), "\"[Group:editors]\"")
+	assert.Contains(suite.T(), string(res), "\"Rule\": \"resource.department eq subject.department\"")
+
+	command = "map from k8s ../../examples/policyExamples/example_k8srbac.yaml"
+	res, err = suite.executeCommand(command, 0)
+	assert.NoError(suite.T(), err, "Should be successful map of k8s")
+	assert.Contains(suite.T(), string(res), "\"ServiceAccount:\\\"default/monitor\\\"\"")
+	assert.Contains(suite.T(), string(res), "\"object\": \"default:pods/log:\"")
 }
 
 func (suite *testSuite) Test09_DeleteCmds() {
@@ -581,6 +692,33 @@ func (suite *testSuite) Test11_LoadShowAndValidateModel() {
 	testLog.Println(string(res))
 	assert.Contains(suite.T(), string(res), ".Valid")
 	assert.Contains(suite.T(), string(res), "invalid condition entity type: PhotoApp:BadAccount:\"stacey\"")
+
+	// Reload the same model from the Cedar human-readable form
+	res, err = suite.executeCommand("load model ./test/photoSchema.cedarschema", 0)
+	assert.NoError(suite.T(), err, "Check no error after load of cedarschema model")
+	assert.Contains(suite.T(), string(res), "PhotoApp")
+
+	res, err = suite.executeCommand(cmdValidate, 0)
+	assert.NoError(suite.T(), err, "Check no error after validate policy")
+	assert.Contains(suite.T(), string(res), ".Valid")
+	assert.Contains(suite.T(), string(res), "invalid condition entity type: PhotoApp:BadAccount:\"stacey\"")
+
+	// Export the model
+	res, err = suite.executeCommand("export model PhotoApp --format=openapi", 0)
+	assert.NoError(suite.T(), err, "Check no error after export model")
+	assert.Contains(suite.T(), string(res), "\"components\"")
+
+	res, err = suite.executeCommand("export model PhotoApp --file=test11.json", 0)
+	assert.NoError(suite.T(), err, "Check no error after export model to file")
+	exportBytes, err := os.ReadFile("test11.json")
+	assert.NoError(suite.T(), err)
+	exported, err := policyInfoModel.ParseSchemaFile(exportBytes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), (*suite.pd.cli.Namespaces)["PhotoApp"], (*exported)["PhotoApp"])
+	_ = os.Remove("test11.json")
+
+	_, err = suite.executeCommand("export model BadApp", 0)
+	assert.Error(suite.T(), err, "namespace BadApp not found")
 }
 
 func (suite *testSuite) Test99_ConfigSave() {
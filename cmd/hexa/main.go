@@ -40,9 +40,10 @@ type CLI struct {
 	Add       AddCmd       `cmd:"" help:"Add a new integration"`
 	Delete    DeleteCmd    `cmd:"" help:"Delete an integration or policy application point from local configuration"`
 	Get       GetCmd       `cmd:"" help:"Retrieve or update information and display"`
-	Export    ExportCmd    `cmd:"" help:"Export an integration configuration (for use with Policy-Orchestrator web application)"`
+	Export    ExportCmd    `cmd:"" help:"Export an integration configuration (for use with Policy-Orchestrator web application) or a policy model"`
 	Map       MapCmd       `cmd:"" help:"Convert syntactical policies to and from IDQL"`
 	Reconcile ReconcileCmd `cmd:"" help:"Reconcile compares a source set of policies another source (file or alias) of policies to determine differences."`
+	Impact    ImpactCmd    `cmd:"" help:"Impact compares two sets of policies (file or alias) and reports requests whose access decision changes."`
 	Set       SetCmd       `cmd:"" help:"Set or update policies (e.g. set policies -file=idql.json)"`
 	Show      ShowCmd      `cmd:"" help:"Show locally stored information about integrations and applications"`
 	Load      LoadCmd      `cmd:"" help:"Load data for local use (eg. load model)"`
@@ -169,7 +170,7 @@ func initParser(cli *CLI) (*ParserData, error) {
 	return &td, err
 }
 
-var keywords = []string{"add", "aws", "cognito", "apigw", "avp", "gcp", "azure", "integration", "int", "paps", "app", "applications", "policies", "map", "to", "from", "reconcile", "set", "show", "exit", "help", "--file="}
+var keywords = []string{"add", "aws", "cognito", "apigw", "avp", "gcp", "azure", "integration", "int", "paps", "app", "applications", "policies", "map", "to", "from", "reconcile", "impact", "set", "show", "exit", "help", "--file="}
 
 // lowercaseKeywords helps make console appear case insensitive
 func lowercaseKeywords(args []string) []string {
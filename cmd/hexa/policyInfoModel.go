@@ -16,7 +16,7 @@ type LoadCmd struct {
 }
 
 type LoadModelCmd struct {
-	File string `arg:"" required:"" type:"path" help:"A json file containing an IDQL Policy Model or Cedar Schema"`
+	File string `arg:"" required:"" type:"path" help:"A file containing an IDQL Policy Model or Cedar Schema (JSON or .cedarschema)"`
 }
 
 func (m *LoadModelCmd) Run(cli *CLI) error {
@@ -175,6 +175,32 @@ func (s *ShowModelCmd) Run(cli *CLI) error {
 	return nil
 }
 
+type ExportModelCmd struct {
+	Namespace string `arg:"" required:"" help:"The policy application namespace to export (e.g. PhotoApp)"`
+	Format    string `short:"f" default:"cedar" enum:"cedar,avp,cedarschema,jsonschema,openapi" help:"Export format: cedar (Cedar JSON schema, also avp), cedarschema, jsonschema (entities document), or openapi (components)"`
+	File      string `optional:"" type:"path" help:"File to write the exported model to (default is console)"`
+}
+
+func (e *ExportModelCmd) Run(cli *CLI) error {
+	if cli.Namespaces == nil {
+		return errors.New("no namespaces loaded. Use the `load model` command")
+	}
+	modelBytes, err := cli.Namespaces.Export(e.Namespace, e.Format)
+	if err != nil {
+		return err
+	}
+
+	if e.File != "" {
+		fmt.Println(fmt.Sprintf("Exporting to: %s ...", e.File))
+		return os.WriteFile(e.File, modelBytes, 0644)
+	}
+
+	ow := cli.GetOutputWriter()
+	fmt.Println(string(modelBytes))
+	ow.WriteBytes(modelBytes, true)
+	return nil
+}
+
 type ValidatePolicyCmd struct {
 	Namespace string `arg:"" required:"" help:"Default namespace for the policy (e.g. PhotoApp)"`
 	File      string `arg:"" required:"" type:"path" help:"A json file containing an IDQL Policy to be validated"`
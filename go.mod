@@ -37,6 +37,7 @@ require (
 	golang.org/x/oauth2 v0.32.0
 	google.golang.org/api v0.254.0
 	google.golang.org/genproto/googleapis/api v0.0.0-20251029180050-ab9386a59fda
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -103,5 +104,4 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251029180050-ab9386a59fda // indirect
 	google.golang.org/grpc v1.76.0 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,745 @@
+package azureabac
+
+/*
+ Condition mapper for Azure attribute-based access control (ABAC) role assignment conditions - See:
+ https://learn.microsoft.com/en-us/azure/role-based-access-control/conditions-format
+
+ Azure conditions compare attributes of the request (@Request), resource (@Resource), principal (@Principal) and
+ environment (@Environment) using typed operators (e.g. StringEquals, NumericGreaterThan, DateTimeLessThan), combined
+ with AND, OR and NOT. For example:
+
+    subject.department eq "sales" and resource.tags.project sw "cascade"
+
+ becomes:
+
+    @Principal[department] StringEquals 'sales' AND @Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:project<$key_case_sensitive$>] StringStartsWith 'cascade'
+
+ IDQL attributes are mapped by prefix: subject is @Principal, resource is @Resource, req is @Request, and env is
+ @Environment. Blob index tags (resource.tags.<key> and req.tags.<key>), custom security attributes
+ (subject.attributes.<set>.<name>), and common storage and environment attributes have default mappings (see
+ defaultAttributes). The NameMapper may map an IDQL attribute to any Azure attribute (e.g. "resource.owner" to
+ "@Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:owner<$key_case_sensitive$>]").
+ The action being performed is req.action (ActionMatches) and req.suboperation (SubOperationMatches).
+*/
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	SourcePrincipal   = "@Principal"
+	SourceResource    = "@Resource"
+	SourceRequest     = "@Request"
+	SourceEnvironment = "@Environment"
+
+	FuncActionMatches       = "ActionMatches"
+	FuncSubOperationMatches = "SubOperationMatches"
+	OpExists                = "Exists"
+	OpNotExists             = "NotExists"
+
+	// QuantifierAnyOfAny is the cross product operator prefix used to map IDQL in
+	QuantifierAnyOfAny = "ForAnyOfAnyValues:"
+
+	// ConditionVersion is the Azure condition language version produced
+	ConditionVersion = "2.0"
+
+	AttrAction       = "req.action"
+	AttrSubOperation = "req.suboperation"
+
+	blobTags         = "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:"
+	keyCaseSensitive = "<$key_case_sensitive$>"
+	customAttributes = "Microsoft.Directory/CustomSecurityAttributes/Id:"
+)
+
+// sourcePrefixes maps IDQL attribute prefixes to Azure attribute sources
+var sourcePrefixes = [][2]string{
+	{"subject.", SourcePrincipal},
+	{"resource.", SourceResource},
+	{"req.", SourceRequest},
+	{"env.", SourceEnvironment},
+}
+
+// defaultAttributes maps IDQL attribute names to common Azure attributes
+var defaultAttributes = map[string]string{
+	"req.time":            SourceEnvironment + "[UtcNow]",
+	"req.privatelink":     SourceEnvironment + "[isPrivateLink]",
+	"req.subnet":          SourceEnvironment + "[Microsoft.Network/virtualNetworks/subnets]",
+	"req.privateendpoint": SourceEnvironment + "[Microsoft.Network/privateEndpoints]",
+	"resource.container":  SourceResource + "[Microsoft.Storage/storageAccounts/blobServices/containers:name]",
+	"resource.path":       SourceResource + "[Microsoft.Storage/storageAccounts/blobServices/containers/blobs:path]",
+	"resource.version":    SourceResource + "[Microsoft.Storage/storageAccounts/blobServices/containers/blobs:versionId]",
+}
+
+var idqlNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*(\.[A-Za-z_][A-Za-z0-9_\-]*)*$`)
+
+// operatorTypes holds the Azure operator for each IDQL comparison by value type
+var operatorTypes = map[parser.CompareOperator]map[int]string{
+	parser.EQ: {types.TypeString: "StringEquals", types.TypeNumber: "NumericEquals", types.TypeBool: "BoolEquals", types.TypeDate: "DateTimeEquals"},
+	parser.NE: {types.TypeString: "StringNotEquals", types.TypeNumber: "NumericNotEquals", types.TypeBool: "BoolNotEquals", types.TypeDate: "DateTimeNotEquals"},
+	parser.GT: {types.TypeNumber: "NumericGreaterThan", types.TypeDate: "DateTimeGreaterThan"},
+	parser.GE: {types.TypeNumber: "NumericGreaterThanEquals", types.TypeDate: "DateTimeGreaterThanEquals"},
+	parser.LT: {types.TypeNumber: "NumericLessThan", types.TypeDate: "DateTimeLessThan"},
+	parser.LE: {types.TypeNumber: "NumericLessThanEquals", types.TypeDate: "DateTimeLessThanEquals"},
+	parser.SW: {types.TypeString: "StringStartsWith"},
+}
+
+// azureOperators maps Azure operators to an IDQL comparison, the type of value compared, and whether it is negated
+var azureOperators = map[string]struct {
+	op        parser.CompareOperator
+	valueType int
+	negate    bool
+}{
+	"StringStartsWith":              {parser.SW, types.TypeString, false},
+	"StringStartsWithIgnoreCase":    {parser.SW, types.TypeString, false},
+	"StringNotStartsWith":           {parser.SW, types.TypeString, true},
+	"StringNotStartsWithIgnoreCase": {parser.SW, types.TypeString, true},
+	"StringEqualsIgnoreCase":        {parser.EQ, types.TypeString, false},
+	"StringNotEqualsIgnoreCase":     {parser.NE, types.TypeString, false},
+	"GuidEquals":                    {parser.EQ, types.TypeString, false},
+	"GuidNotEquals":                 {parser.NE, types.TypeString, false},
+}
+
+func init() {
+	for op, byType := range operatorTypes {
+		for valueType, azureOp := range byType {
+			azureOperators[azureOp] = struct {
+				op        parser.CompareOperator
+				valueType int
+				negate    bool
+			}{op, valueType, false}
+		}
+	}
+}
+
+type AzureAbacConditionMapper struct {
+	NameMapper *conditions.AttributeMap
+}
+
+// MapConditionToProvider converts an IDQL condition into an Azure role assignment condition. The condition action is
+// not mapped as Azure conditions only restrict the role assignment they are part of.
+func (mapper *AzureAbacConditionMapper) MapConditionToProvider(condition conditions.ConditionInfo) (string, error) {
+	ast, err := conditions.ParseConditionRuleAst(condition)
+	if err != nil {
+		return "", err
+	}
+	return mapper.MapFilter(ast)
+}
+
+// MapFilter converts an IDQL condition AST into an Azure condition expression
+func (mapper *AzureAbacConditionMapper) MapFilter(ast parser.Expression) (string, error) {
+	switch element := ast.(type) {
+	case parser.PrecedenceExpression:
+		return mapper.MapFilter(element.Expression)
+	case parser.NotExpression:
+		expression, err := mapper.MapFilter(element.Expression)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + expression + ")", nil
+	case parser.LogicalExpression:
+		op := "AND"
+		if element.Operator == parser.OR {
+			op = "OR"
+		}
+		sides := make([]string, 2)
+		for i, side := range []parser.Expression{element.Left, element.Right} {
+			expression, err := mapper.MapFilter(side)
+			if err != nil {
+				return "", err
+			}
+			if precedence, ok := side.(parser.PrecedenceExpression); ok {
+				side = precedence.Expression
+			}
+			if child, ok := side.(parser.LogicalExpression); ok && child.Operator != element.Operator {
+				expression = "(" + expression + ")"
+			}
+			sides[i] = expression
+		}
+		return sides[0] + " " + op + " " + sides[1], nil
+	case parser.AttributeExpression:
+		return mapper.mapAttrExpr(element)
+	case parser.ValuePathExpression:
+		return "", fmt.Errorf("IDQL value path expressions (%s) are not supported in Azure conditions", element.String())
+	}
+	return "", fmt.Errorf("unsupported IDQL expression: %s", ast.String())
+}
+
+func (mapper *AzureAbacConditionMapper) mapAttrExpr(attrExpr parser.AttributeExpression) (string, error) {
+	entity, ok := attrExpr.AttributePath.(types.Entity)
+	if !ok || !isAttributePath(entity) {
+		return "", fmt.Errorf("left hand side of '%s' must be an attribute", attrExpr.String())
+	}
+	path := entity.String()
+	switch strings.ToLower(path) {
+	case AttrAction, AttrSubOperation:
+		return mapper.mapActionMatch(attrExpr)
+	}
+	attr, err := mapper.mapAttribute(path)
+	if err != nil {
+		return "", err
+	}
+
+	switch attrExpr.Operator {
+	case parser.PR:
+		return OpExists + " " + attr, nil
+	case parser.EW, parser.CO:
+		value, ok := attrExpr.CompareValue.(types.String)
+		if !ok {
+			return "", fmt.Errorf("IDQL operator '%s' requires a string value in Azure conditions", attrExpr.Operator)
+		}
+		pattern, err := quote(value.Value().(string))
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(pattern, "*") {
+			return "", fmt.Errorf("'%s' cannot be expressed as an Azure StringLike pattern", attrExpr.String())
+		}
+		pattern = "'*" + pattern[1:]
+		if attrExpr.Operator == parser.CO {
+			pattern = pattern[:len(pattern)-1] + "*'"
+		}
+		return attr + " StringLike " + pattern, nil
+	case parser.IN:
+		array, ok := attrExpr.CompareValue.(types.Array)
+		if !ok {
+			return "", fmt.Errorf("IDQL entity membership (%s) is not supported in Azure conditions", attrExpr.String())
+		}
+		items := array.Value().([]types.ComparableValue)
+		values := make([]string, len(items))
+		for i, item := range items {
+			if values[i], err = mapper.mapValue(item); err != nil {
+				return "", err
+			}
+		}
+		azureOp, exists := operatorTypes[parser.EQ][items[0].ValueType()]
+		if !exists {
+			return "", fmt.Errorf("unsupported value type in '%s'", attrExpr.String())
+		}
+		return fmt.Sprintf("%s %s%s {%s}", attr, QuantifierAnyOfAny, azureOp, strings.Join(values, ", ")), nil
+	}
+
+	byType, exists := operatorTypes[attrExpr.Operator]
+	if !exists {
+		return "", fmt.Errorf("IDQL operator '%s' is not supported in Azure conditions", attrExpr.Operator)
+	}
+	valueType := attrExpr.CompareValue.ValueType()
+	if valueType == types.TypeVariable {
+		// attribute comparisons are assumed to be of strings, or numbers for ordering operators
+		valueType = types.TypeString
+		if _, isString := byType[valueType]; !isString {
+			valueType = types.TypeNumber
+		}
+	}
+	azureOp, exists := byType[valueType]
+	if !exists {
+		return "", fmt.Errorf("IDQL operator '%s' is not supported for %s values in Azure conditions", attrExpr.Operator, types.TypeName(valueType))
+	}
+	value, err := mapper.mapValue(attrExpr.CompareValue)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", attr, azureOp, value), nil
+}
+
+// mapActionMatch maps comparisons of req.action and req.suboperation to ActionMatches and SubOperationMatches
+func (mapper *AzureAbacConditionMapper) mapActionMatch(attrExpr parser.AttributeExpression) (string, error) {
+	function := FuncActionMatches
+	if strings.EqualFold(attrExpr.AttributePath.String(), AttrSubOperation) {
+		function = FuncSubOperationMatches
+	}
+	value, ok := attrExpr.CompareValue.(types.String)
+	if !ok || (attrExpr.Operator != parser.EQ && attrExpr.Operator != parser.SW) {
+		return "", fmt.Errorf("'%s' must be an eq or sw comparison with a string", attrExpr.String())
+	}
+	pattern, err := quote(value.Value().(string))
+	if err != nil {
+		return "", err
+	}
+	if attrExpr.Operator == parser.SW {
+		pattern = pattern[:len(pattern)-1] + "*'"
+	}
+	return function + "{" + pattern + "}", nil
+}
+
+func (mapper *AzureAbacConditionMapper) mapValue(value types.Value) (string, error) {
+	switch v := value.(type) {
+	case types.String:
+		return quote(v.Value().(string))
+	case types.Date:
+		return quote(v.String())
+	case types.Numeric, types.Boolean:
+		return v.String(), nil
+	case types.Entity:
+		if isAttributePath(v) {
+			return mapper.mapAttribute(v.String())
+		}
+		return "", fmt.Errorf("IDQL entity comparison (%s) is not supported in Azure conditions", v.String())
+	}
+	return "", fmt.Errorf("unsupported value type %s in Azure condition mapping", types.TypeName(value.ValueType()))
+}
+
+// quote returns value as an Azure string literal. Azure string literals cannot contain quotes.
+func quote(value string) (string, error) {
+	if strings.Contains(value, "'") {
+		return "", fmt.Errorf("value '%s' contains a single quote which is not supported in Azure conditions", value)
+	}
+	return "'" + value + "'", nil
+}
+
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+// mapAttribute maps an IDQL attribute path into an Azure attribute (e.g. @Principal[department])
+func (mapper *AzureAbacConditionMapper) mapAttribute(path string) (string, error) {
+	if mapper.NameMapper != nil {
+		if mapped := mapper.NameMapper.GetProviderAttributeName(path); mapped != path {
+			return mapped, nil
+		}
+	}
+	lowerPath := strings.ToLower(path)
+	if attr, exists := defaultAttributes[lowerPath]; exists {
+		return attr, nil
+	}
+	switch {
+	case strings.HasPrefix(lowerPath, "resource.tags."):
+		return SourceResource + "[" + blobTags + path[len("resource.tags."):] + keyCaseSensitive + "]", nil
+	case strings.HasPrefix(lowerPath, "req.tags."):
+		return SourceRequest + "[" + blobTags + path[len("req.tags."):] + keyCaseSensitive + "]", nil
+	case strings.HasPrefix(lowerPath, "subject.attributes."):
+		set, name, found := strings.Cut(path[len("subject.attributes."):], ".")
+		if !found || strings.Contains(name, ".") {
+			return "", fmt.Errorf("custom security attribute '%s' must be of the form subject.attributes.<set>.<name>", path)
+		}
+		return SourcePrincipal + "[" + customAttributes + set + "_" + name + "]", nil
+	}
+	for _, prefix := range sourcePrefixes {
+		if strings.HasPrefix(lowerPath, prefix[0]) {
+			return prefix[1] + "[" + path[len(prefix[0]):] + "]", nil
+		}
+	}
+	return "", fmt.Errorf("attribute '%s' has no Azure source (use a subject, resource, req or env attribute, or map the name)", path)
+}
+
+// mapAzureAttribute maps an Azure attribute (e.g. @Principal[department]) into an IDQL attribute path
+func (mapper *AzureAbacConditionMapper) mapAzureAttribute(attr string) (string, error) {
+	if mapper.NameMapper != nil {
+		if mapped := mapper.NameMapper.GetHexaFilterAttributePath(strings.ToLower(attr)); !strings.EqualFold(mapped, attr) {
+			return mapped, nil
+		}
+	}
+	for path, azureAttr := range defaultAttributes {
+		if strings.EqualFold(azureAttr, attr) {
+			return path, nil
+		}
+	}
+	source, name, _ := strings.Cut(strings.TrimSuffix(attr, "]"), "[")
+	name = strings.TrimSuffix(name, keyCaseSensitive)
+	switch {
+	case strings.EqualFold(source, SourceResource) && strings.HasPrefix(name, blobTags):
+		name = "resource.tags." + name[len(blobTags):]
+	case strings.EqualFold(source, SourceRequest) && strings.HasPrefix(name, blobTags):
+		name = "req.tags." + name[len(blobTags):]
+	case strings.EqualFold(source, SourcePrincipal) && strings.HasPrefix(name, customAttributes):
+		set, attrName, found := strings.Cut(name[len(customAttributes):], "_")
+		if !found {
+			return "", fmt.Errorf("invalid custom security attribute %s", attr)
+		}
+		name = "subject.attributes." + set + "." + attrName
+	default:
+		matched := false
+		for _, prefix := range sourcePrefixes {
+			if strings.EqualFold(source, prefix[1]) {
+				name = prefix[0] + name
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", fmt.Errorf("unsupported Azure attribute source %s", source)
+		}
+	}
+	if !idqlNameRegex.MatchString(name) {
+		return "", fmt.Errorf("Azure attribute %s has no IDQL equivalent (map the name)", attr)
+	}
+	return name, nil
+}
+
+// MapProviderToCondition maps an Azure role assignment condition into an IDQL allow condition
+func (mapper *AzureAbacConditionMapper) MapProviderToCondition(expression string) (conditions.ConditionInfo, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return conditions.ConditionInfo{}, fmt.Errorf("invalid Azure condition '%s': %s", expression, err.Error())
+	}
+	p := &condParser{tokens: tokens, mapper: mapper}
+	ast, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected '%s'", p.peek().text)
+	}
+	if err != nil {
+		return conditions.ConditionInfo{}, fmt.Errorf("invalid Azure condition '%s': %s", expression, err.Error())
+	}
+	return conditions.ConditionInfo{Rule: conditions.SerializeExpression(ast), Action: conditions.AAllow}, nil
+}
+
+const (
+	tokIdent = iota
+	tokAttribute
+	tokString
+	tokNumber
+	tokOperator
+	tokPunct
+	tokEOF
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			start := i + 1
+			i++
+			for i < len(runes) && runes[i] != '\'' {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, token{tokString, string(runes[start:i])})
+			i++
+		case r == '@':
+			start := i
+			for i < len(runes) && runes[i] != ']' {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated attribute")
+			}
+			i++
+			tokens = append(tokens, token{tokAttribute, string(runes[start:i])})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i])})
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == ':') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[start:i])})
+		default:
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				if pair == "&&" || pair == "||" {
+					tokens = append(tokens, token{tokOperator, pair})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '!':
+				tokens = append(tokens, token{tokOperator, string(r)})
+			case '(', ')', '{', '}', ',':
+				tokens = append(tokens, token{tokPunct, string(r)})
+			default:
+				return nil, fmt.Errorf("unexpected character '%c'", r)
+			}
+			i++
+		}
+	}
+	return append(tokens, token{tokEOF, ""}), nil
+}
+
+type condParser struct {
+	tokens []token
+	pos    int
+	mapper *AzureAbacConditionMapper
+}
+
+func (p *condParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *condParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isOperator returns true if the next token is the symbol or keyword (case-insensitive) operator
+func (p *condParser) isOperator(symbol string, keyword string) bool {
+	tok := p.peek()
+	return (tok.kind == tokOperator && tok.text == symbol) || (tok.kind == tokIdent && strings.EqualFold(tok.text, keyword))
+}
+
+func (p *condParser) expectPunct(text string) error {
+	tok := p.next()
+	if tok.kind != tokPunct || tok.text != text {
+		return fmt.Errorf("expected '%s' but found '%s'", text, tok.text)
+	}
+	return nil
+}
+
+func (p *condParser) parseOr() (parser.Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("||", "OR") {
+		left = precedence(left)
+	}
+	for p.isOperator("||", "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = parser.LogicalExpression{Operator: parser.OR, Left: left, Right: precedence(right)}
+	}
+	return left, nil
+}
+
+// precedence brackets an and expression within an or expression (IDQL does not give and a higher precedence)
+func precedence(expression parser.Expression) parser.Expression {
+	if logical, ok := expression.(parser.LogicalExpression); ok && logical.Operator == parser.AND {
+		return parser.PrecedenceExpression{Expression: expression}
+	}
+	return expression
+}
+
+func (p *condParser) parseAnd() (parser.Expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&", "AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = parser.LogicalExpression{Operator: parser.AND, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (parser.Expression, error) {
+	if p.isOperator("!", "NOT") {
+		p.next()
+		expression, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not(expression), nil
+	}
+	if tok := p.peek(); tok.kind == tokPunct && tok.text == "(" {
+		p.next()
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return parser.PrecedenceExpression{Expression: expression}, nil
+	}
+	tok := p.peek()
+	if tok.kind == tokIdent {
+		switch {
+		case strings.EqualFold(tok.text, FuncActionMatches):
+			return p.parseMatches(AttrAction)
+		case strings.EqualFold(tok.text, FuncSubOperationMatches):
+			return p.parseMatches(AttrSubOperation)
+		case strings.EqualFold(tok.text, OpExists), strings.EqualFold(tok.text, OpNotExists):
+			p.next()
+			attr := p.next()
+			if attr.kind != tokAttribute {
+				return nil, fmt.Errorf("%s requires an attribute", tok.text)
+			}
+			path, err := p.mapper.mapAzureAttribute(attr.text)
+			if err != nil {
+				return nil, err
+			}
+			expression, err := parser.NewAttributeExpression(path, parser.PR, "")
+			if err != nil || strings.EqualFold(tok.text, OpExists) {
+				return expression, err
+			}
+			return parser.NotExpression{Expression: expression}, nil
+		}
+	}
+	return p.parseComparison()
+}
+
+func not(expression parser.Expression) parser.Expression {
+	if precedence, ok := expression.(parser.PrecedenceExpression); ok {
+		expression = precedence.Expression
+	}
+	return parser.NotExpression{Expression: expression}
+}
+
+// parseMatches maps ActionMatches{'pattern'} and SubOperationMatches{'pattern'} to eq or sw comparisons
+func (p *condParser) parseMatches(path string) (parser.Expression, error) {
+	name := p.next().text
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	pattern := p.next()
+	if pattern.kind != tokString {
+		return nil, fmt.Errorf("%s requires a string pattern", name)
+	}
+	if err := p.expectPunct("}"); err != nil {
+		return nil, err
+	}
+	op, literal := parser.EQ, pattern.text
+	if strings.HasSuffix(literal, "*") {
+		op, literal = parser.SW, strings.TrimSuffix(literal, "*")
+	}
+	if strings.Contains(literal, "*") {
+		return nil, fmt.Errorf("%s pattern '%s' is not supported", name, pattern.text)
+	}
+	return parser.NewAttributeExpression(path, op, strconv.Quote(literal))
+}
+
+func (p *condParser) parseComparison() (parser.Expression, error) {
+	attr := p.next()
+	if attr.kind != tokAttribute {
+		return nil, fmt.Errorf("expected an attribute but found '%s'", attr.text)
+	}
+	path, err := p.mapper.mapAzureAttribute(attr.text)
+	if err != nil {
+		return nil, err
+	}
+	opTok := p.next()
+	if opTok.kind != tokIdent {
+		return nil, fmt.Errorf("expected an operator but found '%s'", opTok.text)
+	}
+	azureOp, isAnyOfAny := strings.CutPrefix(opTok.text, QuantifierAnyOfAny)
+	if strings.Contains(azureOp, ":") {
+		return nil, fmt.Errorf("operator '%s' is not supported", opTok.text)
+	}
+
+	if p.peek().kind == tokPunct && p.peek().text == "{" {
+		p.next()
+		var values []string
+		for {
+			value, err := p.parseValue(types.TypeString)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if tok := p.next(); tok.kind != tokPunct || tok.text != "," {
+				if tok.text != "}" {
+					return nil, fmt.Errorf("expected '}' but found '%s'", tok.text)
+				}
+				break
+			}
+		}
+		mapping, exists := azureOperators[azureOp]
+		if !isAnyOfAny || !exists || mapping.op != parser.EQ {
+			return nil, fmt.Errorf("operator '%s' with a set of values is not supported", opTok.text)
+		}
+		return parser.NewAttributeExpression(path, parser.IN, "["+strings.Join(values, ",")+"]")
+	}
+
+	if strings.EqualFold(azureOp, "StringLike") || strings.EqualFold(azureOp, "StringNotLike") {
+		return p.parseLike(path, opTok.text, strings.EqualFold(azureOp, "StringNotLike"))
+	}
+	mapping, exists := azureOperators[azureOp]
+	if !exists {
+		return nil, fmt.Errorf("operator '%s' is not supported", opTok.text)
+	}
+	value, err := p.parseValue(mapping.valueType)
+	if err != nil {
+		return nil, err
+	}
+	op := mapping.op
+	if isAnyOfAny {
+		if op != parser.EQ {
+			return nil, fmt.Errorf("operator '%s' is not supported", opTok.text)
+		}
+		op, value = parser.IN, "["+value+"]"
+	}
+	expression, err := parser.NewAttributeExpression(path, op, value)
+	if err != nil || !mapping.negate {
+		return expression, err
+	}
+	return not(expression), nil
+}
+
+// parseLike maps a StringLike pattern with leading and/or trailing wildcards to an ew, co, sw or eq comparison
+func (p *condParser) parseLike(path string, azureOp string, negate bool) (parser.Expression, error) {
+	pattern := p.next()
+	if pattern.kind != tokString {
+		return nil, fmt.Errorf("%s requires a string pattern", azureOp)
+	}
+	literal := pattern.text
+	starts := strings.HasSuffix(literal, "*")
+	ends := strings.HasPrefix(literal, "*")
+	literal = strings.TrimSuffix(strings.TrimPrefix(literal, "*"), "*")
+	if strings.Contains(literal, "*") || literal == "" {
+		return nil, fmt.Errorf("%s pattern '%s' is not supported", azureOp, pattern.text)
+	}
+	op := parser.EQ
+	switch {
+	case starts && ends:
+		op = parser.CO
+	case starts:
+		op = parser.SW
+	case ends:
+		op = parser.EW
+	}
+	expression, err := parser.NewAttributeExpression(path, op, strconv.Quote(literal))
+	if err != nil || !negate {
+		return expression, err
+	}
+	return not(expression), nil
+}
+
+// parseValue returns the next literal or attribute as an IDQL value. valueType is the type expected by the operator.
+func (p *condParser) parseValue(valueType int) (string, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		if valueType == types.TypeDate {
+			if _, err := time.Parse(time.RFC3339, tok.text); err != nil {
+				return "", fmt.Errorf("invalid date '%s'", tok.text)
+			}
+			return tok.text, nil
+		}
+		return strconv.Quote(tok.text), nil
+	case tokNumber:
+		return tok.text, nil
+	case tokIdent:
+		if strings.EqualFold(tok.text, "true") || strings.EqualFold(tok.text, "false") {
+			return strings.ToLower(tok.text), nil
+		}
+	case tokAttribute:
+		return p.mapper.mapAzureAttribute(tok.text)
+	}
+	return "", fmt.Errorf("unexpected '%s'", tok.text)
+}
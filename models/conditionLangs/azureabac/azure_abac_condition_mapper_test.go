@@ -0,0 +1,120 @@
+package azureabac_test
+
+import (
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/models/conditionLangs/azureabac"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+var mapper = azureabac.AzureAbacConditionMapper{
+	NameMapper: conditions.NewNameMapper(map[string]string{
+		"resource.owner": "@Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:owner<$key_case_sensitive$>]",
+	}),
+}
+
+func TestMapConditions(t *testing.T) {
+	tests := []struct {
+		idql  string
+		azure string
+	}{
+		{"subject.department eq \"sales\"", "@Principal[department] StringEquals 'sales'"},
+		{"subject.level ge 5", "@Principal[level] NumericGreaterThanEquals 5"},
+		{"subject.active ne true", "@Principal[active] BoolNotEquals true"},
+		{"req.time lt 2030-01-01T00:00:00Z", "@Environment[UtcNow] DateTimeLessThan '2030-01-01T00:00:00Z'"},
+		{"resource.tags.project sw \"cascade\"", "@Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:project<$key_case_sensitive$>] StringStartsWith 'cascade'"},
+		{"resource.path ew \".log\"", "@Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs:path] StringLike '*.log'"},
+		{"resource.container co \"logs\"", "@Resource[Microsoft.Storage/storageAccounts/blobServices/containers:name] StringLike '*logs*'"},
+		{"subject.attributes.engineering.project in [\"alpha\",\"beta\"]", "@Principal[Microsoft.Directory/CustomSecurityAttributes/Id:engineering_project] ForAnyOfAnyValues:StringEquals {'alpha', 'beta'}"},
+		{"req.tags.project pr", "Exists @Request[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:project<$key_case_sensitive$>]"},
+		{"resource.owner eq subject.sub", "@Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:owner<$key_case_sensitive$>] StringEquals @Principal[sub]"},
+		{"env.region eq \"westus\"", "@Environment[region] StringEquals 'westus'"},
+		{"req.privatelink eq true", "@Environment[isPrivateLink] BoolEquals true"},
+		{
+			"not(req.action eq \"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read\" and req.suboperation eq \"Blob.List\")",
+			"NOT (ActionMatches{'Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read'} AND SubOperationMatches{'Blob.List'})",
+		},
+		{"req.action sw \"Microsoft.Storage/\"", "ActionMatches{'Microsoft.Storage/*'}"},
+		{
+			"(subject.a eq 1 or subject.b eq 2) and subject.c eq 3",
+			"(@Principal[a] NumericEquals 1 OR @Principal[b] NumericEquals 2) AND @Principal[c] NumericEquals 3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.idql, func(t *testing.T) {
+			azure, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: test.idql, Action: conditions.AAllow})
+			assert.NoError(t, err)
+			assert.Equal(t, test.azure, azure)
+
+			condition, err := mapper.MapProviderToCondition(azure)
+			assert.NoError(t, err)
+			expected := conditions.ConditionInfo{Rule: test.idql, Action: conditions.AAllow}
+			assert.True(t, expected.Equals(&condition), "got %s", condition.Rule)
+		})
+	}
+}
+
+func TestMapProviderToCondition(t *testing.T) {
+	tests := [][2]string{
+		{
+			"((!(ActionMatches{'Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read'} AND NOT SubOperationMatches{'Blob.List'})) OR (@Resource[Microsoft.Storage/storageAccounts/blobServices/containers/blobs/tags:Project<$key_case_sensitive$>] StringEqualsIgnoreCase 'Cascade'))",
+			"(not(req.action eq \"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read\" and not(req.suboperation eq \"Blob.List\")) or resource.tags.Project eq \"Cascade\")",
+		},
+		{"@Principal[dept] StringNotStartsWith 'eng' && NotExists @Resource[owner]", "not(subject.dept sw \"eng\") and not(resource.owner pr)"},
+		{"@Principal[dept] ForAnyOfAnyValues:StringEquals 'eng'", "subject.dept in [\"eng\"]"},
+		{"@Principal[dept] StringNotLike 'eng*' || @Request[size] NumericLessThan 10.5", "not(subject.dept sw \"eng\") or req.size lt 10.5"},
+		{"@Principal[dept] StringLike 'eng'", "subject.dept eq \"eng\""},
+		{"@Principal[Id] GuidEquals '2ad0e7d2-1b4c-4a8a-b1b4-7c6a0b4e4c32'", "subject.Id eq \"2ad0e7d2-1b4c-4a8a-b1b4-7c6a0b4e4c32\""},
+		{"@Environment[UtcNow] DateTimeGreaterThan '2023-05-01T13:00:00.000Z'", "req.time gt 2023-05-01T13:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test[0], func(t *testing.T) {
+			condition, err := mapper.MapProviderToCondition(test[0])
+			assert.NoError(t, err)
+			assert.Equal(t, test[1], condition.Rule)
+			assert.Equal(t, conditions.AAllow, condition.Action)
+		})
+	}
+
+	for _, expression := range []string{
+		"@Principal[dept] StringEquals",
+		"@Principal[dept] IpMatch '10.0.0.0/8'",
+		"@Principal[dept] ForAllOfAnyValues:StringEquals {'a'}",
+		"@Principal[dept] StringEquals {'a', 'b'}",
+		"@Principal[dept] ForAnyOfAnyValues:NumericLessThan 5",
+		"@Principal[dept] StringLike 'a*b'",
+		"@Principal[dept] DateTimeEquals 'yesterday'",
+		"@Principal[dept StringEquals 'a'",
+		"@Principal[dept] StringEquals 'a",
+		"@Principal[dept] StringEquals 'a' AND",
+		"(@Principal[dept] StringEquals 'a'",
+		"@Principal[dept] StringEquals 'a' #",
+		"@Device[id] StringEquals 'a'",
+		"@Principal[some attr] StringEquals 'a'",
+		"ActionMatches{'a*b'}",
+		"ActionMatches{5}",
+		"Exists 'a'",
+		"'a' StringEquals @Principal[dept]",
+	} {
+		_, err := mapper.MapProviderToCondition(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestMapConditionToProvider_Errors(t *testing.T) {
+	for _, rule := range []string{
+		"subject.name sw 5",
+		"subject.name eq \"o'brien\"",
+		"subject.name co \"a*b\"",
+		"subject.name gt \"a\"",
+		"subject in Group:admins",
+		"emails[type eq \"work\"] pr",
+		"req.action co \"read\"",
+		"user.name eq \"a\"",
+		"subject.attributes.engineering eq \"a\"",
+	} {
+		_, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: rule, Action: conditions.AAllow})
+		assert.Error(t, err, rule)
+	}
+}
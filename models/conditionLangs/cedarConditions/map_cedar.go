@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cedar-policy/cedar-go/types"
 	cedarjson "github.com/hexa-org/policy-mapper/models/formats/cedar/json"
@@ -36,8 +37,10 @@ func MapCedarConditionToHexa(cedarConditions []cedarjson.ConditionJSON) (*condit
 	default:
 		merge := ""
 		for i, cond := range cedarConditions {
-			var clause string
-			clause, err = mapConditionClause(cond, false)
+			clause, clauseErr := mapConditionClause(cond, false)
+			if clauseErr != nil {
+				return nil, clauseErr
+			}
 			if i == 0 {
 				merge = clause
 			} else {
@@ -120,6 +123,8 @@ func mapCedarRelationComparator(node cedarjson.NodeJSON) (string, error) {
 			return "", err
 		}
 		return fmt.Sprintf("%s.%s", lh, node.Access.Attr), nil
+	case node.ExtensionCall != nil:
+		return mapCedarExtensionValue(node)
 	case node.IfThenElse != nil:
 		return "", formatNodeParseError(node, "if-then-else not supported by Hexa IDQL: %s")
 	default:
@@ -127,6 +132,35 @@ func mapCedarRelationComparator(node cedarjson.NodeJSON) (string, error) {
 	}
 }
 
+// mapCedarExtensionValue maps the extension constructors ip, decimal and datetime to IDQL string, numeric and date values
+func mapCedarExtensionValue(node cedarjson.NodeJSON) (string, error) {
+	for name, args := range node.ExtensionCall {
+		if len(args) != 1 || args[0].Value == nil {
+			break
+		}
+		arg, ok := args[0].Value.V.(types.String)
+		if !ok {
+			break
+		}
+		switch name {
+		case "ip":
+			return strconv.Quote(string(arg)), nil
+		case "decimal":
+			if _, err := strconv.ParseFloat(string(arg), 64); err == nil {
+				return string(arg), nil
+			}
+		case "datetime":
+			if _, err := time.Parse(time.RFC3339, string(arg)); err == nil {
+				return string(arg), nil
+			}
+			if date, err := time.Parse(time.DateOnly, string(arg)); err == nil {
+				return date.Format(time.RFC3339), nil
+			}
+		}
+	}
+	return "", formatNodeParseError(node, "extension value not supported by Hexa IDQL: %s")
+}
+
 func mapRelation(op hexaParser.CompareOperator, left cedarjson.NodeJSON, right cedarjson.NodeJSON) (hexaParser.Expression, error) {
 
 	lh, err := mapCedarRelationComparator(left)
@@ -153,33 +187,106 @@ func mapRelation(op hexaParser.CompareOperator, left cedarjson.NodeJSON, right c
 }
 
 func mapCedarFunc(op hexaParser.CompareOperator, nodes []cedarjson.NodeJSON) (hexaParser.Expression, error) {
-	var left, right string
-	for _, node := range nodes {
-		if node.Value != nil {
-			right = node.Value.V.String()
-		} else if node.Access != nil {
-			attr, _ := mapCedarRelationComparator(node.Access.Left)
-			left = fmt.Sprintf("%s.%s", attr, node.Access.Attr)
+	if len(nodes) != 2 {
+		return nil, fmt.Errorf("expecting 2 arguments for %s comparison, found %d", op, len(nodes))
+	}
+	return mapRelation(op, nodes[0], nodes[1])
+}
+
+// mapCedarContainsSet maps containsAll and containsAny to a series of co comparisons joined by andor
+func mapCedarContainsSet(andor hexaParser.LogicalOperator, node cedarjson.BinaryJSON, isNested bool) (hexaParser.Expression, error) {
+	if node.Right.Set == nil {
+		return nil, formatNodeParseError(node.Right, "containsAll and containsAny are only supported with a set literal: %s")
+	}
+	var exp hexaParser.Expression
+	for _, member := range node.Right.Set {
+		coExp, err := mapRelation(hexaParser.CO, node.Left, member)
+		if err != nil {
+			return nil, err
 		}
+		exp = makeLogical(andor, exp, coExp)
+	}
+	if exp == nil {
+		return nil, formatNodeParseError(node.Right, "empty set not supported by Hexa IDQL: %s")
+	}
+	if andor == hexaParser.OR && isNested && len(node.Right.Set) > 1 {
+		return hexaParser.PrecedenceExpression{Expression: exp}, nil
 	}
+	return exp, nil
+}
 
-	lhv, err := hexaTypes.ParseValue(left)
-	if err != nil {
-		return nil, err
+// mapCedarExtensionCall maps extension functions used as conditions. Only isInRange has an IDQL equivalent (in).
+func mapCedarExtensionCall(node cedarjson.NodeJSON) (hexaParser.Expression, error) {
+	if args, ok := node.ExtensionCall["isInRange"]; ok && len(node.ExtensionCall) == 1 && len(args) == 2 {
+		return mapRelation(hexaParser.IN, args[0], args[1])
+	}
+	return nil, formatNodeParseError(node, "extension function not supported by Hexa IDQL: %s")
+}
+
+// isCedarBool returns the value and true when node is a boolean literal
+func isCedarBool(node cedarjson.NodeJSON) (bool, bool) {
+	if node.Value == nil {
+		return false, false
 	}
-	rhv, err := hexaTypes.ParseValue(right)
+	val, ok := node.Value.V.(types.Boolean)
+	return bool(val), ok
+}
+
+/*
+mapCedarIfThenElse maps a boolean if-then-else to (if and then) or (not if and else). Where the then or else
+branch is a boolean literal, the expression is simplified (e.g. "if a then b else false" becomes "a and b").
+*/
+func mapCedarIfThenElse(ite cedarjson.NodeJSON, isNested bool) (hexaParser.Expression, error) {
+	ifExp, err := mapCedarNode(ite.IfThenElse.If, true)
 	if err != nil {
 		return nil, err
 	}
+	notIfExp := hexaParser.NotExpression{Expression: ifExp}
+	thenVal, thenIsBool := isCedarBool(ite.IfThenElse.Then)
+	elseVal, elseIsBool := isCedarBool(ite.IfThenElse.Else)
 
-	return hexaParser.AttributeExpression{
-		AttributePath: lhv,
-		Operator:      op,
-		CompareValue:  rhv,
-	}, nil
+	var thenExp, elseExp hexaParser.Expression
+	if !thenIsBool {
+		if thenExp, err = mapCedarNode(ite.IfThenElse.Then, true); err != nil {
+			return nil, err
+		}
+	}
+	if !elseIsBool {
+		if elseExp, err = mapCedarNode(ite.IfThenElse.Else, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var exp hexaParser.Expression
+	switch {
+	case thenIsBool && elseIsBool:
+		if thenVal == elseVal {
+			return nil, formatNodeParseError(ite, "constant if-then-else not supported by Hexa IDQL: %s")
+		}
+		if thenVal {
+			return ifExp, nil
+		}
+		return notIfExp, nil
+	case thenIsBool && thenVal:
+		exp = makeLogical(hexaParser.OR, ifExp, elseExp)
+	case thenIsBool:
+		return makeLogical(hexaParser.AND, notIfExp, elseExp), nil
+	case elseIsBool && elseVal:
+		exp = makeLogical(hexaParser.OR, notIfExp, thenExp)
+	case elseIsBool:
+		return makeLogical(hexaParser.AND, ifExp, thenExp), nil
+	default:
+		exp = makeLogical(hexaParser.OR,
+			makeLogical(hexaParser.AND, ifExp, thenExp),
+			makeLogical(hexaParser.AND, notIfExp, elseExp))
+	}
+	if !isNested {
+		return exp, nil
+	}
+	return hexaParser.PrecedenceExpression{Expression: exp}, nil
 }
 
-const likeError = "Hexa supports only SW, EW, and CO comparisons: %s"
+const likeError = "Hexa supports only like patterns that map to EQ, SW, EW, or CO comparisons: %s"
 
 func mapCedarNode(node cedarjson.NodeJSON, isNested bool) (hexaParser.Expression, error) {
 
@@ -281,62 +388,22 @@ func mapCedarNode(node cedarjson.NodeJSON, isNested bool) (hexaParser.Expression
 		}, nil
 
 	case node.Like != nil:
-		lh, _ := mapCedarRelationComparator(node.Like.Left)
-		lhv, err := hexaTypes.ParseValue(lh)
-		if err != nil {
-			return nil, err
-		}
-		pattern := removeQuotes(string(node.Like.Pattern.MarshalCedar()))
-
-		if strings.HasPrefix(pattern, "*") {
-			if strings.HasSuffix(pattern, "*") {
-				// Map "*<match>*" to Contains
-				rhv, err := hexaTypes.ParseValue(strconv.Quote(pattern[1 : len(pattern)-1]))
-				if err != nil {
-					return nil, err
-				}
-				if !strings.Contains(pattern[1:len(pattern)-1], "*") {
-					return hexaParser.AttributeExpression{
-						AttributePath: lhv,
-						Operator:      hexaParser.CO,
-						CompareValue:  rhv,
-					}, nil
-				}
-			}
-			if strings.Contains(pattern[1:], "*") {
-				// this is a complex pattern
-				return nil, errors.New(fmt.Sprintf(likeError, pattern))
-			}
-			rhv, err := hexaTypes.ParseValue(strconv.Quote(pattern[1:]))
-			if err != nil {
-				return nil, err
-			}
-			return hexaParser.AttributeExpression{
-				AttributePath: lhv,
-				Operator:      hexaParser.EW,
-				CompareValue:  rhv,
-			}, nil
-		}
-		if strings.HasSuffix(pattern, "*") {
-
-			if strings.Contains(pattern[0:len(pattern)-1], "*") {
-				// this is a complex pattern
-
-				return nil, errors.New(fmt.Sprintf(likeError, pattern))
-			}
-			rhv, err := hexaTypes.ParseValue(strconv.Quote(pattern[0 : len(pattern)-1]))
-			if err != nil {
-				return nil, err
-			}
-			return hexaParser.AttributeExpression{
-				AttributePath: lhv,
-				Operator:      hexaParser.SW,
-				CompareValue:  rhv,
-			}, nil
-		}
-		return nil, errors.New(fmt.Sprintf(likeError, pattern))
+		return mapCedarLike(node)
+	case node.Access != nil:
+		// a boolean attribute (e.g. when { context.authenticated })
+		return mapRelation(hexaParser.EQ, node, cedarjson.NodeJSON{Value: &cedarjson.ValueJSON{V: types.True}})
+	case node.ContainsAll != nil:
+		return mapCedarContainsSet(hexaParser.AND, *node.ContainsAll, isNested)
+	case node.ContainsAny != nil:
+		return mapCedarContainsSet(hexaParser.OR, *node.ContainsAny, isNested)
+	case node.ExtensionCall != nil:
+		return mapCedarExtensionCall(node)
+	case node.Record != nil:
+		return nil, formatNodeParseError(node, "record literals are not supported by Hexa IDQL: %s")
+	case node.GetTag != nil, node.HasTag != nil:
+		return nil, formatNodeParseError(node, "entity tags are not supported by Hexa IDQL: %s")
 	case node.IfThenElse != nil:
-		return nil, formatNodeParseError(node, "if-then-else is not supported by Hexa IDQL: %s")
+		return mapCedarIfThenElse(node, isNested)
 
 	case node.Negate != nil, node.Subtract != nil, node.Add != nil, node.Multiply != nil:
 		return nil, formatNodeParseError(node, "calculations (negate, add, multiply, subtract) are not supported by Hexa IDQL: %s")
@@ -347,6 +414,84 @@ func mapCedarNode(node cedarjson.NodeJSON, isNested bool) (hexaParser.Expression
 
 }
 
+/*
+mapCedarLike maps like patterns with a single literal to eq ("abc"), sw ("abc*"), ew ("*abc") or co ("*abc*"). Patterns
+with more than one literal (e.g. "a*b") have no IDQL equivalent.
+*/
+func mapCedarLike(node cedarjson.NodeJSON) (hexaParser.Expression, error) {
+	lh, err := mapCedarRelationComparator(node.Like.Left)
+	if err != nil {
+		return nil, err
+	}
+	lhv, err := hexaTypes.ParseValue(lh)
+	if err != nil {
+		return nil, err
+	}
+	pattern := string(node.Like.Pattern.MarshalCedar())
+	// the pattern is not unquoted here as it may contain escaped wildcards (\*)
+	literals, leading, trailing := splitLikePattern(pattern[1 : len(pattern)-1])
+	if len(literals) != 1 {
+		return nil, errors.New(fmt.Sprintf(likeError, pattern))
+	}
+
+	op := hexaParser.EQ
+	switch {
+	case leading && trailing:
+		op = hexaParser.CO
+	case leading:
+		op = hexaParser.EW
+	case trailing:
+		op = hexaParser.SW
+	}
+	rhv, err := hexaTypes.ParseValue(strconv.Quote(literals[0]))
+	if err != nil {
+		return nil, err
+	}
+	return hexaParser.AttributeExpression{
+		AttributePath: lhv,
+		Operator:      op,
+		CompareValue:  rhv,
+	}, nil
+}
+
+// splitLikePattern splits an escaped like pattern into its literals and whether it starts or ends with a wildcard
+func splitLikePattern(pattern string) (literals []string, leading bool, trailing bool) {
+	var literal strings.Builder
+	endLiteral := func() {
+		if literal.Len() > 0 {
+			unquoted, err := strconv.Unquote("\"" + literal.String() + "\"")
+			if err != nil {
+				unquoted = literal.String()
+			}
+			literals = append(literals, unquoted)
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				literal.WriteByte('*')
+			} else if i+1 < len(pattern) {
+				literal.WriteString(pattern[i : i+2])
+			}
+			i++
+		case '*':
+			if i == 0 {
+				leading = true
+			}
+			endLiteral()
+			trailing = true
+			continue
+		default:
+			literal.WriteByte(pattern[i])
+		}
+		trailing = false
+	}
+	endLiteral()
+	return literals, leading, trailing
+}
+
 func formatNodeParseError(node cedarjson.NodeJSON, errMessageFmt string) error {
 	exp, _ := json.Marshal(node)
 	return errors.New(fmt.Sprintf(errMessageFmt, string(exp)))
@@ -1,14 +1,16 @@
 package cedarConditions
 
 import (
-	"errors"
 	"fmt"
+	"net"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/cedar-policy/cedar-go/types"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
 	hexaParser "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	hexaTypes "github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
 )
 
 type CedarConditionMapper struct {
@@ -207,6 +209,8 @@ func (mapper *CedarConditionMapper) mapFilterExpression(node hexaParser.Expressi
 
 	case hexaParser.AttributeExpression:
 		return mapper.mapFilterAttrExpr(&element)
+	case hexaParser.ValuePathExpression:
+		return mapper.mapFilterValuePath(&element)
 	default:
 		attrExpression := node.(*hexaParser.AttributeExpression)
 		return mapper.mapFilterAttrExpr(attrExpression)
@@ -281,10 +285,27 @@ func removeQuotes(val string) string {
 	return val
 }
 
+// likeLiteral escapes a string value for use in a like pattern
+func likeLiteral(val string) string {
+	quoted := strconv.Quote(removeQuotes(val))
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], "*", "\\*")
+}
+
+// mapCompareValue maps an IDQL entity literal (e.g. PhotoApp:Account:"stacey") to a Cedar entity uid. Values already in
+// Cedar form (e.g. PhotoApp::Account::"stacey") are left as is.
+func mapCompareValue(value hexaTypes.Value) string {
+	entity, ok := value.(hexaTypes.Entity)
+	if !ok || entity.Type != hexaTypes.RelTypeEquals || len(entity.Types) == 0 || entity.Id == nil || entity.IsPath() ||
+		slices.Contains(entity.Types, "") {
+		return value.String()
+	}
+	return fmt.Sprintf("%s::%s", strings.Join(entity.Types, "::"), *entity.Id)
+}
+
 func (mapper *CedarConditionMapper) mapFilterAttrExpr(attrExpr *hexaParser.AttributeExpression) string {
 	compareValue := ""
 	if attrExpr.CompareValue != nil {
-		compareValue = attrExpr.CompareValue.String()
+		compareValue = mapCompareValue(attrExpr.CompareValue)
 	}
 
 	mapPath := mapper.NameMapper.GetProviderAttributeName(attrExpr.AttributePath.String())
@@ -294,7 +315,7 @@ func (mapper *CedarConditionMapper) mapFilterAttrExpr(attrExpr *hexaParser.Attri
 	decimalVal, err := types.ParseDecimal(compareValue)
 	if err == nil {
 		isDecimal = true
-		format = "%s.%s(%s)"
+		format = "%s.%s(decimal(\"%s\"))"
 	}
 
 	switch attrExpr.Operator {
@@ -303,28 +324,28 @@ func (mapper *CedarConditionMapper) mapFilterAttrExpr(attrExpr *hexaParser.Attri
 		return mapPath + " != " + compareValue
 	case hexaParser.LT:
 		if isDecimal {
-			return fmt.Sprintf(format, mapPath, "lessThan", decimalVal.String())
+			return fmt.Sprintf(format, mapPath, "lessThan", decimalVal)
 		}
 		return fmt.Sprintf(format, mapPath, "<", compareValue)
 	case hexaParser.LE:
 		if isDecimal {
-			return fmt.Sprintf(format, mapPath, "lessThanOrEqual", decimalVal.String())
+			return fmt.Sprintf(format, mapPath, "lessThanOrEqual", decimalVal)
 		}
 		return fmt.Sprintf(format, mapPath, "<=", compareValue)
 	case hexaParser.GT:
 		if isDecimal {
-			return fmt.Sprintf(format, mapPath, "greaterThan", decimalVal.String())
+			return fmt.Sprintf(format, mapPath, "greaterThan", decimalVal)
 		}
 		return fmt.Sprintf(format, mapPath, ">", compareValue)
 	case hexaParser.GE:
 		if isDecimal {
-			return fmt.Sprintf(format, mapPath, "greaterThanOrEqual", decimalVal.String())
+			return fmt.Sprintf(format, mapPath, "greaterThanOrEqual", decimalVal)
 		}
 		return fmt.Sprintf(format, mapPath, ">=", compareValue)
 	case hexaParser.SW:
-		return fmt.Sprintf("%s like \"%s*\"", mapPath, removeQuotes(compareValue))
+		return fmt.Sprintf("%s like \"%s*\"", mapPath, likeLiteral(compareValue))
 	case hexaParser.EW:
-		return fmt.Sprintf("%s like \"*%s\"", mapPath, removeQuotes(compareValue))
+		return fmt.Sprintf("%s like \"*%s\"", mapPath, likeLiteral(compareValue))
 	case hexaParser.PR:
 		lastIndex := strings.LastIndex(mapPath, ".")
 		left := mapPath[0:lastIndex]
@@ -333,6 +354,9 @@ func (mapper *CedarConditionMapper) mapFilterAttrExpr(attrExpr *hexaParser.Attri
 	case hexaParser.CO:
 		return mapPath + ".contains(" + compareValue + ")"
 	case hexaParser.IN:
+		if _, _, err := net.ParseCIDR(removeQuotes(compareValue)); err == nil {
+			return fmt.Sprintf("%s.isInRange(ip(%s))", mapPath, compareValue)
+		}
 		return mapPath + " in " + compareValue
 	default:
 		return mapPath + " == " + compareValue
@@ -357,9 +381,54 @@ func checkCompatibility(e hexaParser.Expression) error {
 	case hexaParser.PrecedenceExpression:
 		return checkCompatibility(v.Expression)
 	case hexaParser.ValuePathExpression:
-		return errors.New("IDQL ValuePath expression mapping to Google CEL currently not supported")
+		_, err = valuePathValues(v)
+		return err
 	case hexaParser.AttributeExpression:
 		return nil
 	}
 	return nil
 }
+
+/*
+valuePathValues returns the Cedar values matched by a value path over a set of simple values where the filter compares
+the element `value` (e.g. roles[value eq "admin" or value eq "editor"]). Cedar has no quantifier over set elements and
+compares records for equality, so other value path filters (e.g. emails[type eq "work"]) cannot be mapped.
+*/
+func valuePathValues(vpe hexaParser.ValuePathExpression) ([]string, error) {
+	unsupported := fmt.Errorf("IDQL value path %s cannot be mapped to Cedar (only value eq comparisons on a Set, e.g. roles[value eq \"admin\"], are supported)", vpe.String())
+	if vpe.SubAttr != nil || (vpe.Operator != nil && *vpe.Operator != hexaParser.PR) {
+		return nil, unsupported
+	}
+
+	var values []string
+	var collect func(filter hexaParser.Expression) bool
+	collect = func(filter hexaParser.Expression) bool {
+		switch exp := filter.(type) {
+		case hexaParser.PrecedenceExpression:
+			return collect(exp.Expression)
+		case hexaParser.LogicalExpression:
+			return exp.Operator == hexaParser.OR && collect(exp.Left) && collect(exp.Right)
+		case hexaParser.AttributeExpression:
+			if exp.Operator != hexaParser.EQ || !strings.EqualFold(exp.AttributePath.String(), "value") || exp.CompareValue == nil {
+				return false
+			}
+			values = append(values, mapCompareValue(exp.CompareValue))
+			return true
+		}
+		return false
+	}
+	if !collect(vpe.VPathFilter) {
+		return nil, unsupported
+	}
+	return values, nil
+}
+
+// mapFilterValuePath maps a value path to the Cedar set contains (one value) or containsAny (several values) operators
+func (mapper *CedarConditionMapper) mapFilterValuePath(vpe *hexaParser.ValuePathExpression) string {
+	values, _ := valuePathValues(*vpe) // compatibility is checked before mapping
+	mapPath := mapper.NameMapper.GetProviderAttributeName(vpe.Attribute.String())
+	if len(values) == 1 {
+		return fmt.Sprintf("%s.contains(%s)", mapPath, values[0])
+	}
+	return fmt.Sprintf("%s.containsAny([%s])", mapPath, strings.Join(values, ", "))
+}
@@ -157,8 +157,73 @@ when { resource.owner != "somebody" }
 				Action: conditions.AAllow,
 			}, false},
 
+		{"Equals using like", "when { principal.name like \"test\" }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.name eq \"test\"",
+				Action: conditions.AAllow,
+			}, false},
+		{"Like escaped wildcard", "when { principal.name like \"te\\*st*\" }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.name sw \"te*st\"",
+				Action: conditions.AAllow,
+			}, false},
+		{"If then", "when { if principal.numberOfLaptops < 5 then principal.jobLevel > 6 else false }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.numberOfLaptops lt 5 and principal.jobLevel gt 6",
+				Action: conditions.AAllow,
+			}, false},
+		{"If then else", "when { if principal.numberOfLaptops < 5 then principal.jobLevel > 6 else principal.jobLevel > 8 }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.numberOfLaptops lt 5 and principal.jobLevel gt 6 or not (principal.numberOfLaptops lt 5) and principal.jobLevel gt 8",
+				Action: conditions.AAllow,
+			}, false},
+		{"If then true", "when { principal.id > 4 && (if principal.id == \"1\" then true else principal.id == \"2\") }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.id gt 4 and (principal.id eq \"1\" or principal.id eq \"2\")",
+				Action: conditions.AAllow,
+			}, false},
+		{"Expression if", "when { principal.id > 4 && (if principal.id == \"1\" then true else false) }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.id gt 4 and principal.id eq \"1\"",
+				Action: conditions.AAllow,
+			}, false},
+		{"ContainsAll", "when { principal.name.containsAll([\"a\",\"b\"]) }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.name co \"a\" and principal.name co \"b\"",
+				Action: conditions.AAllow,
+			}, false},
+		{"ContainsAny", "when { principal.id > 4 && principal.name.containsAny([\"a\",\"b\"]) }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.id gt 4 and (principal.name co \"a\" or principal.name co \"b\")",
+				Action: conditions.AAllow,
+			}, false},
+		{"IP range", "when { context.ip.isInRange(ip(\"10.0.0.0/8\")) }",
+			&conditions.ConditionInfo{
+				Rule:   "context.ip in \"10.0.0.0/8\"",
+				Action: conditions.AAllow,
+			}, false},
+		{"IP equals", "when { context.ip == ip(\"10.1.2.3\") }",
+			&conditions.ConditionInfo{
+				Rule:   "context.ip eq \"10.1.2.3\"",
+				Action: conditions.AAllow,
+			}, false},
+		{"Decimal", "when { principal.level.lessThan(decimal(\"1.5\")) }",
+			&conditions.ConditionInfo{
+				Rule:   "principal.level lt 1.5",
+				Action: conditions.AAllow,
+			}, false},
+		{"Datetime", "when { context.now < datetime(\"2024-10-01\") }",
+			&conditions.ConditionInfo{
+				Rule:   "context.now lt 2024-10-01T00:00:00Z",
+				Action: conditions.AAllow,
+			}, false},
+		{"Boolean attribute", "when { context.authenticated }",
+			&conditions.ConditionInfo{
+				Rule:   "context.authenticated eq true",
+				Action: conditions.AAllow,
+			}, false},
+
 		// negative tests
-		{"If then error", "when { if principal.numberOfLaptops < 5 then principal.jobLevel > 6 else false }", nil, true},
 		{"Starts with error", "when { resource like \"Todo*::New*\" }", &conditions.ConditionInfo{
 			Rule:   "resource sw \"Todo::New\"",
 			Action: conditions.AAllow,
@@ -169,14 +234,10 @@ when { resource.owner != "somebody" }
 		}, true},
 		{"primary-if-test-error", "when {\n (if principal has name then principal.name else \"Joe\") == \"Alice\"\n}",
 			&conditions.ConditionInfo{}, true},
-		{"expression-if-error", "when { principal.id > 4 && (if principal.id == \"1\" then true else false) }",
-			&conditions.ConditionInfo{}, true},
 		{"recinit-error", "when { {\"key\": \"some value\", id: \"another value\"} }", &conditions.ConditionInfo{}, true},
-		{"ContainsAll-error", "when { principal.name.containsAll([\"a\",\"b\"]) }",
-			&conditions.ConditionInfo{
-				Rule:   "principal.name co \"smith\"",
-				Action: conditions.AAllow,
-			}, true},
+		{"IP function error", "when { context.ip.isLoopback() }", nil, true},
+		{"Duration error", "when { context.now < datetime(\"2024-10-01\").offset(duration(\"1h\")) }", nil, true},
+		{"Multi clause error", "when { context.ip.isLoopback() }\nwhen { principal.id > 4 }", nil, true},
 		{"Negate test", "when { - ( 3 + 1) }",
 			nil, true},
 		{"Calculation test", "when { ( 3 + 4 * 2 )}", nil, true},
@@ -238,11 +299,11 @@ func TestMapHexa(t *testing.T) {
 		{"Precedence", "(username eq \"bjensen\")", "when { username == \"bjensen\" }"},
 		{"Spacing", "userName   eq  \"bjensen\"", "when { userName == \"bjensen\" }"},
 		{"GreaterThan number", "account.level gt 4", "when { account.level > 4 }"},
-		{"GreaterThan decimal", "account.level gt 4.5", "when { account.level.greaterThan(4.5) }"},
-		{"GreaterThanEqual decimal", "account.level ge 4.5", "when { account.level.greaterThanOrEqual(4.5) }"},
+		{"GreaterThan decimal", "account.level gt 4.5", "when { account.level.greaterThan(decimal(\"4.5\")) }"},
+		{"GreaterThanEqual decimal", "account.level ge 4.5", "when { account.level.greaterThanOrEqual(decimal(\"4.5\")) }"},
 		{"GT Test", "level gt 12", "when { level > 12 }"},
-		{"LessThan Dec", "level lt 12.3", "when { level.lessThan(12.3) }"},
-		{"LessThanEqual Dec", "level le 12.3", "when { level.lessThanOrEqual(12.3) }"},
+		{"LessThan Dec", "level lt 12.3", "when { level.lessThan(decimal(\"12.3\")) }"},
+		{"LessThanEqual Dec", "level le 12.3", "when { level.lessThanOrEqual(decimal(\"12.3\")) }"},
 		{"LessThan int", "level lt 12", "when { level < 12 }"},
 		{"LessThanEqual int", "level le 12", "when { level <= 12 }"},
 
@@ -251,11 +312,15 @@ func TestMapHexa(t *testing.T) {
 		{"Operator case test", "userName Eq \"bjensen\"", "when { userName == \"bjensen\" }"},
 		{"Deep Ors", "((userName eq \"A\") or (username eq \"B\")) or username eq \"C\"", "when { (userName == \"A\" || username == \"B\") || username == \"C\" }"},
 		{"Starts with", "userName sw \"J\"", "when { userName like \"J*\" }"},
+		{"Starts with wildcard", "userName sw \"J*\"", "when { userName like \"J\\**\" }"},
+		{"In entity", "resource in PhotoApp:Account:\"stacey\"", "when { resource in PhotoApp::Account::\"stacey\" }"},
+		{"In CIDR", "context.ip in \"10.0.0.0/8\"", "when { context.ip.isInRange(ip(\"10.0.0.0/8\")) }"},
 		{"Long Urn", "urn:ietf:params:scim:schemas:core:2.0:User:userName sw \"J\"", "when { urn:ietf:params:scim:schemas:core:2.0:User:userName like \"J*\" }"},
 
 		{"Nested precedence or", "userType eq \"Employee\" and (emails co \"example.com\" or emails.value co \"example.org\")", "when { userType == \"Employee\" }\nwhen { emails.contains(\"example.com\") || emails.value.contains(\"example.org\") }"},
 		{"Nested Not or", "userType eq \"Employee\" and not(emails co \"example.com\" or emails.value co \"example.org\")", "when { userType == \"Employee\" }\nunless { emails.contains(\"example.com\") || emails.value.contains(\"example.org\") }"},
-		// {"emails[type eq \"work\" and value co \"@example.com\"] or ims[type eq \"xmpp\" and value co \"@foo.com\"]"},
+		{"Value path", "principal.roles[value eq \"admin\"]", "when { principal.roles.contains(\"admin\") }"},
+		{"Value path any", "principal.roles[value eq \"admin\" or value eq \"editor\"] and level gt 2", "when { principal.roles.containsAny([\"admin\", \"editor\"]) }\nwhen { level > 2 }"},
 		{"NotExpression", "not(principal.name eq \"gerry\")", "unless { principal.name == \"gerry\" }"},
 		{"Or NotExpression", "principal.title pr or not(principal.name eq \"gerry\")", "when { principal has title || !( principal.name == \"gerry\" ) }"},
 		{"Or Not LogicalExpression", "principal.title pr or not(principal.name eq \"phil\" or principal.name eq \"gerry\")", "when { principal has title || !( principal.name == \"phil\" || principal.name == \"gerry\" ) }"},
@@ -272,7 +337,6 @@ func TestMapHexa(t *testing.T) {
 			"not ( principal.name eq \"Smith\" )",
 			"unless { principal.name == \"Smith\" }",
 		},
-		// {"emails[type eq work and value ew \"h[exa].org\"]", "emails[type eq \"work\" and value ew \"h[exa].org\"]"},
 	}
 
 	for _, example := range examples {
@@ -310,3 +374,16 @@ func testutilOK(t testing.TB, err error) {
 	}
 	t.Fatalf("got %v want nil", err)
 }
+
+func TestMapHexa_ValuePathUnsupported(t *testing.T) {
+	for _, rule := range []string{
+		"principal.emails[type eq \"work\" and value ew \"example.com\"]",
+		"principal.emails[type eq \"work\"].value eq \"alice@example.com\"",
+		"level gt 2 or principal.roles[value sw \"adm\"]",
+	} {
+		_, err := doMapHexa(rule)
+		if err == nil || !strings.Contains(err.Error(), "cannot be mapped to Cedar") {
+			t.Fatalf("expected value path error for %s, got %v", rule, err)
+		}
+	}
+}
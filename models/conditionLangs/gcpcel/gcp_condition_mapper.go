@@ -2,13 +2,29 @@ package gcpcel
 
 /*
  Condition mapTool for Google IAM - See: https://cloud.google.com/iam/docs/conditions-overview
+
+ The following CEL forms are mapped to and from IDQL:
+   - comparisons (==, !=, <, <=, >, >=) of attributes with constants, lists, other attributes and timestamps.
+     Timestamps may be offset by a duration (e.g. timestamp("2024-01-01T00:00:00Z") + duration("3600s")).
+   - has(a.b) is pr, a in [...] is in, "x" in a.list is co
+   - a.startsWith(), a.endsWith() and a.contains() are sw, ew and co
+   - a.matches() is mapped to eq, sw, ew or co when the regular expression is a literal that is optionally anchored
+   - attribute paths may use field selection or string indexes (e.g. request.auth.claims["email"])
+   - value paths are the exists() macro, e.g. emails[type eq "work"].value ew "@example.com" is
+     emails.exists(v, v.type == "work" && v.value.endsWith("@example.com"))
+ Anything else (e.g. size(), request.time.getHours(), resource.name.extract()) is reported as a CompatibilityIssue.
 */
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"regexp/syntax"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
@@ -17,10 +33,43 @@ import (
 
 var (
 	env, _ = cel.NewEnv()
+
+	attributeNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
 )
 
 type GoogleConditionMapper struct {
 	NameMapper *conditions.AttributeMap
+	scope      string // the exists() variable that value path attributes are relative to
+}
+
+// valuePathVariable is the exists() macro variable used when mapping IDQL value paths
+const valuePathVariable = "v"
+
+// CompatibilityIssue describes part of a condition that cannot be mapped
+type CompatibilityIssue struct {
+	Expression string `json:"expression"`
+	Reason     string `json:"reason"`
+}
+
+// CompatibilityReport lists the parts of a condition that cannot be mapped between IDQL and Google CEL
+type CompatibilityReport struct {
+	Issues []CompatibilityIssue `json:"issues,omitempty"`
+}
+
+func (r *CompatibilityReport) IsCompatible() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *CompatibilityReport) Error() string {
+	msgs := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		msgs[i] = fmt.Sprintf("%s (%s)", issue.Reason, issue.Expression)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (r *CompatibilityReport) add(expression string, err error) {
+	r.Issues = append(r.Issues, CompatibilityIssue{Expression: expression, Reason: err.Error()})
 }
 
 func (mapper *GoogleConditionMapper) MapConditionToProvider(condition conditions.ConditionInfo) (string, error) {
@@ -34,13 +83,24 @@ func (mapper *GoogleConditionMapper) MapConditionToProvider(condition conditions
 }
 
 func (mapper *GoogleConditionMapper) MapFilter(ast parser.Expression) (string, error) {
-	err := checkCompatibility(ast)
-	if err != nil {
-		return "", err
+	report := mapper.checkCompatibility(ast)
+	if !report.IsCompatible() {
+		return "", &report
 	}
 	return mapper.mapFilterInternal(ast, false), nil
 }
 
+// CheckConditionCompatibility reports the parts of an IDQL condition that cannot be mapped to Google CEL
+func (mapper *GoogleConditionMapper) CheckConditionCompatibility(condition conditions.ConditionInfo) CompatibilityReport {
+	ast, err := conditions.ParseConditionRuleAst(condition)
+	if err != nil {
+		report := CompatibilityReport{}
+		report.add(condition.Rule, err)
+		return report
+	}
+	return mapper.checkCompatibility(ast)
+}
+
 func (mapper *GoogleConditionMapper) mapFilterInternal(ast parser.Expression, isChild bool) string {
 
 	switch element := ast.(type) {
@@ -52,11 +112,42 @@ func (mapper *GoogleConditionMapper) mapFilterInternal(ast parser.Expression, is
 	case parser.LogicalExpression:
 		return mapper.mapFilterLogical(element, isChild)
 
+	case parser.ValuePathExpression:
+		return mapper.mapFilterValuePath(element)
+
 	default:
 		attrExpression := ast.(parser.AttributeExpression)
 		return mapper.mapFilterAttrExpr(attrExpression)
 	}
-	// return mapTool.mapFilterValuePath(deref.(idqlCondition.ValuePathExpression))
+}
+
+// valuePathMapper returns a mapper for the filter of a value path where attributes are relative to the exists() variable
+func (mapper *GoogleConditionMapper) valuePathMapper() *GoogleConditionMapper {
+	return &GoogleConditionMapper{NameMapper: mapper.NameMapper, scope: valuePathVariable}
+}
+
+// mapFilterValuePath maps a value path to the CEL exists() macro. A sub-attribute comparison is added to the predicate.
+func (mapper *GoogleConditionMapper) mapFilterValuePath(vpe parser.ValuePathExpression) string {
+	scoped := mapper.valuePathMapper()
+	hasSubCompare := vpe.SubAttr != nil && vpe.Operator != nil
+	predicate := scoped.mapFilterInternal(vpe.VPathFilter, hasSubCompare)
+	if hasSubCompare {
+		subPath, _ := types.ParseValue(*vpe.SubAttr)
+		predicate += " && " + scoped.mapFilterAttrExpr(parser.AttributeExpression{
+			AttributePath: subPath,
+			Operator:      *vpe.Operator,
+			CompareValue:  vpe.CompareValue,
+		})
+	}
+	return fmt.Sprintf("%s.exists(%s, %s)", mapper.providerAttribute(vpe.Attribute.String()), valuePathVariable, predicate)
+}
+
+// providerAttribute maps an IDQL attribute name to CEL. Within a value path, the name is relative to the exists() variable.
+func (mapper *GoogleConditionMapper) providerAttribute(path string) string {
+	if mapper.scope != "" {
+		return mapper.scope + "." + path
+	}
+	return mapper.NameMapper.GetProviderAttributeName(path)
 }
 
 func (mapper *GoogleConditionMapper) mapFilterNot(notFilter parser.NotExpression, _ bool) string {
@@ -120,16 +211,10 @@ func (mapper *GoogleConditionMapper) mapFilterLogical(logicFilter parser.Logical
 func (mapper *GoogleConditionMapper) mapFilterAttrExpr(attrExpr parser.AttributeExpression) string {
 	compareValue := ""
 	if attrExpr.CompareValue != nil {
-		switch attrExpr.CompareValue.(type) {
-		case types.Date:
-			// GCP dates need to be quoted
-			compareValue = fmt.Sprintf("timestamp('%s')", attrExpr.CompareValue.String())
-		default:
-			compareValue = attrExpr.CompareValue.String()
-		}
+		compareValue = mapper.mapCompareValue(attrExpr.CompareValue)
 	}
 
-	mapPath := mapper.NameMapper.GetProviderAttributeName(attrExpr.AttributePath.String())
+	mapPath := mapper.providerAttribute(attrExpr.AttributePath.String())
 
 	switch attrExpr.Operator {
 
@@ -159,6 +244,26 @@ func (mapper *GoogleConditionMapper) mapFilterAttrExpr(attrExpr parser.Attribute
 
 }
 
+func (mapper *GoogleConditionMapper) mapCompareValue(value types.Value) string {
+	switch v := value.(type) {
+	case types.Date:
+		// GCP dates need to be quoted
+		return fmt.Sprintf("timestamp('%s')", v.String())
+	case types.Array:
+		items := v.Value().([]types.ComparableValue)
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = mapper.mapCompareValue(item)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case types.Entity:
+		if v.IsPath() {
+			return mapper.providerAttribute(v.String())
+		}
+	}
+	return value.String()
+}
+
 func (mapper *GoogleConditionMapper) MapProviderToCondition(expression string) (conditions.ConditionInfo, error) {
 
 	celAst, issues := env.Parse(expression)
@@ -186,6 +291,52 @@ func (mapper *GoogleConditionMapper) MapProviderToCondition(expression string) (
 	}, nil
 }
 
+// CheckProviderCompatibility reports each part of a Google CEL expression that cannot be mapped to IDQL
+func (mapper *GoogleConditionMapper) CheckProviderCompatibility(expression string) CompatibilityReport {
+	report := CompatibilityReport{}
+	celAst, issues := env.Parse(expression)
+	if issues != nil {
+		report.add(expression, errors.New("CEL Mapping Error: "+issues.String()))
+		return report
+	}
+	parsedAst, err := cel.AstToParsedExpr(celAst)
+	if err != nil {
+		report.add(expression, err)
+		return report
+	}
+	mapper.checkCelExpr(parsedAst.GetExpr(), &report)
+	return report
+}
+
+func (mapper *GoogleConditionMapper) checkCelExpr(expression *expr.Expr, report *CompatibilityReport) {
+	call := expression.GetCallExpr()
+	if call != nil {
+		switch call.GetFunction() {
+		case "_&&_", "_||_", "_!_", "!_":
+			for _, arg := range call.GetArgs() {
+				mapper.checkCelExpr(arg, report)
+			}
+			return
+		}
+	}
+	if _, err := mapper.mapCelExpr(expression, false); err != nil {
+		report.add(celString(expression), err)
+	}
+}
+
+// celString returns the CEL source of a (sub)expression for reporting
+func celString(expression *expr.Expr) string {
+	native, err := ast.ProtoToExpr(expression)
+	if err == nil {
+		var source string
+		source, err = cel.ExprToString(native, ast.NewSourceInfo(nil))
+		if err == nil {
+			return source
+		}
+	}
+	return expression.String()
+}
+
 func (mapper *GoogleConditionMapper) mapCelExpr(expression *expr.Expr, isChild bool) (parser.Expression, error) {
 
 	cexpr := expression.GetCallExpr()
@@ -198,27 +349,26 @@ func (mapper *GoogleConditionMapper) mapCelExpr(expression *expr.Expr, isChild b
 	switch v := kind.(type) {
 	case *expr.Expr_SelectExpr:
 		return mapper.mapSelectExpr(v)
-	// case *expr.Expr_ComprehensionExpr:
-	//	return nil, errors.New("unimplemented CEL 'comprehension expression' not implemented. ")
+	case *expr.Expr_IdentExpr:
+		return mapper.mapBooleanAttribute(expression)
+	case *expr.Expr_ComprehensionExpr:
+		return mapper.mapCelExists(v.ComprehensionExpr)
 	default:
 		return nil, fmt.Errorf("unimplemented CEL expression: %s", expression.String())
 	}
 }
 
 func (mapper *GoogleConditionMapper) mapSelectExpr(selection *expr.Expr_SelectExpr) (parser.Expression, error) {
-	field := selection.SelectExpr.GetField()
-	/*
-		if !selection.SelectExpr.GetTestOnly() {
-			return nil, errors.New("unimplemented Google CEL Select Expression: " + selection.SelectExpr.String())
-		}
-	*/
-
-	ident := selection.SelectExpr.GetOperand().GetIdentExpr()
+	if !selection.SelectExpr.GetTestOnly() {
+		return mapper.mapBooleanAttribute(&expr.Expr{ExprKind: selection})
+	}
 
-	name := ident.GetName()
-	attr := name + "." + field
-	path := mapper.NameMapper.GetHexaFilterAttributePath(attr)
-	lhv, err := types.ParseValue(path)
+	// has(a.b) macro
+	operand, err := mapper.celAttributePath(selection.SelectExpr.GetOperand())
+	if err != nil {
+		return nil, err
+	}
+	lhv, err := mapper.hexaAttribute(operand + "." + selection.SelectExpr.GetField())
 	if err != nil {
 		return nil, err
 	}
@@ -228,6 +378,130 @@ func (mapper *GoogleConditionMapper) mapSelectExpr(selection *expr.Expr_SelectEx
 	}, nil
 }
 
+// mapBooleanAttribute maps an attribute used as a boolean (e.g. request.auth.verified) to an eq true comparison
+func (mapper *GoogleConditionMapper) mapBooleanAttribute(expression *expr.Expr) (parser.Expression, error) {
+	path, err := mapper.celAttributePath(expression)
+	if err != nil {
+		return nil, err
+	}
+	lhv, err := mapper.hexaAttribute(path)
+	if err != nil {
+		return nil, err
+	}
+	return parser.AttributeExpression{
+		AttributePath: lhv,
+		Operator:      parser.EQ,
+		CompareValue:  types.NewBoolean("true"),
+	}, nil
+}
+
+// celAttributePath returns the dotted attribute path of an identifier, field selection or string index expression
+func (mapper *GoogleConditionMapper) celAttributePath(expression *expr.Expr) (string, error) {
+	switch v := expression.GetExprKind().(type) {
+	case *expr.Expr_IdentExpr:
+		return v.IdentExpr.GetName(), nil
+	case *expr.Expr_SelectExpr:
+		if !v.SelectExpr.GetTestOnly() {
+			operand, err := mapper.celAttributePath(v.SelectExpr.GetOperand())
+			if err != nil {
+				return "", err
+			}
+			return operand + "." + v.SelectExpr.GetField(), nil
+		}
+	case *expr.Expr_CallExpr:
+		args := v.CallExpr.GetArgs()
+		if v.CallExpr.GetFunction() == "_[_]" && len(args) == 2 {
+			key := args[1].GetConstExpr().GetStringValue()
+			if !attributeNameRegex.MatchString(key) {
+				return "", fmt.Errorf("unsupported attribute index: %s", celString(args[1]))
+			}
+			operand, err := mapper.celAttributePath(args[0])
+			if err != nil {
+				return "", err
+			}
+			return operand + "." + key, nil
+		}
+		if v.CallExpr.GetFunction() != "" {
+			return "", fmt.Errorf("unimplemented CEL function: %s", v.CallExpr.GetFunction())
+		}
+	}
+	return "", fmt.Errorf("unsupported CEL attribute: %s", celString(expression))
+}
+
+func (mapper *GoogleConditionMapper) hexaAttribute(celPath string) (types.Value, error) {
+	if mapper.scope != "" {
+		// IDQL value path filter attributes are relative to the element
+		if !strings.HasPrefix(celPath, mapper.scope+".") {
+			return nil, fmt.Errorf("unsupported attribute %s in exists() (attributes must be relative to %s)", celPath, mapper.scope)
+		}
+		return types.ParseValue(strings.TrimPrefix(celPath, mapper.scope+"."))
+	}
+	return types.ParseValue(mapper.NameMapper.GetHexaFilterAttributePath(celPath))
+}
+
+/*
+mapCelExists maps the exists() macro (e.g. emails.exists(e, e.type == "work")) to an IDQL value path. CEL parses
+macros as comprehensions; only exists() is supported (its loop step is accumulator || predicate).
+*/
+func (mapper *GoogleConditionMapper) mapCelExists(comprehension *expr.Expr_Comprehension) (parser.Expression, error) {
+	step := comprehension.GetLoopStep().GetCallExpr()
+	accumulator := comprehension.GetAccuVar()
+	isExists := step.GetFunction() == "_||_" && len(step.GetArgs()) == 2 &&
+		step.GetArgs()[0].GetIdentExpr().GetName() == accumulator &&
+		comprehension.GetResult().GetIdentExpr().GetName() == accumulator &&
+		!comprehension.GetAccuInit().GetConstExpr().GetBoolValue()
+	if !isExists || comprehension.GetIterVar2() != "" {
+		return nil, errors.New("unimplemented CEL macro: only exists() is supported")
+	}
+
+	path, err := mapper.celAttributePath(comprehension.GetIterRange())
+	if err != nil {
+		return nil, err
+	}
+	scoped := &GoogleConditionMapper{NameMapper: mapper.NameMapper, scope: comprehension.GetIterVar()}
+	filter, err := scoped.mapCelExpr(step.GetArgs()[1], false)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkValuePathFilter(filter); err != nil {
+		return nil, err
+	}
+	return parser.ValuePathExpression{
+		Attribute:   *types.ParseEntity(mapper.NameMapper.GetHexaFilterAttributePath(path)),
+		VPathFilter: filter,
+	}, nil
+}
+
+// checkValuePathFilter returns an error if a value path filter contains a nested value path, which IDQL does not support
+func checkValuePathFilter(filter parser.Expression) error {
+	switch v := filter.(type) {
+	case parser.LogicalExpression:
+		if err := checkValuePathFilter(v.Left); err != nil {
+			return err
+		}
+		return checkValuePathFilter(v.Right)
+	case parser.NotExpression:
+		return checkValuePathFilter(v.Expression)
+	case parser.PrecedenceExpression:
+		return checkValuePathFilter(v.Expression)
+	case parser.ValuePathExpression:
+		return fmt.Errorf("nested exists() is not supported: %s", v.String())
+	}
+	return nil
+}
+
+func isCelAttribute(expression *expr.Expr) bool {
+	switch v := expression.GetExprKind().(type) {
+	case *expr.Expr_IdentExpr:
+		return true
+	case *expr.Expr_SelectExpr:
+		return !v.SelectExpr.GetTestOnly()
+	case *expr.Expr_CallExpr:
+		return v.CallExpr.GetFunction() == "_[_]"
+	}
+	return false
+}
+
 func (mapper *GoogleConditionMapper) mapCallExpr(expression *expr.Expr_Call, isChild bool) (parser.Expression, error) {
 	operand := expression.GetFunction()
 	switch operand {
@@ -236,7 +510,7 @@ func (mapper *GoogleConditionMapper) mapCallExpr(expression *expr.Expr_Call, isC
 	case "_||_":
 		return mapper.mapCelLogical(expression.Args, false, isChild)
 	case "_!_", "!_":
-		return mapper.mapCelNot(expression.Args, isChild), nil // was false
+		return mapper.mapCelNot(expression.Args, isChild)
 	case "_==_":
 		return mapper.mapCelAttrCompare(expression.Args, parser.EQ)
 	case "_!=_":
@@ -250,9 +524,11 @@ func (mapper *GoogleConditionMapper) mapCallExpr(expression *expr.Expr_Call, isC
 	case "_>=_":
 		return mapper.mapCelAttrCompare(expression.Args, parser.GE)
 	case "@in":
-		return mapper.mapCelAttrCompare(expression.Args, parser.IN)
+		return mapper.mapCelIn(expression.Args)
+	case "_[_]":
+		return mapper.mapBooleanAttribute(&expr.Expr{ExprKind: &expr.Expr_CallExpr{CallExpr: expression}})
 
-	case "startsWith", "endsWith", "contains", "has":
+	case "startsWith", "endsWith", "contains", "matches":
 		return mapper.mapCelAttrFunction(expression)
 
 	}
@@ -262,52 +538,98 @@ func (mapper *GoogleConditionMapper) mapCallExpr(expression *expr.Expr_Call, isC
 
 func (mapper *GoogleConditionMapper) mapCelAttrFunction(expression *expr.Expr_Call) (parser.Expression, error) {
 	target := expression.GetTarget()
-	selection := target.GetSelectExpr()
-	operand := selection.GetOperand()
-	// subattr := selection.Field
-	name := operand.GetIdentExpr().GetName()
-	var path string
-	if name == "" {
-		name = target.GetIdentExpr().GetName()
-		path = mapper.NameMapper.GetHexaFilterAttributePath(name)
-
-	} else {
-		subattr := selection.GetField()
-		path = mapper.NameMapper.GetHexaFilterAttributePath(name + "." + subattr)
+	args := expression.GetArgs()
+	if target == nil && len(args) == 2 {
+		// global overload e.g. matches(a, "^abc")
+		target = args[0]
+		args = args[1:]
+	}
+	if target == nil || len(args) != 1 {
+		return nil, fmt.Errorf("unsupported CEL function call: %s", expression.GetFunction())
 	}
 
-	lhv, err := types.ParseValue(path)
+	path, err := mapper.celAttributePath(target)
 	if err != nil {
 		return nil, err
 	}
-
-	rh := strconv.Quote(expression.GetArgs()[0].GetConstExpr().GetStringValue())
-	rhv, err := types.ParseValue(rh)
+	lhv, err := mapper.hexaAttribute(path)
 	if err != nil {
 		return nil, err
 	}
+
+	var operator parser.CompareOperator
 	switch expression.GetFunction() {
 	case "startsWith":
-		return parser.AttributeExpression{
-			AttributePath: lhv,
-			Operator:      parser.SW,
-			CompareValue:  rhv,
-		}, nil
+		operator = parser.SW
 	case "endsWith":
-		return parser.AttributeExpression{
-			AttributePath: lhv,
-			Operator:      parser.EW,
-			CompareValue:  rhv,
-		}, nil
+		operator = parser.EW
 	case "contains":
+		operator = parser.CO
+	default:
+		pattern, isString := args[0].GetConstExpr().GetConstantKind().(*expr.Constant_StringValue)
+		if !isString {
+			return nil, fmt.Errorf("unsupported %s argument: %s", expression.GetFunction(), celString(args[0]))
+		}
+		var value string
+		operator, value, err = regexComparison(pattern.StringValue)
+		if err != nil {
+			return nil, err
+		}
 		return parser.AttributeExpression{
 			AttributePath: lhv,
-			Operator:      parser.CO,
-			CompareValue:  rhv,
+			Operator:      operator,
+			CompareValue:  types.NewString(strconv.Quote(value)),
 		}, nil
 	}
-	return nil, errors.New(fmt.Sprintf("unimplemented CEL function:%s", expression.GetFunction()))
 
+	rhv, err := mapper.mapCelValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return parser.AttributeExpression{
+		AttributePath: lhv,
+		Operator:      operator,
+		CompareValue:  rhv,
+	}, nil
+}
+
+/*
+regexComparison maps a regular expression that is a literal, optionally anchored with ^ and/or $, to an IDQL
+comparison (eq when anchored at both ends, sw, ew, or co).
+*/
+func regexComparison(pattern string) (parser.CompareOperator, string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid regular expression %s: %s", pattern, err.Error())
+	}
+	re = re.Simplify()
+
+	parts := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		parts = re.Sub
+	}
+	anchorStart, anchorEnd := false, false
+	if len(parts) > 0 && parts[0].Op == syntax.OpBeginText {
+		anchorStart = true
+		parts = parts[1:]
+	}
+	if len(parts) > 0 && parts[len(parts)-1].Op == syntax.OpEndText {
+		anchorEnd = true
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) != 1 || parts[0].Op != syntax.OpLiteral || parts[0].Flags&syntax.FoldCase != 0 {
+		return "", "", fmt.Errorf("regular expression %s cannot be mapped to an IDQL comparison", pattern)
+	}
+	literal := string(parts[0].Rune)
+	switch {
+	case anchorStart && anchorEnd:
+		return parser.EQ, literal, nil
+	case anchorStart:
+		return parser.SW, literal, nil
+	case anchorEnd:
+		return parser.EW, literal, nil
+	}
+	return parser.CO, literal, nil
 }
 
 func convertConstExpr(cexpr *expr.Expr_ConstExpr) (types.Value, error) {
@@ -343,66 +665,127 @@ func convertConstExpr(cexpr *expr.Expr_ConstExpr) (types.Value, error) {
 	return rhv, nil
 }
 
-func (mapper *GoogleConditionMapper) mapCelAttrCompare(expressions []*expr.Expr, operator parser.CompareOperator) (parser.Expression, error) {
-	// target :=
-
-	path := ""
-	isNot := false
-	callExpr := expressions[0].GetCallExpr()
-	lhExpression := expressions[0]
-	if callExpr != nil {
-		switch callExpr.GetFunction() {
-		case "!_":
-			isNot = true
-			break
-		default:
-			msg := fmt.Sprintf("unimplemented CEL function: %s", callExpr.GetFunction())
-			return nil, errors.New(msg)
+// mapCelValue maps the value side of a comparison
+func (mapper *GoogleConditionMapper) mapCelValue(expression *expr.Expr) (types.Value, error) {
+	switch val := expression.GetExprKind().(type) {
+	case *expr.Expr_ConstExpr:
+		return convertConstExpr(val)
+	case *expr.Expr_IdentExpr:
+		return *types.ParseEntity(mapper.NameMapper.GetHexaFilterAttributePath(val.IdentExpr.Name)), nil
+	case *expr.Expr_ListExpr:
+		elements := val.ListExpr.GetElements()
+		values := make([]types.ComparableValue, len(elements))
+		for i, element := range elements {
+			value, err := mapper.mapCelValue(element)
+			if err != nil {
+				return nil, err
+			}
+			comparable, ok := value.(types.ComparableValue)
+			if !ok {
+				return nil, fmt.Errorf("unsupported list value: %s", celString(element))
+			}
+			values[i] = comparable
 		}
-		lhExpression = callExpr.Args[0]
-	}
-	ident := lhExpression.GetIdentExpr()
-	if ident == nil {
-		selectExpr := lhExpression.GetSelectExpr()
-		path = selectExpr.GetOperand().GetIdentExpr().Name + "." + selectExpr.GetField()
-	} else {
-		path = ident.GetName()
+		return types.NewArray(values), nil
 	}
 
-	// map the path name
-	path = mapper.NameMapper.GetHexaFilterAttributePath(path)
-
-	// Map the RH expression
-	kind := expressions[1].GetExprKind()
-	var rhv types.Value
-	var err error
-	switch val := kind.(type) {
-	case *expr.Expr_ConstExpr:
-		rhv, err = convertConstExpr(val)
+	if isCelAttribute(expression) {
+		path, err := mapper.celAttributePath(expression)
 		if err != nil {
 			return nil, err
 		}
+		return mapper.hexaAttribute(path)
+	}
+	if expression.GetCallExpr() != nil {
+		timestamp, err := celTimestamp(expression)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewDate(timestamp.UTC().Format(time.RFC3339))
+	}
+	return nil, fmt.Errorf("unsupported CEL value: %s", celString(expression))
+}
 
-	case *expr.Expr_IdentExpr:
-		rhv = types.ParseEntity(val.IdentExpr.Name)
-	case *expr.Expr_CallExpr:
-		callFunc := val.CallExpr.GetFunction()
-		args := val.CallExpr.GetArgs()
-		switch callFunc {
-		case "timestamp":
-			val := args[0].GetConstExpr().GetStringValue()
-			rhv, err = types.NewDate(val)
+// celTimestamp evaluates timestamp("...") optionally offset by durations (e.g. timestamp("...") + duration("3600s"))
+func celTimestamp(expression *expr.Expr) (time.Time, error) {
+	call := expression.GetCallExpr()
+	args := call.GetArgs()
+	switch call.GetFunction() {
+	case "timestamp":
+		if len(args) == 1 {
+			if value, ok := args[0].GetConstExpr().GetConstantKind().(*expr.Constant_StringValue); ok {
+				return time.Parse(time.RFC3339Nano, value.StringValue)
+			}
+		}
+	case "_+_", "_-_":
+		if len(args) == 2 {
+			timestampArg, durationArg := args[0], args[1]
+			if call.GetFunction() == "_+_" && timestampArg.GetCallExpr().GetFunction() == "duration" {
+				timestampArg, durationArg = durationArg, timestampArg
+			}
+			timestamp, err := celTimestamp(timestampArg)
 			if err != nil {
-				return nil, err
+				return time.Time{}, err
+			}
+			duration, err := celDuration(durationArg)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if call.GetFunction() == "_-_" {
+				duration = -duration
 			}
-		default:
-			msg := fmt.Sprintf("unsupported function %s in %s", callFunc, expressions[1].String())
-			// fmt.Println("Warning: "+msg)
-			return nil, errors.New(msg)
+			return timestamp.Add(duration), nil
+		}
+	case "":
+		return time.Time{}, fmt.Errorf("unsupported CEL value: %s", celString(expression))
+	}
+	return time.Time{}, fmt.Errorf("unsupported function %s in %s", call.GetFunction(), celString(expression))
+}
+
+func celDuration(expression *expr.Expr) (time.Duration, error) {
+	call := expression.GetCallExpr()
+	if call.GetFunction() == "duration" && len(call.GetArgs()) == 1 {
+		if value, ok := call.GetArgs()[0].GetConstExpr().GetConstantKind().(*expr.Constant_StringValue); ok {
+			return time.ParseDuration(value.StringValue)
 		}
 	}
+	return 0, fmt.Errorf("unsupported duration: %s", celString(expression))
+}
+
+var reversedOperators = map[parser.CompareOperator]parser.CompareOperator{
+	parser.LT: parser.GT,
+	parser.GT: parser.LT,
+	parser.LE: parser.GE,
+	parser.GE: parser.LE,
+}
 
-	lhv, err := types.ParseValue(path)
+func (mapper *GoogleConditionMapper) mapCelAttrCompare(expressions []*expr.Expr, operator parser.CompareOperator) (parser.Expression, error) {
+	isNot := false
+	lhExpression, rhExpression := expressions[0], expressions[1]
+	callExpr := lhExpression.GetCallExpr()
+	if callExpr != nil && (callExpr.GetFunction() == "!_" || callExpr.GetFunction() == "_!_") {
+		isNot = true
+		lhExpression = callExpr.Args[0]
+	}
+
+	if !isCelAttribute(lhExpression) && isCelAttribute(rhExpression) {
+		// e.g. 5 < a.b is a.b > 5
+		lhExpression, rhExpression = rhExpression, lhExpression
+		if reversed, ok := reversedOperators[operator]; ok {
+			operator = reversed
+		}
+	}
+
+	path, err := mapper.celAttributePath(lhExpression)
+	if err != nil {
+		return nil, err
+	}
+	lhv, err := mapper.hexaAttribute(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rhv, err := mapper.mapCelValue(rhExpression)
 	if err != nil {
 		return nil, err
 	}
@@ -420,14 +803,41 @@ func (mapper *GoogleConditionMapper) mapCelAttrCompare(expressions []*expr.Expr,
 	return attrFilter, nil
 }
 
-func (mapper *GoogleConditionMapper) mapCelNot(expressions []*expr.Expr, _ bool) parser.Expression {
+// mapCelIn maps a in [...] and a in b.list to in, and "x" in a.list to co
+func (mapper *GoogleConditionMapper) mapCelIn(expressions []*expr.Expr) (parser.Expression, error) {
+	if _, isConst := expressions[0].GetExprKind().(*expr.Expr_ConstExpr); isConst && isCelAttribute(expressions[1]) {
+		path, err := mapper.celAttributePath(expressions[1])
+		if err != nil {
+			return nil, err
+		}
+		lhv, err := mapper.hexaAttribute(path)
+		if err != nil {
+			return nil, err
+		}
+		rhv, err := mapper.mapCelValue(expressions[0])
+		if err != nil {
+			return nil, err
+		}
+		return parser.AttributeExpression{
+			AttributePath: lhv,
+			Operator:      parser.CO,
+			CompareValue:  rhv,
+		}, nil
+	}
+	return mapper.mapCelAttrCompare(expressions, parser.IN)
+}
+
+func (mapper *GoogleConditionMapper) mapCelNot(expressions []*expr.Expr, _ bool) (parser.Expression, error) {
 
-	expression, _ := mapper.mapCelExpr(expressions[0], false) // ischild is ignored because of not
+	expression, err := mapper.mapCelExpr(expressions[0], false) // ischild is ignored because of not
+	if err != nil {
+		return nil, err
+	}
 
 	notFilter := parser.NotExpression{
 		Expression: expression,
 	}
-	return notFilter
+	return notFilter, nil
 }
 
 func (mapper *GoogleConditionMapper) mapCelLogical(expressions []*expr.Expr, isAnd bool, _ bool) (parser.Expression, error) {
@@ -465,26 +875,35 @@ func (mapper *GoogleConditionMapper) mapCelLogical(expressions []*expr.Expr, isA
 	return filters[0], nil
 }
 
-func checkCompatibility(e parser.Expression) error {
-	var err error
+func (mapper *GoogleConditionMapper) checkCompatibility(e parser.Expression) CompatibilityReport {
+	report := CompatibilityReport{}
+	mapper.checkExpression(e, &report)
+	return report
+}
+
+func (mapper *GoogleConditionMapper) checkExpression(e parser.Expression, report *CompatibilityReport) {
 	switch v := e.(type) {
 	case parser.LogicalExpression:
-		err = checkCompatibility(v.Left)
-		if err != nil {
-			return err
-		}
-		err = checkCompatibility(v.Right)
-		if err != nil {
-			return err
-		}
+		mapper.checkExpression(v.Left, report)
+		mapper.checkExpression(v.Right, report)
 	case parser.NotExpression:
-		return checkCompatibility(v.Expression)
+		mapper.checkExpression(v.Expression, report)
 	case parser.PrecedenceExpression:
-		return checkCompatibility(v.Expression)
+		mapper.checkExpression(v.Expression, report)
 	case parser.ValuePathExpression:
-		return errors.New("IDQL ValuePath expression mapping to Google CEL currently not supported")
+		mapper.valuePathMapper().checkExpression(v.VPathFilter, report)
+		if v.Operator != nil && *v.Operator == parser.IS {
+			report.add(v.String(), errors.New("IDQL is comparisons cannot be mapped to Google CEL"))
+		}
 	case parser.AttributeExpression:
-		return nil
+		switch v.Operator {
+		case parser.IS:
+			report.add(v.String(), errors.New("IDQL is comparisons cannot be mapped to Google CEL"))
+		case parser.PR:
+			// the CEL has() macro requires a field selection
+			if !strings.Contains(mapper.providerAttribute(v.AttributePath.String()), ".") {
+				report.add(v.String(), errors.New("IDQL pr requires an attribute with a parent (e.g. request.auth) in Google CEL"))
+			}
+		}
 	}
-	return nil
 }
@@ -6,6 +6,8 @@ import (
 
 	"github.com/hexa-org/policy-mapper/models/conditionLangs/gcpcel"
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -84,8 +86,19 @@ func TestParseFilter(t *testing.T) {
 			"userType ne \"Employee\" and not (emails co \"example.com\" or emails.value co \"example.org\")",
 			"userType ne \"Employee\" and not(emails co \"example.com\" or emails.value co \"example.org\")",
 		},
-		// "userType eq \"Employee\" and emails[type eq \"work\" and value co \"@example.com\"]",  // ValueFilter not implemented
-		// "emails[type eq \"work\" and value co \"@example.com\"] or ims[type eq \"xmpp\" and value co \"@foo.com\"]",
+		{
+			"userType eq \"Employee\" and emails[type eq \"work\" and value co \"@example.com\"]",
+			"userType eq \"Employee\" and emails[type eq \"work\" and value co \"@example.com\"]",
+		},
+		{
+			"emails[type eq \"work\" and value co \"@example.com\"] or ims[type eq \"xmpp\" and value co \"@foo.com\"]",
+			"emails[type eq \"work\" and value co \"@example.com\"] or ims[type eq \"xmpp\" and value co \"@foo.com\"]",
+		},
+		{
+			// a sub-attribute comparison becomes part of the filter
+			"emails[type eq \"work\" or primary eq true].value ew \"strata.io\"",
+			"emails[(type eq \"work\" or primary eq true) and value ew \"strata.io\"]",
+		},
 
 	}
 	for _, example := range examples {
@@ -128,21 +141,6 @@ func TestNegToProvider(t *testing.T) {
 	assert.Errorf(t, err, "invalid condition: Missing close ']' bracket")
 	assert.Equal(t, "", celString, "Should be empty string")
 
-	valuePath = conditions.ConditionInfo{Rule: "emails[type eq \"work\" and value ew \"strata.io\"]"}
-	celString, err = mapper.MapConditionToProvider(valuePath)
-	assert.Errorf(t, err, "IDQL ValuePath expression mapping to Google CEL currently not supported")
-	assert.Equal(t, "", celString, "Empty, value path not supported")
-
-	valuePath = conditions.ConditionInfo{Rule: "level GT 5 and emails[type eq \"work\" and value ew \"strata.io\"] pr"}
-	celString, err = mapper.MapConditionToProvider(valuePath)
-	assert.Errorf(t, err, "IDQL ValuePath expression mapping to Google CEL currently not supported")
-	assert.Equal(t, "", celString, "Empty, value path not supported")
-
-	valuePath = conditions.ConditionInfo{Rule: "emails[type eq \"work\" and value ew \"strata.io\"] and level gt 5"}
-	celString, err = mapper.MapConditionToProvider(valuePath)
-	assert.Errorf(t, err, "IDQL ValuePath expression mapping to Google CEL currently not supported")
-	assert.Equal(t, "", celString, "Empty, value path not supported")
-
 	badCompare := conditions.ConditionInfo{Rule: "level GT 3 and abc GR 2"}
 	celString, err = mapper.MapConditionToProvider(badCompare)
 	assert.Errorf(t, err, "invalid condition: Unsupported comparison operator: GR")
@@ -179,7 +177,86 @@ func TestNegToIdql(t *testing.T) {
 
 	celString = "emails.exists(emails,type == \"work\" && value.endsWith(\"strata.io\"))"
 	cond, err = mapper.MapProviderToCondition(celString)
-	assert.Contains(t, err.Error(), "unimplemented CEL expression:")
+	assert.Contains(t, err.Error(), "unsupported attribute type in exists()")
 	assert.Equal(t, "", cond.Rule, "Empty rule returned")
 
 }
+
+func TestProviderToIdql(t *testing.T) {
+	examples := [][2]string{
+		{"has(request.auth.claims)", "request.auth.claims pr"},
+		{"request.auth.verified", "request.auth.verified eq true"},
+		{"request.auth.claims[\"email\"].endsWith(\"@example.com\")", "request.auth.claims.email ew \"@example.com\""},
+		{"request.path in [\"/a\", \"/b\"]", "request.path in [\"/a\", \"/b\"]"},
+		{"\"admin\" in request.auth.groups", "request.auth.groups co \"admin\""},
+		{"3 < level", "level gt 3"},
+		{"resource.name.startsWith(\"projects/_/buckets/b1\")", "resource.name sw \"projects/_/buckets/b1\""},
+		{"resource.name.matches(\"^projects/p1$\")", "resource.name eq \"projects/p1\""},
+		{"resource.name.matches(\"^projects/p1\")", "resource.name sw \"projects/p1\""},
+		{"resource.name.matches(\"objects/a\\\\.txt$\")", "resource.name ew \"objects/a.txt\""},
+		{"matches(resource.name, \"buckets\")", "resource.name co \"buckets\""},
+		{"request.time < timestamp(\"2024-01-01T00:00:00Z\")", "request.time lt 2024-01-01T00:00:00Z"},
+		{"request.time < timestamp(\"2024-01-01T00:00:00Z\") + duration(\"3600s\")", "request.time lt 2024-01-01T01:00:00Z"},
+		{"request.time >= timestamp(\"2024-01-01T00:00:00Z\") - duration(\"24h\")", "request.time ge 2023-12-31T00:00:00Z"},
+		{"request.auth.claims.groups.exists(g, g.name == \"admins\" && g.verified)", "request.auth.claims.groups[name eq \"admins\" and verified eq true]"},
+	}
+	for _, example := range examples {
+		t.Run(example[0], func(t *testing.T) {
+			cond, err := mapper.MapProviderToCondition(example[0])
+			assert.NoError(t, err)
+			assert.Equal(t, example[1], cond.Rule)
+		})
+	}
+}
+
+func TestIdqlToProvider(t *testing.T) {
+	examples := [][2]string{
+		{"request.path in [\"/a\", \"/b\"]", "request.path in [\"/a\", \"/b\"]"},
+		{"request.time lt 2024-01-01T00:00:00Z", "request.time < timestamp('2024-01-01T00:00:00Z')"},
+		{"request.auth.claims pr", "has(request.auth.claims)"},
+		{"emails[type eq \"work\" and value ew \"strata.io\"]", "emails.exists(v, v.type == \"work\" && v.value.endsWith(\"strata.io\"))"},
+		{"level gt 5 and emails[type pr]", "level > 5 && emails.exists(v, has(v.type))"},
+		{"emails[not(primary eq true)]", "emails.exists(v, !v.primary == true)"},
+	}
+	for _, example := range examples {
+		t.Run(example[0], func(t *testing.T) {
+			celString, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: example[0]})
+			assert.NoError(t, err)
+			assert.Equal(t, example[1], celString)
+
+			cond, err := mapper.MapProviderToCondition(celString)
+			assert.NoError(t, err)
+			assert.Equal(t, example[0], cond.Rule)
+		})
+	}
+}
+
+func TestCompatibilityReport(t *testing.T) {
+	report := mapper.CheckProviderCompatibility("has(request.auth) && request.path.size() > 3 && (resource.name.matches(\"[a-z]+\") || level > 2)")
+	assert.False(t, report.IsCompatible())
+	assert.Len(t, report.Issues, 2)
+	assert.Equal(t, "request.path.size() > 3", report.Issues[0].Expression)
+	assert.Contains(t, report.Issues[0].Reason, "size")
+	assert.Equal(t, "resource.name.matches(\"[a-z]+\")", report.Issues[1].Expression)
+	assert.Contains(t, report.Error(), "cannot be mapped")
+
+	report = mapper.CheckProviderCompatibility("has(request.auth) && level > 2")
+	assert.True(t, report.IsCompatible())
+
+	report = mapper.CheckConditionCompatibility(conditions.ConditionInfo{Rule: "emails[type eq \"work\"] pr and (level pr or level gt 3)"})
+	assert.Len(t, report.Issues, 1)
+	assert.Equal(t, "level pr", report.Issues[0].Expression)
+
+	report = mapper.CheckProviderCompatibility("emails.all(e, e.verified) || groups.exists(g, g.members.exists(m, m.name == \"alice\"))")
+	assert.Len(t, report.Issues, 2)
+	assert.Contains(t, report.Issues[0].Reason, "only exists()")
+	assert.Contains(t, report.Issues[1].Reason, "nested exists()")
+
+	_, err := mapper.MapFilter(parser.AttributeExpression{
+		AttributePath: *types.ParseEntity("resource"),
+		Operator:      parser.IS,
+		CompareValue:  *types.ParseEntity("Photo"),
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is comparisons")
+}
@@ -0,0 +1,616 @@
+package iamConditions
+
+/*
+ Condition mapper for AWS IAM policy Condition blocks - See:
+ https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_condition_operators.html
+
+ An IAM Condition block is a map of condition operators to a map of condition keys and values. All operators and keys
+ within a block are and'ed, while multiple values for a single key are or'ed. Because IAM cannot express a disjunction
+ across keys, an IDQL condition is mapped into one Condition block per disjunct. For example:
+
+    req.ip eq "10.0.0.0/8" and (subject.sub eq "alice" or subject.sub eq "bob")
+
+ becomes two blocks:
+
+    {"IpAddress": {"aws:SourceIp": "10.0.0.0/8"}, "StringEquals": {"aws:username": "alice"}}
+    {"IpAddress": {"aws:SourceIp": "10.0.0.0/8"}, "StringEquals": {"aws:username": "bob"}}
+
+ IfExists operators are mapped as "not(attr pr) or (comparison)". ForAllValues operators have no IDQL equivalent and
+ are rejected.
+*/
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	OpStringEquals    = "StringEquals"
+	OpStringNotEquals = "StringNotEquals"
+	OpStringLike      = "StringLike"
+	OpStringNotLike   = "StringNotLike"
+	OpNumericEquals   = "NumericEquals"
+	OpNumericNotEq    = "NumericNotEquals"
+	OpNumericLt       = "NumericLessThan"
+	OpNumericLe       = "NumericLessThanEquals"
+	OpNumericGt       = "NumericGreaterThan"
+	OpNumericGe       = "NumericGreaterThanEquals"
+	OpDateEquals      = "DateEquals"
+	OpDateNotEquals   = "DateNotEquals"
+	OpDateLt          = "DateLessThan"
+	OpDateLe          = "DateLessThanEquals"
+	OpDateGt          = "DateGreaterThan"
+	OpDateGe          = "DateGreaterThanEquals"
+	OpBool            = "Bool"
+	OpIpAddress       = "IpAddress"
+	OpNotIpAddress    = "NotIpAddress"
+	OpNull            = "Null"
+
+	prefixForAny  = "ForAnyValue:"
+	prefixForAll  = "ForAllValues:"
+	suffixIfExist = "IfExists"
+)
+
+// likeEscaper escapes the characters of a literal value that are special in a StringLike pattern using the IAM
+// special character variables. likeUnescaper reverses it.
+var (
+	likeEscaper   = strings.NewReplacer("*", "${*}", "?", "${?}", "$", "${$}")
+	likeUnescaper = strings.NewReplacer("${*}", "*", "${?}", "?", "${$}", "$")
+)
+
+// Values holds the values of an IAM condition key. IAM allows a single value or an array, and values may be JSON
+// strings, numbers or booleans.
+type Values []string
+
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	items, isArray := raw.([]interface{})
+	if !isArray {
+		items = []interface{}{raw}
+	}
+	res := make(Values, len(items))
+	for i, item := range items {
+		switch val := item.(type) {
+		case string:
+			res[i] = val
+		case float64, bool:
+			res[i] = fmt.Sprint(val)
+		default:
+			return fmt.Errorf("unsupported IAM condition value: %v", item)
+		}
+	}
+	*v = res
+	return nil
+}
+
+func (v Values) MarshalJSON() ([]byte, error) {
+	if len(v) == 1 {
+		return json.Marshal(v[0])
+	}
+	return json.Marshal([]string(v))
+}
+
+// Condition is an IAM policy Condition block (operator -> condition key -> values)
+type Condition map[string]map[string]Values
+
+func (c Condition) add(operator string, key string, values ...string) error {
+	keys, exists := c[operator]
+	if !exists {
+		keys = map[string]Values{}
+		c[operator] = keys
+	}
+	if _, exists = keys[key]; exists {
+		// IAM or's multiple values for the same key, which would change the meaning of an and'ed IDQL expression
+		return fmt.Errorf("IAM cannot combine multiple %s comparisons of %s in a single condition", operator, key)
+	}
+	keys[key] = values
+	return nil
+}
+
+// defaultKeys maps IDQL attribute names to AWS global condition keys
+var defaultKeys = map[string]string{
+	"req.ip":          "aws:SourceIp",
+	"req.time":        "aws:CurrentTime",
+	"req.secure":      "aws:SecureTransport",
+	"req.vpc":         "aws:SourceVpc",
+	"subject.sub":     "aws:username",
+	"subject.userid":  "aws:userid",
+	"subject.account": "aws:PrincipalAccount",
+	"subject.arn":     "aws:PrincipalArn",
+}
+
+// tagPrefixes maps IDQL attribute prefixes to AWS tag condition key prefixes
+var tagPrefixes = [][2]string{
+	{"subject.tags.", "aws:PrincipalTag/"},
+	{"resource.tags.", "aws:ResourceTag/"},
+	{"req.tags.", "aws:RequestTag/"},
+}
+
+var ipKeys = []string{"aws:sourceip", "aws:vpcsourceip"}
+
+type IamConditionMapper struct {
+	NameMapper *conditions.AttributeMap
+}
+
+// MapConditionToProvider converts an IDQL condition into one or more IAM Condition blocks. Each block represents an
+// alternative (or) and should be used in a separate IAM policy statement.
+func (mapper *IamConditionMapper) MapConditionToProvider(condition conditions.ConditionInfo) ([]Condition, error) {
+	ast, err := conditions.ParseConditionRuleAst(condition)
+	if err != nil {
+		return nil, err
+	}
+	return mapper.MapFilter(ast)
+}
+
+// comparison is a single IAM operator test of a condition key
+type comparison struct {
+	operator string
+	key      string
+	values   []string
+}
+
+// MapFilter converts an IDQL condition AST into IAM Condition blocks (one per disjunct)
+func (mapper *IamConditionMapper) MapFilter(ast parser.Expression) ([]Condition, error) {
+	bodies, err := mapper.mapExpression(ast, false)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Condition, len(bodies))
+	for i, body := range bodies {
+		condition := Condition{}
+		for _, compare := range body {
+			if err = condition.add(compare.operator, compare.key, compare.values...); err != nil {
+				return nil, err
+			}
+		}
+		res[i] = condition
+	}
+	return res, nil
+}
+
+// mapExpression returns the disjunctive normal form of an expression as a list of and'ed comparisons
+func (mapper *IamConditionMapper) mapExpression(ast parser.Expression, negate bool) ([][]comparison, error) {
+	switch element := ast.(type) {
+	case parser.PrecedenceExpression:
+		return mapper.mapExpression(element.Expression, negate)
+	case parser.NotExpression:
+		return mapper.mapExpression(element.Expression, !negate)
+	case parser.LogicalExpression:
+		left, err := mapper.mapExpression(element.Left, negate)
+		if err != nil {
+			return nil, err
+		}
+		right, err := mapper.mapExpression(element.Right, negate)
+		if err != nil {
+			return nil, err
+		}
+		// De Morgan: not(a and b) is not(a) or not(b)
+		isAnd := element.Operator == parser.AND
+		if negate {
+			isAnd = !isAnd
+		}
+		if !isAnd {
+			return append(left, right...), nil
+		}
+		res := make([][]comparison, 0, len(left)*len(right))
+		for _, l := range left {
+			for _, r := range right {
+				combined := make([]comparison, 0, len(l)+len(r))
+				combined = append(combined, l...)
+				res = append(res, append(combined, r...))
+			}
+		}
+		return res, nil
+	case parser.AttributeExpression:
+		compare, err := mapper.mapAttrExpr(element, negate)
+		if err != nil {
+			return nil, err
+		}
+		return [][]comparison{{compare}}, nil
+	case parser.ValuePathExpression:
+		return nil, fmt.Errorf("IDQL value path expressions (%s) are not supported in IAM", element.String())
+	}
+	return nil, fmt.Errorf("unsupported IDQL expression: %s", ast.String())
+}
+
+// negations maps an IDQL operator to its logical inverse
+var negations = map[parser.CompareOperator]parser.CompareOperator{
+	parser.EQ: parser.NE,
+	parser.NE: parser.EQ,
+	parser.LT: parser.GE,
+	parser.LE: parser.GT,
+	parser.GT: parser.LE,
+	parser.GE: parser.LT,
+}
+
+var numericOps = map[parser.CompareOperator]string{
+	parser.EQ: OpNumericEquals, parser.NE: OpNumericNotEq, parser.LT: OpNumericLt,
+	parser.LE: OpNumericLe, parser.GT: OpNumericGt, parser.GE: OpNumericGe,
+}
+
+var dateOps = map[parser.CompareOperator]string{
+	parser.EQ: OpDateEquals, parser.NE: OpDateNotEquals, parser.LT: OpDateLt,
+	parser.LE: OpDateLe, parser.GT: OpDateGt, parser.GE: OpDateGe,
+}
+
+func (mapper *IamConditionMapper) mapAttrExpr(attrExpr parser.AttributeExpression, negate bool) (comparison, error) {
+	entity, ok := attrExpr.AttributePath.(types.Entity)
+	if !ok || !isAttributePath(entity) {
+		return comparison{}, fmt.Errorf("left hand side of '%s' must be an attribute", attrExpr.String())
+	}
+	key := mapper.mapKey(entity.String())
+	operator := attrExpr.Operator
+
+	if operator == parser.PR {
+		return comparison{operator: OpNull, key: key, values: []string{strconv.FormatBool(negate)}}, nil
+	}
+
+	if negate {
+		if inverse, exists := negations[operator]; exists {
+			operator = inverse
+			negate = false
+		}
+	}
+
+	values, valueType, err := mapper.mapValues(attrExpr.CompareValue)
+	if err != nil {
+		return comparison{}, err
+	}
+	if operator == parser.IN && valueType != types.TypeArray {
+		return comparison{}, fmt.Errorf("IDQL entity membership (%s) is not supported in IAM", attrExpr.String())
+	}
+	if valueType == types.TypeArray {
+		if operator != parser.IN {
+			return comparison{}, fmt.Errorf("IDQL operator '%s' does not support an array value in IAM", operator)
+		}
+		// IAM or's the values of a key, so "in" is an equality test of each element
+		operator = parser.EQ
+		if negate {
+			operator = parser.NE
+			negate = false
+		}
+		if arr, ok := attrExpr.CompareValue.(types.Array); ok && len(arr.Value().([]types.ComparableValue)) > 0 {
+			valueType = arr.Value().([]types.ComparableValue)[0].ValueType()
+		} else {
+			valueType = types.TypeString
+		}
+	}
+
+	switch valueType {
+	case types.TypeNumber, types.TypeDate:
+		if negate {
+			break
+		}
+		ops := numericOps
+		if valueType == types.TypeDate {
+			ops = dateOps
+		}
+		if iamOp, exists := ops[operator]; exists {
+			return comparison{operator: iamOp, key: key, values: values}, nil
+		}
+	case types.TypeBool:
+		if negate || (operator != parser.EQ && operator != parser.NE) {
+			break
+		}
+		if operator == parser.NE {
+			values = []string{strconv.FormatBool(values[0] != "true")}
+		}
+		return comparison{operator: OpBool, key: key, values: values}, nil
+	default:
+		if isIpKey(key) && !negate && (operator == parser.EQ || operator == parser.NE) {
+			iamOp := OpIpAddress
+			if operator == parser.NE {
+				iamOp = OpNotIpAddress
+			}
+			return comparison{operator: iamOp, key: key, values: values}, nil
+		}
+		iamOp, pattern := "", ""
+		switch operator {
+		case parser.EQ:
+			iamOp = OpStringEquals
+		case parser.NE:
+			iamOp = OpStringNotEquals
+		case parser.SW:
+			iamOp, pattern = OpStringLike, "%s*"
+		case parser.EW:
+			iamOp, pattern = OpStringLike, "*%s"
+		case parser.CO:
+			iamOp, pattern = OpStringLike, "*%s*"
+		}
+		if iamOp == "" {
+			break
+		}
+		if pattern != "" {
+			value := values[0]
+			if _, isString := attrExpr.CompareValue.(types.String); isString {
+				value = likeEscaper.Replace(value)
+			}
+			values = []string{fmt.Sprintf(pattern, value)}
+			if negate {
+				iamOp = OpStringNotLike
+				negate = false
+			}
+		}
+		if !negate {
+			return comparison{operator: iamOp, key: key, values: values}, nil
+		}
+	}
+	if negate {
+		return comparison{}, fmt.Errorf("negation of '%s' is not supported in IAM", attrExpr.String())
+	}
+	return comparison{}, fmt.Errorf("IDQL operator '%s' is not supported in IAM for %s values", operator, types.TypeName(valueType))
+}
+
+// mapValues returns the IAM string form of a compare value. Attribute references are mapped to IAM policy variables.
+func (mapper *IamConditionMapper) mapValues(value types.Value) ([]string, int, error) {
+	switch v := value.(type) {
+	case types.String:
+		return []string{v.Value().(string)}, types.TypeString, nil
+	case types.Numeric, types.Date, types.Boolean:
+		return []string{v.String()}, v.ValueType(), nil
+	case types.Array:
+		items := v.Value().([]types.ComparableValue)
+		res := make([]string, 0, len(items))
+		for _, item := range items {
+			mapped, _, err := mapper.mapValues(item)
+			if err != nil {
+				return nil, 0, err
+			}
+			res = append(res, mapped...)
+		}
+		return res, types.TypeArray, nil
+	case types.Entity:
+		if isAttributePath(v) {
+			return []string{"${" + mapper.mapKey(v.String()) + "}"}, types.TypeString, nil
+		}
+		return []string{v.String()}, types.TypeVariable, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported value type %s in IAM mapping", types.TypeName(value.ValueType()))
+}
+
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+func isIpKey(key string) bool {
+	for _, ipKey := range ipKeys {
+		if strings.EqualFold(key, ipKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKey maps an IDQL attribute name to an IAM condition key. Unmapped names use the first path element as the service
+// prefix (e.g. s3.prefix becomes s3:prefix).
+func (mapper *IamConditionMapper) mapKey(path string) string {
+	if mapper.NameMapper != nil {
+		if mapped := mapper.NameMapper.GetProviderAttributeName(path); mapped != path {
+			return mapped
+		}
+	}
+	lower := strings.ToLower(path)
+	if key, exists := defaultKeys[lower]; exists {
+		return key
+	}
+	for _, prefix := range tagPrefixes {
+		if strings.HasPrefix(lower, prefix[0]) {
+			return prefix[1] + path[len(prefix[0]):]
+		}
+	}
+	return strings.Replace(path, ".", ":", 1)
+}
+
+// mapAttribute maps an IAM condition key to an IDQL attribute name
+func (mapper *IamConditionMapper) mapAttribute(key string) string {
+	if mapper.NameMapper != nil {
+		if mapped := mapper.NameMapper.GetHexaFilterAttributePath(strings.ToLower(key)); !strings.EqualFold(mapped, key) {
+			return mapped
+		}
+	}
+	for attr, iamKey := range defaultKeys {
+		if strings.EqualFold(key, iamKey) {
+			return attr
+		}
+	}
+	for _, prefix := range tagPrefixes {
+		if len(key) > len(prefix[1]) && strings.EqualFold(key[:len(prefix[1])], prefix[1]) {
+			return prefix[0] + key[len(prefix[1]):]
+		}
+	}
+	return strings.ReplaceAll(strings.Replace(key, ":", ".", 1), "/", ".")
+}
+
+// MapProviderToCondition converts an IAM Condition block into an IDQL condition
+func (mapper *IamConditionMapper) MapProviderToCondition(condition Condition) (conditions.ConditionInfo, error) {
+	if len(condition) == 0 {
+		return conditions.ConditionInfo{}, errors.New("IAM condition is empty")
+	}
+	operators := make([]string, 0, len(condition))
+	for operator := range condition {
+		operators = append(operators, operator)
+	}
+	sort.Strings(operators)
+
+	var clauses []string
+	for _, operator := range operators {
+		keys := make([]string, 0, len(condition[operator]))
+		for key := range condition[operator] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			clause, err := mapper.mapIamComparison(operator, key, condition[operator][key])
+			if err != nil {
+				return conditions.ConditionInfo{}, errors.New("IDQL condition mapper error: " + err.Error())
+			}
+			clauses = append(clauses, clause)
+		}
+	}
+
+	rule := clauses[0]
+	if len(clauses) > 1 {
+		for i, clause := range clauses {
+			if strings.Contains(clause, " or ") {
+				clauses[i] = "(" + clause + ")"
+			}
+		}
+		rule = strings.Join(clauses, " and ")
+	}
+	ast, err := conditions.ParseExpressionAst(rule)
+	if err != nil {
+		return conditions.ConditionInfo{}, errors.New("IDQL condition mapper error: " + err.Error())
+	}
+	return conditions.ConditionInfo{
+		Rule:   conditions.SerializeExpression(ast),
+		Action: conditions.AAllow,
+	}, nil
+}
+
+func (mapper *IamConditionMapper) mapIamComparison(operator string, key string, values Values) (string, error) {
+	if len(values) == 0 {
+		return "", fmt.Errorf("no values for %s %s", operator, key)
+	}
+	if strings.HasPrefix(operator, prefixForAll) {
+		// every value of the key must match (and a missing key matches), which IDQL cannot express
+		return "", fmt.Errorf("IAM condition operator %s is not supported", operator)
+	}
+	baseOp, ifExists := strings.CutSuffix(operator, suffixIfExist)
+	if ifExists && baseOp != OpNull {
+		// the comparison is also true when the key is not present
+		clause, err := mapper.mapIamComparison(baseOp, key, values)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("not(%s pr) or (%s)", mapper.mapAttribute(key), clause), nil
+	}
+	forAny := strings.HasPrefix(operator, prefixForAny)
+	baseOp = strings.TrimPrefix(operator, prefixForAny)
+	attr := mapper.mapAttribute(key)
+
+	var idqlOp parser.CompareOperator
+	valueType := types.TypeString
+	negative := false
+	switch baseOp {
+	case OpStringEquals, "StringEqualsIgnoreCase", "ArnEquals":
+		idqlOp = parser.EQ
+		if forAny {
+			idqlOp = parser.CO
+		}
+	case OpStringNotEquals, "StringNotEqualsIgnoreCase", "ArnNotEquals":
+		idqlOp, negative = parser.NE, true
+	case OpStringLike, "ArnLike":
+		return mapper.joinValues(values, " or ", func(value string) (string, error) {
+			return mapper.mapLike(attr, value)
+		})
+	case OpStringNotLike, "ArnNotLike":
+		return mapper.joinValues(values, " and ", func(value string) (string, error) {
+			like, err := mapper.mapLike(attr, value)
+			return "not(" + like + ")", err
+		})
+	case OpIpAddress:
+		idqlOp = parser.EQ
+	case OpNotIpAddress:
+		idqlOp, negative = parser.NE, true
+	case OpBool:
+		return mapper.joinValues(values, " or ", func(value string) (string, error) {
+			if !strings.EqualFold(value, "true") && !strings.EqualFold(value, "false") {
+				return "", fmt.Errorf("invalid Bool value %s", value)
+			}
+			return fmt.Sprintf("%s eq %s", attr, strings.ToLower(value)), nil
+		})
+	case OpNull:
+		if strings.EqualFold(values[0], "true") {
+			return fmt.Sprintf("not(%s pr)", attr), nil
+		}
+		return attr + " pr", nil
+	default:
+		for op, iamOp := range numericOps {
+			if iamOp == baseOp {
+				idqlOp, valueType = op, types.TypeNumber
+			}
+		}
+		for op, iamOp := range dateOps {
+			if iamOp == baseOp {
+				idqlOp, valueType = op, types.TypeDate
+			}
+		}
+		if idqlOp == "" {
+			return "", fmt.Errorf("IAM condition operator %s is not supported", operator)
+		}
+		negative = idqlOp == parser.NE
+	}
+
+	join := " or "
+	if negative {
+		join = " and "
+	}
+	return mapper.joinValues(values, join, func(value string) (string, error) {
+		mapped, err := mapper.mapIamValue(value, valueType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", attr, idqlOp, mapped), nil
+	})
+}
+
+func (mapper *IamConditionMapper) joinValues(values Values, join string, mapValue func(value string) (string, error)) (string, error) {
+	res := make([]string, len(values))
+	for i, value := range values {
+		mapped, err := mapValue(value)
+		if err != nil {
+			return "", err
+		}
+		res[i] = mapped
+	}
+	return strings.Join(res, join), nil
+}
+
+// mapLike maps a StringLike pattern with a leading and/or trailing wildcard. Escaped special characters (e.g. ${*})
+// are literal.
+func (mapper *IamConditionMapper) mapLike(attr string, pattern string) (string, error) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(pattern, "*"), "*")
+	if strings.ContainsAny(strings.NewReplacer("${*}", "", "${?}", "").Replace(inner), "*?") {
+		return "", fmt.Errorf("IAM wildcard pattern %s is not supported", pattern)
+	}
+	quoted := strconv.Quote(likeUnescaper.Replace(inner))
+	starts, ends := strings.HasPrefix(pattern, "*"), strings.HasSuffix(pattern, "*") && len(pattern) > 1
+	switch {
+	case starts && ends:
+		return fmt.Sprintf("%s co %s", attr, quoted), nil
+	case starts:
+		return fmt.Sprintf("%s ew %s", attr, quoted), nil
+	case ends:
+		return fmt.Sprintf("%s sw %s", attr, quoted), nil
+	}
+	return fmt.Sprintf("%s eq %s", attr, quoted), nil
+}
+
+func (mapper *IamConditionMapper) mapIamValue(value string, valueType int) (string, error) {
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		return mapper.mapAttribute(value[2 : len(value)-1]), nil
+	}
+	switch valueType {
+	case types.TypeNumber:
+		if _, err := types.NewNumeric(value); err != nil {
+			return "", fmt.Errorf("invalid numeric value %s", value)
+		}
+		return value, nil
+	case types.TypeDate:
+		if _, err := types.NewDate(value); err != nil {
+			return "", fmt.Errorf("invalid date value %s", value)
+		}
+		return value, nil
+	}
+	return strconv.Quote(value), nil
+}
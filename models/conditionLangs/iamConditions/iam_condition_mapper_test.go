@@ -0,0 +1,144 @@
+package iamConditions_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/models/conditionLangs/iamConditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+var mapper = iamConditions.IamConditionMapper{
+	NameMapper: conditions.NewNameMapper(map[string]string{
+		"resource.bucket": "s3:prefix",
+	}),
+}
+
+func TestMapToIam(t *testing.T) {
+	tests := []struct {
+		name string
+		idql string
+		iam  string
+	}{
+		{"Equals", "subject.sub eq \"alice\"", `[{"StringEquals":{"aws:username":"alice"}}]`},
+		{"Not equals", "not(subject.sub eq \"alice\")", `[{"StringNotEquals":{"aws:username":"alice"}}]`},
+		{"Ip", "req.ip eq \"10.0.0.0/8\"", `[{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}]`},
+		{"Not ip", "req.ip ne \"10.0.0.0/8\"", `[{"NotIpAddress":{"aws:SourceIp":"10.0.0.0/8"}}]`},
+		{"Date", "req.time lt 2025-01-01T00:00:00Z", `[{"DateLessThan":{"aws:CurrentTime":"2025-01-01T00:00:00Z"}}]`},
+		{"Numeric", "s3.max_keys ge 10 and not(s3.max_keys gt 100)",
+			`[{"NumericGreaterThanEquals":{"s3:max_keys":"10"},"NumericLessThanEquals":{"s3:max_keys":"100"}}]`},
+		{"Bool", "req.secure eq true", `[{"Bool":{"aws:SecureTransport":"true"}}]`},
+		{"Bool ne", "req.secure ne true", `[{"Bool":{"aws:SecureTransport":"false"}}]`},
+		{"Like", "resource.bucket sw \"home/\" and subject.sub ew \"@example.com\"",
+			`[{"StringLike":{"aws:username":"*@example.com","s3:prefix":"home/*"}}]`},
+		{"Not like", "not(resource.tags.project co \"secret\")", `[{"StringNotLike":{"aws:ResourceTag/project":"*secret*"}}]`},
+		{"Like escaped", "resource.bucket sw \"a*?$\"", `[{"StringLike":{"s3:prefix":"a${*}${?}${$}*"}}]`},
+		{"Present", "subject.tags.dept pr", `[{"Null":{"aws:PrincipalTag/dept":"false"}}]`},
+		{"Not present", "not(subject.tags.dept pr)", `[{"Null":{"aws:PrincipalTag/dept":"true"}}]`},
+		{"In", "subject.sub in [\"alice\",\"bob\"]", `[{"StringEquals":{"aws:username":["alice","bob"]}}]`},
+		{"Not in", "not(subject.sub in [\"alice\",\"bob\"])", `[{"StringNotEquals":{"aws:username":["alice","bob"]}}]`},
+		{"Variable", "resource.tags.owner eq subject.sub", `[{"StringEquals":{"aws:ResourceTag/owner":"${aws:username}"}}]`},
+		{"Or", "req.ip eq \"10.0.0.0/8\" and (subject.sub eq \"alice\" or subject.sub eq \"bob\")",
+			`[{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"},"StringEquals":{"aws:username":"alice"}},{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"},"StringEquals":{"aws:username":"bob"}}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			iamConds, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: test.idql, Action: "allow"})
+			assert.NoError(t, err)
+			iamBytes, err := json.Marshal(iamConds)
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.iam, string(iamBytes))
+		})
+	}
+}
+
+func TestMapToIam_Errors(t *testing.T) {
+	for _, idql := range []string{
+		"subject in Group:admins",
+		"emails[type eq \"work\"] pr",
+		"subject.sub sw \"a\" and subject.sub ew \"z\"",
+		"subject.sub gt \"m\"",
+		"subject.level co 5",
+	} {
+		t.Run(idql, func(t *testing.T) {
+			_, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: idql, Action: "allow"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMapFromIam(t *testing.T) {
+	tests := []struct {
+		name string
+		iam  string
+		idql string
+	}{
+		{"Equals", `{"StringEquals":{"aws:username":"alice"}}`, "subject.sub eq \"alice\""},
+		{"Multi value", `{"StringEquals":{"aws:username":["alice","bob"]}}`, "subject.sub eq \"alice\" or subject.sub eq \"bob\""},
+		{"Not multi value", `{"StringNotEquals":{"aws:username":["alice","bob"]}}`, "subject.sub ne \"alice\" and subject.sub ne \"bob\""},
+		{"And", `{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"},"DateLessThan":{"aws:CurrentTime":"2025-01-01T00:00:00Z"}}`,
+			"req.time lt 2025-01-01T00:00:00Z and req.ip eq \"10.0.0.0/8\""},
+		{"Precedence", `{"Bool":{"aws:SecureTransport":true},"StringEquals":{"aws:username":["alice","bob"]}}`,
+			"req.secure eq true and (subject.sub eq \"alice\" or subject.sub eq \"bob\")"},
+		{"Numeric", `{"NumericLessThanEquals":{"s3:max-keys":10}}`, "s3.max-keys le 10"},
+		{"Like", `{"StringLike":{"s3:prefix":["home/*","*.txt","*secret*","exact"]}}`,
+			"resource.bucket sw \"home/\" or resource.bucket ew \".txt\" or resource.bucket co \"secret\" or resource.bucket eq \"exact\""},
+		{"Not like", `{"StringNotLike":{"aws:ResourceTag/project":"*secret*"}}`, "not(resource.tags.project co \"secret\")"},
+		{"ForAnyValue", `{"ForAnyValue:StringEquals":{"aws:PrincipalTag/roles":"admin"}}`, "subject.tags.roles co \"admin\""},
+		{"IfExists", `{"StringEqualsIfExists":{"aws:username":["alice","bob"]}}`,
+			"not(subject.sub pr) or (subject.sub eq \"alice\" or subject.sub eq \"bob\")"},
+		{"Like escaped", `{"StringLike":{"s3:prefix":"a${*}${?}*"}}`, "resource.bucket sw \"a*?\""},
+		{"Null", `{"Null":{"aws:PrincipalTag/dept":"true"}}`, "not(subject.tags.dept pr)"},
+		{"Variable", `{"StringEquals":{"aws:ResourceTag/owner":"${aws:username}"}}`, "resource.tags.owner eq subject.sub"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var iamCond iamConditions.Condition
+			assert.NoError(t, json.Unmarshal([]byte(test.iam), &iamCond))
+			condition, err := mapper.MapProviderToCondition(iamCond)
+			assert.NoError(t, err)
+			expected := conditions.ConditionInfo{Rule: test.idql, Action: "allow"}
+			assert.True(t, expected.Equals(&condition), "Expected %s, got %s", test.idql, condition.Rule)
+		})
+	}
+}
+
+func TestMapFromIam_Errors(t *testing.T) {
+	for _, iam := range []string{
+		`{}`,
+		`{"StringLike":{"aws:username":"a*b"}}`,
+		`{"NumericEquals":{"s3:max-keys":"ten"}}`,
+		`{"ArnNotLikeSomething":{"aws:SourceArn":"x"}}`,
+		`{"ForAllValues:StringEquals":{"aws:TagKeys":["a","b"]}}`,
+	} {
+		t.Run(iam, func(t *testing.T) {
+			var iamCond iamConditions.Condition
+			assert.NoError(t, json.Unmarshal([]byte(iam), &iamCond))
+			_, err := mapper.MapProviderToCondition(iamCond)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, idql := range []string{
+		"subject.sub eq \"alice\"",
+		"req.ip ne \"10.0.0.0/8\"",
+		"req.time ge 2025-01-01T00:00:00Z",
+		"subject.tags.dept pr",
+		"resource.bucket sw \"home/\" and req.secure eq true",
+		"resource.tags.owner eq subject.sub",
+		"resource.bucket co \"a*b?\"",
+	} {
+		t.Run(idql, func(t *testing.T) {
+			iamConds, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: idql, Action: "allow"})
+			assert.NoError(t, err)
+			assert.Len(t, iamConds, 1)
+			condition, err := mapper.MapProviderToCondition(iamConds[0])
+			assert.NoError(t, err)
+			expected := conditions.ConditionInfo{Rule: idql, Action: "allow"}
+			assert.True(t, expected.Equals(&condition), "Expected %s, got %s", idql, condition.Rule)
+		})
+	}
+}
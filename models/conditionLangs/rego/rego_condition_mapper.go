@@ -0,0 +1,312 @@
+package rego
+
+/*
+ Condition mapper for Open Policy Agent Rego - See: https://www.openpolicyagent.org/docs/latest/policy-language/
+
+ Rego has no inline disjunction operator. An IDQL condition is therefore mapped into one or more rule bodies where
+ the expressions within a body are and'ed, and the bodies are or'ed (disjunctive normal form). For example:
+
+    subject.roles co "admin" or (level gt 5 and level lt 10)
+
+ becomes:
+
+    condition if {
+        "admin" in input.subject.roles
+    }
+
+    condition if {
+        input.level > 5
+        input.level < 10
+    }
+*/
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	// DefaultRuleName is the rule name used by MapConditionToProvider
+	DefaultRuleName = "condition"
+
+	prefixInput = "input."
+	prefixData  = "data."
+
+	funcTimeParse = "time.parse_rfc3339_ns"
+)
+
+type RegoConditionMapper struct {
+	NameMapper *conditions.AttributeMap
+}
+
+// body is a set of Rego expressions that must all be true
+type body []string
+
+// MapConditionToProvider converts an IDQL condition into Rego rules named DefaultRuleName
+func (mapper *RegoConditionMapper) MapConditionToProvider(condition conditions.ConditionInfo) (string, error) {
+	ast, err := conditions.ParseConditionRuleAst(condition)
+	if err != nil {
+		return "", err
+	}
+	bodies, err := mapper.MapFilter(ast)
+	if err != nil {
+		return "", err
+	}
+	return FormatRules(DefaultRuleName+" if", bodies), nil
+}
+
+// MapFilter converts an IDQL condition AST into Rego rule bodies. The expressions in each body are and'ed, and the
+// bodies are or'ed.
+func (mapper *RegoConditionMapper) MapFilter(ast parser.Expression) ([][]string, error) {
+	m := &mapContext{mapper: mapper}
+	bodies, err := m.mapExpression(ast, "", false)
+	if err != nil {
+		return nil, err
+	}
+	res := make([][]string, len(bodies))
+	for i, b := range bodies {
+		res[i] = b
+	}
+	return res, nil
+}
+
+// FormatRules renders bodies as Rego rules with the rule head provided (e.g. `allow if`)
+func FormatRules(head string, bodies [][]string) string {
+	sb := strings.Builder{}
+	for i, b := range bodies {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(head)
+		sb.WriteString(" {\n")
+		for _, expression := range b {
+			sb.WriteString("    ")
+			sb.WriteString(expression)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+type mapContext struct {
+	mapper   *RegoConditionMapper
+	varCount int
+}
+
+// mapExpression returns the DNF bodies for an expression. scope is the name of a Rego variable used for value path
+// sub-attributes (empty for top level input attributes)
+func (m *mapContext) mapExpression(ast parser.Expression, scope string, negate bool) ([]body, error) {
+	switch element := ast.(type) {
+	case parser.PrecedenceExpression:
+		return m.mapExpression(element.Expression, scope, negate)
+	case parser.NotExpression:
+		return m.mapExpression(element.Expression, scope, !negate)
+	case parser.LogicalExpression:
+		left, err := m.mapExpression(element.Left, scope, negate)
+		if err != nil {
+			return nil, err
+		}
+		right, err := m.mapExpression(element.Right, scope, negate)
+		if err != nil {
+			return nil, err
+		}
+		// De Morgan: not(a and b) is not(a) or not(b)
+		isAnd := element.Operator == parser.AND
+		if negate {
+			isAnd = !isAnd
+		}
+		if !isAnd {
+			return append(left, right...), nil
+		}
+		res := make([]body, 0, len(left)*len(right))
+		for _, l := range left {
+			for _, r := range right {
+				combined := make(body, 0, len(l)+len(r))
+				combined = append(combined, l...)
+				combined = append(combined, r...)
+				res = append(res, combined)
+			}
+		}
+		return res, nil
+	case parser.ValuePathExpression:
+		if negate {
+			return nil, errors.New("negated IDQL value path expressions are not supported in Rego")
+		}
+		return m.mapValuePath(element, scope)
+	case parser.AttributeExpression:
+		expression, err := m.mapAttrExpr(element, scope)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			expression = "not " + expression
+		}
+		return []body{{expression}}, nil
+	}
+	return nil, fmt.Errorf("unsupported IDQL expression: %s", ast.String())
+}
+
+func (m *mapContext) mapValuePath(element parser.ValuePathExpression, scope string) ([]body, error) {
+	variable := fmt.Sprintf("v%d", m.varCount)
+	m.varCount++
+	some := fmt.Sprintf("some %s in %s", variable, m.mapPath(element.Attribute.String(), scope))
+
+	filters, err := m.mapExpression(element.VPathFilter, variable, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var subCompare string
+	if element.SubAttr != nil && element.Operator != nil {
+		subPath, err := types.ParseValue(*element.SubAttr)
+		if err != nil {
+			return nil, err
+		}
+		subCompare, err = m.mapAttrExpr(parser.AttributeExpression{
+			AttributePath: subPath,
+			Operator:      *element.Operator,
+			CompareValue:  element.CompareValue,
+		}, variable)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]body, len(filters))
+	for i, filter := range filters {
+		b := body{some}
+		b = append(b, filter...)
+		if subCompare != "" {
+			b = append(b, subCompare)
+		}
+		res[i] = b
+	}
+	return res, nil
+}
+
+// mapPath maps an IDQL attribute name to a Rego reference. Within a value path, attributes are relative to the scope
+// variable.
+func (m *mapContext) mapPath(path string, scope string) string {
+	if scope != "" {
+		return scope + "." + path
+	}
+	mapped := path
+	if m.mapper.NameMapper != nil {
+		mapped = m.mapper.NameMapper.GetProviderAttributeName(path)
+	}
+	if strings.HasPrefix(mapped, prefixInput) || strings.HasPrefix(mapped, prefixData) {
+		return mapped
+	}
+	return prefixInput + mapped
+}
+
+func (m *mapContext) mapValue(value types.Value, scope string) (string, error) {
+	switch v := value.(type) {
+	case types.String:
+		return strconv.Quote(v.Value().(string)), nil
+	case types.Numeric:
+		return v.String(), nil
+	case types.Boolean:
+		return strings.ToLower(v.String()), nil
+	case types.Date:
+		return fmt.Sprintf("%s(%s)", funcTimeParse, strconv.Quote(v.String())), nil
+	case types.Array:
+		values := v.Value().([]types.ComparableValue)
+		items := make([]string, len(values))
+		for i, item := range values {
+			mapped, err := m.mapValue(item, scope)
+			if err != nil {
+				return "", err
+			}
+			items[i] = mapped
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case types.Entity:
+		if isAttributePath(v) && v.String() == "null" {
+			return "null", nil
+		}
+		if isAttributePath(v) {
+			return m.mapPath(v.String(), scope), nil
+		}
+		return strconv.Quote(v.String()), nil
+	}
+	return "", fmt.Errorf("unsupported value type %s in Rego mapping", types.TypeName(value.ValueType()))
+}
+
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+func (m *mapContext) mapAttrExpr(attrExpr parser.AttributeExpression, scope string) (string, error) {
+	entity, ok := attrExpr.AttributePath.(types.Entity)
+	if !ok || !isAttributePath(entity) {
+		return "", fmt.Errorf("left hand side of '%s' must be an attribute", attrExpr.String())
+	}
+	mapPath := m.mapPath(entity.String(), scope)
+
+	if attrExpr.Operator == parser.PR {
+		return mapPath + " != null", nil
+	}
+
+	compareValue, err := m.mapValue(attrExpr.CompareValue, scope)
+	if err != nil {
+		return "", err
+	}
+	if attrExpr.CompareValue.ValueType() == types.TypeDate {
+		mapPath = fmt.Sprintf("%s(%s)", funcTimeParse, mapPath)
+	}
+
+	switch attrExpr.Operator {
+	case parser.EQ:
+		return mapPath + " == " + compareValue, nil
+	case parser.NE:
+		return mapPath + " != " + compareValue, nil
+	case parser.LT:
+		return mapPath + " < " + compareValue, nil
+	case parser.LE:
+		return mapPath + " <= " + compareValue, nil
+	case parser.GT:
+		return mapPath + " > " + compareValue, nil
+	case parser.GE:
+		return mapPath + " >= " + compareValue, nil
+	case parser.SW:
+		return fmt.Sprintf("startswith(%s, %s)", mapPath, compareValue), nil
+	case parser.EW:
+		return fmt.Sprintf("endswith(%s, %s)", mapPath, compareValue), nil
+	case parser.CO:
+		// consistent with Cedar, contains tests membership of a multi-valued attribute
+		return compareValue + " in " + mapPath, nil
+	case parser.IN:
+		if attrExpr.CompareValue.ValueType() == types.TypeArray || strings.HasPrefix(compareValue, prefixInput) ||
+			strings.HasPrefix(compareValue, prefixData) || (scope != "" && strings.HasPrefix(compareValue, scope+".")) {
+			return mapPath + " in " + compareValue, nil
+		}
+		return "", fmt.Errorf("IDQL entity membership (%s) is not supported in Rego", attrExpr.String())
+	}
+	return "", fmt.Errorf("IDQL operator '%s' is not supported in Rego", attrExpr.Operator)
+}
+
+// MapProviderToCondition parses a Rego expression and converts it to an IDQL condition. The expression may either be a
+// rule body (expressions separated by new lines or semicolons), or one or more rules (e.g. `allow if { ... }`) in
+// which case each rule body is or'ed together. Package, import and default statements are ignored.
+func (mapper *RegoConditionMapper) MapProviderToCondition(expression string) (conditions.ConditionInfo, error) {
+	bodies, err := parseRego(expression)
+	if err != nil {
+		return conditions.ConditionInfo{}, errors.New("Rego Mapping Error: " + err.Error())
+	}
+	ast, err := mapper.mapBodies(bodies)
+	if err != nil {
+		return conditions.ConditionInfo{}, errors.New("IDQL condition mapper error: " + err.Error())
+	}
+
+	return conditions.ConditionInfo{
+		Rule:   conditions.SerializeExpression(ast),
+		Action: conditions.AAllow,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package rego_test
+
+import (
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/models/conditionLangs/rego"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+var mapper = rego.RegoConditionMapper{
+	NameMapper: conditions.NewNameMapper(map[string]string{
+		"a":        "b",
+		"username": "account.userid",
+		"ip":       "input.req.ip",
+	}),
+}
+
+func TestMapToRego(t *testing.T) {
+	tests := []struct {
+		name string
+		idql string
+		rego string
+	}{
+		{"Equals", "username eq \"june\"", "condition if {\n    input.account.userid == \"june\"\n}\n"},
+		{"Prefixed map", "ip eq \"127.0.0.1\"", "condition if {\n    input.req.ip == \"127.0.0.1\"\n}\n"},
+		{"Present", "subject.email pr", "condition if {\n    input.subject.email != null\n}\n"},
+		{"Contains", "subject.roles co \"admin\"", "condition if {\n    \"admin\" in input.subject.roles\n}\n"},
+		{"Numbers", "level gt 5 and level le 10", "condition if {\n    input.level > 5\n    input.level <= 10\n}\n"},
+		{"Starts", "name sw \"J\" and name ew \"z\"", "condition if {\n    startswith(input.name, \"J\")\n    endswith(input.name, \"z\")\n}\n"},
+		{"In", "level in [1, 2]", "condition if {\n    input.level in [1, 2]\n}\n"},
+		{"Null", "a eq null", "condition if {\n    input.b == null\n}\n"},
+		{"Date", "meta.lastModified lt 2011-05-13T04:42:34Z",
+			"condition if {\n    time.parse_rfc3339_ns(input.meta.lastModified) < time.parse_rfc3339_ns(\"2011-05-13T04:42:34Z\")\n}\n"},
+		{"Attribute compare", "resource.owner eq subject.sub", "condition if {\n    input.resource.owner == input.subject.sub\n}\n"},
+		{"Or", "a eq 1 or level ne 2", "condition if {\n    input.b == 1\n}\n\ncondition if {\n    input.level != 2\n}\n"},
+		{"Distribute", "a eq 1 and (c eq 2 or d eq true)",
+			"condition if {\n    input.b == 1\n    input.c == 2\n}\n\ncondition if {\n    input.b == 1\n    input.d == true\n}\n"},
+		{"Not", "not(a eq 1)", "condition if {\n    not input.b == 1\n}\n"},
+		{"Not and", "not(a eq 1 and c eq 2)", "condition if {\n    not input.b == 1\n}\n\ncondition if {\n    not input.c == 2\n}\n"},
+		{"Value path", "emails[type eq \"work\"].value ew \"@example.com\"",
+			"condition if {\n    some v0 in input.emails\n    v0.type == \"work\"\n    endswith(v0.value, \"@example.com\")\n}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regoString, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: test.idql, Action: "allow"})
+			assert.NoError(t, err)
+			assert.Equal(t, test.rego, regoString)
+		})
+	}
+}
+
+func TestMapToRego_Errors(t *testing.T) {
+	for _, idql := range []string{
+		"subject in Group:admins",
+		"resource is Photo",
+		"not(emails[type eq \"work\"] pr)",
+	} {
+		t.Run(idql, func(t *testing.T) {
+			_, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: idql, Action: "allow"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, idql := range []string{
+		"username eq \"june\"",
+		"subject.email pr",
+		"subject.roles co \"admin\"",
+		"level gt 5 and level le 10",
+		"name sw \"J\" and name ew \"z\"",
+		"level in [1,2]",
+		"meta.lastModified lt 2011-05-13T04:42:34Z",
+		"resource.owner eq subject.sub",
+		"a eq 1 or level ne 2",
+		"not(a eq 1)",
+		"emails[type eq \"work\" and value co \"example.com\"] pr",
+	} {
+		t.Run(idql, func(t *testing.T) {
+			regoString, err := mapper.MapConditionToProvider(conditions.ConditionInfo{Rule: idql, Action: "allow"})
+			assert.NoError(t, err)
+			condition, err := mapper.MapProviderToCondition(regoString)
+			assert.NoError(t, err, "Rego:\n"+regoString)
+			expected := conditions.ConditionInfo{Rule: idql, Action: "allow"}
+			assert.True(t, expected.Equals(&condition), "Expected %s, got %s", idql, condition.Rule)
+		})
+	}
+}
+
+func TestMapFromRego(t *testing.T) {
+	tests := []struct {
+		name string
+		rego string
+		idql string
+	}{
+		{"Body", "input.account.userid == \"june\"; input.level >= 3", "username eq \"june\" and level ge 3"},
+		{"Reversed", "5 < input.level", "level gt 5"},
+		{"Iterate", "input.subject.roles[_] == \"admin\"", "subject.roles co \"admin\""},
+		{"Contains func", "contains(input.name, \"smith\")", "name co \"smith\""},
+		{"Set in", "input.level in {1, 2}", "level in [1,2]"},
+		{"Null", "input.subject.email == null", "not(subject.email pr)"},
+		{"Truthy", "input.subject.active", "subject.active eq true"},
+		{"Not truthy", "not input.a.b", "not(a.b eq true)"},
+		{"Indexed", "input.subject[\"email\"] == \"a@b.com\"", "subject.email eq \"a@b.com\""},
+		{"Module", `package hexa
+
+import rego.v1
+
+default allow := false
+
+# Hand written rules
+allow if {
+	input.subject.roles[_] == "admin"
+}
+
+allow if {
+	some email in input.subject.emails
+	email.type == "work"
+	endswith(email.value, "@example.com")
+}
+`, "subject.roles co \"admin\" or subject.emails[type eq \"work\" and value ew \"@example.com\"] pr"},
+		{"Multi body", "allow { input.b == 1 } { input.c == 2 }", "a eq 1 or c eq 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition, err := mapper.MapProviderToCondition(test.rego)
+			assert.NoError(t, err)
+			expected := conditions.ConditionInfo{Rule: test.idql, Action: "allow"}
+			assert.True(t, expected.Equals(&condition), "Expected %s, got %s", test.idql, condition.Rule)
+		})
+	}
+}
+
+func TestMapFromRego_Errors(t *testing.T) {
+	for _, regoString := range []string{
+		"",
+		"x := input.a",
+		"count(input.roles) > 2",
+		"input.a == \"unterminated",
+		"allow if { input.a == 1",
+		"some x in input.emails",
+		"input.a == 1\nallow if { input.c == 2 }",
+	} {
+		t.Run(regoString, func(t *testing.T) {
+			_, err := mapper.MapProviderToCondition(regoString)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,744 @@
+package rego
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+/*
+rego_parser.go parses the subset of Rego generated by RegoConditionMapper (plus common hand-written equivalents such
+as `input.roles[_] == "admin"`) so that Rego rule bodies can be mapped back to IDQL conditions. It is not a general
+Rego parser.
+*/
+
+const (
+	tokIdent = iota
+	tokString
+	tokNumber
+	tokOperator
+	tokPunct
+	tokNewline
+	tokEOF
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			tokens = append(tokens, token{tokNewline, "\n"})
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '"':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+			i++
+			value, err := strconv.Unquote(string(runes[start:i]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", string(runes[start:i]))
+			}
+			tokens = append(tokens, token{tokString, value})
+		case r == '`':
+			start := i + 1
+			i++
+			for i < len(runes) && runes[i] != '`' {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated raw string")
+			}
+			tokens = append(tokens, token{tokString, string(runes[start:i])})
+			i++
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(".eE+-", runes[i])) {
+				if (runes[i] == '+' || runes[i] == '-') && !(runes[i-1] == 'e' || runes[i-1] == 'E') {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_' || (r == '.' && i+1 < len(runes) && unicode.IsLetter(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[start:i])})
+		default:
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				switch pair {
+				case "==", "!=", "<=", ">=", ":=":
+					tokens = append(tokens, token{tokOperator, pair})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '<', '>', '=':
+				tokens = append(tokens, token{tokOperator, string(r)})
+			case '(', ')', '[', ']', '{', '}', ',', ';':
+				tokens = append(tokens, token{tokPunct, string(r)})
+			default:
+				return nil, fmt.Errorf("unexpected character '%c'", r)
+			}
+			i++
+		}
+	}
+	return append(tokens, token{tokEOF, ""}), nil
+}
+
+const (
+	termRef = iota
+	termString
+	termNumber
+	termBool
+	termNull
+	termArray
+	termSet
+	termCall
+)
+
+type regoTerm struct {
+	kind    int
+	text    string     // the reference path, function name or literal value
+	args    []regoTerm // function arguments or collection members
+	iterate bool       // true when a reference ends with [_] (e.g. input.roles[_])
+}
+
+type regoStatement struct {
+	not     bool
+	someVar string // when set, this is `some <someVar> in <left>`
+	left    regoTerm
+	op      string // empty when the statement is a single term (e.g. a function call)
+	right   regoTerm
+}
+
+type regoParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *regoParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *regoParser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *regoParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *regoParser) isPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.text == text
+}
+
+func (p *regoParser) expectPunct(text string) error {
+	tok := p.next()
+	if tok.kind != tokPunct || tok.text != text {
+		return fmt.Errorf("expected '%s' but found '%s'", text, tok.text)
+	}
+	return nil
+}
+
+func (p *regoParser) skipNewlines() {
+	for p.peek().kind == tokNewline || p.isPunct(";") {
+		p.next()
+	}
+}
+
+func (p *regoParser) skipLine() {
+	for p.peek().kind != tokNewline && p.peek().kind != tokEOF {
+		p.next()
+	}
+}
+
+// parseRego returns the rule bodies found in the input. If the input is a bare body, a single body is returned.
+func parseRego(input string) ([][]regoStatement, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &regoParser{tokens: tokens}
+
+	var bodies [][]regoStatement
+	var bare []regoStatement
+	for {
+		p.skipNewlines()
+		tok := p.peek()
+		if tok.kind == tokEOF {
+			break
+		}
+		if tok.kind == tokIdent && (tok.text == "package" || tok.text == "import" || tok.text == "default") {
+			p.skipLine()
+			continue
+		}
+		if p.isRuleStart() {
+			ruleBodies, err := p.parseRule()
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, ruleBodies...)
+			continue
+		}
+		statement, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		bare = append(bare, statement)
+		if next := p.peek(); next.kind != tokNewline && next.kind != tokEOF && !p.isPunct(";") {
+			return nil, fmt.Errorf("unexpected '%s'", next.text)
+		}
+	}
+
+	if len(bare) > 0 {
+		if len(bodies) > 0 {
+			return nil, errors.New("rule bodies and statements may not be mixed")
+		}
+		bodies = append(bodies, bare)
+	}
+	if len(bodies) == 0 {
+		return nil, errors.New("no Rego expressions found")
+	}
+	return bodies, nil
+}
+
+// isRuleStart detects rule heads of the form `name {`, `name if {`, `name := value if {` or `name contains value if {`
+func (p *regoParser) isRuleStart() bool {
+	tok := p.peek()
+	if tok.kind != tokIdent || strings.Contains(tok.text, ".") || tok.text == "not" || tok.text == "some" {
+		return false
+	}
+	next := p.peekAt(1)
+	switch {
+	case next.kind == tokPunct && next.text == "{":
+		return true
+	case next.kind == tokIdent && (next.text == "if" || next.text == "contains"):
+		return true
+	case next.kind == tokOperator && (next.text == ":=" || next.text == "="):
+		return true
+	}
+	return false
+}
+
+func (p *regoParser) parseRule() ([][]regoStatement, error) {
+	p.next() // rule name
+	for !p.isPunct("{") {
+		tok := p.next()
+		if tok.kind == tokEOF || tok.kind == tokNewline {
+			return nil, errors.New("rules without a body are not supported")
+		}
+	}
+
+	var bodies [][]regoStatement
+	for p.isPunct("{") {
+		p.next()
+		var statements []regoStatement
+		for {
+			p.skipNewlines()
+			if p.isPunct("}") {
+				p.next()
+				break
+			}
+			if p.peek().kind == tokEOF {
+				return nil, errors.New("missing '}'")
+			}
+			statement, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, statement)
+			if next := p.peek(); next.kind != tokNewline && !p.isPunct(";") && !p.isPunct("}") {
+				return nil, fmt.Errorf("unexpected '%s'", next.text)
+			}
+		}
+		bodies = append(bodies, statements)
+	}
+	return bodies, nil
+}
+
+func isCompareOperator(tok token) bool {
+	if tok.kind == tokOperator {
+		switch tok.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return true
+		}
+	}
+	return tok.kind == tokIdent && tok.text == "in"
+}
+
+func (p *regoParser) parseStatement() (regoStatement, error) {
+	tok := p.peek()
+	if tok.kind == tokIdent && tok.text == "not" {
+		p.next()
+		statement, err := p.parseStatement()
+		if err != nil {
+			return statement, err
+		}
+		if statement.someVar != "" {
+			return statement, errors.New("'not some' is not supported")
+		}
+		statement.not = !statement.not
+		return statement, nil
+	}
+	if tok.kind == tokIdent && tok.text == "some" {
+		p.next()
+		variable := p.next()
+		if variable.kind != tokIdent {
+			return regoStatement{}, fmt.Errorf("expected a variable after 'some' but found '%s'", variable.text)
+		}
+		in := p.next()
+		if in.kind != tokIdent || in.text != "in" {
+			return regoStatement{}, errors.New("only 'some <var> in <collection>' is supported")
+		}
+		collection, err := p.parseTerm()
+		if err != nil {
+			return regoStatement{}, err
+		}
+		if collection.kind != termRef {
+			return regoStatement{}, errors.New("'some' must iterate over an attribute")
+		}
+		return regoStatement{someVar: variable.text, left: collection}, nil
+	}
+
+	left, err := p.parseTerm()
+	if err != nil {
+		return regoStatement{}, err
+	}
+	opTok := p.peek()
+	if opTok.kind == tokOperator && (opTok.text == ":=" || opTok.text == "=") {
+		return regoStatement{}, errors.New("assignment is not supported")
+	}
+	if !isCompareOperator(opTok) {
+		return regoStatement{left: left}, nil
+	}
+	p.next()
+	right, err := p.parseTerm()
+	if err != nil {
+		return regoStatement{}, err
+	}
+	return regoStatement{left: left, op: opTok.text, right: right}, nil
+}
+
+func (p *regoParser) parseTerm() (regoTerm, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return regoTerm{kind: termString, text: tok.text}, nil
+	case tokNumber:
+		return regoTerm{kind: termNumber, text: tok.text}, nil
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			term, err := p.parseTerm()
+			if err != nil {
+				return term, err
+			}
+			return term, p.expectPunct(")")
+		case "[":
+			items, err := p.parseItems("]")
+			return regoTerm{kind: termArray, args: items}, err
+		case "{":
+			items, err := p.parseItems("}")
+			return regoTerm{kind: termSet, args: items}, err
+		}
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return regoTerm{kind: termBool, text: tok.text}, nil
+		case "null":
+			return regoTerm{kind: termNull, text: tok.text}, nil
+		}
+		if p.isPunct("(") {
+			p.next()
+			args, err := p.parseItems(")")
+			return regoTerm{kind: termCall, text: tok.text, args: args}, err
+		}
+		term := regoTerm{kind: termRef, text: tok.text}
+		for p.isPunct("[") {
+			p.next()
+			index := p.next()
+			switch {
+			case index.kind == tokIdent && index.text == "_":
+				term.iterate = true
+			case index.kind == tokString || index.kind == tokNumber:
+				term.text = term.text + "." + index.text
+			default:
+				return term, fmt.Errorf("unsupported reference index '%s'", index.text)
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return term, err
+			}
+			if !term.iterate && p.peek().kind == tokIdent && strings.HasPrefix(p.peek().text, ".") {
+				term.text = term.text + p.next().text
+			}
+		}
+		return term, nil
+	}
+	return regoTerm{}, fmt.Errorf("unexpected '%s'", tok.text)
+}
+
+func (p *regoParser) parseItems(closing string) ([]regoTerm, error) {
+	var items []regoTerm
+	for {
+		for p.peek().kind == tokNewline {
+			p.next()
+		}
+		if p.isPunct(closing) {
+			p.next()
+			return items, nil
+		}
+		item, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		for p.peek().kind == tokNewline {
+			p.next()
+		}
+		if p.isPunct(",") {
+			p.next()
+			continue
+		}
+		if !p.isPunct(closing) {
+			return nil, fmt.Errorf("expected '%s' but found '%s'", closing, p.peek().text)
+		}
+	}
+}
+
+// mapBodies converts parsed Rego bodies into an IDQL expression where bodies are or'ed together
+func (mapper *RegoConditionMapper) mapBodies(bodies [][]regoStatement) (parser.Expression, error) {
+	var res parser.Expression
+	for _, statements := range bodies {
+		expression, err := mapper.mapBody(statements)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = expression
+			continue
+		}
+		res = parser.LogicalExpression{Operator: parser.OR, Left: res, Right: expression}
+	}
+	return res, nil
+}
+
+func andExpressions(expressions []parser.Expression) parser.Expression {
+	var res parser.Expression
+	for _, expression := range expressions {
+		if res == nil {
+			res = expression
+			continue
+		}
+		res = parser.LogicalExpression{Operator: parser.AND, Left: res, Right: expression}
+	}
+	return res
+}
+
+func refersTo(term regoTerm, variable string) bool {
+	if term.kind == termRef && (term.text == variable || strings.HasPrefix(term.text, variable+".")) {
+		return true
+	}
+	for _, arg := range term.args {
+		if refersTo(arg, variable) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mapper *RegoConditionMapper) mapBody(statements []regoStatement) (parser.Expression, error) {
+	var expressions []parser.Expression
+	used := make([]bool, len(statements))
+	for i, statement := range statements {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		if statement.someVar == "" {
+			expression, err := mapper.mapStatement(statement, "")
+			if err != nil {
+				return nil, err
+			}
+			expressions = append(expressions, expression)
+			continue
+		}
+
+		// statements referring to the iteration variable form the value path filter
+		var filters []parser.Expression
+		for j := i + 1; j < len(statements); j++ {
+			if used[j] || !(refersTo(statements[j].left, statement.someVar) || refersTo(statements[j].right, statement.someVar)) {
+				continue
+			}
+			used[j] = true
+			filter, err := mapper.mapStatement(statements[j], statement.someVar)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+		if len(filters) == 0 {
+			return nil, fmt.Errorf("variable %s is not used", statement.someVar)
+		}
+		present := parser.PR
+		expressions = append(expressions, parser.ValuePathExpression{
+			Attribute:   *types.ParseEntity(mapper.hexaPath(statement.left.text, "")),
+			VPathFilter: andExpressions(filters),
+			Operator:    &present,
+		})
+	}
+	if len(expressions) == 0 {
+		return nil, errors.New("empty rule body")
+	}
+	return andExpressions(expressions), nil
+}
+
+// hexaPath converts a Rego reference to an IDQL attribute name
+func (mapper *RegoConditionMapper) hexaPath(ref string, scope string) string {
+	if scope != "" && strings.HasPrefix(ref, scope+".") {
+		return ref[len(scope)+1:]
+	}
+	if mapper.NameMapper != nil {
+		if mapped := mapper.NameMapper.GetHexaFilterAttributePath(ref); mapped != ref {
+			return mapped
+		}
+	}
+	path := strings.TrimPrefix(ref, prefixInput)
+	if mapper.NameMapper != nil {
+		return mapper.NameMapper.GetHexaFilterAttributePath(path)
+	}
+	return path
+}
+
+func newString(value string) types.ComparableValue {
+	if strings.HasPrefix(value, "\"") {
+		// types.NewString removes surrounding quotes
+		return types.NewString("\"" + value + "\"")
+	}
+	return types.NewString(value)
+}
+
+func (mapper *RegoConditionMapper) mapValue(term regoTerm, scope string, isDate bool) (types.Value, error) {
+	switch term.kind {
+	case termString:
+		if isDate {
+			return types.NewDate(term.text)
+		}
+		return newString(term.text), nil
+	case termNumber:
+		return types.NewNumeric(term.text)
+	case termBool:
+		return types.NewBoolean(term.text), nil
+	case termRef:
+		if term.iterate {
+			return nil, fmt.Errorf("iteration of %s[_] is only supported as the left hand side of a comparison", term.text)
+		}
+		return *types.ParseEntity(mapper.hexaPath(term.text, scope)), nil
+	case termArray, termSet:
+		values := make([]types.ComparableValue, len(term.args))
+		for i, arg := range term.args {
+			value, err := mapper.mapValue(arg, scope, isDate)
+			if err != nil {
+				return nil, err
+			}
+			comparable, ok := value.(types.ComparableValue)
+			if !ok || arg.kind == termRef {
+				return nil, errors.New("collections may only contain literal values")
+			}
+			values[i] = comparable
+		}
+		return types.NewArray(values), nil
+	}
+	return nil, fmt.Errorf("unsupported Rego value: %s", term.text)
+}
+
+// unwrapTime removes time.parse_rfc3339_ns() used to compare dates
+func unwrapTime(term regoTerm) (regoTerm, bool) {
+	if term.kind == termCall && term.text == funcTimeParse && len(term.args) == 1 {
+		return term.args[0], true
+	}
+	return term, false
+}
+
+var reverseOperator = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "==": "==", "!=": "!="}
+
+var compareOperators = map[string]parser.CompareOperator{
+	"==": parser.EQ, "!=": parser.NE, "<": parser.LT, "<=": parser.LE, ">": parser.GT, ">=": parser.GE,
+}
+
+func (mapper *RegoConditionMapper) mapStatement(statement regoStatement, scope string) (parser.Expression, error) {
+	expression, err := mapper.mapComparison(statement, scope)
+	if err != nil {
+		return nil, err
+	}
+	if statement.not {
+		return parser.NotExpression{Expression: expression}, nil
+	}
+	return expression, nil
+}
+
+func (mapper *RegoConditionMapper) attribute(term regoTerm, scope string) (types.Value, error) {
+	if term.kind != termRef {
+		return nil, fmt.Errorf("expected an attribute but found '%s'", term.text)
+	}
+	return *types.ParseEntity(mapper.hexaPath(term.text, scope)), nil
+}
+
+func (mapper *RegoConditionMapper) mapComparison(statement regoStatement, scope string) (parser.Expression, error) {
+	left := statement.left
+	right := statement.right
+
+	if statement.op == "" {
+		switch {
+		case left.kind == termRef && !left.iterate:
+			// a bare reference is only true when the value is true (false and undefined are both false)
+			attr, err := mapper.attribute(left, scope)
+			if err != nil {
+				return nil, err
+			}
+			return parser.AttributeExpression{AttributePath: attr, Operator: parser.EQ, CompareValue: types.NewBoolean("true")}, nil
+		case left.kind == termCall && len(left.args) == 2:
+			var op parser.CompareOperator
+			switch left.text {
+			case "startswith":
+				op = parser.SW
+			case "endswith":
+				op = parser.EW
+			case "contains":
+				op = parser.CO
+			default:
+				return nil, fmt.Errorf("unsupported Rego function: %s", left.text)
+			}
+			attr, err := mapper.attribute(left.args[0], scope)
+			if err != nil {
+				return nil, err
+			}
+			value, err := mapper.mapValue(left.args[1], scope, false)
+			if err != nil {
+				return nil, err
+			}
+			return parser.AttributeExpression{AttributePath: attr, Operator: op, CompareValue: value}, nil
+		}
+		return nil, fmt.Errorf("unsupported Rego expression: %s", left.text)
+	}
+
+	if statement.op == "in" {
+		switch {
+		case right.kind == termArray || right.kind == termSet:
+			attr, err := mapper.attribute(left, scope)
+			if err != nil {
+				return nil, err
+			}
+			value, err := mapper.mapValue(right, scope, false)
+			if err != nil {
+				return nil, err
+			}
+			return parser.AttributeExpression{AttributePath: attr, Operator: parser.IN, CompareValue: value}, nil
+		case right.kind == termRef && left.kind == termRef:
+			attr, err := mapper.attribute(left, scope)
+			if err != nil {
+				return nil, err
+			}
+			value, err := mapper.mapValue(right, scope, false)
+			if err != nil {
+				return nil, err
+			}
+			return parser.AttributeExpression{AttributePath: attr, Operator: parser.IN, CompareValue: value}, nil
+		case right.kind == termRef:
+			// "admin" in input.roles
+			attr, err := mapper.attribute(right, scope)
+			if err != nil {
+				return nil, err
+			}
+			value, err := mapper.mapValue(left, scope, false)
+			if err != nil {
+				return nil, err
+			}
+			return parser.AttributeExpression{AttributePath: attr, Operator: parser.CO, CompareValue: value}, nil
+		}
+		return nil, errors.New("unsupported use of 'in'")
+	}
+
+	op := statement.op
+	left, leftDate := unwrapTime(left)
+	right, rightDate := unwrapTime(right)
+	if left.kind != termRef && right.kind == termRef {
+		left, right = right, left
+		op = reverseOperator[op]
+	}
+	if left.kind != termRef {
+		return nil, fmt.Errorf("comparison must include an attribute: %s %s %s", statement.left.text, statement.op, statement.right.text)
+	}
+
+	if right.kind == termNull {
+		attr, err := mapper.attribute(left, scope)
+		if err != nil {
+			return nil, err
+		}
+		present := parser.AttributeExpression{AttributePath: attr, Operator: parser.PR}
+		switch op {
+		case "!=":
+			return present, nil
+		case "==":
+			return parser.NotExpression{Expression: present}, nil
+		}
+		return nil, errors.New("null may only be compared using == or !=")
+	}
+
+	compareOp := compareOperators[op]
+	if left.iterate {
+		if op != "==" {
+			return nil, fmt.Errorf("iteration of %s[_] is only supported with ==", left.text)
+		}
+		compareOp = parser.CO
+		left.iterate = false
+	}
+	attr, err := mapper.attribute(left, scope)
+	if err != nil {
+		return nil, err
+	}
+	value, err := mapper.mapValue(right, scope, leftDate || rightDate)
+	if err != nil {
+		return nil, err
+	}
+	return parser.AttributeExpression{AttributePath: attr, Operator: compareOp, CompareValue: value}, nil
+}
@@ -0,0 +1,459 @@
+package awsIam
+
+/*
+ Maps IDQL policies to and from AWS IAM JSON policy documents - See:
+ https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements.html
+
+ Each IDQL policy becomes one or more IAM statements:
+   - Effect is Deny when the IDQL condition action is deny, otherwise Allow
+   - Principal is mapped from subjects ("any" is "*", "anyAuthenticated" is {"AWS": "*"}, ARNs and
+     "aws:", "service:", "federated:" and "canonicalUser:" prefixed members map to the corresponding principal type).
+     A policy with no subjects has no Principal (e.g. an identity policy).
+   - Action holds the IDQL actions (e.g. s3:GetObject), or "*" when there are none
+   - Resource holds the IDQL object, or "*" when there is none
+   - Condition is mapped by iamConditions. An IDQL condition containing an "or" produces a statement per alternative.
+     The additional statements have the Sid of the first followed by "Or" and their position (e.g. Rule1, Rule1Or2).
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hexa-org/policy-mapper/models/conditionLangs/iamConditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+)
+
+const (
+	PolicyVersion = "2012-10-17"
+
+	EffectAllow = "Allow"
+	EffectDeny  = "Deny"
+
+	PrincipalAws           = "AWS"
+	PrincipalService       = "Service"
+	PrincipalFederated     = "Federated"
+	PrincipalCanonicalUser = "CanonicalUser"
+
+	wildcard        = "*"
+	sidContinuation = "Or" // sidContinuation separates the Sid of a policy from the position of further statements
+)
+
+// principalPrefixes maps IDQL subject prefixes to IAM principal types
+var principalPrefixes = [][2]string{
+	{"aws:", PrincipalAws},
+	{"service:", PrincipalService},
+	{"federated:", PrincipalFederated},
+	{"canonicalUser:", PrincipalCanonicalUser},
+}
+
+// StringList is an IAM element that may either be a single string or an array of strings
+type StringList []string
+
+func (s *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+func (s StringList) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+// Principal maps IAM principal types (e.g. AWS, Service) to values. The anonymous principal "*" is held as {"*": ["*"]}.
+type Principal map[string]StringList
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single != wildcard {
+			return fmt.Errorf("invalid IAM principal: %s", single)
+		}
+		*p = Principal{wildcard: {wildcard}}
+		return nil
+	}
+	var principals map[string]StringList
+	if err := json.Unmarshal(data, &principals); err != nil {
+		return err
+	}
+	*p = principals
+	return nil
+}
+
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if _, anonymous := p[wildcard]; anonymous {
+		return json.Marshal(wildcard)
+	}
+	return json.Marshal(map[string]StringList(p))
+}
+
+// Statements accepts either a single statement object or an array of statements
+type Statements []Statement
+
+func (s *Statements) UnmarshalJSON(data []byte) error {
+	var list []Statement
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var single Statement
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*s = Statements{single}
+	return nil
+}
+
+type Statement struct {
+	Sid          string                  `json:"Sid,omitempty"`
+	Effect       string                  `json:"Effect"`
+	Principal    Principal               `json:"Principal,omitempty"`
+	NotPrincipal Principal               `json:"NotPrincipal,omitempty"`
+	Action       StringList              `json:"Action,omitempty"`
+	NotAction    StringList              `json:"NotAction,omitempty"`
+	Resource     StringList              `json:"Resource,omitempty"`
+	NotResource  StringList              `json:"NotResource,omitempty"`
+	Condition    iamConditions.Condition `json:"Condition,omitempty"`
+}
+
+type PolicyDocument struct {
+	Version   string     `json:"Version"`
+	Id        string     `json:"Id,omitempty"`
+	Statement Statements `json:"Statement"`
+}
+
+func ParsePolicyDocument(policyBytes []byte) (*PolicyDocument, error) {
+	var doc PolicyDocument
+	if err := json.Unmarshal(policyBytes, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func ParseFile(path string) (*PolicyDocument, error) {
+	policyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicyDocument(policyBytes)
+}
+
+type IamMapper struct {
+	condMap *iamConditions.IamConditionMapper
+}
+
+func NewIamMapper(attrNameMap map[string]string) *IamMapper {
+	return &IamMapper{condMap: &iamConditions.IamConditionMapper{NameMapper: conditions.NewNameMapper(attrNameMap)}}
+}
+
+// MapPoliciesToIam converts IDQL policies into an IAM policy document
+func (m *IamMapper) MapPoliciesToIam(policies []hexapolicy.PolicyInfo) (*PolicyDocument, error) {
+	doc := PolicyDocument{Version: PolicyVersion, Statement: Statements{}}
+	for i, policy := range policies {
+		statements, err := m.MapPolicyToStatements(policy, i)
+		if err != nil {
+			return nil, err
+		}
+		doc.Statement = append(doc.Statement, statements...)
+	}
+	return &doc, nil
+}
+
+// MapPolicyToStatements converts a single IDQL policy into IAM statements. Index is used to generate a statement id
+// when the policy has no policy id.
+func (m *IamMapper) MapPolicyToStatements(policy hexapolicy.PolicyInfo, index int) ([]Statement, error) {
+	sid := fmt.Sprintf("Statement%d", index)
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		sid = *policy.Meta.PolicyId
+	}
+	sid = sanitizeSid(sid)
+
+	principal, err := mapSubjects(policy.Subjects)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %s", sid, err.Error())
+	}
+
+	statement := Statement{
+		Sid:       sid,
+		Effect:    EffectAllow,
+		Principal: principal,
+		Action:    StringList{wildcard},
+		Resource:  StringList{wildcard},
+	}
+	if len(policy.Actions) > 0 {
+		statement.Action = make(StringList, len(policy.Actions))
+		for i, action := range policy.Actions {
+			statement.Action[i] = action.String()
+		}
+	}
+	if object := policy.Object.String(); object != "" {
+		statement.Resource = StringList{object}
+	}
+
+	if policy.Condition == nil {
+		return []Statement{statement}, nil
+	}
+	if strings.EqualFold(policy.Condition.Action, conditions.ADeny) {
+		statement.Effect = EffectDeny
+	}
+	iamConds, err := m.condMap.MapConditionToProvider(*policy.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %s", sid, err.Error())
+	}
+	statements := make([]Statement, len(iamConds))
+	for i, iamCond := range iamConds {
+		statements[i] = statement
+		statements[i].Condition = iamCond
+		if i > 0 {
+			// Sid values must be unique within a policy document (and are limited to letters and digits)
+			statements[i].Sid = continuationSid(sid, i+1)
+		}
+	}
+	return statements, nil
+}
+
+// continuationSid returns the Sid of an additional statement for the alternative at position (from 2) of a condition
+func continuationSid(sid string, position int) string {
+	return sid + sidContinuation + strconv.Itoa(position)
+}
+
+// sanitizeSid removes characters that are not permitted in an IAM statement id
+func sanitizeSid(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, id)
+}
+
+func mapSubjects(subjects hexapolicy.SubjectInfo) (Principal, error) {
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+	principal := Principal{}
+	for _, subject := range subjects {
+		switch {
+		case strings.EqualFold(subject, hexapolicy.SubjectAnyUser):
+			return Principal{wildcard: {wildcard}}, nil
+		case strings.EqualFold(subject, hexapolicy.SubjectAnyAuth):
+			principal[PrincipalAws] = append(principal[PrincipalAws], wildcard)
+		case strings.HasPrefix(subject, "arn:"):
+			principal[PrincipalAws] = append(principal[PrincipalAws], subject)
+		default:
+			mapped := false
+			for _, prefix := range principalPrefixes {
+				if len(subject) > len(prefix[0]) && strings.EqualFold(subject[:len(prefix[0])], prefix[0]) {
+					principal[prefix[1]] = append(principal[prefix[1]], subject[len(prefix[0]):])
+					mapped = true
+					break
+				}
+			}
+			if !mapped {
+				return nil, fmt.Errorf("subject %s cannot be mapped to an IAM principal", subject)
+			}
+		}
+	}
+	return principal, nil
+}
+
+// MapIamToPolicies converts an IAM policy document into IDQL policies. A statement with multiple resources is mapped
+// into a policy per resource. Consecutive statements generated from a single IDQL policy (Sid, SidOr2, SidOr3, ...)
+// are merged by or'ing their conditions.
+func (m *IamMapper) MapIamToPolicies(doc *PolicyDocument) ([]hexapolicy.PolicyInfo, error) {
+	var policies []hexapolicy.PolicyInfo
+	var previous *Statement
+	position := 1
+	for i, statement := range doc.Statement {
+		if previous != nil && isContinuation(*previous, statement, position+1) {
+			if err := m.mergeCondition(&policies[len(policies)-1], statement); err != nil {
+				return nil, err
+			}
+			position++
+			continue
+		}
+		position = 1
+		statementPolicies, err := m.MapStatementToPolicies(statement, i)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, statementPolicies...)
+		if len(statementPolicies) == 1 {
+			previous = &doc.Statement[i]
+		} else {
+			previous = nil
+		}
+	}
+	return policies, nil
+}
+
+// isContinuation returns true if statement is the alternative at position of the condition of the first statement
+func isContinuation(first Statement, statement Statement, position int) bool {
+	if first.Sid == "" || first.Condition == nil || statement.Condition == nil ||
+		statement.Sid != continuationSid(first.Sid, position) || first.Effect != statement.Effect {
+		return false
+	}
+	firstBytes, _ := json.Marshal([]interface{}{first.Principal, first.Action, first.Resource})
+	statementBytes, _ := json.Marshal([]interface{}{statement.Principal, statement.Action, statement.Resource})
+	return string(firstBytes) == string(statementBytes)
+}
+
+func (m *IamMapper) mergeCondition(policy *hexapolicy.PolicyInfo, statement Statement) error {
+	condition, err := m.condMap.MapProviderToCondition(statement.Condition)
+	if err != nil {
+		return fmt.Errorf("statement %s: %s", statement.Sid, err.Error())
+	}
+	ast, err := conditions.ParseExpressionAst(fmt.Sprintf("(%s) or (%s)", policy.Condition.Rule, condition.Rule))
+	if err != nil {
+		return err
+	}
+	policy.Condition.Rule = conditions.SerializeExpression(ast)
+	return nil
+}
+
+// MapStatementToPolicies converts an IAM statement into IDQL policies (one per resource). Index is used to generate a
+// policy id when the statement has no Sid.
+func (m *IamMapper) MapStatementToPolicies(statement Statement, index int) ([]hexapolicy.PolicyInfo, error) {
+	id := statement.Sid
+	if id == "" {
+		id = fmt.Sprintf("Statement%d", index)
+	}
+	if statement.NotPrincipal != nil || statement.NotAction != nil || statement.NotResource != nil {
+		return nil, fmt.Errorf("statement %s: NotPrincipal, NotAction and NotResource are not supported", id)
+	}
+
+	var condition *conditions.ConditionInfo
+	if len(statement.Condition) > 0 {
+		cond, err := m.condMap.MapProviderToCondition(statement.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("statement %s: %s", id, err.Error())
+		}
+		condition = &cond
+	}
+	switch {
+	case strings.EqualFold(statement.Effect, EffectDeny):
+		if condition == nil {
+			return nil, fmt.Errorf("statement %s: unconditional Deny statements cannot be represented in IDQL", id)
+		}
+		condition.Action = conditions.ADeny
+	case !strings.EqualFold(statement.Effect, EffectAllow):
+		return nil, fmt.Errorf("statement %s: invalid Effect %s", id, statement.Effect)
+	}
+
+	var actions []hexapolicy.ActionInfo
+	for _, action := range statement.Action {
+		if action == wildcard {
+			actions = nil
+			break
+		}
+		actions = append(actions, hexapolicy.ActionInfo(action))
+	}
+
+	subjects := mapPrincipal(statement.Principal)
+
+	resources := statement.Resource
+	if len(resources) == 0 || slicesContainWildcard(resources) {
+		resources = StringList{""}
+	}
+	policies := make([]hexapolicy.PolicyInfo, len(resources))
+	for i, resource := range resources {
+		policyId := id
+		if len(resources) > 1 {
+			policyId = fmt.Sprintf("%s-%d", id, i+1)
+		}
+		policy := hexapolicy.PolicyInfo{
+			Meta: hexapolicy.MetaInfo{
+				Version:  hexapolicy.IdqlVersion,
+				PolicyId: &policyId,
+			},
+			Subjects: subjects,
+			Actions:  actions,
+			Object:   hexapolicy.ObjectInfo(resource),
+		}
+		if condition != nil {
+			policyCondition := *condition
+			policy.Condition = &policyCondition
+		}
+		policies[i] = policy
+	}
+	return policies, nil
+}
+
+func slicesContainWildcard(values StringList) bool {
+	for _, value := range values {
+		if value == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+func mapPrincipal(principal Principal) hexapolicy.SubjectInfo {
+	if principal == nil {
+		return hexapolicy.SubjectInfo{}
+	}
+	if _, anonymous := principal[wildcard]; anonymous {
+		return hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}
+	}
+	principalTypes := make([]string, 0, len(principal))
+	for principalType := range principal {
+		principalTypes = append(principalTypes, principalType)
+	}
+	sort.Strings(principalTypes)
+
+	var subjects hexapolicy.SubjectInfo
+	for _, principalType := range principalTypes {
+		prefix := ""
+		for _, item := range principalPrefixes {
+			if strings.EqualFold(item[1], principalType) {
+				prefix = item[0]
+			}
+		}
+		for _, value := range principal[principalType] {
+			switch {
+			case principalType == PrincipalAws && value == wildcard:
+				subjects = append(subjects, hexapolicy.SubjectAnyAuth)
+			case principalType == PrincipalAws && strings.HasPrefix(value, "arn:"):
+				subjects = append(subjects, value)
+			case prefix == "":
+				subjects = append(subjects, principalType+":"+value)
+			default:
+				subjects = append(subjects, prefix+value)
+			}
+		}
+	}
+	return subjects
+}
+
+// MapIamPolicyBytes parses an IAM policy document and converts it into IDQL policies
+func (m *IamMapper) MapIamPolicyBytes(policyBytes []byte) (*hexapolicy.Policies, error) {
+	doc, err := ParsePolicyDocument(policyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Statement) == 0 {
+		return nil, errors.New("IAM policy document has no statements")
+	}
+	policies, err := m.MapIamToPolicies(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &hexapolicy.Policies{Policies: policies}, nil
+}
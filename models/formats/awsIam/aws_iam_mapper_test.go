@@ -0,0 +1,136 @@
+package awsIam
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func TestMapIamToPolicies(t *testing.T) {
+	doc, err := ParseFile(filepath.Join(getExampleDir(), "policyExamples", "example_iam.json"))
+	assert.NoError(t, err)
+	assert.Len(t, doc.Statement, 2)
+
+	mapper := NewIamMapper(map[string]string{})
+	policies, err := mapper.MapIamToPolicies(doc)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 3, "deny statement has 2 resources")
+
+	read := policies[0]
+	assert.Equal(t, "ReadReports", *read.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{"arn:aws:iam::123456789012:role/analyst", "arn:aws:iam::123456789012:user/alice"}, read.Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"s3:GetObject", "s3:ListBucket"}, read.Actions)
+	assert.Equal(t, "arn:aws:s3:::reports/*", read.Object.String())
+	assert.Equal(t, "req.time lt 2030-01-01T00:00:00Z and req.ip eq \"192.0.2.0/24\"", read.Condition.Rule)
+	assert.Equal(t, conditions.AAllow, read.Condition.Action)
+
+	deny := policies[2]
+	assert.Equal(t, "DenyInsecure-2", *deny.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}, deny.Subjects)
+	assert.Equal(t, "req.secure eq false", deny.Condition.Rule)
+	assert.Equal(t, conditions.ADeny, deny.Condition.Action)
+}
+
+func TestMapPoliciesToIam(t *testing.T) {
+	policyId := "Put:Todo"
+	policies := []hexapolicy.PolicyInfo{
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &policyId},
+			Subjects: []string{"anyAuthenticated", "service:lambda.amazonaws.com"},
+			Actions:  []hexapolicy.ActionInfo{"dynamodb:PutItem"},
+			Object:   "arn:aws:dynamodb:us-east-1:123456789012:table/Todos",
+			Condition: &conditions.ConditionInfo{
+				Rule:   "resource.tags.owner eq subject.sub or subject.tags.role eq \"admin\"",
+				Action: conditions.AAllow,
+			},
+		},
+		{
+			Actions: []hexapolicy.ActionInfo{"s3:GetObject"},
+		},
+	}
+	mapper := NewIamMapper(map[string]string{})
+	doc, err := mapper.MapPoliciesToIam(policies)
+	assert.NoError(t, err)
+	docBytes, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {"Sid": "PutTodo", "Effect": "Allow", "Principal": {"AWS": "*", "Service": "lambda.amazonaws.com"},
+     "Action": "dynamodb:PutItem", "Resource": "arn:aws:dynamodb:us-east-1:123456789012:table/Todos",
+     "Condition": {"StringEquals": {"aws:ResourceTag/owner": "${aws:username}"}}},
+    {"Sid": "PutTodoOr2", "Effect": "Allow", "Principal": {"AWS": "*", "Service": "lambda.amazonaws.com"},
+     "Action": "dynamodb:PutItem", "Resource": "arn:aws:dynamodb:us-east-1:123456789012:table/Todos",
+     "Condition": {"StringEquals": {"aws:PrincipalTag/role": "admin"}}},
+    {"Sid": "Statement1", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}
+  ]
+}`, string(docBytes))
+
+	roundTrip, err := mapper.MapIamPolicyBytes(docBytes)
+	assert.NoError(t, err)
+	assert.Len(t, roundTrip.Policies, 2, "statements for the same policy are merged")
+	merged := roundTrip.Policies[0]
+	assert.Equal(t, "PutTodo", *merged.Meta.PolicyId)
+	assert.True(t, policies[0].Subjects.Equals(merged.Subjects))
+	assert.True(t, policies[0].Condition.Equals(merged.Condition), "got %s", merged.Condition.Rule)
+	assert.Empty(t, roundTrip.Policies[1].Subjects, "identity policy has no principal")
+	assert.Equal(t, "", roundTrip.Policies[1].Object.String())
+}
+
+func TestMapIamToPolicies_SidPrefix(t *testing.T) {
+	mapper := NewIamMapper(map[string]string{})
+	policies, err := mapper.MapIamPolicyBytes([]byte(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {"Sid": "Rule1", "Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "s3:GetObject", "Resource": "*",
+     "Condition": {"Bool": {"aws:SecureTransport": "true"}}},
+    {"Sid": "Rule12", "Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "s3:GetObject", "Resource": "*",
+     "Condition": {"StringEquals": {"aws:PrincipalTag/role": "admin"}}},
+    {"Sid": "Rule1Or3", "Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "s3:GetObject", "Resource": "*",
+     "Condition": {"StringEquals": {"aws:PrincipalTag/role": "owner"}}}
+  ]
+}`))
+	assert.NoError(t, err)
+	assert.Len(t, policies.Policies, 3, "statements are only merged with the next continuation Sid")
+	assert.Equal(t, "Rule1", *policies.Policies[0].Meta.PolicyId)
+	assert.Equal(t, "Rule12", *policies.Policies[1].Meta.PolicyId)
+	assert.Equal(t, "Rule1Or3", *policies.Policies[2].Meta.PolicyId)
+}
+
+func TestMapPoliciesToIam_Errors(t *testing.T) {
+	mapper := NewIamMapper(map[string]string{})
+	_, err := mapper.MapPoliciesToIam([]hexapolicy.PolicyInfo{{Subjects: []string{"User:alice"}}})
+	assert.Error(t, err, "subject has no IAM principal type")
+
+	_, err = mapper.MapPoliciesToIam([]hexapolicy.PolicyInfo{{
+		Condition: &conditions.ConditionInfo{Rule: "subject in Group:admins", Action: conditions.AAllow},
+	}})
+	assert.Error(t, err)
+}
+
+func TestMapIamPolicyBytes_Errors(t *testing.T) {
+	mapper := NewIamMapper(map[string]string{})
+	for _, doc := range []string{
+		`{bad json`,
+		`{"Version": "2012-10-17", "Statement": []}`,
+		`{"Version": "2012-10-17", "Statement": {"Effect": "Deny", "Action": "*", "Resource": "*"}}`,
+		`{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "NotAction": "s3:*", "Resource": "*"}}`,
+		`{"Version": "2012-10-17", "Statement": {"Effect": "Maybe", "Action": "*"}}`,
+		`{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Principal": "bob", "Action": "*"}}`,
+	} {
+		t.Run(doc, func(t *testing.T) {
+			_, err := mapper.MapIamPolicyBytes([]byte(doc))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,574 @@
+package casbin
+
+/*
+ Casbin conditions are govaluate expressions evaluated by the `eval(p.cond)` matcher of ModelConf. IDQL attributes are
+ mapped to request context references (r.ctx.<name>) where, unless mapped by the NameMapper, the name is the IDQL
+ attribute path (i.e. the request context has the same structure as the IDQL input). Comparisons map to the govaluate
+ operators, sw, ew and co use the Casbin regexMatch function and in uses the govaluate IN operator. For example:
+
+    subject.level gt 5 and (resource.owner eq subject.sub or subject.email ew "@example.com")
+
+ becomes:
+
+    r.ctx.subject.level > 5 && (r.ctx.resource.owner == r.ctx.subject.sub || regexMatch(r.ctx.subject.email, "@example\\.com$"))
+
+ When importing, r.sub.<name> and r.obj.<name> (the Casbin ABAC style) are also accepted as subject and resource
+ attributes.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	prefixContext  = "r.ctx."
+	prefixSubject  = "r.sub."
+	prefixObject   = "r.obj."
+	funcRegexMatch = "regexMatch"
+	funcKeyMatch   = "keyMatch"
+)
+
+var compareOps = map[parser.CompareOperator]string{
+	parser.EQ: "==",
+	parser.NE: "!=",
+	parser.GT: ">",
+	parser.GE: ">=",
+	parser.LT: "<",
+	parser.LE: "<=",
+}
+
+// MapConditionToCasbin maps an IDQL condition into a Casbin (govaluate) expression. The condition action is mapped
+// separately as the policy effect.
+func (m *CasbinMapper) MapConditionToCasbin(condition conditions.ConditionInfo) (string, error) {
+	ast, err := conditions.ParseConditionRuleAst(condition)
+	if err != nil {
+		return "", err
+	}
+	return m.mapExpression(ast)
+}
+
+func (m *CasbinMapper) mapExpression(ast parser.Expression) (string, error) {
+	switch element := ast.(type) {
+	case parser.PrecedenceExpression:
+		return m.mapExpression(element.Expression)
+	case parser.NotExpression:
+		expression, err := m.mapExpression(element.Expression)
+		if err != nil {
+			return "", err
+		}
+		return "!(" + expression + ")", nil
+	case parser.LogicalExpression:
+		op := "&&"
+		if element.Operator == parser.OR {
+			op = "||"
+		}
+		sides := make([]string, 2)
+		for i, side := range []parser.Expression{element.Left, element.Right} {
+			expression, err := m.mapExpression(side)
+			if err != nil {
+				return "", err
+			}
+			if precedence, ok := side.(parser.PrecedenceExpression); ok {
+				side = precedence.Expression
+			}
+			if child, ok := side.(parser.LogicalExpression); ok && child.Operator != element.Operator {
+				expression = "(" + expression + ")"
+			}
+			sides[i] = expression
+		}
+		return sides[0] + " " + op + " " + sides[1], nil
+	case parser.AttributeExpression:
+		return m.mapAttrExpr(element)
+	case parser.ValuePathExpression:
+		return "", fmt.Errorf("IDQL value path expressions (%s) are not supported in Casbin", element.String())
+	}
+	return "", fmt.Errorf("unsupported IDQL expression: %s", ast.String())
+}
+
+func (m *CasbinMapper) mapAttrExpr(attrExpr parser.AttributeExpression) (string, error) {
+	entity, ok := attrExpr.AttributePath.(types.Entity)
+	if !ok || !isAttributePath(entity) {
+		return "", fmt.Errorf("left hand side of '%s' must be an attribute", attrExpr.String())
+	}
+	attr := m.mapAttribute(entity.String())
+
+	switch attrExpr.Operator {
+	case parser.PR:
+		return "", fmt.Errorf("IDQL operator 'pr' (%s) is not supported in Casbin", attrExpr.String())
+	case parser.SW, parser.EW, parser.CO:
+		value, ok := attrExpr.CompareValue.(types.String)
+		if !ok {
+			return "", fmt.Errorf("IDQL operator '%s' requires a string value in Casbin", attrExpr.Operator)
+		}
+		pattern := regexp.QuoteMeta(value.Value().(string))
+		switch attrExpr.Operator {
+		case parser.SW:
+			pattern = "^" + pattern
+		case parser.EW:
+			pattern = pattern + "$"
+		}
+		return fmt.Sprintf("%s(%s, %s)", funcRegexMatch, attr, strconv.Quote(pattern)), nil
+	case parser.IN:
+		array, ok := attrExpr.CompareValue.(types.Array)
+		if !ok {
+			return "", fmt.Errorf("IDQL entity membership (%s) is not supported in Casbin", attrExpr.String())
+		}
+		items := array.Value().([]types.ComparableValue)
+		values := make([]string, len(items))
+		for i, item := range items {
+			value, err := m.mapValue(item)
+			if err != nil {
+				return "", err
+			}
+			values[i] = value
+		}
+		return fmt.Sprintf("%s in (%s)", attr, strings.Join(values, ", ")), nil
+	}
+
+	op, exists := compareOps[attrExpr.Operator]
+	if !exists {
+		return "", fmt.Errorf("IDQL operator '%s' is not supported in Casbin", attrExpr.Operator)
+	}
+	value, err := m.mapValue(attrExpr.CompareValue)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", attr, op, value), nil
+}
+
+func (m *CasbinMapper) mapValue(value types.Value) (string, error) {
+	switch v := value.(type) {
+	case types.String:
+		return strconv.Quote(v.Value().(string)), nil
+	case types.Date:
+		return strconv.Quote(v.String()), nil
+	case types.Numeric, types.Boolean:
+		return v.String(), nil
+	case types.Entity:
+		if isAttributePath(v) {
+			return m.mapAttribute(v.String()), nil
+		}
+		return "", fmt.Errorf("IDQL entity comparison (%s) is not supported in Casbin", v.String())
+	}
+	return "", fmt.Errorf("unsupported value type %s in Casbin mapping", types.TypeName(value.ValueType()))
+}
+
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+// mapAttribute maps an IDQL attribute path to a Casbin request context reference
+func (m *CasbinMapper) mapAttribute(path string) string {
+	if m.NameMapper != nil {
+		if mapped := m.NameMapper.GetProviderAttributeName(path); mapped != path {
+			return prefixContext + mapped
+		}
+	}
+	return prefixContext + path
+}
+
+// mapReference maps a Casbin request reference to an IDQL attribute path
+func (m *CasbinMapper) mapReference(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, prefixContext):
+		name := ref[len(prefixContext):]
+		if m.NameMapper != nil {
+			if mapped := m.NameMapper.GetHexaFilterAttributePath(strings.ToLower(name)); !strings.EqualFold(mapped, name) {
+				return mapped, nil
+			}
+		}
+		return name, nil
+	case strings.HasPrefix(ref, prefixSubject):
+		return "subject." + ref[len(prefixSubject):], nil
+	case strings.HasPrefix(ref, prefixObject):
+		return "resource." + ref[len(prefixObject):], nil
+	case ref == "r.sub":
+		return "subject.id", nil
+	case ref == "r.obj":
+		return "resource.id", nil
+	case ref == "r.act":
+		return "action.id", nil
+	}
+	return "", fmt.Errorf("unsupported Casbin reference '%s'", ref)
+}
+
+// MapCasbinToCondition maps a Casbin (govaluate) expression into an IDQL condition with the action provided
+func (m *CasbinMapper) MapCasbinToCondition(expression string, action string) (*conditions.ConditionInfo, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Casbin condition '%s': %s", expression, err.Error())
+	}
+	p := &condParser{tokens: tokens, mapper: m}
+	ast, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected '%s'", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid Casbin condition '%s': %s", expression, err.Error())
+	}
+	return &conditions.ConditionInfo{Rule: conditions.SerializeExpression(ast), Action: action}, nil
+}
+
+const (
+	tokIdent = iota
+	tokString
+	tokNumber
+	tokOperator
+	tokPunct
+	tokEOF
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+			i++
+			literal := string(runes[start:i])
+			if r == '\'' {
+				literal = "\"" + strings.ReplaceAll(literal[1:len(literal)-1], "\"", "\\\"") + "\""
+			}
+			value, err := strconv.Unquote(literal)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", string(runes[start:i]))
+			}
+			tokens = append(tokens, token{tokString, value})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[start:i])})
+		default:
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				switch pair {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, token{tokOperator, pair})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '<', '>', '!':
+				tokens = append(tokens, token{tokOperator, string(r)})
+			case '(', ')', ',':
+				tokens = append(tokens, token{tokPunct, string(r)})
+			default:
+				return nil, fmt.Errorf("unexpected character '%c'", r)
+			}
+			i++
+		}
+	}
+	return append(tokens, token{tokEOF, ""}), nil
+}
+
+// operand is an IDQL attribute path or literal value (in IDQL form) parsed from a Casbin expression
+type operand struct {
+	path    string
+	literal string
+}
+
+func (o operand) value() string {
+	if o.path != "" {
+		return o.path
+	}
+	return o.literal
+}
+
+type condParser struct {
+	tokens []token
+	pos    int
+	mapper *CasbinMapper
+}
+
+func (p *condParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *condParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *condParser) isToken(kind int, text string) bool {
+	tok := p.peek()
+	return tok.kind == kind && tok.text == text
+}
+
+func (p *condParser) expectPunct(text string) error {
+	tok := p.next()
+	if tok.kind != tokPunct || tok.text != text {
+		return fmt.Errorf("expected '%s' but found '%s'", text, tok.text)
+	}
+	return nil
+}
+
+func (p *condParser) parseOr() (parser.Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if p.isToken(tokOperator, "||") {
+		left = precedence(left)
+	}
+	for p.isToken(tokOperator, "||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = parser.LogicalExpression{Operator: parser.OR, Left: left, Right: precedence(right)}
+	}
+	return left, nil
+}
+
+// precedence brackets an and expression within an or expression (IDQL does not give and a higher precedence)
+func precedence(expression parser.Expression) parser.Expression {
+	if logical, ok := expression.(parser.LogicalExpression); ok && logical.Operator == parser.AND {
+		return parser.PrecedenceExpression{Expression: expression}
+	}
+	return expression
+}
+
+func (p *condParser) parseAnd() (parser.Expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isToken(tokOperator, "&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = parser.LogicalExpression{Operator: parser.AND, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (parser.Expression, error) {
+	if p.isToken(tokOperator, "!") {
+		p.next()
+		expression, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if precedence, ok := expression.(parser.PrecedenceExpression); ok {
+			expression = precedence.Expression
+		}
+		return parser.NotExpression{Expression: expression}, nil
+	}
+	if p.isToken(tokPunct, "(") {
+		p.next()
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return parser.PrecedenceExpression{Expression: expression}, nil
+	}
+	if tok := p.peek(); tok.kind == tokIdent && p.tokens[p.pos+1].kind == tokPunct && p.tokens[p.pos+1].text == "(" {
+		return p.parseCall()
+	}
+	return p.parseComparison()
+}
+
+// parseCall maps the regexMatch and keyMatch functions to sw, ew, co or eq comparisons
+func (p *condParser) parseCall() (parser.Expression, error) {
+	name := p.next().text
+	p.next()
+	attr, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct(","); err != nil {
+		return nil, err
+	}
+	pattern := p.next()
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	if attr.path == "" || pattern.kind != tokString {
+		return nil, fmt.Errorf("%s requires an attribute and a string pattern", name)
+	}
+
+	var op parser.CompareOperator
+	var literal string
+	switch name {
+	case funcRegexMatch:
+		op, literal, err = mapRegex(pattern.text)
+		if err != nil {
+			return nil, err
+		}
+	case funcKeyMatch:
+		op, literal = parser.EQ, pattern.text
+		if strings.HasSuffix(literal, Wildcard) {
+			op, literal = parser.SW, strings.TrimSuffix(literal, Wildcard)
+		}
+		if strings.Contains(literal, Wildcard) {
+			return nil, fmt.Errorf("keyMatch pattern '%s' is not supported", pattern.text)
+		}
+	default:
+		return nil, fmt.Errorf("casbin function %s is not supported", name)
+	}
+	return parser.NewAttributeExpression(attr.path, op, strconv.Quote(literal))
+}
+
+// mapRegex maps an anchored literal regular expression to an IDQL comparison
+func mapRegex(pattern string) (parser.CompareOperator, string, error) {
+	starts := strings.HasPrefix(pattern, "^")
+	if starts {
+		pattern = pattern[1:]
+	}
+	ends := strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, "\\$")
+	if ends {
+		pattern = pattern[:len(pattern)-1]
+	}
+	sb := strings.Builder{}
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			i++
+		} else if strings.ContainsRune(`.+*?()|[]{}^$`, runes[i]) {
+			return "", "", fmt.Errorf("regular expression '%s' is not supported", pattern)
+		}
+		sb.WriteRune(runes[i])
+	}
+	switch {
+	case starts && ends:
+		return parser.EQ, sb.String(), nil
+	case starts:
+		return parser.SW, sb.String(), nil
+	case ends:
+		return parser.EW, sb.String(), nil
+	}
+	return parser.CO, sb.String(), nil
+}
+
+func (p *condParser) parseComparison() (parser.Expression, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isToken(tokIdent, "in") {
+		p.next()
+		if left.path == "" {
+			return nil, errors.New("in requires an attribute")
+		}
+		if err = p.expectPunct("("); err != nil {
+			return nil, err
+		}
+		var values []string
+		for !p.isToken(tokPunct, ")") {
+			value, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			if value.path != "" {
+				return nil, errors.New("in values must be literals")
+			}
+			values = append(values, value.literal)
+			if p.isToken(tokPunct, ",") {
+				p.next()
+			}
+		}
+		p.next()
+		return parser.NewAttributeExpression(left.path, parser.IN, "["+strings.Join(values, ",")+"]")
+	}
+
+	tok := p.peek()
+	var op parser.CompareOperator
+	for idqlOp, casbinOp := range compareOps {
+		if tok.kind == tokOperator && tok.text == casbinOp {
+			op = idqlOp
+		}
+	}
+	if op == "" {
+		// a boolean attribute on its own
+		if left.path == "" {
+			return nil, fmt.Errorf("expected a comparison but found '%s'", tok.text)
+		}
+		return parser.NewAttributeExpression(left.path, parser.EQ, "true")
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case left.path != "":
+		return parser.NewAttributeExpression(left.path, op, right.value())
+	case right.path != "":
+		flips := map[parser.CompareOperator]parser.CompareOperator{parser.GT: parser.LT, parser.GE: parser.LE, parser.LT: parser.GT, parser.LE: parser.GE}
+		if flipped, exists := flips[op]; exists {
+			op = flipped
+		}
+		return parser.NewAttributeExpression(right.path, op, left.literal)
+	}
+	return nil, errors.New("a comparison requires an attribute")
+}
+
+func (p *condParser) parseOperand() (operand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		if _, err := time.Parse(time.RFC3339, tok.text); err == nil {
+			return operand{literal: tok.text}, nil
+		}
+		return operand{literal: strconv.Quote(tok.text)}, nil
+	case tokNumber:
+		return operand{literal: tok.text}, nil
+	case tokIdent:
+		if strings.EqualFold(tok.text, "true") || strings.EqualFold(tok.text, "false") {
+			return operand{literal: strings.ToLower(tok.text)}, nil
+		}
+		path, err := p.mapper.mapReference(tok.text)
+		return operand{path: path}, err
+	}
+	return operand{}, fmt.Errorf("unexpected '%s'", tok.text)
+}
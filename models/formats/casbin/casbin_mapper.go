@@ -0,0 +1,389 @@
+// Package casbin maps IDQL policies to and from Casbin (https://casbin.org) policy files.
+//
+// IDQL policies are mapped into `p` policy lines against the model returned by Model (see ModelConf). Each line holds
+// a single subject and action:
+//
+//	p, <subject>, <object>, <action>, <allow|deny>, <condition>, <policyId>
+//
+// IDQL membership subjects (e.g. [Group:admins]) are mapped to the role name (Group:admins) so that they are matched
+// through the `g` role definition. The `g` role edges themselves (e.g. `g, User:alice, Group:admins`) are generated from
+// the parents of an optional decision.EntityDirectory. Type matches (e.g. User:) and prefix matches are mapped to a
+// Casbin keyMatch pattern (User:*). Conditions are mapped into Casbin `eval()` expressions (see MapConditionToCasbin).
+//
+// When importing, a subject that is the role of a `g` line is mapped to an IDQL membership subject. Lines sharing the
+// same policy id are merged into a single IDQL policy. Basic Casbin policies with only `sub, obj, act` or
+// `sub, obj, act, eft` fields are also accepted.
+package casbin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	PTypePolicy = "p"
+	PTypeRole   = "g"
+
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+
+	// Wildcard matches any object or action
+	Wildcard = "*"
+
+	// ModelConf is the Casbin model used by policies generated by CasbinMapper. Subjects are matched directly, by
+	// keyMatch pattern, or through `g` role membership. Deny policies override allow policies.
+	ModelConf = `[request_definition]
+r = sub, obj, act, ctx
+
+[policy_definition]
+p = sub, obj, act, eft, cond, id
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = (p.sub == "any" || (p.sub == "anyAuthenticated" && r.sub != "") || keyMatch(r.sub, p.sub) || g(r.sub, p.sub)) && keyMatch(r.obj, p.obj) && (p.act == "*" || r.act == p.act) && eval(p.cond)
+`
+
+	condTrue = "true"
+)
+
+// Line is a single Casbin policy line (e.g. `p, alice, data1, read`). Values holds the fields following the policy type.
+type Line struct {
+	PType  string
+	Values []string
+}
+
+// String returns the line in Casbin CSV form. Values containing commas or quotes are quoted.
+func (l Line) String() string {
+	fields := make([]string, len(l.Values)+1)
+	fields[0] = l.PType
+	for i, value := range l.Values {
+		if strings.ContainsAny(value, ",\"\n") || strings.TrimSpace(value) != value {
+			value = "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+		}
+		fields[i+1] = value
+	}
+	return strings.Join(fields, ", ")
+}
+
+// FormatPolicy returns lines as the content of a Casbin policy CSV file
+func FormatPolicy(lines []Line) string {
+	sb := strings.Builder{}
+	for _, line := range lines {
+		sb.WriteString(line.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ParsePolicy parses the content of a Casbin policy CSV file. Blank lines and lines starting with # are ignored.
+func ParsePolicy(policyBytes []byte) ([]Line, error) {
+	reader := csv.NewReader(strings.NewReader(string(policyBytes)))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	var lines []Line
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Casbin policy: %s", err.Error())
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		if len(record) < 2 || record[0] == "" {
+			return nil, fmt.Errorf("invalid Casbin policy line: %s", strings.Join(record, ", "))
+		}
+		lines = append(lines, Line{PType: record[0], Values: record[1:]})
+	}
+	return lines, nil
+}
+
+// ParseFile reads and parses a Casbin policy CSV file
+func ParseFile(path string) ([]Line, error) {
+	policyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(policyBytes)
+}
+
+type CasbinMapper struct {
+	NameMapper *conditions.AttributeMap
+}
+
+// NewCasbinMapper returns a mapper where attributeMap maps IDQL attribute names to Casbin request context names
+// (see MapConditionToCasbin)
+func NewCasbinMapper(attributeMap map[string]string) *CasbinMapper {
+	return &CasbinMapper{NameMapper: conditions.NewNameMapper(attributeMap)}
+}
+
+// MapPoliciesToCasbin maps IDQL policies into Casbin `p` lines followed by `g` role lines for the membership of
+// entities in directory. directory may be nil.
+func (m *CasbinMapper) MapPoliciesToCasbin(policies []hexapolicy.PolicyInfo, directory *decision.EntityDirectory) ([]Line, error) {
+	var lines []Line
+	for i, policy := range policies {
+		policyLines, err := m.MapPolicyToLines(policy, i)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, policyLines...)
+	}
+	if directory != nil {
+		for _, entity := range directory.Entities() {
+			for _, parent := range entity.Parents {
+				lines = append(lines, Line{PType: PTypeRole, Values: []string{entity.Id, parent}})
+			}
+		}
+	}
+	return lines, nil
+}
+
+// MapPolicyToLines maps an IDQL policy into one `p` line per subject and action. index is used to generate a policy
+// id when the policy has none.
+func (m *CasbinMapper) MapPolicyToLines(policy hexapolicy.PolicyInfo, index int) ([]Line, error) {
+	policyId := fmt.Sprintf("policy-%d", index)
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		policyId = *policy.Meta.PolicyId
+	}
+
+	subjects, err := mapSubjects(policy.Subjects)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+	}
+	object, err := mapEntityPattern(policy.Object.String())
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: object %s", policyId, err.Error())
+	}
+	if object == "" {
+		object = Wildcard
+	}
+	actions := []string{Wildcard}
+	if len(policy.Actions) > 0 {
+		actions = make([]string, len(policy.Actions))
+		for i, action := range policy.Actions {
+			actions[i] = string(action)
+		}
+	}
+
+	effect, cond := EffectAllow, condTrue
+	if policy.Condition != nil {
+		if strings.EqualFold(policy.Condition.Action, conditions.ADeny) {
+			effect = EffectDeny
+		}
+		cond, err = m.MapConditionToCasbin(*policy.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+	}
+
+	var lines []Line
+	for _, subject := range subjects {
+		for _, action := range actions {
+			lines = append(lines, Line{PType: PTypePolicy, Values: []string{subject, object, action, effect, cond, policyId}})
+		}
+	}
+	return lines, nil
+}
+
+func mapSubjects(subjects hexapolicy.SubjectInfo) ([]string, error) {
+	if len(subjects) == 0 {
+		return []string{hexapolicy.SubjectAnyUser}, nil
+	}
+	var res []string
+	for _, subject := range subjects {
+		switch {
+		case strings.EqualFold(subject, hexapolicy.SubjectAnyUser):
+			res = append(res, hexapolicy.SubjectAnyUser)
+			continue
+		case strings.EqualFold(subject, hexapolicy.SubjectAnyAuth):
+			res = append(res, hexapolicy.SubjectAnyAuth)
+			continue
+		}
+		entity := types.ParseEntity(subject)
+		if entity.Type == types.RelTypeIn {
+			for _, member := range *entity.In {
+				res = append(res, member.String())
+			}
+			continue
+		}
+		pattern, err := mapEntityPattern(subject)
+		if err != nil {
+			return nil, fmt.Errorf("subject %s", err.Error())
+		}
+		res = append(res, pattern)
+	}
+	return res, nil
+}
+
+// mapEntityPattern maps an IDQL subject or object into a Casbin keyMatch pattern
+func mapEntityPattern(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	entity := types.ParseEntity(value)
+	switch entity.Type {
+	case types.RelTypeIs:
+		return value + Wildcard, nil
+	case types.RelTypeIn, types.RelTypeIsIn:
+		return "", fmt.Errorf("%s: membership is only supported for subjects in Casbin", value)
+	}
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "domain:") || strings.HasPrefix(lower, "net:") {
+		return "", fmt.Errorf("%s is not supported in Casbin", value)
+	}
+	return value, nil
+}
+
+// MapCasbinPolicyBytes parses and maps a Casbin policy CSV file into IDQL
+func (m *CasbinMapper) MapCasbinPolicyBytes(policyBytes []byte) (*hexapolicy.Policies, error) {
+	lines, err := ParsePolicy(policyBytes)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := m.MapCasbinToPolicies(lines)
+	if err != nil {
+		return nil, err
+	}
+	return &hexapolicy.Policies{Policies: policies}, nil
+}
+
+// MapCasbinToPolicies maps Casbin `p` lines into IDQL policies. Lines with the same policy id are merged.
+func (m *CasbinMapper) MapCasbinToPolicies(lines []Line) ([]hexapolicy.PolicyInfo, error) {
+	roles := map[string]bool{}
+	for _, line := range lines {
+		if line.PType == PTypeRole && len(line.Values) >= 2 {
+			roles[line.Values[1]] = true
+		}
+	}
+
+	var policies []hexapolicy.PolicyInfo
+	index := map[string]int{}
+	for i, line := range lines {
+		if line.PType != PTypePolicy {
+			continue
+		}
+		policy, err := m.mapLine(line, roles)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err.Error())
+		}
+		policyId := *policy.Meta.PolicyId
+		if policyId == "" {
+			policyId = fmt.Sprintf("policy-%d", len(policies))
+			policy.Meta.PolicyId = &policyId
+			policies = append(policies, policy)
+			continue
+		}
+		existing, exists := index[policyId]
+		if !exists {
+			index[policyId] = len(policies)
+			policies = append(policies, policy)
+			continue
+		}
+		if err := mergePolicy(&policies[existing], policy); err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err.Error())
+		}
+	}
+	if len(policies) == 0 {
+		return nil, errors.New("no Casbin policy (p) lines found")
+	}
+	return policies, nil
+}
+
+func (m *CasbinMapper) mapLine(line Line, roles map[string]bool) (hexapolicy.PolicyInfo, error) {
+	if len(line.Values) < 3 || len(line.Values) > 6 {
+		return hexapolicy.PolicyInfo{}, fmt.Errorf("expecting sub, obj, act[, eft, cond, id] but found %d fields", len(line.Values))
+	}
+	values := make([]string, 6)
+	copy(values, line.Values)
+	sub, obj, act, eft, cond, policyId := values[0], values[1], values[2], strings.ToLower(values[3]), values[4], values[5]
+
+	policy := hexapolicy.PolicyInfo{
+		Meta:     hexapolicy.MetaInfo{Version: hexapolicy.IdqlVersion, PolicyId: &policyId},
+		Subjects: []string{mapSubject(sub, roles)},
+	}
+	if obj != Wildcard {
+		policy.Object = hexapolicy.ObjectInfo(strings.TrimSuffix(obj, Wildcard))
+	}
+	if act != Wildcard {
+		policy.Actions = []hexapolicy.ActionInfo{hexapolicy.ActionInfo(act)}
+	}
+
+	switch eft {
+	case "", EffectAllow:
+		eft = conditions.AAllow
+	case EffectDeny:
+		eft = conditions.ADeny
+	default:
+		return hexapolicy.PolicyInfo{}, fmt.Errorf("unsupported effect '%s'", values[3])
+	}
+	if cond != "" && cond != condTrue {
+		condition, err := m.MapCasbinToCondition(cond, eft)
+		if err != nil {
+			return hexapolicy.PolicyInfo{}, err
+		}
+		policy.Condition = condition
+	} else if eft == conditions.ADeny {
+		return hexapolicy.PolicyInfo{}, errors.New("a deny policy without a condition is not supported in IDQL")
+	}
+	return policy, nil
+}
+
+func mapSubject(subject string, roles map[string]bool) string {
+	switch {
+	case subject == Wildcard:
+		return hexapolicy.SubjectAnyUser
+	case roles[subject]:
+		return "[" + subject + "]"
+	case strings.HasSuffix(subject, ":"+Wildcard):
+		return strings.TrimSuffix(subject, Wildcard)
+	}
+	return subject
+}
+
+// mergePolicy adds the subjects and actions of a line to the policy with the same id
+func mergePolicy(policy *hexapolicy.PolicyInfo, line hexapolicy.PolicyInfo) error {
+	if policy.Object != line.Object || !conditionsEqual(policy.Condition, line.Condition) {
+		return fmt.Errorf("policy %s has lines with different objects, effects, or conditions", *policy.Meta.PolicyId)
+	}
+	for _, subject := range line.Subjects {
+		if !slices.Contains(policy.Subjects, subject) {
+			policy.Subjects = append(policy.Subjects, subject)
+		}
+	}
+	if len(policy.Actions) == 0 || len(line.Actions) == 0 {
+		policy.Actions = nil
+		return nil
+	}
+	for _, action := range line.Actions {
+		if !slices.Contains(policy.Actions, action) {
+			policy.Actions = append(policy.Actions, action)
+		}
+	}
+	return nil
+}
+
+func conditionsEqual(a *conditions.ConditionInfo, b *conditions.ConditionInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Rule == b.Rule && a.Action == b.Action
+}
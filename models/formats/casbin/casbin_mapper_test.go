@@ -0,0 +1,206 @@
+package casbin
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func TestMapCasbinToPolicies(t *testing.T) {
+	lines, err := ParseFile(filepath.Join(getExampleDir(), "policyExamples", "example_casbin.csv"))
+	assert.NoError(t, err)
+	assert.Len(t, lines, 6)
+
+	mapper := NewCasbinMapper(map[string]string{})
+	policies, err := mapper.MapCasbinToPolicies(lines)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 3)
+
+	edit := policies[0]
+	assert.Equal(t, "EditDocuments", *edit.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{"[Group:editors]"}, edit.Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"read", "edit"}, edit.Actions)
+	assert.Equal(t, "Document:", edit.Object.String())
+	assert.Equal(t, "resource.department eq subject.department", edit.Condition.Rule)
+	assert.Equal(t, conditions.AAllow, edit.Condition.Action)
+
+	deny := policies[1]
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}, deny.Subjects)
+	assert.Nil(t, deny.Actions)
+	assert.Equal(t, "subject.level lt 3 or req.ip sw \"10.\"", deny.Condition.Rule)
+	assert.Equal(t, conditions.ADeny, deny.Condition.Action)
+
+	basic := policies[2]
+	assert.Equal(t, "policy-2", *basic.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{"alice"}, basic.Subjects)
+	assert.Equal(t, "/reports", basic.Object.String())
+	assert.Nil(t, basic.Condition)
+}
+
+func TestMapPoliciesToCasbin(t *testing.T) {
+	policyId := "Edit, Docs"
+	policies := []hexapolicy.PolicyInfo{
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &policyId},
+			Subjects: []string{"[Group:editors]", "User:"},
+			Actions:  []hexapolicy.ActionInfo{"read", "edit"},
+			Object:   "Document:",
+			Condition: &conditions.ConditionInfo{
+				Rule:   "subject.email ew \"@example.com\" and (subject.level ge 5 or subject.roles co \"admin\")",
+				Action: conditions.AAllow,
+			},
+		},
+		{},
+	}
+	directory := decision.NewEntityDirectory([]decision.EntityInfo{{Id: "User:alice", Parents: []string{"Group:editors"}}})
+
+	mapper := NewCasbinMapper(map[string]string{"subject.roles": "roles"})
+	lines, err := mapper.MapPoliciesToCasbin(policies, directory)
+	assert.NoError(t, err)
+	cond := `regexMatch(r.ctx.subject.email, "@example\\.com$") && (r.ctx.subject.level >= 5 || regexMatch(r.ctx.roles, "admin"))`
+	quoted := `"` + strings.ReplaceAll(cond, `"`, `""`) + `"`
+	assert.Equal(t, strings.Join([]string{
+		"p, Group:editors, Document:*, read, allow, " + quoted + `, "Edit, Docs"`,
+		"p, Group:editors, Document:*, edit, allow, " + quoted + `, "Edit, Docs"`,
+		"p, User:*, Document:*, read, allow, " + quoted + `, "Edit, Docs"`,
+		"p, User:*, Document:*, edit, allow, " + quoted + `, "Edit, Docs"`,
+		"p, any, *, *, allow, true, policy-1",
+		"g, User:alice, Group:editors",
+	}, "\n")+"\n", FormatPolicy(lines))
+
+	roundTrip, err := mapper.MapCasbinPolicyBytes([]byte(FormatPolicy(lines)))
+	assert.NoError(t, err)
+	assert.Len(t, roundTrip.Policies, 2)
+	result := roundTrip.Policies[0]
+	assert.Equal(t, policyId, *result.Meta.PolicyId)
+	assert.True(t, policies[0].Subjects.Equals(result.Subjects), "got %v", result.Subjects)
+	assert.True(t, policies[0].ActionsEqual(result.Actions))
+	assert.Equal(t, policies[0].Object, result.Object)
+	assert.True(t, policies[0].Condition.Equals(result.Condition), "got %s", result.Condition.Rule)
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}, roundTrip.Policies[1].Subjects)
+}
+
+func TestConditions(t *testing.T) {
+	mapper := NewCasbinMapper(map[string]string{})
+	tests := []struct {
+		idql   string
+		casbin string
+	}{
+		{"subject.level gt 5", "r.ctx.subject.level > 5"},
+		{"subject.name ne \"bob\"", "r.ctx.subject.name != \"bob\""},
+		{"req.time lt 2030-01-01T00:00:00Z", "r.ctx.req.time < \"2030-01-01T00:00:00Z\""},
+		{"subject.active eq true", "r.ctx.subject.active == true"},
+		{"subject.sub eq resource.owner", "r.ctx.subject.sub == r.ctx.resource.owner"},
+		{"not(subject.sub sw \"svc-\" or subject.admin eq true)", "!(regexMatch(r.ctx.subject.sub, \"^svc-\") || r.ctx.subject.admin == true)"},
+		{"subject.dept in [\"sales\",\"marketing\"]", "r.ctx.subject.dept in (\"sales\", \"marketing\")"},
+		{"(subject.a eq 1 or subject.b eq 2) and subject.c eq 3", "(r.ctx.subject.a == 1 || r.ctx.subject.b == 2) && r.ctx.subject.c == 3"},
+	}
+	for _, test := range tests {
+		t.Run(test.idql, func(t *testing.T) {
+			casbin, err := mapper.MapConditionToCasbin(conditions.ConditionInfo{Rule: test.idql, Action: conditions.AAllow})
+			assert.NoError(t, err)
+			assert.Equal(t, test.casbin, casbin)
+
+			condition, err := mapper.MapCasbinToCondition(casbin, conditions.AAllow)
+			assert.NoError(t, err)
+			expected := conditions.ConditionInfo{Rule: test.idql, Action: conditions.AAllow}
+			assert.True(t, expected.Equals(condition), "got %s", condition.Rule)
+		})
+	}
+}
+
+func TestMapCasbinToCondition_AbacStyle(t *testing.T) {
+	mapper := NewCasbinMapper(map[string]string{})
+	condition, err := mapper.MapCasbinToCondition(`r.sub.Age > 18 && 'admin' == r.sub.Role && keyMatch(r.obj.Path, "/data/*") && r.sub.Active`, conditions.AAllow)
+	assert.NoError(t, err)
+	assert.Equal(t, "subject.Age gt 18 and subject.Role eq \"admin\" and resource.Path sw \"/data/\" and subject.Active eq true", condition.Rule)
+
+	condition, err = mapper.MapCasbinToCondition(`10 < r.sub.Age`, conditions.ADeny)
+	assert.NoError(t, err)
+	assert.Equal(t, "subject.Age gt 10", condition.Rule)
+
+	for _, expression := range []string{
+		`r.sub.Age >`,
+		`regexMatch(r.sub.Name, "a.*b")`,
+		`globMatch(r.sub.Name, "a*")`,
+		`p.sub == "bob"`,
+		`"a" == "b"`,
+		`(r.sub.Age > 1`,
+		`r.sub.Age > 1 #`,
+	} {
+		_, err := mapper.MapCasbinToCondition(expression, conditions.AAllow)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestMapPoliciesToCasbin_Errors(t *testing.T) {
+	mapper := NewCasbinMapper(map[string]string{})
+	for _, policy := range []hexapolicy.PolicyInfo{
+		{Subjects: []string{"User[Group:admins]"}},
+		{Subjects: []string{"domain:example.com"}},
+		{Object: "[Folder:shared]"},
+		{Condition: &conditions.ConditionInfo{Rule: "subject.email pr", Action: conditions.AAllow}},
+		{Condition: &conditions.ConditionInfo{Rule: "subject in Group:admins", Action: conditions.AAllow}},
+		{Condition: &conditions.ConditionInfo{Rule: "emails[type eq \"work\"] pr", Action: conditions.AAllow}},
+		{Condition: &conditions.ConditionInfo{Rule: "subject.level sw 5", Action: conditions.AAllow}},
+	} {
+		_, err := mapper.MapPoliciesToCasbin([]hexapolicy.PolicyInfo{policy}, nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestMapCasbinPolicyBytes_Errors(t *testing.T) {
+	mapper := NewCasbinMapper(map[string]string{})
+	for _, policy := range []string{
+		"g, alice, admin\n",
+		"p, alice\n",
+		"p, alice, data1, read, maybe\n",
+		"p, alice, data1, read, deny\n",
+		"p, alice, data1, read, allow, r.sub.Age >, id\n",
+		"p, alice, data1, read, allow, true, id\np, alice, data2, read, allow, true, id\n",
+		"p, \"alice, data1\n",
+	} {
+		t.Run(policy, func(t *testing.T) {
+			_, err := mapper.MapCasbinPolicyBytes([]byte(policy))
+			assert.Error(t, err)
+		})
+	}
+	_, err := ParseFile(filepath.Join(getExampleDir(), "doesNotExist.csv"))
+	assert.Error(t, err)
+}
+
+func TestRoundTrip(t *testing.T) {
+	policies, err := hexapolicysupport.ParsePolicyFile(filepath.Join(getExampleDir(), "authZen", "data.json"))
+	assert.NoError(t, err)
+
+	mapper := NewCasbinMapper(map[string]string{})
+	lines, err := mapper.MapPoliciesToCasbin(policies, nil)
+	assert.NoError(t, err)
+	mapped, err := mapper.MapCasbinPolicyBytes([]byte(FormatPolicy(lines)))
+	assert.NoError(t, err)
+	assert.Len(t, mapped.Policies, len(policies))
+	for i, policy := range policies {
+		result := mapped.Policies[i]
+		assert.Equal(t, *policy.Meta.PolicyId, *result.Meta.PolicyId)
+		assert.True(t, policy.Subjects.Equals(result.Subjects), "subjects %s: %v", *policy.Meta.PolicyId, result.Subjects)
+		assert.True(t, policy.ActionsEqual(result.Actions), "actions %s", *policy.Meta.PolicyId)
+		assert.Equal(t, policy.Object, result.Object)
+		if policy.Condition == nil {
+			assert.Nil(t, result.Condition)
+			continue
+		}
+		assert.True(t, policy.Condition.Equals(result.Condition), "expected %s, got %s", policy.Condition.Rule, result.Condition.Rule)
+	}
+}
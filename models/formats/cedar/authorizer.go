@@ -0,0 +1,202 @@
+package cedar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cedar-policy/cedar-go"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	hexaTypes "github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+/*
+Authorizer evaluates IDQL policies locally by mapping them to Cedar (see CedarMapper.MapHexaPolicies) and using the
+cedar-go authorizer. This makes it possible to check what would be provisioned to a Cedar based provider (e.g. AVP)
+without calling the provider.
+*/
+type Authorizer struct {
+	mapper    *CedarMapper
+	policySet *cedar.PolicySet
+	policyIds map[cedar.PolicyID]string // maps the Cedar policy id to the IDQL policy id
+	entities  cedar.EntityMap
+}
+
+// AuthorizationResult is the decision of an Authorizer along with the IDQL policies that determined it
+type AuthorizationResult struct {
+	Allowed  bool
+	Policies []string // the IDQL policy ids of the policies that determined the decision
+	Errors   []string // errors evaluating policies (policies with errors are skipped)
+}
+
+// NewAuthorizer returns an Authorizer for the IDQL policies. If mapper is nil a mapper with no attribute name map is used.
+func NewAuthorizer(mapper *CedarMapper, policies []hexapolicy.PolicyInfo) (*Authorizer, error) {
+	if mapper == nil {
+		mapper = NewCedarMapper(map[string]string{})
+	}
+	authorizer := &Authorizer{
+		mapper:   mapper,
+		entities: cedar.EntityMap{},
+	}
+	if err := authorizer.SetPolicies(policies); err != nil {
+		return nil, err
+	}
+	return authorizer, nil
+}
+
+/*
+SetPolicies replaces the policies used for authorization. Policies are identified by their Meta.PolicyId or, if not
+set, by their position (e.g. policy-0). An IDQL policy with multiple subjects is mapped to one Cedar policy per subject.
+*/
+func (a *Authorizer) SetPolicies(policies []hexapolicy.PolicyInfo) error {
+	policySet := cedar.NewPolicySet()
+	policyIds := map[cedar.PolicyID]string{}
+	for i, policy := range policies {
+		policyId := fmt.Sprintf("policy-%d", i)
+		if policy.Meta.PolicyId != nil {
+			policyId = *policy.Meta.PolicyId
+		}
+		cedarText, err := a.mapper.MapHexaPolicies(policyId, []hexapolicy.PolicyInfo{policy})
+		if err != nil {
+			return fmt.Errorf("unable to map policy %s to cedar: %w", policyId, err)
+		}
+		cedarPolicies, err := cedar.NewPolicyListFromBytes(policyId, []byte(cedarText))
+		if err != nil {
+			return fmt.Errorf("invalid cedar mapped from policy %s: %w", policyId, err)
+		}
+		for j, cedarPolicy := range cedarPolicies {
+			cedarId := cedar.PolicyID(policyId)
+			if len(cedarPolicies) > 1 {
+				cedarId = cedar.PolicyID(fmt.Sprintf("%s.%d", policyId, j))
+			}
+			policySet.Add(cedarId, cedarPolicy)
+			policyIds[cedarId] = policyId
+		}
+	}
+	a.policySet = policySet
+	a.policyIds = policyIds
+	return nil
+}
+
+// SetEntitiesJSON replaces the entities with those in a Cedar entities JSON document
+func (a *Authorizer) SetEntitiesJSON(entitiesBytes []byte) error {
+	entities := cedar.EntityMap{}
+	if err := json.Unmarshal(entitiesBytes, &entities); err != nil {
+		return fmt.Errorf("error parsing cedar entities: %w", err)
+	}
+	a.entities = entities
+	return nil
+}
+
+// SetEntityStore replaces the entities with those loaded by an EntityStore
+func (a *Authorizer) SetEntityStore(store *EntityStore) error {
+	storeEntities, err := store.GetEntities()
+	if err != nil {
+		return err
+	}
+	cedarEntities := make([]map[string]interface{}, len(storeEntities))
+	for i, entity := range storeEntities {
+		uid, err := parseEntityUID(entity.Identifier)
+		if err != nil {
+			return err
+		}
+		parents := make([]cedar.EntityUID, len(entity.Parents))
+		for j, parent := range entity.Parents {
+			if parents[j], err = parseEntityUID(parent); err != nil {
+				return err
+			}
+		}
+		attrs := map[string]interface{}{}
+		for _, attr := range entity.Attributes {
+			switch {
+			case attr.StringValue != nil:
+				attrs[attr.Name] = *attr.StringValue
+			case attr.LongValue != nil:
+				attrs[attr.Name] = *attr.LongValue
+			case attr.BooleanValue != nil:
+				attrs[attr.Name] = *attr.BooleanValue
+			case attr.RecordValue != nil:
+				attrs[attr.Name] = *attr.RecordValue
+			case attr.SetValue != nil:
+				attrs[attr.Name] = *attr.SetValue
+			}
+		}
+		cedarEntities[i] = map[string]interface{}{"uid": uid, "parents": parents, "attrs": attrs}
+	}
+	entitiesBytes, err := json.Marshal(cedarEntities)
+	if err != nil {
+		return err
+	}
+	return a.SetEntitiesJSON(entitiesBytes)
+}
+
+/*
+IsAuthorized evaluates a request. principal, action and resource may be IDQL entities (e.g. PhotoApp:User:alice) or
+Cedar entity uids (e.g. PhotoApp::User::"alice"). context values are converted using the Cedar JSON value format (e.g.
+{"__extn": {"fn": "ip", "arg": "10.0.0.1"}} for an ip address).
+*/
+func (a *Authorizer) IsAuthorized(principal, action, resource string, context map[string]interface{}) (*AuthorizationResult, error) {
+	var request cedar.Request
+	var err error
+	if request.Principal, err = parseEntityUID(principal); err != nil {
+		return nil, err
+	}
+	if request.Action, err = parseEntityUID(action); err != nil {
+		return nil, err
+	}
+	if request.Resource, err = parseEntityUID(resource); err != nil {
+		return nil, err
+	}
+	if context != nil {
+		contextBytes, err := json.Marshal(context)
+		if err != nil {
+			return nil, err
+		}
+		if err = request.Context.UnmarshalJSON(contextBytes); err != nil {
+			return nil, fmt.Errorf("invalid request context: %w", err)
+		}
+	}
+
+	decision, diagnostic := cedar.Authorize(a.policySet, a.entities, request)
+	result := &AuthorizationResult{Allowed: decision == cedar.Allow}
+	for _, reason := range diagnostic.Reasons {
+		result.Policies = appendUnique(result.Policies, a.policyIds[reason.PolicyID])
+	}
+	for _, diagError := range diagnostic.Errors {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", a.policyIds[diagError.PolicyID], diagError.Message))
+	}
+	return result, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// parseEntityUID parses a Cedar entity uid (Type::"id") or an IDQL entity (Type:id)
+func parseEntityUID(entity string) (cedar.EntityUID, error) {
+	if entityType, id, found := strings.Cut(entity, "::\""); found && !strings.Contains(entityType, "\"") {
+		unquoted, err := strconv.Unquote("\"" + id)
+		if err != nil {
+			return cedar.EntityUID{}, fmt.Errorf("invalid cedar entity %s: %w", entity, err)
+		}
+		return cedar.NewEntityUID(cedar.EntityType(entityType), cedar.String(unquoted)), nil
+	}
+	path := hexaTypes.ParseEntity(entity)
+	if path.Type != hexaTypes.RelTypeEquals || path.Id == nil || len(path.Types) == 0 {
+		return cedar.EntityUID{}, fmt.Errorf("expecting an entity of the form Type:id, found: %s", entity)
+	}
+	return cedar.NewEntityUID(cedar.EntityType(strings.Join(path.Types, "::")), cedar.String(removeQuotes(*path.Id))), nil
+}
+
+func removeQuotes(val string) string {
+	if unquoted, err := strconv.Unquote(val); err == nil {
+		return unquoted
+	}
+	return val
+}
@@ -0,0 +1,120 @@
+package cedar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+const authorizerPolicies = `{
+  "policies": [
+    {
+      "meta": {"version": "0.7", "policyId": "alice-view"},
+      "subjects": ["PhotoApp:User:alice"],
+      "actions": ["PhotoApp:Action:viewPhoto"],
+      "object": "PhotoApp:Photo:vacationPhoto.jpg"
+    },
+    {
+      "meta": {"version": "0.7", "policyId": "team-edit"},
+      "subjects": ["[PhotoApp:UserGroup:AVTeam]"],
+      "actions": ["PhotoApp:Action:editPhoto"],
+      "object": "",
+      "condition": {"rule": "resource.private eq false", "action": "allow"}
+    },
+    {
+      "meta": {"version": "0.7"},
+      "subjects": ["PhotoApp:User:bob", "PhotoApp:User:carol"],
+      "actions": ["PhotoApp:Action:viewPhoto"],
+      "object": ""
+    },
+    {
+      "meta": {"version": "0.7", "policyId": "office-delete"},
+      "subjects": ["PhotoApp:User:alice"],
+      "actions": ["PhotoApp:Action:deletePhoto"],
+      "object": "",
+      "condition": {"rule": "context.ip in \"10.0.0.0/8\"", "action": "allow"}
+    }
+  ]
+}`
+
+func newTestAuthorizer(t *testing.T) *Authorizer {
+	policies, err := hexapolicysupport.ParsePolicies([]byte(authorizerPolicies))
+	assert.NoError(t, err)
+	authorizer, err := NewAuthorizer(nil, policies)
+	assert.NoError(t, err)
+	entities, err := os.ReadFile(filepath.Join("test", "photoEntities.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, authorizer.SetEntitiesJSON(entities))
+	return authorizer
+}
+
+func TestAuthorizer(t *testing.T) {
+	authorizer := newTestAuthorizer(t)
+	officeIp := map[string]interface{}{"ip": map[string]interface{}{"__extn": map[string]interface{}{"fn": "ip", "arg": "10.1.2.3"}}}
+	homeIp := map[string]interface{}{"ip": map[string]interface{}{"__extn": map[string]interface{}{"fn": "ip", "arg": "192.168.1.2"}}}
+
+	tests := []struct {
+		name      string
+		principal string
+		action    string
+		resource  string
+		context   map[string]interface{}
+		allowed   bool
+		policies  []string
+	}{
+		{"direct", "PhotoApp:User:alice", "PhotoApp:Action:viewPhoto", "PhotoApp:Photo:vacationPhoto.jpg", nil, true, []string{"alice-view"}},
+		{"cedar uids", `PhotoApp::User::"alice"`, `PhotoApp::Action::"viewPhoto"`, `PhotoApp::Photo::"vacationPhoto.jpg"`, nil, true, []string{"alice-view"}},
+		{"other photo", "PhotoApp:User:alice", "PhotoApp:Action:viewPhoto", "PhotoApp:Photo:other.jpg", nil, false, nil},
+		{"group member", "PhotoApp:User:alice", "PhotoApp:Action:editPhoto", "PhotoApp:Photo:vacationPhoto.jpg", nil, true, []string{"team-edit"}},
+		{"not a member", "PhotoApp:User:bob", "PhotoApp:Action:editPhoto", "PhotoApp:Photo:vacationPhoto.jpg", nil, false, nil},
+		{"multi-subject", "PhotoApp:User:carol", "PhotoApp:Action:viewPhoto", "PhotoApp:Photo:any.jpg", nil, true, []string{"policy-2"}},
+		{"context allowed", "PhotoApp:User:alice", "PhotoApp:Action:deletePhoto", "PhotoApp:Photo:vacationPhoto.jpg", officeIp, true, []string{"office-delete"}},
+		{"context denied", "PhotoApp:User:alice", "PhotoApp:Action:deletePhoto", "PhotoApp:Photo:vacationPhoto.jpg", homeIp, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := authorizer.IsAuthorized(tt.principal, tt.action, tt.resource, tt.context)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, result.Allowed)
+			assert.Equal(t, tt.policies, result.Policies)
+		})
+	}
+
+	// a missing context attribute is reported as an error against the IDQL policy
+	result, err := authorizer.IsAuthorized("PhotoApp:User:alice", "PhotoApp:Action:deletePhoto", "PhotoApp:Photo:vacationPhoto.jpg", nil)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Len(t, result.Errors, 1)
+	assert.True(t, strings.HasPrefix(result.Errors[0], "office-delete: "), "error should reference the IDQL policy")
+
+	_, err = authorizer.IsAuthorized("[PhotoApp:UserGroup:AVTeam]", "PhotoApp:Action:viewPhoto", "PhotoApp:Photo:vacationPhoto.jpg", nil)
+	assert.Error(t, err, "a principal must be a single entity")
+	_, err = authorizer.IsAuthorized("PhotoApp:User:alice", "PhotoApp:Action:viewPhoto", "PhotoApp:Photo:vacationPhoto.jpg",
+		map[string]interface{}{"ip": map[string]interface{}{"__extn": map[string]interface{}{"fn": "ip", "arg": "bad"}}})
+	assert.Error(t, err, "invalid context")
+}
+
+func TestAuthorizerEntityStore(t *testing.T) {
+	authorizer := newTestAuthorizer(t)
+	store := NewEntityStore(strings.NewReader(`[
+  {"uid": "PhotoApp::User::\"dave\"", "parents": ["PhotoApp::UserGroup::\"AVTeam\""], "attrs": {"userId": "1234"}},
+  {"uid": "PhotoApp::Photo::\"team.jpg\"", "parents": [], "attrs": {"private": false}}
+]`))
+	assert.NoError(t, authorizer.SetEntityStore(store))
+
+	result, err := authorizer.IsAuthorized("PhotoApp:User:dave", "PhotoApp:Action:editPhoto", "PhotoApp:Photo:team.jpg", nil)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, []string{"team-edit"}, result.Policies)
+
+	// alice is no longer a known entity
+	result, err = authorizer.IsAuthorized("PhotoApp:User:alice", "PhotoApp:Action:editPhoto", "PhotoApp:Photo:team.jpg", nil)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	assert.Error(t, authorizer.SetEntitiesJSON([]byte(`{"bad": true}`)))
+}
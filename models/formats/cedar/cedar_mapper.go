@@ -1,9 +1,12 @@
 package cedar
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -17,12 +20,17 @@ import (
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
 )
 
+const (
+	SourceDataAnnotations = "annotations" // the Cedar policy annotations
+	SourceDataCedar       = "cedar"       // the original Cedar policy text including leading comments and annotations
+)
+
 type CedarMapper struct {
 	condMap *cedarConditions.CedarConditionMapper
 }
 
 func NewCedarMapper(attrNameMap map[string]string) *CedarMapper {
-	return &CedarMapper{condMap: &cedarConditions.CedarConditionMapper{conditions.NewNameMapper(attrNameMap)}}
+	return &CedarMapper{condMap: &cedarConditions.CedarConditionMapper{NameMapper: conditions.NewNameMapper(attrNameMap)}}
 }
 
 /*
@@ -46,6 +54,39 @@ type ParseSet struct {
 	conditionMapper *cedarConditions.CedarConditionMapper
 }
 
+// MappingIssue describes a Cedar policy that could not be mapped to IDQL
+type MappingIssue struct {
+	Location string // file:line:column of the policy
+	PolicyId string // the policy id (if known, e.g. an AVP policy id)
+	Message  string
+}
+
+func (i MappingIssue) String() string {
+	if i.PolicyId != "" {
+		return fmt.Sprintf("%s (%s): %s", i.Location, i.PolicyId, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.Location, i.Message)
+}
+
+/*
+MappingReport is returned as the error by MapCedarPolicyBytes when one or more policies could not be mapped. The
+policies that could be mapped are still returned so that a caller may treat the report as a set of warnings.
+*/
+type MappingReport []MappingIssue
+
+func (r MappingReport) Error() string {
+	issues := make([]string, len(r))
+	for i, issue := range r {
+		issues[i] = issue.String()
+	}
+	return fmt.Sprintf("%d cedar policies could not be mapped:\n%s", len(r), strings.Join(issues, "\n"))
+}
+
+/*
+MapCedarPolicyBytes maps each Cedar policy in cedarBytes to IDQL. A syntax error in cedarBytes is returned as an
+error. Policies which parse but cannot be mapped are skipped and reported in a MappingReport (returned as the error)
+along with the policies that were mapped.
+*/
 func (c *CedarMapper) MapCedarPolicyBytes(location string, cedarBytes []byte) (*hexapolicy.Policies, error) {
 
 	policies, err := cedar.NewPolicyListFromBytes(location, cedarBytes)
@@ -60,19 +101,38 @@ func (c *CedarMapper) MapCedarPolicyBytes(location string, cedarBytes []byte) (*
 		conditionMapper: c.condMap,
 	}
 
-	for _, cedarPolicy := range policies {
+	sources := policySources(cedarBytes, policies)
+	var report MappingReport
+	for i, cedarPolicy := range policies {
 		err := cset.MapCedarPolicy(cedarPolicy)
-		if err != nil {
-			break
+		if err == nil {
+			// keep the original text so that an unchanged policy can be re-emitted as is (see MapHexaPolicy)
+			mapped := &cset.IdqlPolicies[len(cset.IdqlPolicies)-1]
+			if mapped.Meta.SourceData == nil {
+				mapped.Meta.SourceData = make(map[string]interface{})
+			}
+			mapped.Meta.SourceData[SourceDataCedar] = sources[i]
+		} else {
+			pos := cedarPolicy.Position()
+			issue := MappingIssue{
+				Location: fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column),
+				Message:  err.Error(),
+			}
+			if id, ok := cedarPolicy.Annotations()["id"]; ok {
+				issue.PolicyId = string(id)
+			}
+			report = append(report, issue)
 		}
-
 	}
 
-	return &hexapolicy.Policies{
+	mapped := &hexapolicy.Policies{
 		Policies: cset.IdqlPolicies,
 		App:      &location,
-	}, err
-
+	}
+	if len(report) > 0 {
+		return mapped, report
+	}
+	return mapped, nil
 }
 
 func (t *ParseSet) MapCedarPolicy(policy *cedar.Policy) error {
@@ -146,30 +206,158 @@ func (c *CedarMapper) MapHexaPolicies(location string, policies []hexapolicy.Pol
 	return sb.String(), nil
 }
 
+/*
+policySources returns the text of each policy. The text of a policy starts at any comment lines that immediately
+precede it and ends where the next policy's text starts.
+*/
+func policySources(cedarBytes []byte, policies cedar.PolicyList) []string {
+	starts := make([]int, len(policies))
+	for i, policy := range policies {
+		starts[i] = leadingCommentStart(cedarBytes, policy.Position().Offset)
+	}
+	sources := make([]string, len(policies))
+	for i, start := range starts {
+		end := len(cedarBytes)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		sources[i] = strings.TrimSpace(string(cedarBytes[start:end]))
+	}
+	return sources
+}
+
+// leadingCommentStart returns the offset of the comment and blank lines that precede the policy at offset
+func leadingCommentStart(src []byte, offset int) int {
+	start := offset
+	for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+		start--
+	}
+	if start > 0 && src[start-1] != '\n' {
+		// the policy follows another statement on the same line
+		return offset
+	}
+	for start > 0 {
+		lineStart := bytes.LastIndexByte(src[:start-1], '\n') + 1
+		line := strings.TrimSpace(string(src[lineStart : start-1]))
+		if line != "" && !strings.HasPrefix(line, "//") {
+			break
+		}
+		start = lineStart
+	}
+	return start
+}
+
+// sourceUnchanged returns true if the policy is semantically the same as the Cedar source it was mapped from
+func (t *ParseSet) sourceUnchanged(policy hexapolicy.PolicyInfo, source string) bool {
+	cedarPolicies, err := cedar.NewPolicyListFromBytes(t.loc, []byte(source))
+	if err != nil || len(cedarPolicies) != 1 {
+		return false
+	}
+	original := ParseSet{conditionMapper: t.conditionMapper}
+	if original.MapCedarPolicy(cedarPolicies[0]) != nil {
+		return false
+	}
+	sourcePolicy := original.IdqlPolicies[0]
+	return sourcePolicy.Equals(policy) &&
+		reflect.DeepEqual(sourcePolicy.Meta.SourceData[SourceDataAnnotations], policy.Meta.SourceData[SourceDataAnnotations])
+}
+
+/*
+MapHexaPolicy maps an IDQL policy to Cedar. If the policy was mapped from Cedar (see SourceDataCedar) and has not
+changed, the original Cedar text is returned so that comments, annotations and the form of conditions are kept.
+*/
 func (t *ParseSet) MapHexaPolicy(policy hexapolicy.PolicyInfo) (string, error) {
+	if source, ok := policy.Meta.SourceData[SourceDataCedar].(string); ok && t.sourceUnchanged(policy, source) {
+		return source + "\n", nil
+	}
+
 	pp := PolicyPair{
 		HexaPolicy: &policy,
 		res:        strings.Builder{},
 	}
 
 	annotations := pp.mapHexaAnnotations()
-	subjects := pp.mapHexaSubjects()
+	subjects, subjectConditions := pp.mapHexaSubjects()
 	actions := pp.mapHexaAction()
-	resource := pp.mapHexaResource()
+	resource, resourceCondition := pp.mapHexaResource()
 
 	condition, err := t.conditionMapper.MapConditionToCedar(pp.HexaPolicy.Condition)
 	if err != nil {
 		return "", err
 	}
 
-	// conditions ;= pp.mapConditions()
-	for _, subject := range subjects {
-		pp.writeCedarPolicy(annotations, subject, actions, resource, condition)
+	for i, subject := range subjects {
+		conditions := joinConditions(subjectConditions[i], resourceCondition, condition)
+		pp.writeCedarPolicy(annotations, subject, actions, resource, conditions)
 	}
 
 	return pp.res.String(), nil
 }
 
+/*
+mapHexaScope maps a principal or resource member to a Cedar scope. Cedar only permits a set of entities in the action
+scope, so a set (e.g. "principal in [Group::"a",Group::"b"]") is returned as a when clause and the scope is relaxed
+to the entity type (if any).
+*/
+func mapHexaScope(verb string, member string) (string, string) {
+	scope := mapHexaValue(verb, member)
+	if member == "" || strings.EqualFold(member, "any") || strings.EqualFold(member, "anyAuthenticated") {
+		return scope, ""
+	}
+	path := hexaTypes.ParseEntity(member)
+	if path.In == nil || len(*path.In) < 2 {
+		return scope, ""
+	}
+	comma := ","
+	if verb == "resource" {
+		comma = ""
+	}
+	when := fmt.Sprintf("when { %s in %s }", verb, cedarEntitySet(*path.In))
+	switch path.Type {
+	case hexaTypes.RelTypeIn:
+		return verb + comma, when
+	case hexaTypes.RelTypeIsIn:
+		return fmt.Sprintf("%s is %s%s", verb, strings.Join(path.Types, "::"), comma), when
+	}
+	return scope, ""
+}
+
+// cedarEntityId returns an IDQL entity id as a Cedar string. Ids mapped from Cedar are already quoted (e.g. User:"alice").
+func cedarEntityId(id string) string {
+	if _, err := strconv.Unquote(id); err == nil && strings.HasPrefix(id, "\"") {
+		return id
+	}
+	return strconv.Quote(id)
+}
+
+// cedarEntitySet returns a Cedar set literal of entities (e.g. [Group::"a",Group::"b"])
+func cedarEntitySet(entities []hexaTypes.Entity) string {
+	sb := strings.Builder{}
+	sb.WriteString("[")
+	for i, entity := range entities {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		types := strings.Join(entity.Types, "::")
+		if entity.Id != nil {
+			types = types + "::" + cedarEntityId(*entity.Id)
+		}
+		sb.WriteString(types)
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func joinConditions(clauses ...string) string {
+	var conditions []string
+	for _, clause := range clauses {
+		if clause != "" {
+			conditions = append(conditions, clause)
+		}
+	}
+	return strings.Join(conditions, "\n")
+}
+
 func (pp *PolicyPair) writeCedarPolicy(annotations, subject, actions, resource, conditions string) {
 	pp.res.WriteString(annotations)
 	// Note: IDQL policies are always a permit
@@ -202,7 +390,11 @@ func (pp *PolicyPair) mapCedarAnnotations() {
 	if meta.SourceData == nil {
 		meta.SourceData = make(map[string]interface{})
 	}
-	meta.SourceData["annotations"] = aMap
+	annotationMap := make(map[string]interface{}, len(aMap))
+	for key, val := range aMap {
+		annotationMap[string(key)] = string(val)
+	}
+	meta.SourceData[SourceDataAnnotations] = annotationMap
 	pp.HexaPolicy.Meta = meta
 }
 
@@ -212,13 +404,18 @@ func (pp *PolicyPair) mapHexaAnnotations() string {
 	if sourceData == nil {
 		return ""
 	}
-	annotationMap, ok := sourceData["annotations"].(map[string]interface{})
+	annotationMap, ok := sourceData[SourceDataAnnotations].(map[string]interface{})
 	if !ok {
 		return ""
 	}
+	keys := make([]string, 0, len(annotationMap))
+	for key := range annotationMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 	sb := strings.Builder{}
-	for key, val := range annotationMap {
-		sb.WriteString(fmt.Sprintf("@%s(\"%s\")\n", key, val))
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("@%s(%s)\n", key, strconv.Quote(fmt.Sprint(annotationMap[key]))))
 	}
 	return sb.String()
 }
@@ -341,7 +538,7 @@ func mapHexaValue(verb string, member string) string {
 	switch path.Type {
 	case hexaTypes.RelTypeEquals:
 		types := strings.Join(path.Types, "::")
-		id := strconv.Quote(*path.Id)
+		id := cedarEntityId(*path.Id)
 		if verb == "action" {
 			return fmt.Sprintf("%s::%s", types, id)
 		}
@@ -356,24 +553,11 @@ func mapHexaValue(verb string, member string) string {
 			entity := inset[0]
 			types := strings.Join(entity.Types, "::")
 			if entity.Id != nil {
-				types = types + "::" + strconv.Quote(*entity.Id)
+				types = types + "::" + cedarEntityId(*entity.Id)
 			}
 			return fmt.Sprintf("%s in %s%s", verb, types, comma)
 		}
-		sb := strings.Builder{}
-		sb.WriteString("[")
-		for i, entity := range *path.In {
-			if i > 0 {
-				sb.WriteString(",")
-			}
-			types := strings.Join(entity.Types, "::")
-			if entity.Id != nil {
-				types = types + "::" + strconv.Quote(*entity.Id)
-			}
-			sb.WriteString(types)
-		}
-		sb.WriteString("]")
-		return fmt.Sprintf("%s in %s%s", verb, sb.String(), comma)
+		return fmt.Sprintf("%s in %s%s", verb, cedarEntitySet(*path.In), comma)
 	case hexaTypes.RelTypeIsIn:
 		sb := strings.Builder{}
 		if len(*path.In) == 1 {
@@ -382,22 +566,11 @@ func mapHexaValue(verb string, member string) string {
 			entity := inset[0]
 			types := strings.Join(entity.Types, "::")
 			if entity.Id != nil {
-				types = types + "::" + strconv.Quote(*entity.Id)
+				types = types + "::" + cedarEntityId(*entity.Id)
 			}
 			sb.WriteString(types)
 		} else {
-			sb.WriteString("[")
-			for i, entity := range *path.In {
-				if i > 0 {
-					sb.WriteString(",")
-				}
-				types := strings.Join(entity.Types, "::")
-				if entity.Id != nil {
-					types = types + "::" + strconv.Quote(*entity.Id)
-				}
-				sb.WriteString(types)
-			}
-			sb.WriteString("]")
+			sb.WriteString(cedarEntitySet(*path.In))
 		}
 		types := strings.Join(path.Types, "::")
 		return fmt.Sprintf("%s is %s in %s%s", verb, types, sb.String(), comma)
@@ -405,19 +578,23 @@ func mapHexaValue(verb string, member string) string {
 	return "<unexpected type [" + path.Type + "]>"
 }
 
-func (pp *PolicyPair) mapHexaSubjects() []string {
+// mapHexaSubjects returns a principal scope and any additional when clause (see mapHexaScope) for each subject
+func (pp *PolicyPair) mapHexaSubjects() ([]string, []string) {
 	if pp.HexaPolicy == nil || pp.HexaPolicy.Subjects == nil {
-		return nil
+		return nil, nil
 	}
 	members := pp.HexaPolicy.Subjects
 	if len(members) == 0 || strings.EqualFold(members[0], hexapolicy.SubjectAnyUser) {
-		return []string{"principal,"}
+		return []string{"principal,"}, []string{""}
 	}
 	res := make([]string, 0)
+	conditions := make([]string, 0)
 	for _, member := range members {
-		res = append(res, mapHexaValue("principal", member))
+		scope, condition := mapHexaScope("principal", member)
+		res = append(res, scope)
+		conditions = append(conditions, condition)
 	}
-	return res
+	return res, conditions
 }
 
 func (pp *PolicyPair) mapCedarAction() {
@@ -467,11 +644,10 @@ func (pp *PolicyPair) mapCedarResource() {
 
 }
 
-func (pp *PolicyPair) mapHexaResource() string {
+func (pp *PolicyPair) mapHexaResource() (string, string) {
 	resource := pp.HexaPolicy.Object.String()
 
-	return mapHexaValue("resource", resource)
-
+	return mapHexaScope("resource", resource)
 }
 
 func (pp *PolicyPair) mapCedarConditions() error {
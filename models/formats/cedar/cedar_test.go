@@ -2,7 +2,10 @@ package cedar
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -291,6 +294,59 @@ when { resource in PhotoShop::"Photo" };`,
   "Rule": "resource in PhotoShop::\"Photo\"",
   "Action": "allow"
  }
+}`,
+			err: false},
+		{
+			name: "Quoted ids",
+			cedar: `permit (
+  principal == User::"alice",
+  action == Action::"viewPhoto",
+  resource in Album::"vacation"
+);`,
+			idql: `{
+ "meta": {"version": "0.7"},
+ "subjects": [ "User:\"alice\"" ],
+ "actions": [ "Action:\"viewPhoto\"" ],
+ "object": "[Album:\"vacation\"]"
+}`,
+			err: false},
+		{
+			name: "Subject set",
+			cedar: `permit (
+  principal is User,
+  action == Action::"viewPhoto",
+  resource
+)
+when { principal in [Group::"AVTeam",Group::"admins"] }
+when { resource in PhotoShop::"Photo" };`,
+			idql: `{
+ "meta": {"version": "0.7"},
+ "subjects": [
+   "User[Group:AVTeam,Group:admins]"
+  ],
+ "actions": [ "Action:viewPhoto" ],
+ "object": "",
+ "Condition": {
+  "Rule": "resource in PhotoShop::\"Photo\"",
+  "Action": "allow"
+ }
+}`,
+			err: false},
+		{
+			name: "Resource set",
+			cedar: `permit (
+  principal in Group::"AVTeam",
+  action == Action::"viewPhoto",
+  resource
+)
+when { resource in [Album::"vacation",Album::"family"] };`,
+			idql: `{
+ "meta": {"version": "0.7"},
+ "subjects": [
+   "[Group:AVTeam]"
+  ],
+ "actions": [ "Action:viewPhoto" ],
+ "object": "[Album:vacation,Album:family]"
 }`,
 			err: false},
 	}
@@ -346,3 +402,119 @@ func testutilEquals[T any](t testing.TB, a, b T) {
 	}
 	t.Fatalf("\ngot  %+v\nwant %+v", a, b)
 }
+
+func TestMapCedarReport(t *testing.T) {
+	policies := `@id("good")
+permit (
+  principal == User::"alice",
+  action == Action::"viewPhoto",
+  resource
+)
+when { context.ip.isInRange(ip("10.0.0.0/8")) };
+
+@id("bad")
+permit (
+  principal,
+  action,
+  resource
+)
+when { resource.name like "a*b*c" };
+
+permit (
+  principal,
+  action,
+  resource
+)
+when { context.ip.isLoopback() };
+`
+	mapper := NewCedarMapper(map[string]string{})
+	result, err := mapper.MapCedarPolicyBytes("report.cedar", []byte(policies))
+	assert.Error(t, err)
+	var report MappingReport
+	assert.ErrorAs(t, err, &report)
+	assert.Len(t, report, 2)
+	assert.Equal(t, "report.cedar:9:1", report[0].Location)
+	assert.Equal(t, "bad", report[0].PolicyId)
+	assert.Contains(t, report[0].Message, "like")
+	assert.Equal(t, "report.cedar:17:1", report[1].Location)
+	assert.Contains(t, err.Error(), "2 cedar policies could not be mapped")
+
+	assert.NotNil(t, result)
+	assert.Len(t, result.Policies, 1)
+	assert.Equal(t, "context.ip in \"10.0.0.0/8\"", result.Policies[0].Condition.Rule)
+
+	_, err = mapper.MapCedarPolicyBytes("bad.cedar", []byte("permit (principal, action, resource"))
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &report), "syntax errors are not a mapping report")
+}
+
+// roundTripCorpus returns the Cedar policy files in examples/policyExamples and test/roundtrip
+func roundTripCorpus(t *testing.T) []string {
+	examples, err := filepath.Glob(filepath.Join("..", "..", "..", "examples", "policyExamples", "cedar*.txt"))
+	assert.NoError(t, err)
+	corpus, err := filepath.Glob(filepath.Join("test", "roundtrip", "*.cedar"))
+	assert.NoError(t, err)
+	files := append(examples, corpus...)
+	assert.GreaterOrEqual(t, len(files), 5, "expecting the round trip corpus files")
+	return files
+}
+
+func TestCedarRoundTrip(t *testing.T) {
+	mapper := NewCedarMapper(map[string]string{})
+	for _, file := range roundTripCorpus(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			cedarBytes, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			policies, err := mapper.MapCedarPolicyBytes(file, cedarBytes)
+			assert.NoError(t, err)
+
+			// save and reload the IDQL as a policy file would be
+			idqlBytes, err := json.Marshal(policies)
+			assert.NoError(t, err)
+			var saved hexapolicy.Policies
+			assert.NoError(t, json.Unmarshal(idqlBytes, &saved))
+
+			cedarOut, err := mapper.MapHexaPolicies(file, saved.Policies)
+			assert.NoError(t, err)
+			for _, policy := range policies.Policies {
+				source := policy.Meta.SourceData[SourceDataCedar].(string)
+				assert.Contains(t, cedarOut, source, "original policy text should be re-emitted")
+			}
+
+			roundTrip, err := mapper.MapCedarPolicyBytes(file, []byte(cedarOut))
+			assert.NoError(t, err)
+			assert.Len(t, roundTrip.Policies, len(policies.Policies))
+			for i, policy := range roundTrip.Policies {
+				assert.Equal(t, policies.Policies[i].Meta.SourceData, policy.Meta.SourceData)
+			}
+
+			difs := policies.ReconcilePolicies(roundTrip.Policies, true)
+			assert.Empty(t, difs, "round trip should not report differences")
+		})
+	}
+}
+
+func TestCedarRoundTripChanged(t *testing.T) {
+	mapper := NewCedarMapper(map[string]string{})
+	cedarBytes, err := os.ReadFile(filepath.Join("test", "roundtrip", "photoAnnotated.cedar"))
+	assert.NoError(t, err)
+	policies, err := mapper.MapCedarPolicyBytes("photoAnnotated.cedar", cedarBytes)
+	assert.NoError(t, err)
+	assert.Len(t, policies.Policies, 2)
+
+	// a changed policy is mapped from IDQL while the unchanged policy keeps its comments
+	policies.Policies[1].Actions = []hexapolicy.ActionInfo{"PhotoApp:Action:editPhoto"}
+	cedarOut, err := mapper.MapHexaPolicies("photoAnnotated.cedar", policies.Policies)
+	assert.NoError(t, err)
+	assert.Contains(t, cedarOut, "// Alice may view her own vacation photo")
+	assert.NotContains(t, cedarOut, "// Stacey may view")
+	assert.Contains(t, cedarOut, `action == PhotoApp::Action::"editPhoto"`)
+	assert.Contains(t, cedarOut, `@id("stacey-view")`)
+
+	// changed annotations are also mapped from IDQL
+	policies.Policies[0].Meta.SourceData[SourceDataAnnotations] = map[string]interface{}{"id": "alice-view"}
+	cedarOut, err = mapper.MapHexaPolicies("photoAnnotated.cedar", policies.Policies[0:1])
+	assert.NoError(t, err)
+	assert.NotContains(t, cedarOut, "direct grant")
+	assert.Contains(t, cedarOut, `@id("alice-view")`)
+}
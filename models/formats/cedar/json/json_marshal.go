@@ -4,6 +4,8 @@ package json
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/cedar-policy/cedar-go/types"
 	"github.com/cedar-policy/cedar-go/x/exp/ast"
@@ -290,6 +292,45 @@ func (j *NodeJSON) MarshalJSON() ([]byte, error) {
 	return json.Marshal((*nodeJSONAlias)(j))
 }
 
+// nodeKeys are the JSON keys of NodeJSON fields. Any other key is an extension call (e.g. ip, decimal, isInRange).
+var nodeKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	nodeType := reflect.TypeOf(NodeJSON{})
+	for i := 0; i < nodeType.NumField(); i++ {
+		name, _, _ := strings.Cut(nodeType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
+func (j *NodeJSON) UnmarshalJSON(b []byte) error {
+	type nodeJSONAlias NodeJSON
+	if err := json.Unmarshal(b, (*nodeJSONAlias)(j)); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		if nodeKeys[key] {
+			continue
+		}
+		var args arrayJSON
+		if err := json.Unmarshal(value, &args); err != nil {
+			return err
+		}
+		if j.ExtensionCall == nil {
+			j.ExtensionCall = extensionJSON{}
+		}
+		j.ExtensionCall[key] = args
+	}
+	return nil
+}
+
 type Policy ast.Policy
 
 func wrapPolicy(p *ast.Policy) *Policy {
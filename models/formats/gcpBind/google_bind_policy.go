@@ -2,6 +2,7 @@ package gcpBind
 
 import (
     "encoding/json"
+    "errors"
     "fmt"
     "os"
     "strings"
@@ -88,6 +89,9 @@ func (m *GooglePolicyMapper) MapBindingToPolicy(objectId string, binding iam.Bin
 }
 
 func (m *GooglePolicyMapper) MapPolicyToBinding(policy hexapolicy.PolicyInfo) (*iam.Binding, error) {
+    if IsDenyPolicy(policy) {
+        return nil, errors.New("policies with a deny condition must be mapped to a deny policy (see MapPolicyToDenyRule)")
+    }
     cond := policy.Condition
     var condExpr *iam.Expr
     var err error
@@ -111,6 +115,9 @@ func (m *GooglePolicyMapper) MapPoliciesToBindings(policies []hexapolicy.PolicyI
     bindingMap := make(map[string][]iam.Binding)
 
     for i, policy := range policies {
+        if IsDenyPolicy(policy) {
+            continue // see MapPoliciesToDenyPolicies
+        }
         binding, err := m.MapPolicyToBinding(policy)
         if err != nil {
             fmt.Println(err.Error())
@@ -0,0 +1,316 @@
+package gcpBind
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/url"
+    "os"
+    "strings"
+
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+    iamv2 "google.golang.org/api/iam/v2"
+)
+
+/*
+Google IAM v2 deny policies (see https://cloud.google.com/iam/docs/deny-overview) are mapped to IDQL policies whose
+condition action is deny. Each deny rule becomes one IDQL policy:
+  - deniedPrincipals are the subjects, converted to the member form used by bindings (e.g. user:alice@example.com)
+  - deniedPermissions are the actions, prefixed with "gcp:"
+  - the object is the attachment point of the deny policy (e.g. cloudresourcemanager.googleapis.com/projects/my-project)
+  - denialCondition is mapped using the CEL condition mapper. A rule without a condition uses UnconditionalDenyRule.
+  - exceptionPrincipals and exceptionPermissions have no IDQL equivalent and are kept in the policy sourceData
+*/
+
+const (
+    SourceDataDenyPolicy           = "denyPolicy"
+    SourceDataExceptionPrincipals  = "exceptionPrincipals"
+    SourceDataExceptionPermissions = "exceptionPermissions"
+
+    // DefaultDenyPolicyId is used for deny rules that have no deny policy id or IDQL policy id
+    DefaultDenyPolicyId = "hexa-deny-policy"
+
+    // UnconditionalDenyRule is the IDQL deny condition used for a deny rule that has no denialCondition
+    UnconditionalDenyRule = "subject pr"
+
+    PrincipalPublic = "principalSet://goog/public:all"
+    MemberAllUsers  = "allUsers"
+)
+
+var memberPrincipalPrefixes = [][2]string{
+    {"user:", "principal://goog/subject/"},
+    {"group:", "principalSet://goog/group/"},
+    {"serviceAccount:", "principal://iam.googleapis.com/projects/-/serviceAccounts/"},
+}
+
+// IsDenyPolicy returns true when the policy condition action is deny
+func IsDenyPolicy(policy hexapolicy.PolicyInfo) bool {
+    return policy.Condition != nil && strings.EqualFold(policy.Condition.Action, conditions.ADeny)
+}
+
+// DenyPolicyName returns the resource name of a deny policy (policies/{attachment_point}/denypolicies/{policy_id})
+func DenyPolicyName(attachmentPoint, policyId string) string {
+    return fmt.Sprintf("policies/%s/denypolicies/%s", url.PathEscape(attachmentPoint), policyId)
+}
+
+// ParseDenyPolicyName returns the attachment point and policy id of a deny policy resource name
+func ParseDenyPolicyName(name string) (string, string, error) {
+    attachment, policyId, found := strings.Cut(strings.TrimPrefix(name, "policies/"), "/denypolicies/")
+    if !found || !strings.HasPrefix(name, "policies/") || policyId == "" {
+        return "", "", fmt.Errorf("invalid deny policy name: %s", name)
+    }
+    attachmentPoint, err := url.PathUnescape(attachment)
+    if err != nil {
+        return "", "", fmt.Errorf("invalid deny policy attachment point: %s", err.Error())
+    }
+    return attachmentPoint, policyId, nil
+}
+
+func (m *GooglePolicyMapper) MapDenyPoliciesToPolicies(denyPolicies []*iamv2.GoogleIamV2Policy) ([]hexapolicy.PolicyInfo, error) {
+    var policies []hexapolicy.PolicyInfo
+    for _, denyPolicy := range denyPolicies {
+        pols, err := m.MapDenyPolicyToPolicies(denyPolicy)
+        if err != nil {
+            return nil, err
+        }
+        policies = append(policies, pols...)
+    }
+    return policies, nil
+}
+
+// MapDenyPolicyToPolicies returns an IDQL policy for each deny rule of the deny policy
+func (m *GooglePolicyMapper) MapDenyPolicyToPolicies(denyPolicy *iamv2.GoogleIamV2Policy) ([]hexapolicy.PolicyInfo, error) {
+    policies, _, errs := m.MapDenyPolicyRules(denyPolicy)
+    if len(errs) > 0 {
+        return nil, errs[0]
+    }
+    return policies, nil
+}
+
+/*
+MapDenyPolicyRules returns an IDQL policy for each deny rule of the deny policy that can be mapped. Rules that cannot be
+mapped (e.g. a denialCondition with an unsupported CEL function) are returned in unmapped, with an error for each.
+*/
+func (m *GooglePolicyMapper) MapDenyPolicyRules(denyPolicy *iamv2.GoogleIamV2Policy) (policies []hexapolicy.PolicyInfo, unmapped []*iamv2.GoogleIamV2PolicyRule, errs []error) {
+    attachmentPoint, policyId, err := ParseDenyPolicyName(denyPolicy.Name)
+    if err != nil {
+        return nil, denyPolicy.Rules, []error{err}
+    }
+
+    for i, rule := range denyPolicy.Rules {
+        if rule.DenyRule == nil {
+            continue
+        }
+        policy, err := m.MapDenyRuleToPolicy(attachmentPoint, *rule.DenyRule)
+        if err != nil {
+            unmapped = append(unmapped, rule)
+            errs = append(errs, fmt.Errorf("deny policy %s rule %d: %s", policyId, i, err.Error()))
+            continue
+        }
+        id := policyId
+        if len(denyPolicy.Rules) > 1 {
+            id = fmt.Sprintf("%s-%d", policyId, i)
+        }
+        policy.Meta.PolicyId = &id
+        policy.Meta.Description = rule.Description
+        policy.Meta.SourceData[SourceDataDenyPolicy] = policyId
+        policies = append(policies, policy)
+    }
+    return policies, unmapped, errs
+}
+
+func (m *GooglePolicyMapper) MapDenyRuleToPolicy(attachmentPoint string, rule iamv2.GoogleIamV2DenyRule) (hexapolicy.PolicyInfo, error) {
+    condition := conditions.ConditionInfo{Rule: UnconditionalDenyRule}
+    if rule.DenialCondition != nil && rule.DenialCondition.Expression != "" {
+        var err error
+        condition, err = m.conditionMapper.MapProviderToCondition(rule.DenialCondition.Expression)
+        if err != nil {
+            return hexapolicy.PolicyInfo{}, err
+        }
+    }
+    condition.Action = conditions.ADeny
+
+    subjects := make([]string, len(rule.DeniedPrincipals))
+    for i, principal := range rule.DeniedPrincipals {
+        subjects[i] = convertPrincipalToMember(principal)
+    }
+    var actions []hexapolicy.ActionInfo
+    for _, permission := range rule.DeniedPermissions {
+        actions = append(actions, hexapolicy.ActionInfo("gcp:"+permission))
+    }
+
+    sourceData := make(map[string]interface{})
+    if len(rule.ExceptionPrincipals) > 0 {
+        sourceData[SourceDataExceptionPrincipals] = rule.ExceptionPrincipals
+    }
+    if len(rule.ExceptionPermissions) > 0 {
+        sourceData[SourceDataExceptionPermissions] = rule.ExceptionPermissions
+    }
+
+    return hexapolicy.PolicyInfo{
+        Meta:      hexapolicy.MetaInfo{Version: hexapolicy.IdqlVersion, SourceData: sourceData},
+        Subjects:  subjects,
+        Actions:   actions,
+        Object:    hexapolicy.ObjectInfo(attachmentPoint),
+        Condition: &condition,
+    }, nil
+}
+
+// MapPolicyToDenyRule maps an IDQL policy with a deny condition to a deny policy rule
+func (m *GooglePolicyMapper) MapPolicyToDenyRule(policy hexapolicy.PolicyInfo) (*iamv2.GoogleIamV2PolicyRule, error) {
+    if !IsDenyPolicy(policy) {
+        return nil, errors.New("policy does not have a deny condition")
+    }
+    if len(policy.Subjects) == 0 {
+        return nil, errors.New("deny rules require at least one subject")
+    }
+    if len(policy.Actions) == 0 {
+        return nil, errors.New("deny rules require at least one action")
+    }
+
+    rule := iamv2.GoogleIamV2DenyRule{
+        ExceptionPrincipals:  sourceDataStrings(policy.Meta.SourceData[SourceDataExceptionPrincipals]),
+        ExceptionPermissions: sourceDataStrings(policy.Meta.SourceData[SourceDataExceptionPermissions]),
+    }
+    for _, subject := range policy.Subjects {
+        rule.DeniedPrincipals = append(rule.DeniedPrincipals, convertMemberToPrincipal(subject))
+    }
+    for _, action := range policy.Actions {
+        rule.DeniedPermissions = append(rule.DeniedPermissions, strings.TrimPrefix(string(action), "gcp:"))
+    }
+    if strings.TrimSpace(policy.Condition.Rule) != UnconditionalDenyRule {
+        celString, err := m.conditionMapper.MapConditionToProvider(*policy.Condition)
+        if err != nil {
+            return nil, err
+        }
+        rule.DenialCondition = &iamv2.GoogleTypeExpr{Expression: celString}
+    }
+
+    return &iamv2.GoogleIamV2PolicyRule{DenyRule: &rule, Description: policy.Meta.Description}, nil
+}
+
+/*
+MapPoliciesToDenyPolicies maps the IDQL policies that have a deny condition to deny policies. Other policies are ignored
+(see MapPoliciesToBindings). Rules are grouped into deny policies by object (the attachment point) and by the
+sourceData denyPolicy value, the IDQL policy id, or DefaultDenyPolicyId.
+*/
+func (m *GooglePolicyMapper) MapPoliciesToDenyPolicies(policies []hexapolicy.PolicyInfo) ([]*iamv2.GoogleIamV2Policy, error) {
+    var denyPolicies []*iamv2.GoogleIamV2Policy
+    policyMap := make(map[string]*iamv2.GoogleIamV2Policy)
+    for _, policy := range policies {
+        if !IsDenyPolicy(policy) {
+            continue
+        }
+        attachmentPoint := policy.Object.String()
+        if attachmentPoint == "" {
+            return nil, errors.New("deny policies require an object identifying the attachment point")
+        }
+        rule, err := m.MapPolicyToDenyRule(policy)
+        if err != nil {
+            return nil, err
+        }
+
+        policyId, _ := policy.Meta.SourceData[SourceDataDenyPolicy].(string)
+        if policyId == "" && policy.Meta.PolicyId != nil {
+            policyId = *policy.Meta.PolicyId
+        }
+        if policyId == "" {
+            policyId = DefaultDenyPolicyId
+        }
+        name := DenyPolicyName(attachmentPoint, policyId)
+        denyPolicy, exists := policyMap[name]
+        if !exists {
+            denyPolicy = &iamv2.GoogleIamV2Policy{Name: name, DisplayName: policyId}
+            policyMap[name] = denyPolicy
+            denyPolicies = append(denyPolicies, denyPolicy)
+        }
+        denyPolicy.Rules = append(denyPolicy.Rules, rule)
+    }
+    return denyPolicies, nil
+}
+
+func convertMemberToPrincipal(member string) string {
+    if member == MemberAllUsers || member == hexapolicy.SubjectAnyUser {
+        return PrincipalPublic
+    }
+    for _, prefix := range memberPrincipalPrefixes {
+        if strings.HasPrefix(member, prefix[0]) {
+            return prefix[1] + member[len(prefix[0]):]
+        }
+    }
+    return member // allow principal identifiers to be passed unmapped
+}
+
+func convertPrincipalToMember(principal string) string {
+    if principal == PrincipalPublic {
+        return MemberAllUsers
+    }
+    for _, prefix := range memberPrincipalPrefixes {
+        if strings.HasPrefix(principal, prefix[1]) {
+            return prefix[0] + principal[len(prefix[1]):]
+        }
+    }
+    return principal
+}
+
+// sourceDataStrings handles source data values that are either []string or have been parsed from JSON
+func sourceDataStrings(value interface{}) []string {
+    switch values := value.(type) {
+    case []string:
+        return values
+    case []interface{}:
+        var ret []string
+        for _, v := range values {
+            if s, ok := v.(string); ok {
+                ret = append(ret, s)
+            }
+        }
+        return ret
+    }
+    return nil
+}
+
+/*
+ParseDenyPolicies parses a single deny policy, an array of deny policies, or a list policies response
+(e.g. from gcloud iam policies list).
+*/
+func ParseDenyPolicies(policyBytes []byte) ([]*iamv2.GoogleIamV2Policy, error) {
+    trimmed := strings.TrimSpace(string(policyBytes))
+    if trimmed == "" {
+        return nil, fmt.Errorf("no bytes to unmarshal")
+    }
+
+    var denyPolicies []*iamv2.GoogleIamV2Policy
+    if trimmed[0] == '[' {
+        err := json.Unmarshal(policyBytes, &denyPolicies)
+        return denyPolicies, err
+    }
+
+    var list iamv2.GoogleIamV2ListPoliciesResponse
+    if err := json.Unmarshal(policyBytes, &list); err != nil {
+        return nil, err
+    }
+    if len(list.Policies) > 0 {
+        return list.Policies, nil
+    }
+    var single iamv2.GoogleIamV2Policy
+    if err := json.Unmarshal(policyBytes, &single); err != nil {
+        return nil, err
+    }
+    if single.Name == "" {
+        return nil, errors.New("deny policy name is missing")
+    }
+    return []*iamv2.GoogleIamV2Policy{&single}, nil
+}
+
+/*
+ParseDenyFile will load a file from the specified path and return the deny policies it contains. See ParseDenyPolicies
+*/
+func ParseDenyFile(path string) ([]*iamv2.GoogleIamV2Policy, error) {
+    policyBytes, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return ParseDenyPolicies(policyBytes)
+}
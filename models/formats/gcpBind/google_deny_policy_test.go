@@ -0,0 +1,166 @@
+package gcpBind_test
+
+import (
+    "encoding/json"
+    "path/filepath"
+    "runtime"
+    "testing"
+
+    "github.com/hexa-org/policy-mapper/models/formats/gcpBind"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+    "github.com/stretchr/testify/assert"
+    iamv2 "google.golang.org/api/iam/v2"
+)
+
+const attachmentPoint = "cloudresourcemanager.googleapis.com/projects/hexa-demo"
+
+func getDenyFile() string {
+    _, file, _, _ := runtime.Caller(0)
+    return filepath.Join(file, "../test/test_deny_policy.json")
+}
+
+func TestMapDenyPolicyToPolicies(t *testing.T) {
+    denyPolicies, err := gcpBind.ParseDenyFile(getDenyFile())
+    assert.NoError(t, err)
+    assert.Len(t, denyPolicies, 1)
+
+    policies, err := gcpMapper.MapDenyPoliciesToPolicies(denyPolicies)
+    assert.NoError(t, err)
+    assert.Len(t, policies, 2)
+
+    owners := policies[0]
+    assert.Equal(t, "block-deletes-0", *owners.Meta.PolicyId)
+    assert.Equal(t, "Only the project owners group may delete projects", owners.Meta.Description)
+    assert.Equal(t, hexapolicy.SubjectInfo{gcpBind.MemberAllUsers}, owners.Subjects)
+    assert.Equal(t, []hexapolicy.ActionInfo{"gcp:cloudresourcemanager.googleapis.com/projects.delete"}, owners.Actions)
+    assert.Equal(t, attachmentPoint, owners.Object.String())
+    assert.Equal(t, &conditions.ConditionInfo{Rule: gcpBind.UnconditionalDenyRule, Action: conditions.ADeny}, owners.Condition)
+    assert.Equal(t, []string{"principalSet://goog/group/project-admins@example.com"}, owners.Meta.SourceData[gcpBind.SourceDataExceptionPrincipals])
+    assert.True(t, gcpBind.IsDenyPolicy(owners))
+
+    contractors := policies[1]
+    assert.Equal(t, "block-deletes-1", *contractors.Meta.PolicyId)
+    assert.Equal(t, hexapolicy.SubjectInfo{"user:contractor@example.com", "serviceAccount:deployer@hexa-demo.iam.gserviceaccount.com"}, contractors.Subjects)
+    assert.Equal(t, conditions.ADeny, contractors.Condition.Action)
+    assert.Equal(t, "resource.type ne \"staging\"", contractors.Condition.Rule)
+
+    // the mapped policies produce the original deny policy after a JSON round trip
+    policyBytes, err := json.Marshal(policies)
+    assert.NoError(t, err)
+    var parsed []hexapolicy.PolicyInfo
+    assert.NoError(t, json.Unmarshal(policyBytes, &parsed))
+    result, err := gcpMapper.MapPoliciesToDenyPolicies(parsed)
+    assert.NoError(t, err)
+    assert.Len(t, result, 1)
+    assert.Equal(t, denyPolicies[0].Name, result[0].Name)
+    assert.Len(t, result[0].Rules, 2)
+    assert.Equal(t, denyPolicies[0].Rules[0].DenyRule, result[0].Rules[0].DenyRule)
+    assert.Equal(t, denyPolicies[0].Rules[1].Description, result[0].Rules[1].Description)
+    assert.Equal(t, denyPolicies[0].Rules[1].DenyRule.DeniedPrincipals, result[0].Rules[1].DenyRule.DeniedPrincipals)
+    assert.Equal(t, denyPolicies[0].Rules[1].DenyRule.DeniedPermissions, result[0].Rules[1].DenyRule.DeniedPermissions)
+    assert.Equal(t, "resource.type != \"staging\"", result[0].Rules[1].DenyRule.DenialCondition.Expression)
+}
+
+func TestMapDenyPolicyRules(t *testing.T) {
+    denyPolicies, err := gcpBind.ParseDenyFile(getDenyFile())
+    assert.NoError(t, err)
+    denyPolicy := denyPolicies[0]
+    unmappable := &iamv2.GoogleIamV2PolicyRule{DenyRule: &iamv2.GoogleIamV2DenyRule{
+        DeniedPrincipals:  []string{"principal://goog/subject/eve@example.com"},
+        DeniedPermissions: []string{"cloudresourcemanager.googleapis.com/projects.update"},
+        DenialCondition:   &iamv2.GoogleTypeExpr{Expression: "resource.type =="},
+    }}
+    denyPolicy.Rules = append(denyPolicy.Rules, unmappable)
+
+    policies, unmapped, errs := gcpMapper.MapDenyPolicyRules(denyPolicy)
+    assert.Len(t, policies, 2)
+    assert.Equal(t, []*iamv2.GoogleIamV2PolicyRule{unmappable}, unmapped)
+    assert.Len(t, errs, 1)
+    assert.Contains(t, errs[0].Error(), "deny policy block-deletes rule 2")
+
+    _, err = gcpMapper.MapDenyPolicyToPolicies(denyPolicy)
+    assert.Error(t, err)
+}
+
+func TestMapPoliciesToDenyPolicies(t *testing.T) {
+    policyId := "no-admin"
+    allow := hexapolicy.PolicyInfo{
+        Subjects: []string{"user:alice@example.com"},
+        Actions:  []hexapolicy.ActionInfo{"gcp:roles/iap.httpsResourceAccessor"},
+        Object:   attachmentPoint,
+    }
+    deny := hexapolicy.PolicyInfo{
+        Meta:      hexapolicy.MetaInfo{PolicyId: &policyId},
+        Subjects:  []string{"group:contractors@example.com", "any"},
+        Actions:   []hexapolicy.ActionInfo{"iam.googleapis.com/roles.update"},
+        Object:    attachmentPoint,
+        Condition: &conditions.ConditionInfo{Rule: "resource.name sw \"projects/hexa-demo/roles/admin\"", Action: conditions.ADeny},
+    }
+    unnamed := deny
+    unnamed.Meta = hexapolicy.MetaInfo{}
+
+    denyPolicies, err := gcpMapper.MapPoliciesToDenyPolicies([]hexapolicy.PolicyInfo{allow, deny, unnamed, unnamed})
+    assert.NoError(t, err)
+    assert.Len(t, denyPolicies, 2)
+    assert.Equal(t, "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fhexa-demo/denypolicies/no-admin", denyPolicies[0].Name)
+    assert.Equal(t, &iamv2.GoogleIamV2DenyRule{
+        DeniedPrincipals:  []string{"principalSet://goog/group/contractors@example.com", gcpBind.PrincipalPublic},
+        DeniedPermissions: []string{"iam.googleapis.com/roles.update"},
+        DenialCondition:   &iamv2.GoogleTypeExpr{Expression: "resource.name.startsWith(\"projects/hexa-demo/roles/admin\")"},
+    }, denyPolicies[0].Rules[0].DenyRule)
+    assert.Equal(t, gcpBind.DenyPolicyName(attachmentPoint, gcpBind.DefaultDenyPolicyId), denyPolicies[1].Name)
+    assert.Len(t, denyPolicies[1].Rules, 2)
+
+    // deny policies are not mapped as bindings
+    _, err = gcpMapper.MapPolicyToBinding(deny)
+    assert.Error(t, err)
+    bindings := gcpMapper.MapPoliciesToBindings([]hexapolicy.PolicyInfo{allow, deny})
+    assert.Len(t, bindings, 1)
+    assert.Len(t, bindings[0].Bindings, 1)
+
+    for _, policy := range []hexapolicy.PolicyInfo{
+        {Subjects: deny.Subjects, Actions: deny.Actions, Condition: deny.Condition},
+        {Actions: deny.Actions, Object: attachmentPoint, Condition: deny.Condition},
+        {Subjects: deny.Subjects, Object: attachmentPoint, Condition: deny.Condition},
+        {Subjects: deny.Subjects, Actions: deny.Actions, Object: attachmentPoint, Condition: &conditions.ConditionInfo{Rule: "(subject.name eq \"a\"", Action: conditions.ADeny}},
+    } {
+        _, err = gcpMapper.MapPoliciesToDenyPolicies([]hexapolicy.PolicyInfo{policy})
+        assert.Error(t, err)
+    }
+    _, err = gcpMapper.MapPolicyToDenyRule(allow)
+    assert.Error(t, err)
+}
+
+func TestParseDenyPolicies(t *testing.T) {
+    name := gcpBind.DenyPolicyName(attachmentPoint, "p1")
+    for _, input := range []string{
+        `{"name":"` + name + `","rules":[]}`,
+        `[{"name":"` + name + `"}]`,
+        `{"policies":[{"name":"` + name + `"}]}`,
+    } {
+        denyPolicies, err := gcpBind.ParseDenyPolicies([]byte(input))
+        assert.NoError(t, err, input)
+        assert.Len(t, denyPolicies, 1)
+        assert.Equal(t, name, denyPolicies[0].Name)
+    }
+
+    for _, input := range []string{"", "{", "[{]", "{}", `{"policies":5}`} {
+        _, err := gcpBind.ParseDenyPolicies([]byte(input))
+        assert.Error(t, err, input)
+    }
+
+    attachment, policyId, err := gcpBind.ParseDenyPolicyName(name)
+    assert.NoError(t, err)
+    assert.Equal(t, attachmentPoint, attachment)
+    assert.Equal(t, "p1", policyId)
+    for _, bad := range []string{"p1", "policies/a/denypolicies/", "denypolicies/p1", "policies/%zz/denypolicies/p1"} {
+        _, _, err = gcpBind.ParseDenyPolicyName(bad)
+        assert.Error(t, err, bad)
+    }
+    _, err = gcpMapper.MapDenyPolicyToPolicies(&iamv2.GoogleIamV2Policy{Name: "bad"})
+    assert.Error(t, err)
+
+    _, err = gcpBind.ParseDenyFile("doesNotExist.json")
+    assert.Error(t, err)
+}
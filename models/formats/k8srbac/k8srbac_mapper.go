@@ -0,0 +1,449 @@
+// Package k8srbac maps IDQL policies to and from Kubernetes RBAC Role/ClusterRole and RoleBinding/ClusterRoleBinding
+// manifests.
+//
+// Each IDQL policy is mapped into a role with a single rule and a binding of the role to the policy subjects:
+//
+//   - Actions are the rule verbs (e.g. get, list, watch). No actions maps to the verb `*`.
+//   - The object selects the resources of the rule in the form `[<namespace>:]<resource>[.<apiGroup>]:["<name>"]`. For
+//     example `pods:` is all pods in the cluster, `default:deployments.apps:"web"` is the deployment web in namespace
+//     default, and an empty object is all resources. Objects starting with `/` are non-resource URLs (e.g. /healthz).
+//     Policies with a namespace generate a Role and RoleBinding, otherwise a ClusterRole and ClusterRoleBinding.
+//   - Subjects are `User:"alice"`, `Group:"devs"` (or `[Group:"devs"]`), and `ServiceAccount:"<namespace>/<name>"`.
+//     `anyAuthenticated` maps to the group system:authenticated, and `any` (or no subjects) also includes the group
+//     system:unauthenticated.
+//
+// Kubernetes RBAC is allow only and has no conditions, so policies with conditions are rejected.
+//
+// When mapping manifests into IDQL, each binding produces a policy for each rule and resource of the role it references.
+// The policy id is taken from the binding annotation hexaorchestration.org/policy-id or the binding name. Roles that are
+// not bound are ignored as they grant no access.
+package k8srbac
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+	"gopkg.in/yaml.v3"
+)
+
+var invalidNameRegex = regexp.MustCompile("[^a-z0-9.-]+")
+
+// resourceScope is the parsed form of an IDQL object
+type resourceScope struct {
+	namespace      string
+	apiGroup       string
+	resource       string
+	name           string
+	nonResourceURL string
+}
+
+// MapPoliciesToManifests maps IDQL policies into roles and bindings
+func MapPoliciesToManifests(policies []hexapolicy.PolicyInfo) (*Manifests, error) {
+	manifests := Manifests{}
+	names := map[string]bool{}
+	for i, policy := range policies {
+		policyId := fmt.Sprintf("policy-%d", i)
+		if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+			policyId = *policy.Meta.PolicyId
+		}
+		role, binding, err := mapPolicy(policy, policyId)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+
+		// names must be unique within the scope of the role
+		name := role.Metadata.Name
+		for n := 1; names[role.Metadata.Namespace+"/"+name]; n++ {
+			name = fmt.Sprintf("%s-%d", role.Metadata.Name, n)
+		}
+		names[role.Metadata.Namespace+"/"+name] = true
+		role.Metadata.Name = name
+		binding.Metadata.Name = name
+		binding.RoleRef.Name = name
+
+		manifests.Roles = append(manifests.Roles, *role)
+		manifests.Bindings = append(manifests.Bindings, *binding)
+	}
+	return &manifests, nil
+}
+
+func mapPolicy(policy hexapolicy.PolicyInfo, policyId string) (*Role, *RoleBinding, error) {
+	if policy.Condition != nil {
+		return nil, nil, errors.New("conditions are not supported by Kubernetes RBAC")
+	}
+	scope, err := parseObject(policy.Object.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	subjects, err := mapSubjects(policy.Subjects, scope.namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rule := PolicyRule{Verbs: []string{Wildcard}}
+	if len(policy.Actions) > 0 {
+		rule.Verbs = nil
+		for _, action := range policy.Actions {
+			verb := types.ParseEntity(string(action)).GetId()
+			if !slices.Contains(rule.Verbs, verb) {
+				rule.Verbs = append(rule.Verbs, verb)
+			}
+		}
+	}
+	if scope.nonResourceURL != "" {
+		rule.NonResourceURLs = []string{scope.nonResourceURL}
+	} else {
+		rule.APIGroups = []string{scope.apiGroup}
+		rule.Resources = []string{scope.resource}
+		if scope.name != "" {
+			rule.ResourceNames = []string{scope.name}
+		}
+	}
+
+	name := strings.Trim(invalidNameRegex.ReplaceAllString(strings.ToLower(policyId), "-"), "-.")
+	if name == "" {
+		name = "policy"
+	}
+	metadata := ObjectMeta{
+		Name:        name,
+		Namespace:   scope.namespace,
+		Annotations: map[string]string{AnnotationPolicyId: policyId},
+	}
+	roleKind, bindingKind := KindClusterRole, KindClusterRoleBinding
+	if scope.namespace != "" {
+		roleKind, bindingKind = KindRole, KindRoleBinding
+	}
+	role := Role{APIVersion: APIVersion, Kind: roleKind, Metadata: metadata, Rules: []PolicyRule{rule}}
+	binding := RoleBinding{
+		APIVersion: APIVersion,
+		Kind:       bindingKind,
+		Metadata:   metadata,
+		Subjects:   subjects,
+		RoleRef:    RoleRef{APIGroup: APIGroupRbac, Kind: roleKind, Name: name},
+	}
+	return &role, &binding, nil
+}
+
+// parseObject parses an object of the form [<namespace>:]<resource>[.<apiGroup>]:["<name>"] or a non-resource URL.
+// Entity parsing is not used as quoted names may contain colons.
+func parseObject(object string) (*resourceScope, error) {
+	if object == "" {
+		return &resourceScope{apiGroup: Wildcard, resource: Wildcard}, nil
+	}
+	if strings.HasPrefix(object, "/") {
+		return &resourceScope{nonResourceURL: object}, nil
+	}
+
+	scope := resourceScope{}
+	typePath := object
+	if index := strings.Index(object, "\""); index >= 0 {
+		name, err := strconv.Unquote(object[index:])
+		if err != nil || !strings.HasSuffix(object[:index], ":") {
+			return nil, fmt.Errorf("invalid object '%s'", object)
+		}
+		scope.name = name
+		typePath = object[:index]
+	}
+	parts := strings.Split(typePath, ":")
+	if scope.name == "" {
+		scope.name = parts[len(parts)-1]
+	}
+	parts = parts[:len(parts)-1]
+	switch len(parts) {
+	case 1:
+	case 2:
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid object '%s'", object)
+		}
+		scope.namespace = parts[0]
+		parts = parts[1:]
+	default:
+		return nil, fmt.Errorf("object '%s' must be of the form [<namespace>:]<resource>[.<apiGroup>]:[\"<name>\"]", object)
+	}
+	scope.resource, scope.apiGroup, _ = strings.Cut(parts[0], ".")
+	if scope.resource == "" {
+		return nil, fmt.Errorf("invalid object '%s'", object)
+	}
+	return &scope, nil
+}
+
+// mapSubjects maps IDQL subjects into binding subjects. namespace is the default for service accounts.
+func mapSubjects(subjects hexapolicy.SubjectInfo, namespace string) ([]Subject, error) {
+	if len(subjects) == 0 {
+		subjects = []string{hexapolicy.SubjectAnyUser}
+	}
+	var result []Subject
+	add := func(subject Subject) {
+		if !slices.Contains(result, subject) {
+			result = append(result, subject)
+		}
+	}
+	for _, value := range subjects {
+		switch {
+		case strings.EqualFold(value, hexapolicy.SubjectAnyUser):
+			add(Subject{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: GroupAuthenticated})
+			add(Subject{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: GroupUnauthenticated})
+			continue
+		case strings.EqualFold(value, hexapolicy.SubjectAnyAuth):
+			add(Subject{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: GroupAuthenticated})
+			continue
+		}
+
+		members := []string{value}
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			members = strings.Split(value[1:len(value)-1], ",")
+		}
+		for _, member := range members {
+			kind, name, found := strings.Cut(strings.TrimSpace(member), ":")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if !found || name == "" {
+				return nil, fmt.Errorf("subject '%s' must be a specific User, Group, or ServiceAccount", value)
+			}
+			switch kind {
+			case SubjectUser, SubjectGroup:
+				add(Subject{Kind: kind, APIGroup: APIGroupRbac, Name: name})
+			case SubjectServiceAccount:
+				saNamespace, saName, found := strings.Cut(name, "/")
+				if !found {
+					saNamespace, saName = namespace, name
+				}
+				if saNamespace == "" || saName == "" {
+					return nil, fmt.Errorf("service account subject '%s' must be of the form ServiceAccount:\"<namespace>/<name>\"", value)
+				}
+				add(Subject{Kind: kind, Name: saName, Namespace: saNamespace})
+			default:
+				return nil, fmt.Errorf("subject type '%s' is not supported by Kubernetes RBAC", kind)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Marshal returns the manifests as a multi-document YAML stream (roles followed by bindings)
+func (m *Manifests) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, role := range m.Roles {
+		if err := encoder.Encode(role); err != nil {
+			return nil, err
+		}
+	}
+	for _, binding := range m.Bindings {
+		if err := encoder.Encode(binding); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseManifests parses a multi-document YAML stream for RBAC roles and bindings. Documents of other kinds are ignored
+// and List documents (e.g. from kubectl get -o yaml) are expanded.
+func ParseManifests(manifestBytes []byte) (*Manifests, error) {
+	manifests := Manifests{}
+	decoder := yaml.NewDecoder(bytes.NewReader(manifestBytes))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes manifest: %s", err.Error())
+		}
+		if err = manifests.addNode(&node); err != nil {
+			return nil, err
+		}
+	}
+	return &manifests, nil
+}
+
+func (m *Manifests) addNode(node *yaml.Node) error {
+	var header manifestHeader
+	if err := node.Decode(&header); err != nil {
+		return fmt.Errorf("invalid Kubernetes manifest: %s", err.Error())
+	}
+	var err error
+	switch header.Kind {
+	case KindRole, KindClusterRole:
+		var role Role
+		if err = node.Decode(&role); err == nil {
+			m.Roles = append(m.Roles, role)
+		}
+	case KindRoleBinding, KindClusterRoleBinding:
+		var binding RoleBinding
+		if err = node.Decode(&binding); err == nil {
+			m.Bindings = append(m.Bindings, binding)
+		}
+	case KindList:
+		var list struct {
+			Items []yaml.Node `yaml:"items"`
+		}
+		if err = node.Decode(&list); err == nil {
+			for i := range list.Items {
+				if err = m.addNode(&list.Items[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid Kubernetes %s: %s", header.Kind, err.Error())
+	}
+	return nil
+}
+
+// ParseFile reads and parses a Kubernetes manifest file (see ParseManifests)
+func ParseFile(path string) (*Manifests, error) {
+	manifestBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifests(manifestBytes)
+}
+
+// MapManifestBytes parses Kubernetes manifests and maps their bindings into IDQL
+func MapManifestBytes(manifestBytes []byte) (*hexapolicy.Policies, error) {
+	manifests, err := ParseManifests(manifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := MapManifestsToPolicies(manifests)
+	if err != nil {
+		return nil, err
+	}
+	return &hexapolicy.Policies{Policies: policies}, nil
+}
+
+// MapManifestsToPolicies maps each binding and the rules of the role it references into IDQL policies
+func MapManifestsToPolicies(manifests *Manifests) ([]hexapolicy.PolicyInfo, error) {
+	var policies []hexapolicy.PolicyInfo
+	for _, binding := range manifests.Bindings {
+		namespace := ""
+		if binding.Kind == KindRoleBinding {
+			namespace = binding.Metadata.Namespace
+		}
+		role := manifests.role(binding.RoleRef, namespace)
+		if role == nil {
+			return nil, fmt.Errorf("%s %s: %s %s not found", binding.Kind, binding.Metadata.Name, binding.RoleRef.Kind, binding.RoleRef.Name)
+		}
+		subjects, err := mapBindingSubjects(binding.Subjects, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %s", binding.Kind, binding.Metadata.Name, err.Error())
+		}
+
+		policyId := binding.Metadata.Name
+		if id, exists := binding.Metadata.Annotations[AnnotationPolicyId]; exists {
+			policyId = id
+		}
+
+		var bindingPolicies []hexapolicy.PolicyInfo
+		for _, rule := range role.Rules {
+			var actions []hexapolicy.ActionInfo
+			if !slices.Contains(rule.Verbs, Wildcard) {
+				for _, verb := range rule.Verbs {
+					actions = append(actions, hexapolicy.ActionInfo(verb))
+				}
+			}
+			for _, object := range ruleObjects(rule, namespace) {
+				bindingPolicies = append(bindingPolicies, hexapolicy.PolicyInfo{
+					Meta:     hexapolicy.MetaInfo{Version: hexapolicy.IdqlVersion},
+					Subjects: subjects,
+					Actions:  actions,
+					Object:   hexapolicy.ObjectInfo(object),
+				})
+			}
+		}
+		for i := range bindingPolicies {
+			id := policyId
+			if len(bindingPolicies) > 1 {
+				id = fmt.Sprintf("%s-%d", policyId, i)
+			}
+			bindingPolicies[i].Meta.PolicyId = &id
+		}
+		policies = append(policies, bindingPolicies...)
+	}
+	return policies, nil
+}
+
+// ruleObjects returns an IDQL object for each resource (or non-resource URL) the rule applies to. Non-resource URLs
+// only apply to cluster bindings.
+func ruleObjects(rule PolicyRule, namespace string) []string {
+	var objects []string
+	prefix := ""
+	if namespace == "" {
+		objects = slices.Clone(rule.NonResourceURLs)
+	} else {
+		prefix = namespace + ":"
+	}
+	for _, apiGroup := range rule.APIGroups {
+		for _, resource := range rule.Resources {
+			if namespace == "" && apiGroup == Wildcard && resource == Wildcard && len(rule.ResourceNames) == 0 {
+				objects = append(objects, "")
+				continue
+			}
+			if apiGroup != "" {
+				resource = resource + "." + apiGroup
+			}
+			if len(rule.ResourceNames) == 0 {
+				objects = append(objects, prefix+resource+":")
+			}
+			for _, name := range rule.ResourceNames {
+				objects = append(objects, prefix+resource+":"+strconv.Quote(name))
+			}
+		}
+	}
+	return objects
+}
+
+// mapBindingSubjects maps binding subjects into IDQL. namespace is the default for service accounts.
+func mapBindingSubjects(subjects []Subject, namespace string) (hexapolicy.SubjectInfo, error) {
+	var result hexapolicy.SubjectInfo
+	authenticated, unauthenticated := false, false
+	for _, subject := range subjects {
+		switch subject.Kind {
+		case SubjectUser:
+			result = append(result, SubjectUser+":"+strconv.Quote(subject.Name))
+		case SubjectGroup:
+			switch subject.Name {
+			case GroupAuthenticated:
+				authenticated = true
+			case GroupUnauthenticated:
+				unauthenticated = true
+			default:
+				result = append(result, "["+SubjectGroup+":"+strconv.Quote(subject.Name)+"]")
+			}
+		case SubjectServiceAccount:
+			saNamespace := subject.Namespace
+			if saNamespace == "" {
+				saNamespace = namespace
+			}
+			result = append(result, SubjectServiceAccount+":"+strconv.Quote(saNamespace+"/"+subject.Name))
+		default:
+			return nil, fmt.Errorf("subject kind '%s' is not supported", subject.Kind)
+		}
+	}
+	switch {
+	case authenticated && unauthenticated:
+		result = append(hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}, result...)
+	case authenticated:
+		result = append(hexapolicy.SubjectInfo{hexapolicy.SubjectAnyAuth}, result...)
+	case unauthenticated:
+		result = append(result, "["+SubjectGroup+":"+strconv.Quote(GroupUnauthenticated)+"]")
+	}
+	return result, nil
+}
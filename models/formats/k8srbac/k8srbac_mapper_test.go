@@ -0,0 +1,185 @@
+package k8srbac
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func TestMapManifestsToPolicies(t *testing.T) {
+	manifests, err := ParseFile(filepath.Join(getExampleDir(), "policyExamples", "example_k8srbac.yaml"))
+	assert.NoError(t, err)
+	assert.Len(t, manifests.Roles, 2)
+	assert.Len(t, manifests.Bindings, 3)
+
+	policies, err := MapManifestsToPolicies(manifests)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 5)
+
+	readPods := policies[0]
+	assert.Equal(t, "read-pods-0", *readPods.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{"User:\"jane\"", "ServiceAccount:\"default/monitor\""}, readPods.Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"get", "list", "watch"}, readPods.Actions)
+	assert.Equal(t, "default:pods:", readPods.Object.String())
+	assert.Equal(t, "read-pods-1", *policies[1].Meta.PolicyId)
+	assert.Equal(t, "default:pods/log:", policies[1].Object.String())
+
+	admin := policies[2]
+	assert.Equal(t, "DeploymentAdmins-0", *admin.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{"[Group:\"system:masters\"]"}, admin.Subjects)
+	assert.Nil(t, admin.Actions)
+	assert.Equal(t, "deployments.apps:\"web\"", admin.Object.String())
+	assert.Equal(t, []hexapolicy.ActionInfo{"get"}, policies[3].Actions)
+	assert.Equal(t, "/healthz", policies[3].Object.String())
+
+	dev := policies[4]
+	assert.Equal(t, "dev-deployments", *dev.Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyAuth}, dev.Subjects)
+	assert.Equal(t, "dev:deployments.apps:\"web\"", dev.Object.String())
+}
+
+func TestMapPoliciesToManifests(t *testing.T) {
+	policyId := "Read Pods"
+	policies := []hexapolicy.PolicyInfo{
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &policyId},
+			Subjects: []string{"User:\"jane\"", "[Group:\"system:masters\",Group:devs]", "ServiceAccount:\"monitor\""},
+			Actions:  []hexapolicy.ActionInfo{"k8s:Action:\"get\"", "list"},
+			Object:   "default:pods:",
+		},
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &policyId},
+			Subjects: []string{hexapolicy.SubjectAnyAuth},
+			Actions:  []hexapolicy.ActionInfo{"get"},
+			Object:   "/healthz",
+		},
+		{
+			Subjects: []string{"ServiceAccount:\"kube-system/admin\""},
+			Object:   "deployments.apps:\"web\"",
+		},
+		{},
+	}
+	manifests, err := MapPoliciesToManifests(policies)
+	assert.NoError(t, err)
+	assert.Len(t, manifests.Roles, 4)
+
+	role := manifests.Roles[0]
+	assert.Equal(t, KindRole, role.Kind)
+	assert.Equal(t, ObjectMeta{Name: "read-pods", Namespace: "default", Annotations: map[string]string{AnnotationPolicyId: policyId}}, role.Metadata)
+	assert.Equal(t, []PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}}}, role.Rules)
+	binding := manifests.Bindings[0]
+	assert.Equal(t, KindRoleBinding, binding.Kind)
+	assert.Equal(t, RoleRef{APIGroup: APIGroupRbac, Kind: KindRole, Name: "read-pods"}, binding.RoleRef)
+	assert.Equal(t, []Subject{
+		{Kind: SubjectUser, APIGroup: APIGroupRbac, Name: "jane"},
+		{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: "system:masters"},
+		{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: "devs"},
+		{Kind: SubjectServiceAccount, Name: "monitor", Namespace: "default"},
+	}, binding.Subjects)
+
+	// cluster scoped names are unique separately from namespaced names
+	assert.Equal(t, KindClusterRole, manifests.Roles[1].Kind)
+	assert.Equal(t, "read-pods", manifests.Roles[1].Metadata.Name)
+	assert.Equal(t, []string{"/healthz"}, manifests.Roles[1].Rules[0].NonResourceURLs)
+	assert.Equal(t, "policy-2", manifests.Roles[2].Metadata.Name)
+	assert.Equal(t, []PolicyRule{{Verbs: []string{Wildcard}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}, ResourceNames: []string{"web"}}}, manifests.Roles[2].Rules)
+	assert.Equal(t, []PolicyRule{{Verbs: []string{Wildcard}, APIGroups: []string{Wildcard}, Resources: []string{Wildcard}}}, manifests.Roles[3].Rules)
+	assert.Equal(t, []Subject{
+		{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: GroupAuthenticated},
+		{Kind: SubjectGroup, APIGroup: APIGroupRbac, Name: GroupUnauthenticated},
+	}, manifests.Bindings[3].Subjects)
+
+	manifestBytes, err := manifests.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, 7, strings.Count(string(manifestBytes), "---\n"))
+
+	roundTrip, err := MapManifestBytes(manifestBytes)
+	assert.NoError(t, err)
+	assert.Len(t, roundTrip.Policies, len(policies))
+	for i, policy := range roundTrip.Policies {
+		expected := policies[i]
+		if expected.Meta.PolicyId != nil {
+			assert.Equal(t, *expected.Meta.PolicyId, *policy.Meta.PolicyId)
+		}
+		assert.Equal(t, expected.Object, policy.Object)
+	}
+	assert.Equal(t, hexapolicy.SubjectInfo{"User:\"jane\"", "[Group:\"system:masters\"]", "[Group:\"devs\"]", "ServiceAccount:\"default/monitor\""}, roundTrip.Policies[0].Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"get", "list"}, roundTrip.Policies[0].Actions)
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyAuth}, roundTrip.Policies[1].Subjects)
+	assert.Nil(t, roundTrip.Policies[2].Actions)
+	assert.Equal(t, "policy-3", *roundTrip.Policies[3].Meta.PolicyId)
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}, roundTrip.Policies[3].Subjects)
+}
+
+func TestMapPoliciesToManifests_Errors(t *testing.T) {
+	for _, policy := range []hexapolicy.PolicyInfo{
+		{Condition: &conditions.ConditionInfo{Rule: "subject.level gt 3", Action: conditions.AAllow}},
+		{Object: "pods"},
+		{Object: "a:b:pods:"},
+		{Object: ":pods:"},
+		{Object: ".apps:"},
+		{Object: "pods:\"web"},
+		{Object: "pods\"web\""},
+		{Subjects: []string{"User:"}},
+		{Subjects: []string{"alice"}},
+		{Subjects: []string{"Device:\"phone\""}},
+		{Subjects: []string{"ServiceAccount:\"monitor\""}},
+	} {
+		_, err := MapPoliciesToManifests([]hexapolicy.PolicyInfo{policy})
+		assert.Error(t, err, "policy %v", policy)
+	}
+}
+
+func TestParseManifests(t *testing.T) {
+	list := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: rbac.authorization.k8s.io/v1
+    kind: ClusterRole
+    metadata:
+      name: viewer
+    rules:
+      - apiGroups: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+  - apiVersion: rbac.authorization.k8s.io/v1
+    kind: ClusterRoleBinding
+    metadata:
+      name: viewers
+    subjects:
+      - kind: Group
+        name: system:unauthenticated
+    roleRef:
+      kind: ClusterRole
+      name: viewer
+`
+	policies, err := MapManifestBytes([]byte(list))
+	assert.NoError(t, err)
+	assert.Len(t, policies.Policies, 1)
+	assert.Equal(t, hexapolicy.SubjectInfo{"[Group:\"system:unauthenticated\"]"}, policies.Policies[0].Subjects)
+	assert.Equal(t, "", policies.Policies[0].Object.String())
+
+	for _, manifest := range []string{
+		"kind: [",
+		"kind: Role\nrules: 5\n",
+		"kind: List\nitems:\n  - kind: Role\n    rules: 5\n",
+		"kind: ClusterRoleBinding\nmetadata:\n  name: a\nroleRef:\n  kind: ClusterRole\n  name: missing\n",
+		"kind: ClusterRole\nmetadata:\n  name: r\n---\nkind: ClusterRoleBinding\nmetadata:\n  name: a\nsubjects:\n  - kind: Device\n    name: x\nroleRef:\n  kind: ClusterRole\n  name: r\n",
+	} {
+		_, err := MapManifestBytes([]byte(manifest))
+		assert.Error(t, err, manifest)
+	}
+
+	_, err = ParseFile(filepath.Join(getExampleDir(), "doesNotExist.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package k8srbac
+
+/*
+ YAML bindings for the Kubernetes rbac.authorization.k8s.io/v1 resources - See:
+ https://kubernetes.io/docs/reference/access-authn-authz/rbac/
+*/
+
+const (
+	APIGroupRbac = "rbac.authorization.k8s.io"
+	APIVersion   = APIGroupRbac + "/v1"
+
+	KindRole               = "Role"
+	KindClusterRole        = "ClusterRole"
+	KindRoleBinding        = "RoleBinding"
+	KindClusterRoleBinding = "ClusterRoleBinding"
+	KindList               = "List"
+
+	SubjectUser           = "User"
+	SubjectGroup          = "Group"
+	SubjectServiceAccount = "ServiceAccount"
+
+	// GroupAuthenticated and GroupUnauthenticated are the system groups Kubernetes assigns to every request
+	GroupAuthenticated   = "system:authenticated"
+	GroupUnauthenticated = "system:unauthenticated"
+
+	// AnnotationPolicyId holds the IDQL policy id a role and binding were generated from
+	AnnotationPolicyId = "hexaorchestration.org/policy-id"
+
+	Wildcard = "*"
+)
+
+type ObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type PolicyRule struct {
+	Verbs           []string `yaml:"verbs"`
+	APIGroups       []string `yaml:"apiGroups,omitempty"`
+	Resources       []string `yaml:"resources,omitempty"`
+	ResourceNames   []string `yaml:"resourceNames,omitempty"`
+	NonResourceURLs []string `yaml:"nonResourceURLs,omitempty"`
+}
+
+// Role is a Role (namespaced) or ClusterRole
+type Role struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Metadata   ObjectMeta   `yaml:"metadata"`
+	Rules      []PolicyRule `yaml:"rules"`
+}
+
+type Subject struct {
+	Kind      string `yaml:"kind"`
+	APIGroup  string `yaml:"apiGroup,omitempty"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type RoleRef struct {
+	APIGroup string `yaml:"apiGroup"`
+	Kind     string `yaml:"kind"`
+	Name     string `yaml:"name"`
+}
+
+// RoleBinding is a RoleBinding (namespaced) or ClusterRoleBinding
+type RoleBinding struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	Subjects   []Subject  `yaml:"subjects"`
+	RoleRef    RoleRef    `yaml:"roleRef"`
+}
+
+// Manifests holds the RBAC resources found in, or to be written to, a set of YAML documents
+type Manifests struct {
+	Roles    []Role
+	Bindings []RoleBinding
+}
+
+// manifestHeader is used to determine the kind of a YAML document before decoding it
+type manifestHeader struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+// role returns the role referenced by a binding in namespace or nil
+func (m *Manifests) role(ref RoleRef, namespace string) *Role {
+	for i, role := range m.Roles {
+		if role.Kind != ref.Kind || role.Metadata.Name != ref.Name {
+			continue
+		}
+		if role.Kind == KindRole && role.Metadata.Namespace != namespace {
+			continue
+		}
+		return &m.Roles[i]
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+// Package openfga maps IDQL policies to and from OpenFGA (Zanzibar style) relationship tuples.
+//
+// An OpenFGA authorization model is generated from a Policy Information Model (policyInfoModel) namespace:
+//
+//   - each entity type becomes an OpenFGA type
+//   - each entity type that other types are members of (memberOfTypes) has a `member` relation of those types
+//   - each action becomes a relation of its resource types, which the action principal types (including wildcards and
+//     group members) may be directly related to
+//
+// Each IDQL policy subject, action and object is then mapped into a tuple (user, relation, object). For example,
+// subject PhotoApp:User:"alice", action PhotoApp:Action:"viewPhoto" and object PhotoApp:Photo:"vacation.jpg" becomes
+// (User:alice, viewPhoto, Photo:vacation.jpg). Membership subjects ([PhotoApp:UserGroup:"friends"]) become usersets
+// (UserGroup:friends#member), and type or any subjects become wildcards (User:*). A policy condition is mapped into a
+// CEL OpenFGA condition using the Google CEL condition mapper and the tuples are conditioned on it.
+//
+// Tuples are mapped back into RBAC-style IDQL policies, one per relation, object and condition, where tuples that have
+// the same object and condition are merged.
+package openfga
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/models/conditionLangs/gcpcel"
+	"github.com/hexa-org/policy-mapper/models/policyInfoModel"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const typeAction = "Action"
+
+var conditionNameRegex = regexp.MustCompile("[^A-Za-z0-9_]")
+
+type OpenFgaMapper struct {
+	// Namespace is the policy information model namespace used to generate the model and to qualify imported entities
+	Namespace       string
+	schema          policyInfoModel.SchemaType
+	conditionMapper gcpcel.GoogleConditionMapper
+}
+
+// NewOpenFgaMapper returns a mapper for the namespace of the policy information model. If namespace is empty and the
+// model has a single namespace, it is used. namespaces may be nil when only mapping tuples into IDQL.
+func NewOpenFgaMapper(namespaces *policyInfoModel.Namespaces, namespace string, attributeMap map[string]string) (*OpenFgaMapper, error) {
+	mapper := &OpenFgaMapper{
+		Namespace:       namespace,
+		conditionMapper: gcpcel.GoogleConditionMapper{NameMapper: conditions.NewNameMapper(attributeMap)},
+	}
+	if namespaces == nil {
+		return mapper, nil
+	}
+	if namespace == "" && len(*namespaces) == 1 {
+		for name := range *namespaces {
+			mapper.Namespace = name
+		}
+	}
+	schema, exists := (*namespaces)[mapper.Namespace]
+	if !exists {
+		return nil, fmt.Errorf("namespace '%s' not found in the policy information model", namespace)
+	}
+	mapper.schema = schema
+	return mapper, nil
+}
+
+// ParseStore parses a JSON document holding an authorization model and tuples (see Store)
+func ParseStore(storeBytes []byte) (*Store, error) {
+	var store Store
+	if err := json.Unmarshal(storeBytes, &store); err != nil {
+		return nil, fmt.Errorf("invalid OpenFGA store: %s", err.Error())
+	}
+	return &store, nil
+}
+
+// ParseFile reads and parses an OpenFGA store file (see ParseStore)
+func ParseFile(path string) (*Store, error) {
+	storeBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStore(storeBytes)
+}
+
+// MapSchemaToModel generates an OpenFGA authorization model from the policy information model namespace
+func (m *OpenFgaMapper) MapSchemaToModel() AuthorizationModel {
+	model := AuthorizationModel{SchemaVersion: SchemaVersion}
+
+	typeNames := make([]string, 0, len(m.schema.EntityTypes))
+	groups := map[string]bool{}
+	for name, entityType := range m.schema.EntityTypes {
+		typeNames = append(typeNames, name)
+		for _, parent := range entityType.MemberOfTypes {
+			groups[parent] = true
+		}
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		model.TypeDefinitions = append(model.TypeDefinitions, TypeDefinition{Type: name})
+	}
+
+	for _, name := range typeNames {
+		for _, parent := range m.schema.EntityTypes[name].MemberOfTypes {
+			definition := model.definition(parent)
+			definition.addReference(RelationMember, RelationReference{Type: name})
+			if groups[name] {
+				definition.addReference(RelationMember, RelationReference{Type: name, Relation: RelationMember})
+			}
+		}
+	}
+
+	actionNames := make([]string, 0, len(m.schema.Actions))
+	for name := range m.schema.Actions {
+		actionNames = append(actionNames, name)
+	}
+	sort.Strings(actionNames)
+	for _, name := range actionNames {
+		appliesTo := m.schema.Actions[name].AppliesTo
+		if appliesTo.PrincipalTypes == nil || appliesTo.ResourceTypes == nil {
+			continue
+		}
+		for _, resourceType := range *appliesTo.ResourceTypes {
+			definition := model.definition(resourceType)
+			for _, principalType := range *appliesTo.PrincipalTypes {
+				definition.addReference(name, RelationReference{Type: principalType})
+				definition.addReference(name, RelationReference{Type: principalType, Wildcard: &struct{}{}})
+				if groups[principalType] {
+					definition.addReference(name, RelationReference{Type: principalType, Relation: RelationMember})
+				}
+			}
+		}
+	}
+	return model
+}
+
+// definition returns the type definition for typeName, adding it when not defined
+func (m *AuthorizationModel) definition(typeName string) *TypeDefinition {
+	if definition := m.TypeDefinition(typeName); definition != nil {
+		return definition
+	}
+	m.TypeDefinitions = append(m.TypeDefinitions, TypeDefinition{Type: typeName})
+	return &m.TypeDefinitions[len(m.TypeDefinitions)-1]
+}
+
+// MapPoliciesToStore generates the authorization model and maps the IDQL policies into tuples
+func (m *OpenFgaMapper) MapPoliciesToStore(policies []hexapolicy.PolicyInfo) (*Store, error) {
+	store := Store{Model: m.MapSchemaToModel(), Tuples: []TupleKey{}}
+	for i, policy := range policies {
+		tuples, err := m.MapPolicyToTuples(&store.Model, policy, i)
+		if err != nil {
+			return nil, err
+		}
+		store.Tuples = append(store.Tuples, tuples...)
+	}
+	return &store, nil
+}
+
+// MapPolicyToTuples maps an IDQL policy into tuples valid for model. When the policy has a condition, it is added to
+// model. index is used to name the condition when the policy has no id.
+func (m *OpenFgaMapper) MapPolicyToTuples(model *AuthorizationModel, policy hexapolicy.PolicyInfo, index int) ([]TupleKey, error) {
+	policyId := fmt.Sprintf("policy-%d", index)
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		policyId = *policy.Meta.PolicyId
+	}
+
+	object := types.ParseEntity(policy.Object.String())
+	if object.Type != types.RelTypeEquals || len(object.Types) == 0 {
+		return nil, fmt.Errorf("policy %s: OpenFGA tuples require a specific object (e.g. Photo:\"vacation.jpg\")", policyId)
+	}
+	definition := model.TypeDefinition(object.GetType())
+	if definition == nil {
+		return nil, fmt.Errorf("policy %s: type %s is not defined in the model", policyId, object.GetType())
+	}
+	if len(policy.Actions) == 0 {
+		return nil, fmt.Errorf("policy %s: OpenFGA tuples require at least one action", policyId)
+	}
+
+	var tupleCondition *RelationshipCondition
+	if policy.Condition != nil {
+		if strings.EqualFold(policy.Condition.Action, conditions.ADeny) {
+			return nil, fmt.Errorf("policy %s: deny conditions are not supported in OpenFGA", policyId)
+		}
+		name := conditionNameRegex.ReplaceAllString(policyId, "_")
+		condition, err := m.MapCondition(name, *policy.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+		if model.Conditions == nil {
+			model.Conditions = map[string]Condition{}
+		}
+		model.Conditions[name] = condition
+		tupleCondition = &RelationshipCondition{Name: name}
+	}
+
+	var tuples []TupleKey
+	for _, action := range policy.Actions {
+		relation := types.ParseEntity(string(action)).GetId()
+		if _, exists := definition.Relations[relation]; !exists {
+			return nil, fmt.Errorf("policy %s: relation %s is not defined for type %s", policyId, relation, definition.Type)
+		}
+		users, err := m.mapSubjects(definition, relation, policy.Subjects)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+		for _, user := range users {
+			if tupleCondition != nil {
+				ref := user.ref
+				ref.Condition = tupleCondition.Name
+				definition.addReference(relation, ref)
+			}
+			tuples = append(tuples, TupleKey{
+				User:      user.user,
+				Relation:  relation,
+				Object:    object.GetType() + ":" + object.GetId(),
+				Condition: tupleCondition,
+			})
+		}
+	}
+	return tuples, nil
+}
+
+type tupleUser struct {
+	user string
+	ref  RelationReference
+}
+
+func (m *OpenFgaMapper) mapSubjects(definition *TypeDefinition, relation string, subjects hexapolicy.SubjectInfo) ([]tupleUser, error) {
+	if len(subjects) == 0 {
+		subjects = []string{hexapolicy.SubjectAnyUser}
+	}
+	var users []tupleUser
+	for _, subject := range subjects {
+		entity := types.ParseEntity(subject)
+		switch {
+		case entity.Type == types.RelTypeAny || entity.Type == types.RelTypeAnyAuthenticated:
+			// any user is mapped to a wildcard of each principal type of the relation
+			for _, ref := range definition.Metadata.Relations[relation].DirectlyRelatedUserTypes {
+				if ref.Wildcard != nil && ref.Condition == "" {
+					users = append(users, tupleUser{user: ref.Type + ":*", ref: ref})
+				}
+			}
+			continue
+		case entity.Type == types.RelTypeIs:
+			users = append(users, tupleUser{user: entity.GetType() + ":*", ref: RelationReference{Type: entity.GetType(), Wildcard: &struct{}{}}})
+		case entity.Type == types.RelTypeIn:
+			for _, member := range *entity.In {
+				if member.Type != types.RelTypeEquals || len(member.Types) == 0 {
+					return nil, fmt.Errorf("subject %s: membership must be of a specific entity", subject)
+				}
+				users = append(users, tupleUser{
+					user: member.GetType() + ":" + member.GetId() + "#" + RelationMember,
+					ref:  RelationReference{Type: member.GetType(), Relation: RelationMember},
+				})
+			}
+		case entity.Type == types.RelTypeEquals && len(entity.Types) > 0:
+			users = append(users, tupleUser{user: entity.GetType() + ":" + entity.GetId(), ref: RelationReference{Type: entity.GetType()}})
+		default:
+			return nil, fmt.Errorf("subject %s is not supported in OpenFGA", subject)
+		}
+	}
+	for _, user := range users {
+		if !definition.allows(relation, user.ref) {
+			return nil, fmt.Errorf("user %s is not allowed for relation %s of type %s", user.user, relation, definition.Type)
+		}
+	}
+	return users, nil
+}
+
+// MapCondition maps an IDQL condition into a named OpenFGA CEL condition. Each top level attribute name (e.g. subject
+// in subject.age) becomes a map parameter of the condition.
+func (m *OpenFgaMapper) MapCondition(name string, condition conditions.ConditionInfo) (Condition, error) {
+	ast, err := conditions.ParseConditionRuleAst(condition)
+	if err != nil {
+		return Condition{}, err
+	}
+	parameters := map[string]ConditionParamType{}
+	for _, use := range conditions.FindEntityUses(ast) {
+		attrExpr, ok := use.(parser.AttributeExpression)
+		if !ok {
+			return Condition{}, fmt.Errorf("IDQL value path expressions (%s) are not supported in OpenFGA", use.String())
+		}
+		for _, value := range []types.Value{attrExpr.AttributePath, attrExpr.CompareValue} {
+			entity, ok := value.(types.Entity)
+			if !ok {
+				continue
+			}
+			if !isAttributePath(entity) {
+				return Condition{}, fmt.Errorf("IDQL entity comparison (%s) is not supported in OpenFGA", attrExpr.String())
+			}
+			param := m.conditionMapper.NameMapper.GetProviderAttributeName(entity.String())
+			parameters[strings.SplitN(param, ".", 2)[0]] = ConditionParamType{
+				TypeName:     ParamTypeMap,
+				GenericTypes: []ConditionParamType{{TypeName: ParamTypeAny}},
+			}
+		}
+	}
+	expression, err := m.conditionMapper.MapFilter(ast)
+	if err != nil {
+		return Condition{}, err
+	}
+	return Condition{Name: name, Expression: expression, Parameters: parameters}, nil
+}
+
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+// MapStoreToPolicies maps the tuples of a store into IDQL policies
+func (m *OpenFgaMapper) MapStoreToPolicies(store Store) ([]hexapolicy.PolicyInfo, error) {
+	return m.MapTuplesToPolicies(&store.Model, store.Tuples)
+}
+
+// MapTuplesToPolicies maps tuples into RBAC-style IDQL policies. model is used to look up tuple conditions and may be
+// nil when tuples are unconditioned.
+func (m *OpenFgaMapper) MapTuplesToPolicies(model *AuthorizationModel, tuples []TupleKey) ([]hexapolicy.PolicyInfo, error) {
+	type policyKey struct {
+		relation, object, condition string
+	}
+	var policies []hexapolicy.PolicyInfo
+	index := map[policyKey]int{}
+	for _, tuple := range tuples {
+		subject, err := m.mapUser(tuple.User)
+		if err != nil {
+			return nil, err
+		}
+		objectType, objectId, found := strings.Cut(tuple.Object, ":")
+		if !found || objectId == "" || objectId == "*" {
+			return nil, fmt.Errorf("invalid OpenFGA tuple object '%s'", tuple.Object)
+		}
+		key := policyKey{relation: tuple.Relation, object: tuple.Object}
+		if tuple.Condition != nil {
+			key.condition = tuple.Condition.Name
+		}
+		if i, exists := index[key]; exists {
+			if !slices.Contains(policies[i].Subjects, subject) {
+				policies[i].Subjects = append(policies[i].Subjects, subject)
+			}
+			continue
+		}
+
+		policyId := fmt.Sprintf("policy-%d", len(policies))
+		policy := hexapolicy.PolicyInfo{
+			Meta:     hexapolicy.MetaInfo{Version: hexapolicy.IdqlVersion, PolicyId: &policyId},
+			Subjects: []string{subject},
+			Actions:  []hexapolicy.ActionInfo{hexapolicy.ActionInfo(m.formatEntity(typeAction, tuple.Relation))},
+			Object:   hexapolicy.ObjectInfo(m.formatEntity(objectType, objectId)),
+		}
+		if tuple.Condition != nil {
+			condition, err := m.mapTupleCondition(model, *tuple.Condition)
+			if err != nil {
+				return nil, err
+			}
+			policy.Condition = condition
+		}
+		index[key] = len(policies)
+		policies = append(policies, policy)
+	}
+	return mergeActions(policies), nil
+}
+
+func (m *OpenFgaMapper) mapTupleCondition(model *AuthorizationModel, tupleCondition RelationshipCondition) (*conditions.ConditionInfo, error) {
+	if model == nil {
+		return nil, fmt.Errorf("condition %s requires an authorization model", tupleCondition.Name)
+	}
+	if len(tupleCondition.Context) > 0 {
+		return nil, fmt.Errorf("condition %s: tuple condition context is not supported in IDQL", tupleCondition.Name)
+	}
+	condition, exists := model.Conditions[tupleCondition.Name]
+	if !exists {
+		return nil, fmt.Errorf("condition %s is not defined in the model", tupleCondition.Name)
+	}
+	info, err := m.conditionMapper.MapProviderToCondition(condition.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("condition %s: %s", tupleCondition.Name, err.Error())
+	}
+	return &info, nil
+}
+
+// mapUser maps a tuple user (e.g. User:alice, User:*, or UserGroup:friends#member) into an IDQL subject
+func (m *OpenFgaMapper) mapUser(user string) (string, error) {
+	userType, id, found := strings.Cut(user, ":")
+	if !found || userType == "" || id == "" {
+		return "", fmt.Errorf("invalid OpenFGA tuple user '%s'", user)
+	}
+	if id == "*" {
+		return m.formatType(userType), nil
+	}
+	if id, relation, isUserset := strings.Cut(id, "#"); isUserset {
+		if relation != RelationMember {
+			return "", fmt.Errorf("userset relation '%s' of %s is not supported in IDQL", relation, user)
+		}
+		return "[" + m.formatEntity(userType, id) + "]", nil
+	}
+	return m.formatEntity(userType, id), nil
+}
+
+func (m *OpenFgaMapper) formatType(typeName string) string {
+	if m.Namespace == "" {
+		return typeName + ":"
+	}
+	return m.Namespace + ":" + typeName + ":"
+}
+
+// formatEntity returns the IDQL entity for the type and id. Actions are returned as the relation when there is no
+// namespace.
+func (m *OpenFgaMapper) formatEntity(typeName string, id string) string {
+	if m.Namespace == "" && typeName == typeAction {
+		return id
+	}
+	return m.formatType(typeName) + strconv.Quote(id)
+}
+
+// mergeActions merges policies that differ only by action
+func mergeActions(policies []hexapolicy.PolicyInfo) []hexapolicy.PolicyInfo {
+	var merged []hexapolicy.PolicyInfo
+	for _, policy := range policies {
+		matched := false
+		for i, existing := range merged {
+			if existing.Object == policy.Object && existing.Subjects.Equals(policy.Subjects) &&
+				((existing.Condition == nil && policy.Condition == nil) ||
+					(existing.Condition != nil && policy.Condition != nil && existing.Condition.Rule == policy.Condition.Rule)) {
+				merged[i].Actions = append(merged[i].Actions, policy.Actions...)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			merged = append(merged, policy)
+		}
+	}
+	for i := range merged {
+		policyId := fmt.Sprintf("policy-%d", i)
+		merged[i].Meta.PolicyId = &policyId
+	}
+	return merged
+}
+
+var errNoTuples = errors.New("no OpenFGA tuples found")
+
+// MapStoreBytes parses an OpenFGA store document and maps its tuples into IDQL
+func (m *OpenFgaMapper) MapStoreBytes(storeBytes []byte) (*hexapolicy.Policies, error) {
+	store, err := ParseStore(storeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(store.Tuples) == 0 {
+		return nil, errNoTuples
+	}
+	policies, err := m.MapStoreToPolicies(*store)
+	if err != nil {
+		return nil, err
+	}
+	return &hexapolicy.Policies{Policies: policies}, nil
+}
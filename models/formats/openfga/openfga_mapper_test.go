@@ -0,0 +1,201 @@
+package openfga
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/models/policyInfoModel"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func getMapper(t *testing.T) *OpenFgaMapper {
+	schemaBytes, err := os.ReadFile(filepath.Join(getExampleDir(), "policyInfoModels", "photoSchema.json"))
+	assert.NoError(t, err)
+	namespaces, err := policyInfoModel.ParseSchemaFile(schemaBytes)
+	assert.NoError(t, err)
+	mapper, err := NewOpenFgaMapper(namespaces, "", map[string]string{})
+	assert.NoError(t, err)
+	return mapper
+}
+
+func TestNewOpenFgaMapper(t *testing.T) {
+	mapper := getMapper(t)
+	assert.Equal(t, "PhotoApp", mapper.Namespace)
+
+	namespaces := policyInfoModel.Namespaces{}
+	_, err := NewOpenFgaMapper(&namespaces, "Unknown", nil)
+	assert.Error(t, err)
+
+	mapper, err = NewOpenFgaMapper(nil, "", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, mapper.Namespace)
+}
+
+func TestMapSchemaToModel(t *testing.T) {
+	model := getMapper(t).MapSchemaToModel()
+	assert.Equal(t, SchemaVersion, model.SchemaVersion)
+
+	var typeNames []string
+	for _, definition := range model.TypeDefinitions {
+		typeNames = append(typeNames, definition.Type)
+	}
+	assert.Equal(t, []string{"Account", "Album", "Photo", "User", "UserGroup"}, typeNames)
+
+	group := model.TypeDefinition("UserGroup")
+	assert.True(t, group.allows(RelationMember, RelationReference{Type: "User"}))
+	assert.False(t, group.allows(RelationMember, RelationReference{Type: "User", Wildcard: &struct{}{}}))
+	assert.True(t, model.TypeDefinition("Album").allows(RelationMember, RelationReference{Type: "Photo"}))
+
+	photo := model.TypeDefinition("Photo")
+	for _, relation := range []string{"viewPhoto", "createPhoto", "listPhotos"} {
+		assert.Contains(t, photo.Relations, relation)
+		assert.True(t, photo.allows(relation, RelationReference{Type: "User"}))
+		assert.True(t, photo.allows(relation, RelationReference{Type: "User", Wildcard: &struct{}{}}))
+		assert.True(t, photo.allows(relation, RelationReference{Type: "UserGroup", Relation: RelationMember}))
+		assert.False(t, photo.allows(relation, RelationReference{Type: "User", Relation: RelationMember}))
+	}
+	assert.Nil(t, model.TypeDefinition("User").Relations)
+}
+
+func TestMapPoliciesToStore(t *testing.T) {
+	mapper := getMapper(t)
+	policyId := "Adults view"
+	policies := []hexapolicy.PolicyInfo{
+		{
+			Subjects: []string{"PhotoApp:User:\"alice\"", "[PhotoApp:UserGroup:\"friends\"]"},
+			Actions:  []hexapolicy.ActionInfo{"PhotoApp:Action:\"viewPhoto\"", "PhotoApp:Action:\"listPhotos\""},
+			Object:   "PhotoApp:Photo:\"vacation.jpg\"",
+		},
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &policyId},
+			Subjects: []string{hexapolicy.SubjectAnyUser},
+			Actions:  []hexapolicy.ActionInfo{"PhotoApp:Action:\"viewPhoto\""},
+			Object:   "PhotoApp:Photo:\"public.jpg\"",
+			Condition: &conditions.ConditionInfo{
+				Rule:   "subject.age gt 18",
+				Action: conditions.AAllow,
+			},
+		},
+	}
+	store, err := mapper.MapPoliciesToStore(policies)
+	assert.NoError(t, err)
+
+	adults := &RelationshipCondition{Name: "Adults_view"}
+	assert.Equal(t, []TupleKey{
+		{User: "User:alice", Relation: "viewPhoto", Object: "Photo:vacation.jpg"},
+		{User: "UserGroup:friends#member", Relation: "viewPhoto", Object: "Photo:vacation.jpg"},
+		{User: "User:alice", Relation: "listPhotos", Object: "Photo:vacation.jpg"},
+		{User: "UserGroup:friends#member", Relation: "listPhotos", Object: "Photo:vacation.jpg"},
+		{User: "User:*", Relation: "viewPhoto", Object: "Photo:public.jpg", Condition: adults},
+		{User: "UserGroup:*", Relation: "viewPhoto", Object: "Photo:public.jpg", Condition: adults},
+	}, store.Tuples)
+
+	condition := store.Model.Conditions["Adults_view"]
+	assert.Equal(t, "subject.age > 18", condition.Expression)
+	assert.Equal(t, map[string]ConditionParamType{
+		"subject": {TypeName: ParamTypeMap, GenericTypes: []ConditionParamType{{TypeName: ParamTypeAny}}},
+	}, condition.Parameters)
+	assert.Contains(t, store.Model.TypeDefinition("Photo").Metadata.Relations["viewPhoto"].DirectlyRelatedUserTypes,
+		RelationReference{Type: "User", Wildcard: &struct{}{}, Condition: "Adults_view"})
+
+	storeBytes, err := json.Marshal(store)
+	assert.NoError(t, err)
+	roundTrip, err := mapper.MapStoreBytes(storeBytes)
+	assert.NoError(t, err)
+	assert.Len(t, roundTrip.Policies, 2)
+
+	result := roundTrip.Policies[0]
+	assert.Equal(t, "policy-0", *result.Meta.PolicyId)
+	assert.True(t, policies[0].Subjects.Equals(result.Subjects), "got %v", result.Subjects)
+	assert.True(t, policies[0].ActionsEqual(result.Actions))
+	assert.Equal(t, policies[0].Object, result.Object)
+	assert.Nil(t, result.Condition)
+
+	result = roundTrip.Policies[1]
+	assert.Equal(t, hexapolicy.SubjectInfo{"PhotoApp:User:", "PhotoApp:UserGroup:"}, result.Subjects)
+	assert.Equal(t, policies[1].Object, result.Object)
+	assert.True(t, policies[1].Condition.Equals(result.Condition), "got %s", result.Condition.Rule)
+}
+
+func TestMapPoliciesToStore_Errors(t *testing.T) {
+	mapper := getMapper(t)
+	for _, policy := range []hexapolicy.PolicyInfo{
+		{Actions: []hexapolicy.ActionInfo{"viewPhoto"}},
+		{Actions: []hexapolicy.ActionInfo{"viewPhoto"}, Object: "PhotoApp:Photo:"},
+		{Actions: []hexapolicy.ActionInfo{"viewPhoto"}, Object: "PhotoApp:Video:\"a.mp4\""},
+		{Object: "PhotoApp:Photo:\"a.jpg\""},
+		{Actions: []hexapolicy.ActionInfo{"deletePhoto"}, Object: "PhotoApp:Photo:\"a.jpg\""},
+		{Subjects: []string{"PhotoApp:Album:\"trip\""}, Actions: []hexapolicy.ActionInfo{"viewPhoto"}, Object: "PhotoApp:Photo:\"a.jpg\""},
+		{Subjects: []string{"PhotoApp:User[PhotoApp:UserGroup:\"friends\"]"}, Actions: []hexapolicy.ActionInfo{"viewPhoto"}, Object: "PhotoApp:Photo:\"a.jpg\""},
+		{
+			Actions: []hexapolicy.ActionInfo{"viewPhoto"}, Object: "PhotoApp:Photo:\"a.jpg\"",
+			Condition: &conditions.ConditionInfo{Rule: "subject.age lt 18", Action: conditions.ADeny},
+		},
+		{
+			Actions: []hexapolicy.ActionInfo{"viewPhoto"}, Object: "PhotoApp:Photo:\"a.jpg\"",
+			Condition: &conditions.ConditionInfo{Rule: "subject in PhotoApp:UserGroup:\"friends\"", Action: conditions.AAllow},
+		},
+	} {
+		_, err := mapper.MapPoliciesToStore([]hexapolicy.PolicyInfo{policy})
+		assert.Error(t, err, "policy %v", policy)
+	}
+}
+
+func TestMapTuplesToPolicies(t *testing.T) {
+	mapper, err := NewOpenFgaMapper(nil, "", map[string]string{})
+	assert.NoError(t, err)
+
+	policies, err := mapper.MapTuplesToPolicies(nil, []TupleKey{
+		{User: "user:anne", Relation: "reader", Object: "document:budget"},
+		{User: "user:anne", Relation: "writer", Object: "document:budget"},
+		{User: "team:eng#member", Relation: "reader", Object: "document:roadmap"},
+		{User: "user:*", Relation: "reader", Object: "document:roadmap"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, policies, 2)
+	assert.Equal(t, hexapolicy.SubjectInfo{"user:\"anne\""}, policies[0].Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"reader", "writer"}, policies[0].Actions)
+	assert.Equal(t, "document:\"budget\"", policies[0].Object.String())
+	assert.Equal(t, hexapolicy.SubjectInfo{"[team:\"eng\"]", "user:"}, policies[1].Subjects)
+	assert.Equal(t, "policy-1", *policies[1].Meta.PolicyId)
+
+	for _, tuple := range []TupleKey{
+		{User: "anne", Relation: "reader", Object: "document:budget"},
+		{User: "team:eng#owner", Relation: "reader", Object: "document:budget"},
+		{User: "user:anne", Relation: "reader", Object: "document:*"},
+		{User: "user:anne", Relation: "reader", Object: "document:budget", Condition: &RelationshipCondition{Name: "missing"}},
+	} {
+		_, err := mapper.MapTuplesToPolicies(&AuthorizationModel{}, []TupleKey{tuple})
+		assert.Error(t, err, "tuple %v", tuple)
+	}
+
+	model := AuthorizationModel{Conditions: map[string]Condition{"ip": {Name: "ip", Expression: "request.ip == \"10.0.0.1\""}}}
+	_, err = mapper.MapTuplesToPolicies(&model, []TupleKey{{User: "user:anne", Relation: "reader", Object: "document:budget",
+		Condition: &RelationshipCondition{Name: "ip", Context: map[string]interface{}{"request": "x"}}}})
+	assert.Error(t, err)
+	_, err = mapper.MapTuplesToPolicies(nil, []TupleKey{{User: "user:anne", Relation: "reader", Object: "document:budget",
+		Condition: &RelationshipCondition{Name: "ip"}}})
+	assert.Error(t, err)
+}
+
+func TestParseStore(t *testing.T) {
+	_, err := ParseStore([]byte("{not json"))
+	assert.Error(t, err)
+
+	mapper := getMapper(t)
+	_, err = mapper.MapStoreBytes([]byte("{\"tuples\": []}"))
+	assert.Error(t, err)
+
+	_, err = ParseFile(filepath.Join(getExampleDir(), "doesNotExist.json"))
+	assert.Error(t, err)
+}
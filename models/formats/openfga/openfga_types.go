@@ -0,0 +1,126 @@
+package openfga
+
+/*
+ JSON bindings for the OpenFGA authorization model and relationship tuples - See:
+ https://openfga.dev/docs/configuration-language and https://openfga.dev/api/service
+*/
+
+const (
+	SchemaVersion = "1.1"
+
+	// RelationMember is the relation generated for entity types that other types are members of (memberOfTypes)
+	RelationMember = "member"
+
+	ParamTypeMap = "TYPE_NAME_MAP"
+	ParamTypeAny = "TYPE_NAME_ANY"
+)
+
+type AuthorizationModel struct {
+	SchemaVersion   string               `json:"schema_version"`
+	TypeDefinitions []TypeDefinition     `json:"type_definitions"`
+	Conditions      map[string]Condition `json:"conditions,omitempty"`
+}
+
+type TypeDefinition struct {
+	Type      string             `json:"type"`
+	Relations map[string]Userset `json:"relations,omitempty"`
+	Metadata  *Metadata          `json:"metadata,omitempty"`
+}
+
+// Userset defines how a relation is computed. Only directly assigned relations (this) are generated.
+type Userset struct {
+	This *struct{} `json:"this,omitempty"`
+}
+
+type Metadata struct {
+	Relations map[string]RelationMetadata `json:"relations,omitempty"`
+}
+
+type RelationMetadata struct {
+	DirectlyRelatedUserTypes []RelationReference `json:"directly_related_user_types"`
+}
+
+// RelationReference is a user type that may be directly related (e.g. user, user:*, group#member, or user with condition)
+type RelationReference struct {
+	Type      string    `json:"type"`
+	Relation  string    `json:"relation,omitempty"`
+	Wildcard  *struct{} `json:"wildcard,omitempty"`
+	Condition string    `json:"condition,omitempty"`
+}
+
+// Condition is a named CEL expression that a tuple may be conditioned on
+type Condition struct {
+	Name       string                        `json:"name"`
+	Expression string                        `json:"expression"`
+	Parameters map[string]ConditionParamType `json:"parameters,omitempty"`
+}
+
+type ConditionParamType struct {
+	TypeName     string               `json:"type_name"`
+	GenericTypes []ConditionParamType `json:"generic_types,omitempty"`
+}
+
+// TupleKey is a relationship tuple (user, relation, object) with an optional condition
+type TupleKey struct {
+	User      string                 `json:"user"`
+	Relation  string                 `json:"relation"`
+	Object    string                 `json:"object"`
+	Condition *RelationshipCondition `json:"condition,omitempty"`
+}
+
+type RelationshipCondition struct {
+	Name    string                 `json:"name"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// Store holds an authorization model and the tuples written against it
+type Store struct {
+	Model  AuthorizationModel `json:"model"`
+	Tuples []TupleKey         `json:"tuples"`
+}
+
+// TypeDefinition returns the definition for typeName or nil
+func (m *AuthorizationModel) TypeDefinition(typeName string) *TypeDefinition {
+	for i := range m.TypeDefinitions {
+		if m.TypeDefinitions[i].Type == typeName {
+			return &m.TypeDefinitions[i]
+		}
+	}
+	return nil
+}
+
+// allows returns true if the relation of the type accepts the user reference
+func (t *TypeDefinition) allows(relation string, ref RelationReference) bool {
+	if t.Metadata == nil {
+		return false
+	}
+	metadata, exists := t.Metadata.Relations[relation]
+	if !exists {
+		return false
+	}
+	for _, allowed := range metadata.DirectlyRelatedUserTypes {
+		if allowed.Type == ref.Type && allowed.Relation == ref.Relation && (allowed.Wildcard == nil) == (ref.Wildcard == nil) &&
+			allowed.Condition == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// addReference adds a user reference to a relation if it is not already present
+func (t *TypeDefinition) addReference(relation string, ref RelationReference) {
+	if t.Relations == nil {
+		t.Relations = map[string]Userset{}
+		t.Metadata = &Metadata{Relations: map[string]RelationMetadata{}}
+	}
+	t.Relations[relation] = Userset{This: &struct{}{}}
+	metadata := t.Metadata.Relations[relation]
+	for _, existing := range metadata.DirectlyRelatedUserTypes {
+		if existing.Type == ref.Type && existing.Relation == ref.Relation && (existing.Wildcard == nil) == (ref.Wildcard == nil) &&
+			existing.Condition == ref.Condition {
+			return
+		}
+	}
+	metadata.DirectlyRelatedUserTypes = append(metadata.DirectlyRelatedUserTypes, ref)
+	t.Metadata.Relations[relation] = metadata
+}
@@ -0,0 +1,433 @@
+package rego
+
+/*
+ Maps IDQL policies to a standalone Open Policy Agent Rego module (and back). Each IDQL policy is compiled into one or
+ more partial set rules that add the policy id to either allow_set or deny_set. A METADATA annotation preceding each
+ rule preserves the IDQL policy id and description. The generated module includes helper functions that implement IDQL
+ subject, action and object matching against the same input document used by the Hexa IDQL interpreter
+ (see providers/openpolicyagent/resources/bundles/bundle/hexaPolicy.rego), so no Hexa runtime bundle is needed.
+
+ For example:
+
+    # METADATA
+    # title: GetUsers
+    # custom:
+    #   policyId: GetUsers
+    allow_set contains "GetUsers" if {
+        hexa_subjects(["anyAuthenticated"])
+        hexa_actions(["can_read_user"])
+        hexa_object("User:")
+    }
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	regoConditions "github.com/hexa-org/policy-mapper/models/conditionLangs/rego"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+)
+
+const (
+	DefaultPackage = "hexa.policies"
+
+	ruleAllow = "allow_set"
+	ruleDeny  = "deny_set"
+
+	funcSubjects = "hexa_subjects"
+	funcActions  = "hexa_actions"
+	funcObject   = "hexa_object"
+
+	metaPrefix = "# METADATA"
+)
+
+// ErrNoRules is returned by MapRegoPolicyBytes when a module has no allow_set or deny_set rules
+var ErrNoRules = errors.New("no IDQL rules (allow_set or deny_set) found in Rego module")
+
+// regoSupport implements IDQL matching semantics equivalent to the Hexa IDQL interpreter
+const regoSupport = `default allow := false
+
+allow if {
+    count(deny_set) == 0
+    count(allow_set) > 0
+}
+
+hexa_subjects(members) if {
+    some member in members
+    hexa_subject_match(member)
+}
+
+hexa_subject_match(member) if lower(member) == "any"
+
+hexa_subject_match(member) if {
+    lower(member) == "anyauthenticated"
+    input.subject.sub
+}
+
+hexa_subject_match(member) if {
+    startswith(lower(member), "domain:")
+    endswith(lower(input.subject.sub), lower(substring(member, 7, -1)))
+}
+
+hexa_subject_match(member) if {
+    startswith(lower(member), "user:")
+    not contains(input.subject.sub, ":")
+    lower(substring(member, 5, -1)) == lower(input.subject.sub)
+}
+
+hexa_subject_match(member) if {
+    not endswith(member, ":")
+    lower(member) == lower(input.subject.sub)
+}
+
+hexa_subject_match(member) if {
+    endswith(member, ":")
+    startswith(lower(input.subject.sub), lower(member))
+}
+
+hexa_subject_match(member) if {
+    startswith(lower(member), "role:")
+    substring(member, 5, -1) in input.subject.roles
+}
+
+hexa_subject_match(member) if {
+    startswith(lower(member), "net:")
+    addr := split(input.req.ip, ":")
+    net.cidr_contains(substring(member, 4, -1), addr[0])
+}
+
+hexa_actions(actions) if {
+    some action in actions
+    hexa_action_match(action)
+}
+
+hexa_action_match(action) if {
+    some uri in input.req.actionUris
+    lower(action) == lower(uri)
+}
+
+hexa_action_match(action) if {
+    comps := split(lower(action), ":")
+    count(comps) > 2
+    startswith(comps[0], "http")
+    hexa_http_method(comps[1], lower(input.req.method))
+    glob.match(concat(":", array.slice(comps, 2, count(comps))), ["*"], lower(input.req.path))
+}
+
+hexa_http_method(mask, _) if contains(mask, "*")
+
+hexa_http_method(mask, method) if {
+    startswith(mask, "!")
+    not contains(mask, method)
+}
+
+hexa_http_method(mask, method) if {
+    not startswith(mask, "!")
+    contains(mask, method)
+}
+
+hexa_object(object) if {
+    some id in input.req.resourceIds
+    lower(object) == lower(id)
+}
+
+hexa_object(object) if {
+    endswith(object, ":")
+    some id in input.req.resourceIds
+    startswith(lower(id), lower(object))
+}
+`
+
+var ruleHeadRegex = regexp.MustCompile(`^(allow_set|deny_set)\s+contains\s+("(?:[^"\\]|\\.)*")\s+if\s*\{$`)
+
+type RegoMapper struct {
+	condMap *regoConditions.RegoConditionMapper
+	Package string // Package is the Rego package name of the generated module (default DefaultPackage)
+}
+
+func NewRegoMapper(attrNameMap map[string]string) *RegoMapper {
+	return &RegoMapper{
+		condMap: &regoConditions.RegoConditionMapper{NameMapper: conditions.NewNameMapper(attrNameMap)},
+		Package: DefaultPackage,
+	}
+}
+
+// MapHexaPolicies converts a set of IDQL policies into a standalone Rego module
+func (m *RegoMapper) MapHexaPolicies(policies []hexapolicy.PolicyInfo) (string, error) {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("package %s\n\nimport rego.v1\n\n", m.Package))
+	sb.WriteString(regoSupport)
+
+	for i, policy := range policies {
+		policyRules, err := m.MapHexaPolicy(policy, i)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("\n")
+		sb.WriteString(policyRules)
+	}
+	return sb.String(), nil
+}
+
+// MapHexaPolicy converts a single IDQL policy into one or more Rego rules. Index is used to generate a policy id when
+// the policy does not have one.
+func (m *RegoMapper) MapHexaPolicy(policy hexapolicy.PolicyInfo, index int) (string, error) {
+	policyId := fmt.Sprintf("policy-%d", index)
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		policyId = *policy.Meta.PolicyId
+	}
+
+	var matches []string
+	if len(policy.Subjects) > 0 {
+		matches = append(matches, fmt.Sprintf("%s(%s)", funcSubjects, quoteArray(policy.Subjects)))
+	}
+	if len(policy.Actions) > 0 {
+		actions := make([]string, len(policy.Actions))
+		for i, action := range policy.Actions {
+			actions[i] = action.String()
+		}
+		matches = append(matches, fmt.Sprintf("%s(%s)", funcActions, quoteArray(actions)))
+	}
+	if object := policy.Object.String(); object != "" {
+		matches = append(matches, fmt.Sprintf("%s(%s)", funcObject, strconv.Quote(object)))
+	}
+
+	head := ruleAllow
+	bodies := [][]string{matches}
+	if policy.Condition != nil {
+		if !strings.EqualFold(policy.Condition.Action, conditions.AAllow) && policy.Condition.Action != "" {
+			head = ruleDeny
+		}
+		ast, err := conditions.ParseConditionRuleAst(*policy.Condition)
+		if err != nil {
+			return "", fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+		conditionBodies, err := m.condMap.MapFilter(ast)
+		if err != nil {
+			return "", fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+		bodies = make([][]string, len(conditionBodies))
+		for i, conditionBody := range conditionBodies {
+			b := make([]string, 0, len(matches)+len(conditionBody))
+			b = append(b, matches...)
+			bodies[i] = append(b, conditionBody...)
+		}
+	}
+
+	annotation := formatAnnotation(policyId, policy.Meta.Description)
+	sb := strings.Builder{}
+	for i, b := range bodies {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if len(b) == 0 {
+			b = []string{"true"}
+		}
+		sb.WriteString(annotation)
+		sb.WriteString(regoConditions.FormatRules(fmt.Sprintf("%s contains %s if", head, strconv.Quote(policyId)), [][]string{b}))
+	}
+	return sb.String(), nil
+}
+
+func quoteArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func formatAnnotation(policyId string, description string) string {
+	sb := strings.Builder{}
+	sb.WriteString(metaPrefix + "\n")
+	sb.WriteString("# title: " + strconv.Quote(policyId) + "\n")
+	if description != "" {
+		sb.WriteString("# description: " + strconv.Quote(description) + "\n")
+	}
+	sb.WriteString("# custom:\n")
+	sb.WriteString("#   policyId: " + strconv.Quote(policyId) + "\n")
+	return sb.String()
+}
+
+type regoRule struct {
+	policyId    string
+	description string
+	deny        bool
+	subjects    []string
+	actions     []string
+	object      string
+	condition   []string
+}
+
+// MapRegoPolicyBytes performs a best-effort conversion of a Rego module produced by MapHexaPolicies back into IDQL.
+// Rules that are not in the generated form (a partial set rule adding a policy id to allow_set or deny_set) are
+// ignored. Multiple rules for the same policy id are merged by or'ing their conditions.
+func (m *RegoMapper) MapRegoPolicyBytes(regoBytes []byte) (*hexapolicy.Policies, error) {
+	rules, err := parseRules(regoBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrNoRules
+	}
+
+	var policies []hexapolicy.PolicyInfo
+	index := map[string]int{}
+	for _, rule := range rules {
+		var condition *conditions.ConditionInfo
+		if len(rule.condition) > 0 {
+			cond, err := m.condMap.MapProviderToCondition(strings.Join(rule.condition, "\n"))
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %s", rule.policyId, err.Error())
+			}
+			condition = &cond
+			if rule.deny {
+				condition.Action = conditions.ADeny
+			}
+		}
+
+		if pos, exists := index[rule.policyId]; exists {
+			existing := &policies[pos]
+			// a rule with no condition matches unconditionally
+			if existing.Condition == nil || condition == nil {
+				existing.Condition = nil
+				continue
+			}
+			existing.Condition.Rule = existing.Condition.Rule + " or " + condition.Rule
+			continue
+		}
+
+		actions := make([]hexapolicy.ActionInfo, len(rule.actions))
+		for i, action := range rule.actions {
+			actions[i] = hexapolicy.ActionInfo(action)
+		}
+		policyId := rule.policyId
+		subjects := rule.subjects
+		if subjects == nil {
+			subjects = []string{hexapolicy.SubjectAnyUser}
+		}
+		index[rule.policyId] = len(policies)
+		policies = append(policies, hexapolicy.PolicyInfo{
+			Meta: hexapolicy.MetaInfo{
+				Version:     hexapolicy.IdqlVersion,
+				Description: rule.description,
+				PolicyId:    &policyId,
+			},
+			Subjects:  subjects,
+			Actions:   actions,
+			Object:    hexapolicy.ObjectInfo(rule.object),
+			Condition: condition,
+		})
+	}
+	return &hexapolicy.Policies{Policies: policies}, nil
+}
+
+func parseRules(regoBytes []byte) ([]regoRule, error) {
+	var rules []regoRule
+	var current *regoRule
+	var annotation []string
+	inAnnotation := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(regoBytes))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if current != nil {
+			if line == "}" {
+				rules = append(rules, *current)
+				current = nil
+				continue
+			}
+			if err := current.addStatement(line); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err.Error())
+			}
+			continue
+		}
+
+		if line == metaPrefix {
+			inAnnotation = true
+			annotation = nil
+			continue
+		}
+		if inAnnotation && strings.HasPrefix(line, "#") {
+			annotation = append(annotation, strings.TrimPrefix(line, "#"))
+			continue
+		}
+		inAnnotation = false
+
+		match := ruleHeadRegex.FindStringSubmatch(line)
+		if match == nil {
+			if !strings.HasPrefix(line, "#") {
+				annotation = nil
+			}
+			continue
+		}
+		policyId, err := strconv.Unquote(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid policy id %s", lineNum, match[2])
+		}
+		current = &regoRule{policyId: policyId, deny: match[1] == ruleDeny}
+		current.applyAnnotation(annotation)
+		annotation = nil
+	}
+	if current != nil {
+		return nil, fmt.Errorf("rule for %s is not terminated", current.policyId)
+	}
+	return rules, scanner.Err()
+}
+
+// applyAnnotation extracts the description and custom policyId from METADATA annotation lines
+func (r *regoRule) applyAnnotation(lines []string) {
+	for _, line := range lines {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		switch key {
+		case "description":
+			r.description = value
+		case "policyId":
+			if value != "" {
+				r.policyId = value
+			}
+		}
+	}
+}
+
+func (r *regoRule) addStatement(line string) error {
+	switch {
+	case line == "" || line == "true" || strings.HasPrefix(line, "#"):
+		return nil
+	case strings.HasPrefix(line, funcSubjects+"("):
+		return parseArgument(line, funcSubjects, &r.subjects)
+	case strings.HasPrefix(line, funcActions+"("):
+		return parseArgument(line, funcActions, &r.actions)
+	case strings.HasPrefix(line, funcObject+"("):
+		return parseArgument(line, funcObject, &r.object)
+	}
+	r.condition = append(r.condition, line)
+	return nil
+}
+
+// parseArgument decodes the literal argument of a generated helper call. Rego string and array literals of the form
+// generated are valid JSON.
+func parseArgument(line string, funcName string, value interface{}) error {
+	arg := strings.TrimSuffix(strings.TrimPrefix(line, funcName+"("), ")")
+	if err := json.Unmarshal([]byte(arg), value); err != nil {
+		return fmt.Errorf("invalid %s argument: %s", funcName, arg)
+	}
+	return nil
+}
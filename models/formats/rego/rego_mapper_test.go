@@ -0,0 +1,123 @@
+package rego
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func TestMapHexaPolicies(t *testing.T) {
+	policies, err := hexapolicysupport.ParsePolicyFile(filepath.Join(getExampleDir(), "authZen", "data.json"))
+	assert.NoError(t, err)
+
+	mapper := NewRegoMapper(map[string]string{})
+	regoString, err := mapper.MapHexaPolicies(policies)
+	assert.NoError(t, err)
+
+	assert.Contains(t, regoString, "package hexa.policies\n\nimport rego.v1\n")
+	assert.Contains(t, regoString, `# METADATA
+# title: "GetUsers"
+# description: "Get information (e.g. email, picture) associated with a user"
+# custom:
+#   policyId: "GetUsers"
+allow_set contains "GetUsers" if {
+    hexa_subjects(["anyAuthenticated"])
+    hexa_actions(["can_read_user"])
+    hexa_object("User:")
+}
+`)
+	assert.Contains(t, regoString, `allow_set contains "PutTodo" if {
+    hexa_subjects(["anyAuthenticated"])
+    hexa_actions(["can_update_todo"])
+    hexa_object("Todo:")
+    "editor" in input.subject.roles
+    input.resource.properties.ownerID == input.subject.claims.email
+}
+`, "or'ed conditions map to a rule per body")
+}
+
+func TestMapHexaPolicy_DenyAndDefaults(t *testing.T) {
+	mapper := NewRegoMapper(map[string]string{"ip": "req.ip"})
+
+	policy := hexapolicy.PolicyInfo{
+		Subjects:  []string{"any"},
+		Condition: &conditions.ConditionInfo{Rule: "ip sw \"10.\"", Action: conditions.ADeny},
+	}
+	regoString, err := mapper.MapHexaPolicy(policy, 3)
+	assert.NoError(t, err)
+	assert.Contains(t, regoString, "deny_set contains \"policy-3\" if {\n    hexa_subjects([\"any\"])\n    startswith(input.req.ip, \"10.\")\n}\n")
+
+	regoString, err = mapper.MapHexaPolicy(hexapolicy.PolicyInfo{}, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, regoString, "allow_set contains \"policy-0\" if {\n    true\n}\n")
+
+	_, err = mapper.MapHexaPolicy(hexapolicy.PolicyInfo{
+		Condition: &conditions.ConditionInfo{Rule: "subject in Group:admins", Action: conditions.AAllow},
+	}, 0)
+	assert.Error(t, err)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, file := range []string{
+		filepath.Join(getExampleDir(), "authZen", "data.json"),
+		filepath.Join(getExampleDir(), "policyExamples", "example_idql.json"),
+	} {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			policies, err := hexapolicysupport.ParsePolicyFile(file)
+			assert.NoError(t, err)
+
+			mapper := NewRegoMapper(map[string]string{})
+			regoString, err := mapper.MapHexaPolicies(policies)
+			assert.NoError(t, err)
+
+			mapped, err := mapper.MapRegoPolicyBytes([]byte(regoString))
+			assert.NoError(t, err, regoString)
+			assert.Len(t, mapped.Policies, len(policies))
+			for i, policy := range policies {
+				result := mapped.Policies[i]
+				policyId := fmt.Sprintf("policy-%d", i)
+				if policy.Meta.PolicyId != nil {
+					policyId = *policy.Meta.PolicyId
+				}
+				assert.Equal(t, policyId, *result.Meta.PolicyId)
+				assert.Equal(t, policy.Meta.Description, result.Meta.Description)
+				assert.True(t, policy.Subjects.Equals(result.Subjects), "subjects %s", policyId)
+				assert.True(t, policy.ActionsEqual(result.Actions), "actions %s", policyId)
+				assert.Equal(t, policy.Object, result.Object)
+				if policy.Condition == nil {
+					assert.Nil(t, result.Condition)
+					continue
+				}
+				assert.NotNil(t, result.Condition)
+				assert.True(t, policy.Condition.Equals(result.Condition), "expected %s, got %s", policy.Condition.Rule, result.Condition.Rule)
+			}
+		})
+	}
+}
+
+func TestMapRegoPolicyBytes_Errors(t *testing.T) {
+	mapper := NewRegoMapper(map[string]string{})
+
+	_, err := mapper.MapRegoPolicyBytes([]byte("package test\n\nallow if { input.a == 1 }\n"))
+	assert.Error(t, err, "no generated rules")
+
+	_, err = mapper.MapRegoPolicyBytes([]byte("allow_set contains \"a\" if {\n    hexa_subjects(\"any\")\n}\n"))
+	assert.Error(t, err, "subjects must be an array")
+
+	_, err = mapper.MapRegoPolicyBytes([]byte("allow_set contains \"a\" if {\n    count(input.roles) > 2\n}\n"))
+	assert.Error(t, err, "unsupported condition")
+
+	_, err = mapper.MapRegoPolicyBytes([]byte("allow_set contains \"a\" if {\n    true\n"))
+	assert.Error(t, err, "unterminated rule")
+}
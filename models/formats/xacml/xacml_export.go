@@ -0,0 +1,321 @@
+package xacml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const DefaultPolicySetId = "urn:hexa:idql:policies"
+
+// MapPoliciesToXacml converts IDQL policies into an XACML PolicySet using deny-overrides. Each IDQL policy becomes a
+// Policy with a single Rule. Subjects, actions and object are mapped into the Policy Target, and the IDQL condition
+// into the Rule Condition.
+func (m *XacmlMapper) MapPoliciesToXacml(policies []hexapolicy.PolicyInfo) (*PolicySet, error) {
+	set := PolicySet{
+		Xmlns:                Namespace,
+		PolicySetId:          DefaultPolicySetId,
+		Version:              "1.0",
+		PolicyCombiningAlgId: AlgPolicyDeny,
+	}
+	for i, policy := range policies {
+		xPolicy, err := m.MapPolicyToXacml(policy, i)
+		if err != nil {
+			return nil, err
+		}
+		set.Policies = append(set.Policies, *xPolicy)
+	}
+	return &set, nil
+}
+
+// MapPoliciesToXacmlBytes converts IDQL policies into an indented XACML document
+func (m *XacmlMapper) MapPoliciesToXacmlBytes(policies []hexapolicy.PolicyInfo) ([]byte, error) {
+	set, err := m.MapPoliciesToXacml(policies)
+	if err != nil {
+		return nil, err
+	}
+	xmlBytes, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), xmlBytes...), nil
+}
+
+// MapPolicyToXacml converts a single IDQL policy into an XACML Policy. Index is used to generate a policy id when the
+// policy does not have one.
+func (m *XacmlMapper) MapPolicyToXacml(policy hexapolicy.PolicyInfo, index int) (*Policy, error) {
+	policyId := fmt.Sprintf("policy-%d", index)
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		policyId = *policy.Meta.PolicyId
+	}
+	res := Policy{
+		PolicyId:           policyId,
+		Version:            "1.0",
+		RuleCombiningAlgId: AlgDenyOverrides,
+		Description:        policy.Meta.Description,
+	}
+	rule := Rule{RuleId: policyId, Effect: EffectPermit}
+
+	var conditionArgs []Expression
+	subjectMatches, authenticated, err := m.mapSubjects(policy.Subjects)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+	}
+	if len(subjectMatches) > 0 {
+		res.Target.AnyOf = append(res.Target.AnyOf, anyOf(subjectMatches))
+	}
+	if authenticated {
+		conditionArgs = append(conditionArgs, m.presentExpression(m.designator("subject.id", TypeString)))
+	}
+
+	if len(policy.Actions) > 0 {
+		matches := make([]Match, len(policy.Actions))
+		for i, action := range policy.Actions {
+			matches[i] = newMatch("string-equal", action.String(), AttributeDesignator{Category: CategoryAction, AttributeId: AttrActionId, DataType: TypeString})
+		}
+		res.Target.AnyOf = append(res.Target.AnyOf, anyOf(matches))
+	}
+
+	if object := policy.Object.String(); object != "" {
+		function := "string-equal"
+		if strings.HasSuffix(object, ":") {
+			function = "string-starts-with"
+		}
+		resource := AttributeDesignator{Category: CategoryResource, AttributeId: AttrResourceId, DataType: TypeString}
+		res.Target.AnyOf = append(res.Target.AnyOf, anyOf([]Match{newMatch(function, object, resource)}))
+	}
+
+	if policy.Condition != nil {
+		if strings.EqualFold(policy.Condition.Action, conditions.ADeny) {
+			rule.Effect = EffectDeny
+		}
+		ast, err := conditions.ParseConditionRuleAst(*policy.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+		expression, err := m.mapIdqlExpression(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %s", policyId, err.Error())
+		}
+		if expression.XMLName.Local == elemApply && strings.HasSuffix(expression.FunctionId, ":and") {
+			conditionArgs = append(conditionArgs, expression.Args...)
+		} else {
+			conditionArgs = append(conditionArgs, expression)
+		}
+	}
+
+	switch len(conditionArgs) {
+	case 0:
+	case 1:
+		rule.Condition = &Condition{Expression: conditionArgs[0]}
+	default:
+		rule.Condition = &Condition{Expression: newApply(FunctionPrefix1+"and", conditionArgs...)}
+	}
+	res.Rules = []Rule{rule}
+	return &res, nil
+}
+
+func newMatch(function string, value string, designator AttributeDesignator) Match {
+	prefix := FunctionPrefix1
+	if function == "string-starts-with" {
+		prefix = FunctionPrefix3
+	}
+	return Match{
+		MatchId:             prefix + function,
+		AttributeValue:      AttributeValue{DataType: designator.DataType, Value: value},
+		AttributeDesignator: &designator,
+	}
+}
+
+// anyOf returns an AnyOf in which each match is an alternative
+func anyOf(matches []Match) AnyOf {
+	res := AnyOf{AllOf: make([]AllOf, len(matches))}
+	for i, match := range matches {
+		res.AllOf[i] = AllOf{Match: []Match{match}}
+	}
+	return res
+}
+
+// mapSubjects maps IDQL subjects to subject-id and role matches. Returns authenticated if the policy applies to any
+// authenticated subject.
+func (m *XacmlMapper) mapSubjects(subjects hexapolicy.SubjectInfo) ([]Match, bool, error) {
+	var matches []Match
+	authenticated := false
+	for _, subject := range subjects {
+		lower := strings.ToLower(subject)
+		switch {
+		case lower == strings.ToLower(hexapolicy.SubjectAnyUser):
+			return nil, false, nil
+		case lower == strings.ToLower(hexapolicy.SubjectAnyAuth):
+			authenticated = true
+		case strings.HasPrefix(lower, "role:"):
+			matches = append(matches, newMatch("string-equal", subject[5:], AttributeDesignator{Category: CategorySubject, AttributeId: AttrRole, DataType: TypeString}))
+		case strings.HasPrefix(lower, "domain:") || strings.HasPrefix(lower, "net:"):
+			return nil, false, fmt.Errorf("subject %s cannot be mapped to XACML", subject)
+		default:
+			matches = append(matches, newMatch("string-equal", subject, AttributeDesignator{Category: CategorySubject, AttributeId: AttrSubjectId, DataType: TypeString}))
+		}
+	}
+	if authenticated {
+		// any authenticated subject includes the other subjects
+		return nil, true, nil
+	}
+	return matches, false, nil
+}
+
+func (m *XacmlMapper) presentExpression(designator AttributeDesignator) Expression {
+	return newApply(FunctionPrefix1+"integer-greater-than",
+		newApply(FunctionPrefix1+shortName(designator.DataType)+"-bag-size", newDesignator(designator)),
+		newValue(TypeInteger, "0"))
+}
+
+// mapIdqlExpression converts an IDQL condition AST into an XACML expression
+func (m *XacmlMapper) mapIdqlExpression(ast parser.Expression) (Expression, error) {
+	switch element := ast.(type) {
+	case parser.PrecedenceExpression:
+		return m.mapIdqlExpression(element.Expression)
+	case parser.NotExpression:
+		expression, err := m.mapIdqlExpression(element.Expression)
+		if err != nil {
+			return Expression{}, err
+		}
+		return newApply(FunctionPrefix1+"not", expression), nil
+	case parser.LogicalExpression:
+		left, err := m.mapIdqlExpression(element.Left)
+		if err != nil {
+			return Expression{}, err
+		}
+		right, err := m.mapIdqlExpression(element.Right)
+		if err != nil {
+			return Expression{}, err
+		}
+		functionId := FunctionPrefix1 + string(element.Operator)
+		var args []Expression
+		// flatten repeated and/or (e.g. a and b and c)
+		for _, side := range []Expression{left, right} {
+			if side.XMLName.Local == elemApply && side.FunctionId == functionId {
+				args = append(args, side.Args...)
+			} else {
+				args = append(args, side)
+			}
+		}
+		return newApply(functionId, args...), nil
+	case parser.AttributeExpression:
+		return m.mapIdqlAttrExpr(element)
+	case parser.ValuePathExpression:
+		return Expression{}, fmt.Errorf("IDQL value path expressions (%s) are not supported in XACML", element.String())
+	}
+	return Expression{}, fmt.Errorf("unsupported IDQL expression: %s", ast.String())
+}
+
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+// literalType returns the XACML data type and value of an IDQL literal
+func literalType(value types.Value) (string, string, error) {
+	switch v := value.(type) {
+	case types.String:
+		return TypeString, v.Value().(string), nil
+	case types.Numeric:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return TypeDouble, v.String(), nil
+		}
+		return TypeInteger, v.String(), nil
+	case types.Boolean:
+		return TypeBoolean, v.String(), nil
+	case types.Date:
+		return TypeDateTime, v.String(), nil
+	}
+	return "", "", fmt.Errorf("unsupported value type %s in XACML mapping", types.TypeName(value.ValueType()))
+}
+
+var comparisonFunctions = map[parser.CompareOperator]string{
+	parser.EQ: "-equal",
+	parser.GT: "-greater-than",
+	parser.GE: "-greater-than-or-equal",
+	parser.LT: "-less-than",
+	parser.LE: "-less-than-or-equal",
+}
+
+func (m *XacmlMapper) mapIdqlAttrExpr(attrExpr parser.AttributeExpression) (Expression, error) {
+	entity, ok := attrExpr.AttributePath.(types.Entity)
+	if !ok || !isAttributePath(entity) {
+		return Expression{}, fmt.Errorf("left hand side of '%s' must be an attribute", attrExpr.String())
+	}
+	path := entity.String()
+
+	if attrExpr.Operator == parser.PR {
+		return m.presentExpression(m.designator(path, TypeString)), nil
+	}
+
+	compare := attrExpr.CompareValue
+	var valueExpr Expression
+	dataType := TypeString
+	switch value := compare.(type) {
+	case types.Entity:
+		if !isAttributePath(value) {
+			return Expression{}, fmt.Errorf("IDQL entity comparison (%s) is not supported in XACML", attrExpr.String())
+		}
+		valueExpr = newApply(FunctionPrefix1+"string-one-and-only", newDesignator(m.designator(value.String(), TypeString)))
+	case types.Array:
+		if attrExpr.Operator != parser.IN {
+			return Expression{}, fmt.Errorf("IDQL operator '%s' does not support an array value in XACML", attrExpr.Operator)
+		}
+		items := value.Value().([]types.ComparableValue)
+		values := make([]Expression, len(items))
+		for i, item := range items {
+			itemType, itemValue, err := literalType(item)
+			if err != nil {
+				return Expression{}, err
+			}
+			dataType = itemType
+			values[i] = newValue(itemType, itemValue)
+		}
+		typeName := shortName(dataType)
+		return newApply(FunctionPrefix1+typeName+"-at-least-one-member-of",
+			newDesignator(m.designator(path, dataType)),
+			newApply(FunctionPrefix1+typeName+"-bag", values...)), nil
+	default:
+		var literal string
+		var err error
+		dataType, literal, err = literalType(compare)
+		if err != nil {
+			return Expression{}, err
+		}
+		valueExpr = newValue(dataType, literal)
+	}
+
+	typeName := shortName(dataType)
+	designator := newDesignator(m.designator(path, dataType))
+	single := newApply(FunctionPrefix1+typeName+"-one-and-only", designator)
+
+	switch attrExpr.Operator {
+	case parser.NE:
+		return newApply(FunctionPrefix1+"not", newApply(FunctionPrefix1+typeName+"-equal", single, valueExpr)), nil
+	case parser.SW, parser.EW:
+		if dataType != TypeString {
+			break
+		}
+		function := "-starts-with"
+		if attrExpr.Operator == parser.EW {
+			function = "-ends-with"
+		}
+		return newApply(FunctionPrefix3+typeName+function, valueExpr, single), nil
+	case parser.CO:
+		// consistent with Cedar, contains tests membership of a multi-valued attribute
+		return newApply(FunctionPrefix1+typeName+"-is-in", valueExpr, designator), nil
+	case parser.IN:
+		return Expression{}, fmt.Errorf("IDQL entity membership (%s) is not supported in XACML", attrExpr.String())
+	default:
+		if function, exists := comparisonFunctions[attrExpr.Operator]; exists {
+			return newApply(FunctionPrefix1+typeName+function, single, valueExpr), nil
+		}
+	}
+	return Expression{}, fmt.Errorf("IDQL operator '%s' is not supported in XACML for %s values", attrExpr.Operator, shortName(dataType))
+}
@@ -0,0 +1,702 @@
+package xacml
+
+/*
+ Maps XACML 3.0 policies to and from IDQL.
+
+ On import, each XACML Rule becomes an IDQL policy. Target matches for subject-id and role become subjects, action-id
+ matches become actions, and a resource-id match becomes the object. All other target matches (including those of the
+ enclosing Policy and PolicySets) and the rule Condition are combined into the IDQL condition. A Deny rule is mapped
+ to a condition with a deny action.
+
+ IDQL uses deny-overrides semantics. Combining algorithms that differ from deny-overrides for the rules they combine
+ are reported as issues, as are constructs that cannot be mapped (e.g. obligations, variable references, selectors).
+ A rule that cannot be mapped is skipped and reported rather than failing the whole document.
+*/
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	idqlSubject     = "subject"
+	idqlResource    = "resource"
+	idqlAction      = "action"
+	idqlEnvironment = "req"
+
+	sourcePolicyId = "xacmlPolicyId"
+)
+
+// categoryNames maps XACML attribute categories to IDQL attribute prefixes
+var categoryNames = [][2]string{
+	{CategorySubject, idqlSubject},
+	{CategoryResource, idqlResource},
+	{CategoryAction, idqlAction},
+	{CategoryEnvironment, idqlEnvironment},
+}
+
+// knownAttributes maps IDQL attribute names to standard XACML attribute identifiers
+var knownAttributes = [][2]string{
+	{"subject.id", AttrSubjectId},
+	{"subject.roles", AttrRole},
+	{"resource.id", AttrResourceId},
+	{"action.id", AttrActionId},
+	{"req.time", AttrCurrentTime},
+}
+
+// Issue reports an XACML construct that could not be (fully) mapped to IDQL
+type Issue struct {
+	PolicyId string `json:"policyId"`
+	RuleId   string `json:"ruleId,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.RuleId == "" {
+		return fmt.Sprintf("policy %s: %s", i.PolicyId, i.Message)
+	}
+	return fmt.Sprintf("policy %s, rule %s: %s", i.PolicyId, i.RuleId, i.Message)
+}
+
+type XacmlMapper struct {
+	NameMapper *conditions.AttributeMap
+}
+
+func NewXacmlMapper(attrNameMap map[string]string) *XacmlMapper {
+	return &XacmlMapper{NameMapper: conditions.NewNameMapper(attrNameMap)}
+}
+
+// attributePath maps an XACML attribute designator to an IDQL attribute name
+func (m *XacmlMapper) attributePath(category string, attributeId string) string {
+	if m.NameMapper != nil {
+		if mapped := m.NameMapper.GetHexaFilterAttributePath(strings.ToLower(attributeId)); !strings.EqualFold(mapped, attributeId) {
+			return mapped
+		}
+	}
+	for _, known := range knownAttributes {
+		if known[1] == attributeId {
+			return known[0]
+		}
+	}
+	prefix := shortName(category)
+	for _, item := range categoryNames {
+		if item[0] == category {
+			prefix = item[1]
+		}
+	}
+	return prefix + "." + shortName(attributeId)
+}
+
+// designator maps an IDQL attribute name to an XACML attribute designator
+func (m *XacmlMapper) designator(path string, dataType string) AttributeDesignator {
+	res := AttributeDesignator{DataType: dataType}
+	prefix, name, _ := strings.Cut(path, ".")
+	switch strings.ToLower(prefix) {
+	case idqlSubject, "principal":
+		res.Category = CategorySubject
+	case idqlResource:
+		res.Category = CategoryResource
+	case idqlAction:
+		res.Category = CategoryAction
+	case idqlEnvironment, "env", "environment":
+		res.Category = CategoryEnvironment
+	default:
+		res.Category = prefix
+	}
+	res.AttributeId = name
+	for _, known := range knownAttributes {
+		if strings.EqualFold(known[0], path) {
+			res.AttributeId = known[1]
+		}
+	}
+	if m.NameMapper != nil {
+		if mapped := m.NameMapper.GetProviderAttributeName(path); mapped != path {
+			res.AttributeId = mapped
+		}
+	}
+	return res
+}
+
+// ParseXacml parses a document whose root element is either a PolicySet or a Policy. A Policy is returned wrapped in
+// a PolicySet using deny-overrides.
+func ParseXacml(xmlBytes []byte) (*PolicySet, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(xmlBytes))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, errors.New("invalid XACML document: " + err.Error())
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "PolicySet":
+			var set PolicySet
+			if err = xml.Unmarshal(xmlBytes, &set); err != nil {
+				return nil, err
+			}
+			return &set, nil
+		case "Policy":
+			var policy Policy
+			if err = xml.Unmarshal(xmlBytes, &policy); err != nil {
+				return nil, err
+			}
+			return &PolicySet{PolicyCombiningAlgId: AlgPolicyDeny, Policies: []Policy{policy}}, nil
+		}
+		return nil, fmt.Errorf("unexpected XACML root element: %s", start.Name.Local)
+	}
+}
+
+func ParseFile(path string) (*PolicySet, error) {
+	xmlBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseXacml(xmlBytes)
+}
+
+// MapXacmlBytes parses an XACML document and maps it into IDQL policies. Issues holds the constructs that could not
+// be mapped.
+func (m *XacmlMapper) MapXacmlBytes(xmlBytes []byte) (*hexapolicy.Policies, []Issue, error) {
+	set, err := ParseXacml(xmlBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	policies, issues := m.MapPolicySet(set)
+	return &hexapolicy.Policies{Policies: policies}, issues, nil
+}
+
+type importContext struct {
+	mapper   *XacmlMapper
+	policies []hexapolicy.PolicyInfo
+	issues   []Issue
+}
+
+// MapPolicySet maps the rules of a PolicySet (and its descendants) into IDQL policies
+func (m *XacmlMapper) MapPolicySet(set *PolicySet) ([]hexapolicy.PolicyInfo, []Issue) {
+	ctx := &importContext{mapper: m}
+	ctx.mapPolicySet(*set, nil, false)
+	return ctx.policies, ctx.issues
+}
+
+func (ctx *importContext) issue(policyId string, ruleId string, format string, args ...interface{}) {
+	ctx.issues = append(ctx.issues, Issue{PolicyId: policyId, RuleId: ruleId, Message: fmt.Sprintf(format, args...)})
+}
+
+func (ctx *importContext) mapPolicySet(set PolicySet, targets []AnyOf, ignoreDeny bool) {
+	targets = append(append([]AnyOf{}, targets...), set.Target.AnyOf...)
+	for _, ref := range append(set.PolicyIdReferences, set.PolicySetIdReferences...) {
+		ctx.issue(set.PolicySetId, "", "policy reference %s is not supported", strings.TrimSpace(ref))
+	}
+
+	ignore, message := checkCombining(set.PolicyCombiningAlgId, setEffects(set))
+	if message != "" {
+		ctx.issue(set.PolicySetId, "", "%s", message)
+	}
+	for _, child := range set.PolicySets {
+		ctx.mapPolicySet(child, targets, ignoreDeny || ignore)
+	}
+	for _, policy := range set.Policies {
+		ctx.mapPolicy(policy, targets, ignoreDeny || ignore)
+	}
+}
+
+func setEffects(set PolicySet) []string {
+	var effects []string
+	for _, child := range set.PolicySets {
+		effects = append(effects, setEffects(child)...)
+	}
+	for _, policy := range set.Policies {
+		for _, rule := range policy.Rules {
+			effects = append(effects, rule.Effect)
+		}
+	}
+	return effects
+}
+
+// checkCombining compares a combining algorithm with IDQL deny-overrides semantics for the effects it combines. When
+// ignoreDeny is true, deny rules have no effect (deny-unless-permit).
+func checkCombining(algorithm string, effects []string) (ignoreDeny bool, message string) {
+	name := shortName(algorithm)
+	hasPermit, hasDeny := false, false
+	for _, effect := range effects {
+		if strings.EqualFold(effect, EffectDeny) {
+			hasDeny = true
+		} else {
+			hasPermit = true
+		}
+	}
+	switch name {
+	case "", "deny-overrides", "ordered-deny-overrides":
+		return false, ""
+	case "deny-unless-permit":
+		if hasDeny {
+			return true, "deny rules are ignored by combining algorithm deny-unless-permit"
+		}
+		return false, ""
+	case "permit-unless-deny":
+		return false, "the default permit of combining algorithm permit-unless-deny cannot be represented in IDQL"
+	}
+	if hasPermit && hasDeny {
+		return false, fmt.Sprintf("combining algorithm %s is mapped using deny-overrides (deny rules take precedence)", name)
+	}
+	return false, ""
+}
+
+func (ctx *importContext) mapPolicy(policy Policy, targets []AnyOf, ignoreDeny bool) {
+	targets = append(append([]AnyOf{}, targets...), policy.Target.AnyOf...)
+	if len(policy.VariableDefinitions) > 0 {
+		ctx.issue(policy.PolicyId, "", "variable definitions are not supported")
+	}
+	if policy.ObligationExpressions != nil || policy.AdviceExpressions != nil {
+		ctx.issue(policy.PolicyId, "", "obligations and advice are ignored")
+	}
+
+	effects := make([]string, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		effects[i] = rule.Effect
+	}
+	ignore, message := checkCombining(policy.RuleCombiningAlgId, effects)
+	if message != "" {
+		ctx.issue(policy.PolicyId, "", "%s", message)
+	}
+
+	for _, rule := range policy.Rules {
+		if (ignoreDeny || ignore) && strings.EqualFold(rule.Effect, EffectDeny) {
+			ctx.issue(policy.PolicyId, rule.RuleId, "rule skipped: deny rules have no effect under deny-unless-permit")
+			continue
+		}
+		if rule.ObligationExpressions != nil || rule.AdviceExpressions != nil {
+			ctx.issue(policy.PolicyId, rule.RuleId, "obligations and advice are ignored")
+		}
+		idqlPolicy, err := ctx.mapper.mapRule(policy, rule, targets)
+		if err != nil {
+			ctx.issue(policy.PolicyId, rule.RuleId, "rule skipped: %s", err.Error())
+			continue
+		}
+		ctx.policies = append(ctx.policies, *idqlPolicy)
+	}
+}
+
+func (m *XacmlMapper) mapRule(policy Policy, rule Rule, targets []AnyOf) (*hexapolicy.PolicyInfo, error) {
+	if rule.Target != nil {
+		targets = append(append([]AnyOf{}, targets...), rule.Target.AnyOf...)
+	}
+
+	policyId := rule.RuleId
+	if policyId == "" {
+		policyId = policy.PolicyId
+	}
+	description := strings.TrimSpace(rule.Description)
+	if description == "" {
+		description = strings.TrimSpace(policy.Description)
+	}
+	res := hexapolicy.PolicyInfo{
+		Meta: hexapolicy.MetaInfo{
+			Version:     hexapolicy.IdqlVersion,
+			PolicyId:    &policyId,
+			Description: description,
+			SourceData:  map[string]interface{}{sourcePolicyId: policy.PolicyId},
+		},
+		Subjects: hexapolicy.SubjectInfo{},
+	}
+
+	var clauses []string
+	for _, anyOf := range targets {
+		if m.mapTargetElement(anyOf, &res) {
+			continue
+		}
+		clause, err := m.mapAnyOf(anyOf)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if rule.Condition != nil {
+		conditionClauses, authenticated, err := m.mapCondition(rule.Condition.Expression)
+		if err != nil {
+			return nil, err
+		}
+		if authenticated && len(res.Subjects) == 0 {
+			res.Subjects = hexapolicy.SubjectInfo{hexapolicy.SubjectAnyAuth}
+		}
+		clauses = append(clauses, conditionClauses...)
+	}
+	if len(res.Subjects) == 0 {
+		res.Subjects = hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}
+	}
+
+	isDeny := strings.EqualFold(rule.Effect, EffectDeny)
+	if !isDeny && !strings.EqualFold(rule.Effect, EffectPermit) {
+		return nil, fmt.Errorf("invalid effect %s", rule.Effect)
+	}
+	if len(clauses) == 0 {
+		if isDeny {
+			return nil, errors.New("unconditional deny rules cannot be represented in IDQL")
+		}
+		return &res, nil
+	}
+
+	for i, clause := range clauses {
+		if len(clauses) > 1 && strings.Contains(clause, " or ") {
+			clauses[i] = "(" + clause + ")"
+		}
+	}
+	ast, err := conditions.ParseExpressionAst(strings.Join(clauses, " and "))
+	if err != nil {
+		return nil, err
+	}
+	action := conditions.AAllow
+	if isDeny {
+		action = conditions.ADeny
+	}
+	res.Condition = &conditions.ConditionInfo{Rule: conditions.SerializeExpression(ast), Action: action}
+	return &res, nil
+}
+
+// mapTargetElement maps an AnyOf consisting only of subject, action or resource identifier matches into the
+// corresponding IDQL policy element. Returns false if the AnyOf must be mapped as a condition.
+func (m *XacmlMapper) mapTargetElement(anyOf AnyOf, policy *hexapolicy.PolicyInfo) bool {
+	var kind string
+	var values []string
+	for _, allOf := range anyOf.AllOf {
+		if len(allOf.Match) != 1 || allOf.Match[0].AttributeDesignator == nil {
+			return false
+		}
+		match := allOf.Match[0]
+		function := shortName(match.MatchId)
+		value := strings.TrimSpace(match.AttributeValue.Value)
+		matchKind := ""
+		switch match.AttributeDesignator.AttributeId {
+		case AttrSubjectId:
+			matchKind = idqlSubject
+		case AttrRole:
+			matchKind, value = idqlSubject, "role:"+value
+		case AttrActionId:
+			matchKind = idqlAction
+		case AttrResourceId:
+			matchKind = idqlResource
+			if function == "string-starts-with" && strings.HasSuffix(value, ":") {
+				function = "string-equal"
+			}
+		}
+		if matchKind == "" || function != "string-equal" || (kind != "" && kind != matchKind) {
+			return false
+		}
+		kind = matchKind
+		values = append(values, value)
+	}
+
+	switch kind {
+	case idqlSubject:
+		if len(policy.Subjects) > 0 {
+			return false
+		}
+		policy.Subjects = values
+	case idqlAction:
+		if len(policy.Actions) > 0 {
+			return false
+		}
+		for _, value := range values {
+			policy.Actions = append(policy.Actions, hexapolicy.ActionInfo(value))
+		}
+	case idqlResource:
+		if len(values) != 1 || policy.Object != "" {
+			return false
+		}
+		policy.Object = hexapolicy.ObjectInfo(values[0])
+	default:
+		return false
+	}
+	return true
+}
+
+// mapAnyOf maps a target AnyOf element into an IDQL condition clause
+func (m *XacmlMapper) mapAnyOf(anyOf AnyOf) (string, error) {
+	var alternatives []string
+	for _, allOf := range anyOf.AllOf {
+		var matches []string
+		for _, match := range allOf.Match {
+			if match.AttributeDesignator == nil {
+				return "", errors.New("attribute selectors are not supported")
+			}
+			path := m.attributePath(match.AttributeDesignator.Category, match.AttributeDesignator.AttributeId)
+			literal := formatLiteral(match.AttributeValue.DataType, match.AttributeValue.Value)
+			// A match function is called with the attribute value first, and the designator value second
+			clause, err := mapComparison(shortName(match.MatchId), operand{literal: literal}, operand{path: path})
+			if err != nil {
+				return "", err
+			}
+			matches = append(matches, clause)
+		}
+		alternatives = append(alternatives, joinClauses(matches, " and "))
+	}
+	return joinClauses(alternatives, " or "), nil
+}
+
+func joinClauses(clauses []string, join string) string {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	for i, clause := range clauses {
+		if strings.Contains(clause, " or ") || strings.Contains(clause, " and ") {
+			clauses[i] = "(" + clause + ")"
+		}
+	}
+	return strings.Join(clauses, join)
+}
+
+// operand is either an IDQL attribute path or a literal value
+type operand struct {
+	path    string
+	literal string
+}
+
+func formatLiteral(dataType string, value string) string {
+	value = strings.TrimSpace(value)
+	switch shortName(dataType) {
+	case "integer", "double":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return value
+		}
+	case "boolean":
+		return strings.ToLower(value)
+	case "dateTime":
+		if _, err := types.NewDate(value); err == nil {
+			return value
+		}
+	}
+	return strconv.Quote(value)
+}
+
+var operators = []struct {
+	suffix string
+	op     string
+	flip   string
+}{
+	{"-equal-ignore-case", "eq", "eq"},
+	{"-greater-than-or-equal", "ge", "le"},
+	{"-less-than-or-equal", "le", "ge"},
+	{"-greater-than", "gt", "lt"},
+	{"-less-than", "lt", "gt"},
+	{"-equal", "eq", "eq"},
+	{"-starts-with", "sw", "sw"},
+	{"-ends-with", "ew", "ew"},
+	{"-contains", "co", "co"},
+}
+
+// mapComparison maps a two argument XACML comparison function to an IDQL comparison. For starts-with, ends-with and
+// contains the first argument is the pattern (XACML 3.0 argument order).
+func mapComparison(function string, first operand, second operand) (string, error) {
+	for _, item := range operators {
+		if !strings.HasSuffix(function, item.suffix) {
+			continue
+		}
+		switch {
+		case item.op == "sw" || item.op == "ew" || item.op == "co":
+			if second.path == "" {
+				return "", fmt.Errorf("%s requires an attribute as the second argument", function)
+			}
+			return fmt.Sprintf("%s %s %s", second.path, item.op, first.value()), nil
+		case first.path != "":
+			return fmt.Sprintf("%s %s %s", first.path, item.op, second.value()), nil
+		case second.path != "":
+			return fmt.Sprintf("%s %s %s", second.path, item.flip, first.value()), nil
+		}
+		return "", fmt.Errorf("%s must compare an attribute", function)
+	}
+	return "", fmt.Errorf("XACML function %s is not supported", function)
+}
+
+func (o operand) value() string {
+	if o.path != "" {
+		return o.path
+	}
+	return o.literal
+}
+
+// mapCondition maps a rule Condition into and'ed IDQL clauses. A top level test that the subject-id is present is
+// reported as authenticated (anyAuthenticated) rather than as a clause.
+func (m *XacmlMapper) mapCondition(expression Expression) ([]string, bool, error) {
+	expressions := []Expression{expression}
+	if expression.XMLName.Local == elemApply && shortName(expression.FunctionId) == "and" {
+		expressions = arguments(expression)
+	}
+	var clauses []string
+	authenticated := false
+	for _, item := range expressions {
+		clause, err := m.mapExpression(item)
+		if err != nil {
+			return nil, false, err
+		}
+		if clause == "subject.id pr" {
+			authenticated = true
+			continue
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, authenticated, nil
+}
+
+// arguments returns the expression arguments of an Apply, ignoring Description elements
+func arguments(expression Expression) []Expression {
+	var args []Expression
+	for _, arg := range expression.Args {
+		if arg.XMLName.Local != "Description" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func (m *XacmlMapper) mapExpression(expression Expression) (string, error) {
+	switch expression.XMLName.Local {
+	case elemApply:
+	case elemDesignator:
+		if shortName(expression.DataType) == "boolean" {
+			return m.attributePath(expression.Category, expression.AttributeId) + " eq true", nil
+		}
+		return "", fmt.Errorf("attribute %s is not a boolean expression", expression.AttributeId)
+	default:
+		return "", fmt.Errorf("XACML %s expressions are not supported", expression.XMLName.Local)
+	}
+
+	function := shortName(expression.FunctionId)
+	args := arguments(expression)
+	switch {
+	case function == "and" || function == "or":
+		clauses := make([]string, len(args))
+		for i, arg := range args {
+			clause, err := m.mapExpression(arg)
+			if err != nil {
+				return "", err
+			}
+			clauses[i] = clause
+		}
+		if len(clauses) == 0 {
+			return "", fmt.Errorf("%s requires arguments", function)
+		}
+		return joinClauses(clauses, " "+function+" "), nil
+	case function == "not":
+		if len(args) != 1 {
+			return "", errors.New("not requires a single argument")
+		}
+		clause, err := m.mapExpression(args[0])
+		if err != nil {
+			return "", err
+		}
+		// not(string-equal(a, b)) is the XACML form of IDQL ne
+		if negated := shortName(args[0].FunctionId); args[0].XMLName.Local == elemApply && strings.HasSuffix(negated, "-equal") &&
+			!strings.HasSuffix(negated, "-ignore-case") && strings.Count(clause, " eq ") == 1 {
+			return strings.Replace(clause, " eq ", " ne ", 1), nil
+		}
+		return "not(" + clause + ")", nil
+	case function == "any-of":
+		if len(args) != 3 || args[0].XMLName.Local != elemFunction {
+			return "", errors.New("any-of is only supported with a function, a value, and a bag")
+		}
+		return m.mapFunction(shortName(args[0].FunctionId), args[1:])
+	}
+	return m.mapFunction(function, args)
+}
+
+func (m *XacmlMapper) mapFunction(function string, args []Expression) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("XACML function %s with %d arguments is not supported", function, len(args))
+	}
+	switch {
+	case strings.HasSuffix(function, "-is-in"):
+		value, err := m.mapOperand(args[0])
+		if err != nil {
+			return "", err
+		}
+		bag, err := m.mapOperand(args[1])
+		if err != nil || bag.path == "" {
+			return "", fmt.Errorf("%s requires an attribute bag", function)
+		}
+		return fmt.Sprintf("%s co %s", bag.path, value.value()), nil
+	case strings.HasSuffix(function, "-at-least-one-member-of"):
+		bag, err := m.mapOperand(args[0])
+		if err != nil || bag.path == "" {
+			return "", fmt.Errorf("%s requires an attribute bag", function)
+		}
+		values, err := m.mapBagLiteral(args[1])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s in %s", bag.path, values), nil
+	}
+
+	// bag-size(attr) > 0 tests that an attribute is present
+	if sizeFunction := shortName(args[0].FunctionId); args[0].XMLName.Local == elemApply && strings.HasSuffix(sizeFunction, "-bag-size") {
+		sizeArgs := arguments(args[0])
+		if len(sizeArgs) == 1 && sizeArgs[0].XMLName.Local == elemDesignator && strings.TrimSpace(args[1].Value) == "0" {
+			path := m.attributePath(sizeArgs[0].Category, sizeArgs[0].AttributeId)
+			switch function {
+			case "integer-greater-than":
+				return path + " pr", nil
+			case "integer-equal":
+				return "not(" + path + " pr)", nil
+			}
+		}
+		return "", fmt.Errorf("%s of %s is not supported", function, sizeFunction)
+	}
+
+	first, err := m.mapOperand(args[0])
+	if err != nil {
+		return "", err
+	}
+	second, err := m.mapOperand(args[1])
+	if err != nil {
+		return "", err
+	}
+	return mapComparison(function, first, second)
+}
+
+// mapOperand maps an attribute designator (optionally wrapped in a one-and-only function) or a literal value
+func (m *XacmlMapper) mapOperand(expression Expression) (operand, error) {
+	switch expression.XMLName.Local {
+	case elemValue:
+		return operand{literal: formatLiteral(expression.DataType, expression.Value)}, nil
+	case elemDesignator:
+		return operand{path: m.attributePath(expression.Category, expression.AttributeId)}, nil
+	case elemApply:
+		args := arguments(expression)
+		if strings.HasSuffix(expression.FunctionId, "-one-and-only") && len(args) == 1 {
+			return m.mapOperand(args[0])
+		}
+		if strings.HasSuffix(expression.FunctionId, "-bag") {
+			values, err := m.mapBagLiteral(expression)
+			return operand{literal: values}, err
+		}
+		return operand{}, fmt.Errorf("XACML function %s is not supported as an argument", shortName(expression.FunctionId))
+	}
+	return operand{}, fmt.Errorf("XACML %s expressions are not supported", expression.XMLName.Local)
+}
+
+// mapBagLiteral maps a bag function of literal values (e.g. string-bag) into an IDQL array
+func (m *XacmlMapper) mapBagLiteral(expression Expression) (string, error) {
+	if expression.XMLName.Local != elemApply || !strings.HasSuffix(expression.FunctionId, "-bag") {
+		return "", errors.New("expected a bag of values")
+	}
+	args := arguments(expression)
+	values := make([]string, len(args))
+	for i, arg := range args {
+		if arg.XMLName.Local != elemValue {
+			return "", errors.New("bag values must be literals")
+		}
+		values[i] = formatLiteral(arg.DataType, arg.Value)
+	}
+	return "[" + strings.Join(values, ",") + "]", nil
+}
@@ -0,0 +1,234 @@
+package xacml
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func TestMapXacmlToIdql(t *testing.T) {
+	set, err := ParseFile(filepath.Join(getExampleDir(), "policyExamples", "example_xacml.xml"))
+	assert.NoError(t, err)
+
+	mapper := NewXacmlMapper(map[string]string{})
+	policies, issues := mapper.MapPolicySet(set)
+	assert.Len(t, policies, 2)
+
+	edit := policies[0]
+	assert.Equal(t, "EditorsCanEdit", *edit.Meta.PolicyId)
+	assert.Equal(t, "Editors may read and edit documents in their department", edit.Meta.Description)
+	assert.Equal(t, "urn:example:policy:documents", edit.Meta.SourceData[sourcePolicyId])
+	assert.Equal(t, hexapolicy.SubjectInfo{"role:editor"}, edit.Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"read", "edit"}, edit.Actions)
+	assert.Equal(t, "Document:", edit.Object.String())
+	assert.Equal(t, "resource.department eq subject.department", edit.Condition.Rule)
+	assert.Equal(t, conditions.AAllow, edit.Condition.Action)
+
+	deny := policies[1]
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyUser}, deny.Subjects)
+	assert.Equal(t, "req.time gt 2030-01-01T00:00:00Z or subject.status co \"suspended\"", deny.Condition.Rule)
+	assert.Equal(t, conditions.ADeny, deny.Condition.Action)
+
+	assert.Len(t, issues, 1)
+	assert.Equal(t, "RegexRule", issues[0].RuleId)
+	assert.Contains(t, issues[0].String(), "string-regexp-match is not supported")
+}
+
+func TestMapXacml_Combining(t *testing.T) {
+	policyXml := `<Policy xmlns="urn:oasis:names:tc:xacml:3.0:core:schema:wd-17" PolicyId="p1" Version="1.0"
+    RuleCombiningAlgId="urn:oasis:names:tc:xacml:3.0:rule-combining-algorithm:%s">
+  <Target/>
+  <Rule RuleId="permit" Effect="Permit"/>
+  <Rule RuleId="deny" Effect="Deny">
+    <Condition>
+      <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:integer-greater-than">
+        <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:integer-one-and-only">
+          <AttributeDesignator Category="urn:oasis:names:tc:xacml:1.0:subject-category:access-subject"
+              AttributeId="risk" DataType="http://www.w3.org/2001/XMLSchema#integer" MustBePresent="false"/>
+        </Apply>
+        <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#integer">5</AttributeValue>
+      </Apply>
+    </Condition>
+  </Rule>
+  <ObligationExpressions/>
+</Policy>`
+	mapper := NewXacmlMapper(map[string]string{})
+
+	tests := []struct {
+		algorithm string
+		policies  int
+		issues    []string
+	}{
+		{"deny-overrides", 2, []string{"obligations and advice are ignored"}},
+		{"permit-overrides", 2, []string{"obligations and advice are ignored", "mapped using deny-overrides"}},
+		{"first-applicable", 2, []string{"obligations and advice are ignored", "mapped using deny-overrides"}},
+		{"deny-unless-permit", 1, []string{"obligations and advice are ignored", "deny rules are ignored", "rule skipped"}},
+		{"permit-unless-deny", 2, []string{"obligations and advice are ignored", "default permit"}},
+	}
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			policies, issues, err := mapper.MapXacmlBytes([]byte(strings.Replace(policyXml, "%s", test.algorithm, 1)))
+			assert.NoError(t, err)
+			assert.Len(t, policies.Policies, test.policies)
+			assert.Len(t, issues, len(test.issues))
+			for i, issue := range issues {
+				assert.Contains(t, issue.Message, test.issues[i])
+			}
+			if test.policies == 2 {
+				assert.Equal(t, "subject.risk gt 5", policies.Policies[1].Condition.Rule)
+			}
+		})
+	}
+}
+
+func TestMapXacml_TargetConditions(t *testing.T) {
+	policyXml := `<Policy PolicyId="p1" RuleCombiningAlgId="urn:oasis:names:tc:xacml:3.0:rule-combining-algorithm:deny-overrides">
+  <Target>
+    <AnyOf>
+      <AllOf>
+        <Match MatchId="urn:oasis:names:tc:xacml:1.0:function:integer-greater-than">
+          <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#integer">18</AttributeValue>
+          <AttributeDesignator Category="urn:oasis:names:tc:xacml:1.0:subject-category:access-subject" AttributeId="age" DataType="http://www.w3.org/2001/XMLSchema#integer"/>
+        </Match>
+        <Match MatchId="urn:oasis:names:tc:xacml:1.0:function:string-equal">
+          <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#string">alice</AttributeValue>
+          <AttributeDesignator Category="urn:oasis:names:tc:xacml:1.0:subject-category:access-subject" AttributeId="urn:oasis:names:tc:xacml:1.0:subject:subject-id" DataType="http://www.w3.org/2001/XMLSchema#string"/>
+        </Match>
+      </AllOf>
+      <AllOf>
+        <Match MatchId="urn:oasis:names:tc:xacml:1.0:function:boolean-equal">
+          <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#boolean">true</AttributeValue>
+          <AttributeDesignator Category="urn:oasis:names:tc:xacml:1.0:subject-category:access-subject" AttributeId="admin" DataType="http://www.w3.org/2001/XMLSchema#boolean"/>
+        </Match>
+      </AllOf>
+    </AnyOf>
+  </Target>
+  <Rule RuleId="r1" Effect="Permit">
+    <Condition>
+      <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:and">
+        <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:integer-greater-than">
+          <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:string-bag-size">
+            <AttributeDesignator Category="urn:oasis:names:tc:xacml:1.0:subject-category:access-subject" AttributeId="urn:oasis:names:tc:xacml:1.0:subject:subject-id" DataType="http://www.w3.org/2001/XMLSchema#string"/>
+          </Apply>
+          <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#integer">0</AttributeValue>
+        </Apply>
+        <Apply FunctionId="urn:oasis:names:tc:xacml:3.0:function:any-of">
+          <Function FunctionId="urn:oasis:names:tc:xacml:3.0:function:string-starts-with"/>
+          <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#string">/docs</AttributeValue>
+          <AttributeDesignator Category="urn:oasis:names:tc:xacml:3.0:attribute-category:resource" AttributeId="path" DataType="http://www.w3.org/2001/XMLSchema#string"/>
+        </Apply>
+        <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:string-at-least-one-member-of">
+          <AttributeDesignator Category="urn:oasis:names:tc:xacml:3.0:attribute-category:resource" AttributeId="classification" DataType="http://www.w3.org/2001/XMLSchema#string"/>
+          <Apply FunctionId="urn:oasis:names:tc:xacml:1.0:function:string-bag">
+            <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#string">public</AttributeValue>
+            <AttributeValue DataType="http://www.w3.org/2001/XMLSchema#string">internal</AttributeValue>
+          </Apply>
+        </Apply>
+      </Apply>
+    </Condition>
+  </Rule>
+</Policy>`
+	mapper := NewXacmlMapper(map[string]string{"subject.level": "urn:example:level"})
+	policies, issues, err := mapper.MapXacmlBytes([]byte(policyXml))
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	assert.Len(t, policies.Policies, 1)
+	policy := policies.Policies[0]
+	assert.Equal(t, hexapolicy.SubjectInfo{hexapolicy.SubjectAnyAuth}, policy.Subjects)
+	expected := conditions.ConditionInfo{
+		Rule:   "((subject.age lt 18 and subject.id eq \"alice\") or subject.admin eq true) and resource.path sw \"/docs\" and resource.classification in [\"public\",\"internal\"]",
+		Action: conditions.AAllow,
+	}
+	assert.True(t, expected.Equals(policy.Condition), "got %s", policy.Condition.Rule)
+}
+
+func TestParseXacml_Errors(t *testing.T) {
+	_, err := ParseXacml([]byte("<Policy"))
+	assert.Error(t, err)
+	_, err = ParseXacml([]byte("<Request/>"))
+	assert.Error(t, err)
+	_, err = ParseFile(filepath.Join(getExampleDir(), "doesNotExist.xml"))
+	assert.Error(t, err)
+}
+
+func TestRoundTrip(t *testing.T) {
+	policies, err := hexapolicysupport.ParsePolicyFile(filepath.Join(getExampleDir(), "authZen", "data.json"))
+	assert.NoError(t, err)
+
+	mapper := NewXacmlMapper(map[string]string{})
+	xmlBytes, err := mapper.MapPoliciesToXacmlBytes(policies)
+	assert.NoError(t, err)
+	assert.Contains(t, string(xmlBytes), `<PolicySet xmlns="urn:oasis:names:tc:xacml:3.0:core:schema:wd-17"`)
+
+	mapped, issues, err := mapper.MapXacmlBytes(xmlBytes)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	assert.Len(t, mapped.Policies, len(policies))
+	for i, policy := range policies {
+		result := mapped.Policies[i]
+		assert.Equal(t, *policy.Meta.PolicyId, *result.Meta.PolicyId)
+		assert.Equal(t, policy.Meta.Description, result.Meta.Description)
+		assert.True(t, policy.Subjects.Equals(result.Subjects), "subjects %s: %v", *policy.Meta.PolicyId, result.Subjects)
+		assert.True(t, policy.ActionsEqual(result.Actions), "actions %s", *policy.Meta.PolicyId)
+		assert.Equal(t, policy.Object, result.Object)
+		if policy.Condition == nil {
+			assert.Nil(t, result.Condition)
+			continue
+		}
+		assert.True(t, policy.Condition.Equals(result.Condition), "expected %s, got %s", policy.Condition.Rule, result.Condition.Rule)
+	}
+}
+
+func TestMapPoliciesToXacml_Conditions(t *testing.T) {
+	mapper := NewXacmlMapper(map[string]string{})
+	for _, rule := range []string{
+		"subject.level ge 5 and subject.level lt 10",
+		"subject.name ne \"bob\"",
+		"subject.email ew \"@example.com\" or subject.email sw \"admin\"",
+		"not(subject.active eq true or subject.level gt 3)",
+		"subject.score gt 1.5",
+		"resource.tags co \"public\"",
+		"subject.dept in [\"sales\",\"marketing\"]",
+		"subject.email pr",
+	} {
+		t.Run(rule, func(t *testing.T) {
+			policy := hexapolicy.PolicyInfo{
+				Subjects:  []string{"User:alice"},
+				Condition: &conditions.ConditionInfo{Rule: rule, Action: conditions.ADeny},
+			}
+			xmlBytes, err := mapper.MapPoliciesToXacmlBytes([]hexapolicy.PolicyInfo{policy})
+			assert.NoError(t, err)
+			mapped, issues, err := mapper.MapXacmlBytes(xmlBytes)
+			assert.NoError(t, err)
+			assert.Empty(t, issues)
+			assert.Len(t, mapped.Policies, 1)
+			assert.Equal(t, "policy-0", *mapped.Policies[0].Meta.PolicyId)
+			assert.Equal(t, hexapolicy.SubjectInfo{"User:alice"}, mapped.Policies[0].Subjects)
+			assert.True(t, policy.Condition.Equals(mapped.Policies[0].Condition), "got %s", mapped.Policies[0].Condition.Rule)
+		})
+	}
+}
+
+func TestMapPoliciesToXacml_Errors(t *testing.T) {
+	mapper := NewXacmlMapper(map[string]string{})
+	for _, policy := range []hexapolicy.PolicyInfo{
+		{Subjects: []string{"net:10.0.0.0/8"}},
+		{Condition: &conditions.ConditionInfo{Rule: "subject in Group:admins", Action: conditions.AAllow}},
+		{Condition: &conditions.ConditionInfo{Rule: "emails[type eq \"work\"] pr", Action: conditions.AAllow}},
+		{Condition: &conditions.ConditionInfo{Rule: "subject.level sw 5", Action: conditions.AAllow}},
+	} {
+		_, err := mapper.MapPoliciesToXacml([]hexapolicy.PolicyInfo{policy})
+		assert.Error(t, err)
+	}
+}
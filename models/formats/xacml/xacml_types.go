@@ -0,0 +1,161 @@
+package xacml
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+/*
+ XML bindings for the subset of the XACML 3.0 core schema used by the mapper - See:
+ https://docs.oasis-open.org/xacml/3.0/xacml-3.0-core-spec-os-en.html
+*/
+
+const (
+	Namespace = "urn:oasis:names:tc:xacml:3.0:core:schema:wd-17"
+
+	CategorySubject     = "urn:oasis:names:tc:xacml:1.0:subject-category:access-subject"
+	CategoryResource    = "urn:oasis:names:tc:xacml:3.0:attribute-category:resource"
+	CategoryAction      = "urn:oasis:names:tc:xacml:3.0:attribute-category:action"
+	CategoryEnvironment = "urn:oasis:names:tc:xacml:3.0:attribute-category:environment"
+
+	AttrSubjectId   = "urn:oasis:names:tc:xacml:1.0:subject:subject-id"
+	AttrRole        = "urn:oasis:names:tc:xacml:2.0:subject:role"
+	AttrResourceId  = "urn:oasis:names:tc:xacml:1.0:resource:resource-id"
+	AttrActionId    = "urn:oasis:names:tc:xacml:1.0:action:action-id"
+	AttrCurrentTime = "urn:oasis:names:tc:xacml:1.0:environment:current-dateTime"
+
+	TypeString   = "http://www.w3.org/2001/XMLSchema#string"
+	TypeInteger  = "http://www.w3.org/2001/XMLSchema#integer"
+	TypeDouble   = "http://www.w3.org/2001/XMLSchema#double"
+	TypeBoolean  = "http://www.w3.org/2001/XMLSchema#boolean"
+	TypeDateTime = "http://www.w3.org/2001/XMLSchema#dateTime"
+
+	FunctionPrefix1 = "urn:oasis:names:tc:xacml:1.0:function:"
+	FunctionPrefix3 = "urn:oasis:names:tc:xacml:3.0:function:"
+
+	AlgDenyOverrides = "urn:oasis:names:tc:xacml:3.0:rule-combining-algorithm:deny-overrides"
+	AlgPolicyDeny    = "urn:oasis:names:tc:xacml:3.0:policy-combining-algorithm:deny-overrides"
+
+	EffectPermit = "Permit"
+	EffectDeny   = "Deny"
+
+	elemApply      = "Apply"
+	elemValue      = "AttributeValue"
+	elemDesignator = "AttributeDesignator"
+	elemFunction   = "Function"
+)
+
+type PolicySet struct {
+	XMLName               xml.Name    `xml:"PolicySet"`
+	Xmlns                 string      `xml:"xmlns,attr,omitempty"`
+	PolicySetId           string      `xml:"PolicySetId,attr"`
+	Version               string      `xml:"Version,attr"`
+	PolicyCombiningAlgId  string      `xml:"PolicyCombiningAlgId,attr"`
+	Description           string      `xml:"Description,omitempty"`
+	Target                Target      `xml:"Target"`
+	PolicySets            []PolicySet `xml:"PolicySet"`
+	Policies              []Policy    `xml:"Policy"`
+	PolicyIdReferences    []string    `xml:"PolicyIdReference"`
+	PolicySetIdReferences []string    `xml:"PolicySetIdReference"`
+}
+
+type Policy struct {
+	XMLName               xml.Name             `xml:"Policy"`
+	Xmlns                 string               `xml:"xmlns,attr,omitempty"`
+	PolicyId              string               `xml:"PolicyId,attr"`
+	Version               string               `xml:"Version,attr"`
+	RuleCombiningAlgId    string               `xml:"RuleCombiningAlgId,attr"`
+	Description           string               `xml:"Description,omitempty"`
+	Target                Target               `xml:"Target"`
+	VariableDefinitions   []UnsupportedElement `xml:"VariableDefinition"`
+	Rules                 []Rule               `xml:"Rule"`
+	ObligationExpressions *UnsupportedElement  `xml:"ObligationExpressions"`
+	AdviceExpressions     *UnsupportedElement  `xml:"AdviceExpressions"`
+}
+
+type Rule struct {
+	RuleId                string              `xml:"RuleId,attr"`
+	Effect                string              `xml:"Effect,attr"`
+	Description           string              `xml:"Description,omitempty"`
+	Target                *Target             `xml:"Target"`
+	Condition             *Condition          `xml:"Condition"`
+	ObligationExpressions *UnsupportedElement `xml:"ObligationExpressions"`
+	AdviceExpressions     *UnsupportedElement `xml:"AdviceExpressions"`
+}
+
+// UnsupportedElement is used to detect elements that have no IDQL equivalent
+type UnsupportedElement struct {
+	Inner string `xml:",innerxml"`
+}
+
+type Target struct {
+	AnyOf []AnyOf `xml:"AnyOf"`
+}
+
+type AnyOf struct {
+	AllOf []AllOf `xml:"AllOf"`
+}
+
+type AllOf struct {
+	Match []Match `xml:"Match"`
+}
+
+type Match struct {
+	MatchId             string               `xml:"MatchId,attr"`
+	AttributeValue      AttributeValue       `xml:"AttributeValue"`
+	AttributeDesignator *AttributeDesignator `xml:"AttributeDesignator"`
+	AttributeSelector   *UnsupportedElement  `xml:"AttributeSelector"`
+}
+
+type AttributeValue struct {
+	DataType string `xml:"DataType,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type AttributeDesignator struct {
+	Category      string `xml:"Category,attr"`
+	AttributeId   string `xml:"AttributeId,attr"`
+	DataType      string `xml:"DataType,attr"`
+	MustBePresent bool   `xml:"MustBePresent,attr"`
+}
+
+type Condition struct {
+	Expression Expression `xml:",any"`
+}
+
+// Expression is a generic XACML expression element (Apply, AttributeValue, AttributeDesignator, Function, ...). The
+// element name is held in XMLName and Apply arguments are held in document order.
+type Expression struct {
+	XMLName       xml.Name
+	FunctionId    string       `xml:"FunctionId,attr,omitempty"`
+	Category      string       `xml:"Category,attr,omitempty"`
+	AttributeId   string       `xml:"AttributeId,attr,omitempty"`
+	DataType      string       `xml:"DataType,attr,omitempty"`
+	MustBePresent string       `xml:"MustBePresent,attr,omitempty"`
+	Value         string       `xml:",chardata"`
+	Args          []Expression `xml:",any"`
+}
+
+// shortName returns the final segment of an XACML identifier (e.g. string-equal for
+// urn:oasis:names:tc:xacml:1.0:function:string-equal)
+func shortName(id string) string {
+	return id[strings.LastIndexAny(id, ":#/")+1:]
+}
+
+func newApply(functionId string, args ...Expression) Expression {
+	return Expression{XMLName: xml.Name{Local: elemApply}, FunctionId: functionId, Args: args}
+}
+
+func newValue(dataType string, value string) Expression {
+	return Expression{XMLName: xml.Name{Local: elemValue}, DataType: dataType, Value: value}
+}
+
+func newDesignator(designator AttributeDesignator) Expression {
+	return Expression{
+		XMLName:       xml.Name{Local: elemDesignator},
+		Category:      designator.Category,
+		AttributeId:   designator.AttributeId,
+		DataType:      designator.DataType,
+		MustBePresent: "false",
+	}
+}
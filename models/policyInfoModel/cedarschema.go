@@ -0,0 +1,742 @@
+package policyInfoModel
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+This file implements the Cedar human-readable schema syntax (see https://docs.cedarpolicy.com/schema/human-readable-schema-grammar.html).
+
+	Schema    := {Namespace}
+	Namespace := ('namespace' Path '{' {Decl} '}') | Decl
+	Decl      := Entity | Action | TypeDecl
+	Entity    := 'entity' Idents ['in' EntOrTyps] [['='] RecType] ';'
+	Action    := 'action' Names ['in' RefOrRefs] [AppliesTo] ';'
+	TypeDecl  := 'type' IDENT '=' Type ';'
+	AppliesTo := 'appliesTo' '{' AppDecls '}'
+	AppDecls  := ('principal' | 'resource') ':' EntOrTyps [',' | ',' AppDecls] | 'context' ':' (Path | RecType) [',' | ',' AppDecls]
+	RecType   := '{' [AttrDecls] '}'
+	AttrDecls := Name ['?'] ':' Type [',' | ',' AttrDecls]
+	Type      := Path | 'Set' '<' Type '>' | RecType
+
+Annotations (e.g. @doc("...")) are accepted and ignored. Declarations outside a namespace are returned under the "" namespace.
+*/
+
+const (
+	TypeBoolean string = "Boolean"
+	TypeEntity  string = "Entity"
+
+	cedarPathSeparator = "::"
+)
+
+var (
+	cedarIdentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	cedarExtensionTypes = map[string]bool{
+		"ipaddr":   true,
+		"decimal":  true,
+		"datetime": true,
+		"duration": true,
+	}
+)
+
+// IsCedarSchema returns true when the schema is not in JSON form (i.e. is a Cedar human-readable schema)
+func IsCedarSchema(schemaBytes []byte) bool {
+	trimmed := strings.TrimLeftFunc(string(schemaBytes), unicode.IsSpace)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "{")
+}
+
+// ParseCedarSchema parses a Cedar human-readable schema (.cedarschema) into Namespaces
+func ParseCedarSchema(schemaBytes []byte) (*Namespaces, error) {
+	tokens, err := scanCedarSchema(string(schemaBytes))
+	if err != nil {
+		return nil, err
+	}
+	p := &cedarSchemaParser{tokens: tokens}
+	namespaces := Namespaces{}
+	for !p.atEnd() {
+		if err = p.skipAnnotations(); err != nil {
+			return nil, err
+		}
+		if p.peekIs("namespace") {
+			p.next()
+			name, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("{"); err != nil {
+				return nil, err
+			}
+			schema := namespaces[name]
+			for !p.peekIs("}") {
+				if p.atEnd() {
+					return nil, fmt.Errorf("cedar schema: missing '}' for namespace %s", name)
+				}
+				if err = p.parseDecl(&schema); err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+			namespaces[name] = schema
+			continue
+		}
+		schema := namespaces[""]
+		if err = p.parseDecl(&schema); err != nil {
+			return nil, err
+		}
+		namespaces[""] = schema
+	}
+
+	for name, schema := range namespaces {
+		schema.resolveTypes(name)
+		namespaces[name] = schema
+	}
+	return &namespaces, nil
+}
+
+type cedarTokenKind int
+
+const (
+	tokenIdent cedarTokenKind = iota
+	tokenString
+	tokenSymbol
+)
+
+type cedarToken struct {
+	kind cedarTokenKind
+	text string
+	line int
+}
+
+func (t cedarToken) String() string {
+	if t.kind == tokenString {
+		return strconv.Quote(t.text)
+	}
+	return t.text
+}
+
+func scanCedarSchema(source string) ([]cedarToken, error) {
+	var tokens []cedarToken
+	runes := []rune(source)
+	line := 1
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\n':
+			line++
+		case unicode.IsSpace(c):
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			line++
+		case c == '"':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' {
+					i++
+				}
+				if i < len(runes) && runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("cedar schema line %d: unterminated string", line)
+			}
+			value, err := strconv.Unquote(string(runes[start : i+1]))
+			if err != nil {
+				return nil, fmt.Errorf("cedar schema line %d: invalid string %s", line, string(runes[start:i+1]))
+			}
+			tokens = append(tokens, cedarToken{kind: tokenString, text: value, line: line})
+		case c == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, cedarToken{kind: tokenSymbol, text: cedarPathSeparator, line: line})
+			i++
+		case strings.ContainsRune("{}[]<>(),;:=?@", c):
+			tokens = append(tokens, cedarToken{kind: tokenSymbol, text: string(c), line: line})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i+1 < len(runes) && (runes[i+1] == '_' || unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			tokens = append(tokens, cedarToken{kind: tokenIdent, text: string(runes[start : i+1]), line: line})
+		default:
+			return nil, fmt.Errorf("cedar schema line %d: unexpected character '%c'", line, c)
+		}
+	}
+	return tokens, nil
+}
+
+type cedarSchemaParser struct {
+	tokens []cedarToken
+	pos    int
+}
+
+func (p *cedarSchemaParser) atEnd() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *cedarSchemaParser) peekIs(text string) bool {
+	return !p.atEnd() && p.tokens[p.pos].kind != tokenString && p.tokens[p.pos].text == text
+}
+
+func (p *cedarSchemaParser) next() cedarToken {
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+func (p *cedarSchemaParser) errorf(format string, args ...interface{}) error {
+	if p.atEnd() {
+		return errors.New("cedar schema: unexpected end of schema, " + fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("cedar schema line %d: %s (found %s)", p.tokens[p.pos].line, fmt.Sprintf(format, args...), p.tokens[p.pos].String())
+}
+
+func (p *cedarSchemaParser) expect(symbol string) error {
+	if !p.peekIs(symbol) {
+		return p.errorf("expected '%s'", symbol)
+	}
+	p.next()
+	return nil
+}
+
+func (p *cedarSchemaParser) parseIdent() (string, error) {
+	if p.atEnd() || p.tokens[p.pos].kind != tokenIdent {
+		return "", p.errorf("expected identifier")
+	}
+	return p.next().text, nil
+}
+
+// parseName parses an IDENT or STR
+func (p *cedarSchemaParser) parseName() (string, error) {
+	if !p.atEnd() && p.tokens[p.pos].kind == tokenString {
+		return p.next().text, nil
+	}
+	return p.parseIdent()
+}
+
+func (p *cedarSchemaParser) parsePath() (string, error) {
+	ident, err := p.parseIdent()
+	if err != nil {
+		return "", err
+	}
+	path := ident
+	for p.peekIs(cedarPathSeparator) && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == tokenIdent {
+		p.next()
+		path = path + cedarPathSeparator + p.next().text
+	}
+	return path, nil
+}
+
+func (p *cedarSchemaParser) skipAnnotations() error {
+	for p.peekIs("@") {
+		p.next()
+		if _, err := p.parseIdent(); err != nil {
+			return err
+		}
+		if p.peekIs("(") {
+			p.next()
+			if p.atEnd() || p.tokens[p.pos].kind != tokenString {
+				return p.errorf("expected annotation value")
+			}
+			p.next()
+			if err := p.expect(")"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *cedarSchemaParser) parseDecl(schema *SchemaType) error {
+	if err := p.skipAnnotations(); err != nil {
+		return err
+	}
+	switch {
+	case p.peekIs("entity"):
+		p.next()
+		return p.parseEntity(schema)
+	case p.peekIs("action"):
+		p.next()
+		return p.parseAction(schema)
+	case p.peekIs("type"):
+		p.next()
+		return p.parseTypeDecl(schema)
+	}
+	return p.errorf("expected entity, action or type declaration")
+}
+
+// parseList parses either a single item or a bracketed list of items
+func (p *cedarSchemaParser) parseList(item func() (string, error)) ([]string, error) {
+	if !p.peekIs("[") {
+		value, err := item()
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+	p.next()
+	values := []string{}
+	for !p.peekIs("]") {
+		value, err := item()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if !p.peekIs(",") {
+			break
+		}
+		p.next()
+	}
+	return values, p.expect("]")
+}
+
+func (p *cedarSchemaParser) parseEntity(schema *SchemaType) error {
+	names, err := p.parseNames(p.parseIdent)
+	if err != nil {
+		return err
+	}
+	entity := EntityType{Shape: ShapeTypes{Type: TypeRecord, Attributes: map[string]AttrType{}}}
+	if p.peekIs("in") {
+		p.next()
+		if entity.MemberOfTypes, err = p.parseList(p.parsePath); err != nil {
+			return err
+		}
+	}
+	if p.peekIs("enum") || p.peekIs("tags") {
+		return p.errorf("entity %s are not supported", p.tokens[p.pos].text)
+	}
+	if p.peekIs("=") {
+		p.next()
+	}
+	if p.peekIs("{") {
+		if entity.Shape.Attributes, err = p.parseRecord(); err != nil {
+			return err
+		}
+	}
+	if err = p.expect(";"); err != nil {
+		return err
+	}
+	if schema.EntityTypes == nil {
+		schema.EntityTypes = map[string]EntityType{}
+	}
+	for _, name := range names {
+		schema.EntityTypes[name] = entity
+	}
+	return nil
+}
+
+func (p *cedarSchemaParser) parseNames(item func() (string, error)) ([]string, error) {
+	var names []string
+	for {
+		name, err := item()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if !p.peekIs(",") {
+			return names, nil
+		}
+		p.next()
+	}
+}
+
+// parseActionRef parses an action reference (e.g. "read" or Action::"read")
+func (p *cedarSchemaParser) parseActionRef() (string, error) {
+	if !p.atEnd() && p.tokens[p.pos].kind == tokenString {
+		return p.next().text, nil
+	}
+	path, err := p.parseIdent()
+	if err != nil {
+		return "", err
+	}
+	for p.peekIs(cedarPathSeparator) {
+		p.next()
+		if !p.atEnd() && p.tokens[p.pos].kind == tokenString {
+			id := p.next().text
+			if path == "Action" {
+				return id, nil
+			}
+			return path + cedarPathSeparator + strconv.Quote(id), nil
+		}
+		ident, err := p.parseIdent()
+		if err != nil {
+			return "", err
+		}
+		path = path + cedarPathSeparator + ident
+	}
+	return path, nil
+}
+
+func (p *cedarSchemaParser) parseAction(schema *SchemaType) error {
+	names, err := p.parseNames(p.parseName)
+	if err != nil {
+		return err
+	}
+	action := ActionType{}
+	if p.peekIs("in") {
+		p.next()
+		if action.MemberOf, err = p.parseList(p.parseActionRef); err != nil {
+			return err
+		}
+	}
+	if p.peekIs("appliesTo") {
+		p.next()
+		if action.AppliesTo, err = p.parseAppliesTo(); err != nil {
+			return err
+		}
+	}
+	if err = p.expect(";"); err != nil {
+		return err
+	}
+	if schema.Actions == nil {
+		schema.Actions = map[string]ActionType{}
+	}
+	for _, name := range names {
+		schema.Actions[name] = action
+	}
+	return nil
+}
+
+func (p *cedarSchemaParser) parseAppliesTo() (AppliesType, error) {
+	applies := AppliesType{}
+	if err := p.expect("{"); err != nil {
+		return applies, err
+	}
+	for !p.peekIs("}") {
+		key, err := p.parseIdent()
+		if err != nil {
+			return applies, err
+		}
+		if err = p.expect(":"); err != nil {
+			return applies, err
+		}
+		switch key {
+		case "principal":
+			types, err := p.parseList(p.parsePath)
+			if err != nil {
+				return applies, err
+			}
+			principals := PrincipalTypes(types)
+			applies.PrincipalTypes = &principals
+		case "resource":
+			types, err := p.parseList(p.parsePath)
+			if err != nil {
+				return applies, err
+			}
+			resources := ResourceTypes(types)
+			applies.ResourceTypes = &resources
+		case "context":
+			context := ContextType{Type: TypeRecord}
+			if p.peekIs("{") {
+				if context.Attributes, err = p.parseRecord(); err != nil {
+					return applies, err
+				}
+			} else if context.Type, err = p.parsePath(); err != nil {
+				return applies, err
+			}
+			applies.Context = &context
+		default:
+			p.pos--
+			return applies, p.errorf("expected principal, resource or context")
+		}
+		if !p.peekIs(",") {
+			break
+		}
+		p.next()
+	}
+	return applies, p.expect("}")
+}
+
+func (p *cedarSchemaParser) parseTypeDecl(schema *SchemaType) error {
+	name, err := p.parseIdent()
+	if err != nil {
+		return err
+	}
+	if err = p.expect("="); err != nil {
+		return err
+	}
+	attrType, err := p.parseType()
+	if err != nil {
+		return err
+	}
+	if attrType.Type == TypeSet {
+		return fmt.Errorf("cedar schema: common type %s: Set common types are not supported", name)
+	}
+	if err = p.expect(";"); err != nil {
+		return err
+	}
+	if schema.CommonTypes == nil {
+		schema.CommonTypes = map[string]ContextType{}
+	}
+	schema.CommonTypes[name] = ContextType{Type: attrType.Type, Attributes: attrType.Attributes}
+	return nil
+}
+
+func (p *cedarSchemaParser) parseRecord() (map[string]AttrType, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	attributes := map[string]AttrType{}
+	for !p.peekIs("}") {
+		if err := p.skipAnnotations(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		required := true
+		if p.peekIs("?") {
+			p.next()
+			required = false
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		attrType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		attrType.Required = required
+		attributes[name] = attrType
+		if !p.peekIs(",") {
+			break
+		}
+		p.next()
+	}
+	return attributes, p.expect("}")
+}
+
+func (p *cedarSchemaParser) parseType() (AttrType, error) {
+	if p.peekIs("{") {
+		attributes, err := p.parseRecord()
+		return AttrType{Type: TypeRecord, RecordType: RecordType{Attributes: attributes}}, err
+	}
+	path, err := p.parsePath()
+	if err != nil {
+		return AttrType{}, err
+	}
+	name := strings.TrimPrefix(path, "__cedar"+cedarPathSeparator)
+	switch {
+	case name == "Set" && p.peekIs("<"):
+		p.next()
+		element, err := p.parseType()
+		if err != nil {
+			return AttrType{}, err
+		}
+		element.Required = true
+		if err = p.expect(">"); err != nil {
+			return AttrType{}, err
+		}
+		return AttrType{Type: TypeSet, SetType: SetType{Element: &element}}, nil
+	case name == TypeString || name == TypeLong:
+		return AttrType{Type: name}, nil
+	case name == TypeBool || name == TypeBoolean:
+		return AttrType{Type: TypeBoolean}, nil
+	case cedarExtensionTypes[name]:
+		return AttrType{Type: TypeExtension, Name: name}, nil
+	}
+	// resolved to a common type or entity once the whole namespace is parsed
+	return AttrType{Type: path}, nil
+}
+
+// resolveTypes converts type references that are not common types into entity references
+func (s SchemaType) resolveTypes(namespace string) {
+	for name, entity := range s.EntityTypes {
+		s.resolveAttributes(namespace, entity.Shape.Attributes)
+		s.EntityTypes[name] = entity
+	}
+	for _, action := range s.Actions {
+		if action.AppliesTo.Context != nil {
+			action.AppliesTo.Context.Type = s.commonTypeName(namespace, action.AppliesTo.Context.Type)
+			s.resolveAttributes(namespace, action.AppliesTo.Context.Attributes)
+		}
+	}
+	for name, commonType := range s.CommonTypes {
+		s.resolveAttributes(namespace, commonType.Attributes)
+		s.CommonTypes[name] = commonType
+	}
+}
+
+func (s SchemaType) commonTypeName(namespace, path string) string {
+	if namespace != "" {
+		local := strings.TrimPrefix(path, namespace+cedarPathSeparator)
+		if _, ok := s.CommonTypes[local]; ok {
+			return local
+		}
+	}
+	return path
+}
+
+func (s SchemaType) resolveAttribute(namespace string, attr AttrType) AttrType {
+	switch attr.Type {
+	case TypeString, TypeLong, TypeBoolean, TypeExtension, TypeEntity:
+	case TypeRecord:
+		s.resolveAttributes(namespace, attr.Attributes)
+	case TypeSet:
+		if attr.Element != nil {
+			element := s.resolveAttribute(namespace, *attr.Element)
+			attr.Element = &element
+		}
+	default:
+		name := s.commonTypeName(namespace, attr.Type)
+		if _, ok := s.CommonTypes[name]; ok {
+			attr.Type = name
+		} else {
+			attr.Name = attr.Type
+			attr.Type = TypeEntity
+		}
+	}
+	return attr
+}
+
+func (s SchemaType) resolveAttributes(namespace string, attributes map[string]AttrType) {
+	for name, attr := range attributes {
+		attributes[name] = s.resolveAttribute(namespace, attr)
+	}
+}
+
+// CedarSchema returns the namespaces in Cedar human-readable schema form
+func (n Namespaces) CedarSchema() string {
+	var sb strings.Builder
+	for i, name := range sortedKeys(n) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		schema := n[name]
+		if name == "" {
+			schema.writeCedarSchema(&sb, "")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("namespace %s {\n", name))
+		schema.writeCedarSchema(&sb, "  ")
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+func (s SchemaType) writeCedarSchema(sb *strings.Builder, indent string) {
+	for _, name := range sortedKeys(s.CommonTypes) {
+		commonType := s.CommonTypes[name]
+		attr := AttrType{Type: commonType.Type, RecordType: RecordType{Attributes: commonType.Attributes}}
+		if commonType.Type == "" {
+			attr.Type = TypeRecord
+		}
+		sb.WriteString(fmt.Sprintf("%stype %s = %s;\n", indent, name, cedarTypeString(attr, indent)))
+	}
+	for _, name := range sortedKeys(s.EntityTypes) {
+		entity := s.EntityTypes[name]
+		sb.WriteString(indent + "entity " + name)
+		if len(entity.MemberOfTypes) > 0 {
+			sb.WriteString(" in " + cedarList(entity.MemberOfTypes, func(v string) string { return v }))
+		}
+		switch {
+		case entity.Shape.Type != "" && entity.Shape.Type != TypeRecord:
+			sb.WriteString(" = " + entity.Shape.Type)
+		case len(entity.Shape.Attributes) > 0:
+			sb.WriteString(" " + cedarRecordString(entity.Shape.Attributes, indent))
+		}
+		sb.WriteString(";\n")
+	}
+	for _, name := range sortedKeys(s.Actions) {
+		action := s.Actions[name]
+		sb.WriteString(indent + "action " + strconv.Quote(name))
+		if len(action.MemberOf) > 0 {
+			sb.WriteString(" in " + cedarList(action.MemberOf, cedarActionRef))
+		}
+		var applies []string
+		if action.AppliesTo.PrincipalTypes != nil {
+			applies = append(applies, "principal: "+cedarList(*action.AppliesTo.PrincipalTypes, func(v string) string { return v }))
+		}
+		if action.AppliesTo.ResourceTypes != nil {
+			applies = append(applies, "resource: "+cedarList(*action.AppliesTo.ResourceTypes, func(v string) string { return v }))
+		}
+		if context := action.AppliesTo.Context; context != nil {
+			if context.Type != "" && context.Type != TypeRecord {
+				applies = append(applies, "context: "+context.Type)
+			} else {
+				applies = append(applies, "context: "+cedarRecordString(context.Attributes, indent+"  "))
+			}
+		}
+		if len(applies) > 0 {
+			childIndent := indent + "  "
+			sb.WriteString(" appliesTo {\n" + childIndent)
+			sb.WriteString(strings.Join(applies, ",\n"+childIndent))
+			sb.WriteString("\n" + indent + "}")
+		}
+		sb.WriteString(";\n")
+	}
+}
+
+func cedarActionRef(ref string) string {
+	if strings.Contains(ref, cedarPathSeparator) {
+		return ref
+	}
+	return strconv.Quote(ref)
+}
+
+func cedarList(values []string, format func(string) string) string {
+	items := make([]string, len(values))
+	for i, value := range values {
+		items[i] = format(value)
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func cedarTypeString(attr AttrType, indent string) string {
+	switch attr.Type {
+	case TypeBoolean, TypeBool:
+		return "Bool"
+	case TypeRecord:
+		return cedarRecordString(attr.Attributes, indent)
+	case TypeSet:
+		if attr.Element == nil {
+			return "Set<String>"
+		}
+		return "Set<" + cedarTypeString(*attr.Element, indent) + ">"
+	case TypeExtension, TypeEntity, "EntityOrCommon":
+		return attr.Name
+	}
+	return attr.Type
+}
+
+func cedarRecordString(attributes map[string]AttrType, indent string) string {
+	if len(attributes) == 0 {
+		return "{}"
+	}
+	childIndent := indent + "  "
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, name := range sortedKeys(attributes) {
+		attr := attributes[name]
+		attrName := name
+		if !cedarIdentRegex.MatchString(name) {
+			attrName = strconv.Quote(name)
+		}
+		optional := ""
+		if !attr.Required {
+			optional = "?"
+		}
+		sb.WriteString(fmt.Sprintf("%s%s%s: %s,\n", childIndent, attrName, optional, cedarTypeString(attr, childIndent)))
+	}
+	sb.WriteString(indent + "}")
+	return sb.String()
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
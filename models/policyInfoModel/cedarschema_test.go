@@ -0,0 +1,115 @@
+package policyInfoModel
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestFile(t *testing.T, name string) []byte {
+	_, file, _, _ := runtime.Caller(0)
+	fileBytes, err := os.ReadFile(filepath.Join(file, "../test", name))
+	assert.NoError(t, err)
+	return fileBytes
+}
+
+func TestParseCedarSchema(t *testing.T) {
+	jsonNamespaces, err := ParseSchemaFile(readTestFile(t, "photoSchema.json"))
+	assert.NoError(t, err)
+
+	cedarBytes := readTestFile(t, "photoSchema.cedarschema")
+	assert.True(t, IsCedarSchema(cedarBytes))
+	namespaces, err := ParseSchemaFile(cedarBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, *jsonNamespaces, *namespaces)
+
+	app := (*namespaces)["PhotoApp"]
+	assert.Equal(t, TypeEntity, app.EntityTypes["Photo"].Shape.Attributes["account"].Type)
+	assert.Equal(t, "PersonType", app.EntityTypes["User"].Shape.Attributes["personInformation"].Type)
+	assert.Equal(t, "ipaddr", app.CommonTypes["ContextType"].Attributes["ip"].Name)
+}
+
+func TestCedarSchemaRoundTrip(t *testing.T) {
+	for _, name := range []string{"photoSchema.json", "healthSchema.json", "todoSchema.json", "cmvSchemaTest.json"} {
+		t.Run(name, func(t *testing.T) {
+			namespaces, err := ParseSchemaFile(readTestFile(t, name))
+			assert.NoError(t, err)
+
+			schema := namespaces.CedarSchema()
+			assert.True(t, IsCedarSchema([]byte(schema)))
+			parsed, err := ParseCedarSchema([]byte(schema))
+			assert.NoError(t, err)
+			assert.Equal(t, schema, parsed.CedarSchema())
+		})
+	}
+}
+
+func TestParseCedarSchemaTypes(t *testing.T) {
+	schema := `
+entity Team;
+entity User in Team = {
+  "display name": String,
+  emails?: Set<{ value: String, primary?: __cedar::Bool }>,
+  teams: Set<Team>,
+  address: { street: String, zip?: Long },
+};
+action "read";
+action "write" in ["read", Admin::"all"] appliesTo {
+  principal: User,
+  resource: [Team],
+  context: { ip: ipaddr, when: datetime },
+};
+`
+	namespaces, err := ParseCedarSchema([]byte(schema))
+	assert.NoError(t, err)
+	app := (*namespaces)[""]
+
+	user := app.EntityTypes["User"]
+	assert.Equal(t, []string{"Team"}, user.MemberOfTypes)
+	attrs := user.Shape.Attributes
+	assert.True(t, attrs["display name"].Required)
+	assert.False(t, attrs["emails"].Required)
+	assert.Equal(t, TypeSet, attrs["emails"].Type)
+	assert.Equal(t, TypeRecord, attrs["emails"].Element.Type)
+	assert.Equal(t, TypeBoolean, attrs["emails"].Element.Attributes["primary"].Type)
+	assert.Equal(t, TypeEntity, attrs["teams"].Element.Type)
+	assert.Equal(t, "Team", attrs["teams"].Element.Name)
+	assert.Equal(t, TypeLong, attrs["address"].Attributes["zip"].Type)
+	assert.Equal(t, TypeString, user.FindAttrType("address.street", app).Type)
+
+	write := app.Actions["write"]
+	assert.Equal(t, ActionRefs{"read", "Admin::\"all\""}, write.MemberOf)
+	assert.Equal(t, PrincipalTypes{"User"}, *write.AppliesTo.PrincipalTypes)
+	assert.Equal(t, TypeRecord, write.AppliesTo.Context.Type)
+	assert.Equal(t, "datetime", write.AppliesTo.Context.Attributes["when"].Name)
+
+	output := namespaces.CedarSchema()
+	assert.Contains(t, output, `"display name": String,`)
+	assert.Contains(t, output, `action "write" in ["read", Admin::"all"] appliesTo {`)
+	reparsed, err := ParseCedarSchema([]byte(output))
+	assert.NoError(t, err)
+	assert.Equal(t, *namespaces, *reparsed)
+}
+
+func TestParseCedarSchemaErrors(t *testing.T) {
+	tests := map[string]string{
+		"entity User":                              "unexpected end of schema",
+		"namespace App {\n entity User }":          "line 2: expected ';'",
+		"entity User = { name: String ;":           "expected '}'",
+		"action \"read\" appliesTo { who: User };": "expected principal, resource or context",
+		"entity Color enum [\"red\"];":             "entity enum are not supported",
+		"type Tags = Set<String>;":                 "Set common types are not supported",
+		"entity \"User\";":                         "expected identifier",
+		"entity User { name: \"String };":          "unterminated string",
+	}
+	for schema, message := range tests {
+		_, err := ParseCedarSchema([]byte(schema))
+		assert.Error(t, err, schema)
+		if err != nil {
+			assert.Contains(t, err.Error(), message, schema)
+		}
+	}
+}
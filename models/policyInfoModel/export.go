@@ -0,0 +1,342 @@
+package policyInfoModel
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	ExportCedarJson   string = "cedar"       // Cedar JSON schema (as used by the AVP PutSchema API)
+	ExportCedarSchema string = "cedarschema" // Cedar human-readable schema
+	ExportJsonSchema  string = "jsonschema"  // JSON Schema for Cedar entity documents
+	ExportOpenApi     string = "openapi"     // OpenAPI components section
+
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// ExportFormats lists the formats supported by Namespaces.Export
+var ExportFormats = []string{ExportCedarJson, ExportCedarSchema, ExportJsonSchema, ExportOpenApi}
+
+// Export returns the namespace in the requested format (see ExportFormats)
+func (n Namespaces) Export(namespace string, format string) ([]byte, error) {
+	schema, ok := n[namespace]
+	if !ok {
+		return nil, fmt.Errorf("namespace %s not found", namespace)
+	}
+	switch strings.ToLower(format) {
+	case ExportCedarJson, "avp":
+		return schema.CedarJson(namespace)
+	case ExportCedarSchema:
+		return []byte(Namespaces{namespace: schema}.CedarSchema()), nil
+	case ExportJsonSchema:
+		return schema.EntityJsonSchema(namespace)
+	case ExportOpenApi:
+		return schema.OpenApiComponents(namespace)
+	}
+	return nil, fmt.Errorf("unsupported model export format: %s (expecting one of %s)", format, strings.Join(ExportFormats, ", "))
+}
+
+// CedarJson returns the schema as a Cedar JSON schema document which may be used with the AVP PutSchema API
+func (s SchemaType) CedarJson(namespace string) ([]byte, error) {
+	entityTypes := map[string]interface{}{}
+	for name, entity := range s.EntityTypes {
+		cedarEntity := map[string]interface{}{}
+		if len(entity.MemberOfTypes) > 0 {
+			cedarEntity["memberOfTypes"] = entity.MemberOfTypes
+		}
+		shapeType := entity.Shape.Type
+		if shapeType == "" {
+			shapeType = TypeRecord
+		}
+		if shapeType == TypeRecord {
+			cedarEntity["shape"] = cedarRecord(entity.Shape.Attributes)
+		} else {
+			cedarEntity["shape"] = map[string]interface{}{"type": shapeType}
+		}
+		entityTypes[name] = cedarEntity
+	}
+
+	actions := map[string]interface{}{}
+	for name, action := range s.Actions {
+		cedarAction := map[string]interface{}{}
+		if len(action.MemberOf) > 0 {
+			memberOf := make([]map[string]string, len(action.MemberOf))
+			for i, ref := range action.MemberOf {
+				memberOf[i] = cedarActionUid(ref)
+			}
+			cedarAction["memberOf"] = memberOf
+		}
+		applies := map[string]interface{}{}
+		if action.AppliesTo.PrincipalTypes != nil {
+			applies["principalTypes"] = *action.AppliesTo.PrincipalTypes
+		}
+		if action.AppliesTo.ResourceTypes != nil {
+			applies["resourceTypes"] = *action.AppliesTo.ResourceTypes
+		}
+		if context := action.AppliesTo.Context; context != nil {
+			if context.Type != "" && context.Type != TypeRecord {
+				applies["context"] = map[string]interface{}{"type": context.Type}
+			} else {
+				applies["context"] = cedarRecord(context.Attributes)
+			}
+		}
+		if len(applies) > 0 {
+			cedarAction["appliesTo"] = applies
+		}
+		actions[name] = cedarAction
+	}
+
+	cedarSchema := map[string]interface{}{
+		"entityTypes": entityTypes,
+		"actions":     actions,
+	}
+	if len(s.CommonTypes) > 0 {
+		commonTypes := map[string]interface{}{}
+		for name, commonType := range s.CommonTypes {
+			if commonType.Type == "" || commonType.Type == TypeRecord {
+				commonTypes[name] = cedarRecord(commonType.Attributes)
+			} else {
+				commonTypes[name] = cedarAttribute(AttrType{Type: commonType.Type, Required: true})
+			}
+		}
+		cedarSchema["commonTypes"] = commonTypes
+	}
+	return json.MarshalIndent(map[string]interface{}{namespace: cedarSchema}, "", "  ")
+}
+
+// cedarActionUid converts an action reference (e.g. "read" or Admin::"all") to a Cedar JSON action uid
+func cedarActionUid(ref string) map[string]string {
+	typeName, id, found := strings.Cut(ref, cedarPathSeparator+"\"")
+	if !found {
+		return map[string]string{"id": ref}
+	}
+	return map[string]string{"type": typeName, "id": strings.TrimSuffix(id, "\"")}
+}
+
+func cedarRecord(attributes map[string]AttrType) map[string]interface{} {
+	cedarAttributes := map[string]interface{}{}
+	for name, attr := range attributes {
+		cedarAttributes[name] = cedarAttribute(attr)
+	}
+	return map[string]interface{}{
+		"type":       TypeRecord,
+		"attributes": cedarAttributes,
+	}
+}
+
+func cedarAttribute(attr AttrType) map[string]interface{} {
+	cedarAttr := map[string]interface{}{}
+	switch attr.Type {
+	case TypeBool, TypeBoolean:
+		cedarAttr["type"] = TypeBoolean
+	case TypeNumeric:
+		cedarAttr["type"] = TypeExtension
+		cedarAttr["name"] = "decimal"
+	case TypeDate:
+		cedarAttr["type"] = TypeExtension
+		cedarAttr["name"] = "datetime"
+	case TypeRecord:
+		cedarAttr = cedarRecord(attr.Attributes)
+	case TypeSet:
+		cedarAttr["type"] = TypeSet
+		element := AttrType{Type: TypeString, Required: true}
+		if attr.Element != nil {
+			element = *attr.Element
+		}
+		elementAttr := cedarAttribute(element)
+		delete(elementAttr, "required")
+		cedarAttr["element"] = elementAttr
+	default:
+		cedarAttr["type"] = attr.Type
+		if attr.Name != "" {
+			cedarAttr["name"] = attr.Name
+		}
+	}
+	// required is always set as a missing value is parsed as not required
+	cedarAttr["required"] = attr.Required
+	return cedarAttr
+}
+
+// jsonSchemaBuilder converts attribute types to JSON Schema where refPrefix locates common types (e.g. #/$defs/)
+type jsonSchemaBuilder struct {
+	namespace string
+	schema    SchemaType
+	refPrefix string
+}
+
+func (b jsonSchemaBuilder) object(attributes map[string]AttrType) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for _, name := range sortedKeys(attributes) {
+		attr := attributes[name]
+		properties[name] = b.attribute(attr)
+		if attr.Required {
+			required = append(required, name)
+		}
+	}
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+func (b jsonSchemaBuilder) attribute(attr AttrType) map[string]interface{} {
+	switch attr.Type {
+	case TypeString:
+		return map[string]interface{}{"type": "string"}
+	case TypeLong:
+		return map[string]interface{}{"type": "integer"}
+	case TypeNumeric:
+		return map[string]interface{}{"type": "number"}
+	case TypeBool, TypeBoolean:
+		return map[string]interface{}{"type": "boolean"}
+	case TypeDate:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case TypeExtension:
+		extension := map[string]interface{}{"type": "string"}
+		switch attr.Name {
+		case "ipaddr":
+			extension["format"] = "ipaddr"
+		case "datetime":
+			extension["format"] = "date-time"
+		case "decimal", "duration":
+			extension["format"] = attr.Name
+		}
+		return extension
+	case TypeRecord:
+		return b.object(attr.Attributes)
+	case TypeSet:
+		items := map[string]interface{}{}
+		if attr.Element != nil {
+			items = b.attribute(*attr.Element)
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case TypeEntity, "EntityOrCommon":
+		return b.entityRef(attr.Name)
+	}
+	if _, ok := b.schema.CommonTypes[attr.Type]; ok {
+		return map[string]interface{}{"$ref": b.refPrefix + attr.Type}
+	}
+	// an unresolved reference is assumed to be an entity type
+	return b.entityRef(attr.Type)
+}
+
+// entityRef returns a schema for an entity reference in either the {"type","id"} or {"__entity":{"type","id"}} form
+func (b jsonSchemaBuilder) entityRef(entityType string) map[string]interface{} {
+	uid := entityUidSchema(map[string]interface{}{"type": "string"})
+	if entityType != "" {
+		uid = entityUidSchema(map[string]interface{}{"const": qualifiedName(b.namespace, entityType)})
+	}
+	return map[string]interface{}{
+		"anyOf": []interface{}{
+			uid,
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"__entity": uid},
+				"required":   []string{"__entity"},
+			},
+		},
+	}
+}
+
+func entityUidSchema(typeSchema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": typeSchema,
+			"id":   map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"type", "id"},
+	}
+}
+
+func (b jsonSchemaBuilder) commonTypes() map[string]interface{} {
+	defs := map[string]interface{}{}
+	for name, commonType := range b.schema.CommonTypes {
+		if commonType.Type == "" || commonType.Type == TypeRecord {
+			defs[name] = b.object(commonType.Attributes)
+		} else {
+			defs[name] = b.attribute(AttrType{Type: commonType.Type})
+		}
+	}
+	return defs
+}
+
+func qualifiedName(namespace, name string) string {
+	if namespace == "" || strings.Contains(name, cedarPathSeparator) {
+		return name
+	}
+	return namespace + cedarPathSeparator + name
+}
+
+/*
+EntityJsonSchema returns a JSON Schema describing a Cedar entities document (an array of entities with uid, attrs
+and parents) for the entity types of the namespace.
+*/
+func (s SchemaType) EntityJsonSchema(namespace string) ([]byte, error) {
+	builder := jsonSchemaBuilder{namespace: namespace, schema: s, refPrefix: "#/$defs/"}
+	defs := builder.commonTypes()
+
+	var entities []interface{}
+	for _, name := range sortedKeys(s.EntityTypes) {
+		entity := s.EntityTypes[name]
+		attrs := builder.object(entity.Shape.Attributes)
+		if entity.Shape.Type != "" && entity.Shape.Type != TypeRecord {
+			attrs = builder.attribute(AttrType{Type: entity.Shape.Type})
+		}
+
+		parentTypes := make([]string, len(entity.MemberOfTypes))
+		for i, parent := range entity.MemberOfTypes {
+			parentTypes[i] = qualifiedName(namespace, parent)
+		}
+		parents := map[string]interface{}{"type": "array", "maxItems": 0}
+		if len(parentTypes) > 0 {
+			parents = map[string]interface{}{
+				"type":  "array",
+				"items": entityUidSchema(map[string]interface{}{"enum": parentTypes}),
+			}
+		}
+
+		defs[name] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"uid":     entityUidSchema(map[string]interface{}{"const": qualifiedName(namespace, name)}),
+				"attrs":   attrs,
+				"parents": parents,
+			},
+			"required": []string{"uid"},
+		}
+		entities = append(entities, map[string]interface{}{"$ref": builder.refPrefix + name})
+	}
+
+	jsonSchema := map[string]interface{}{
+		"$schema": jsonSchemaDraft,
+		"title":   namespace + " entities",
+		"type":    "array",
+		"items":   map[string]interface{}{"oneOf": entities},
+		"$defs":   defs,
+	}
+	return json.MarshalIndent(jsonSchema, "", "  ")
+}
+
+// OpenApiComponents returns an OpenAPI components section with a schema for each entity and common type
+func (s SchemaType) OpenApiComponents(namespace string) ([]byte, error) {
+	builder := jsonSchemaBuilder{namespace: namespace, schema: s, refPrefix: "#/components/schemas/"}
+	schemas := builder.commonTypes()
+	for name, entity := range s.EntityTypes {
+		entitySchema := builder.object(entity.Shape.Attributes)
+		if entity.Shape.Type != "" && entity.Shape.Type != TypeRecord {
+			entitySchema = builder.attribute(AttrType{Type: entity.Shape.Type})
+		}
+		entitySchema["description"] = qualifiedName(namespace, name) + " entity"
+		schemas[name] = entitySchema
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"components": map[string]interface{}{"schemas": schemas},
+	}, "", "  ")
+}
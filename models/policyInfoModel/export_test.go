@@ -0,0 +1,105 @@
+package policyInfoModel
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCedarJson(t *testing.T) {
+	namespaces, err := ParseSchemaFile(readTestFile(t, "photoSchema.json"))
+	assert.NoError(t, err)
+
+	cedarBytes, err := namespaces.Export("PhotoApp", ExportCedarJson)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(cedarBytes), "null")
+
+	exported, err := ParseSchemaFile(cedarBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, *namespaces, *exported)
+
+	_, err = namespaces.Export("BadApp", ExportCedarJson)
+	assert.ErrorContains(t, err, "namespace BadApp not found")
+	_, err = namespaces.Export("PhotoApp", "xacml")
+	assert.ErrorContains(t, err, "unsupported model export format: xacml")
+}
+
+func TestExportCedarJsonTypes(t *testing.T) {
+	schema := `
+namespace App {
+  entity Team;
+  entity User in Team { tags?: Set<String>, when: datetime };
+  action "read";
+  action "write" in ["read", Admin::"all"] appliesTo { principal: User, resource: Team, context: { mfa: Bool } };
+}`
+	namespaces, err := ParseCedarSchema([]byte(schema))
+	assert.NoError(t, err)
+	cedarBytes, err := namespaces.Export("App", "avp")
+	assert.NoError(t, err)
+
+	var cedarJson map[string]map[string]map[string]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(cedarBytes, &cedarJson))
+	write := cedarJson["App"]["actions"]["write"]
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "read"},
+		map[string]interface{}{"type": "Admin", "id": "all"},
+	}, write["memberOf"])
+	tags := cedarJson["App"]["entityTypes"]["User"]["shape"].(map[string]interface{})["attributes"].(map[string]interface{})["tags"]
+	assert.Equal(t, map[string]interface{}{
+		"type":     "Set",
+		"element":  map[string]interface{}{"type": "String"},
+		"required": false,
+	}, tags)
+
+	exported, err := ParseSchemaFile(cedarBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, namespaces.CedarSchema(), exported.CedarSchema())
+}
+
+func TestExportEntityJsonSchema(t *testing.T) {
+	namespaces, err := ParseSchemaFile(readTestFile(t, "photoSchema.json"))
+	assert.NoError(t, err)
+
+	schemaBytes, err := namespaces.Export("PhotoApp", ExportJsonSchema)
+	assert.NoError(t, err)
+
+	compiled := compileJsonSchema(t, schemaBytes)
+	entities, err := jsonschema.UnmarshalJSON(bytes.NewReader(readTestFile(t, "photoEntities.json")))
+	assert.NoError(t, err)
+	assert.NoError(t, compiled.Validate(entities))
+
+	badEntities, _ := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(`[
+      {"uid": {"type": "PhotoApp::Photo", "id": "a.jpg"}, "attrs": {"private": "no", "account": {"type": "PhotoApp::Account", "id": "x"}}, "parents": []}
+    ]`)))
+	assert.Error(t, compiled.Validate(badEntities))
+}
+
+func TestExportOpenApi(t *testing.T) {
+	namespaces, err := ParseSchemaFile(readTestFile(t, "photoSchema.json"))
+	assert.NoError(t, err)
+
+	openApiBytes, err := namespaces.Export("PhotoApp", ExportOpenApi)
+	assert.NoError(t, err)
+
+	var openApi map[string]map[string]map[string]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(openApiBytes, &openApi))
+	schemas := openApi["components"]["schemas"]
+	assert.Contains(t, schemas, "Photo")
+	assert.Contains(t, schemas, "PersonType")
+	userProperties := schemas["User"]["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/PersonType"}, userProperties["personInformation"])
+	assert.Equal(t, []interface{}{"account", "private"}, schemas["Photo"]["required"])
+}
+
+func compileJsonSchema(t *testing.T, schemaBytes []byte) *jsonschema.Schema {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaBytes))
+	assert.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	assert.NoError(t, c.AddResource("entities.json", doc))
+	compiled, err := c.Compile("entities.json")
+	assert.NoError(t, err)
+	return compiled
+}
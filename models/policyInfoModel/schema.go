@@ -4,6 +4,7 @@ package policyInfoModel
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	hexaTypes "github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
@@ -77,9 +78,39 @@ type AppliesType struct {
 	Context        *ContextType    `json:"context,omitempty"`
 }
 
+// ActionRefs holds action group references. Cedar JSON action uids ({"id": STR, "type": STR}) are read as the id, or
+// as Type::"id" when the type is not Action.
+type ActionRefs []string
+
+func (r *ActionRefs) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	refs := make(ActionRefs, len(raw))
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &refs[i]); err == nil {
+			continue
+		}
+		var uid struct {
+			Id   string `json:"id"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(item, &uid); err != nil {
+			return err
+		}
+		refs[i] = uid.Id
+		if uid.Type != "" && uid.Type != "Action" {
+			refs[i] = uid.Type + "::" + strconv.Quote(uid.Id)
+		}
+	}
+	*r = refs
+	return nil
+}
+
 // Action ::= STR ':' '{' [ '"memberOf"' ':' '[' [ STR { ',' STR } ] ']' ] ',' [ '"appliesTo"' ':' '{' [PrincipalTypes] ',' [ResourceTypes] ',' [Context] '}' ] '}'
 type ActionType struct {
-	MemberOf  []string    `json:"memberOf,omitempty"`
+	MemberOf  ActionRefs  `json:"memberOf,omitempty"`
 	AppliesTo AppliesType `json:"appliesTo,omitempty"`
 }
 
@@ -154,7 +185,11 @@ func (s SchemaType) FindAttrType(entity hexaTypes.Entity) *AttrType {
 
 type Namespaces map[string]SchemaType
 
+// ParseSchemaFile parses a Cedar JSON schema or a Cedar human-readable schema (see ParseCedarSchema)
 func ParseSchemaFile(schemaBytes []byte) (*Namespaces, error) {
+	if IsCedarSchema(schemaBytes) {
+		return ParseCedarSchema(schemaBytes)
+	}
 
 	var namespaces Namespaces
 	err := json.Unmarshal(schemaBytes, &namespaces)
@@ -0,0 +1,255 @@
+package authzen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/hexa-org/policy-mapper/sdk"
+)
+
+const (
+	keyProperties = "properties"
+	keyContext    = "context"
+	keyClaims     = "claims"
+	keyPolicies   = "policies"
+	keyUnknown    = "unknown_policies"
+	keyError      = "error"
+)
+
+// PolicyLoader returns the IDQL policies to be evaluated by a PolicyDecisionPoint
+type PolicyLoader func() ([]hexapolicy.PolicyInfo, error)
+
+// FilePolicyLoader loads IDQL policies from a JSON file
+func FilePolicyLoader(path string) PolicyLoader {
+	return func() ([]hexapolicy.PolicyInfo, error) {
+		return hexapolicysupport.ParsePolicyFile(path)
+	}
+}
+
+// IntegrationPolicyLoader retrieves IDQL policies from the policy application point papAlias of an SDK integration
+func IntegrationPolicyLoader(integration *sdk.Integration, papAlias string) PolicyLoader {
+	return func() ([]hexapolicy.PolicyInfo, error) {
+		policies, err := integration.GetPolicies(papAlias)
+		if err != nil {
+			return nil, err
+		}
+		return policies.Policies, nil
+	}
+}
+
+/*
+ParseUsers parses a JSON object of users keyed by subject id (see examples/authZen/users.json) into entities of type
+subjectType. Each user's attributes are available to conditions directly (e.g. subject.roles) and as subject.claims.
+*/
+func ParseUsers(userBytes []byte, subjectType string) ([]decision.EntityInfo, error) {
+	var users map[string]map[string]interface{}
+	if err := json.Unmarshal(userBytes, &users); err != nil {
+		return nil, fmt.Errorf("error parsing users: %s", err.Error())
+	}
+	entities := make([]decision.EntityInfo, 0, len(users))
+	for id, user := range users {
+		attributes := make(map[string]interface{}, len(user)+1)
+		for k, v := range user {
+			attributes[k] = v
+		}
+		attributes[keyClaims] = user
+		entities = append(entities, decision.EntityInfo{Id: entityId(subjectType, id), Attributes: attributes})
+	}
+	return entities, nil
+}
+
+// ParseUserFile reads a file of users. See ParseUsers
+func ParseUserFile(path string, subjectType string) ([]decision.EntityInfo, error) {
+	userBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUsers(userBytes, subjectType)
+}
+
+// PolicyDecisionPoint evaluates AuthZEN requests against IDQL policies
+type PolicyDecisionPoint struct {
+	loader    PolicyLoader
+	directory *decision.EntityDirectory
+	evaluator *decision.Evaluator
+	loadErr   error
+	mutex     sync.RWMutex
+}
+
+// NewPolicyDecisionPoint loads policies using loader. directory provides subject and resource attributes and
+// memberships and may be nil.
+func NewPolicyDecisionPoint(loader PolicyLoader, directory *decision.EntityDirectory) (*PolicyDecisionPoint, error) {
+	pdp := &PolicyDecisionPoint{loader: loader, directory: directory}
+	if err := pdp.Reload(); err != nil {
+		return nil, err
+	}
+	return pdp, nil
+}
+
+// Reload re-reads policies using the PolicyLoader. On error, the current policies remain in use.
+func (p *PolicyDecisionPoint) Reload() error {
+	policies, err := p.loader()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.loadErr = err
+	if err != nil {
+		return fmt.Errorf("unable to load policies: %w", err)
+	}
+	p.evaluator = decision.NewEvaluator(policies, p.directory)
+	return nil
+}
+
+// Name returns the name of the PDP health check
+func (p *PolicyDecisionPoint) Name() string {
+	return "authzen-pdp"
+}
+
+// Check returns true if the last policy load succeeded (see healthsupport.HealthCheck)
+func (p *PolicyDecisionPoint) Check() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.evaluator != nil && p.loadErr == nil
+}
+
+/*
+Evaluate returns the decision for an AuthZEN request. The subject and resource are evaluated as the IDQL entities
+<type>:<id>. Their properties are available to conditions both directly (e.g. resource.ownerID) and as properties (e.g.
+resource.properties.ownerID). The request context is available as context (e.g. context.time).
+*/
+func (p *PolicyDecisionPoint) Evaluate(request EvaluationRequest) (*EvaluationResponse, error) {
+	if err := validateRequest(request); err != nil {
+		return nil, err
+	}
+	result := p.getEvaluator().Evaluate(toDecisionRequest(request))
+
+	response := &EvaluationResponse{Decision: isAllowed(result)}
+	context := map[string]interface{}{}
+	if result.Allowed {
+		context[keyPolicies] = result.AllowPolicies
+	} else if len(result.DenyPolicies) > 0 {
+		context[keyPolicies] = result.DenyPolicies
+	}
+	if result.Indeterminate {
+		context[keyUnknown] = result.UnknownPolicies
+	}
+	if len(context) > 0 {
+		response.Context = context
+	}
+	return response, nil
+}
+
+func (p *PolicyDecisionPoint) getEvaluator() *decision.Evaluator {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.evaluator
+}
+
+func validateRequest(request EvaluationRequest) error {
+	if request.Subject == nil || request.Subject.Id == "" {
+		return errors.New("missing subject")
+	}
+	if request.Action == nil || request.Action.Name == "" {
+		return errors.New("missing action")
+	}
+	if request.Resource == nil || request.Resource.Type == "" {
+		return errors.New("missing resource")
+	}
+	return nil
+}
+
+// isAllowed returns true if the decision is allowed and does not depend on unknown attributes
+func isAllowed(result decision.Decision) bool {
+	return result.Allowed && !result.Indeterminate
+}
+
+func toDecisionRequest(request EvaluationRequest) decision.Request {
+	input := map[string]interface{}{
+		decision.InputSubject:  entityInput(request.Subject),
+		decision.InputResource: entityInput(request.Resource),
+	}
+	if request.Context != nil {
+		input[keyContext] = request.Context
+	}
+	return decision.Request{
+		Subject: entityId(request.Subject.Type, request.Subject.Id),
+		Action:  request.Action.Name,
+		Object:  entityId(request.Resource.Type, request.Resource.Id),
+		Context: input,
+	}
+}
+
+/*
+Evaluations processes a batch of evaluations. Each evaluation is merged with the request defaults and evaluated in
+order. Evaluations that are invalid are returned as a deny with the error in the response context. Processing stops
+at the first deny or permit when the options specify SemanticDenyOnFirstDeny or SemanticPermitOnFirstPermit.
+*/
+func (p *PolicyDecisionPoint) Evaluations(request EvaluationsRequest) (*EvaluationsResponse, error) {
+	semantic := SemanticExecuteAll
+	if request.Options != nil && request.Options.EvaluationsSemantic != "" {
+		semantic = request.Options.EvaluationsSemantic
+	}
+	switch semantic {
+	case SemanticExecuteAll, SemanticDenyOnFirstDeny, SemanticPermitOnFirstPermit:
+	default:
+		return nil, fmt.Errorf("unsupported evaluations_semantic: %s", semantic)
+	}
+
+	response := &EvaluationsResponse{Evaluations: []EvaluationResponse{}}
+	for _, item := range request.Evaluations {
+		evaluation := mergeRequest(request.EvaluationRequest, item)
+		result, err := p.Evaluate(evaluation)
+		if err != nil {
+			result = &EvaluationResponse{Context: map[string]interface{}{keyError: err.Error()}}
+		}
+		response.Evaluations = append(response.Evaluations, *result)
+		if (semantic == SemanticDenyOnFirstDeny && !result.Decision) || (semantic == SemanticPermitOnFirstPermit && result.Decision) {
+			break
+		}
+	}
+	return response, nil
+}
+
+// mergeRequest returns item with any unspecified values taken from defaults
+func mergeRequest(defaults EvaluationRequest, item EvaluationRequest) EvaluationRequest {
+	if item.Subject == nil {
+		item.Subject = defaults.Subject
+	}
+	if item.Action == nil {
+		item.Action = defaults.Action
+	}
+	if item.Resource == nil {
+		item.Resource = defaults.Resource
+	}
+	if item.Context == nil {
+		item.Context = defaults.Context
+	}
+	return item
+}
+
+// entityId returns the IDQL entity for an AuthZEN type and id (e.g. user:alice)
+func entityId(entityType string, id string) string {
+	if entityType == "" {
+		return id
+	}
+	return fmt.Sprintf("%s:%s", entityType, id)
+}
+
+// entityInput returns the attributes of an AuthZEN entity used to evaluate conditions
+func entityInput(entity *Entity) map[string]interface{} {
+	input := make(map[string]interface{}, len(entity.Properties)+3)
+	for k, v := range entity.Properties {
+		input[k] = v
+	}
+	input["id"] = entity.Id
+	input["type"] = entity.Type
+	if entity.Properties != nil {
+		input[keyProperties] = entity.Properties
+	}
+	return input
+}
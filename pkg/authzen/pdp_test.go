@@ -0,0 +1,122 @@
+package authzen
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	rickId  = "CiRmZDA2MTRkMy1jMzlhLTQ3ODEtYjdiZC04Yjk2ZjVhNTEwMGQSBWxvY2Fs"
+	mortyId = "CiRmZDE2MTRkMy1jMzlhLTQ3ODEtYjdiZC04Yjk2ZjVhNTEwMGQSBWxvY2Fs"
+	bethId  = "CiRmZDM2MTRkMy1jMzlhLTQ3ODEtYjdiZC04Yjk2ZjVhNTEwMGQSBWxvY2Fs"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../examples/authZen")
+}
+
+func newTestPdp(t *testing.T) *PolicyDecisionPoint {
+	users, err := ParseUserFile(filepath.Join(getExampleDir(), "users.json"), "user")
+	assert.NoError(t, err)
+	pdp, err := NewPolicyDecisionPoint(FilePolicyLoader(filepath.Join(getExampleDir(), "data.json")), decision.NewEntityDirectory(users))
+	assert.NoError(t, err)
+	return pdp
+}
+
+func todoRequest(subjectId string, action string, ownerId string) EvaluationRequest {
+	return EvaluationRequest{
+		Subject:  &Entity{Type: "user", Id: subjectId},
+		Action:   &Action{Name: action},
+		Resource: &Entity{Type: "todo", Id: "7240d0db", Properties: map[string]interface{}{"ownerID": ownerId}},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	pdp := newTestPdp(t)
+
+	tests := []struct {
+		name     string
+		request  EvaluationRequest
+		decision bool
+	}{
+		{"read user", EvaluationRequest{
+			Subject:  &Entity{Type: "user", Id: bethId},
+			Action:   &Action{Name: "can_read_user"},
+			Resource: &Entity{Type: "user", Id: "rick@the-citadel.com"},
+		}, true},
+		{"admin create", todoRequest(rickId, "can_create_todo", ""), true},
+		{"viewer create", todoRequest(bethId, "can_create_todo", ""), false},
+		{"evil genius update", todoRequest(rickId, "can_update_todo", "morty@the-citadel.com"), true},
+		{"editor update own", todoRequest(mortyId, "can_update_todo", "morty@the-citadel.com"), true},
+		{"editor update other", todoRequest(mortyId, "can_update_todo", "rick@the-citadel.com"), false},
+		{"viewer delete", todoRequest(bethId, "can_delete_todo", "beth@the-smiths.com"), false},
+		{"unknown action", todoRequest(rickId, "can_share_todo", ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := pdp.Evaluate(tt.request)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.decision, response.Decision)
+		})
+	}
+
+	response, err := pdp.Evaluate(todoRequest(rickId, "can_create_todo", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PostTodo"}, response.Context[keyPolicies])
+
+	_, err = pdp.Evaluate(EvaluationRequest{Action: &Action{Name: "can_read_user"}, Resource: &Entity{Type: "user"}})
+	assert.EqualError(t, err, "missing subject")
+	_, err = pdp.Evaluate(EvaluationRequest{Subject: &Entity{Type: "user", Id: rickId}, Resource: &Entity{Type: "user"}})
+	assert.EqualError(t, err, "missing action")
+	_, err = pdp.Evaluate(EvaluationRequest{Subject: &Entity{Type: "user", Id: rickId}, Action: &Action{Name: "can_read_user"}})
+	assert.EqualError(t, err, "missing resource")
+}
+
+func TestEvaluations(t *testing.T) {
+	pdp := newTestPdp(t)
+
+	request := EvaluationsRequest{
+		EvaluationRequest: EvaluationRequest{Subject: &Entity{Type: "user", Id: mortyId}},
+		Evaluations: []EvaluationRequest{
+			todoRequest(rickId, "can_create_todo", ""),
+			{Action: &Action{Name: "can_update_todo"}, Resource: &Entity{Type: "todo", Properties: map[string]interface{}{"ownerID": "rick@the-citadel.com"}}},
+			{Action: &Action{Name: "can_read_todos"}, Resource: &Entity{Type: "todo"}},
+			{Action: &Action{Name: "can_read_todos"}},
+		},
+	}
+	response, err := pdp.Evaluations(request)
+	assert.NoError(t, err)
+	assert.Len(t, response.Evaluations, 4)
+	assert.True(t, response.Evaluations[0].Decision)
+	assert.False(t, response.Evaluations[1].Decision, "morty is not the owner")
+	assert.True(t, response.Evaluations[2].Decision, "defaults to morty")
+	assert.False(t, response.Evaluations[3].Decision)
+	assert.Equal(t, "missing resource", response.Evaluations[3].Context[keyError])
+
+	request.Options = &EvaluationsOptions{EvaluationsSemantic: SemanticDenyOnFirstDeny}
+	response, err = pdp.Evaluations(request)
+	assert.NoError(t, err)
+	assert.Len(t, response.Evaluations, 2)
+
+	request.Options.EvaluationsSemantic = SemanticPermitOnFirstPermit
+	response, err = pdp.Evaluations(request)
+	assert.NoError(t, err)
+	assert.Len(t, response.Evaluations, 1)
+
+	request.Options.EvaluationsSemantic = "bad"
+	_, err = pdp.Evaluations(request)
+	assert.Error(t, err)
+}
+
+func TestNewPolicyDecisionPoint_Error(t *testing.T) {
+	_, err := NewPolicyDecisionPoint(FilePolicyLoader(filepath.Join(getExampleDir(), "missing.json")), nil)
+	assert.Error(t, err)
+
+	_, err = ParseUsers([]byte(`["bad"]`), "user")
+	assert.Error(t, err)
+}
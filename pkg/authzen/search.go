@@ -0,0 +1,139 @@
+package authzen
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/decision"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+// DefaultPageLimit is the number of search results returned when a request does not specify a page limit
+const DefaultPageLimit = 50
+
+/*
+SearchSubjects returns the subjects of the requested subject type that are allowed to perform the action on the
+resource. Candidates are the subjects referenced by policies and entities of the type in the directory. Conditions are
+evaluated using the attributes known for each candidate; candidates whose decision depends on unknown attributes are
+not returned.
+*/
+func (p *PolicyDecisionPoint) SearchSubjects(request SearchRequest) (*EntitySearchResponse, error) {
+	if request.Subject == nil || request.Subject.Type == "" {
+		return nil, errors.New("missing subject type")
+	}
+	searchType := request.Subject.Type
+	request.Subject = &Entity{Type: searchType, Id: searchType}
+	if err := validateRequest(request.EvaluationRequest); err != nil {
+		return nil, err
+	}
+
+	evaluator := p.getEvaluator()
+	return searchEntities(evaluator, evaluator.SubjectCandidates(searchType), searchType, request.Page, func(candidate *Entity) EvaluationRequest {
+		evaluation := request.EvaluationRequest
+		evaluation.Subject = candidate
+		return evaluation
+	})
+}
+
+// SearchResources returns the resources of the requested resource type that the subject may perform the action on.
+// See SearchSubjects.
+func (p *PolicyDecisionPoint) SearchResources(request SearchRequest) (*EntitySearchResponse, error) {
+	if request.Resource == nil || request.Resource.Type == "" {
+		return nil, errors.New("missing resource type")
+	}
+	if err := validateRequest(request.EvaluationRequest); err != nil {
+		return nil, err
+	}
+
+	evaluator := p.getEvaluator()
+	searchType := request.Resource.Type
+	return searchEntities(evaluator, evaluator.ObjectCandidates(searchType), searchType, request.Page, func(candidate *Entity) EvaluationRequest {
+		evaluation := request.EvaluationRequest
+		evaluation.Resource = candidate
+		return evaluation
+	})
+}
+
+// SearchActions returns the actions referenced by policies that the subject may perform on the resource
+func (p *PolicyDecisionPoint) SearchActions(request SearchRequest) (*ActionSearchResponse, error) {
+	request.Action = &Action{Name: "search"}
+	if err := validateRequest(request.EvaluationRequest); err != nil {
+		return nil, err
+	}
+
+	evaluator := p.getEvaluator()
+	var results []Action
+	for _, candidate := range evaluator.ActionCandidates() {
+		evaluation := request.EvaluationRequest
+		evaluation.Action = &Action{Name: candidate}
+		if isAllowed(evaluator.Evaluate(toDecisionRequest(evaluation))) {
+			results = append(results, Action{Name: candidate})
+		}
+	}
+
+	start, end, pageResult, err := paginate(len(results), request.Page)
+	if err != nil {
+		return nil, err
+	}
+	return &ActionSearchResponse{Results: append([]Action{}, results[start:end]...), Page: pageResult}, nil
+}
+
+func searchEntities(evaluator *decision.Evaluator, candidates []string, entityType string, page *Page, evaluation func(candidate *Entity) EvaluationRequest) (*EntitySearchResponse, error) {
+	var results []Entity
+	found := map[string]bool{}
+	for _, candidate := range candidates {
+		id := types.ParseEntity(candidate).GetId()
+		if found[strings.ToLower(id)] {
+			continue
+		}
+		entity := &Entity{Type: entityType, Id: id}
+		if isAllowed(evaluator.Evaluate(toDecisionRequest(evaluation(entity)))) {
+			found[strings.ToLower(id)] = true
+			results = append(results, Entity{Type: entityType, Id: id})
+		}
+	}
+
+	start, end, pageResult, err := paginate(len(results), page)
+	if err != nil {
+		return nil, err
+	}
+	return &EntitySearchResponse{Results: append([]Entity{}, results[start:end]...), Page: pageResult}, nil
+}
+
+// paginate returns the range of results to return for page and the token for the next page
+func paginate(count int, page *Page) (int, int, *PageResult, error) {
+	start := 0
+	limit := DefaultPageLimit
+	if page != nil {
+		if page.Limit > 0 {
+			limit = page.Limit
+		}
+		if page.Token != "" {
+			var err error
+			if start, err = decodeToken(page.Token); err != nil || start < 0 || start > count {
+				return 0, 0, nil, fmt.Errorf("invalid page token: %s", page.Token)
+			}
+		}
+	}
+	end := min(start+limit, count)
+	result := &PageResult{}
+	if end < count {
+		result.NextToken = encodeToken(end)
+	}
+	return start, end, result, nil
+}
+
+func encodeToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeToken(token string) (int, error) {
+	offset, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(offset))
+}
@@ -0,0 +1,87 @@
+package authzen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resultIds(results []Entity) []string {
+	var ids []string
+	for _, entity := range results {
+		ids = append(ids, entity.Id)
+	}
+	return ids
+}
+
+func TestSearchSubjects(t *testing.T) {
+	pdp := newTestPdp(t)
+
+	request := SearchRequest{EvaluationRequest: todoRequest("", "can_create_todo", "")}
+	request.Subject = &Entity{Type: "user"}
+	response, err := pdp.SearchSubjects(request)
+	assert.NoError(t, err)
+	// rick is an admin, morty and summer are editors
+	assert.ElementsMatch(t, []string{rickId, mortyId, "CiRmZDI2MTRkMy1jMzlhLTQ3ODEtYjdiZC04Yjk2ZjVhNTEwMGQSBWxvY2Fs"}, resultIds(response.Results))
+	assert.Empty(t, response.Page.NextToken)
+
+	// Page through the results one at a time
+	request.Page = &Page{Limit: 1}
+	var ids []string
+	for {
+		response, err = pdp.SearchSubjects(request)
+		assert.NoError(t, err)
+		assert.Len(t, response.Results, 1)
+		ids = append(ids, response.Results[0].Id)
+		if response.Page.NextToken == "" {
+			break
+		}
+		request.Page.Token = response.Page.NextToken
+	}
+	assert.ElementsMatch(t, []string{rickId, mortyId, "CiRmZDI2MTRkMy1jMzlhLTQ3ODEtYjdiZC04Yjk2ZjVhNTEwMGQSBWxvY2Fs"}, ids)
+
+	request.Page = &Page{Token: "bad"}
+	_, err = pdp.SearchSubjects(request)
+	assert.EqualError(t, err, "invalid page token: bad")
+
+	request.Subject = nil
+	_, err = pdp.SearchSubjects(request)
+	assert.EqualError(t, err, "missing subject type")
+}
+
+func TestSearchResources(t *testing.T) {
+	pdp := newTestPdp(t)
+
+	response, err := pdp.SearchResources(SearchRequest{EvaluationRequest: EvaluationRequest{
+		Subject:  &Entity{Type: "user", Id: bethId},
+		Action:   &Action{Name: "can_read_user"},
+		Resource: &Entity{Type: "user"},
+	}})
+	assert.NoError(t, err)
+	assert.Contains(t, resultIds(response.Results), rickId)
+
+	_, err = pdp.SearchResources(SearchRequest{EvaluationRequest: EvaluationRequest{
+		Subject: &Entity{Type: "user", Id: bethId},
+		Action:  &Action{Name: "can_read_user"},
+	}})
+	assert.EqualError(t, err, "missing resource type")
+}
+
+func TestSearchActions(t *testing.T) {
+	pdp := newTestPdp(t)
+
+	request := SearchRequest{EvaluationRequest: todoRequest(bethId, "", "beth@the-smiths.com")}
+	request.Action = nil
+	response, err := pdp.SearchActions(request)
+	assert.NoError(t, err)
+	var names []string
+	for _, action := range response.Results {
+		names = append(names, action.Name)
+	}
+	assert.Contains(t, names, "can_read_todos")
+	assert.NotContains(t, names, "can_create_todo")
+
+	request.Subject = nil
+	_, err = pdp.SearchActions(request)
+	assert.EqualError(t, err, "missing subject")
+}
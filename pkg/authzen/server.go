@@ -0,0 +1,183 @@
+package authzen
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hexa-org/policy-mapper/pkg/healthsupport"
+	"github.com/hexa-org/policy-mapper/pkg/oauth2support"
+	"github.com/hexa-org/policy-mapper/pkg/websupport"
+	log "golang.org/x/exp/slog"
+)
+
+const (
+	EvaluationPath  = "/access/v1/evaluation"
+	EvaluationsPath = "/access/v1/evaluations"
+	SearchSubject   = "/access/v1/search/subject"
+	SearchResource  = "/access/v1/search/resource"
+	SearchAction    = "/access/v1/search/action"
+	MetadataPath    = "/.well-known/authzen-configuration"
+
+	HeaderRequestId = "X-Request-ID"
+)
+
+// AuthZenHandler serves the AuthZEN Access Evaluation API for a PolicyDecisionPoint
+type AuthZenHandler struct {
+	Pdp        *PolicyDecisionPoint
+	Authorizer *oauth2support.ResourceJwtAuthorizer // Authorizer validates bearer tokens. If nil, requests are not authenticated
+	Scopes     []string                             // Scopes are the token scopes (or roles) accepted. If nil, any scope is accepted
+}
+
+/*
+NewAuthZenServer returns a server (see websupport.Create) for the AuthZEN endpoints. The server health check reports
+whether the policy decision point has policies loaded. Use websupport.Start to start the server.
+*/
+func NewAuthZenServer(addr string, handler *AuthZenHandler) *http.Server {
+	return websupport.Create(addr, handler.Handlers, websupport.Options{
+		HealthChecks: []healthsupport.HealthCheck{handler.Pdp},
+	})
+}
+
+// Handlers registers the AuthZEN endpoints with router
+func (h *AuthZenHandler) Handlers(router *mux.Router) {
+	authorizer := h.Authorizer
+	if authorizer == nil {
+		authorizer = &oauth2support.ResourceJwtAuthorizer{}
+	}
+	router.HandleFunc(MetadataPath, h.HandleMetadata).Methods(http.MethodGet)
+	router.HandleFunc(EvaluationPath, oauth2support.JwtAuthenticationHandler(h.HandleEvaluation, authorizer, h.Scopes)).Methods(http.MethodPost)
+	router.HandleFunc(EvaluationsPath, oauth2support.JwtAuthenticationHandler(h.HandleEvaluations, authorizer, h.Scopes)).Methods(http.MethodPost)
+	router.HandleFunc(SearchSubject, oauth2support.JwtAuthenticationHandler(h.HandleSearchSubject, authorizer, h.Scopes)).Methods(http.MethodPost)
+	router.HandleFunc(SearchResource, oauth2support.JwtAuthenticationHandler(h.HandleSearchResource, authorizer, h.Scopes)).Methods(http.MethodPost)
+	router.HandleFunc(SearchAction, oauth2support.JwtAuthenticationHandler(h.HandleSearchAction, authorizer, h.Scopes)).Methods(http.MethodPost)
+}
+
+// HandleMetadata returns the PDP metadata. Endpoint URLs are based on the host of the request.
+func (h *AuthZenHandler) HandleMetadata(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host
+	writeJson(w, r, &Metadata{
+		PolicyDecisionPoint:       base,
+		AccessEvaluationEndpoint:  base + EvaluationPath,
+		AccessEvaluationsEndpoint: base + EvaluationsPath,
+		SearchSubjectEndpoint:     base + SearchSubject,
+		SearchResourceEndpoint:    base + SearchResource,
+		SearchActionEndpoint:      base + SearchAction,
+	})
+}
+
+// HandleEvaluation handles an Access Evaluation API request
+func (h *AuthZenHandler) HandleEvaluation(w http.ResponseWriter, r *http.Request) {
+	var request EvaluationRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	response, err := h.Pdp.Evaluate(request)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, r, response)
+}
+
+// HandleEvaluations handles an Access Evaluations API request. A request without evaluations is treated as a single
+// evaluation.
+func (h *AuthZenHandler) HandleEvaluations(w http.ResponseWriter, r *http.Request) {
+	var request EvaluationsRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	if len(request.Evaluations) == 0 {
+		response, err := h.Pdp.Evaluate(request.EvaluationRequest)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJson(w, r, response)
+		return
+	}
+	response, err := h.Pdp.Evaluations(request)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, r, response)
+}
+
+// HandleSearchSubject handles a subject search request
+func (h *AuthZenHandler) HandleSearchSubject(w http.ResponseWriter, r *http.Request) {
+	var request SearchRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	response, err := h.Pdp.SearchSubjects(request)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, r, response)
+}
+
+// HandleSearchResource handles a resource search request
+func (h *AuthZenHandler) HandleSearchResource(w http.ResponseWriter, r *http.Request) {
+	var request SearchRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	response, err := h.Pdp.SearchResources(request)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, r, response)
+}
+
+// HandleSearchAction handles an action search request
+func (h *AuthZenHandler) HandleSearchAction(w http.ResponseWriter, r *http.Request) {
+	var request SearchRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	response, err := h.Pdp.SearchActions(request)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, r, response)
+}
+
+func readJson(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, value)
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	log.Info("AuthZEN request error", "status", status, "error", message)
+	setRequestId(w, r)
+	http.Error(w, message, status)
+}
+
+func writeJson(w http.ResponseWriter, r *http.Request, value interface{}) {
+	setRequestId(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// setRequestId returns the request id header (if any) as required by AuthZEN
+func setRequestId(w http.ResponseWriter, r *http.Request) {
+	if requestId := r.Header.Get(HeaderRequestId); requestId != "" {
+		w.Header().Set(HeaderRequestId, requestId)
+	}
+}
@@ -0,0 +1,180 @@
+package authzen
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/mockOidcSupport"
+	"github.com/hexa-org/policy-mapper/pkg/oauth2support"
+	"github.com/hexa-org/policy-mapper/pkg/websupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestServer(t *testing.T, handler *AuthZenHandler) string {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	server := NewAuthZenServer(listener.Addr().String(), handler)
+	go websupport.Start(server, listener)
+	t.Cleanup(func() { websupport.Stop(server) })
+	return "http://" + listener.Addr().String()
+}
+
+func post(t *testing.T, url string, token string, body string) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderRequestId, "req-1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return resp, respBody
+}
+
+func TestAuthZenServer(t *testing.T) {
+	baseUrl := startTestServer(t, &AuthZenHandler{Pdp: newTestPdp(t)})
+
+	resp, body := post(t, baseUrl+EvaluationPath, "", `{
+  "subject": {"type": "user", "id": "`+rickId+`"},
+  "action": {"name": "can_create_todo"},
+  "resource": {"type": "todo", "id": "1"}
+}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "req-1", resp.Header.Get(HeaderRequestId))
+	var response EvaluationResponse
+	assert.NoError(t, json.Unmarshal(body, &response))
+	assert.True(t, response.Decision)
+
+	resp, body = post(t, baseUrl+EvaluationsPath, "", `{
+  "subject": {"type": "user", "id": "`+bethId+`"},
+  "resource": {"type": "todo", "id": "1"},
+  "evaluations": [
+    {"action": {"name": "can_read_todos"}},
+    {"action": {"name": "can_create_todo"}}
+  ]
+}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var responses EvaluationsResponse
+	assert.NoError(t, json.Unmarshal(body, &responses))
+	assert.Len(t, responses.Evaluations, 2)
+	assert.True(t, responses.Evaluations[0].Decision)
+	assert.False(t, responses.Evaluations[1].Decision)
+
+	// without evaluations, the request is a single evaluation
+	resp, body = post(t, baseUrl+EvaluationsPath, "", `{
+  "subject": {"type": "user", "id": "`+bethId+`"},
+  "action": {"name": "can_read_todos"},
+  "resource": {"type": "todo", "id": "1"}
+}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	response = EvaluationResponse{}
+	assert.NoError(t, json.Unmarshal(body, &response))
+	assert.True(t, response.Decision)
+
+	resp, _ = post(t, baseUrl+EvaluationPath, "", `{"subject": `)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = post(t, baseUrl+EvaluationPath, "", `{"action": {"name": "can_read_todos"}}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	metaResp, err := http.Get(baseUrl + MetadataPath)
+	assert.NoError(t, err)
+	var metadata Metadata
+	assert.NoError(t, json.NewDecoder(metaResp.Body).Decode(&metadata))
+	assert.Equal(t, baseUrl, metadata.PolicyDecisionPoint)
+	assert.Equal(t, baseUrl+EvaluationPath, metadata.AccessEvaluationEndpoint)
+	assert.Equal(t, baseUrl+SearchSubject, metadata.SearchSubjectEndpoint)
+}
+
+func TestAuthZenServer_Search(t *testing.T) {
+	baseUrl := startTestServer(t, &AuthZenHandler{Pdp: newTestPdp(t)})
+
+	resp, body := post(t, baseUrl+SearchSubject, "", `{
+  "subject": {"type": "user"},
+  "action": {"name": "can_create_todo"},
+  "resource": {"type": "todo", "id": "1"},
+  "page": {"limit": 2}
+}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var subjects EntitySearchResponse
+	assert.NoError(t, json.Unmarshal(body, &subjects))
+	assert.Len(t, subjects.Results, 2)
+	assert.NotEmpty(t, subjects.Page.NextToken)
+
+	resp, body = post(t, baseUrl+SearchResource, "", `{
+  "subject": {"type": "user", "id": "`+bethId+`"},
+  "action": {"name": "can_read_user"},
+  "resource": {"type": "user"}
+}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var resources EntitySearchResponse
+	assert.NoError(t, json.Unmarshal(body, &resources))
+	assert.Contains(t, resultIds(resources.Results), rickId)
+
+	resp, body = post(t, baseUrl+SearchAction, "", `{
+  "subject": {"type": "user", "id": "`+rickId+`"},
+  "resource": {"type": "todo", "id": "1"}
+}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var actions ActionSearchResponse
+	assert.NoError(t, json.Unmarshal(body, &actions))
+	assert.Contains(t, actions.Results, Action{Name: "can_create_todo"})
+
+	resp, _ = post(t, baseUrl+SearchSubject, "", `{"action": {"name": "can_create_todo"}, "resource": {"type": "todo"}}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	healthResp, err := http.Get(baseUrl + "/health")
+	assert.NoError(t, err)
+	healthBody, _ := io.ReadAll(healthResp.Body)
+	_ = healthResp.Body.Close()
+	assert.Equal(t, http.StatusOK, healthResp.StatusCode)
+	assert.Contains(t, string(healthBody), "authzen-pdp")
+}
+
+func TestAuthZenServer_Jwt(t *testing.T) {
+	mockAuth := mockOidcSupport.NewMockAuthServer("clientId", "secret", map[string]interface{}{})
+	defer mockAuth.Shutdown()
+	jwksUrl, _ := url.JoinPath(mockAuth.Server.URL, "/jwks")
+	_ = os.Setenv(oauth2support.EnvOAuthJwksUrl, jwksUrl)
+	_ = os.Unsetenv(oauth2support.EnvTknPubKeyFile)
+	_ = os.Setenv(oauth2support.EnvJwtAuth, "true")
+	_ = os.Setenv(oauth2support.EnvJwtAudience, "authzen")
+	defer func() {
+		_ = os.Unsetenv(oauth2support.EnvJwtAuth)
+		_ = os.Unsetenv(oauth2support.EnvOAuthJwksUrl)
+		_ = os.Unsetenv(oauth2support.EnvJwtAudience)
+	}()
+	authorizer, err := oauth2support.NewResourceJwtAuthorizer()
+	assert.NoError(t, err)
+
+	baseUrl := startTestServer(t, &AuthZenHandler{Pdp: newTestPdp(t), Authorizer: authorizer, Scopes: []string{"pdp"}})
+	request := `{
+  "subject": {"type": "user", "id": "` + rickId + `"},
+  "action": {"name": "can_create_todo"},
+  "resource": {"type": "todo", "id": "1"}
+}`
+
+	resp, _ := post(t, baseUrl+EvaluationPath, "", request)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	token, err := mockAuth.BuildJWT(60, []string{"other"}, []string{"authzen"}, "", false)
+	assert.NoError(t, err)
+	resp, _ = post(t, baseUrl+EvaluationPath, token, request)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	token, err = mockAuth.BuildJWT(60, []string{"pdp"}, []string{"authzen"}, "", false)
+	assert.NoError(t, err)
+	resp, body := post(t, baseUrl+EvaluationPath, token, request)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response EvaluationResponse
+	assert.NoError(t, json.Unmarshal(body, &response))
+	assert.True(t, response.Decision)
+}
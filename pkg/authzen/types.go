@@ -0,0 +1,101 @@
+/*
+Package authzen implements an OpenID AuthZEN Access Evaluation API policy decision point (PDP) that evaluates IDQL
+policies locally using the decision package. See https://openid.github.io/authzen/.
+*/
+package authzen
+
+const (
+	SemanticExecuteAll          = "execute_all"
+	SemanticDenyOnFirstDeny     = "deny_on_first_deny"
+	SemanticPermitOnFirstPermit = "permit_on_first_permit"
+)
+
+// Entity is an AuthZEN subject or resource (e.g. `{"type": "user", "id": "alice@example.com"}`)
+type Entity struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Action is the AuthZEN action being performed (e.g. `{"name": "can_read"}`)
+type Action struct {
+	Name       string                 `json:"name"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// EvaluationRequest is the body of an Access Evaluation API request
+type EvaluationRequest struct {
+	Subject  *Entity                `json:"subject,omitempty"`
+	Action   *Action                `json:"action,omitempty"`
+	Resource *Entity                `json:"resource,omitempty"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+}
+
+// EvaluationResponse is the decision returned for an EvaluationRequest
+type EvaluationResponse struct {
+	Decision bool                   `json:"decision"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+}
+
+// EvaluationsOptions controls how a batch of evaluations is processed
+type EvaluationsOptions struct {
+	EvaluationsSemantic string `json:"evaluations_semantic,omitempty"` // one of SemanticExecuteAll (default), SemanticDenyOnFirstDeny or SemanticPermitOnFirstPermit
+}
+
+/*
+EvaluationsRequest is the body of an Access Evaluations API (batch) request. The subject, action, resource and context
+are defaults for each item in Evaluations, and are overridden by any value an item specifies.
+*/
+type EvaluationsRequest struct {
+	EvaluationRequest
+	Evaluations []EvaluationRequest `json:"evaluations,omitempty"`
+	Options     *EvaluationsOptions `json:"options,omitempty"`
+}
+
+// EvaluationsResponse holds the decisions for an EvaluationsRequest in request order
+type EvaluationsResponse struct {
+	Evaluations []EvaluationResponse `json:"evaluations"`
+}
+
+// Page requests a page of search results. Token is the next_token of a previous response.
+type Page struct {
+	Token string `json:"token,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// PageResult is returned with search results. NextToken is empty when there are no more results.
+type PageResult struct {
+	NextToken string `json:"next_token"`
+}
+
+/*
+SearchRequest is the body of a subject, resource or action search. For a subject search, subject holds only the type
+of subject to search for. Similarly, for a resource search, resource holds only the type. An action search has no
+action.
+*/
+type SearchRequest struct {
+	EvaluationRequest
+	Page *Page `json:"page,omitempty"`
+}
+
+// EntitySearchResponse holds the subjects or resources found by a search
+type EntitySearchResponse struct {
+	Results []Entity    `json:"results"`
+	Page    *PageResult `json:"page,omitempty"`
+}
+
+// ActionSearchResponse holds the actions found by a search
+type ActionSearchResponse struct {
+	Results []Action    `json:"results"`
+	Page    *PageResult `json:"page,omitempty"`
+}
+
+// Metadata is the PDP metadata returned from the well-known AuthZEN configuration endpoint
+type Metadata struct {
+	PolicyDecisionPoint       string `json:"policy_decision_point"`
+	AccessEvaluationEndpoint  string `json:"access_evaluation_endpoint"`
+	AccessEvaluationsEndpoint string `json:"access_evaluations_endpoint,omitempty"`
+	SearchSubjectEndpoint     string `json:"search_subject_endpoint,omitempty"`
+	SearchResourceEndpoint    string `json:"search_resource_endpoint,omitempty"`
+	SearchActionEndpoint      string `json:"search_action_endpoint,omitempty"`
+}
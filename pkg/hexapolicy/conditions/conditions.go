@@ -284,7 +284,17 @@ func walk(e conditionparser.Expression, isChild bool) string {
 
 		return fmt.Sprintf("(%v)", subExpressionString)
 	case conditionparser.ValuePathExpression:
-		return walk(v.VPathFilter, true)
+		path := fmt.Sprintf("%s[%s]", v.Attribute.String(), walk(v.VPathFilter, false))
+		if v.SubAttr != nil {
+			path = path + "." + *v.SubAttr
+		}
+		if v.Operator == nil {
+			return path
+		}
+		if *v.Operator == conditionparser.PR {
+			return path + " pr"
+		}
+		return fmt.Sprintf("%s %s %s", path, *v.Operator, v.CompareValue.String())
 	// case idqlCondition.AttributeExpression:
 	default:
 		return v.String()
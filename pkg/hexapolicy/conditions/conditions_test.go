@@ -216,3 +216,15 @@ func TestFindEntities(t *testing.T) {
 		})
 	}
 }
+
+func TestSerializeValuePath(t *testing.T) {
+	for _, example := range []string{
+		"emails[type eq \"work\" and value ew \"strata.io\"] pr",
+		"emails[type eq \"work\"].value ew \"strata.io\"",
+		"userType eq \"Employee\" and emails[type eq \"work\" or primary eq true] pr",
+	} {
+		ast, err := conditions.ParseExpressionAst(example)
+		assert.NoError(t, err)
+		assert.Equal(t, example, conditions.SerializeExpression(ast))
+	}
+}
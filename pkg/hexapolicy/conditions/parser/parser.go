@@ -216,6 +216,14 @@ func parseFilterSub(expression string, parentAttr string) (Expression, error) {
 			if wordIndex > -1 {
 				phrase := expression[wordIndex:charPos]
 				if strings.EqualFold(phrase, "or") || strings.EqualFold(phrase, "and") {
+					if vpe != nil && cond == "" {
+						// a value path filter without a comparison (e.g. emails[type eq "work"] and ...)
+						clauses = append(clauses, *vpe)
+						vpe = nil
+						attr = ""
+						isAttr = false
+						isExpr = false
+					}
 					isLogic = true
 					isAnd = strings.EqualFold(phrase, "and")
 					wordIndex = -1
@@ -242,6 +250,7 @@ func parseFilterSub(expression string, parentAttr string) (Expression, error) {
 							isExpr = false
 							isValue = false
 							clauses = append(clauses, attrFilter)
+							vpe = nil
 						}
 					} else {
 						if isValue {
@@ -365,6 +374,11 @@ func parseFilterSub(expression string, parentAttr string) (Expression, error) {
 	if valPathCnt > 0 {
 		return nil, errors.New("invalid condition: Missing ']' bracket")
 	}
+	if vpe != nil && wordIndex == -1 && cond == "" {
+		// the condition ends with a value path filter that has no comparison
+		clauses = append(clauses, *vpe)
+		vpe = nil
+	}
 	if wordIndex > -1 && charPos == len(expression) {
 		filterAttr := attr
 		if parentAttr != "" {
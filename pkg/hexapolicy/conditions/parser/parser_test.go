@@ -44,6 +44,11 @@ func TestParseFilter(t *testing.T) {
 		{"NAME PR AND NOT (FIRST EQ \"t[es]t\") AND ANOTHER NE \"test\"", "NAME pr and not (FIRST eq \"t[es]t\") and ANOTHER ne \"test\""},
 		{"name pr or userName pr or title pr"},
 		{"emails[type eq \"work\"].value ew \"h[exa].org\"", "emails[type eq \"work\"].value ew \"h[exa].org\""},
+		{"emails[type eq \"work\" and value ew \"example.com\"]"},
+		{"emails[type eq \"work\"] and level gt 5"},
+		{"level gt 5 or emails[primary eq true]"},
+		{"not (emails[primary eq true]) and level gt 5"},
+		{"emails[type eq \"work\"].value pr and level gt 5"},
 	}
 	for _, example := range examples {
 		t.Run(example[0], func(t *testing.T) {
@@ -0,0 +1,221 @@
+package decision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+// EntityInfo is a known subject, resource or group used when evaluating IDQL policies. Id is held in IDQL entity
+// form (e.g. PhotoApp:User:alice).
+type EntityInfo struct {
+	Id         string                 `json:"id"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Parents    []string               `json:"parents,omitempty"`
+}
+
+// Entity returns the parsed IDQL entity for the identifier
+func (e *EntityInfo) Entity() types.Entity {
+	return *types.ParseEntity(e.Id)
+}
+
+// EntityDirectory holds the set of known entities that provide membership (parents) and attributes for evaluation.
+type EntityDirectory struct {
+	entities []*EntityInfo
+	index    map[string][]*EntityInfo
+}
+
+// NewEntityDirectory creates a directory from a list of entities
+func NewEntityDirectory(entities []EntityInfo) *EntityDirectory {
+	dir := &EntityDirectory{
+		index: make(map[string][]*EntityInfo),
+	}
+	for i := range entities {
+		dir.Add(entities[i])
+	}
+	return dir
+}
+
+// Add adds or replaces an entity in the directory
+func (d *EntityDirectory) Add(entity EntityInfo) {
+	key := entityKey(*types.ParseEntity(entity.Id))
+	item := &entity
+	existing := d.index[key]
+	for i, v := range existing {
+		if sameEntity(v.Entity(), item.Entity()) {
+			existing[i] = item
+			for j, e := range d.entities {
+				if e == v {
+					d.entities[j] = item
+				}
+			}
+			return
+		}
+	}
+	d.index[key] = append(existing, item)
+	d.entities = append(d.entities, item)
+}
+
+// Entities returns all entities in the directory
+func (d *EntityDirectory) Entities() []EntityInfo {
+	res := make([]EntityInfo, len(d.entities))
+	for i, e := range d.entities {
+		res[i] = *e
+	}
+	return res
+}
+
+// Get locates an entity by its IDQL identifier. The namespace is only compared when both identifiers specify one.
+func (d *EntityDirectory) Get(id string) *EntityInfo {
+	if d == nil {
+		return nil
+	}
+	entity := types.ParseEntity(id)
+	for _, v := range d.index[entityKey(*entity)] {
+		if sameEntity(v.Entity(), *entity) {
+			return v
+		}
+	}
+	return nil
+}
+
+// IsMemberOf returns true if the entity identified by id is (directly or transitively) a member of the group entity
+func (d *EntityDirectory) IsMemberOf(id string, group types.Entity) bool {
+	if d == nil {
+		return false
+	}
+	visited := map[string]bool{}
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		entity := d.Get(current)
+		if entity == nil {
+			continue
+		}
+		for _, parent := range entity.Parents {
+			lower := strings.ToLower(parent)
+			if visited[lower] {
+				continue
+			}
+			visited[lower] = true
+			if sameEntity(*types.ParseEntity(parent), group) {
+				return true
+			}
+			queue = append(queue, parent)
+		}
+	}
+	return false
+}
+
+type cedarUid struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type cedarEntity struct {
+	Uid     json.RawMessage        `json:"uid"`
+	Attrs   map[string]interface{} `json:"attrs"`
+	Parents []json.RawMessage      `json:"parents"`
+}
+
+// ParseEntities parses a JSON document containing either an array of EntityInfo or Cedar entities
+// (e.g. `{"uid": {"type": "PhotoApp::User", "id": "alice"}, "attrs": {}, "parents": []}`)
+func ParseEntities(entityBytes []byte) (*EntityDirectory, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(entityBytes, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing entities: %s", err.Error())
+	}
+
+	entities := make([]EntityInfo, 0, len(raw))
+	for i, item := range raw {
+		if _, ok := item["uid"]; !ok {
+			var info EntityInfo
+			itemBytes, _ := json.Marshal(item)
+			if err := json.Unmarshal(itemBytes, &info); err != nil {
+				return nil, err
+			}
+			if info.Id == "" {
+				return nil, fmt.Errorf("entity %d is missing an identifier", i)
+			}
+			entities = append(entities, info)
+			continue
+		}
+
+		var cEntity cedarEntity
+		itemBytes, _ := json.Marshal(item)
+		if err := json.Unmarshal(itemBytes, &cEntity); err != nil {
+			return nil, err
+		}
+		id, err := mapCedarUid(cEntity.Uid)
+		if err != nil {
+			return nil, err
+		}
+		info := EntityInfo{Id: id, Attributes: cEntity.Attrs}
+		for _, parent := range cEntity.Parents {
+			pid, err := mapCedarUid(parent)
+			if err != nil {
+				return nil, err
+			}
+			info.Parents = append(info.Parents, pid)
+		}
+		entities = append(entities, info)
+	}
+	return NewEntityDirectory(entities), nil
+}
+
+// ParseEntityFile reads a file of entities. See ParseEntities
+func ParseEntityFile(path string) (*EntityDirectory, error) {
+	entityBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEntities(entityBytes)
+}
+
+// mapCedarUid converts a Cedar uid (either `{"type":"A::User","id":"alice"}` or `A::User::"alice"`) to IDQL form
+func mapCedarUid(raw json.RawMessage) (string, error) {
+	var uid cedarUid
+	if err := json.Unmarshal(raw, &uid); err == nil && uid.Type != "" {
+		return fmt.Sprintf("%s:%s", strings.ReplaceAll(uid.Type, "::", ":"), uid.Id), nil
+	}
+	var entityRef map[string]cedarUid
+	if err := json.Unmarshal(raw, &entityRef); err == nil {
+		if ref, ok := entityRef["__entity"]; ok {
+			return fmt.Sprintf("%s:%s", strings.ReplaceAll(ref.Type, "::", ":"), ref.Id), nil
+		}
+	}
+	var uidString string
+	if err := json.Unmarshal(raw, &uidString); err == nil {
+		index := strings.LastIndex(uidString, "::")
+		if index > 0 {
+			id := uidString[index+2:]
+			if unquoted, err := strconv.Unquote(id); err == nil {
+				id = unquoted
+			}
+			return fmt.Sprintf("%s:%s", strings.ReplaceAll(uidString[0:index], "::", ":"), id), nil
+		}
+	}
+	return "", errors.New("unable to parse entity uid: " + string(raw))
+}
+
+// entityKey returns a case-insensitive key based on entity type and id (excluding namespace)
+func entityKey(entity types.Entity) string {
+	return strings.ToLower(entity.GetType() + ":" + entity.GetId())
+}
+
+// sameEntity compares two entities by type and id. Namespaces are compared only when both have one.
+func sameEntity(a, b types.Entity) bool {
+	if !strings.EqualFold(a.GetType(), b.GetType()) || !strings.EqualFold(a.GetId(), b.GetId()) {
+		return false
+	}
+	if len(a.Types) > 1 && len(b.Types) > 1 {
+		return strings.EqualFold(strings.Join(a.Types, ":"), strings.Join(b.Types, ":"))
+	}
+	return true
+}
@@ -0,0 +1,322 @@
+/*
+Package decision evaluates IDQL policies against access requests (subject, action, object and context) in the same
+manner as the Hexa Rego interpreter (see providers/openpolicyagent/resources/bundles/bundle/hexaPolicy.rego). It is
+used to determine locally how policies behave (e.g. change impact analysis) without a remote policy decision point.
+*/
+package decision
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	keyId   = "id"
+	keyType = "type"
+
+	InputSubject   = "subject"
+	InputPrincipal = "principal"
+	InputResource  = "resource"
+	InputObject    = "object"
+	InputAction    = "action"
+
+	DecisionAllow         = "ALLOW"
+	DecisionDeny          = "DENY"
+	DecisionIndeterminate = "INDETERMINATE"
+)
+
+// Request is an access request to be evaluated.
+type Request struct {
+	Subject string                 // Subject is the IDQL entity making the request (e.g. User:alice). Empty indicates an anonymous request
+	Action  string                 // Action is the action being performed (e.g. can_read_todos)
+	Object  string                 // Object is the IDQL entity of the resource being accessed (e.g. Todo:1)
+	Context map[string]interface{} // Context holds additional attributes (e.g. subject claims, req.ip) referenced by conditions
+}
+
+// Decision is the result of evaluating a Request
+type Decision struct {
+	Allowed         bool     // Allowed is true when at least one allow policy matched and no deny policy matched
+	Indeterminate   bool     // Indeterminate is true when a condition could not be evaluated and may change the result
+	AllowPolicies   []string // AllowPolicies are the identifiers of the allow policies that matched
+	DenyPolicies    []string // DenyPolicies are the identifiers of deny policies that matched
+	UnknownPolicies []string // UnknownPolicies are policies whose conditions could not be evaluated due to missing attributes
+}
+
+// Result returns DecisionAllow, DecisionDeny, or DecisionIndeterminate
+func (d Decision) Result() string {
+	if d.Indeterminate {
+		return DecisionIndeterminate
+	}
+	if d.Allowed {
+		return DecisionAllow
+	}
+	return DecisionDeny
+}
+
+type compiledPolicy struct {
+	id        string
+	policy    hexapolicy.PolicyInfo
+	condition parser.Expression
+	condErr   error
+}
+
+// Evaluator evaluates requests against a set of IDQL policies and an optional EntityDirectory.
+type Evaluator struct {
+	policies  []compiledPolicy
+	directory *EntityDirectory
+}
+
+// PolicyLabel returns the policyId of the policy or, if not assigned, a label based on its position (e.g. Policy-1)
+func PolicyLabel(policy hexapolicy.PolicyInfo, index int) string {
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		return *policy.Meta.PolicyId
+	}
+	return fmt.Sprintf("Policy-%d", index)
+}
+
+// NewEvaluator returns an Evaluator for policies. directory may be nil in which case membership and entity attributes
+// are only available through Request.Context.
+func NewEvaluator(policies []hexapolicy.PolicyInfo, directory *EntityDirectory) *Evaluator {
+	compiled := make([]compiledPolicy, len(policies))
+	for i, policy := range policies {
+		item := compiledPolicy{
+			id:     PolicyLabel(policy, i),
+			policy: policy,
+		}
+		if policy.Condition != nil && policy.Condition.Rule != "" {
+			item.condition, item.condErr = conditions.ParseConditionRuleAst(*policy.Condition)
+		}
+		compiled[i] = item
+	}
+	return &Evaluator{policies: compiled, directory: directory}
+}
+
+// Evaluate returns the Decision for req. Policies with conditions that cannot be parsed are treated as
+// indeterminate.
+func (e *Evaluator) Evaluate(req Request) Decision {
+	decision := Decision{}
+	input := e.buildInput(req)
+	ctx := &filterContext{input: input, directory: e.directory}
+	var unknownAllow, unknownDeny bool
+
+	for _, item := range e.policies {
+		policy := item.policy
+		if !e.subjectMatch(policy.Subjects, req.Subject, input) ||
+			!actionsMatch(policy.Actions, req.Action) ||
+			!e.objectMatch(policy.Object, req.Object) {
+			continue
+		}
+
+		result := True
+		if item.condErr != nil {
+			result = Unknown
+		} else if item.condition != nil {
+			result = ctx.evaluate(item.condition)
+		}
+
+		isDeny := policy.Condition != nil && strings.EqualFold(policy.Condition.Action, conditions.ADeny)
+		switch result {
+		case True:
+			if isDeny {
+				decision.DenyPolicies = append(decision.DenyPolicies, item.id)
+			} else {
+				decision.AllowPolicies = append(decision.AllowPolicies, item.id)
+			}
+		case Unknown:
+			decision.UnknownPolicies = append(decision.UnknownPolicies, item.id)
+			if isDeny {
+				unknownDeny = true
+			} else {
+				unknownAllow = true
+			}
+		}
+	}
+
+	decision.Allowed = len(decision.DenyPolicies) == 0 && len(decision.AllowPolicies) > 0
+	if decision.Allowed {
+		decision.Indeterminate = unknownDeny
+	} else {
+		decision.Indeterminate = len(decision.DenyPolicies) == 0 && unknownAllow
+	}
+	return decision
+}
+
+// buildInput constructs the attribute document used to evaluate conditions. Entity attributes from the directory
+// are merged with Request.Context (context values take precedence).
+func (e *Evaluator) buildInput(req Request) map[string]interface{} {
+	input := make(map[string]interface{}, len(req.Context)+4)
+	for k, v := range req.Context {
+		input[k] = v
+	}
+
+	subject := e.entityAttributes(req.Subject, input[InputSubject])
+	input[InputSubject] = subject
+	input[InputPrincipal] = subject
+
+	object := e.entityAttributes(req.Object, input[InputResource])
+	input[InputResource] = object
+	input[InputObject] = object
+
+	if _, ok := input[InputAction]; !ok {
+		input[InputAction] = req.Action
+	}
+	return input
+}
+
+func (e *Evaluator) entityAttributes(id string, contextValue interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	if id != "" {
+		entity := types.ParseEntity(id)
+		res[keyId] = id
+		res[keyType] = entity.GetType()
+		if info := e.directory.Get(id); info != nil {
+			for k, v := range info.Attributes {
+				res[k] = v
+			}
+		}
+	}
+	if ctxMap, ok := contextValue.(map[string]interface{}); ok {
+		for k, v := range ctxMap {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func (e *Evaluator) subjectMatch(subjects hexapolicy.SubjectInfo, subject string, input map[string]interface{}) bool {
+	if len(subjects) == 0 {
+		return true
+	}
+	for _, member := range subjects {
+		if e.subjectMemberMatch(member, subject, input) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Evaluator) subjectMemberMatch(member string, subject string, input map[string]interface{}) bool {
+	lower := strings.ToLower(member)
+	switch {
+	case lower == strings.ToLower(hexapolicy.SubjectAnyUser):
+		return true
+	case lower == strings.ToLower(hexapolicy.SubjectAnyAuth):
+		return subject != ""
+	case subject == "":
+		return false
+	case strings.HasPrefix(lower, "domain:"):
+		return strings.HasSuffix(strings.ToLower(types.ParseEntity(subject).GetId()), lower[7:])
+	case strings.HasPrefix(lower, "net:"):
+		return netMatch(member[4:], input)
+	case strings.HasPrefix(lower, "role:"):
+		if roles, ok := lookupPath(input, "subject.roles"); ok {
+			if list, ok := toList(roles); ok {
+				for _, role := range list {
+					if strings.EqualFold(fmt.Sprint(role), member[5:]) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	memberEntity := types.ParseEntity(member)
+	subjectEntity := types.ParseEntity(subject)
+	// a policy of user:<sub> matches a subject with no type (default User entity type)
+	if len(subjectEntity.Types) == 0 && strings.EqualFold(memberEntity.GetType(), "user") {
+		return strings.EqualFold(memberEntity.GetId(), subjectEntity.GetId())
+	}
+	return e.entityMatch(*memberEntity, subject)
+}
+
+// entityMatch tests whether the IDQL entity pattern (e.g. User:, User:alice, [Group:admins], User[Group:admins])
+// matches the entity identified by id.
+func (e *Evaluator) entityMatch(pattern types.Entity, id string) bool {
+	entity := types.ParseEntity(id)
+	switch pattern.Type {
+	case types.RelTypeAny:
+		return true
+	case types.RelTypeAnyAuthenticated:
+		return id != ""
+	case types.RelTypeEmpty:
+		return true
+	case types.RelTypeEquals:
+		return sameEntity(pattern, *entity)
+	case types.RelTypeIs:
+		return typeMatch(pattern, *entity)
+	case types.RelTypeIn:
+		return e.inMatch(*pattern.In, id)
+	case types.RelTypeIsIn:
+		return typeMatch(pattern, *entity) && e.inMatch(*pattern.In, id)
+	}
+	return false
+}
+
+func (e *Evaluator) inMatch(groups []types.Entity, id string) bool {
+	entity := types.ParseEntity(id)
+	for _, group := range groups {
+		if sameEntity(group, *entity) || e.directory.IsMemberOf(id, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeMatch(pattern types.Entity, entity types.Entity) bool {
+	if !strings.EqualFold(pattern.GetType(), entity.GetType()) {
+		return false
+	}
+	if len(pattern.Types) > 1 && len(entity.Types) > 1 {
+		return strings.EqualFold(strings.Join(pattern.Types, ":"), strings.Join(entity.Types, ":"))
+	}
+	return true
+}
+
+func actionsMatch(actions []hexapolicy.ActionInfo, action string) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, policyAction := range actions {
+		if strings.EqualFold(string(policyAction), action) {
+			return true
+		}
+		if sameEntity(*policyAction.EntityPath(), *types.ParseEntity(action)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Evaluator) objectMatch(object hexapolicy.ObjectInfo, id string) bool {
+	if object == "" {
+		return true
+	}
+	if strings.EqualFold(object.String(), id) {
+		return true
+	}
+	return e.entityMatch(*object.Entity(), id)
+}
+
+// netMatch checks whether the request ip (req.ip) is within the cidr
+func netMatch(cidr string, input map[string]interface{}) bool {
+	ipValue, ok := lookupPath(input, "req.ip")
+	if !ok {
+		return false
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	addr := fmt.Sprint(ipValue)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && network.Contains(ip)
+}
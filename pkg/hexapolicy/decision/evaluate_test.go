@@ -0,0 +1,190 @@
+package decision
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../../examples")
+}
+
+func TestParseEntities(t *testing.T) {
+	dir, err := ParseEntityFile(filepath.Join(getExampleDir(), "policyInfoModels", "photoEntities.json"))
+	assert.NoError(t, err)
+	assert.Len(t, dir.Entities(), 4)
+
+	alice := dir.Get("PhotoApp:User:alice")
+	assert.NotNil(t, alice)
+	assert.Equal(t, "897345789237492878", alice.Attributes["userId"])
+	assert.NotNil(t, dir.Get("User:\"alice\""), "namespace optional and id may be quoted")
+	assert.Nil(t, dir.Get("User:bob"))
+
+	assert.True(t, dir.IsMemberOf("PhotoApp:User:alice", *types.ParseEntity("PhotoApp:UserGroup:AVTeam")))
+}
+
+func TestParseEntities_Idql(t *testing.T) {
+	entityBytes := []byte(`[
+  {"id": "User:alice", "attributes": {"dept": "sales"}, "parents": ["Group:staff"]},
+  {"id": "Group:staff", "parents": ["Group:everyone"]}
+]`)
+	dir, err := ParseEntities(entityBytes)
+	assert.NoError(t, err)
+	group := dir.Get("Group:everyone")
+	assert.Nil(t, group)
+	assert.True(t, dir.IsMemberOf("User:alice", *types.ParseEntity("Group:everyone")))
+
+	_, err = ParseEntities([]byte(`[{"attributes": {}}]`))
+	assert.Error(t, err)
+
+	_, err = ParseEntities([]byte(`{bad json`))
+	assert.Error(t, err)
+}
+
+func TestEvaluateFilter(t *testing.T) {
+	input := map[string]interface{}{
+		"subject": map[string]interface{}{
+			"roles":  []interface{}{"admin", "editor"},
+			"claims": map[string]interface{}{"email": "rick@the-citadel.com"},
+			"level":  float64(5),
+		},
+		"resource": map[string]interface{}{
+			"properties": map[string]interface{}{"ownerID": "rick@the-citadel.com"},
+			"created":    "2024-01-05T10:00:00Z",
+		},
+		"emails": []interface{}{
+			map[string]interface{}{"type": "work", "value": "rick@example.com"},
+		},
+	}
+
+	tests := []struct {
+		rule string
+		want Result
+	}{
+		{"subject.roles co \"admin\"", True},
+		{"subject.roles co \"viewer\"", False},
+		{"subject.roles co \"viewer\" or (subject.roles co \"editor\" and resource.properties.ownerID eq subject.claims.email)", True},
+		{"subject.level gt 3 and subject.level le 5", True},
+		{"subject.level lt 3", False},
+		{"not(subject.level lt 3)", True},
+		{"subject.missing eq \"x\"", Unknown},
+		{"subject.missing eq \"x\" or subject.level eq 5", True},
+		{"subject.missing eq \"x\" and subject.level eq 4", False},
+		{"subject.missing pr", False},
+		{"unknown.value pr", Unknown},
+		{"subject.claims.email ew \"citadel.com\"", True},
+		{"subject.claims.email sw \"morty\"", False},
+		{"resource.created lt 2025-01-01T00:00:00Z", True},
+		{"subject.claims.email in [\"rick@the-citadel.com\",\"beth@the-smiths.com\"]", True},
+		{"emails[type eq \"work\" and value ew \"example.com\"] pr", True},
+		{"emails[type eq \"work\"].value ew \"example.com\"", True},
+		{"emails[type eq \"home\"] pr", False},
+	}
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			ast, err := conditions.ParseExpressionAst(test.rule)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, EvaluateFilter(ast, input))
+		})
+	}
+}
+
+func TestEvaluate_AuthZen(t *testing.T) {
+	policies, err := hexapolicysupport.ParsePolicyFile(filepath.Join(getExampleDir(), "authZen", "data.json"))
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(policies, nil)
+
+	rick := map[string]interface{}{
+		"subject": map[string]interface{}{
+			"roles":  []interface{}{"admin", "evil_genius"},
+			"claims": map[string]interface{}{"email": "rick@the-citadel.com"},
+		},
+	}
+	morty := map[string]interface{}{
+		"subject": map[string]interface{}{
+			"roles":  []interface{}{"editor"},
+			"claims": map[string]interface{}{"email": "morty@the-citadel.com"},
+		},
+		"resource": map[string]interface{}{
+			"properties": map[string]interface{}{"ownerID": "rick@the-citadel.com"},
+		},
+	}
+
+	decision := evaluator.Evaluate(Request{Subject: "User:rick", Action: "can_read_user", Object: "User:morty"})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []string{"GetUsers"}, decision.AllowPolicies)
+
+	decision = evaluator.Evaluate(Request{Action: "can_read_user", Object: "User:morty"})
+	assert.False(t, decision.Allowed, "anonymous not allowed")
+	assert.Equal(t, DecisionDeny, decision.Result())
+
+	decision = evaluator.Evaluate(Request{Subject: "User:rick", Action: "can_delete_todo", Object: "Todo:1", Context: rick})
+	assert.True(t, decision.Allowed)
+
+	decision = evaluator.Evaluate(Request{Subject: "User:morty", Action: "can_delete_todo", Object: "Todo:1", Context: morty})
+	assert.False(t, decision.Allowed, "morty is not the owner")
+	assert.False(t, decision.Indeterminate)
+
+	decision = evaluator.Evaluate(Request{Subject: "User:morty", Action: "can_delete_todo", Object: "Todo:1"})
+	assert.Equal(t, DecisionIndeterminate, decision.Result(), "roles unknown")
+	assert.Equal(t, []string{"DeleteTodo"}, decision.UnknownPolicies)
+
+	decision = evaluator.Evaluate(Request{Subject: "User:morty", Action: "can_create_todo", Object: "Todo:"})
+	assert.False(t, decision.Allowed)
+	decision = evaluator.Evaluate(Request{Subject: "User:morty", Action: "can_create_todo", Object: "Todo:", Context: map[string]interface{}{
+		"subject": map[string]interface{}{"roles": []interface{}{"editor"}},
+	}})
+	assert.True(t, decision.Allowed, "role:editor matches subject roles")
+}
+
+func TestEvaluate_EntitiesAndDeny(t *testing.T) {
+	dir, err := ParseEntityFile(filepath.Join(getExampleDir(), "policyInfoModels", "photoEntities.json"))
+	assert.NoError(t, err)
+
+	policyBytes := []byte(`{"policies":[
+  {"meta": {"policyId": "friends"}, "subjects": ["[PhotoApp:UserGroup:alice_friends]"], "actions": ["viewPhoto"], "object": "PhotoApp:Photo:"},
+  {"meta": {"policyId": "private"}, "subjects": ["any"], "actions": ["viewPhoto"], "object": "Photo:",
+   "condition": {"rule": "resource.private eq true", "action": "deny"}},
+  {"meta": {"policyId": "age"}, "subjects": ["User:"], "actions": ["edit"], "object": "",
+   "condition": {"rule": "subject.personInformation.age ge 21", "action": "allow"}},
+  {"meta": {"policyId": "local"}, "subjects": ["net:127.0.0.1/24"], "actions": ["admin"]}
+]}`)
+	policies, err := hexapolicysupport.ParsePolicies(policyBytes)
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(policies, dir)
+
+	decision := evaluator.Evaluate(Request{Subject: "PhotoApp:User:alice", Action: "viewPhoto", Object: "PhotoApp:Photo:vacationPhoto.jpg"})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []string{"friends"}, decision.AllowPolicies)
+
+	decision = evaluator.Evaluate(Request{Subject: "PhotoApp:User:bob", Action: "viewPhoto", Object: "PhotoApp:Photo:vacationPhoto.jpg"})
+	assert.False(t, decision.Allowed, "bob not in alice_friends")
+
+	decision = evaluator.Evaluate(Request{Subject: "PhotoApp:User:alice", Action: "viewPhoto", Object: "PhotoApp:Photo:secret.jpg",
+		Context: map[string]interface{}{"resource": map[string]interface{}{"private": true}}})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, []string{"private"}, decision.DenyPolicies)
+
+	decision = evaluator.Evaluate(Request{Subject: "PhotoApp:User:alice", Action: "edit", Object: "PhotoApp:Photo:vacationPhoto.jpg"})
+	assert.True(t, decision.Allowed, "alice is 25")
+
+	decision = evaluator.Evaluate(Request{Subject: "PhotoApp:User:alice", Action: "admin", Object: "x",
+		Context: map[string]interface{}{"req": map[string]interface{}{"ip": "127.0.0.5:8080"}}})
+	assert.True(t, decision.Allowed)
+	decision = evaluator.Evaluate(Request{Subject: "PhotoApp:User:alice", Action: "admin", Object: "x",
+		Context: map[string]interface{}{"req": map[string]interface{}{"ip": "10.0.0.1"}}})
+	assert.False(t, decision.Allowed)
+}
+
+func TestParseEntityFile_NotFound(t *testing.T) {
+	_, err := ParseEntityFile(filepath.Join(os.TempDir(), "doesNotExist.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,404 @@
+package decision
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions/parser"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+// Result is a three-valued logic result used when evaluating conditions where some attributes may be unknown
+type Result int
+
+const (
+	False Result = iota
+	True
+	Unknown
+)
+
+func (r Result) String() string {
+	switch r {
+	case True:
+		return "true"
+	case False:
+		return "false"
+	}
+	return "unknown"
+}
+
+func fromBool(b bool) Result {
+	if b {
+		return True
+	}
+	return False
+}
+
+func and(l, r Result) Result {
+	if l == False || r == False {
+		return False
+	}
+	if l == Unknown || r == Unknown {
+		return Unknown
+	}
+	return True
+}
+
+func or(l, r Result) Result {
+	if l == True || r == True {
+		return True
+	}
+	if l == Unknown || r == Unknown {
+		return Unknown
+	}
+	return False
+}
+
+func not(r Result) Result {
+	switch r {
+	case True:
+		return False
+	case False:
+		return True
+	}
+	return Unknown
+}
+
+// filterContext resolves attribute paths used in an IDQL condition for a single request
+type filterContext struct {
+	input     map[string]interface{}
+	directory *EntityDirectory
+}
+
+// EvaluateFilter evaluates a parsed IDQL condition expression against input. Attribute paths (e.g. subject.roles) are
+// resolved by walking the input map. When an attribute is missing, Unknown is returned for the comparison.
+func EvaluateFilter(expression parser.Expression, input map[string]interface{}) Result {
+	ctx := &filterContext{input: input}
+	return ctx.evaluate(expression)
+}
+
+func (c *filterContext) evaluate(expression parser.Expression) Result {
+	switch exp := expression.(type) {
+	case parser.LogicalExpression:
+		left := c.evaluate(exp.Left)
+		if exp.Operator == parser.AND {
+			if left == False {
+				return False
+			}
+			return and(left, c.evaluate(exp.Right))
+		}
+		if left == True {
+			return True
+		}
+		return or(left, c.evaluate(exp.Right))
+	case parser.NotExpression:
+		return not(c.evaluate(exp.Expression))
+	case parser.PrecedenceExpression:
+		return c.evaluate(exp.Expression)
+	case parser.AttributeExpression:
+		return c.evaluateAttribute(exp)
+	case parser.ValuePathExpression:
+		return c.evaluateValuePath(exp)
+	}
+	return Unknown
+}
+
+// isAttributePath returns true when the entity value is an attribute reference (e.g. subject.roles) rather than an
+// entity literal (e.g. Group:admins)
+func isAttributePath(entity types.Entity) bool {
+	return entity.Type == types.RelTypeEquals && len(entity.Types) == 0 && entity.Id != nil && entity.IsPath()
+}
+
+// resolve returns the raw value of an operand and whether it is known
+func (c *filterContext) resolve(value types.Value) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	switch v := value.(type) {
+	case types.Entity:
+		if isAttributePath(v) {
+			return lookupPath(c.input, *v.Id)
+		}
+		return v, true
+	case types.Array:
+		return v.Value(), true
+	default:
+		return v.Value(), true
+	}
+}
+
+// lookupPath walks a dotted attribute path within a nested map. Matching of keys is case-insensitive.
+func lookupPath(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		val, ok := obj[part]
+		if !ok {
+			found := false
+			for k, v := range obj {
+				if strings.EqualFold(k, part) {
+					val = v
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		}
+		current = val
+	}
+	return current, true
+}
+
+// toComparable converts a raw attribute value into an IDQL comparable value
+func toComparable(raw interface{}) (types.ComparableValue, bool) {
+	switch v := raw.(type) {
+	case types.ComparableValue:
+		return v, true
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			date, _ := types.NewDate(v)
+			return date, true
+		}
+		return types.NewString("\"" + v + "\""), true
+	case bool:
+		return types.NewBoolean(strconv.FormatBool(v)), true
+	case float64:
+		num, err := types.NewNumeric(strconv.FormatFloat(v, 'f', -1, 64))
+		return num, err == nil
+	case int:
+		num, err := types.NewNumeric(strconv.Itoa(v))
+		return num, err == nil
+	case int64:
+		num, err := types.NewNumeric(strconv.FormatInt(v, 10))
+		return num, err == nil
+	case time.Time:
+		date, err := types.NewDate(v.Format(time.RFC3339))
+		return date, err == nil
+	case types.Entity:
+		return types.NewString("\"" + v.String() + "\""), true
+	}
+	return nil, false
+}
+
+func toList(raw interface{}) ([]interface{}, bool) {
+	switch v := raw.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		res := make([]interface{}, len(v))
+		for i, s := range v {
+			res[i] = s
+		}
+		return res, true
+	case []types.ComparableValue:
+		res := make([]interface{}, len(v))
+		for i, s := range v {
+			res[i] = s
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+func compareRaw(left, right interface{}, op string) Result {
+	lv, ok := toComparable(left)
+	if !ok {
+		return Unknown
+	}
+	rv, ok := toComparable(right)
+	if !ok {
+		return Unknown
+	}
+	// Dates provided as strings need to be compared as dates
+	if lv.ValueType() == types.TypeDate && rv.ValueType() == types.TypeString {
+		if d, err := types.NewDate(fmt.Sprint(rv.Value())); err == nil {
+			rv = d
+		}
+	}
+	res, incompatible := types.CompareValues(lv, rv, op)
+	if incompatible {
+		return Unknown
+	}
+	return fromBool(res)
+}
+
+func (c *filterContext) evaluateAttribute(exp parser.AttributeExpression) Result {
+	op := string(exp.Operator)
+	left, known := c.resolve(exp.AttributePath)
+
+	if op == types.PR {
+		if !known {
+			// the attribute path could not be found in the input. If the root of the input is present, not present is definitive.
+			return c.absentResult(exp.AttributePath)
+		}
+		if left == nil {
+			return False
+		}
+		if s, ok := left.(string); ok {
+			return fromBool(s != "")
+		}
+		if list, ok := toList(left); ok {
+			return fromBool(len(list) > 0)
+		}
+		return True
+	}
+
+	if !known {
+		return Unknown
+	}
+	right, rKnown := c.resolve(exp.CompareValue)
+	if !rKnown {
+		return Unknown
+	}
+
+	switch op {
+	case types.IS:
+		leftEntity, ok := left.(types.Entity)
+		if !ok {
+			leftEntity = *types.ParseEntity(fmt.Sprint(left))
+		}
+		rightType := strings.TrimSuffix(fmt.Sprint(exp.CompareValue.String()), ":")
+		return fromBool(strings.EqualFold(leftEntity.GetType(), rightType) ||
+			strings.EqualFold(strings.Join(leftEntity.Types, ":"), rightType))
+	case types.IN:
+		if entity, ok := right.(types.Entity); ok {
+			return c.evaluateMembership(left, entity)
+		}
+		if list, ok := toList(right); ok {
+			res := False
+			for _, item := range list {
+				res = or(res, compareRaw(left, item, types.EQ))
+				if res == True {
+					return True
+				}
+			}
+			return res
+		}
+	case types.CO:
+		if list, ok := toList(left); ok {
+			res := False
+			for _, item := range list {
+				res = or(res, compareRaw(item, right, types.EQ))
+				if res == True {
+					return True
+				}
+			}
+			return res
+		}
+	}
+
+	// Multi-valued attributes match if any value matches (RFC7644 semantics)
+	if list, ok := toList(left); ok {
+		res := False
+		for _, item := range list {
+			res = or(res, compareRaw(item, right, op))
+			if res == True {
+				return True
+			}
+		}
+		return res
+	}
+	return compareRaw(left, right, op)
+}
+
+// absentResult determines whether a missing attribute means "not present" (False) or unknown data. An attribute is
+// considered known to be absent when its parent object is present in the input.
+func (c *filterContext) absentResult(value types.Value) Result {
+	entity, ok := value.(types.Entity)
+	if !ok || !isAttributePath(entity) {
+		return Unknown
+	}
+	path := *entity.Id
+	index := strings.LastIndex(path, ".")
+	if index < 0 {
+		return Unknown
+	}
+	parent, ok := lookupPath(c.input, path[0:index])
+	if !ok {
+		return Unknown
+	}
+	if _, isMap := parent.(map[string]interface{}); isMap {
+		return False
+	}
+	return Unknown
+}
+
+// evaluateMembership checks whether the left value (an entity reference) is a member of the entity group
+func (c *filterContext) evaluateMembership(left interface{}, group types.Entity) Result {
+	var id string
+	switch v := left.(type) {
+	case types.Entity:
+		id = v.String()
+	case string:
+		id = v
+	case map[string]interface{}:
+		if entityId, ok := v[keyId].(string); ok {
+			id = entityId
+		}
+	}
+	if id == "" {
+		return Unknown
+	}
+	if group.Type == types.RelTypeIn || group.Type == types.RelTypeIsIn {
+		res := False
+		for _, member := range *group.In {
+			res = or(res, c.evaluateMembership(left, member))
+		}
+		return res
+	}
+	if sameEntity(*types.ParseEntity(id), group) {
+		return True
+	}
+	if c.directory == nil || c.directory.Get(id) == nil {
+		return Unknown
+	}
+	return fromBool(c.directory.IsMemberOf(id, group))
+}
+
+func (c *filterContext) evaluateValuePath(exp parser.ValuePathExpression) Result {
+	raw, ok := lookupPath(c.input, exp.Attribute.String())
+	if !ok {
+		return Unknown
+	}
+	list, ok := toList(raw)
+	if !ok {
+		list = []interface{}{raw}
+	}
+	res := False
+	for _, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sub := &filterContext{input: obj, directory: c.directory}
+		match := sub.evaluate(exp.VPathFilter)
+		if match == True && exp.SubAttr != nil && exp.Operator != nil {
+			subValue, found := lookupPath(obj, *exp.SubAttr)
+			if !found {
+				match = False
+				if *exp.Operator != parser.PR {
+					match = Unknown
+				}
+			} else if *exp.Operator != parser.PR {
+				compareValue, known := c.resolve(exp.CompareValue)
+				if !known {
+					match = Unknown
+				} else {
+					match = compareRaw(subValue, compareValue, string(*exp.Operator))
+				}
+			}
+		}
+		res = or(res, match)
+		if res == True {
+			return True
+		}
+	}
+	return res
+}
@@ -0,0 +1,220 @@
+package decision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+const (
+	ImpactGained      = "GAINED"      // ImpactGained indicates a request that was denied is now allowed
+	ImpactLost        = "LOST"        // ImpactLost indicates a request that was allowed is now denied
+	ImpactConditional = "CONDITIONAL" // ImpactConditional indicates the decision changed but depends on attributes that are not known
+
+	// placeholderId is used to represent "any member" of an entity type when no specific entity is known
+	placeholderId = "*"
+)
+
+// ImpactDif describes a request whose decision differs between two policy sets
+type ImpactDif struct {
+	Type           string   // Type is one of ImpactGained, ImpactLost, ImpactConditional
+	Subject        string   // Subject of the request. An empty value is an anonymous subject
+	Action         string   // Action of the request
+	Object         string   // Object of the request
+	Before         string   // Before is the decision (DecisionAllow, DecisionDeny, DecisionIndeterminate) under the original policies
+	After          string   // After is the decision under the new policies
+	BeforePolicies []string // BeforePolicies are the policies that determined the original decision
+	AfterPolicies  []string // AfterPolicies are the policies that determined the new decision
+}
+
+func (d *ImpactDif) Report() string {
+	subject := d.Subject
+	if subject == "" {
+		subject = "<anonymous>"
+	}
+	return fmt.Sprintf("IMPACT: %s subject: %s, action: %s, object: %s (%s -> %s) policies: %v -> %v",
+		d.Type, subject, d.Action, d.Object, d.Before, d.After, d.BeforePolicies, d.AfterPolicies)
+}
+
+// AnalyzeImpact compares the decisions of oldPolicies and newPolicies for every combination of subject, action and
+// object referenced by either policy set (plus entities in directory, which may be nil) and returns the requests
+// whose decision changes. Where a policy only refers to an entity type (e.g. `User:`), a placeholder entity (`User:*`)
+// is used to represent any member of the type.
+func AnalyzeImpact(oldPolicies, newPolicies []hexapolicy.PolicyInfo, directory *EntityDirectory) []ImpactDif {
+	all := append(append([]hexapolicy.PolicyInfo{}, oldPolicies...), newPolicies...)
+	subjects, actions, objects := enumerateRequests(all, directory)
+
+	before := NewEvaluator(oldPolicies, directory)
+	after := NewEvaluator(newPolicies, directory)
+
+	res := make([]ImpactDif, 0)
+	for _, subject := range subjects {
+		for _, action := range actions {
+			for _, object := range objects {
+				req := Request{Subject: subject, Action: action, Object: object}
+				oldDecision := before.Evaluate(req)
+				newDecision := after.Evaluate(req)
+				oldResult := oldDecision.Result()
+				newResult := newDecision.Result()
+				if oldResult == newResult {
+					continue
+				}
+				impactType := ImpactConditional
+				switch {
+				case oldResult == DecisionIndeterminate || newResult == DecisionIndeterminate:
+				case newResult == DecisionAllow:
+					impactType = ImpactGained
+				default:
+					impactType = ImpactLost
+				}
+				res = append(res, ImpactDif{
+					Type:           impactType,
+					Subject:        subject,
+					Action:         action,
+					Object:         object,
+					Before:         oldResult,
+					After:          newResult,
+					BeforePolicies: decidingPolicies(oldDecision),
+					AfterPolicies:  decidingPolicies(newDecision),
+				})
+			}
+		}
+	}
+	return res
+}
+
+func decidingPolicies(decision Decision) []string {
+	var res []string
+	if len(decision.DenyPolicies) > 0 {
+		res = append(res, decision.DenyPolicies...)
+	} else {
+		res = append(res, decision.AllowPolicies...)
+	}
+	if decision.Indeterminate {
+		res = append(res, decision.UnknownPolicies...)
+	}
+	return res
+}
+
+type candidateSet map[string]string
+
+func (c candidateSet) add(id string) {
+	key := strings.ToLower(id)
+	if _, ok := c[key]; !ok {
+		c[key] = id
+	}
+}
+
+func (c candidateSet) sorted() []string {
+	res := make([]string, 0, len(c))
+	for _, v := range c {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// addPattern adds the entities referenced by an IDQL subject or object value and returns the entity types referenced
+func (c candidateSet) addPattern(entity types.Entity, typesUsed map[string]bool) {
+	switch entity.Type {
+	case types.RelTypeEquals:
+		c.add(entity.String())
+		typesUsed[strings.ToLower(entity.GetType())] = true
+	case types.RelTypeIs:
+		c.add(entity.String() + placeholderId)
+		typesUsed[strings.ToLower(entity.GetType())] = true
+	case types.RelTypeIn:
+		for _, member := range *entity.In {
+			c.add(member.String())
+		}
+	case types.RelTypeIsIn:
+		c.add(strings.Join(entity.Types, ":") + ":" + placeholderId)
+		for _, member := range *entity.In {
+			c.add(member.String())
+		}
+		typesUsed[strings.ToLower(entity.GetType())] = true
+	}
+}
+
+func enumerateRequests(policies []hexapolicy.PolicyInfo, directory *EntityDirectory) ([]string, []string, []string) {
+	subjects := candidateSet{}
+	actions := candidateSet{}
+	objects := candidateSet{}
+	subjectTypes := map[string]bool{}
+	objectTypes := map[string]bool{}
+	var objectGroups []types.Entity
+	broadSubjects := false
+	anyAction := false
+	anyObject := false
+
+	// the anonymous subject is always a candidate
+	subjects.add("")
+
+	for _, policy := range policies {
+		if len(policy.Subjects) == 0 {
+			broadSubjects = true
+		}
+		for _, member := range policy.Subjects {
+			entity := types.ParseEntity(member)
+			switch entity.Type {
+			case types.RelTypeAny, types.RelTypeAnyAuthenticated:
+				broadSubjects = true
+			case types.RelTypeIn:
+				broadSubjects = true
+			}
+			subjects.addPattern(*entity, subjectTypes)
+		}
+
+		if len(policy.Actions) == 0 {
+			anyAction = true
+		}
+		for _, action := range policy.Actions {
+			actions.add(string(action))
+		}
+
+		if policy.Object == "" {
+			anyObject = true
+			continue
+		}
+		entity := policy.Object.Entity()
+		objects.addPattern(*entity, objectTypes)
+		if entity.In != nil {
+			objectGroups = append(objectGroups, *entity.In...)
+		}
+	}
+
+	if broadSubjects {
+		// represents an authenticated subject not otherwise known
+		subjects.add(placeholderId)
+	}
+	if anyAction || len(actions) == 0 {
+		actions.add(placeholderId)
+	}
+	if anyObject || len(objects) == 0 {
+		objects.add(placeholderId)
+	}
+
+	if directory != nil {
+		for _, info := range directory.Entities() {
+			entityType := strings.ToLower(info.Entity().GetType())
+			if subjectTypes[entityType] || (broadSubjects && !objectTypes[entityType]) {
+				subjects.add(info.Id)
+			}
+			if objectTypes[entityType] {
+				objects.add(info.Id)
+				continue
+			}
+			for _, group := range objectGroups {
+				if directory.IsMemberOf(info.Id, group) {
+					objects.add(info.Id)
+					break
+				}
+			}
+		}
+	}
+
+	return subjects.sorted(), actions.sorted(), objects.sorted()
+}
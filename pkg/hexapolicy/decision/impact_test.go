@@ -0,0 +1,92 @@
+package decision
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func findImpact(difs []ImpactDif, subject, action, object string) *ImpactDif {
+	for _, dif := range difs {
+		if dif.Subject == subject && dif.Action == action && dif.Object == object {
+			return &dif
+		}
+	}
+	return nil
+}
+
+func TestAnalyzeImpact(t *testing.T) {
+	oldBytes := []byte(`{"policies":[
+  {"meta": {"policyId": "p1"}, "subjects": ["User:alice", "User:bob"], "actions": ["read"], "object": "Doc:1"},
+  {"meta": {"policyId": "p2"}, "subjects": ["User:"], "actions": ["list"], "object": "Doc:"}
+]}`)
+	newBytes := []byte(`{"policies":[
+  {"meta": {"policyId": "p1"}, "subjects": ["User:alice", "User:carol"], "actions": ["read"], "object": "Doc:1"},
+  {"meta": {"policyId": "p2"}, "subjects": ["User:"], "actions": ["list"], "object": "Doc:"}
+]}`)
+	oldPolicies, err := hexapolicysupport.ParsePolicies(oldBytes)
+	assert.NoError(t, err)
+	newPolicies, err := hexapolicysupport.ParsePolicies(newBytes)
+	assert.NoError(t, err)
+
+	// Textually p1 was updated, but only bob and carol's access changed
+	difs := AnalyzeImpact(oldPolicies, newPolicies, nil)
+	assert.Len(t, difs, 2)
+
+	bob := findImpact(difs, "User:bob", "read", "Doc:1")
+	assert.NotNil(t, bob)
+	assert.Equal(t, ImpactLost, bob.Type)
+	assert.Equal(t, []string{"p1"}, bob.BeforePolicies)
+	assert.Empty(t, bob.AfterPolicies)
+
+	carol := findImpact(difs, "User:carol", "read", "Doc:1")
+	assert.NotNil(t, carol)
+	assert.Equal(t, ImpactGained, carol.Type)
+	assert.Contains(t, carol.Report(), "IMPACT: GAINED subject: User:carol, action: read, object: Doc:1 (DENY -> ALLOW)")
+
+	// No changes
+	difs = AnalyzeImpact(oldPolicies, oldPolicies, nil)
+	assert.Empty(t, difs)
+}
+
+func TestAnalyzeImpact_Conditions(t *testing.T) {
+	oldPolicies := []hexapolicy.PolicyInfo{}
+	newBytes := []byte(`[
+  {"meta": {"policyId": "todo"}, "subjects": ["anyAuthenticated"], "actions": ["can_delete_todo"], "object": "Todo:",
+   "condition": {"rule": "subject.roles co \"admin\"", "action": "allow"}}
+]`)
+	newPolicies, err := hexapolicysupport.ParsePolicies(newBytes)
+	assert.NoError(t, err)
+
+	difs := AnalyzeImpact(oldPolicies, newPolicies, nil)
+	assert.Len(t, difs, 1)
+	assert.Equal(t, ImpactConditional, difs[0].Type)
+	assert.Equal(t, placeholderId, difs[0].Subject)
+	assert.Equal(t, "Todo:*", difs[0].Object)
+	assert.Equal(t, DecisionIndeterminate, difs[0].After)
+}
+
+func TestAnalyzeImpact_Entities(t *testing.T) {
+	dir, err := ParseEntityFile(filepath.Join(getExampleDir(), "policyInfoModels", "photoEntities.json"))
+	assert.NoError(t, err)
+
+	oldPolicies, err := hexapolicysupport.ParsePolicies([]byte(`[
+  {"meta": {"policyId": "view"}, "subjects": ["[PhotoApp:UserGroup:AVTeam]"], "actions": ["viewPhoto"], "object": "PhotoApp:Photo:"}
+]`))
+	assert.NoError(t, err)
+	newPolicies, err := hexapolicysupport.ParsePolicies([]byte(`[
+  {"meta": {"policyId": "view"}, "subjects": ["[PhotoApp:UserGroup:AVTeam]"], "actions": ["viewPhoto"], "object": "PhotoApp:Photo:"},
+  {"meta": {"policyId": "noPublic"}, "subjects": ["any"], "actions": ["viewPhoto"], "object": "PhotoApp:Photo:",
+   "condition": {"rule": "resource.private eq false", "action": "deny"}}
+]`))
+	assert.NoError(t, err)
+
+	difs := AnalyzeImpact(oldPolicies, newPolicies, dir)
+	alice := findImpact(difs, "PhotoApp:User:alice", "viewPhoto", "PhotoApp:Photo:vacationPhoto.jpg")
+	assert.NotNil(t, alice, "alice is a member of AVTeam and vacationPhoto is not private")
+	assert.Equal(t, ImpactLost, alice.Type)
+	assert.Equal(t, []string{"noPublic"}, alice.AfterPolicies)
+}
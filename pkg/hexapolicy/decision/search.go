@@ -0,0 +1,84 @@
+package decision
+
+import (
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+)
+
+/*
+SubjectCandidates returns the subjects of entityType (e.g. User or PhotoApp:User) that are referenced by policy subjects
+(including members of subject sets) or known in the directory. Candidates are returned in IDQL entity form sorted by
+identifier.
+*/
+func (e *Evaluator) SubjectCandidates(entityType string) []string {
+	candidates := candidateSet{}
+	for _, item := range e.policies {
+		for _, member := range item.policy.Subjects {
+			candidates.addTyped(*types.ParseEntity(member), entityType)
+		}
+	}
+	e.addDirectoryCandidates(candidates, entityType)
+	return candidates.sorted()
+}
+
+// ObjectCandidates returns the objects of entityType that are referenced by policy objects or known in the directory.
+// See SubjectCandidates.
+func (e *Evaluator) ObjectCandidates(entityType string) []string {
+	candidates := candidateSet{}
+	for _, item := range e.policies {
+		if item.policy.Object != "" {
+			candidates.addTyped(*item.policy.Object.Entity(), entityType)
+		}
+	}
+	e.addDirectoryCandidates(candidates, entityType)
+	return candidates.sorted()
+}
+
+// ActionCandidates returns the actions referenced by policies sorted by name
+func (e *Evaluator) ActionCandidates() []string {
+	candidates := candidateSet{}
+	for _, item := range e.policies {
+		for _, action := range item.policy.Actions {
+			candidates.add(string(action))
+		}
+	}
+	return candidates.sorted()
+}
+
+func (e *Evaluator) addDirectoryCandidates(candidates candidateSet, entityType string) {
+	if e.directory == nil {
+		return
+	}
+	for _, info := range e.directory.Entities() {
+		if isEntityType(info.Entity(), entityType) {
+			candidates.add(info.Id)
+		}
+	}
+}
+
+// addTyped adds the specific entities of entityType referenced by an IDQL subject or object value
+func (c candidateSet) addTyped(entity types.Entity, entityType string) {
+	switch entity.Type {
+	case types.RelTypeEquals:
+		if isEntityType(entity, entityType) {
+			c.add(entity.String())
+		}
+	case types.RelTypeIn:
+		for _, member := range *entity.In {
+			c.addTyped(member, entityType)
+		}
+	}
+}
+
+// isEntityType returns true if entity is of entityType. The namespace is only compared when entityType has one.
+func isEntityType(entity types.Entity, entityType string) bool {
+	if entity.Id == nil || len(entity.Types) == 0 {
+		return false
+	}
+	typePath := strings.Split(entityType, ":")
+	if len(typePath) > 1 {
+		return strings.EqualFold(strings.Join(entity.Types, ":"), entityType)
+	}
+	return strings.EqualFold(entity.GetType(), entityType)
+}
@@ -0,0 +1,28 @@
+package decision
+
+import (
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidates(t *testing.T) {
+	policies, err := hexapolicysupport.ParsePolicies([]byte(`[
+  {"meta": {"policyId": "p1"}, "subjects": ["User:alice", "Group:admins"], "actions": ["read", "write"], "object": "Doc:1"},
+  {"meta": {"policyId": "p2"}, "subjects": ["[User:bob,User:carol]"], "actions": ["list"], "object": "Folder:a"}
+]`))
+	assert.NoError(t, err)
+	directory := NewEntityDirectory([]EntityInfo{
+		{Id: "User:dave", Parents: []string{"Group:admins"}},
+		{Id: "Doc:2"},
+	})
+	evaluator := NewEvaluator(policies, directory)
+
+	assert.Equal(t, []string{"User:alice", "User:bob", "User:carol", "User:dave"}, evaluator.SubjectCandidates("User"))
+	assert.Equal(t, []string{"Group:admins"}, evaluator.SubjectCandidates("group"))
+	assert.Equal(t, []string{"Doc:1", "Doc:2"}, evaluator.ObjectCandidates("Doc"))
+	assert.Equal(t, []string{"Folder:a"}, evaluator.ObjectCandidates("Folder"))
+	assert.Empty(t, evaluator.ObjectCandidates("Photo"))
+	assert.Equal(t, []string{"list", "read", "write"}, evaluator.ActionCandidates())
+}
@@ -333,16 +333,10 @@ func (v *Validator) checkOperand(operand types.Value) (string, error) {
 			return "error", errors.New(fmt.Sprintf("invalid namespace \"%s\" for %s", namespace, value.String()))
 		}
 
-		if slices.Contains(specialEntities, strings.ToLower(*value.Id)) { // checks for subject, principal, action, resource
+		if isSpecialEntity(value) {
 			return policyInfoModel.TypeRecord, nil
 		}
 
-		for _, t := range specialEntities {
-			if strings.HasPrefix(*value.Id, fmt.Sprintf("%s%s", t, ".")) { // checks for attributes on special entities
-				return policyInfoModel.TypeRecord, nil
-			}
-		}
-
 		eTypeId := value.GetType()
 		_, ok = schema.EntityTypes[eTypeId]
 		if !ok {
@@ -370,6 +364,160 @@ func (v *Validator) checkOperand(operand types.Value) (string, error) {
 	return "error", errors.New("invalid operand")
 }
 
+// isSpecialEntity returns true for subject, principal, action and resource and their attributes which are not defined
+// by the schema
+func isSpecialEntity(value types.Entity) bool {
+	if slices.Contains(specialEntities, strings.ToLower(*value.Id)) {
+		return true
+	}
+	for _, t := range specialEntities {
+		if strings.HasPrefix(*value.Id, fmt.Sprintf("%s%s", t, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkComparison checks that the operand types (lType and rType) are valid for the expression operator
+func checkComparison(expression string, operator parser.CompareOperator, left string, lType string, right string, rType string) []error {
+	var errs []error
+	switch operator {
+	case parser.PR:
+		// do nothing
+	case parser.EQ, parser.NE, parser.GT, parser.GE, parser.LT, parser.LE:
+		// can only compare like types
+		if !strings.EqualFold(lType, rType) {
+			errs = append(errs, errors.New(fmt.Sprintf("expression \"%s\" has mis-matched attribute types: %s and %s", expression, lType, rType)))
+		}
+	case parser.SW, parser.EW:
+		if !strings.EqualFold(lType, policyInfoModel.TypeString) {
+			errs = append(errs, errors.New(fmt.Sprintf("expression \"%s\" requires String comparators (%s is %s)", expression, left, lType)))
+		}
+		if !strings.EqualFold(rType, policyInfoModel.TypeString) {
+			errs = append(errs, errors.New(fmt.Sprintf("expression \"%s\" requires String comparators (%s is %s)", expression, right, rType)))
+		}
+	case parser.CO, parser.IN:
+		errFmt := "expression \"%s\" requires an Entity or String comparator (%s is %s)"
+		if !strings.EqualFold(lType, policyInfoModel.TypeRecord) && !strings.EqualFold(lType, policyInfoModel.TypeString) {
+			errs = append(errs, errors.New(fmt.Sprintf(errFmt, expression, left, lType)))
+		} else if !strings.EqualFold(rType, policyInfoModel.TypeRecord) && !strings.EqualFold(rType, policyInfoModel.TypeString) {
+			errs = append(errs, errors.New(fmt.Sprintf(errFmt, expression, right, rType)))
+		}
+	}
+	return errs
+}
+
+// findElementAttr locates a sub-attribute of a Set element. The element may be a Record or a common type.
+func findElementAttr(element policyInfoModel.AttrType, name string, schema policyInfoModel.SchemaType) *policyInfoModel.AttrType {
+	if element.Attributes == nil {
+		cType, ok := schema.CommonTypes[element.Type]
+		if !ok {
+			return nil
+		}
+		return cType.FindAttrTypes(name, schema)
+	}
+	return element.FindAttrTypes(name, schema)
+}
+
+/*
+checkValuePath validates a valuePath expression (e.g. User:emails[type eq "work"].value ew "@example.com"). The main
+attribute must be a Set of Records. Attributes in the filter and the sub-attribute are checked against the Record
+element, and the comparisons are type checked as for attribute expressions. Value paths on subject, resource, etc. are
+not defined by the schema and only the comparison value is checked.
+*/
+func (v *Validator) checkValuePath(exp parser.ValuePathExpression) []error {
+	if _, err := v.checkOperand(exp.Attribute); err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	rType := "na"
+	if exp.CompareValue != nil {
+		var err error
+		if rType, err = v.checkOperand(exp.CompareValue); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if isSpecialEntity(exp.Attribute) {
+		return errs
+	}
+
+	schema := v.namespaces[exp.Attribute.GetNamespace(v.defNamespace)]
+	attr := schema.FindAttrType(exp.Attribute)
+	if attr == nil || !strings.EqualFold(attr.Type, policyInfoModel.TypeSet) || attr.Element == nil {
+		attrType := policyInfoModel.TypeRecord
+		if attr != nil {
+			attrType = attr.Type
+		}
+		return append(errs, errors.New(fmt.Sprintf("valuePath attribute %s must be a Set of Records (is %s)", exp.Attribute.String(), attrType)))
+	}
+	element := *attr.Element
+
+	errs = append(errs, v.checkValueFilter(exp.VPathFilter, exp.Attribute, element, schema)...)
+
+	if exp.SubAttr == nil {
+		if exp.Operator != nil && *exp.Operator != parser.PR {
+			errs = append(errs, errors.New(fmt.Sprintf("valuePath expression \"%s\" requires a sub-attribute to compare", exp.String())))
+		}
+		return errs
+	}
+	subAttr := findElementAttr(element, *exp.SubAttr, schema)
+	if subAttr == nil {
+		return append(errs, errors.New(fmt.Sprintf("invalid valuePath sub-attribute: %s.%s", exp.Attribute.String(), *exp.SubAttr)))
+	}
+	if exp.Operator != nil && exp.CompareValue != nil && rType != "error" {
+		errs = append(errs, checkComparison(exp.String(), *exp.Operator, *exp.SubAttr, subAttr.Type, exp.CompareValue.String(), rType)...)
+	}
+	return errs
+}
+
+// checkValueFilter checks a valuePath filter where attributes are relative to the Set element
+func (v *Validator) checkValueFilter(filter parser.Expression, attribute types.Entity, element policyInfoModel.AttrType, schema policyInfoModel.SchemaType) []error {
+	var errs []error
+	switch exp := filter.(type) {
+	case parser.LogicalExpression:
+		errs = append(errs, v.checkValueFilter(exp.Left, attribute, element, schema)...)
+		errs = append(errs, v.checkValueFilter(exp.Right, attribute, element, schema)...)
+	case parser.NotExpression:
+		errs = append(errs, v.checkValueFilter(exp.Expression, attribute, element, schema)...)
+	case parser.PrecedenceExpression:
+		errs = append(errs, v.checkValueFilter(exp.Expression, attribute, element, schema)...)
+	case parser.AttributeExpression:
+		operandType := func(operand types.Value) string {
+			entity, ok := operand.(types.Entity)
+			if ok && len(entity.Types) == 0 && entity.IsPath() {
+				subAttr := findElementAttr(element, entity.String(), schema)
+				if subAttr == nil {
+					errs = append(errs, errors.New(fmt.Sprintf("invalid valuePath filter attribute: %s.%s", attribute.String(), entity.String())))
+					return "error"
+				}
+				return subAttr.Type
+			}
+			oType, err := v.checkOperand(operand)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			return oType
+		}
+		lType := operandType(exp.AttributePath)
+		rType := "na"
+		if exp.CompareValue != nil {
+			rType = operandType(exp.CompareValue)
+		}
+		if errs != nil {
+			break
+		}
+		right := ""
+		if exp.CompareValue != nil {
+			right = exp.CompareValue.String()
+		}
+		errs = append(errs, checkComparison(exp.String(), exp.Operator, exp.AttributePath.String(), lType, right, rType)...)
+	default:
+		errs = append(errs, errors.New(fmt.Sprintf("unsupported valuePath filter \"%s\"", filter.String())))
+	}
+	return errs
+}
+
 func (v *Validator) checkExpression(expression parser.Expression) []error {
 	var errs []error
 	switch exp := expression.(type) {
@@ -388,38 +536,14 @@ func (v *Validator) checkExpression(expression parser.Expression) []error {
 		if errs != nil {
 			break
 		}
-
-		switch exp.Operator {
-		case parser.PR:
-			// do nothing
-		case parser.EQ, parser.NE, parser.GT, parser.GE, parser.LT, parser.LE:
-			// can only compare like types
-			if !strings.EqualFold(lType, rType) {
-				errs = append(errs, errors.New(fmt.Sprintf("expression \"%s\" has mis-matched attribute types: %s and %s", expression.String(), lType, rType)))
-			}
-		case parser.SW, parser.EW:
-			if !strings.EqualFold(lType, policyInfoModel.TypeString) {
-				errs = append(errs, errors.New(fmt.Sprintf("expression \"%s\" requires String comparators (%s is %s)", expression.String(), exp.AttributePath.String(), lType)))
-			}
-			if !strings.EqualFold(rType, policyInfoModel.TypeString) {
-				errs = append(errs, errors.New(fmt.Sprintf("expression \"%s\" requires String comparators (%s is %s)", expression.String(), exp.CompareValue.String(), rType)))
-			}
-		case parser.CO, parser.IN:
-			errFmt := "expression \"%s\" requires an Entity or String comparator (%s is %s)"
-			if !strings.EqualFold(lType, policyInfoModel.TypeRecord) && !strings.EqualFold(lType, policyInfoModel.TypeString) {
-				errs = append(errs, errors.New(fmt.Sprintf(errFmt, expression.String(), exp.AttributePath.String(), lType)))
-			} else if !strings.EqualFold(rType, policyInfoModel.TypeRecord) && !strings.EqualFold(rType, policyInfoModel.TypeString) {
-				errs = append(errs, errors.New(fmt.Sprintf(errFmt, expression.String(), exp.CompareValue.String(), rType)))
-			}
+		right := ""
+		if exp.CompareValue != nil {
+			right = exp.CompareValue.String()
 		}
+		errs = checkComparison(expression.String(), exp.Operator, exp.AttributePath.String(), lType, right, rType)
 
 	case parser.ValuePathExpression:
-		// TODO Need to verify
-		// 1. Main attribute is valid
-		// 2. Each attribute in the filter is valid
-		// 3. if specified, the sub-attribute is valid
-		// 4. The comparison is valid.
-		errs = append(errs, errors.New(fmt.Sprintf("valuePath expressions \"%s\" are not supported", exp.String())))
+		errs = v.checkValuePath(exp)
 
 	}
 	return errs
@@ -418,9 +418,71 @@ func TestValidate_Policy(t *testing.T) {
 		    "rule": "User:emails[type eq \"work\"].value ew \"@example.com\"",
 		    "action": "allow"
 		  }
+		}`,
+			wantErrs: nil,
+		},
+		{name: "ValuePath filter",
+			idql: `{
+		  "meta": {
+		    "version": "0.7"
+		  },
+		  "subjects": [
+		    "User:alice"
+		  ],
+		  "actions": [
+		    "Action:viewPhoto"
+		  ],
+		  "object": "Photo:VacationPhoto.jpg",
+		  "condition": {
+		    "rule": "User:emails[type eq \"work\" and not(primary eq true)] and subject.emails[type eq \"home\"]",
+		    "action": "allow"
+		  }
+		}`,
+			wantErrs: nil,
+		},
+		{name: "ValuePath invalid",
+			idql: `{
+		  "meta": {
+		    "version": "0.7"
+		  },
+		  "subjects": [
+		    "User:alice"
+		  ],
+		  "actions": [
+		    "Action:viewPhoto"
+		  ],
+		  "object": "Photo:VacationPhoto.jpg",
+		  "condition": {
+		    "rule": "User:emails[kind eq \"work\" or primary eq \"yes\"].address pr and User:lastReviewed[type eq \"work\"]",
+		    "action": "allow"
+		  }
+		}`,
+			wantErrs: []error{
+				errors.New("invalid valuePath filter attribute: User:emails.kind"),
+				errors.New("expression \"primary eq \"yes\"\" has mis-matched attribute types: Bool and String"),
+				errors.New("invalid valuePath sub-attribute: User:emails.address"),
+				errors.New("valuePath attribute User:lastReviewed must be a Set of Records (is Date)"),
+			},
+		},
+		{name: "ValuePath compare",
+			idql: `{
+		  "meta": {
+		    "version": "0.7"
+		  },
+		  "subjects": [
+		    "User:alice"
+		  ],
+		  "actions": [
+		    "Action:viewPhoto"
+		  ],
+		  "object": "Photo:VacationPhoto.jpg",
+		  "condition": {
+		    "rule": "User:emails[type eq \"work\"].value gt 5",
+		    "action": "allow"
+		  }
 		}`,
 			wantErrs: []error{
-				errors.New("valuePath expressions \"User:emails[type eq \"work\"].value ew \"@example.com\"\" are not supported"),
+				errors.New("expression \"User:emails[type eq \"work\"].value gt 5\" has mis-matched attribute types: String and Long"),
 			},
 		},
 		{name: "Invalid Object Type",
@@ -1,7 +1,9 @@
 package avpProvider
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"slices"
 	"strings"
@@ -59,6 +61,12 @@ type (
 	AmazonAvpProvider struct {
 		AwsClientOpts awscommon.AWSClientOptions
 		CedarMapper   *cedar.CedarMapper
+		// Model is the policy information model used to validate policies before they are provisioned. If nil, the
+		// schema of the AVP policy store is used (if one is defined).
+		Model *policyInfoModel.Namespaces
+		// AbortOnInvalid causes SetPolicyInfo to make no changes if any policy fails validation. Otherwise, invalid
+		// policies are skipped and the remaining changes are applied.
+		AbortOnInvalid bool
 	}
 )
 
@@ -96,6 +104,17 @@ func (a AmazonAvpProvider) DiscoverApplications(info policyprovider.IntegrationI
 	return client.ListStores()
 }
 
+// mapCedarStatement maps an AVP policy statement to IDQL. Policies that cannot be mapped are logged and skipped.
+func (a AmazonAvpProvider) mapCedarStatement(avpPolicy types.PolicyItem, applicationInfo policyprovider.ApplicationInfo, statement string) (*hexapolicy.Policies, error) {
+	mapPols, err := a.CedarMapper.MapCedarPolicyBytes(applicationInfo.ObjectID, []byte(statement))
+	var report cedar.MappingReport
+	if errors.As(err, &report) {
+		log.Printf("avp: skipping policy %s, %s", *avpPolicy.PolicyId, report.Error())
+		return mapPols, nil
+	}
+	return mapPols, err
+}
+
 func (a AmazonAvpProvider) mapAvpPolicyToHexa(avpPolicy types.PolicyItem, client avpClient.AvpClient, applicationInfo policyprovider.ApplicationInfo) ([]hexapolicy.PolicyInfo, error) {
 	hexaPols := make([]hexapolicy.PolicyInfo, 0)
 	policyType := avpPolicy.PolicyType
@@ -109,14 +128,19 @@ func (a AmazonAvpProvider) mapAvpPolicyToHexa(avpPolicy types.PolicyItem, client
 		policyDefinition := output.Definition
 		policyStatic := policyDefinition.(*types.PolicyDefinitionDetailMemberStatic).Value
 		cedarPolicy := policyStatic.Statement
-		mapPols, err := a.CedarMapper.MapCedarPolicyBytes(applicationInfo.ObjectID, []byte(*cedarPolicy))
-		if err != nil {
-			return nil, err
+		mapPols, err := a.mapCedarStatement(avpPolicy, applicationInfo, *cedarPolicy)
+		if err != nil || len(mapPols.Policies) == 0 {
+			return hexaPols, err
 		}
 		hexaPolicy := mapPols.Policies[0]
 
-		// Update IDQL Meta
+		// Update IDQL Meta, keeping the Cedar source so that unchanged policies are not rewritten
 		avpMeta := MapAvpMeta(avpPolicy)
+		for _, key := range []string{cedar.SourceDataCedar, cedar.SourceDataAnnotations} {
+			if value, ok := hexaPolicy.Meta.SourceData[key]; ok {
+				avpMeta.SourceData[key] = value
+			}
+		}
 		hexaPolicy.Meta = avpMeta
 		hexaPolicy.Meta.Description = *policyStatic.Description
 		hexaPolicy.CalculateEtag()
@@ -140,9 +164,9 @@ func (a AmazonAvpProvider) mapAvpPolicyToHexa(avpPolicy types.PolicyItem, client
 		policyString = strings.Replace(policyString, "?principal", "Template::\"principal\"", -1)
 		policyString = strings.Replace(policyString, "?resource", "Template::\"resource\"", -1)
 
-		mapPols, err := a.CedarMapper.MapCedarPolicyBytes(applicationInfo.ObjectID, []byte(policyString))
-		if err != nil {
-			return nil, err
+		mapPols, err := a.mapCedarStatement(avpPolicy, applicationInfo, policyString)
+		if err != nil || len(mapPols.Policies) == 0 {
+			return hexaPols, err
 		}
 		hexaPolicy := mapPols.Policies[0]
 
@@ -297,7 +321,27 @@ func (a AmazonAvpProvider) SetPolicyInfo(info policyprovider.IntegrationInfo, ap
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	for _, dif := range differences {
+
+	model := a.Model
+	if model == nil {
+		model, err = a.GetSchema(info, applicationInfo)
+		if err != nil {
+			log.Printf("Unable to retrieve AVP schema, policies will not be validated against a schema: %s", err)
+		}
+	}
+	invalid, report := a.validateDifferences(model, differences)
+	if len(report) > 0 {
+		fmt.Println(report.Error())
+		if a.AbortOnInvalid {
+			return http.StatusBadRequest, report
+		}
+	}
+
+	for i, dif := range differences {
+		if invalid[i] {
+			fmt.Printf("AVP policy %s skipped (failed validation)\n", policyIdentifier(*dif.PolicyCompare))
+			continue
+		}
 		switch dif.Type {
 
 		case hexapolicy.ChangeTypeNew:
@@ -0,0 +1,120 @@
+package avpProvider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cedar-policy/cedar-go"
+	cedarMapper "github.com/hexa-org/policy-mapper/models/formats/cedar"
+	"github.com/hexa-org/policy-mapper/models/policyInfoModel"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/pimValidate"
+)
+
+// PolicyValidationError lists the problems found with the Cedar generated for an IDQL policy
+type PolicyValidationError struct {
+	Policy string // the AVP policy id, or the etag of a new policy
+	Errors []string
+}
+
+// ValidationReport is returned by SetPolicyInfo when AbortOnInvalid is set and one or more policies are invalid
+type ValidationReport []PolicyValidationError
+
+func (r ValidationReport) Error() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%d policies failed validation:", len(r)))
+	for _, policyErr := range r {
+		sb.WriteString(fmt.Sprintf("\n%s:\n  %s", policyErr.Policy, strings.Join(policyErr.Errors, "\n  ")))
+	}
+	return sb.String()
+}
+
+/*
+ValidatePolicy validates the Cedar that would be provisioned to AVP for an IDQL policy. The Cedar is parsed with cedar-go
+and, when a model is provided, mapped back to IDQL and checked against the model with pimValidate. Returns nil if valid.
+*/
+func (a AmazonAvpProvider) ValidatePolicy(model *policyInfoModel.Namespaces, hexaPolicy hexapolicy.PolicyInfo) []string {
+	if a.CedarMapper == nil {
+		a.CedarMapper = cedarMapper.NewCedarMapper(map[string]string{})
+	}
+	statement, err := a.convertCedarStatement(hexaPolicy)
+	if err != nil {
+		return []string{fmt.Sprintf("unable to map policy to cedar: %s", err)}
+	}
+	cedarPolicies, err := cedar.NewPolicyListFromBytes("", []byte(*statement))
+	if err != nil {
+		return []string{fmt.Sprintf("invalid cedar: %s", err)}
+	}
+	if model == nil || len(*model) == 0 {
+		return nil
+	}
+
+	mapped, err := a.CedarMapper.MapCedarPolicyBytes("", []byte(*statement))
+	var report cedarMapper.MappingReport
+	if errors.As(err, &report) {
+		var errs []string
+		for _, issue := range report {
+			errs = append(errs, issue.Message)
+		}
+		return errs
+	}
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	validator := pimValidate.GetValidator(*model, defaultNamespace(*model))
+	var errs []string
+	for i, policy := range mapped.Policies {
+		for _, valErr := range validator.ValidatePolicy(policy, i) {
+			for _, e := range valErr.Errors() {
+				errs = append(errs, fmt.Sprintf("%s %s: %s", valErr.ElementName, valErr.Value, e))
+			}
+		}
+	}
+	if len(cedarPolicies) != len(mapped.Policies) {
+		errs = append(errs, fmt.Sprintf("expected %d cedar policies, mapped %d", len(cedarPolicies), len(mapped.Policies)))
+	}
+	return errs
+}
+
+// defaultNamespace returns the namespace of a model with a single namespace
+func defaultNamespace(model policyInfoModel.Namespaces) string {
+	if len(model) != 1 {
+		return ""
+	}
+	for namespace := range model {
+		return namespace
+	}
+	return ""
+}
+
+/*
+validateDifferences validates the policies to be created or updated. The model is a.Model or, if not set, the schema of
+the AVP policy store (if any). Returns the indexes of invalid differences and a report of the errors.
+*/
+func (a AmazonAvpProvider) validateDifferences(model *policyInfoModel.Namespaces, differences []hexapolicy.PolicyDif) (map[int]bool, ValidationReport) {
+	invalid := map[int]bool{}
+	var report ValidationReport
+	for i, dif := range differences {
+		if dif.Type != hexapolicy.ChangeTypeNew && dif.Type != hexapolicy.ChangeTypeUpdate {
+			continue
+		}
+		hexaPolicy := *dif.PolicyCompare
+		if isTemplate(hexaPolicy) {
+			continue
+		}
+		if errs := a.ValidatePolicy(model, hexaPolicy); len(errs) > 0 {
+			invalid[i] = true
+			report = append(report, PolicyValidationError{Policy: policyIdentifier(hexaPolicy), Errors: errs})
+		}
+	}
+	return invalid, report
+}
+
+func policyIdentifier(hexaPolicy hexapolicy.PolicyInfo) string {
+	if hexaPolicy.Meta.PolicyId != nil {
+		return *hexaPolicy.Meta.PolicyId
+	}
+	return fmt.Sprintf("etag: %s", hexaPolicy.CalculateEtag())
+}
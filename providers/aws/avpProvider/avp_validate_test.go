@@ -0,0 +1,71 @@
+package avpProvider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/models/policyInfoModel"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	"github.com/stretchr/testify/assert"
+)
+
+const validatePolicies = `{
+  "policies": [
+    {
+      "meta": {"version": "0.7", "policyId": "valid"},
+      "subjects": ["PhotoApp:User:alice"],
+      "actions": ["PhotoApp:Action:viewPhoto"],
+      "object": "PhotoApp:Photo:vacationPhoto.jpg",
+      "condition": {"rule": "resource in PhotoApp:Account:\"stacey\"", "action": "allow"}
+    },
+    {
+      "meta": {"version": "0.7", "policyId": "badObject"},
+      "subjects": ["PhotoApp:User:alice"],
+      "actions": ["PhotoApp:Action:viewPhoto"],
+      "object": "PhotoApp:BadPhoto:vacationPhoto.jpg"
+    },
+    {
+      "meta": {"version": "0.7", "policyId": "badAction"},
+      "subjects": ["PhotoApp:User:alice"],
+      "actions": ["PhotoApp:Action:sharePhoto"],
+      "object": "PhotoApp:Photo:vacationPhoto.jpg"
+    },
+    {
+      "meta": {"version": "0.7", "policyId": "badCondition"},
+      "subjects": ["PhotoApp:User:alice"],
+      "actions": ["PhotoApp:Action:viewPhoto"],
+      "object": "PhotoApp:Photo:vacationPhoto.jpg",
+      "condition": {"rule": "resource in PhotoApp:Folder:\"stacey\"", "action": "allow"}
+    }
+  ]
+}`
+
+func TestValidatePolicy(t *testing.T) {
+	schemaBytes, err := os.ReadFile(filepath.Join("..", "..", "..", "examples", "policyInfoModels", "photoSchema.json"))
+	assert.NoError(t, err)
+	model, err := policyInfoModel.ParseSchemaFile(schemaBytes)
+	assert.NoError(t, err)
+	policies, err := hexapolicysupport.ParsePolicies([]byte(validatePolicies))
+	assert.NoError(t, err)
+
+	provider := AmazonAvpProvider{}
+	assert.Empty(t, provider.ValidatePolicy(model, policies[0]))
+	assert.NotEmpty(t, provider.ValidatePolicy(model, policies[1]), "invalid object type")
+	assert.NotEmpty(t, provider.ValidatePolicy(model, policies[2]), "invalid action")
+	assert.NotEmpty(t, provider.ValidatePolicy(model, policies[3]), "invalid condition entity type")
+
+	// without a model only the cedar syntax is checked
+	assert.Empty(t, provider.ValidatePolicy(nil, policies[1]))
+
+	var differences []hexapolicy.PolicyDif
+	for i := range policies {
+		differences = append(differences, hexapolicy.PolicyDif{Type: hexapolicy.ChangeTypeNew, PolicyCompare: &policies[i]})
+	}
+	invalid, report := provider.validateDifferences(model, differences)
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true}, invalid)
+	assert.Len(t, report, 3)
+	assert.Equal(t, "badObject", report[0].Policy)
+	assert.Contains(t, report.Error(), "3 policies failed validation")
+}
@@ -90,6 +90,8 @@ type AzureAppRoleAssignment struct {
 	PrincipalType        string `json:"principalType"`
 	ResourceDisplayName  string `json:"resourceDisplayName"`
 	ResourceId           string `json:"resourceId" validate:"required"`
+	Condition            string `json:"condition,omitempty"`        // Condition is an Azure ABAC condition (read only, Graph cannot set it)
+	ConditionVersion     string `json:"conditionVersion,omitempty"` // ConditionVersion is the condition language version (e.g. 2.0)
 }
 
 func NewAzureClient(httpClient azurecommon.HTTPClient) AzureClient {
@@ -263,6 +265,12 @@ func (c *azureClient) SetAppRoleAssignedTo(key []byte, servicePrincipalId string
 		log.Println("Validate error ", vErr)
 		return vErr
 	}
+	for _, assignment := range assignments {
+		if assignment.Condition != "" {
+			// Microsoft Graph app role assignments have no condition, posting one would grant unconditional access
+			return fmt.Errorf("app role %s: conditions are not supported for Microsoft Graph app role assignments", assignment.AppRoleId)
+		}
+	}
 	existingRoleAssignments, err := c.GetAppRoleAssignedTo(key, servicePrincipalId)
 	if err != nil {
 		log.Println("Unable to get azure app role assignments. Error=" + err.Error())
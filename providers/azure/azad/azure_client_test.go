@@ -496,6 +496,23 @@ func TestAzureClient_SetAppRoleAssignedTo_InvalidAppRoleAssignment(t *testing.T)
 	}
 }
 
+func TestAzureClient_SetAppRoleAssignedTo_WithCondition(t *testing.T) {
+	m := azuretestsupport.NewAzureHttpClient()
+	client := m.AzureClient()
+
+	conditioned := azuretestsupport.NewAppRoleAssignments(azuretestsupport.AppRoleIdGetHrUs, policytestsupport.UserIdGetProfile)
+	conditioned.Condition = "@Principal[department] StringEquals 'hr'"
+	conditioned.ConditionVersion = "2.0"
+
+	err := client.SetAppRoleAssignedTo(
+		azuretestsupport.AzureKeyBytes(),
+		azuretestsupport.ServicePrincipalId,
+		append([]azad.AzureAppRoleAssignment{conditioned}, azuretestsupport.AppRoleAssignmentGetHrUs...))
+	assert.Error(t, err)
+	assert.ErrorContains(t, err, "conditions are not supported")
+	assert.Empty(t, m.MockHttpClient.Called, "no assignment is removed or added")
+}
+
 func TestAzureClient_SetAppRoleAssignedTo_WithBadGetAssignments(t *testing.T) {
 	m := azuretestsupport.NewAzureHttpClient()
 	m.TokenRequest("accessToken")
@@ -635,6 +652,26 @@ func TestAzureClient_SetAppRoleAssignedTo_DoesNotAddExistingAssignment(t *testin
 	assert.True(t, m.MockHttpClient.VerifyCalled())
 }
 
+func TestAzureClient_SetAppRoleAssignedTo_DoesNotReplaceConditionalAssignment(t *testing.T) {
+	m := azuretestsupport.NewAzureHttpClient()
+	existingAssignments := make([]azad.AzureAppRoleAssignment, len(azuretestsupport.AppRoleAssignmentGetHrUs))
+	copy(existingAssignments, azuretestsupport.AppRoleAssignmentGetHrUs)
+	existingAssignments[0].Condition = "@Principal[department] StringEquals 'hr'"
+	existingAssignments[0].ConditionVersion = "2.0"
+
+	m.TokenRequest("accessToken")
+	m.GetAppRoleAssignmentsRequest(existingAssignments)
+
+	// there is no mock response for adding or deleting an assignment, so replacing the assignment would fail
+	client := m.AzureClient()
+	err := client.SetAppRoleAssignedTo(
+		azuretestsupport.AzureKeyBytes(),
+		azuretestsupport.ServicePrincipalId,
+		azuretestsupport.AssignmentsWithoutId(azuretestsupport.AppRoleAssignmentGetHrUs))
+	assert.NoError(t, err)
+	assert.True(t, m.MockHttpClient.VerifyCalled())
+}
+
 func TestAzureClient_SetAppRoleAssignedTo_DoesNotDeleteMemberInPolicy(t *testing.T) {
 	m := azuretestsupport.NewAzureHttpClient()
 
@@ -2,16 +2,23 @@ package azureProvider
 
 import (
     "fmt"
+    "log"
 
+    "github.com/hexa-org/policy-mapper/models/conditionLangs/azureabac"
     "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
     "github.com/hexa-org/policy-mapper/providers/azure/azad"
 )
 
+// SourceDataCondition holds an Azure assignment condition that could not be mapped to IDQL, so that it is not lost
+const SourceDataCondition = "condition"
+
 type AzurePolicyMapper struct {
     objectId             string
     roleIdToAppRole      map[string]azad.AzureAppRole
     existingRoleIdToAras map[string][]azad.AzureAppRoleAssignment
     azureUserEmail       map[string]string
+    conditionMapper      azureabac.AzureAbacConditionMapper
 }
 
 func NewAzurePolicyMapper(sps azad.AzureServicePrincipals, existingAssignments []azad.AzureAppRoleAssignment, azureUserEmail map[string]string) *AzurePolicyMapper {
@@ -23,19 +30,61 @@ func NewAzurePolicyMapper(sps azad.AzureServicePrincipals, existingAssignments [
         objectId:             sps.List[0].Name,
         roleIdToAppRole:      mapAppRoles(sps.List[0].AppRoles),
         existingRoleIdToAras: mapAppRoleAssignments(existingAssignments),
-        azureUserEmail:       azureUserEmail}
+        azureUserEmail:       azureUserEmail,
+        conditionMapper:      azureabac.AzureAbacConditionMapper{NameMapper: conditions.NewNameMapper(map[string]string{})}}
 }
 
+// ToIDQL returns a policy for each app role. Assignments with an Azure ABAC condition are returned as an additional
+// policy for each distinct condition.
 func (azm *AzurePolicyMapper) ToIDQL() []hexapolicy.PolicyInfo {
     policies := make([]hexapolicy.PolicyInfo, 0)
     for appRoleId, appRole := range azm.roleIdToAppRole {
-        pol := azm.appRoleAssignmentToIDQL(azm.existingRoleIdToAras[appRoleId], appRole)
-        policies = append(policies, pol)
+        var unconditioned []azad.AzureAppRoleAssignment
+        var conditionOrder []string
+        conditioned := make(map[string][]azad.AzureAppRoleAssignment)
+        for _, assignment := range azm.existingRoleIdToAras[appRoleId] {
+            if assignment.Condition == "" {
+                unconditioned = append(unconditioned, assignment)
+                continue
+            }
+            if _, exists := conditioned[assignment.Condition]; !exists {
+                conditionOrder = append(conditionOrder, assignment.Condition)
+            }
+            conditioned[assignment.Condition] = append(conditioned[assignment.Condition], assignment)
+        }
+
+        policies = append(policies, azm.appRoleAssignmentToIDQL(unconditioned, appRole))
+        for i, condition := range conditionOrder {
+            pol := azm.appRoleAssignmentToIDQL(conditioned[condition], appRole)
+            policyId := fmt.Sprintf("%s-%d", appRole.ID, i+1)
+            pol.Meta.PolicyId = &policyId
+            idqlCondition, err := azm.conditionMapper.MapProviderToCondition(condition)
+            if err != nil {
+                log.Printf("Unable to map condition of app role %s assignment: %s", appRole.Value, err.Error())
+                pol.Meta.SourceData[SourceDataCondition] = condition
+            } else {
+                pol.Condition = &idqlCondition
+            }
+            policies = append(policies, pol)
+        }
     }
     return policies
 
 }
 
+// ToAzureCondition maps the policy condition to an Azure ABAC condition (as used by Azure RBAC role assignments). A
+// condition that could not be mapped to IDQL is returned from the policy source data.
+func (azm *AzurePolicyMapper) ToAzureCondition(policy hexapolicy.PolicyInfo) (string, error) {
+    if policy.Condition == nil {
+        condition, _ := policy.Meta.SourceData[SourceDataCondition].(string)
+        return condition, nil
+    }
+    if policy.Condition.Action == conditions.ADeny {
+        return "", fmt.Errorf("deny conditions are not supported by Azure (%s)", policy.Condition.Rule)
+    }
+    return azm.conditionMapper.MapConditionToProvider(*policy.Condition)
+}
+
 func (azm *AzurePolicyMapper) appRoleAssignmentToIDQL(assignments []azad.AzureAppRoleAssignment, role azad.AzureAppRole) hexapolicy.PolicyInfo {
 
     members := make([]string, 0)
@@ -5,6 +5,8 @@ import (
     "testing"
 
     "github.com/hexa-org/policy-mapper/models/rar/testsupport/policytestsupport"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
     "github.com/hexa-org/policy-mapper/providers/azure/azad"
     "github.com/hexa-org/policy-mapper/providers/azure/azureProvider"
     "github.com/hexa-org/policy-mapper/providers/azure/azuretestsupport"
@@ -71,3 +73,41 @@ func TestAzurePolicyMapper_ToIDQL_NoAppRoles(t *testing.T) {
     assert.NotNil(t, actPolicies)
     assert.Equal(t, 0, len(actPolicies))
 }
+
+func TestAzurePolicyMapper_ToIDQL_WithConditions(t *testing.T) {
+    principalEmails := policytestsupport.MakePrincipalEmailMap()
+    conditioned := azuretestsupport.NewAppRoleAssignments(azuretestsupport.AppRoleIdGetHrUs, policytestsupport.UserIdGetProfile)
+    conditioned.Condition = "@Principal[department] StringEquals 'hr'"
+    unmapped := azuretestsupport.NewAppRoleAssignments(azuretestsupport.AppRoleIdGetHrUs, policytestsupport.UserIdGetHrUsAndProfile)
+    unmapped.Condition = "@Request[address] IpMatch '10.0.0.0/8'"
+    roleAssignments := append([]azad.AzureAppRoleAssignment{conditioned, unmapped}, azuretestsupport.AppRoleAssignmentGetHrUs...)
+
+    sps := azuretestsupport.AzureServicePrincipals()
+    mapper := azureProvider.NewAzurePolicyMapper(sps, roleAssignments, principalEmails)
+    actPolicies := mapper.ToIDQL()
+    assert.Equal(t, len(sps.List[0].AppRoles)+2, len(actPolicies))
+
+    policies := make(map[string]hexapolicy.PolicyInfo)
+    for _, pol := range actPolicies {
+        policies[*pol.Meta.PolicyId] = pol
+    }
+    roleId := string(azuretestsupport.AppRoleIdGetHrUs)
+    assert.Equal(t, hexapolicy.SubjectInfo{"user:" + policytestsupport.UserEmailGetHrUs}, policies[roleId].Subjects)
+    assert.Nil(t, policies[roleId].Condition)
+
+    pol := policies[roleId+"-1"]
+    assert.Equal(t, hexapolicy.SubjectInfo{"user:" + policytestsupport.UserEmailGetProfile}, pol.Subjects)
+    assert.Equal(t, policytestsupport.ActionGetHrUs, pol.Actions[0].String())
+    assert.Equal(t, &conditions.ConditionInfo{Rule: "subject.department eq \"hr\"", Action: conditions.AAllow}, pol.Condition)
+    condition, err := mapper.ToAzureCondition(pol)
+    assert.NoError(t, err)
+    assert.Equal(t, conditioned.Condition, condition)
+
+    pol = policies[roleId+"-2"]
+    assert.Equal(t, hexapolicy.SubjectInfo{"user:" + policytestsupport.UserEmailGetHrUsAndProfile}, pol.Subjects)
+    assert.Nil(t, pol.Condition)
+    assert.Equal(t, unmapped.Condition, pol.Meta.SourceData[azureProvider.SourceDataCondition])
+    condition, err = mapper.ToAzureCondition(pol)
+    assert.NoError(t, err)
+    assert.Equal(t, unmapped.Condition, condition)
+}
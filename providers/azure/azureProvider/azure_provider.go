@@ -2,6 +2,7 @@ package azureProvider
 
 import (
     "errors"
+    "fmt"
     "log"
     "net/http"
     "strings"
@@ -102,6 +103,9 @@ func (a *AzureProvider) SetPolicyInfo(integrationInfo policyprovider.Integration
         appRoleValueToId[ara.Value] = ara.ID
     }
 
+    // assignments are set once per app role so that several policies for the same role are combined
+    var appRoleIds []string
+    roleAssignments := make(map[string][]azad.AzureAppRoleAssignment)
     for _, policyInfo := range policyInfos {
         var assignments []azad.AzureAppRoleAssignment
 
@@ -112,6 +116,11 @@ func (a *AzureProvider) SetPolicyInfo(integrationInfo policyprovider.Integration
             continue
         }
 
+        // Microsoft Graph app role assignments cannot hold a condition, so writing one would widen access
+        if _, hasSourceCondition := policyInfo.Meta.SourceData[SourceDataCondition]; policyInfo.Condition != nil || hasSourceCondition {
+            return http.StatusBadRequest, fmt.Errorf("policy for %s has a condition: conditions cannot be set on Azure app role assignments", actionUri)
+        }
+
         if len(policyInfo.Subjects) == 0 {
             assignments = append(assignments, azad.AzureAppRoleAssignment{
                 AppRoleId:  appRoleId,
@@ -135,8 +144,14 @@ func (a *AzureProvider) SetPolicyInfo(integrationInfo policyprovider.Integration
         if len(assignments) == 0 {
             continue
         }
+        if _, exists := roleAssignments[appRoleId]; !exists {
+            appRoleIds = append(appRoleIds, appRoleId)
+        }
+        roleAssignments[appRoleId] = append(roleAssignments[appRoleId], assignments...)
+    }
 
-        err := a.client.SetAppRoleAssignedTo(key, sps.List[0].ID, assignments)
+    for _, appRoleId := range appRoleIds {
+        err := a.client.SetAppRoleAssignedTo(key, sps.List[0].ID, roleAssignments[appRoleId])
         if err != nil {
             return http.StatusInternalServerError, err
         }
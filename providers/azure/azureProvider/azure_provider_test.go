@@ -8,6 +8,7 @@ import (
     "github.com/hexa-org/policy-mapper/api/policyprovider"
     "github.com/hexa-org/policy-mapper/models/rar/testsupport/policytestsupport"
     "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
     "github.com/hexa-org/policy-mapper/providers/azure/azad"
     "github.com/hexa-org/policy-mapper/providers/azure/azureProvider"
     "github.com/hexa-org/policy-mapper/providers/azure/azuretestsupport"
@@ -384,3 +385,61 @@ func TestSetPolicy_MultipleAppRolePolicies(t *testing.T) {
     assert.Equal(t, http.StatusCreated, status)
     mockAzClient.AssertExpectations(t)
 }
+
+func TestSetPolicy_WithCondition(t *testing.T) {
+    appId := azuretestsupport.AzureAppId
+    key := azuretestsupport.AzureKeyBytes()
+
+    // no assignment is set because app role assignments cannot hold the condition
+    mockAzClient := azuretestsupport.NewMockAzureClient()
+    mockAzClient.ExpectGetServicePrincipals()
+    mockAzClient.ExpectGetPrincipalIdFromEmail(policytestsupport.UserEmailGetHrUs, policytestsupport.UserIdGetHrUs)
+
+    p := azureProvider.NewAzureProvider(azureProvider.WithAzureClient(mockAzClient))
+    status, err := p.SetPolicyInfo(
+        policyprovider.IntegrationInfo{Name: "azure", Key: key},
+        policyprovider.ApplicationInfo{ObjectID: "anObjectId", Name: "anAppName", Description: appId},
+        []hexapolicy.PolicyInfo{
+            {
+                Meta:     hexapolicy.MetaInfo{Version: "0"},
+                Actions:  []hexapolicy.ActionInfo{"azure:" + policytestsupport.ActionGetHrUs},
+                Subjects: []string{"user:" + policytestsupport.UserEmailGetHrUs},
+                Object:   policytestsupport.PolicyObjectResourceId,
+            },
+            {
+                Meta:      hexapolicy.MetaInfo{Version: "0"},
+                Actions:   []hexapolicy.ActionInfo{"azure:" + policytestsupport.ActionGetHrUs},
+                Subjects:  []string{"user:" + policytestsupport.UserEmailGetProfile},
+                Object:    policytestsupport.PolicyObjectResourceId,
+                Condition: &conditions.ConditionInfo{Rule: "subject.department eq \"hr\"", Action: conditions.AAllow},
+            },
+        })
+
+    assert.ErrorContains(t, err, "conditions cannot be set on Azure app role assignments")
+    assert.Equal(t, http.StatusBadRequest, status)
+    mockAzClient.AssertExpectations(t)
+}
+
+func TestSetPolicy_WithDenyCondition(t *testing.T) {
+    appId := azuretestsupport.AzureAppId
+    key := azuretestsupport.AzureKeyBytes()
+
+    mockAzClient := azuretestsupport.NewMockAzureClient()
+    mockAzClient.ExpectGetServicePrincipals()
+
+    p := azureProvider.NewAzureProvider(azureProvider.WithAzureClient(mockAzClient))
+    status, err := p.SetPolicyInfo(
+        policyprovider.IntegrationInfo{Name: "azure", Key: key},
+        policyprovider.ApplicationInfo{ObjectID: "anObjectId", Name: "anAppName", Description: appId},
+        []hexapolicy.PolicyInfo{{
+            Meta:      hexapolicy.MetaInfo{Version: "0"},
+            Actions:   []hexapolicy.ActionInfo{"azure:" + policytestsupport.ActionGetHrUs},
+            Subjects:  []string{"user:" + policytestsupport.UserEmailGetHrUs},
+            Object:    policytestsupport.PolicyObjectResourceId,
+            Condition: &conditions.ConditionInfo{Rule: "subject.department eq \"hr\"", Action: conditions.ADeny},
+        }})
+
+    assert.Error(t, err)
+    assert.Equal(t, http.StatusBadRequest, status)
+    mockAzClient.AssertExpectations(t)
+}
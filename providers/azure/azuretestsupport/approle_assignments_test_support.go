@@ -57,9 +57,11 @@ func AssignmentsWithoutId(assignments []azad.AzureAppRoleAssignment) []azad.Azur
 	newAssignments := make([]azad.AzureAppRoleAssignment, 0)
 	for _, ara := range assignments {
 		newAra := azad.AzureAppRoleAssignment{
-			AppRoleId:   ara.AppRoleId,
-			PrincipalId: ara.PrincipalId,
-			ResourceId:  ara.ResourceId,
+			AppRoleId:        ara.AppRoleId,
+			PrincipalId:      ara.PrincipalId,
+			ResourceId:       ara.ResourceId,
+			Condition:        ara.Condition,
+			ConditionVersion: ara.ConditionVersion,
 		}
 
 		newAssignments = append(newAssignments, newAra)
@@ -0,0 +1,408 @@
+/*
+Package fileProvider implements a policy provider over a local directory (optionally a Git working tree). Each
+subdirectory of the directory is an application containing a single policy file. The format of the file is selected by
+its extension:
+
+  - `.cedar` - Cedar policy
+  - `.rego` - Rego policy (see models/formats/rego)
+  - `.bindings.json` - Google IAM bindings
+  - `.json` - IDQL policies
+
+Policies are written atomically (a temporary file is renamed over the policy file). When AutoCommit is enabled, each
+change is committed to the Git repository containing the directory (a repository is initialized if there is none).
+*/
+package fileProvider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/models/formats/cedar"
+	"github.com/hexa-org/policy-mapper/models/formats/gcpBind"
+	"github.com/hexa-org/policy-mapper/models/formats/rego"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicysupport"
+	log "golang.org/x/exp/slog"
+)
+
+const (
+	ProviderTypeFile string = "file"
+
+	FormatIdql   string = "idql"
+	FormatCedar  string = "cedar"
+	FormatRego   string = "rego"
+	FormatGcp    string = "gcp"
+	defaultMode         = 0644
+	policyPrefix        = "policies"
+)
+
+// formatExtensions is in match order (the GCP bindings suffix must be checked before .json)
+var formatExtensions = []struct {
+	format    string
+	extension string
+}{
+	{FormatGcp, ".bindings.json"},
+	{FormatIdql, ".json"},
+	{FormatCedar, ".cedar"},
+	{FormatRego, ".rego"},
+}
+
+/*
+Credentials is the integration key for a file provider, for example:
+
+	{"directory": "/policies", "autoCommit": true, "gitUser": "hexa", "gitEmail": "hexa@example.com"}
+*/
+type Credentials struct {
+	Directory     string `json:"directory"`               // Directory holds a subdirectory for each application
+	DefaultFormat string `json:"defaultFormat,omitempty"` // DefaultFormat is the format of new applications (default idql)
+	AutoCommit    bool   `json:"autoCommit,omitempty"`    // AutoCommit commits each policy update to the Git repository
+	GitUser       string `json:"gitUser,omitempty"`       // GitUser overrides the configured Git user.name for commits
+	GitEmail      string `json:"gitEmail,omitempty"`      // GitEmail overrides the configured Git user.email for commits
+}
+
+// FileProvider is a policyprovider.V2Provider for policy files in a local directory
+type FileProvider struct{}
+
+func (f *FileProvider) Name() string {
+	return ProviderTypeFile
+}
+
+func (f *FileProvider) credentials(key []byte) (*Credentials, error) {
+	var creds Credentials
+	if err := json.Unmarshal(key, &creds); err != nil {
+		return nil, fmt.Errorf("invalid file provider key: %w", err)
+	}
+	if creds.Directory == "" {
+		return nil, errors.New("invalid file provider key: missing directory")
+	}
+	if creds.DefaultFormat == "" {
+		creds.DefaultFormat = FormatIdql
+	}
+	if fileExtension(creds.DefaultFormat) == "" {
+		return nil, fmt.Errorf("invalid file provider key: unsupported format %s", creds.DefaultFormat)
+	}
+	return &creds, nil
+}
+
+// DiscoverApplications returns an application for each subdirectory that contains a policy file
+func (f *FileProvider) DiscoverApplications(info policyprovider.IntegrationInfo) ([]policyprovider.ApplicationInfo, error) {
+	creds, err := f.credentials(info.Key)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(creds.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []policyprovider.ApplicationInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path, format, err := policyFile(filepath.Join(creds.Directory, entry.Name()))
+		if err != nil {
+			log.Warn("file provider, skipping application", "app", entry.Name(), "error", err.Error())
+			continue
+		}
+		if path == "" {
+			continue
+		}
+		apps = append(apps, policyprovider.ApplicationInfo{
+			ObjectID:    entry.Name(),
+			Name:        entry.Name(),
+			Description: filepath.Base(path),
+			Service:     format,
+		})
+	}
+	return apps, nil
+}
+
+// GetPolicyInfo reads and maps the application policy file to IDQL
+func (f *FileProvider) GetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) ([]hexapolicy.PolicyInfo, error) {
+	creds, err := f.credentials(info.Key)
+	if err != nil {
+		return nil, err
+	}
+	appDir, err := appDirectory(creds, app)
+	if err != nil {
+		return nil, err
+	}
+	path, format, err := policyFile(appDir)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no policy file found for application %s", app.ObjectID)
+	}
+
+	policyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := readPolicies(path, format, policyBytes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range policies {
+		policies[i].Meta.PapId = &app.ObjectID
+		policies[i].Meta.ProviderType = ProviderTypeFile
+	}
+	return policies, nil
+}
+
+/*
+SetPolicyInfo replaces the policies in the application policy file. If the application does not exist, it is created
+using the format in ApplicationInfo.Service (if one of the supported formats) or the DefaultFormat.
+*/
+func (f *FileProvider) SetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, policies []hexapolicy.PolicyInfo) (int, error) {
+	creds, err := f.credentials(info.Key)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	appDir, err := appDirectory(creds, app)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	path, format, err := policyFile(appDir)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if path == "" {
+		format = creds.DefaultFormat
+		if fileExtension(app.Service) != "" {
+			format = app.Service
+		}
+		path = filepath.Join(appDir, policyPrefix+fileExtension(format))
+	}
+
+	policyBytes, err := writePolicies(path, format, policies)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err = os.MkdirAll(appDir, 0755); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err = writeAtomic(path, policyBytes); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if creds.AutoCommit {
+		if err = commit(creds, path, fmt.Sprintf("Update %s policies", app.ObjectID)); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (f *FileProvider) Reconcile(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, comparePolicies []hexapolicy.PolicyInfo, diffsOnly bool) ([]hexapolicy.PolicyDif, error) {
+	existing, err := f.GetPolicyInfo(info, app)
+	if err != nil {
+		return nil, err
+	}
+	existingPolicies := hexapolicy.Policies{Policies: existing, App: &app.ObjectID}
+	return existingPolicies.ReconcilePolicies(comparePolicies, diffsOnly), nil
+}
+
+// appDirectory returns the application directory, ensuring the application id cannot refer outside the directory
+func appDirectory(creds *Credentials, app policyprovider.ApplicationInfo) (string, error) {
+	if app.ObjectID == "" || app.ObjectID != filepath.Base(app.ObjectID) || strings.HasPrefix(app.ObjectID, ".") {
+		return "", fmt.Errorf("invalid application id: %s", app.ObjectID)
+	}
+	return filepath.Join(creds.Directory, app.ObjectID), nil
+}
+
+// policyFile returns the policy file in an application directory and its format. An empty path is returned if there
+// is none.
+func policyFile(appDir string) (string, string, error) {
+	entries, err := os.ReadDir(appDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	var found []string
+	format := ""
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if entryFormat := fileFormat(entry.Name()); entryFormat != "" {
+			found = append(found, entry.Name())
+			format = entryFormat
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", "", nil
+	case 1:
+		return filepath.Join(appDir, found[0]), format, nil
+	default:
+		slices.Sort(found)
+		return "", "", fmt.Errorf("more than one policy file found in %s: %s", appDir, strings.Join(found, ", "))
+	}
+}
+
+func fileFormat(name string) string {
+	lower := strings.ToLower(name)
+	for _, item := range formatExtensions {
+		if strings.HasSuffix(lower, item.extension) {
+			return item.format
+		}
+	}
+	return ""
+}
+
+func fileExtension(format string) string {
+	for _, item := range formatExtensions {
+		if strings.EqualFold(item.format, format) {
+			return item.extension
+		}
+	}
+	return ""
+}
+
+func readPolicies(path string, format string, policyBytes []byte) ([]hexapolicy.PolicyInfo, error) {
+	switch format {
+	case FormatCedar:
+		policies, err := cedar.NewCedarMapper(map[string]string{}).MapCedarPolicyBytes(path, policyBytes)
+		if err != nil {
+			return nil, err
+		}
+		return policies.Policies, nil
+	case FormatRego:
+		policies, err := rego.NewRegoMapper(map[string]string{}).MapRegoPolicyBytes(policyBytes)
+		if errors.Is(err, rego.ErrNoRules) {
+			return []hexapolicy.PolicyInfo{}, nil // an empty policy set was written
+		}
+		if err != nil {
+			return nil, err
+		}
+		return policies.Policies, nil
+	case FormatGcp:
+		assignments, err := gcpBind.ParseBindings(policyBytes)
+		if err != nil {
+			return nil, err
+		}
+		return gcpBind.New(map[string]string{}).MapBindingAssignmentsToPolicy(assignments)
+	default:
+		return hexapolicysupport.ParsePolicies(policyBytes)
+	}
+}
+
+func writePolicies(path string, format string, policies []hexapolicy.PolicyInfo) ([]byte, error) {
+	switch format {
+	case FormatCedar:
+		cedarString, err := cedar.NewCedarMapper(map[string]string{}).MapHexaPolicies(path, policies)
+		return []byte(cedarString), err
+	case FormatRego:
+		regoString, err := rego.NewRegoMapper(map[string]string{}).MapHexaPolicies(policies)
+		return []byte(regoString), err
+	case FormatGcp:
+		return writeBindings(policies)
+	default:
+		return json.MarshalIndent(hexapolicy.Policies{Policies: policies}, "", "  ")
+	}
+}
+
+// writeBindings maps each policy to a binding of its object. Unlike MapPoliciesToBindings, policies that cannot be
+// mapped (including deny policies) are rejected rather than dropped.
+func writeBindings(policies []hexapolicy.PolicyInfo) ([]byte, error) {
+	mapper := gcpBind.New(map[string]string{})
+	assignments := []*gcpBind.BindAssignment{}
+	byObject := map[string]*gcpBind.BindAssignment{}
+	for _, policy := range policies {
+		if gcpBind.IsDenyPolicy(policy) {
+			return nil, fmt.Errorf("policy %s: deny policies cannot be mapped to bindings", policyId(policy))
+		}
+		binding, err := mapper.MapPolicyToBinding(policy)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", policyId(policy), err)
+		}
+		assignment, ok := byObject[policy.Object.String()]
+		if !ok {
+			assignment = &gcpBind.BindAssignment{ResourceId: policy.Object.String()}
+			byObject[assignment.ResourceId] = assignment
+			assignments = append(assignments, assignment)
+		}
+		assignment.Bindings = append(assignment.Bindings, *binding)
+	}
+	return json.MarshalIndent(assignments, "", "  ")
+}
+
+func policyId(policy hexapolicy.PolicyInfo) string {
+	if policy.Meta.PolicyId != nil {
+		return *policy.Meta.PolicyId
+	}
+	return policy.Object.String()
+}
+
+// writeAtomic writes to a temporary file in the same directory and renames it over path
+func writeAtomic(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tempName := temp.Name()
+	defer func() { _ = os.Remove(tempName) }()
+
+	if _, err = temp.Write(data); err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempName, defaultMode)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempName, path)
+}
+
+// commit commits path to the Git repository containing the provider directory. Nothing is committed if the file is
+// unchanged.
+func commit(creds *Credentials, path string, message string) error {
+	if _, err := git(creds, "rev-parse", "--show-toplevel"); err != nil {
+		if _, err = git(creds, "init"); err != nil {
+			return err
+		}
+	}
+	if _, err := git(creds, "add", "--", path); err != nil {
+		return err
+	}
+	if _, err := git(creds, "diff", "--cached", "--quiet", "--", path); err == nil {
+		return nil
+	}
+	_, err := git(creds, "commit", "-m", message, "--", path)
+	return err
+}
+
+func git(creds *Credentials, args ...string) (string, error) {
+	var gitArgs []string
+	if creds.GitUser != "" {
+		gitArgs = append(gitArgs, "-c", "user.name="+creds.GitUser)
+	}
+	if creds.GitEmail != "" {
+		gitArgs = append(gitArgs, "-c", "user.email="+creds.GitEmail)
+	}
+	gitArgs = append(gitArgs, args...)
+
+	cmd := exec.Command("git", gitArgs...)
+	cmd.Dir = creds.Directory
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s failed: %s %w", args[0], strings.TrimSpace(string(out)), err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,255 @@
+package fileProvider
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+func getExampleDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "../../examples")
+}
+
+func copyExample(t *testing.T, example string, dest string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(getExampleDir(), "policyExamples", example))
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(dest), 0755))
+	assert.NoError(t, os.WriteFile(dest, data, 0644))
+}
+
+func integration(t *testing.T, creds Credentials) policyprovider.IntegrationInfo {
+	t.Helper()
+	key, err := json.Marshal(creds)
+	assert.NoError(t, err)
+	return policyprovider.IntegrationInfo{Name: ProviderTypeFile, Key: key}
+}
+
+func setupDirectory(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	copyExample(t, "example_idql.json", filepath.Join(dir, "idqlApp", "policies.json"))
+	copyExample(t, "cedarAlice.txt", filepath.Join(dir, "cedarApp", "policies.cedar"))
+	copyExample(t, "example_bindings.json", filepath.Join(dir, "gcpApp", "policies.bindings.json"))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "emptyDir"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	return dir
+}
+
+func TestFileProvider_Credentials(t *testing.T) {
+	provider := FileProvider{}
+	assert.Equal(t, ProviderTypeFile, provider.Name())
+
+	_, err := provider.DiscoverApplications(policyprovider.IntegrationInfo{Name: ProviderTypeFile, Key: []byte("bad")})
+	assert.ErrorContains(t, err, "invalid file provider key")
+
+	_, err = provider.DiscoverApplications(integration(t, Credentials{}))
+	assert.ErrorContains(t, err, "missing directory")
+
+	_, err = provider.DiscoverApplications(integration(t, Credentials{Directory: t.TempDir(), DefaultFormat: "xacml"}))
+	assert.ErrorContains(t, err, "unsupported format xacml")
+}
+
+func TestFileProvider_DiscoverApplications(t *testing.T) {
+	dir := setupDirectory(t)
+	provider := FileProvider{}
+	info := integration(t, Credentials{Directory: dir})
+
+	apps, err := provider.DiscoverApplications(info)
+	assert.NoError(t, err)
+	assert.Len(t, apps, 3)
+
+	formats := map[string]string{}
+	for _, app := range apps {
+		formats[app.ObjectID] = app.Service
+		assert.Equal(t, app.ObjectID, app.Name)
+	}
+	assert.Equal(t, map[string]string{"cedarApp": FormatCedar, "gcpApp": FormatGcp, "idqlApp": FormatIdql}, formats)
+
+	// an application with more than one policy file is skipped
+	copyExample(t, "example_idql.json", filepath.Join(dir, "cedarApp", "policies.json"))
+	apps, err = provider.DiscoverApplications(info)
+	assert.NoError(t, err)
+	assert.Len(t, apps, 2)
+
+	_, err = provider.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "cedarApp"})
+	assert.ErrorContains(t, err, "more than one policy file")
+}
+
+func TestFileProvider_GetSetPolicyInfo(t *testing.T) {
+	dir := setupDirectory(t)
+	provider := FileProvider{}
+	info := integration(t, Credentials{Directory: dir})
+
+	for _, appId := range []string{"idqlApp", "cedarApp", "gcpApp"} {
+		t.Run(appId, func(t *testing.T) {
+			app := policyprovider.ApplicationInfo{ObjectID: appId}
+			policies, err := provider.GetPolicyInfo(info, app)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, policies)
+			assert.Equal(t, ProviderTypeFile, policies[0].Meta.ProviderType)
+			assert.Equal(t, appId, *policies[0].Meta.PapId)
+
+			status, err := provider.SetPolicyInfo(info, app, policies)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+
+			difs, err := provider.Reconcile(info, app, policies, true)
+			assert.NoError(t, err)
+			assert.Empty(t, difs)
+
+			entries, err := os.ReadDir(filepath.Join(dir, appId))
+			assert.NoError(t, err)
+			assert.Len(t, entries, 1, "temporary files should be removed")
+		})
+	}
+
+	_, err := provider.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "emptyDir"})
+	assert.ErrorContains(t, err, "no policy file found")
+
+	_, err = provider.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "../idqlApp"})
+	assert.ErrorContains(t, err, "invalid application id")
+}
+
+func TestFileProvider_NewApplication(t *testing.T) {
+	dir := setupDirectory(t)
+	provider := FileProvider{}
+	info := integration(t, Credentials{Directory: dir, DefaultFormat: FormatCedar})
+
+	policies, err := provider.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "cedarApp"})
+	assert.NoError(t, err)
+
+	// policies that cannot be mapped do not create the application
+	status, err := provider.SetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "badApp", Service: FormatRego}, policies)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NoDirExists(t, filepath.Join(dir, "badApp"))
+
+	// the format is taken from the application service, otherwise the default format is used
+	idqlPolicies, err := provider.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "idqlApp"})
+	assert.NoError(t, err)
+	status, err = provider.SetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "regoApp", Service: FormatRego}, idqlPolicies)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.FileExists(t, filepath.Join(dir, "regoApp", "policies.rego"))
+
+	status, err = provider.SetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "newApp"}, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.FileExists(t, filepath.Join(dir, "newApp", "policies.cedar"))
+
+	regoPolicies, err := provider.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "regoApp"})
+	assert.NoError(t, err)
+	assert.Len(t, regoPolicies, len(idqlPolicies))
+
+	difs, err := provider.Reconcile(info, policyprovider.ApplicationInfo{ObjectID: "newApp"}, policies[0:1], true)
+	assert.NoError(t, err)
+	assert.Len(t, difs, 1)
+	assert.Equal(t, hexapolicy.ChangeTypeDelete, difs[0].Type)
+}
+
+func TestFileProvider_GcpUnmappable(t *testing.T) {
+	dir := setupDirectory(t)
+	provider := FileProvider{}
+	info := integration(t, Credentials{Directory: dir})
+	app := policyprovider.ApplicationInfo{ObjectID: "gcpApp"}
+	path := filepath.Join(dir, "gcpApp", "policies.bindings.json")
+	original := readFile(t, path)
+
+	policies, err := provider.GetPolicyInfo(info, app)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, policies)
+
+	// a deny policy would be dropped from the bindings (widening access), so nothing is written
+	denyId := "denyAll"
+	deny := hexapolicy.PolicyInfo{
+		Meta:      hexapolicy.MetaInfo{PolicyId: &denyId},
+		Subjects:  []string{"user:bob@example.com"},
+		Actions:   []hexapolicy.ActionInfo{"roles/viewer"},
+		Object:    policies[0].Object,
+		Condition: &conditions.ConditionInfo{Rule: "subject pr", Action: conditions.ADeny},
+	}
+	status, err := provider.SetPolicyInfo(info, app, append(policies, deny))
+	assert.ErrorContains(t, err, "policy denyAll: deny policies cannot be mapped to bindings")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, string(original), string(readFile(t, path)))
+
+	// a condition that cannot be mapped to CEL is rejected
+	unmappable := deny
+	unmappable.Condition = &conditions.ConditionInfo{Rule: "resource is Photo", Action: conditions.AAllow}
+	status, err = provider.SetPolicyInfo(info, app, append(policies, unmappable))
+	assert.ErrorContains(t, err, "policy denyAll:")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, string(original), string(readFile(t, path)))
+}
+
+func TestFileProvider_EmptyPolicies(t *testing.T) {
+	dir := setupDirectory(t)
+	provider := FileProvider{}
+	info := integration(t, Credentials{Directory: dir})
+
+	for _, format := range []string{FormatIdql, FormatCedar, FormatRego, FormatGcp} {
+		t.Run(format, func(t *testing.T) {
+			app := policyprovider.ApplicationInfo{ObjectID: format + "Empty", Service: format}
+			status, err := provider.SetPolicyInfo(info, app, []hexapolicy.PolicyInfo{})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+
+			policies, err := provider.GetPolicyInfo(info, app)
+			assert.NoError(t, err)
+			assert.Empty(t, policies)
+		})
+	}
+}
+
+func TestFileProvider_AutoCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	dir := t.TempDir()
+	copyExample(t, "example_idql.json", filepath.Join(dir, "source.json"))
+	provider := FileProvider{}
+	info := integration(t, Credentials{Directory: dir, AutoCommit: true, GitUser: "hexa", GitEmail: "hexa@example.com"})
+
+	policies, err := readPolicies("", FormatIdql, readFile(t, filepath.Join(dir, "source.json")))
+	assert.NoError(t, err)
+
+	app := policyprovider.ApplicationInfo{ObjectID: "app1"}
+	_, err = provider.SetPolicyInfo(info, app, policies)
+	assert.NoError(t, err)
+	assert.DirExists(t, filepath.Join(dir, ".git"))
+
+	// an unchanged update does not create a commit
+	_, err = provider.SetPolicyInfo(info, app, policies)
+	assert.NoError(t, err)
+
+	_, err = provider.SetPolicyInfo(info, app, policies[0:1])
+	assert.NoError(t, err)
+
+	logOut, err := exec.Command("git", "-C", dir, "log", "--format=%an %s").CombinedOutput()
+	assert.NoError(t, err, string(logOut))
+	lines := strings.Split(strings.TrimSpace(string(logOut)), "\n")
+	assert.Equal(t, []string{"hexa Update app1 policies", "hexa Update app1 policies"}, lines)
+
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "app1").CombinedOutput()
+	assert.NoError(t, err)
+	assert.Empty(t, string(status))
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	return data
+}
@@ -7,18 +7,32 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/models/formats/gcpBind"
 	"google.golang.org/api/appengine/v1"
 	"google.golang.org/api/iam/v1"
+	iamv2 "google.golang.org/api/iam/v2"
 )
 
+const iamV2Url = "https://iam.googleapis.com/v2/"
+
+// ServiceDenyPolicies is the service of the application holding the project IAM deny policies
+const ServiceDenyPolicies = "IAM Deny Policies"
+
 type HTTPClient interface {
 	Get(url string) (resp *http.Response, err error)
 	Post(url, contentType string, body io.Reader) (resp *http.Response, err error)
 }
 
+// requestDoer is implemented by HTTP clients (e.g. *http.Client) that can send PUT and DELETE requests, which are
+// needed to write deny policies
+type requestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 type GoogleClient struct {
 	HttpClient HTTPClient
 	ProjectId  string
@@ -144,3 +158,157 @@ func (c *GoogleClient) SetBackendPolicy(name, objectId string, binding *iam.Bind
 	_, err := c.HttpClient.Post(url, "application/json", b)
 	return err
 }
+
+// ProjectAttachmentPoint returns the deny policy attachment point of the project
+func (c *GoogleClient) ProjectAttachmentPoint() string {
+	return "cloudresourcemanager.googleapis.com/projects/" + c.ProjectId
+}
+
+// GetDenyPolicyApplication returns the application that holds the IAM deny policies attached to the project
+func (c *GoogleClient) GetDenyPolicyApplication() policyprovider.ApplicationInfo {
+	return policyprovider.ApplicationInfo{
+		ObjectID:    c.ProjectAttachmentPoint(),
+		Name:        c.ProjectId,
+		Description: "IAM deny policies attached to the project",
+		Service:     ServiceDenyPolicies,
+	}
+}
+
+// GetDenyPolicies returns the IAM deny policies (including rules) attached to the attachment point
+func (c *GoogleClient) GetDenyPolicies(attachmentPoint string) ([]*iamv2.GoogleIamV2Policy, error) {
+	listUrl := fmt.Sprintf("%spolicies/%s/denypolicies", iamV2Url, url.PathEscape(attachmentPoint))
+
+	var denyPolicies []*iamv2.GoogleIamV2Policy
+	pageToken := ""
+	for {
+		reqUrl := listUrl
+		if pageToken != "" {
+			reqUrl = listUrl + "?pageToken=" + url.QueryEscape(pageToken)
+		}
+		get, err := c.HttpClient.Get(reqUrl)
+		if err != nil {
+			log.Println("Unable to find google cloud deny policies.")
+			return nil, err
+		}
+		if get.StatusCode == http.StatusNotFound || get.StatusCode == http.StatusForbidden {
+			// deny policies require the iam.denyReviewer role, which IAP integrations may not have
+			log.Printf("Google cloud deny policies not available (%s).\n", get.Status)
+			return nil, nil
+		}
+		if get.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unable to list google cloud deny policies: %s", get.Status)
+		}
+
+		var list iamv2.GoogleIamV2ListPoliciesResponse
+		if err = json.NewDecoder(get.Body).Decode(&list); err != nil {
+			log.Println("Unable to decode google cloud deny policies.")
+			return nil, err
+		}
+
+		// the list response does not include policy rules
+		for _, listed := range list.Policies {
+			denyPolicy, err := c.getDenyPolicy(listed.Name)
+			if err != nil {
+				return nil, err
+			}
+			if denyPolicy != nil {
+				denyPolicies = append(denyPolicies, denyPolicy)
+			}
+		}
+		if list.NextPageToken == "" {
+			return denyPolicies, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+func (c *GoogleClient) getDenyPolicy(name string) (*iamv2.GoogleIamV2Policy, error) {
+	get, err := c.HttpClient.Get(iamV2Url + name)
+	if err != nil {
+		return nil, err
+	}
+	if get.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if get.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get google cloud deny policy %s: %s", name, get.Status)
+	}
+
+	var denyPolicy iamv2.GoogleIamV2Policy
+	if err = json.NewDecoder(get.Body).Decode(&denyPolicy); err != nil {
+		log.Println("Unable to decode google cloud deny policy.")
+		return nil, err
+	}
+	return &denyPolicy, nil
+}
+
+// SetDenyPolicy creates the deny policy, or replaces the rules of the deny policy when it already exists
+func (c *GoogleClient) SetDenyPolicy(denyPolicy *iamv2.GoogleIamV2Policy) error {
+	attachmentPoint, policyId, err := gcpBind.ParseDenyPolicyName(denyPolicy.Name)
+	if err != nil {
+		return err
+	}
+	existing, err := c.getDenyPolicy(denyPolicy.Name)
+	if err != nil {
+		return err
+	}
+
+	update := *denyPolicy
+	var req *http.Request
+	b := new(bytes.Buffer)
+	if existing == nil {
+		update.Name = ""
+		_ = json.NewEncoder(b).Encode(update)
+		createUrl := fmt.Sprintf("%spolicies/%s/denypolicies?policyId=%s", iamV2Url, url.PathEscape(attachmentPoint), url.QueryEscape(policyId))
+		req, err = http.NewRequest(http.MethodPost, createUrl, b)
+	} else {
+		update.Etag = existing.Etag
+		_ = json.NewEncoder(b).Encode(update)
+		req, err = http.NewRequest(http.MethodPut, iamV2Url+denyPolicy.Name, b)
+	}
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unable to set google cloud deny policy %s: %s", denyPolicy.Name, resp.Status)
+	}
+	return nil
+}
+
+// DeleteDenyPolicy deletes the deny policy
+func (c *GoogleClient) DeleteDenyPolicy(denyPolicy *iamv2.GoogleIamV2Policy) error {
+	deleteUrl := iamV2Url + denyPolicy.Name
+	if denyPolicy.Etag != "" {
+		deleteUrl += "?etag=" + url.QueryEscape(denyPolicy.Etag)
+	}
+	req, err := http.NewRequest(http.MethodDelete, deleteUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unable to delete google cloud deny policy %s: %s", denyPolicy.Name, resp.Status)
+	}
+	return nil
+}
+
+func (c *GoogleClient) do(req *http.Request) (*http.Response, error) {
+	doer, ok := c.HttpClient.(requestDoer)
+	if !ok {
+		return nil, fmt.Errorf("the google http client does not support %s requests", req.Method)
+	}
+	return doer.Do(req)
+}
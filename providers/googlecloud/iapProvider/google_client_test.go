@@ -2,6 +2,7 @@ package iapProvider_test
 
 import (
     "errors"
+    "net/http"
     "testing"
 
     "github.com/hexa-org/policy-mapper/models/formats/gcpBind"
@@ -213,3 +214,108 @@ func TestGoogleClient_SetBackendPolicies_withRequestError(t *testing.T) {
     err = client.SetBackendPolicy("k8sName", "anObjectId", bindPolicy)
     assert.Error(t, err)
 }
+
+func TestGoogleClient_GetDenyPolicies(t *testing.T) {
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusOK, denyPoliciesJSON)
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyJSON)
+    client := iapProvider.GoogleClient{HttpClient: m, ProjectId: "google-cloud-project-id"}
+
+    denyPolicies, err := client.GetDenyPolicies(client.ProjectAttachmentPoint())
+    assert.NoError(t, err)
+    assert.Equal(t, 1, len(denyPolicies))
+    assert.Equal(t, 1, len(denyPolicies[0].Rules))
+
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusInternalServerError, nil)
+    _, err = client.GetDenyPolicies(client.ProjectAttachmentPoint())
+    assert.Error(t, err)
+
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusForbidden, nil)
+    denyPolicies, err = client.GetDenyPolicies(client.ProjectAttachmentPoint())
+    assert.NoError(t, err)
+    assert.Equal(t, 0, len(denyPolicies))
+
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusOK, []byte("-"))
+    _, err = client.GetDenyPolicies(client.ProjectAttachmentPoint())
+    assert.Error(t, err)
+
+    m.Err = errors.New("oops")
+    _, err = client.GetDenyPolicies(client.ProjectAttachmentPoint())
+    assert.Error(t, err)
+}
+
+func TestGoogleClient_SetDenyPolicy_update(t *testing.T) {
+    mapper := gcpBind.New(map[string]string{})
+    denyPolicies, err := gcpBind.ParseDenyPolicies(denyPolicyJSON)
+    assert.NoError(t, err)
+    policies, err := mapper.MapDenyPoliciesToPolicies(denyPolicies)
+    assert.NoError(t, err)
+    policies[0].Subjects = append(policies[0].Subjects, "group:contractors@example.com")
+    update, err := mapper.MapPoliciesToDenyPolicies(policies)
+    assert.NoError(t, err)
+
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyJSON)
+    m.AddRequest(http.MethodPut, denyPolicyUrl, http.StatusOK, nil)
+    client := iapProvider.GoogleClient{HttpClient: m, ProjectId: "google-cloud-project-id"}
+
+    assert.NoError(t, client.SetDenyPolicy(update[0]))
+    assert.True(t, m.VerifyCalled())
+    assert.JSONEq(t, `{
+  "name": "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fgoogle-cloud-project-id/denypolicies/block-deletes",
+  "displayName": "block-deletes",
+  "etag": "MTc3NDI1NzQ4NTE1ODE3NDk0Mw==",
+  "rules": [
+    {
+      "denyRule": {
+        "deniedPrincipals": ["principal://goog/subject/eve@example.com", "principalSet://goog/group/contractors@example.com"],
+        "deniedPermissions": ["cloudresourcemanager.googleapis.com/projects.delete"]
+      }
+    }
+  ]
+}`, string(m.GetRequestBodyByKey(http.MethodPut, denyPolicyUrl)))
+
+    m.AddRequest(http.MethodPut, denyPolicyUrl, http.StatusBadRequest, nil)
+    assert.Error(t, client.SetDenyPolicy(update[0]))
+
+    update[0].Name = "bad"
+    assert.Error(t, client.SetDenyPolicy(update[0]))
+}
+
+func TestGoogleClient_DeleteDenyPolicy(t *testing.T) {
+    denyPolicies, err := gcpBind.ParseDenyPolicies(denyPolicyJSON)
+    assert.NoError(t, err)
+    deleteUrl := denyPolicyUrl + "?etag=MTc3NDI1NzQ4NTE1ODE3NDk0Mw%3D%3D"
+
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodDelete, deleteUrl, http.StatusOK, nil)
+    client := iapProvider.GoogleClient{HttpClient: m, ProjectId: "google-cloud-project-id"}
+
+    assert.NoError(t, client.DeleteDenyPolicy(denyPolicies[0]))
+    assert.True(t, m.VerifyCalled())
+
+    m.AddRequest(http.MethodDelete, deleteUrl, http.StatusNotFound, nil)
+    assert.NoError(t, client.DeleteDenyPolicy(denyPolicies[0]))
+
+    m.AddRequest(http.MethodDelete, deleteUrl, http.StatusConflict, nil)
+    assert.Error(t, client.DeleteDenyPolicy(denyPolicies[0]))
+}
+
+// getPostClient implements HTTPClient without Do
+type getPostClient struct {
+    iapProvider.HTTPClient
+}
+
+func TestGoogleClient_DenyPolicy_requiresDo(t *testing.T) {
+    denyPolicies, err := gcpBind.ParseDenyPolicies(denyPolicyJSON)
+    assert.NoError(t, err)
+
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyJSON)
+    client := iapProvider.GoogleClient{HttpClient: getPostClient{m}, ProjectId: "google-cloud-project-id"}
+
+    err = client.SetDenyPolicy(denyPolicies[0])
+    assert.ErrorContains(t, err, "does not support PUT requests")
+    err = client.DeleteDenyPolicy(denyPolicies[0])
+    assert.ErrorContains(t, err, "does not support DELETE requests")
+}
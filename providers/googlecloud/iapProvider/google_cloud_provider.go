@@ -11,6 +11,7 @@ import (
     "github.com/hexa-org/policy-mapper/api/policyprovider"
     "github.com/hexa-org/policy-mapper/models/formats/gcpBind"
     "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    iamv2 "google.golang.org/api/iam/v2"
     "google.golang.org/api/option"
     "google.golang.org/api/transport/http"
 
@@ -59,6 +60,9 @@ func (g *GoogleProvider) DiscoverApplications(info policyprovider.IntegrationInf
     appEngineApplications, err2 := googleClient.GetAppEngineApplications()
     apps = append(apps, appEngineApplications...)
 
+    // deny policies are attached to the project rather than a backend, so they are managed as one application
+    apps = append(apps, googleClient.GetDenyPolicyApplication())
+
     err = err2
     // report first error
     if err1 != nil {
@@ -79,6 +83,10 @@ func (g *GoogleProvider) GetPolicyInfo(integration policyprovider.IntegrationInf
     }
     googleClient := GoogleClient{client, foundCredentials.ProjectId}
 
+    if app.ObjectID == googleClient.ProjectAttachmentPoint() {
+        return g.getDenyPolicyInfo(googleClient)
+    }
+
     bindings, err := googleClient.GetBackendPolicy(app.Name, app.ObjectID)
     if err != nil {
         return infos, err
@@ -97,6 +105,22 @@ func (g *GoogleProvider) GetPolicyInfo(integration policyprovider.IntegrationInf
     return result, nil
 }
 
+func (g *GoogleProvider) getDenyPolicyInfo(googleClient GoogleClient) (infos []hexapolicy.PolicyInfo, err error) {
+    denyPolicies, err := googleClient.GetDenyPolicies(googleClient.ProjectAttachmentPoint())
+    if err != nil {
+        return infos, err
+    }
+    for _, denyPolicy := range denyPolicies {
+        policies, _, errs := g.GcpMapper.MapDenyPolicyRules(denyPolicy)
+        if len(errs) > 0 {
+            // returning the other rules would hide a deny (rules that cannot be mapped are still preserved on set)
+            return nil, fmt.Errorf("deny policy %s has rules that cannot be mapped to IDQL: %w", denyPolicy.Name, errors.Join(errs...))
+        }
+        infos = append(infos, policies...)
+    }
+    return infos, nil
+}
+
 func (g *GoogleProvider) SetPolicyInfo(integration policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, policyInfos []hexapolicy.PolicyInfo) (int, error) {
     g.initMapper()
 
@@ -118,8 +142,16 @@ func (g *GoogleProvider) SetPolicyInfo(integration policyprovider.IntegrationInf
         return 500, createClientErr
     }
     googleClient := GoogleClient{client, foundCredentials.ProjectId}
-    for _, policyInfo := range policyInfos {
+    if app.ObjectID == googleClient.ProjectAttachmentPoint() {
+        return g.setDenyPolicyInfo(googleClient, policyInfos)
+    }
 
+    for _, policyInfo := range policyInfos {
+        if gcpBind.IsDenyPolicy(policyInfo) {
+            return 500, fmt.Errorf("deny policy %s must be set on the %s application", policyId(policyInfo), googleClient.ProjectAttachmentPoint())
+        }
+    }
+    for _, policyInfo := range policyInfos {
         binding, err := g.GcpMapper.MapPolicyToBinding(policyInfo)
         if err != nil {
             return 500, err
@@ -133,6 +165,73 @@ func (g *GoogleProvider) SetPolicyInfo(integration policyprovider.IntegrationInf
     return 201, nil
 }
 
+/*
+setDenyPolicyInfo replaces the deny policies attached to the project with policyInfos. Deny policies are created or
+updated as a whole, and existing deny policies that are not in policyInfos are deleted. Existing rules that cannot be
+mapped to IDQL are preserved: they are kept in their deny policy, and a deny policy holding only such rules is not
+deleted.
+*/
+func (g *GoogleProvider) setDenyPolicyInfo(googleClient GoogleClient, policyInfos []hexapolicy.PolicyInfo) (int, error) {
+    attachmentPoint := googleClient.ProjectAttachmentPoint()
+    for _, policyInfo := range policyInfos {
+        if !gcpBind.IsDenyPolicy(policyInfo) {
+            return 500, fmt.Errorf("policy %s is not a deny policy: only deny policies can be set on the %s application", policyId(policyInfo), attachmentPoint)
+        }
+        if policyInfo.Object.String() != attachmentPoint {
+            return 500, fmt.Errorf("deny policy %s object must be %s", policyId(policyInfo), attachmentPoint)
+        }
+    }
+
+    denyPolicies, err := g.GcpMapper.MapPoliciesToDenyPolicies(policyInfos)
+    if err != nil {
+        return 500, err
+    }
+    desired := make(map[string]*iamv2.GoogleIamV2Policy, len(denyPolicies))
+    for _, denyPolicy := range denyPolicies {
+        desired[denyPolicy.Name] = denyPolicy
+    }
+
+    existingPolicies, err := googleClient.GetDenyPolicies(attachmentPoint)
+    if err != nil {
+        return 500, err
+    }
+    var deletes []*iamv2.GoogleIamV2Policy
+    for _, existing := range existingPolicies {
+        _, unmapped, _ := g.GcpMapper.MapDenyPolicyRules(existing)
+        denyPolicy, found := desired[existing.Name]
+        switch {
+        case found:
+            denyPolicy.Rules = append(denyPolicy.Rules, unmapped...)
+        case len(unmapped) == 0:
+            deletes = append(deletes, existing)
+        case len(unmapped) < len(existing.Rules):
+            // keep only the rules that were not read as IDQL policies
+            denyPolicies = append(denyPolicies, &iamv2.GoogleIamV2Policy{Name: existing.Name, DisplayName: existing.DisplayName, Rules: unmapped})
+        }
+    }
+
+    for _, denyPolicy := range denyPolicies {
+        err = googleClient.SetDenyPolicy(denyPolicy)
+        if err != nil {
+            return 500, err
+        }
+    }
+    for _, denyPolicy := range deletes {
+        err = googleClient.DeleteDenyPolicy(denyPolicy)
+        if err != nil {
+            return 500, err
+        }
+    }
+    return 201, nil
+}
+
+func policyId(policyInfo hexapolicy.PolicyInfo) string {
+    if policyInfo.Meta.PolicyId != nil {
+        return *policyInfo.Meta.PolicyId
+    }
+    return policyInfo.Object.String()
+}
+
 func (g *GoogleProvider) NewHttpClient(key []byte) (HTTPClient, error) {
     var opts []option.ClientOption
     opt := option.WithCredentialsJSON(key)
@@ -1,16 +1,24 @@
 package iapProvider_test
 
 import (
+    "net/http"
     "testing"
 
     "github.com/hexa-org/policy-mapper/api/policyprovider"
+    "github.com/hexa-org/policy-mapper/models/formats/gcpBind"
     "github.com/hexa-org/policy-mapper/models/rar/testsupport"
     "github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+    "github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
 
     "github.com/hexa-org/policy-mapper/providers/googlecloud/iapProvider"
     "github.com/stretchr/testify/assert"
 )
 
+const denyPoliciesUrl = "https://iam.googleapis.com/v2/policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fgoogle-cloud-project-id/denypolicies"
+const denyPolicyUrl = denyPoliciesUrl + "/block-deletes"
+
+var denyPolicyApp = policyprovider.ApplicationInfo{ObjectID: "cloudresourcemanager.googleapis.com/projects/google-cloud-project-id", Name: "google-cloud-project-id"}
+
 func TestGoogleProvider_BadClientKey(t *testing.T) {
     p := iapProvider.GoogleProvider{}
     info := policyprovider.IntegrationInfo{Name: iapProvider.ProviderTypeGoogleCloudIAP, Key: []byte("aKey")}
@@ -36,11 +44,13 @@ func TestGoogleProvider_DiscoverApplications(t *testing.T) {
     applications, err := p.DiscoverApplications(info)
 
     assert.NoError(t, err)
-    assert.Equal(t, 4, len(applications))
+    assert.Equal(t, 5, len(applications))
     assert.Equal(t, "Kubernetes", applications[0].Service)
     assert.Equal(t, "Kubernetes", applications[1].Service)
     assert.Equal(t, "Cloud Run", applications[2].Service)
     assert.Equal(t, "AppEngine", applications[3].Service)
+    assert.Equal(t, iapProvider.ServiceDenyPolicies, applications[4].Service)
+    assert.Equal(t, denyPolicyApp.ObjectID, applications[4].ObjectID)
     assert.Equal(t, iapProvider.ProviderTypeGoogleCloudIAP, p.Name())
 }
 
@@ -54,7 +64,7 @@ func TestGoogleProvider_DiscoverApplications_ignoresProviderCase(t *testing.T) {
     applications, err := p.DiscoverApplications(info)
 
     assert.NoError(t, err)
-    assert.Equal(t, 4, len(applications))
+    assert.Equal(t, 5, len(applications))
     assert.Equal(t, iapProvider.ProviderTypeGoogleCloudIAP, p.Name())
 }
 
@@ -102,6 +112,135 @@ func TestGoogleProvider_GetPolicy(t *testing.T) {
     assert.Equal(t, 2, len(infos))
 }
 
+func TestGoogleProvider_GetPolicy_withDenyPolicies(t *testing.T) {
+    m := testsupport.NewMockHTTPClient()
+    m.ResponseBody["https://iap.googleapis.com/v1/projects/google-cloud-project-id/iap_web/compute/services/k8sObjectId:getIamPolicy"] = policyJSON
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusOK, denyPoliciesJSON)
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyJSON)
+    p := iapProvider.GoogleProvider{HttpClientOverride: m}
+    info := policyprovider.IntegrationInfo{Name: iapProvider.ProviderTypeGoogleCloudIAP, Key: projectJSON}
+
+    // deny policies are only returned for the project application
+    infos, err := p.GetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "k8sObjectId", Name: "k8sName"})
+    assert.NoError(t, err)
+    assert.Equal(t, 2, len(infos))
+    assert.NotContains(t, m.Called, "GET "+denyPoliciesUrl)
+
+    infos, err = p.GetPolicyInfo(info, denyPolicyApp)
+    assert.NoError(t, err)
+    assert.Equal(t, 1, len(infos))
+    deny := infos[0]
+    assert.Equal(t, "block-deletes", *deny.Meta.PolicyId)
+    assert.Equal(t, hexapolicy.SubjectInfo{"user:eve@example.com"}, deny.Subjects)
+    assert.Equal(t, "cloudresourcemanager.googleapis.com/projects/google-cloud-project-id", deny.Object.String())
+    assert.Equal(t, conditions.ADeny, deny.Condition.Action)
+}
+
+func TestGoogleProvider_GetPolicy_unmappableDenyRules(t *testing.T) {
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusOK, denyPoliciesJSON)
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyUnmappableJSON)
+    p := iapProvider.GoogleProvider{HttpClientOverride: m}
+    info := policyprovider.IntegrationInfo{Name: iapProvider.ProviderTypeGoogleCloudIAP, Key: projectJSON}
+
+    infos, err := p.GetPolicyInfo(info, denyPolicyApp)
+    assert.ErrorContains(t, err, "has rules that cannot be mapped to IDQL")
+    assert.Empty(t, infos)
+}
+
+func TestGoogleProvider_SetPolicy_withDenyPolicy(t *testing.T) {
+    policyId := "block-deletes"
+    policy := hexapolicy.PolicyInfo{
+        Meta:      hexapolicy.MetaInfo{Version: "aVersion", PolicyId: &policyId},
+        Actions:   []hexapolicy.ActionInfo{"gcp:cloudresourcemanager.googleapis.com/projects.delete"},
+        Subjects:  []string{"user:eve@example.com"},
+        Object:    "cloudresourcemanager.googleapis.com/projects/google-cloud-project-id",
+        Condition: &conditions.ConditionInfo{Rule: gcpBind.UnconditionalDenyRule, Action: conditions.ADeny},
+    }
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusNotFound, nil)
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusNotFound, nil)
+    m.AddRequest(http.MethodPost, denyPoliciesUrl+"?policyId=block-deletes", http.StatusOK, nil)
+
+    p := iapProvider.GoogleProvider{HttpClientOverride: m}
+    info := policyprovider.IntegrationInfo{Name: iapProvider.ProviderTypeGoogleCloudIAP, Key: projectJSON}
+    status, err := p.SetPolicyInfo(info, denyPolicyApp, []hexapolicy.PolicyInfo{policy})
+    assert.Equal(t, 201, status)
+    assert.NoError(t, err)
+    assert.True(t, m.VerifyCalled())
+    assert.JSONEq(t, `{
+  "displayName": "block-deletes",
+  "rules": [
+    {
+      "denyRule": {
+        "deniedPrincipals": ["principal://goog/subject/eve@example.com"],
+        "deniedPermissions": ["cloudresourcemanager.googleapis.com/projects.delete"]
+      }
+    }
+  ]
+}`, string(m.GetRequestBodyByKey(http.MethodPost, denyPoliciesUrl+"?policyId=block-deletes")))
+
+    // deny policies are not written to backend applications
+    status, err = p.SetPolicyInfo(info, policyprovider.ApplicationInfo{ObjectID: "anObjectId"}, []hexapolicy.PolicyInfo{policy})
+    assert.Equal(t, 500, status)
+    assert.ErrorContains(t, err, "must be set on the cloudresourcemanager.googleapis.com/projects/google-cloud-project-id application")
+
+    binding := hexapolicy.PolicyInfo{
+        Meta: hexapolicy.MetaInfo{Version: "aVersion"}, Actions: []hexapolicy.ActionInfo{"anAction"}, Subjects: []string{"aUser"}, Object: "anObjectId",
+    }
+    status, err = p.SetPolicyInfo(info, denyPolicyApp, []hexapolicy.PolicyInfo{binding})
+    assert.Equal(t, 500, status)
+    assert.ErrorContains(t, err, "is not a deny policy")
+
+    policy.Subjects = nil
+    status, err = p.SetPolicyInfo(info, denyPolicyApp, []hexapolicy.PolicyInfo{policy})
+    assert.Equal(t, 500, status)
+    assert.Error(t, err)
+}
+
+func TestGoogleProvider_SetPolicy_deletesDenyPolicy(t *testing.T) {
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusOK, denyPoliciesJSON)
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyJSON)
+    m.AddRequest(http.MethodDelete, denyPolicyUrl+"?etag=MTc3NDI1NzQ4NTE1ODE3NDk0Mw%3D%3D", http.StatusOK, nil)
+
+    p := iapProvider.GoogleProvider{HttpClientOverride: m}
+    info := policyprovider.IntegrationInfo{Name: iapProvider.ProviderTypeGoogleCloudIAP, Key: projectJSON}
+    status, err := p.SetPolicyInfo(info, denyPolicyApp, []hexapolicy.PolicyInfo{})
+    assert.Equal(t, 201, status)
+    assert.NoError(t, err)
+    assert.True(t, m.VerifyCalled())
+}
+
+func TestGoogleProvider_SetPolicy_preservesUnmappableDenyRules(t *testing.T) {
+    m := testsupport.NewMockHTTPClient()
+    m.AddRequest(http.MethodGet, denyPoliciesUrl, http.StatusOK, denyPoliciesJSON)
+    m.AddRequest(http.MethodGet, denyPolicyUrl, http.StatusOK, denyPolicyUnmappableJSON)
+    m.AddRequest(http.MethodPut, denyPolicyUrl, http.StatusOK, nil)
+
+    // removing the mapped rule keeps the deny policy with only the unmappable rule
+    p := iapProvider.GoogleProvider{HttpClientOverride: m}
+    info := policyprovider.IntegrationInfo{Name: iapProvider.ProviderTypeGoogleCloudIAP, Key: projectJSON}
+    status, err := p.SetPolicyInfo(info, denyPolicyApp, []hexapolicy.PolicyInfo{})
+    assert.Equal(t, 201, status)
+    assert.NoError(t, err)
+    assert.True(t, m.VerifyCalled())
+    assert.JSONEq(t, `{
+  "name": "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fgoogle-cloud-project-id/denypolicies/block-deletes",
+  "displayName": "Block deletes",
+  "etag": "MTc3NDI1NzQ4NTE1ODE3NDk0Mw==",
+  "rules": [
+    {
+      "denyRule": {
+        "deniedPrincipals": ["principal://goog/subject/bob@example.com"],
+        "deniedPermissions": ["cloudresourcemanager.googleapis.com/projects.update"],
+        "denialCondition": {"expression": "resource.type =="}
+      }
+    }
+  ]
+}`, string(m.GetRequestBodyByKey(http.MethodPut, denyPolicyUrl)))
+}
+
 func TestGoogleProvider_SetPolicy(t *testing.T) {
     policy := hexapolicy.PolicyInfo{
         Meta: hexapolicy.MetaInfo{Version: "aVersion"}, Actions: []hexapolicy.ActionInfo{"anAction"}, Subjects: []string{"aUser"}, Object: "anObjectId",
@@ -88,3 +88,64 @@ var projectJSON = []byte(`{
   "auth_provider_x509_cert_url": "https://www.googleapis.com/oauth2/v1/certs",
   "client_x509_cert_url": "https://www.googleapis.com/robot/v1/metadata/x509/google-cloud-project-id%google-cloud-project-id.iam.gserviceaccount.com"
 }`)
+
+var denyPoliciesJSON = []byte(`{
+  "policies": [
+    {
+      "name": "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fgoogle-cloud-project-id/denypolicies/block-deletes",
+      "kind": "DenyPolicy",
+      "displayName": "Block deletes"
+    }
+  ]
+}`)
+
+var denyPolicyJSON = []byte(`{
+  "name": "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fgoogle-cloud-project-id/denypolicies/block-deletes",
+  "kind": "DenyPolicy",
+  "displayName": "Block deletes",
+  "etag": "MTc3NDI1NzQ4NTE1ODE3NDk0Mw==",
+  "rules": [
+    {
+      "denyRule": {
+        "deniedPrincipals": [
+          "principal://goog/subject/eve@example.com"
+        ],
+        "deniedPermissions": [
+          "cloudresourcemanager.googleapis.com/projects.delete"
+        ]
+      }
+    }
+  ]
+}`)
+
+var denyPolicyUnmappableJSON = []byte(`{
+  "name": "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Fgoogle-cloud-project-id/denypolicies/block-deletes",
+  "kind": "DenyPolicy",
+  "displayName": "Block deletes",
+  "etag": "MTc3NDI1NzQ4NTE1ODE3NDk0Mw==",
+  "rules": [
+    {
+      "denyRule": {
+        "deniedPrincipals": [
+          "principal://goog/subject/eve@example.com"
+        ],
+        "deniedPermissions": [
+          "cloudresourcemanager.googleapis.com/projects.delete"
+        ]
+      }
+    },
+    {
+      "denyRule": {
+        "deniedPrincipals": [
+          "principal://goog/subject/bob@example.com"
+        ],
+        "deniedPermissions": [
+          "cloudresourcemanager.googleapis.com/projects.update"
+        ],
+        "denialCondition": {
+          "expression": "resource.type =="
+        }
+      }
+    }
+  ]
+}`)
@@ -0,0 +1,354 @@
+/*
+Package kcClient is a minimal client for the Keycloak Admin REST API covering client Authorization Services (resource
+servers): resources, scopes, policies and permissions, together with the realm roles, users and groups they reference.
+*/
+package kcClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	PolicyTypeRole  string = "role"
+	PolicyTypeUser  string = "user"
+	PolicyTypeGroup string = "group"
+
+	PermissionTypeResource string = "resource"
+	PermissionTypeScope    string = "scope"
+
+	LogicPositive string = "POSITIVE"
+	LogicNegative string = "NEGATIVE"
+
+	DecisionAffirmative string = "AFFIRMATIVE"
+	DecisionUnanimous   string = "UNANIMOUS"
+)
+
+/*
+Credentials is the integration key for Keycloak. The client must be a confidential client with a service account that
+has the realm-management `manage-clients`, `view-users` and `view-realm` roles. For example:
+
+	{"url": "https://keycloak.example.com", "realm": "hexa", "clientId": "hexa-admin", "clientSecret": "secret"}
+*/
+type Credentials struct {
+	Url          string `json:"url"`                  // Url is the base url of the Keycloak server
+	Realm        string `json:"realm"`                // Realm is the realm whose clients are managed
+	TokenRealm   string `json:"tokenRealm,omitempty"` // TokenRealm is the realm issuing admin tokens (defaults to Realm)
+	ClientId     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+type Client struct {
+	Id                           string `json:"id"`
+	ClientId                     string `json:"clientId"`
+	Name                         string `json:"name,omitempty"`
+	Description                  string `json:"description,omitempty"`
+	AuthorizationServicesEnabled bool   `json:"authorizationServicesEnabled,omitempty"`
+}
+
+type Scope struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+type Resource struct {
+	Id     string   `json:"_id,omitempty"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type,omitempty"`
+	Uris   []string `json:"uris,omitempty"`
+	Scopes []Scope  `json:"scopes,omitempty"`
+}
+
+// Policy is a Keycloak authorization policy. Role, user and group settings are returned by Keycloak in Config as JSON
+// encoded strings and are sent as the typed Roles, Users and Groups values when creating policies.
+type Policy struct {
+	Id               string            `json:"id,omitempty"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	Type             string            `json:"type"`
+	Logic            string            `json:"logic,omitempty"`
+	DecisionStrategy string            `json:"decisionStrategy,omitempty"`
+	Config           map[string]string `json:"config,omitempty"`
+	Roles            []PolicyRole      `json:"roles,omitempty"`
+	Users            []string          `json:"users,omitempty"`
+	Groups           []PolicyGroup     `json:"groups,omitempty"`
+}
+
+type PolicyRole struct {
+	Id       string `json:"id"`
+	Required bool   `json:"required"`
+}
+
+type PolicyGroup struct {
+	Id             string `json:"id,omitempty"`
+	Path           string `json:"path,omitempty"`
+	ExtendChildren bool   `json:"extendChildren"`
+}
+
+// Permission is a resource or scope permission. Resources, Scopes and Policies hold ids.
+type Permission struct {
+	Id               string   `json:"id,omitempty"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description,omitempty"`
+	Type             string   `json:"type"`
+	Logic            string   `json:"logic,omitempty"`
+	DecisionStrategy string   `json:"decisionStrategy,omitempty"`
+	Resources        []string `json:"resources,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"`
+	Policies         []string `json:"policies,omitempty"`
+}
+
+type Role struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	ClientRole  bool   `json:"clientRole"`
+	ContainerId string `json:"containerId"`
+}
+
+type User struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+}
+
+type Group struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// KeycloakClient invokes the admin API of a realm using an access token obtained with a client credentials grant
+type KeycloakClient struct {
+	creds      Credentials
+	httpClient *http.Client
+}
+
+// NewKeycloakClient parses the integration key. If httpClient is nil, http.DefaultClient is used to obtain tokens.
+func NewKeycloakClient(key []byte, httpClient *http.Client) (*KeycloakClient, error) {
+	var creds Credentials
+	if err := json.Unmarshal(key, &creds); err != nil {
+		return nil, fmt.Errorf("invalid keycloak integration key: %w", err)
+	}
+	if creds.Url == "" || creds.Realm == "" || creds.ClientId == "" {
+		return nil, errors.New("invalid keycloak integration key: url, realm and clientId are required")
+	}
+	creds.Url = strings.TrimSuffix(creds.Url, "/")
+	if creds.TokenRealm == "" {
+		creds.TokenRealm = creds.Realm
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     creds.ClientId,
+		ClientSecret: creds.ClientSecret,
+		TokenURL:     fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token", creds.Url, url.PathEscape(creds.TokenRealm)),
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	return &KeycloakClient{creds: creds, httpClient: config.Client(ctx)}, nil
+}
+
+func (c *KeycloakClient) Realm() string {
+	return c.creds.Realm
+}
+
+func (c *KeycloakClient) GetClients() ([]Client, error) {
+	var clients []Client
+	err := c.doJson(http.MethodGet, c.adminUrl("clients"), nil, &clients)
+	return clients, err
+}
+
+func (c *KeycloakClient) GetResources(clientUuid string) ([]Resource, error) {
+	var resources []Resource
+	err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "resource?max=-1"), nil, &resources)
+	return resources, err
+}
+
+func (c *KeycloakClient) GetScopes(clientUuid string) ([]Scope, error) {
+	var scopes []Scope
+	err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "scope?max=-1"), nil, &scopes)
+	return scopes, err
+}
+
+// GetPolicies returns the policies of a resource server, excluding permissions
+func (c *KeycloakClient) GetPolicies(clientUuid string) ([]Policy, error) {
+	var policies []Policy
+	err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "policy?max=-1&permission=false"), nil, &policies)
+	return policies, err
+}
+
+// GetPermissions returns the permissions of a resource server including their resource, scope and policy ids
+func (c *KeycloakClient) GetPermissions(clientUuid string) ([]Permission, error) {
+	var permissions []Permission
+	if err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "permission?max=-1"), nil, &permissions); err != nil {
+		return nil, err
+	}
+	for i, permission := range permissions {
+		var resources []Resource
+		if err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "policy/"+permission.Id+"/resources"), nil, &resources); err != nil {
+			return nil, err
+		}
+		var scopes []Scope
+		if err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "policy/"+permission.Id+"/scopes"), nil, &scopes); err != nil {
+			return nil, err
+		}
+		var policies []Policy
+		if err := c.doJson(http.MethodGet, c.authzUrl(clientUuid, "policy/"+permission.Id+"/associatedPolicies"), nil, &policies); err != nil {
+			return nil, err
+		}
+		permissions[i].Resources = []string{}
+		for _, resource := range resources {
+			permissions[i].Resources = append(permissions[i].Resources, resource.Id)
+		}
+		permissions[i].Scopes = []string{}
+		for _, scope := range scopes {
+			permissions[i].Scopes = append(permissions[i].Scopes, scope.Id)
+		}
+		permissions[i].Policies = []string{}
+		for _, policy := range policies {
+			permissions[i].Policies = append(permissions[i].Policies, policy.Id)
+		}
+	}
+	return permissions, nil
+}
+
+// CreatePolicy creates a role, user or group policy and returns it with its assigned id
+func (c *KeycloakClient) CreatePolicy(clientUuid string, policy Policy) (*Policy, error) {
+	var created Policy
+	err := c.doJson(http.MethodPost, c.authzUrl(clientUuid, "policy/"+policy.Type), policy, &created)
+	return &created, err
+}
+
+func (c *KeycloakClient) CreatePermission(clientUuid string, permission Permission) (*Permission, error) {
+	var created Permission
+	err := c.doJson(http.MethodPost, c.authzUrl(clientUuid, "permission/"+permission.Type), permission, &created)
+	return &created, err
+}
+
+func (c *KeycloakClient) UpdatePermission(clientUuid string, permission Permission) error {
+	return c.doJson(http.MethodPut, c.authzUrl(clientUuid, "permission/"+permission.Type+"/"+permission.Id), permission, nil)
+}
+
+func (c *KeycloakClient) DeletePermission(clientUuid string, id string) error {
+	return c.doJson(http.MethodDelete, c.authzUrl(clientUuid, "permission/"+id), nil, nil)
+}
+
+func (c *KeycloakClient) GetRoleById(id string) (*Role, error) {
+	var role Role
+	err := c.doJson(http.MethodGet, c.adminUrl("roles-by-id/"+url.PathEscape(id)), nil, &role)
+	return &role, err
+}
+
+// GetRole returns a realm role, or a client role when clientUuid is not empty
+func (c *KeycloakClient) GetRole(clientUuid string, name string) (*Role, error) {
+	path := "roles/" + url.PathEscape(name)
+	if clientUuid != "" {
+		path = "clients/" + clientUuid + "/" + path
+	}
+	var role Role
+	err := c.doJson(http.MethodGet, c.adminUrl(path), nil, &role)
+	return &role, err
+}
+
+func (c *KeycloakClient) GetUserById(id string) (*User, error) {
+	var user User
+	err := c.doJson(http.MethodGet, c.adminUrl("users/"+url.PathEscape(id)), nil, &user)
+	return &user, err
+}
+
+func (c *KeycloakClient) GetUserByUsername(username string) (*User, error) {
+	var users []User
+	err := c.doJson(http.MethodGet, c.adminUrl("users?exact=true&username="+url.QueryEscape(username)), nil, &users)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("keycloak user %s not found", username)
+	}
+	return &users[0], nil
+}
+
+func (c *KeycloakClient) GetGroupById(id string) (*Group, error) {
+	var group Group
+	err := c.doJson(http.MethodGet, c.adminUrl("groups/"+url.PathEscape(id)), nil, &group)
+	return &group, err
+}
+
+func (c *KeycloakClient) GetGroupByPath(path string) (*Group, error) {
+	var group Group
+	err := c.doJson(http.MethodGet, c.adminUrl("group-by-path/"+strings.TrimPrefix(path, "/")), nil, &group)
+	return &group, err
+}
+
+func (c *KeycloakClient) adminUrl(path string) string {
+	return fmt.Sprintf("%s/admin/realms/%s/%s", c.creds.Url, url.PathEscape(c.creds.Realm), path)
+}
+
+func (c *KeycloakClient) authzUrl(clientUuid string, path string) string {
+	return c.adminUrl(fmt.Sprintf("clients/%s/authz/resource-server/%s", url.PathEscape(clientUuid), path))
+}
+
+func (c *KeycloakClient) doJson(method string, reqUrl string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, reqUrl, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &HttpError{Method: method, Url: reqUrl, StatusCode: resp.StatusCode, Body: string(respBytes)}
+	}
+	if result == nil || len(respBytes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBytes, result)
+}
+
+// HttpError is returned when the Keycloak admin API returns an error status
+type HttpError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("keycloak %s %s failed (%d): %s", e.Method, e.Url, e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// IsNotFound returns true if the error is a Keycloak 404 response
+func IsNotFound(err error) bool {
+	var httpErr *HttpError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,109 @@
+package kcClient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/providers/keycloak/keycloaktestsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeycloakClient(t *testing.T) {
+	_, err := NewKeycloakClient([]byte("bad"), nil)
+	assert.ErrorContains(t, err, "invalid keycloak integration key")
+
+	_, err = NewKeycloakClient([]byte(`{"url":"https://keycloak.example.com"}`), nil)
+	assert.ErrorContains(t, err, "url, realm and clientId are required")
+
+	client, err := NewKeycloakClient([]byte(`{"url":"https://keycloak.example.com/","realm":"hexa","clientId":"admin"}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "hexa", client.Realm())
+	assert.Equal(t, "https://keycloak.example.com/admin/realms/hexa/clients", client.adminUrl("clients"))
+	assert.Equal(t, "hexa", client.creds.TokenRealm)
+}
+
+func TestKeycloakClient(t *testing.T) {
+	fake := keycloaktestsupport.NewFakeKeycloak("hexa")
+	defer fake.Close()
+	clientUuid := fake.AddClient("photos", true)
+	fake.AddClient("other", false)
+	roleId := fake.AddRealmRole("admin")
+	userId := fake.AddUser("alice")
+	groupId := fake.AddGroup("/staff/editors")
+	resourceId := fake.AddResource(clientUuid, "album", "view", "edit")
+	policyId := fake.AddRolePolicy(clientUuid, "admins", roleId)
+	fake.AddPermission(clientUuid, "view albums", PermissionTypeScope, DecisionAffirmative, []string{resourceId}, nil, []string{policyId})
+
+	client, err := NewKeycloakClient(fake.Key(), fake.Server.Client())
+	assert.NoError(t, err)
+
+	clients, err := client.GetClients()
+	assert.NoError(t, err)
+	assert.Len(t, clients, 2)
+	assert.True(t, clients[0].AuthorizationServicesEnabled)
+
+	resources, err := client.GetResources(clientUuid)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+	assert.Equal(t, resourceId, resources[0].Id)
+	assert.Len(t, resources[0].Scopes, 2)
+
+	scopes, err := client.GetScopes(clientUuid)
+	assert.NoError(t, err)
+	assert.Len(t, scopes, 2)
+
+	policies, err := client.GetPolicies(clientUuid)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 1)
+	var roles []PolicyRole
+	assert.NoError(t, json.Unmarshal([]byte(policies[0].Config["roles"]), &roles))
+	assert.Equal(t, roleId, roles[0].Id)
+
+	permissions, err := client.GetPermissions(clientUuid)
+	assert.NoError(t, err)
+	assert.Len(t, permissions, 1)
+	assert.Equal(t, []string{resourceId}, permissions[0].Resources)
+	assert.Equal(t, []string{}, permissions[0].Scopes)
+	assert.Equal(t, []string{policyId}, permissions[0].Policies)
+
+	role, err := client.GetRoleById(roleId)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", role.Name)
+	_, err = client.GetRole("", "missing")
+	assert.True(t, IsNotFound(err))
+
+	user, err := client.GetUserByUsername("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, userId, user.Id)
+	_, err = client.GetUserByUsername("bob")
+	assert.ErrorContains(t, err, "user bob not found")
+
+	group, err := client.GetGroupByPath("/staff/editors")
+	assert.NoError(t, err)
+	assert.Equal(t, groupId, group.Id)
+
+	created, err := client.CreatePolicy(clientUuid, Policy{Name: "alice", Type: PolicyTypeUser, Users: []string{userId}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.Id)
+
+	permission := permissions[0]
+	permission.Policies = append(permission.Policies, created.Id)
+	assert.NoError(t, client.UpdatePermission(clientUuid, permission))
+	assert.Equal(t, []string{"admins", "alice"}, fake.Permissions(clientUuid)[0].Policies)
+
+	assert.NoError(t, client.DeletePermission(clientUuid, permission.Id))
+	assert.Empty(t, fake.Permissions(clientUuid))
+	err = client.DeletePermission(clientUuid, permission.Id)
+	assert.True(t, IsNotFound(err))
+}
+
+func TestKeycloakClient_Unauthorized(t *testing.T) {
+	fake := keycloaktestsupport.NewFakeKeycloak("hexa")
+	defer fake.Close()
+
+	key, _ := json.Marshal(Credentials{Url: fake.Server.URL, Realm: "hexa", ClientId: keycloaktestsupport.FakeClientId, ClientSecret: "wrong"})
+	client, err := NewKeycloakClient(key, fake.Server.Client())
+	assert.NoError(t, err)
+	_, err = client.GetClients()
+	assert.ErrorContains(t, err, "unauthorized_client")
+}
@@ -0,0 +1,688 @@
+/*
+Package keycloakProvider maps Keycloak Authorization Services (fine-grained authorization for clients) to and from
+IDQL. Each client with authorization enabled is an application. Each resource or scope permission is an IDQL policy where:
+
+  - Object is the name of the permission resource (a permission with several resources becomes one policy per resource,
+    which are regrouped into the permission when set)
+  - Actions are the names of the permission scopes
+  - Subjects are derived from the role (role:<role> or role:<clientId>/<role>), user (user:<username>) and group
+    (group:<path>) policies associated with the permission
+
+Permissions that cannot be mapped without granting more (NEGATIVE logic, no policy mapped to subjects, or UNANIMOUS
+with several policies) are not returned, and are left unchanged when policies are set.
+*/
+package keycloakProvider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/providers/keycloak/kcClient"
+	log "golang.org/x/exp/slog"
+)
+
+const (
+	ProviderTypeKeycloak string = "keycloak"
+
+	SourceDecisionStrategy string = "decisionStrategy" // SourceDecisionStrategy is the Keycloak permission decision strategy
+	SourcePermissionType   string = "permissionType"   // SourcePermissionType is resource or scope
+	SourcePermission       string = "permission"       // SourcePermission is the name of the Keycloak permission
+
+	subjectRole  = "role:"
+	subjectUser  = "user:"
+	subjectGroup = "group:"
+)
+
+type KeycloakProvider struct {
+	httpClient *http.Client
+}
+
+type ProviderOpt func(provider *KeycloakProvider)
+
+// WithHttpClient overrides the http client used to call Keycloak (e.g. to configure TLS)
+func WithHttpClient(client *http.Client) ProviderOpt {
+	return func(provider *KeycloakProvider) {
+		provider.httpClient = client
+	}
+}
+
+func NewKeycloakProvider(opts ...ProviderOpt) *KeycloakProvider {
+	provider := &KeycloakProvider{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(provider)
+		}
+	}
+	return provider
+}
+
+func (k *KeycloakProvider) Name() string {
+	return ProviderTypeKeycloak
+}
+
+// DiscoverApplications returns the clients that have Authorization Services enabled
+func (k *KeycloakProvider) DiscoverApplications(info policyprovider.IntegrationInfo) ([]policyprovider.ApplicationInfo, error) {
+	client, err := kcClient.NewKeycloakClient(info.Key, k.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := client.GetClients()
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []policyprovider.ApplicationInfo
+	for _, kcApp := range clients {
+		if !kcApp.AuthorizationServicesEnabled {
+			continue
+		}
+		description := kcApp.Description
+		if description == "" {
+			description = kcApp.Name
+		}
+		apps = append(apps, policyprovider.ApplicationInfo{
+			ObjectID:    kcApp.Id,
+			Name:        kcApp.ClientId,
+			Description: description,
+			Service:     "Authorization Services",
+		})
+	}
+	return apps, nil
+}
+
+func (k *KeycloakProvider) GetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) ([]hexapolicy.PolicyInfo, error) {
+	server, err := k.loadResourceServer(info, app)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []hexapolicy.PolicyInfo
+	for _, permission := range server.permissions {
+		policies = append(policies, server.mapPermission(permission)...)
+	}
+	return policies, nil
+}
+
+/*
+SetPolicyInfo replaces the permissions of the client with the supplied policies. Permissions are matched by name
+(the IDQL policy id); new permissions are created, changed permissions are updated and permissions not in the supplied
+policies are deleted (except NEGATIVE permissions, which are not mapped). Policies read from one permission with
+several resources are regrouped into that permission when they still have the same subjects and actions. Resources
+and scopes must already exist. Role, user and group policies are re-used when an existing policy grants exactly the
+subject, otherwise a policy is created. Associated policies that cannot be mapped to subjects (e.g. time or JavaScript
+policies) are retained. All policies are validated before Keycloak is changed.
+*/
+func (k *KeycloakProvider) SetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, policies []hexapolicy.PolicyInfo) (int, error) {
+	server, err := k.loadResourceServer(info, app)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	var mapped []mappedPermission
+	ids := map[string]bool{}
+	for i, policy := range policies {
+		permission, err := server.toPermission(i, policy)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if ids[permission.permission.Name] {
+			return http.StatusBadRequest, fmt.Errorf("duplicate policy id: %s", permission.permission.Name)
+		}
+		ids[permission.permission.Name] = true
+		mapped = append(mapped, *permission)
+	}
+	grouped := groupPermissions(mapped)
+	names := map[string]bool{}
+	for _, permission := range grouped {
+		if names[permission.permission.Name] {
+			return http.StatusBadRequest, fmt.Errorf("duplicate keycloak permission: %s", permission.permission.Name)
+		}
+		names[permission.permission.Name] = true
+	}
+
+	// subject policies are only created once all policies are valid
+	if err = server.createSubjectPolicies(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	var desired []kcClient.Permission
+	for _, permission := range grouped {
+		permission.permission.Policies = server.subjectPolicyIds(permission.subjects)
+		desired = append(desired, permission.permission)
+	}
+
+	existing := map[string]kcClient.Permission{}
+	for _, permission := range server.permissions {
+		existing[permission.Name] = permission
+	}
+
+	for _, permission := range desired {
+		current, ok := existing[permission.Name]
+		if !ok {
+			if _, err = server.client.CreatePermission(server.clientUuid, permission); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			continue
+		}
+		// associated policies that cannot be mapped to IDQL subjects are retained
+		for _, policyId := range current.Policies {
+			if _, mapped := server.subjects[policyId]; !mapped && !slices.Contains(permission.Policies, policyId) {
+				permission.Policies = append(permission.Policies, policyId)
+			}
+		}
+		if permissionEquals(current, permission) {
+			continue
+		}
+		if current.Type != permission.Type {
+			// the permission type cannot be changed by an update
+			if err = server.client.DeletePermission(server.clientUuid, current.Id); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			if _, err = server.client.CreatePermission(server.clientUuid, permission); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			continue
+		}
+		permission.Id = current.Id
+		if err = server.client.UpdatePermission(server.clientUuid, permission); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	for _, permission := range server.permissions {
+		// permissions that cannot be mapped to IDQL are left unchanged
+		if _, reason := server.permissionSubjects(permission); !names[permission.Name] && reason == "" {
+			if err = server.client.DeletePermission(server.clientUuid, permission.Id); err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (k *KeycloakProvider) Reconcile(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, comparePolicies []hexapolicy.PolicyInfo, diffsOnly bool) ([]hexapolicy.PolicyDif, error) {
+	existing, err := k.GetPolicyInfo(info, app)
+	if err != nil {
+		return nil, err
+	}
+	existingPolicies := hexapolicy.Policies{Policies: existing, App: &app.ObjectID}
+	return existingPolicies.ReconcilePolicies(comparePolicies, diffsOnly), nil
+}
+
+// resourceServer is a snapshot of a client's authorization settings with resolved names
+type resourceServer struct {
+	client     *kcClient.KeycloakClient
+	clientUuid string
+	clientIds  map[string]string // client uuid to clientId
+
+	resources   map[string]kcClient.Resource
+	scopes      map[string]kcClient.Scope
+	policies    map[string]kcClient.Policy
+	permissions []kcClient.Permission
+
+	subjects      map[string][]string // policy id to IDQL subjects (supported policies only)
+	subjectPolicy map[string]string   // lower case subject to the id of a policy granting only that subject
+
+	pendingPolicies []kcClient.Policy // subject policies to create, by lower case subject in pendingSubjects
+	pendingSubjects []string
+}
+
+// mappedPermission is a permission mapped from an IDQL policy before its subjects are resolved to policy ids
+type mappedPermission struct {
+	permission kcClient.Permission
+	subjects   []string
+	key        string // the permission the policy was read from (SourcePermission), or the policy id
+}
+
+func (k *KeycloakProvider) loadResourceServer(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) (*resourceServer, error) {
+	client, err := kcClient.NewKeycloakClient(info.Key, k.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if app.ObjectID == "" {
+		return nil, errors.New("missing keycloak client id (ApplicationInfo.ObjectID)")
+	}
+	server := &resourceServer{
+		client:        client,
+		clientUuid:    app.ObjectID,
+		clientIds:     map[string]string{},
+		resources:     map[string]kcClient.Resource{},
+		scopes:        map[string]kcClient.Scope{},
+		policies:      map[string]kcClient.Policy{},
+		subjects:      map[string][]string{},
+		subjectPolicy: map[string]string{},
+	}
+
+	clients, err := client.GetClients()
+	if err != nil {
+		return nil, err
+	}
+	for _, kcApp := range clients {
+		server.clientIds[kcApp.Id] = kcApp.ClientId
+	}
+	resources, err := client.GetResources(app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		server.resources[resource.Id] = resource
+	}
+	scopes, err := client.GetScopes(app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		server.scopes[scope.Id] = scope
+	}
+	policies, err := client.GetPolicies(app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		server.policies[policy.Id] = policy
+		subjects, err := server.policySubjects(policy)
+		if err != nil {
+			return nil, err
+		}
+		if subjects == nil {
+			continue
+		}
+		server.subjects[policy.Id] = subjects
+		if len(subjects) == 1 {
+			if _, exists := server.subjectPolicy[strings.ToLower(subjects[0])]; !exists {
+				server.subjectPolicy[strings.ToLower(subjects[0])] = policy.Id
+			}
+		}
+	}
+	server.permissions, err = client.GetPermissions(app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// policySubjects returns the IDQL subjects of a role, user or group policy, or nil if the policy cannot be mapped
+func (s *resourceServer) policySubjects(policy kcClient.Policy) ([]string, error) {
+	if policy.Logic == kcClient.LogicNegative {
+		return nil, nil
+	}
+	subjects := []string{}
+	switch policy.Type {
+	case kcClient.PolicyTypeRole:
+		var roles []kcClient.PolicyRole
+		if err := json.Unmarshal([]byte(policy.Config["roles"]), &roles); err != nil {
+			return nil, fmt.Errorf("invalid roles in keycloak policy %s: %w", policy.Name, err)
+		}
+		for _, policyRole := range roles {
+			if policyRole.Required && len(roles) > 1 {
+				// all roles required cannot be expressed as IDQL subjects
+				return nil, nil
+			}
+			role, err := s.client.GetRoleById(policyRole.Id)
+			if err != nil {
+				return nil, err
+			}
+			if role.ClientRole {
+				subjects = append(subjects, subjectRole+s.clientIds[role.ContainerId]+"/"+role.Name)
+				continue
+			}
+			subjects = append(subjects, subjectRole+role.Name)
+		}
+	case kcClient.PolicyTypeUser:
+		var users []string
+		if err := json.Unmarshal([]byte(policy.Config["users"]), &users); err != nil {
+			return nil, fmt.Errorf("invalid users in keycloak policy %s: %w", policy.Name, err)
+		}
+		for _, userId := range users {
+			user, err := s.client.GetUserById(userId)
+			if err != nil {
+				return nil, err
+			}
+			subjects = append(subjects, subjectUser+user.Username)
+		}
+	case kcClient.PolicyTypeGroup:
+		var groups []kcClient.PolicyGroup
+		if err := json.Unmarshal([]byte(policy.Config["groups"]), &groups); err != nil {
+			return nil, fmt.Errorf("invalid groups in keycloak policy %s: %w", policy.Name, err)
+		}
+		for _, policyGroup := range groups {
+			group, err := s.client.GetGroupById(policyGroup.Id)
+			if err != nil {
+				return nil, err
+			}
+			subjects = append(subjects, subjectGroup+group.Path)
+		}
+	default:
+		return nil, nil
+	}
+	return subjects, nil
+}
+
+// mapPermission maps a permission to IDQL (one policy per resource)
+func (s *resourceServer) mapPermission(permission kcClient.Permission) []hexapolicy.PolicyInfo {
+	subjects, reason := s.permissionSubjects(permission)
+	if reason != "" {
+		log.Warn("keycloak permission cannot be mapped to IDQL", "permission", permission.Name, "reason", reason)
+		return nil
+	}
+
+	actions := []hexapolicy.ActionInfo{}
+	for _, scopeId := range permission.Scopes {
+		actions = append(actions, hexapolicy.ActionInfo(s.scopes[scopeId].Name))
+	}
+
+	objects := []string{""}
+	if len(permission.Resources) > 0 {
+		objects = []string{}
+		for _, resourceId := range permission.Resources {
+			objects = append(objects, s.resources[resourceId].Name)
+		}
+	}
+
+	var policies []hexapolicy.PolicyInfo
+	for _, object := range objects {
+		policyId := permission.Name
+		if len(objects) > 1 {
+			policyId = permission.Name + "/" + object
+		}
+		clientUuid := s.clientUuid
+		policies = append(policies, hexapolicy.PolicyInfo{
+			Meta: hexapolicy.MetaInfo{
+				Version:      hexapolicy.IdqlVersion,
+				Description:  permission.Description,
+				PolicyId:     &policyId,
+				PapId:        &clientUuid,
+				ProviderType: ProviderTypeKeycloak,
+				SourceData: map[string]interface{}{
+					SourcePermission:       permission.Name,
+					SourcePermissionType:   permission.Type,
+					SourceDecisionStrategy: permission.DecisionStrategy,
+				},
+			},
+			Subjects: slices.Clone(subjects),
+			Actions:  slices.Clone(actions),
+			Object:   hexapolicy.ObjectInfo(object),
+		})
+	}
+	return policies
+}
+
+/*
+permissionSubjects returns the IDQL subjects of a permission, or the reason the permission cannot be mapped. Empty IDQL
+subjects mean any subject, so a permission without mapped subjects (e.g. only time or JavaScript policies) is not
+mapped. Neither are NEGATIVE permissions and UNANIMOUS permissions with several policies (IDQL subjects are
+alternatives), as the IDQL policy would grant more than the permission. Policies of an AFFIRMATIVE permission that
+cannot be mapped are alternatives and are omitted.
+*/
+func (s *resourceServer) permissionSubjects(permission kcClient.Permission) (hexapolicy.SubjectInfo, string) {
+	if permission.Logic == kcClient.LogicNegative {
+		return nil, "NEGATIVE logic"
+	}
+	if permission.DecisionStrategy == kcClient.DecisionUnanimous && len(permission.Policies) > 1 {
+		return nil, "UNANIMOUS decision strategy with several policies"
+	}
+	subjects := hexapolicy.SubjectInfo{}
+	for _, policyId := range permission.Policies {
+		policySubjects, ok := s.subjects[policyId]
+		if !ok {
+			log.Warn("keycloak policy cannot be mapped to IDQL subjects", "permission", permission.Name, "policy", s.policies[policyId].Name)
+			continue
+		}
+		for _, subject := range policySubjects {
+			if !slices.Contains(subjects, subject) {
+				subjects = append(subjects, subject)
+			}
+		}
+	}
+	if len(subjects) == 0 {
+		return nil, "no policies that can be mapped to IDQL subjects"
+	}
+	return subjects, ""
+}
+
+// toPermission maps an IDQL policy to a permission, validating the subjects (missing subject policies are created later)
+func (s *resourceServer) toPermission(index int, policy hexapolicy.PolicyInfo) (*mappedPermission, error) {
+	name := fmt.Sprintf("hexa-policy-%d", index)
+	if policy.Meta.PolicyId != nil && *policy.Meta.PolicyId != "" {
+		name = *policy.Meta.PolicyId
+	}
+	if policy.Condition != nil {
+		return nil, fmt.Errorf("policy %s: conditions are not supported by the keycloak provider", name)
+	}
+
+	permission := &kcClient.Permission{
+		Name:             name,
+		Description:      policy.Meta.Description,
+		Type:             kcClient.PermissionTypeResource,
+		Logic:            kcClient.LogicPositive,
+		DecisionStrategy: kcClient.DecisionAffirmative,
+		Resources:        []string{},
+		Scopes:           []string{},
+		Policies:         []string{},
+	}
+	if strategy, ok := policy.Meta.SourceData[SourceDecisionStrategy].(string); ok && strategy != "" {
+		permission.DecisionStrategy = strategy
+	}
+
+	if object := policy.Object.String(); object != "" {
+		resourceId := s.resourceId(object)
+		if resourceId == "" {
+			return nil, fmt.Errorf("policy %s: keycloak resource %s not found", name, object)
+		}
+		permission.Resources = append(permission.Resources, resourceId)
+	}
+	for _, action := range policy.Actions {
+		scopeId := s.scopeId(action.String())
+		if scopeId == "" {
+			return nil, fmt.Errorf("policy %s: keycloak scope %s not found", name, action.String())
+		}
+		permission.Scopes = append(permission.Scopes, scopeId)
+	}
+	if len(permission.Scopes) > 0 {
+		permission.Type = kcClient.PermissionTypeScope
+	} else if len(permission.Resources) == 0 {
+		return nil, fmt.Errorf("policy %s: an object or actions are required", name)
+	}
+
+	for _, subject := range policy.Subjects {
+		if err := s.prepareSubjectPolicy(subject); err != nil {
+			return nil, fmt.Errorf("policy %s: %w", name, err)
+		}
+	}
+
+	key := name
+	if source, ok := policy.Meta.SourceData[SourcePermission].(string); ok && source != "" {
+		// the id of a policy read from a permission is the permission name, or <permission>/<resource>
+		if name == source || name == source+"/"+policy.Object.String() {
+			key = source
+		}
+	}
+	return &mappedPermission{permission: *permission, subjects: policy.Subjects, key: key}, nil
+}
+
+/*
+groupPermissions regroups policies that were read from the same permission. They are merged into one permission
+(named after the original permission) when each has one resource and they have the same type, description, decision
+strategy, scopes and subjects. Otherwise, each policy remains its own permission named by the policy id.
+*/
+func groupPermissions(mapped []mappedPermission) []mappedPermission {
+	var keys []string
+	groups := map[string][]mappedPermission{}
+	for _, permission := range mapped {
+		if _, ok := groups[permission.key]; !ok {
+			keys = append(keys, permission.key)
+		}
+		groups[permission.key] = append(groups[permission.key], permission)
+	}
+
+	var result []mappedPermission
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) == 1 {
+			group[0].permission.Name = key
+			result = append(result, group[0])
+			continue
+		}
+		if !canMerge(group) {
+			result = append(result, group...)
+			continue
+		}
+		merged := group[0]
+		merged.permission.Name = key
+		merged.permission.Resources = []string{}
+		for _, permission := range group {
+			if !slices.Contains(merged.permission.Resources, permission.permission.Resources[0]) {
+				merged.permission.Resources = append(merged.permission.Resources, permission.permission.Resources[0])
+			}
+		}
+		result = append(result, merged)
+	}
+	return result
+}
+
+func canMerge(group []mappedPermission) bool {
+	first := group[0]
+	for _, permission := range group {
+		if len(permission.permission.Resources) != 1 ||
+			permission.permission.Type != first.permission.Type ||
+			permission.permission.Description != first.permission.Description ||
+			permission.permission.DecisionStrategy != first.permission.DecisionStrategy ||
+			!sameIds(permission.permission.Scopes, first.permission.Scopes) ||
+			!sameSubjects(permission.subjects, first.subjects) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSubjects(a []string, b []string) bool {
+	lowerA, lowerB := make([]string, len(a)), make([]string, len(b))
+	for i, subject := range a {
+		lowerA[i] = strings.ToLower(subject)
+	}
+	for i, subject := range b {
+		lowerB[i] = strings.ToLower(subject)
+	}
+	return sameIds(slices.Compact(lowerA), slices.Compact(lowerB))
+}
+
+// prepareSubjectPolicy validates subject and, when no policy grants the subject, records a policy to create
+func (s *resourceServer) prepareSubjectPolicy(subject string) error {
+	lower := strings.ToLower(subject)
+	if _, ok := s.subjectPolicy[lower]; ok {
+		return nil
+	}
+	if slices.Contains(s.pendingSubjects, lower) {
+		return nil
+	}
+
+	policy := kcClient.Policy{
+		Name:             "hexa " + subject,
+		Description:      "Created by Hexa for " + subject,
+		Logic:            kcClient.LogicPositive,
+		DecisionStrategy: kcClient.DecisionUnanimous,
+	}
+	switch {
+	case strings.HasPrefix(lower, subjectRole):
+		roleName := subject[len(subjectRole):]
+		containerUuid := ""
+		if clientId, clientRole, found := strings.Cut(roleName, "/"); found {
+			for uuid, id := range s.clientIds {
+				if id == clientId {
+					containerUuid, roleName = uuid, clientRole
+					break
+				}
+			}
+		}
+		role, err := s.client.GetRole(containerUuid, roleName)
+		if err != nil {
+			return fmt.Errorf("keycloak role for %s: %w", subject, err)
+		}
+		policy.Type = kcClient.PolicyTypeRole
+		policy.Roles = []kcClient.PolicyRole{{Id: role.Id}}
+	case strings.HasPrefix(lower, subjectUser):
+		user, err := s.client.GetUserByUsername(subject[len(subjectUser):])
+		if err != nil {
+			return err
+		}
+		policy.Type = kcClient.PolicyTypeUser
+		policy.Users = []string{user.Id}
+	case strings.HasPrefix(lower, subjectGroup):
+		group, err := s.client.GetGroupByPath(subject[len(subjectGroup):])
+		if err != nil {
+			return fmt.Errorf("keycloak group for %s: %w", subject, err)
+		}
+		policy.Type = kcClient.PolicyTypeGroup
+		policy.Groups = []kcClient.PolicyGroup{{Id: group.Id}}
+	default:
+		return fmt.Errorf("unsupported subject %s (expecting role:, user: or group:)", subject)
+	}
+
+	s.pendingPolicies = append(s.pendingPolicies, policy)
+	s.pendingSubjects = append(s.pendingSubjects, lower)
+	return nil
+}
+
+// createSubjectPolicies creates the policies recorded by prepareSubjectPolicy
+func (s *resourceServer) createSubjectPolicies() error {
+	for i, policy := range s.pendingPolicies {
+		created, err := s.client.CreatePolicy(s.clientUuid, policy)
+		if err != nil {
+			return err
+		}
+		s.subjectPolicy[s.pendingSubjects[i]] = created.Id
+	}
+	s.pendingPolicies, s.pendingSubjects = nil, nil
+	return nil
+}
+
+// subjectPolicyIds returns the ids of the policies granting subjects
+func (s *resourceServer) subjectPolicyIds(subjects []string) []string {
+	policyIds := []string{}
+	for _, subject := range subjects {
+		policyId := s.subjectPolicy[strings.ToLower(subject)]
+		if !slices.Contains(policyIds, policyId) {
+			policyIds = append(policyIds, policyId)
+		}
+	}
+	return policyIds
+}
+
+func (s *resourceServer) resourceId(name string) string {
+	for id, resource := range s.resources {
+		if resource.Name == name {
+			return id
+		}
+	}
+	return ""
+}
+
+func (s *resourceServer) scopeId(name string) string {
+	for id, scope := range s.scopes {
+		if scope.Name == name {
+			return id
+		}
+	}
+	return ""
+}
+
+func permissionEquals(current kcClient.Permission, desired kcClient.Permission) bool {
+	return current.Type == desired.Type &&
+		current.Description == desired.Description &&
+		current.DecisionStrategy == desired.DecisionStrategy &&
+		sameIds(current.Resources, desired.Resources) &&
+		sameIds(current.Scopes, desired.Scopes) &&
+		sameIds(current.Policies, desired.Policies)
+}
+
+func sameIds(a []string, b []string) bool {
+	sortedA, sortedB := slices.Clone(a), slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
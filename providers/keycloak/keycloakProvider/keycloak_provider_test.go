@@ -0,0 +1,337 @@
+package keycloakProvider
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/providers/keycloak/keycloaktestsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRealm struct {
+	fake       *keycloaktestsupport.FakeKeycloak
+	provider   *KeycloakProvider
+	info       policyprovider.IntegrationInfo
+	app        policyprovider.ApplicationInfo
+	clientUuid string
+}
+
+// setupRealm creates a photos client with album and photo resources, view/edit/delete scopes and some permissions
+func setupRealm(t *testing.T) *testRealm {
+	t.Helper()
+	fake := keycloaktestsupport.NewFakeKeycloak("hexa")
+	t.Cleanup(fake.Close)
+
+	clientUuid := fake.AddClient("photos", true)
+	fake.AddClient("account", false)
+	adminRole := fake.AddRealmRole("admin")
+	editorRole := fake.AddClientRole(clientUuid, "editor")
+	alice := fake.AddUser("alice")
+	staff := fake.AddGroup("/staff")
+
+	album := fake.AddResource(clientUuid, "album", "view", "edit")
+	photo := fake.AddResource(clientUuid, "photo", "view", "edit", "delete")
+	view := fake.AddScope(clientUuid, "view")
+	edit := fake.AddScope(clientUuid, "edit")
+
+	admins := fake.AddRolePolicy(clientUuid, "admins", adminRole)
+	editors := fake.AddRolePolicy(clientUuid, "editors", editorRole)
+	alicePolicy := fake.AddUserPolicy(clientUuid, "alice only", alice)
+	staffPolicy := fake.AddGroupPolicy(clientUuid, "staff", staff)
+	jsPolicy := fake.AddPolicy(clientUuid, "business hours", "time", "POSITIVE", nil)
+
+	fake.AddPermission(clientUuid, "admin access", "resource", "AFFIRMATIVE", []string{album, photo}, nil, []string{admins})
+	fake.AddPermission(clientUuid, "view photos", "scope", "AFFIRMATIVE", []string{photo}, []string{view}, []string{staffPolicy, alicePolicy, jsPolicy})
+	fake.AddPermission(clientUuid, "edit anything", "scope", "UNANIMOUS", nil, []string{edit}, []string{editors})
+	fake.AddPermission(clientUuid, "deny", "scope", "AFFIRMATIVE", nil, []string{view}, nil)
+	return &testRealm{
+		fake:       fake,
+		provider:   NewKeycloakProvider(WithHttpClient(fake.Server.Client())),
+		info:       policyprovider.IntegrationInfo{Name: ProviderTypeKeycloak, Key: fake.Key()},
+		app:        policyprovider.ApplicationInfo{ObjectID: clientUuid, Name: "photos"},
+		clientUuid: clientUuid,
+	}
+}
+
+func policyMap(policies []hexapolicy.PolicyInfo) map[string]hexapolicy.PolicyInfo {
+	result := map[string]hexapolicy.PolicyInfo{}
+	for _, policy := range policies {
+		result[*policy.Meta.PolicyId] = policy
+	}
+	return result
+}
+
+func TestKeycloakProvider_DiscoverApplications(t *testing.T) {
+	realm := setupRealm(t)
+	assert.Equal(t, ProviderTypeKeycloak, realm.provider.Name())
+
+	apps, err := realm.provider.DiscoverApplications(realm.info)
+	assert.NoError(t, err)
+	assert.Len(t, apps, 1)
+	assert.Equal(t, realm.clientUuid, apps[0].ObjectID)
+	assert.Equal(t, "photos", apps[0].Name)
+	assert.Equal(t, "photos application", apps[0].Description)
+
+	_, err = realm.provider.DiscoverApplications(policyprovider.IntegrationInfo{Name: ProviderTypeKeycloak, Key: []byte("{}")})
+	assert.Error(t, err)
+}
+
+func TestKeycloakProvider_GetPolicyInfo(t *testing.T) {
+	realm := setupRealm(t)
+
+	policies, err := realm.provider.GetPolicyInfo(realm.info, realm.app)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 4)
+	byId := policyMap(policies)
+
+	// a resource permission with two resources becomes a policy per resource
+	albumAdmin := byId["admin access/album"]
+	assert.Equal(t, hexapolicy.ObjectInfo("album"), albumAdmin.Object)
+	assert.Empty(t, albumAdmin.Actions)
+	assert.Equal(t, hexapolicy.SubjectInfo{"role:admin"}, albumAdmin.Subjects)
+	assert.Equal(t, "admin access", albumAdmin.Meta.SourceData[SourcePermission])
+	assert.Equal(t, "resource", albumAdmin.Meta.SourceData[SourcePermissionType])
+	assert.Equal(t, ProviderTypeKeycloak, albumAdmin.Meta.ProviderType)
+	assert.Equal(t, realm.clientUuid, *albumAdmin.Meta.PapId)
+	assert.Equal(t, hexapolicy.ObjectInfo("photo"), byId["admin access/photo"].Object)
+
+	// the time policy cannot be mapped and is omitted from the subjects
+	viewPhotos := byId["view photos"]
+	assert.Equal(t, hexapolicy.ObjectInfo("photo"), viewPhotos.Object)
+	assert.Equal(t, []hexapolicy.ActionInfo{"view"}, viewPhotos.Actions)
+	assert.True(t, viewPhotos.Subjects.Equals(hexapolicy.SubjectInfo{"group:/staff", "user:alice"}))
+
+	editAnything := byId["edit anything"]
+	assert.Equal(t, hexapolicy.ObjectInfo(""), editAnything.Object)
+	assert.Equal(t, hexapolicy.SubjectInfo{"role:photos/editor"}, editAnything.Subjects)
+	assert.Equal(t, "UNANIMOUS", editAnything.Meta.SourceData[SourceDecisionStrategy])
+
+	// a permission without policies would grant any subject and is not returned
+	assert.NotContains(t, byId, "deny")
+
+	_, err = realm.provider.GetPolicyInfo(realm.info, policyprovider.ApplicationInfo{ObjectID: "unknown"})
+	assert.Error(t, err)
+	_, err = realm.provider.GetPolicyInfo(realm.info, policyprovider.ApplicationInfo{})
+	assert.ErrorContains(t, err, "missing keycloak client id")
+}
+
+func TestKeycloakProvider_RoundTrip(t *testing.T) {
+	realm := setupRealm(t)
+
+	policies, err := realm.provider.GetPolicyInfo(realm.info, realm.app)
+	assert.NoError(t, err)
+	before := realm.fake.Permissions(realm.clientUuid)
+	posts := realm.fake.RequestCount(http.MethodPost)
+
+	// the policies split from the multi-resource permission are regrouped, so nothing changes
+	status, err := realm.provider.SetPolicyInfo(realm.info, realm.app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, before, realm.fake.Permissions(realm.clientUuid))
+	assert.Equal(t, 0, realm.fake.RequestCount(http.MethodPut))
+	assert.Equal(t, 0, realm.fake.RequestCount(http.MethodDelete))
+	assert.Equal(t, posts, realm.fake.RequestCount(http.MethodPost))
+
+	difs, err := realm.provider.Reconcile(realm.info, realm.app, policies, true)
+	assert.NoError(t, err)
+	assert.Empty(t, difs)
+}
+
+func TestKeycloakProvider_RoundTrip_MultiResource(t *testing.T) {
+	realm := setupRealm(t)
+
+	policies, err := realm.provider.GetPolicyInfo(realm.info, realm.app)
+	assert.NoError(t, err)
+	byId := policyMap(policies)
+	before := realm.fake.Permissions(realm.clientUuid)
+	assert.Equal(t, "admin access", before[0].Name)
+	assert.Equal(t, []string{"album", "photo"}, before[0].Resources)
+
+	// removing one resource policy updates the original permission
+	var withoutPhoto []hexapolicy.PolicyInfo
+	for _, policy := range policies {
+		if *policy.Meta.PolicyId != "admin access/photo" {
+			withoutPhoto = append(withoutPhoto, policy)
+		}
+	}
+	_, err = realm.provider.SetPolicyInfo(realm.info, realm.app, withoutPhoto)
+	assert.NoError(t, err)
+	after := realm.fake.Permissions(realm.clientUuid)
+	assert.Equal(t, "admin access", after[0].Name)
+	assert.Equal(t, []string{"album"}, after[0].Resources)
+	assert.Equal(t, before[1:], after[1:])
+	assert.Equal(t, 1, realm.fake.RequestCount(http.MethodPut))
+	assert.Equal(t, 0, realm.fake.RequestCount(http.MethodDelete))
+
+	// restoring it re-adds the resource
+	_, err = realm.provider.SetPolicyInfo(realm.info, realm.app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, before, realm.fake.Permissions(realm.clientUuid))
+
+	// policies that no longer agree cannot be regrouped and become a permission each
+	photoAdmin := byId["admin access/photo"]
+	photoAdmin.Subjects = hexapolicy.SubjectInfo{"role:admin", "user:alice"}
+	split := []hexapolicy.PolicyInfo{byId["admin access/album"], photoAdmin}
+	_, err = realm.provider.SetPolicyInfo(realm.info, realm.app, split)
+	assert.NoError(t, err)
+	assert.Equal(t, []keycloaktestsupport.PermissionView{
+		{Name: "admin access/album", Type: "resource", DecisionStrategy: "AFFIRMATIVE", Resources: []string{"album"}, Policies: []string{"admins"}},
+		{Name: "admin access/photo", Type: "resource", DecisionStrategy: "AFFIRMATIVE", Resources: []string{"photo"}, Policies: []string{"admins", "alice only"}},
+		{Name: "deny", Type: "scope", DecisionStrategy: "AFFIRMATIVE", Scopes: []string{"view"}},
+	}, realm.fake.Permissions(realm.clientUuid))
+}
+
+func TestKeycloakProvider_SetPolicyInfo(t *testing.T) {
+	realm := setupRealm(t)
+	realm.fake.AddUser("bob")
+	realm.fake.AddGroup("/staff/managers")
+
+	viewId, editId, newId := "view photos", "edit anything", "manage albums"
+	policies := []hexapolicy.PolicyInfo{
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &viewId, Description: "Viewers"},
+			Subjects: hexapolicy.SubjectInfo{"group:/staff", "user:bob"},
+			Actions:  []hexapolicy.ActionInfo{"view"},
+			Object:   "photo",
+		},
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &editId, SourceData: map[string]interface{}{SourceDecisionStrategy: "UNANIMOUS"}},
+			Subjects: hexapolicy.SubjectInfo{"role:photos/editor"},
+			Actions:  []hexapolicy.ActionInfo{"edit"},
+		},
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: &newId},
+			Subjects: hexapolicy.SubjectInfo{"role:admin", "group:/staff/managers"},
+			Object:   "album",
+		},
+	}
+
+	status, err := realm.provider.SetPolicyInfo(realm.info, realm.app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	permissions := realm.fake.Permissions(realm.clientUuid)
+	assert.Equal(t, []keycloaktestsupport.PermissionView{
+		{Name: "deny", Type: "scope", DecisionStrategy: "AFFIRMATIVE", Scopes: []string{"view"}},
+		{Name: "edit anything", Type: "scope", DecisionStrategy: "UNANIMOUS", Scopes: []string{"edit"}, Policies: []string{"editors"}},
+		{Name: "manage albums", Type: "resource", DecisionStrategy: "AFFIRMATIVE", Resources: []string{"album"}, Policies: []string{"admins", "hexa group:/staff/managers"}},
+		{Name: "view photos", Description: "Viewers", Type: "scope", DecisionStrategy: "AFFIRMATIVE", Resources: []string{"photo"}, Scopes: []string{"view"}, Policies: []string{"business hours", "hexa user:bob", "staff"}},
+	}, permissions)
+	assert.Equal(t, []string{"admins", "alice only", "business hours", "editors", "hexa group:/staff/managers", "hexa user:bob", "staff"}, realm.fake.PolicyNames(realm.clientUuid))
+
+	// a second update re-uses the created policies and makes no changes
+	puts, posts := realm.fake.RequestCount(http.MethodPut), realm.fake.RequestCount(http.MethodPost)
+	_, err = realm.provider.SetPolicyInfo(realm.info, realm.app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, puts, realm.fake.RequestCount(http.MethodPut))
+	assert.Equal(t, posts, realm.fake.RequestCount(http.MethodPost))
+
+	difs, err := realm.provider.Reconcile(realm.info, realm.app, policies, true)
+	assert.NoError(t, err)
+	assert.Empty(t, difs)
+}
+
+func TestKeycloakProvider_NegativePermission(t *testing.T) {
+	realm := setupRealm(t)
+	denyId := realm.fake.AddPolicy(realm.clientUuid, "deny bob", "scope", "NEGATIVE", nil)
+	assert.NotEmpty(t, denyId)
+
+	policies, err := realm.provider.GetPolicyInfo(realm.info, realm.app)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 4)
+
+	_, err = realm.provider.SetPolicyInfo(realm.info, realm.app, nil)
+	assert.NoError(t, err)
+	permissions := realm.fake.Permissions(realm.clientUuid)
+	assert.Len(t, permissions, 2)
+	assert.Equal(t, "deny", permissions[0].Name)
+	assert.Equal(t, "deny bob", permissions[1].Name)
+}
+
+func TestKeycloakProvider_UnmappablePermissions(t *testing.T) {
+	realm := setupRealm(t)
+	policies, err := realm.provider.GetPolicyInfo(realm.info, realm.app)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 4)
+
+	// only unmapped (time) policies, and all of several policies required
+	edit := realm.fake.AddScope(realm.clientUuid, "edit")
+	timeOnly := realm.fake.AddPolicy(realm.clientUuid, "weekdays", "time", "POSITIVE", nil)
+	bob := realm.fake.AddUserPolicy(realm.clientUuid, "bob only", realm.fake.AddUser("bob"))
+	carol := realm.fake.AddUserPolicy(realm.clientUuid, "carol only", realm.fake.AddUser("carol"))
+	realm.fake.AddPermission(realm.clientUuid, "weekday edits", "scope", "AFFIRMATIVE", nil, []string{edit}, []string{timeOnly})
+	realm.fake.AddPermission(realm.clientUuid, "bob and carol", "scope", "UNANIMOUS", nil, []string{edit}, []string{bob, carol})
+
+	mapped, err := realm.provider.GetPolicyInfo(realm.info, realm.app)
+	assert.NoError(t, err)
+	assert.Equal(t, policies, mapped)
+	for _, policy := range mapped {
+		assert.NotEmpty(t, policy.Subjects, *policy.Meta.PolicyId)
+	}
+
+	// unmapped permissions are not deleted
+	_, err = realm.provider.SetPolicyInfo(realm.info, realm.app, mapped)
+	assert.NoError(t, err)
+	var names []string
+	for _, permission := range realm.fake.Permissions(realm.clientUuid) {
+		names = append(names, permission.Name)
+	}
+	assert.Equal(t, []string{"admin access", "bob and carol", "deny", "edit anything", "view photos", "weekday edits"}, names)
+}
+
+func TestKeycloakProvider_SetPolicyInfo_Errors(t *testing.T) {
+	realm := setupRealm(t)
+	id := "bad"
+	tests := []struct {
+		name   string
+		policy hexapolicy.PolicyInfo
+		err    string
+	}{
+		{"resource", hexapolicy.PolicyInfo{Subjects: hexapolicy.SubjectInfo{"role:admin"}, Object: "video"}, "keycloak resource video not found"},
+		{"scope", hexapolicy.PolicyInfo{Subjects: hexapolicy.SubjectInfo{"role:admin"}, Actions: []hexapolicy.ActionInfo{"print"}}, "keycloak scope print not found"},
+		{"no target", hexapolicy.PolicyInfo{Subjects: hexapolicy.SubjectInfo{"role:admin"}}, "an object or actions are required"},
+		{"subject", hexapolicy.PolicyInfo{Subjects: hexapolicy.SubjectInfo{"any"}, Object: "album"}, "unsupported subject any"},
+		{"role", hexapolicy.PolicyInfo{Subjects: hexapolicy.SubjectInfo{"role:missing"}, Object: "album"}, "keycloak role for role:missing"},
+		{"user", hexapolicy.PolicyInfo{Subjects: hexapolicy.SubjectInfo{"user:zed"}, Object: "album"}, "keycloak user zed not found"},
+		{"condition", hexapolicy.PolicyInfo{
+			Meta:      hexapolicy.MetaInfo{PolicyId: &id},
+			Subjects:  hexapolicy.SubjectInfo{"role:admin"},
+			Object:    "album",
+			Condition: &conditions.ConditionInfo{Rule: "subject.type eq \"user\""},
+		}, "policy bad: conditions are not supported"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, err := realm.provider.SetPolicyInfo(realm.info, realm.app, []hexapolicy.PolicyInfo{test.policy})
+			assert.ErrorContains(t, err, test.err)
+			assert.Equal(t, http.StatusBadRequest, status)
+		})
+	}
+
+	dup := []hexapolicy.PolicyInfo{
+		{Meta: hexapolicy.MetaInfo{PolicyId: &id}, Object: "album"},
+		{Meta: hexapolicy.MetaInfo{PolicyId: &id}, Object: "photo"},
+	}
+	_, err := realm.provider.SetPolicyInfo(realm.info, realm.app, dup)
+	assert.ErrorContains(t, err, "duplicate policy id: bad")
+
+	// a subject policy is not created when a later policy is invalid
+	bobId := "bob"
+	realm.fake.AddUser("bob")
+	invalid := []hexapolicy.PolicyInfo{
+		{Meta: hexapolicy.MetaInfo{PolicyId: &bobId}, Subjects: hexapolicy.SubjectInfo{"user:bob"}, Object: "album"},
+		{Subjects: hexapolicy.SubjectInfo{"role:admin"}, Object: "video"},
+	}
+	status, err := realm.provider.SetPolicyInfo(realm.info, realm.app, invalid)
+	assert.ErrorContains(t, err, "keycloak resource video not found")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// nothing is changed when validation fails
+	assert.Len(t, realm.fake.Permissions(realm.clientUuid), 4)
+	assert.Equal(t, []string{"admins", "alice only", "business hours", "editors", "staff"}, realm.fake.PolicyNames(realm.clientUuid))
+	assert.Equal(t, 0, realm.fake.RequestCount(http.MethodDelete))
+	assert.Equal(t, 0, realm.fake.RequestCount(http.MethodPut))
+}
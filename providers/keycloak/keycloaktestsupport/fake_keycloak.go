@@ -0,0 +1,682 @@
+/*
+Package keycloaktestsupport provides an in-memory stand-in for the Keycloak Admin REST API (Authorization Services)
+served by httptest. Used by the kcClient and keycloakProvider tests.
+*/
+package keycloaktestsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	FakeClientId     = "hexa-admin"
+	FakeClientSecret = "hexa-secret"
+	fakeAccessToken  = "fake-keycloak-token"
+)
+
+type fakeClient struct {
+	Id                           string `json:"id"`
+	ClientId                     string `json:"clientId"`
+	Name                         string `json:"name,omitempty"`
+	Description                  string `json:"description,omitempty"`
+	AuthorizationServicesEnabled bool   `json:"authorizationServicesEnabled"`
+}
+
+type fakeRole struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	ClientRole  bool   `json:"clientRole"`
+	ContainerId string `json:"containerId"`
+}
+
+type fakeUser struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type fakeGroup struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type fakeScope struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type fakeResource struct {
+	Id     string      `json:"_id"`
+	Name   string      `json:"name"`
+	Scopes []fakeScope `json:"scopes"`
+}
+
+// fakePolicy holds both policies and permissions (resource and scope policies)
+type fakePolicy struct {
+	Id               string            `json:"id"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	Type             string            `json:"type"`
+	Logic            string            `json:"logic"`
+	DecisionStrategy string            `json:"decisionStrategy"`
+	Config           map[string]string `json:"config"`
+	resources        []string
+	scopes           []string
+	policies         []string
+}
+
+// policyRequest is the body of policy and permission create and update requests
+type policyRequest struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	Type             string   `json:"type"`
+	Logic            string   `json:"logic"`
+	DecisionStrategy string   `json:"decisionStrategy"`
+	Resources        []string `json:"resources"`
+	Scopes           []string `json:"scopes"`
+	Policies         []string `json:"policies"`
+	Roles            []struct {
+		Id       string `json:"id"`
+		Required bool   `json:"required"`
+	} `json:"roles"`
+	Users  []string `json:"users"`
+	Groups []struct {
+		Id             string `json:"id"`
+		Path           string `json:"path"`
+		ExtendChildren bool   `json:"extendChildren"`
+	} `json:"groups"`
+}
+
+type resourceServer struct {
+	resources []*fakeResource
+	scopes    []*fakeScope
+	policies  []*fakePolicy
+}
+
+// PermissionView is a permission with its resources, scopes and policies referenced by name
+type PermissionView struct {
+	Name             string
+	Description      string
+	Type             string
+	DecisionStrategy string
+	Resources        []string
+	Scopes           []string
+	Policies         []string
+}
+
+// FakeKeycloak is an httptest server implementing the subset of the Keycloak admin API used by kcClient
+type FakeKeycloak struct {
+	Server   *httptest.Server
+	Realm    string
+	Requests []string // Requests is the list of "METHOD path" admin requests received
+
+	mu      sync.Mutex
+	nextId  int
+	clients []*fakeClient
+	roles   []*fakeRole
+	users   []*fakeUser
+	groups  []*fakeGroup
+	authz   map[string]*resourceServer
+}
+
+func NewFakeKeycloak(realm string) *FakeKeycloak {
+	fake := &FakeKeycloak{Realm: realm, authz: map[string]*resourceServer{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /realms/{realm}/protocol/openid-connect/token", fake.handleToken)
+	admin := "/admin/realms/{realm}/"
+	authz := admin + "clients/{client}/authz/resource-server/"
+	fake.handle(mux, "GET "+admin+"clients", fake.getClients)
+	fake.handle(mux, "GET "+admin+"roles-by-id/{id}", fake.getRoleById)
+	fake.handle(mux, "GET "+admin+"roles/{name}", fake.getRole)
+	fake.handle(mux, "GET "+admin+"clients/{client}/roles/{name}", fake.getRole)
+	fake.handle(mux, "GET "+admin+"users", fake.getUsers)
+	fake.handle(mux, "GET "+admin+"users/{id}", fake.getUser)
+	fake.handle(mux, "GET "+admin+"groups/{id}", fake.getGroup)
+	fake.handle(mux, "GET "+admin+"group-by-path/{path...}", fake.getGroupByPath)
+	fake.handle(mux, "GET "+authz+"resource", fake.getResources)
+	fake.handle(mux, "GET "+authz+"scope", fake.getScopes)
+	fake.handle(mux, "GET "+authz+"policy", fake.getPolicies)
+	fake.handle(mux, "GET "+authz+"permission", fake.getPolicies)
+	fake.handle(mux, "GET "+authz+"policy/{id}/{association}", fake.getAssociations)
+	fake.handle(mux, "POST "+authz+"policy/{type}", fake.createPolicy)
+	fake.handle(mux, "POST "+authz+"permission/{type}", fake.createPolicy)
+	fake.handle(mux, "PUT "+authz+"permission/{type}/{id}", fake.updatePermission)
+	fake.handle(mux, "DELETE "+authz+"permission/{id}", fake.deletePermission)
+	fake.Server = httptest.NewServer(mux)
+	return fake
+}
+
+func (f *FakeKeycloak) Close() {
+	f.Server.Close()
+}
+
+// Key returns an integration key for the fake server
+func (f *FakeKeycloak) Key() []byte {
+	key, _ := json.Marshal(map[string]string{
+		"url":          f.Server.URL,
+		"realm":        f.Realm,
+		"clientId":     FakeClientId,
+		"clientSecret": FakeClientSecret,
+	})
+	return key
+}
+
+func (f *FakeKeycloak) AddClient(clientId string, authorizationEnabled bool) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	client := &fakeClient{Id: f.newId(), ClientId: clientId, Name: clientId, Description: clientId + " application", AuthorizationServicesEnabled: authorizationEnabled}
+	f.clients = append(f.clients, client)
+	if authorizationEnabled {
+		f.authz[client.Id] = &resourceServer{}
+	}
+	return client.Id
+}
+
+func (f *FakeKeycloak) AddRealmRole(name string) string {
+	return f.addRole(name, "")
+}
+
+func (f *FakeKeycloak) AddClientRole(clientUuid string, name string) string {
+	return f.addRole(name, clientUuid)
+}
+
+func (f *FakeKeycloak) addRole(name string, clientUuid string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	role := &fakeRole{Id: f.newId(), Name: name, ClientRole: clientUuid != "", ContainerId: clientUuid}
+	if clientUuid == "" {
+		role.ContainerId = f.Realm
+	}
+	f.roles = append(f.roles, role)
+	return role.Id
+}
+
+func (f *FakeKeycloak) AddUser(username string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user := &fakeUser{Id: f.newId(), Username: username}
+	f.users = append(f.users, user)
+	return user.Id
+}
+
+// AddGroup adds a group with a path such as /staff/admins
+func (f *FakeKeycloak) AddGroup(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	group := &fakeGroup{Id: f.newId(), Name: path[strings.LastIndex(path, "/")+1:], Path: path}
+	f.groups = append(f.groups, group)
+	return group.Id
+}
+
+func (f *FakeKeycloak) AddScope(clientUuid string, name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.scope(f.authz[clientUuid], name).Id
+}
+
+// AddResource adds a resource and its scopes (scopes are created if needed)
+func (f *FakeKeycloak) AddResource(clientUuid string, name string, scopes ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	server := f.authz[clientUuid]
+	resource := &fakeResource{Id: f.newId(), Name: name, Scopes: []fakeScope{}}
+	for _, scopeName := range scopes {
+		resource.Scopes = append(resource.Scopes, *f.scope(server, scopeName))
+	}
+	server.resources = append(server.resources, resource)
+	return resource.Id
+}
+
+// AddPolicy adds a policy of any type, for example a "js" policy or a role policy with NEGATIVE logic
+func (f *FakeKeycloak) AddPolicy(clientUuid string, name string, policyType string, logic string, config map[string]string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if config == nil {
+		config = map[string]string{}
+	}
+	policy := &fakePolicy{Id: f.newId(), Name: name, Type: policyType, Logic: logic, DecisionStrategy: "UNANIMOUS", Config: config}
+	server := f.authz[clientUuid]
+	server.policies = append(server.policies, policy)
+	return policy.Id
+}
+
+func (f *FakeKeycloak) AddRolePolicy(clientUuid string, name string, roleIds ...string) string {
+	var roles []map[string]interface{}
+	for _, id := range roleIds {
+		roles = append(roles, map[string]interface{}{"id": id, "required": false})
+	}
+	rolesJson, _ := json.Marshal(roles)
+	return f.AddPolicy(clientUuid, name, "role", "POSITIVE", map[string]string{"roles": string(rolesJson)})
+}
+
+func (f *FakeKeycloak) AddUserPolicy(clientUuid string, name string, userIds ...string) string {
+	usersJson, _ := json.Marshal(userIds)
+	return f.AddPolicy(clientUuid, name, "user", "POSITIVE", map[string]string{"users": string(usersJson)})
+}
+
+func (f *FakeKeycloak) AddGroupPolicy(clientUuid string, name string, groupIds ...string) string {
+	var groups []map[string]interface{}
+	for _, id := range groupIds {
+		groups = append(groups, map[string]interface{}{"id": id, "extendChildren": false})
+	}
+	groupsJson, _ := json.Marshal(groups)
+	return f.AddPolicy(clientUuid, name, "group", "POSITIVE", map[string]string{"groups": string(groupsJson)})
+}
+
+// AddPermission adds a resource or scope permission referring to resource, scope and policy ids
+func (f *FakeKeycloak) AddPermission(clientUuid string, name string, permissionType string, decisionStrategy string, resources []string, scopes []string, policies []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	permission := &fakePolicy{
+		Id:               f.newId(),
+		Name:             name,
+		Type:             permissionType,
+		Logic:            "POSITIVE",
+		DecisionStrategy: decisionStrategy,
+		Config:           map[string]string{},
+		resources:        resources,
+		scopes:           scopes,
+		policies:         policies,
+	}
+	server := f.authz[clientUuid]
+	server.policies = append(server.policies, permission)
+	return permission.Id
+}
+
+// Permissions returns the permissions of a client by name, sorted by permission name
+func (f *FakeKeycloak) Permissions(clientUuid string) []PermissionView {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	server := f.authz[clientUuid]
+	var views []PermissionView
+	for _, policy := range server.policies {
+		if !isPermission(policy.Type) {
+			continue
+		}
+		view := PermissionView{Name: policy.Name, Description: policy.Description, Type: policy.Type, DecisionStrategy: policy.DecisionStrategy}
+		for _, id := range policy.resources {
+			if resource := server.resource(id); resource != nil {
+				view.Resources = append(view.Resources, resource.Name)
+			}
+		}
+		for _, id := range policy.scopes {
+			if scope := server.scopeById(id); scope != nil {
+				view.Scopes = append(view.Scopes, scope.Name)
+			}
+		}
+		for _, id := range policy.policies {
+			if associated := server.policy(id); associated != nil {
+				view.Policies = append(view.Policies, associated.Name)
+			}
+		}
+		slices.Sort(view.Resources)
+		slices.Sort(view.Scopes)
+		slices.Sort(view.Policies)
+		views = append(views, view)
+	}
+	slices.SortFunc(views, func(a, b PermissionView) int { return strings.Compare(a.Name, b.Name) })
+	return views
+}
+
+// PolicyNames returns the names of the (non-permission) policies of a client
+func (f *FakeKeycloak) PolicyNames(clientUuid string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for _, policy := range f.authz[clientUuid].policies {
+		if !isPermission(policy.Type) {
+			names = append(names, policy.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// RequestCount returns the number of admin requests with the given method
+func (f *FakeKeycloak) RequestCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, request := range f.Requests {
+		if strings.HasPrefix(request, method+" ") {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *FakeKeycloak) newId() string {
+	f.nextId++
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", f.nextId)
+}
+
+func (f *FakeKeycloak) scope(server *resourceServer, name string) *fakeScope {
+	for _, scope := range server.scopes {
+		if scope.Name == name {
+			return scope
+		}
+	}
+	scope := &fakeScope{Id: f.newId(), Name: name}
+	server.scopes = append(server.scopes, scope)
+	return scope
+}
+
+func (s *resourceServer) resource(id string) *fakeResource {
+	for _, resource := range s.resources {
+		if resource.Id == id {
+			return resource
+		}
+	}
+	return nil
+}
+
+func (s *resourceServer) scopeById(id string) *fakeScope {
+	for _, scope := range s.scopes {
+		if scope.Id == id {
+			return scope
+		}
+	}
+	return nil
+}
+
+func (s *resourceServer) policy(id string) *fakePolicy {
+	for _, policy := range s.policies {
+		if policy.Id == id {
+			return policy
+		}
+	}
+	return nil
+}
+
+func isPermission(policyType string) bool {
+	return policyType == "resource" || policyType == "scope"
+}
+
+func (f *FakeKeycloak) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("realm") != f.Realm {
+		http.Error(w, "realm not found", http.StatusNotFound)
+		return
+	}
+	clientId, secret, ok := r.BasicAuth()
+	if !ok {
+		_ = r.ParseForm()
+		clientId, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != FakeClientId || secret != FakeClientSecret {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"unauthorized_client"}`))
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{"access_token": fakeAccessToken, "token_type": "Bearer", "expires_in": 300})
+}
+
+// handle registers an admin handler that checks the access token and realm and holds the lock
+func (f *FakeKeycloak) handle(mux *http.ServeMux, pattern string, handler func(w http.ResponseWriter, r *http.Request, server *resourceServer)) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+fakeAccessToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.Requests = append(f.Requests, r.Method+" "+r.URL.Path)
+		if r.PathValue("realm") != f.Realm {
+			http.Error(w, "realm not found", http.StatusNotFound)
+			return
+		}
+		var server *resourceServer
+		if clientUuid := r.PathValue("client"); clientUuid != "" && strings.Contains(r.URL.Path, "/authz/") {
+			server = f.authz[clientUuid]
+			if server == nil {
+				http.Error(w, "client not found", http.StatusNotFound)
+				return
+			}
+		}
+		handler(w, r, server)
+	})
+}
+
+func (f *FakeKeycloak) getClients(w http.ResponseWriter, _ *http.Request, _ *resourceServer) {
+	writeJson(w, http.StatusOK, f.clients)
+}
+
+func (f *FakeKeycloak) getRoleById(w http.ResponseWriter, r *http.Request, _ *resourceServer) {
+	for _, role := range f.roles {
+		if role.Id == r.PathValue("id") {
+			writeJson(w, http.StatusOK, role)
+			return
+		}
+	}
+	http.Error(w, "role not found", http.StatusNotFound)
+}
+
+func (f *FakeKeycloak) getRole(w http.ResponseWriter, r *http.Request, _ *resourceServer) {
+	container := r.PathValue("client")
+	if container == "" {
+		container = f.Realm
+	}
+	for _, role := range f.roles {
+		if role.Name == r.PathValue("name") && role.ContainerId == container {
+			writeJson(w, http.StatusOK, role)
+			return
+		}
+	}
+	http.Error(w, "role not found", http.StatusNotFound)
+}
+
+func (f *FakeKeycloak) getUsers(w http.ResponseWriter, r *http.Request, _ *resourceServer) {
+	users := []*fakeUser{}
+	for _, user := range f.users {
+		if user.Username == r.URL.Query().Get("username") {
+			users = append(users, user)
+		}
+	}
+	writeJson(w, http.StatusOK, users)
+}
+
+func (f *FakeKeycloak) getUser(w http.ResponseWriter, r *http.Request, _ *resourceServer) {
+	for _, user := range f.users {
+		if user.Id == r.PathValue("id") {
+			writeJson(w, http.StatusOK, user)
+			return
+		}
+	}
+	http.Error(w, "user not found", http.StatusNotFound)
+}
+
+func (f *FakeKeycloak) getGroup(w http.ResponseWriter, r *http.Request, _ *resourceServer) {
+	for _, group := range f.groups {
+		if group.Id == r.PathValue("id") {
+			writeJson(w, http.StatusOK, group)
+			return
+		}
+	}
+	http.Error(w, "group not found", http.StatusNotFound)
+}
+
+func (f *FakeKeycloak) getGroupByPath(w http.ResponseWriter, r *http.Request, _ *resourceServer) {
+	for _, group := range f.groups {
+		if group.Path == "/"+r.PathValue("path") {
+			writeJson(w, http.StatusOK, group)
+			return
+		}
+	}
+	http.Error(w, "group not found", http.StatusNotFound)
+}
+
+func (f *FakeKeycloak) getResources(w http.ResponseWriter, _ *http.Request, server *resourceServer) {
+	writeJson(w, http.StatusOK, nonNil(server.resources))
+}
+
+func (f *FakeKeycloak) getScopes(w http.ResponseWriter, _ *http.Request, server *resourceServer) {
+	writeJson(w, http.StatusOK, nonNil(server.scopes))
+}
+
+// getPolicies lists permissions (permission endpoint or permission=true) or other policies (permission=false)
+func (f *FakeKeycloak) getPolicies(w http.ResponseWriter, r *http.Request, server *resourceServer) {
+	permissions := strings.HasSuffix(r.URL.Path, "/permission") || r.URL.Query().Get("permission") == "true"
+	all := strings.HasSuffix(r.URL.Path, "/policy") && r.URL.Query().Get("permission") == ""
+	policies := []*fakePolicy{}
+	for _, policy := range server.policies {
+		if all || isPermission(policy.Type) == permissions {
+			policies = append(policies, policy)
+		}
+	}
+	writeJson(w, http.StatusOK, policies)
+}
+
+func (f *FakeKeycloak) getAssociations(w http.ResponseWriter, r *http.Request, server *resourceServer) {
+	policy := server.policy(r.PathValue("id"))
+	if policy == nil {
+		http.Error(w, "policy not found", http.StatusNotFound)
+		return
+	}
+	var result []interface{}
+	switch r.PathValue("association") {
+	case "resources":
+		for _, id := range policy.resources {
+			if resource := server.resource(id); resource != nil {
+				result = append(result, map[string]string{"_id": resource.Id, "name": resource.Name})
+			}
+		}
+	case "scopes":
+		for _, id := range policy.scopes {
+			if scope := server.scopeById(id); scope != nil {
+				result = append(result, scope)
+			}
+		}
+	case "associatedPolicies":
+		for _, id := range policy.policies {
+			if associated := server.policy(id); associated != nil {
+				result = append(result, associated)
+			}
+		}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJson(w, http.StatusOK, nonNil(result))
+}
+
+func (f *FakeKeycloak) createPolicy(w http.ResponseWriter, r *http.Request, server *resourceServer) {
+	var req policyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, existing := range server.policies {
+		if existing.Name == req.Name {
+			writeJson(w, http.StatusConflict, map[string]string{"errorMessage": "Policy with name [" + req.Name + "] already exists"})
+			return
+		}
+	}
+	policy, status, msg := f.toPolicy(r.PathValue("type"), req, server)
+	if policy == nil {
+		http.Error(w, msg, status)
+		return
+	}
+	policy.Id = f.newId()
+	server.policies = append(server.policies, policy)
+	writeJson(w, http.StatusCreated, policy)
+}
+
+func (f *FakeKeycloak) updatePermission(w http.ResponseWriter, r *http.Request, server *resourceServer) {
+	existing := server.policy(r.PathValue("id"))
+	if existing == nil || !isPermission(existing.Type) {
+		http.Error(w, "permission not found", http.StatusNotFound)
+		return
+	}
+	var req policyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	policy, status, msg := f.toPolicy(r.PathValue("type"), req, server)
+	if policy == nil {
+		http.Error(w, msg, status)
+		return
+	}
+	policy.Id = existing.Id
+	*existing = *policy
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *FakeKeycloak) deletePermission(w http.ResponseWriter, r *http.Request, server *resourceServer) {
+	id := r.PathValue("id")
+	index := slices.IndexFunc(server.policies, func(policy *fakePolicy) bool { return policy.Id == id })
+	if index < 0 {
+		http.Error(w, "permission not found", http.StatusNotFound)
+		return
+	}
+	server.policies = slices.Delete(server.policies, index, index+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *FakeKeycloak) toPolicy(policyType string, req policyRequest, server *resourceServer) (*fakePolicy, int, string) {
+	policy := &fakePolicy{
+		Name:             req.Name,
+		Description:      req.Description,
+		Type:             policyType,
+		Logic:            req.Logic,
+		DecisionStrategy: req.DecisionStrategy,
+		Config:           map[string]string{},
+	}
+	if policy.Logic == "" {
+		policy.Logic = "POSITIVE"
+	}
+	if policy.DecisionStrategy == "" {
+		policy.DecisionStrategy = "UNANIMOUS"
+	}
+	switch policyType {
+	case "resource", "scope":
+		for _, id := range req.Resources {
+			if server.resource(id) == nil {
+				return nil, http.StatusBadRequest, "unknown resource " + id
+			}
+		}
+		for _, id := range req.Scopes {
+			if server.scopeById(id) == nil {
+				return nil, http.StatusBadRequest, "unknown scope " + id
+			}
+		}
+		for _, id := range req.Policies {
+			if server.policy(id) == nil {
+				return nil, http.StatusBadRequest, "unknown policy " + id
+			}
+		}
+		policy.resources, policy.scopes, policy.policies = req.Resources, req.Scopes, req.Policies
+	case "role":
+		rolesJson, _ := json.Marshal(req.Roles)
+		policy.Config["roles"] = string(rolesJson)
+	case "user":
+		usersJson, _ := json.Marshal(req.Users)
+		policy.Config["users"] = string(usersJson)
+	case "group":
+		groupsJson, _ := json.Marshal(req.Groups)
+		policy.Config["groups"] = string(groupsJson)
+	default:
+		return nil, http.StatusBadRequest, "unsupported policy type " + policyType
+	}
+	return policy, 0, ""
+}
+
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,339 @@
+/*
+Package oktaClient is a minimal client for the Okta management API covering applications and their group and user
+assignments. List operations follow the Okta `Link: <...>; rel="next"` pagination headers.
+*/
+package oktaClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	SignOnModeOidc   string = "OPENID_CONNECT"
+	SignOnModeSaml2  string = "SAML_2_0"
+	SignOnModeSaml11 string = "SAML_1_1"
+
+	ScopeUser  string = "USER"
+	ScopeGroup string = "GROUP"
+
+	DefaultPageLimit     = 200
+	DefaultRoleAttribute = "role"
+
+	maxPages = 10000 // maxPages stops pagination that does not end
+)
+
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+/*
+Credentials is the integration key for Okta, for example:
+
+	{"orgUrl": "https://dev-123456.okta.com", "apiToken": "00abc...", "roleAttribute": "roles", "multiValuedRoles": true}
+
+The API token requires read access to applications, groups and users and permission to manage app assignments.
+*/
+type Credentials struct {
+	OrgUrl           string `json:"orgUrl"`
+	ApiToken         string `json:"apiToken"`
+	RoleAttribute    string `json:"roleAttribute,omitempty"`    // RoleAttribute is the app assignment profile attribute holding app roles (default role)
+	MultiValuedRoles bool   `json:"multiValuedRoles,omitempty"` // MultiValuedRoles is true when RoleAttribute is an array
+}
+
+type App struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Status     string `json:"status"`
+	SignOnMode string `json:"signOnMode"`
+}
+
+type Group struct {
+	Id      string `json:"id"`
+	Profile struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	} `json:"profile"`
+}
+
+type User struct {
+	Id      string `json:"id"`
+	Status  string `json:"status,omitempty"`
+	Profile struct {
+		Login string `json:"login"`
+		Email string `json:"email,omitempty"`
+	} `json:"profile"`
+}
+
+// GroupAssignment is an application group assignment (expanded with the group)
+type GroupAssignment struct {
+	Id       string                 `json:"id"`
+	Priority int                    `json:"priority,omitempty"`
+	Profile  map[string]interface{} `json:"profile,omitempty"`
+	Embedded *struct {
+		Group *Group `json:"group,omitempty"`
+	} `json:"_embedded,omitempty"`
+}
+
+func (g GroupAssignment) GroupName() string {
+	if g.Embedded == nil || g.Embedded.Group == nil {
+		return ""
+	}
+	return g.Embedded.Group.Profile.Name
+}
+
+// UserAssignment is an application user assignment (expanded with the user). Scope is USER for direct assignments and
+// GROUP for assignments inherited from a group assignment.
+type UserAssignment struct {
+	Id       string                 `json:"id"`
+	Scope    string                 `json:"scope,omitempty"`
+	Profile  map[string]interface{} `json:"profile,omitempty"`
+	Embedded *struct {
+		User *User `json:"user,omitempty"`
+	} `json:"_embedded,omitempty"`
+}
+
+func (u UserAssignment) Login() string {
+	if u.Embedded == nil || u.Embedded.User == nil {
+		return ""
+	}
+	return u.Embedded.User.Profile.Login
+}
+
+type OktaClient struct {
+	Creds      Credentials
+	PageLimit  int
+	httpClient HTTPClient
+}
+
+func NewOktaClient(key []byte, httpClient HTTPClient) (*OktaClient, error) {
+	var creds Credentials
+	if err := json.Unmarshal(key, &creds); err != nil {
+		return nil, fmt.Errorf("invalid okta integration key: %w", err)
+	}
+	if creds.OrgUrl == "" || creds.ApiToken == "" {
+		return nil, errors.New("invalid okta integration key: orgUrl and apiToken are required")
+	}
+	creds.OrgUrl = strings.TrimSuffix(creds.OrgUrl, "/")
+	if creds.RoleAttribute == "" {
+		creds.RoleAttribute = DefaultRoleAttribute
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &OktaClient{Creds: creds, PageLimit: DefaultPageLimit, httpClient: httpClient}, nil
+}
+
+// GetApps returns the active OIDC and SAML applications
+func (c *OktaClient) GetApps() ([]App, error) {
+	var apps []App
+	err := getAll(c, c.apiUrl("apps", url.Values{"filter": {`status eq "ACTIVE"`}}), &apps)
+	if err != nil {
+		return nil, err
+	}
+	var ssoApps []App
+	for _, app := range apps {
+		switch app.SignOnMode {
+		case SignOnModeOidc, SignOnModeSaml2, SignOnModeSaml11:
+			ssoApps = append(ssoApps, app)
+		}
+	}
+	return ssoApps, nil
+}
+
+func (c *OktaClient) GetGroupAssignments(appId string) ([]GroupAssignment, error) {
+	var assignments []GroupAssignment
+	err := getAll(c, c.apiUrl("apps/"+url.PathEscape(appId)+"/groups", url.Values{"expand": {"group"}}), &assignments)
+	return assignments, err
+}
+
+func (c *OktaClient) GetUserAssignments(appId string) ([]UserAssignment, error) {
+	var assignments []UserAssignment
+	err := getAll(c, c.apiUrl("apps/"+url.PathEscape(appId)+"/users", url.Values{"expand": {"user"}}), &assignments)
+	return assignments, err
+}
+
+// AssignGroup creates or updates the assignment of a group to an application
+func (c *OktaClient) AssignGroup(appId string, groupId string, profile map[string]interface{}) error {
+	body := map[string]interface{}{"profile": profile}
+	return c.doJson(http.MethodPut, c.apiUrl("apps/"+url.PathEscape(appId)+"/groups/"+url.PathEscape(groupId), nil), body, nil)
+}
+
+func (c *OktaClient) UnassignGroup(appId string, groupId string) error {
+	return c.doJson(http.MethodDelete, c.apiUrl("apps/"+url.PathEscape(appId)+"/groups/"+url.PathEscape(groupId), nil), nil, nil)
+}
+
+// AssignUser directly assigns a user to an application
+func (c *OktaClient) AssignUser(appId string, userId string, profile map[string]interface{}) error {
+	body := map[string]interface{}{"id": userId, "scope": ScopeUser, "profile": profile}
+	return c.doJson(http.MethodPost, c.apiUrl("apps/"+url.PathEscape(appId)+"/users", nil), body, nil)
+}
+
+// UpdateUserAssignment updates the profile of a user assignment
+func (c *OktaClient) UpdateUserAssignment(appId string, userId string, profile map[string]interface{}) error {
+	body := map[string]interface{}{"profile": profile}
+	return c.doJson(http.MethodPost, c.apiUrl("apps/"+url.PathEscape(appId)+"/users/"+url.PathEscape(userId), nil), body, nil)
+}
+
+func (c *OktaClient) UnassignUser(appId string, userId string) error {
+	return c.doJson(http.MethodDelete, c.apiUrl("apps/"+url.PathEscape(appId)+"/users/"+url.PathEscape(userId), nil), nil, nil)
+}
+
+// GetGroupByName returns the Okta group with the exact name
+func (c *OktaClient) GetGroupByName(name string) (*Group, error) {
+	var groups []Group
+	search := fmt.Sprintf(`profile.name eq "%s"`, strings.ReplaceAll(name, `"`, `\"`))
+	if err := c.doJson(http.MethodGet, c.apiUrl("groups", url.Values{"search": {search}}), nil, &groups); err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.Profile.Name == name {
+			return &group, nil
+		}
+	}
+	return nil, fmt.Errorf("okta group %s not found", name)
+}
+
+// GetUser returns the Okta user by login or id
+func (c *OktaClient) GetUser(login string) (*User, error) {
+	var user User
+	if err := c.doJson(http.MethodGet, c.apiUrl("users/"+url.PathEscape(login), nil), nil, &user); err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("okta user %s not found", login)
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *OktaClient) apiUrl(path string, query url.Values) string {
+	apiUrl := c.Creds.OrgUrl + "/api/v1/" + path
+	if len(query) > 0 {
+		apiUrl += "?" + query.Encode()
+	}
+	return apiUrl
+}
+
+// getAll retrieves all pages of a list following the rel="next" links. Links must be on the Okta org (OrgUrl) so that
+// the API token is not sent elsewhere.
+func getAll[T any](c *OktaClient, listUrl string, result *[]T) error {
+	parsed, err := url.Parse(listUrl)
+	if err != nil {
+		return err
+	}
+	query := parsed.Query()
+	query.Set("limit", fmt.Sprint(c.PageLimit))
+	parsed.RawQuery = query.Encode()
+	origin, err := url.Parse(c.Creds.OrgUrl)
+	if err != nil {
+		return err
+	}
+
+	pageUrl := parsed
+	for pages := 0; pages < maxPages; pages++ {
+		var page []T
+		resp, err := c.do(http.MethodGet, pageUrl.String(), nil, &page)
+		if err != nil {
+			return err
+		}
+		*result = append(*result, page...)
+		var nextUrl *url.URL
+		for _, link := range resp.Header.Values("Link") {
+			if match := nextLinkRegex.FindStringSubmatch(link); match != nil {
+				if nextUrl, err = resolveLink(origin, pageUrl, match[1]); err != nil {
+					return err
+				}
+			}
+		}
+		if nextUrl == nil {
+			return nil
+		}
+		pageUrl = nextUrl
+	}
+	return fmt.Errorf("okta list %s: more than %d pages", listUrl, maxPages)
+}
+
+// resolveLink returns the absolute url of a next page link relative to the page url. The link must have the scheme and
+// host of origin.
+func resolveLink(origin *url.URL, pageUrl *url.URL, link string) (*url.URL, error) {
+	linkUrl, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	nextUrl := pageUrl.ResolveReference(linkUrl)
+	if !strings.EqualFold(nextUrl.Scheme, origin.Scheme) || !strings.EqualFold(nextUrl.Host, origin.Host) {
+		return nil, fmt.Errorf("okta next page link %s is not on %s://%s", nextUrl.Redacted(), origin.Scheme, origin.Host)
+	}
+	return nextUrl, nil
+}
+
+func (c *OktaClient) doJson(method string, reqUrl string, body interface{}, result interface{}) error {
+	_, err := c.do(method, reqUrl, body, result)
+	return err
+}
+
+func (c *OktaClient) do(method string, reqUrl string, body interface{}, result interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, reqUrl, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "SSWS "+c.Creds.ApiToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &HttpError{Method: method, Url: reqUrl, StatusCode: resp.StatusCode, Body: string(respBytes)}
+	}
+	if result != nil && len(respBytes) > 0 {
+		if err = json.Unmarshal(respBytes, result); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// HttpError is returned when the Okta API returns an error status
+type HttpError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("okta %s %s failed (%d): %s", e.Method, e.Url, e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+func IsNotFound(err error) bool {
+	var httpErr *HttpError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
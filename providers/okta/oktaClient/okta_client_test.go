@@ -0,0 +1,131 @@
+package oktaClient
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/providers/okta/oktatestsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOktaClient(t *testing.T) {
+	_, err := NewOktaClient([]byte("bad"), nil)
+	assert.ErrorContains(t, err, "invalid okta integration key")
+
+	_, err = NewOktaClient([]byte(`{"orgUrl":"https://dev-123.okta.com"}`), nil)
+	assert.ErrorContains(t, err, "orgUrl and apiToken are required")
+
+	client, err := NewOktaClient([]byte(`{"orgUrl":"https://dev-123.okta.com/","apiToken":"token"}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRoleAttribute, client.Creds.RoleAttribute)
+	assert.Equal(t, "https://dev-123.okta.com/api/v1/apps", client.apiUrl("apps", nil))
+}
+
+func TestOktaClient(t *testing.T) {
+	fake := oktatestsupport.NewFakeOktaClient()
+	appId := fake.AddApp("Photos", SignOnModeOidc)
+	fake.AddApp("Payroll", SignOnModeSaml2)
+	fake.AddApp("Intranet", "BOOKMARK")
+	groupIds := []string{fake.AddGroup("staff"), fake.AddGroup("editors"), fake.AddGroup("admins")}
+	for _, groupId := range groupIds {
+		fake.AssignGroup(appId, groupId, nil)
+	}
+	aliceId := fake.AddUser("alice@example.com")
+	bobId := fake.AddUser("bob@example.com")
+	fake.AssignUser(appId, aliceId, ScopeUser, map[string]interface{}{"role": "admin"})
+
+	client, err := NewOktaClient(fake.Key("", false), fake)
+	assert.NoError(t, err)
+	client.PageLimit = 2
+
+	apps, err := client.GetApps()
+	assert.NoError(t, err)
+	assert.Len(t, apps, 2)
+	assert.Equal(t, "Photos", apps[0].Label)
+	assert.Equal(t, SignOnModeSaml2, apps[1].SignOnMode)
+	assert.Equal(t, 2, fake.RequestCount("GET", "/api/v1/apps"))
+
+	groups, err := client.GetGroupAssignments(appId)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, "admins", groups[2].GroupName())
+	assert.Equal(t, 2, fake.RequestCount("GET", "/groups"))
+
+	users, err := client.GetUserAssignments(appId)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "alice@example.com", users[0].Login())
+	assert.Equal(t, ScopeUser, users[0].Scope)
+	assert.Equal(t, "admin", users[0].Profile["role"])
+
+	group, err := client.GetGroupByName("editors")
+	assert.NoError(t, err)
+	assert.Equal(t, groupIds[1], group.Id)
+	_, err = client.GetGroupByName("missing")
+	assert.ErrorContains(t, err, "okta group missing not found")
+
+	user, err := client.GetUser("bob@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, bobId, user.Id)
+	_, err = client.GetUser("carol@example.com")
+	assert.ErrorContains(t, err, "okta user carol@example.com not found")
+
+	assert.NoError(t, client.AssignUser(appId, bobId, map[string]interface{}{"role": "viewer"}))
+	assert.NoError(t, client.UpdateUserAssignment(appId, aliceId, map[string]interface{}{"role": nil}))
+	assert.NoError(t, client.AssignGroup(appId, groupIds[0], map[string]interface{}{"role": "staff"}))
+	assert.NoError(t, client.UnassignGroup(appId, groupIds[2]))
+	assert.NoError(t, client.UnassignUser(appId, aliceId))
+
+	assert.Equal(t, map[string]map[string]interface{}{"bob@example.com": {"_scope": ScopeUser, "role": "viewer"}}, fake.UserAssignments(appId))
+	groupAssignments := fake.GroupAssignments(appId)
+	assert.Len(t, groupAssignments, 2)
+	assert.Equal(t, "staff", groupAssignments["staff"]["role"])
+
+	err = client.UnassignUser(appId, aliceId)
+	assert.True(t, IsNotFound(err))
+}
+
+func TestOktaClient_Unauthorized(t *testing.T) {
+	fake := oktatestsupport.NewFakeOktaClient()
+	client, err := NewOktaClient([]byte(`{"orgUrl":"`+oktatestsupport.OrgUrl+`","apiToken":"wrong"}`), fake)
+	assert.NoError(t, err)
+	_, err = client.GetApps()
+	assert.ErrorContains(t, err, "Invalid token provided")
+}
+
+// linkClient returns an empty page with the next Link header for every request
+type linkClient struct {
+	next     string
+	requests []string
+}
+
+func (l *linkClient) Do(req *http.Request) (*http.Response, error) {
+	l.requests = append(l.requests, req.URL.Host)
+	header := http.Header{}
+	header.Set("Link", `<`+l.next+`>; rel="next"`)
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("[]"))}, nil
+}
+
+func TestOktaClient_NextLink(t *testing.T) {
+	other := &linkClient{next: "https://attacker.example.com/api/v1/apps?after=x"}
+	client, err := NewOktaClient([]byte(`{"orgUrl":"https://dev-123.okta.com","apiToken":"token"}`), other)
+	assert.NoError(t, err)
+	_, err = client.GetApps()
+	assert.ErrorContains(t, err, "okta next page link https://attacker.example.com/api/v1/apps?after=x is not on https://dev-123.okta.com")
+	assert.Equal(t, []string{"dev-123.okta.com"}, other.requests)
+
+	other.next = "http://dev-123.okta.com/api/v1/apps?after=x"
+	other.requests = nil
+	_, err = client.GetApps()
+	assert.ErrorContains(t, err, "is not on https://dev-123.okta.com")
+
+	// relative links are resolved against the page url, and pagination that does not end is stopped
+	endless := &linkClient{next: "/api/v1/apps?after=x"}
+	client, err = NewOktaClient([]byte(`{"orgUrl":"https://dev-123.okta.com","apiToken":"token"}`), endless)
+	assert.NoError(t, err)
+	_, err = client.GetApps()
+	assert.ErrorContains(t, err, "more than 10000 pages")
+	assert.Len(t, endless.requests, maxPages)
+}
@@ -0,0 +1,363 @@
+/*
+Package oktaProvider maps Okta OIDC and SAML application assignments to and from IDQL. Each application is a policy
+application point with the following policies:
+
+  - `access` - the groups (group:<name>) and directly assigned users (user:<login>) of the app with the action okta:access
+  - `role:<role>` - the subjects whose assignment profile role attribute holds <role>, with the action okta:role:<role>
+
+Users assigned through a group (scope GROUP) are represented by the group. Empty IDQL subjects mean any subject, so an
+app without assignments has no `access` policy, and policies without subjects are rejected when set.
+*/
+package oktaProvider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/providers/okta/oktaClient"
+)
+
+const (
+	ProviderTypeOkta string = "okta"
+
+	ActionAccess     string = "okta:access" // ActionAccess is the action of the app assignment policy
+	ActionRolePrefix string = "okta:role:"  // ActionRolePrefix prefixes app role actions (e.g. okta:role:admin)
+
+	policyIdAccess = "access"
+	policyIdRole   = "role:"
+	subjectGroup   = "group:"
+	subjectUser    = "user:"
+)
+
+type OktaProvider struct {
+	httpClient oktaClient.HTTPClient
+}
+
+type ProviderOpt func(provider *OktaProvider)
+
+func WithHttpClient(client oktaClient.HTTPClient) ProviderOpt {
+	return func(provider *OktaProvider) {
+		provider.httpClient = client
+	}
+}
+
+func NewOktaProvider(opts ...ProviderOpt) *OktaProvider {
+	provider := &OktaProvider{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(provider)
+		}
+	}
+	return provider
+}
+
+func (o *OktaProvider) Name() string {
+	return ProviderTypeOkta
+}
+
+// DiscoverApplications returns the active OIDC and SAML applications
+func (o *OktaProvider) DiscoverApplications(info policyprovider.IntegrationInfo) ([]policyprovider.ApplicationInfo, error) {
+	client, err := oktaClient.NewOktaClient(info.Key, o.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	oktaApps, err := client.GetApps()
+	if err != nil {
+		return nil, err
+	}
+	var apps []policyprovider.ApplicationInfo
+	for _, app := range oktaApps {
+		apps = append(apps, policyprovider.ApplicationInfo{
+			ObjectID:    app.Id,
+			Name:        app.Label,
+			Description: app.Name,
+			Service:     app.SignOnMode,
+		})
+	}
+	return apps, nil
+}
+
+func (o *OktaProvider) GetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) ([]hexapolicy.PolicyInfo, error) {
+	client, err := oktaClient.NewOktaClient(info.Key, o.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	state, err := loadAssignments(client, app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := hexapolicy.SubjectInfo{}
+	roleSubjects := map[string]hexapolicy.SubjectInfo{}
+	var roleOrder []string
+	for _, subject := range state.subjects {
+		access = append(access, subject)
+		for _, role := range state.roles[strings.ToLower(subject)] {
+			if _, exists := roleSubjects[role]; !exists {
+				roleOrder = append(roleOrder, role)
+			}
+			roleSubjects[role] = append(roleSubjects[role], subject)
+		}
+	}
+	slices.Sort(roleOrder)
+
+	var policies []hexapolicy.PolicyInfo
+	if len(access) > 0 {
+		policies = append(policies, newPolicy(app.ObjectID, policyIdAccess, "Okta application assignment", access, ActionAccess))
+	}
+	for _, role := range roleOrder {
+		policies = append(policies, newPolicy(app.ObjectID, policyIdRole+role, "Okta application role "+role, roleSubjects[role], ActionRolePrefix+role))
+	}
+	return policies, nil
+}
+
+/*
+SetPolicyInfo reconciles the app group and direct user assignments with the policies. A subject of any policy is
+assigned to the app and the roles of its okta:role: actions are set in the assignment profile role attribute. Group and
+direct user assignments not in the policies are removed. All subjects are resolved before any assignment is changed.
+*/
+func (o *OktaProvider) SetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, policies []hexapolicy.PolicyInfo) (int, error) {
+	client, err := oktaClient.NewOktaClient(info.Key, o.httpClient)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	desired, err := desiredAssignments(client.Creds, app.ObjectID, policies)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	state, err := loadAssignments(client, app.ObjectID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// resolve new subjects before making changes
+	var changes []func() error
+	for _, subject := range desired.subjects {
+		key := strings.ToLower(subject)
+		roles := desired.roles[key]
+		switch {
+		case strings.HasPrefix(key, subjectGroup):
+			if assignment, ok := state.groups[key]; ok {
+				if !sameRoles(state.roles[key], roles) {
+					profile := rolesProfile(client.Creds, assignment.Profile, roles)
+					changes = append(changes, func() error { return client.AssignGroup(app.ObjectID, assignment.Id, profile) })
+				}
+				continue
+			}
+			group, err := client.GetGroupByName(subject[len(subjectGroup):])
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			profile := rolesProfile(client.Creds, nil, roles)
+			changes = append(changes, func() error { return client.AssignGroup(app.ObjectID, group.Id, profile) })
+		default:
+			if assignment, ok := state.users[key]; ok {
+				if !sameRoles(state.roles[key], roles) {
+					profile := rolesProfile(client.Creds, assignment.Profile, roles)
+					changes = append(changes, func() error { return client.UpdateUserAssignment(app.ObjectID, assignment.Id, profile) })
+				}
+				continue
+			}
+			user, err := client.GetUser(subject[len(subjectUser):])
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			profile := rolesProfile(client.Creds, state.inherited[user.Id], roles)
+			changes = append(changes, func() error { return client.AssignUser(app.ObjectID, user.Id, profile) })
+		}
+	}
+	for key, assignment := range state.groups {
+		if _, ok := desired.roles[key]; !ok {
+			groupId := assignment.Id
+			changes = append(changes, func() error { return client.UnassignGroup(app.ObjectID, groupId) })
+		}
+	}
+	for key, assignment := range state.users {
+		if _, ok := desired.roles[key]; !ok {
+			userId := assignment.Id
+			changes = append(changes, func() error { return client.UnassignUser(app.ObjectID, userId) })
+		}
+	}
+
+	for _, change := range changes {
+		if err = change(); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (o *OktaProvider) Reconcile(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, comparePolicies []hexapolicy.PolicyInfo, diffsOnly bool) ([]hexapolicy.PolicyDif, error) {
+	existing, err := o.GetPolicyInfo(info, app)
+	if err != nil {
+		return nil, err
+	}
+	existingPolicies := hexapolicy.Policies{Policies: existing, App: &app.ObjectID}
+	return existingPolicies.ReconcilePolicies(comparePolicies, diffsOnly), nil
+}
+
+// assignments holds the current or desired subjects of an app (in order) and their roles by lower case subject
+type assignments struct {
+	subjects  []string
+	roles     map[string][]string
+	groups    map[string]oktaClient.GroupAssignment
+	users     map[string]oktaClient.UserAssignment // users holds direct (USER scope) assignments
+	inherited map[string]map[string]interface{}    // inherited holds the profiles of GROUP scope assignments by user id
+}
+
+func loadAssignments(client *oktaClient.OktaClient, appId string) (*assignments, error) {
+	if appId == "" {
+		return nil, errors.New("missing okta application id (ApplicationInfo.ObjectID)")
+	}
+	groupAssignments, err := client.GetGroupAssignments(appId)
+	if err != nil {
+		return nil, err
+	}
+	userAssignments, err := client.GetUserAssignments(appId)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &assignments{
+		roles:     map[string][]string{},
+		groups:    map[string]oktaClient.GroupAssignment{},
+		users:     map[string]oktaClient.UserAssignment{},
+		inherited: map[string]map[string]interface{}{},
+	}
+	for _, assignment := range groupAssignments {
+		subject := subjectGroup + assignment.GroupName()
+		key := strings.ToLower(subject)
+		state.subjects = append(state.subjects, subject)
+		state.roles[key] = profileRoles(client.Creds, assignment.Profile)
+		state.groups[key] = assignment
+	}
+	for _, assignment := range userAssignments {
+		if assignment.Scope == oktaClient.ScopeGroup {
+			state.inherited[assignment.Id] = assignment.Profile
+			continue
+		}
+		subject := subjectUser + assignment.Login()
+		key := strings.ToLower(subject)
+		state.subjects = append(state.subjects, subject)
+		state.roles[key] = profileRoles(client.Creds, assignment.Profile)
+		state.users[key] = assignment
+	}
+	return state, nil
+}
+
+func desiredAssignments(creds oktaClient.Credentials, appId string, policies []hexapolicy.PolicyInfo) (*assignments, error) {
+	desired := &assignments{roles: map[string][]string{}}
+	for _, policy := range policies {
+		policyId := ""
+		if policy.Meta.PolicyId != nil {
+			policyId = *policy.Meta.PolicyId
+		}
+		if policy.Condition != nil {
+			return nil, fmt.Errorf("policy %s: conditions are not supported by the okta provider", policyId)
+		}
+		if len(policy.Subjects) == 0 {
+			// IDQL reads empty subjects as any subject, which cannot be expressed as app assignments
+			return nil, fmt.Errorf("policy %s: at least one subject is required", policyId)
+		}
+		if policy.Object.String() != appId {
+			return nil, fmt.Errorf("policy %s: object %s is not the okta application %s", policyId, policy.Object.String(), appId)
+		}
+		var roles []string
+		for _, action := range policy.Actions {
+			actionValue := action.String()
+			switch {
+			case strings.EqualFold(actionValue, ActionAccess):
+			case len(actionValue) > len(ActionRolePrefix) && strings.EqualFold(actionValue[:len(ActionRolePrefix)], ActionRolePrefix):
+				roles = append(roles, actionValue[len(ActionRolePrefix):])
+			default:
+				return nil, fmt.Errorf("policy %s: unsupported action %s (expecting %s or %s<role>)", policyId, actionValue, ActionAccess, ActionRolePrefix)
+			}
+		}
+		for _, subject := range policy.Subjects {
+			key := strings.ToLower(subject)
+			if !strings.HasPrefix(key, subjectGroup) && !strings.HasPrefix(key, subjectUser) {
+				return nil, fmt.Errorf("policy %s: unsupported subject %s (expecting group: or user:)", policyId, subject)
+			}
+			if _, exists := desired.roles[key]; !exists {
+				desired.subjects = append(desired.subjects, subject)
+				desired.roles[key] = []string{}
+			}
+			for _, role := range roles {
+				if !slices.Contains(desired.roles[key], role) {
+					desired.roles[key] = append(desired.roles[key], role)
+				}
+			}
+		}
+	}
+	if !creds.MultiValuedRoles {
+		for _, subject := range desired.subjects {
+			if roles := desired.roles[strings.ToLower(subject)]; len(roles) > 1 {
+				return nil, fmt.Errorf("subject %s has more than one role (%s) but role attribute %s is single valued", subject, strings.Join(roles, ", "), creds.RoleAttribute)
+			}
+		}
+	}
+	return desired, nil
+}
+
+func newPolicy(appId string, policyId string, description string, subjects hexapolicy.SubjectInfo, action string) hexapolicy.PolicyInfo {
+	papId := appId
+	return hexapolicy.PolicyInfo{
+		Meta: hexapolicy.MetaInfo{
+			Version:      hexapolicy.IdqlVersion,
+			Description:  description,
+			PolicyId:     &policyId,
+			PapId:        &papId,
+			ProviderType: ProviderTypeOkta,
+		},
+		Subjects: subjects,
+		Actions:  []hexapolicy.ActionInfo{hexapolicy.ActionInfo(action)},
+		Object:   hexapolicy.ObjectInfo(appId),
+	}
+}
+
+// profileRoles returns the roles held in the assignment profile role attribute (a string or an array)
+func profileRoles(creds oktaClient.Credentials, profile map[string]interface{}) []string {
+	roles := []string{}
+	switch value := profile[creds.RoleAttribute].(type) {
+	case string:
+		if value != "" {
+			roles = append(roles, value)
+		}
+	case []interface{}:
+		for _, item := range value {
+			if role, ok := item.(string); ok && role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// rolesProfile returns a copy of profile with the role attribute set to roles (null removes the attribute)
+func rolesProfile(creds oktaClient.Credentials, profile map[string]interface{}, roles []string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range profile {
+		result[k] = v
+	}
+	switch {
+	case len(roles) == 0:
+		result[creds.RoleAttribute] = nil
+	case creds.MultiValuedRoles:
+		result[creds.RoleAttribute] = roles
+	default:
+		result[creds.RoleAttribute] = roles[0]
+	}
+	return result
+}
+
+func sameRoles(a []string, b []string) bool {
+	sortedA, sortedB := slices.Clone(a), slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
@@ -0,0 +1,234 @@
+package oktaProvider
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/providers/okta/oktaClient"
+	"github.com/hexa-org/policy-mapper/providers/okta/oktatestsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrg struct {
+	fake     *oktatestsupport.FakeOktaClient
+	provider *OktaProvider
+	info     policyprovider.IntegrationInfo
+	app      policyprovider.ApplicationInfo
+}
+
+// setupOrg creates a Photos OIDC app assigned to the staff and admins groups, alice (direct) and bob (via staff)
+func setupOrg(t *testing.T, multiValued bool) *testOrg {
+	t.Helper()
+	fake := oktatestsupport.NewFakeOktaClient()
+	appId := fake.AddApp("Photos", oktaClient.SignOnModeOidc)
+	fake.AddApp("Payroll", oktaClient.SignOnModeSaml2)
+	fake.AddApp("Intranet", "BOOKMARK")
+
+	staff := fake.AddGroup("staff")
+	admins := fake.AddGroup("admins")
+	fake.AddGroup("editors")
+	alice := fake.AddUser("alice@example.com")
+	bob := fake.AddUser("bob@example.com")
+	fake.AddUser("carol@example.com")
+
+	adminRole := interface{}("admin")
+	if multiValued {
+		adminRole = []interface{}{"admin", "viewer"}
+	}
+	fake.AssignGroup(appId, staff, map[string]interface{}{"department": "photo"})
+	fake.AssignGroup(appId, admins, map[string]interface{}{"role": adminRole})
+	fake.AssignUser(appId, alice, oktaClient.ScopeUser, map[string]interface{}{"role": "viewer"})
+	fake.AssignUser(appId, bob, oktaClient.ScopeGroup, map[string]interface{}{"department": "photo"})
+	return &testOrg{
+		fake:     fake,
+		provider: NewOktaProvider(WithHttpClient(fake)),
+		info:     policyprovider.IntegrationInfo{Name: ProviderTypeOkta, Key: fake.Key("", multiValued)},
+		app:      policyprovider.ApplicationInfo{ObjectID: appId, Name: "Photos"},
+	}
+}
+
+func policyMap(policies []hexapolicy.PolicyInfo) map[string]hexapolicy.PolicyInfo {
+	result := map[string]hexapolicy.PolicyInfo{}
+	for _, policy := range policies {
+		result[*policy.Meta.PolicyId] = policy
+	}
+	return result
+}
+
+func TestOktaProvider_DiscoverApplications(t *testing.T) {
+	org := setupOrg(t, false)
+	assert.Equal(t, ProviderTypeOkta, org.provider.Name())
+
+	apps, err := org.provider.DiscoverApplications(org.info)
+	assert.NoError(t, err)
+	assert.Len(t, apps, 2)
+	assert.Equal(t, org.app.ObjectID, apps[0].ObjectID)
+	assert.Equal(t, "Photos", apps[0].Name)
+	assert.Equal(t, "photos", apps[0].Description)
+	assert.Equal(t, oktaClient.SignOnModeOidc, apps[0].Service)
+	assert.Equal(t, oktaClient.SignOnModeSaml2, apps[1].Service)
+
+	_, err = org.provider.DiscoverApplications(policyprovider.IntegrationInfo{Name: ProviderTypeOkta, Key: []byte("{}")})
+	assert.ErrorContains(t, err, "orgUrl and apiToken are required")
+}
+
+func TestOktaProvider_GetPolicyInfo(t *testing.T) {
+	org := setupOrg(t, false)
+
+	policies, err := org.provider.GetPolicyInfo(org.info, org.app)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 3)
+	assert.Equal(t, "access", *policies[0].Meta.PolicyId)
+	assert.Equal(t, ProviderTypeOkta, policies[0].Meta.ProviderType)
+	assert.Equal(t, org.app.ObjectID, *policies[0].Meta.PapId)
+	assert.Equal(t, hexapolicy.ObjectInfo(org.app.ObjectID), policies[0].Object)
+
+	byId := policyMap(policies)
+	assert.Equal(t, hexapolicy.SubjectInfo{"group:staff", "group:admins", "user:alice@example.com"}, byId["access"].Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"okta:access"}, byId["access"].Actions)
+	assert.Equal(t, hexapolicy.SubjectInfo{"group:admins"}, byId["role:admin"].Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"okta:role:admin"}, byId["role:admin"].Actions)
+	assert.Equal(t, hexapolicy.SubjectInfo{"user:alice@example.com"}, byId["role:viewer"].Subjects)
+
+	multi := setupOrg(t, true)
+	policies, err = multi.provider.GetPolicyInfo(multi.info, multi.app)
+	assert.NoError(t, err)
+	byId = policyMap(policies)
+	assert.Equal(t, hexapolicy.SubjectInfo{"group:admins", "user:alice@example.com"}, byId["role:viewer"].Subjects)
+
+	_, err = org.provider.GetPolicyInfo(org.info, policyprovider.ApplicationInfo{})
+	assert.ErrorContains(t, err, "missing okta application id")
+}
+
+func TestOktaProvider_NoAssignments(t *testing.T) {
+	org := setupOrg(t, false)
+
+	// removing every assignment leaves no policies (an access policy without subjects would mean any subject)
+	status, err := org.provider.SetPolicyInfo(org.info, org.app, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, org.fake.GroupAssignments(org.app.ObjectID))
+
+	policies, err := org.provider.GetPolicyInfo(org.info, org.app)
+	assert.NoError(t, err)
+	assert.Empty(t, policies)
+
+	// the policies read back are applied without changes
+	before := len(org.fake.Requests)
+	_, err = org.provider.SetPolicyInfo(org.info, org.app, policies)
+	assert.NoError(t, err)
+	for _, request := range org.fake.Requests[before:] {
+		assert.Regexp(t, "^GET ", request)
+	}
+}
+
+func TestOktaProvider_SetPolicyInfo(t *testing.T) {
+	org := setupOrg(t, false)
+
+	policies := []hexapolicy.PolicyInfo{
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: ptr("access")},
+			Subjects: []string{"group:staff", "group:editors", "user:carol@example.com", "user:bob@example.com"},
+			Actions:  []hexapolicy.ActionInfo{"okta:access"},
+			Object:   hexapolicy.ObjectInfo(org.app.ObjectID),
+		},
+		{
+			Meta:     hexapolicy.MetaInfo{PolicyId: ptr("role:editor")},
+			Subjects: []string{"group:editors", "user:carol@example.com"},
+			Actions:  []hexapolicy.ActionInfo{"okta:role:editor"},
+			Object:   hexapolicy.ObjectInfo(org.app.ObjectID),
+		},
+	}
+	status, err := org.provider.SetPolicyInfo(org.info, org.app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	groups := org.fake.GroupAssignments(org.app.ObjectID)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, map[string]interface{}{"department": "photo"}, groups["staff"])
+	assert.Equal(t, map[string]interface{}{"role": "editor"}, groups["editors"])
+
+	users := org.fake.UserAssignments(org.app.ObjectID)
+	assert.Len(t, users, 2)
+	assert.Equal(t, map[string]interface{}{"_scope": oktaClient.ScopeUser, "role": "editor"}, users["carol@example.com"])
+	assert.Equal(t, map[string]interface{}{"_scope": oktaClient.ScopeUser, "department": "photo"}, users["bob@example.com"])
+	assert.Equal(t, 0, org.fake.RequestCount("PUT", "/groups/"+groupId(t, org, "staff")))
+
+	// applying the same policies again makes no changes
+	before := len(org.fake.Requests)
+	_, err = org.provider.SetPolicyInfo(org.info, org.app, policies)
+	assert.NoError(t, err)
+	for _, request := range org.fake.Requests[before:] {
+		assert.Regexp(t, "^GET ", request)
+	}
+}
+
+func TestOktaProvider_SetPolicyInfo_Errors(t *testing.T) {
+	org := setupOrg(t, false)
+	object := hexapolicy.ObjectInfo(org.app.ObjectID)
+
+	tests := []struct {
+		name   string
+		policy hexapolicy.PolicyInfo
+		err    string
+	}{
+		{"condition", hexapolicy.PolicyInfo{Subjects: []string{"group:staff"}, Actions: []hexapolicy.ActionInfo{"okta:access"}, Object: object, Condition: &conditions.ConditionInfo{Rule: "req.ip sw 10."}}, "conditions are not supported"},
+		{"action", hexapolicy.PolicyInfo{Subjects: []string{"group:staff"}, Actions: []hexapolicy.ActionInfo{"http:GET"}, Object: object}, "unsupported action http:GET"},
+		{"subject", hexapolicy.PolicyInfo{Subjects: []string{"role:admin"}, Actions: []hexapolicy.ActionInfo{"okta:access"}, Object: object}, "unsupported subject role:admin"},
+		{"multiple roles", hexapolicy.PolicyInfo{Subjects: []string{"group:staff"}, Actions: []hexapolicy.ActionInfo{"okta:role:a", "okta:role:b"}, Object: object}, "is single valued"},
+		{"unknown group", hexapolicy.PolicyInfo{Subjects: []string{"group:missing"}, Actions: []hexapolicy.ActionInfo{"okta:access"}, Object: object}, "okta group missing not found"},
+		{"unknown user", hexapolicy.PolicyInfo{Subjects: []string{"user:dave@example.com"}, Actions: []hexapolicy.ActionInfo{"okta:access"}, Object: object}, "okta user dave@example.com not found"},
+		{"no subjects", hexapolicy.PolicyInfo{Subjects: []string{}, Actions: []hexapolicy.ActionInfo{"okta:access"}, Object: object}, "at least one subject is required"},
+		{"other object", hexapolicy.PolicyInfo{Subjects: []string{"group:staff"}, Actions: []hexapolicy.ActionInfo{"okta:access"}, Object: "0oaOther"}, "object 0oaOther is not the okta application"},
+		{"no object", hexapolicy.PolicyInfo{Subjects: []string{"group:staff"}, Actions: []hexapolicy.ActionInfo{"okta:access"}}, "object  is not the okta application"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, err := org.provider.SetPolicyInfo(org.info, org.app, []hexapolicy.PolicyInfo{test.policy})
+			assert.ErrorContains(t, err, test.err)
+			assert.Equal(t, http.StatusBadRequest, status)
+		})
+	}
+	// no assignments were changed
+	assert.Len(t, org.fake.GroupAssignments(org.app.ObjectID), 2)
+	assert.Len(t, org.fake.UserAssignments(org.app.ObjectID), 2)
+}
+
+func TestOktaProvider_MultiValuedRoundTrip(t *testing.T) {
+	org := setupOrg(t, true)
+
+	policies, err := org.provider.GetPolicyInfo(org.info, org.app)
+	assert.NoError(t, err)
+	policies[2].Subjects = append(policies[2].Subjects, "group:staff") // role:viewer
+
+	_, err = org.provider.SetPolicyInfo(org.info, org.app, policies)
+	assert.NoError(t, err)
+	groups := org.fake.GroupAssignments(org.app.ObjectID)
+	assert.Equal(t, []interface{}{"viewer"}, groups["staff"]["role"])
+	assert.Equal(t, "photo", groups["staff"]["department"])
+
+	difs, err := org.provider.Reconcile(org.info, org.app, policies, true)
+	assert.NoError(t, err)
+	assert.Empty(t, difs)
+
+	difs, err = org.provider.Reconcile(org.info, org.app, policies[:2], true)
+	assert.NoError(t, err)
+	assert.Len(t, difs, 1)
+	assert.Equal(t, hexapolicy.ChangeTypeDelete, difs[0].Type)
+}
+
+func groupId(t *testing.T, org *testOrg, name string) string {
+	t.Helper()
+	client, err := oktaClient.NewOktaClient(org.info.Key, org.fake)
+	assert.NoError(t, err)
+	group, err := client.GetGroupByName(name)
+	assert.NoError(t, err)
+	return group.Id
+}
+
+func ptr(value string) *string {
+	return &value
+}
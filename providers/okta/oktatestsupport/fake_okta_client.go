@@ -0,0 +1,427 @@
+/*
+Package oktatestsupport provides FakeOktaClient, an in-memory stand-in for the Okta management API that implements
+oktaClient.HTTPClient. List responses are paginated (honoring `limit` and `after`) with `Link` headers.
+*/
+package oktatestsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	OrgUrl   = "https://hexa.okta.example"
+	ApiToken = "fake-okta-api-token"
+)
+
+type fakeApp struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Status     string `json:"status"`
+	SignOnMode string `json:"signOnMode"`
+}
+
+type fakeGroup struct {
+	Id      string            `json:"id"`
+	Profile map[string]string `json:"profile"`
+}
+
+type fakeUser struct {
+	Id      string            `json:"id"`
+	Status  string            `json:"status"`
+	Profile map[string]string `json:"profile"`
+}
+
+type fakeAssignment struct {
+	id      string
+	scope   string
+	profile map[string]interface{}
+}
+
+// FakeOktaClient is an oktaClient.HTTPClient serving an in-memory Okta org
+type FakeOktaClient struct {
+	Requests []string // Requests is the list of "METHOD path" requests received
+
+	mu               sync.Mutex
+	handler          *http.ServeMux
+	nextId           int
+	apps             []*fakeApp
+	groups           []*fakeGroup
+	users            []*fakeUser
+	groupAssignments map[string][]*fakeAssignment
+	userAssignments  map[string][]*fakeAssignment
+}
+
+func NewFakeOktaClient() *FakeOktaClient {
+	fake := &FakeOktaClient{
+		groupAssignments: map[string][]*fakeAssignment{},
+		userAssignments:  map[string][]*fakeAssignment{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/apps", fake.listApps)
+	mux.HandleFunc("GET /api/v1/apps/{app}/groups", fake.listGroupAssignments)
+	mux.HandleFunc("PUT /api/v1/apps/{app}/groups/{id}", fake.assignGroup)
+	mux.HandleFunc("DELETE /api/v1/apps/{app}/groups/{id}", fake.unassignGroup)
+	mux.HandleFunc("GET /api/v1/apps/{app}/users", fake.listUserAssignments)
+	mux.HandleFunc("POST /api/v1/apps/{app}/users", fake.assignUser)
+	mux.HandleFunc("POST /api/v1/apps/{app}/users/{id}", fake.updateUser)
+	mux.HandleFunc("DELETE /api/v1/apps/{app}/users/{id}", fake.unassignUser)
+	mux.HandleFunc("GET /api/v1/groups", fake.searchGroups)
+	mux.HandleFunc("GET /api/v1/users/{id}", fake.getUser)
+	fake.handler = mux
+	return fake
+}
+
+// Key returns an integration key for the fake org
+func (f *FakeOktaClient) Key(roleAttribute string, multiValued bool) []byte {
+	key, _ := json.Marshal(map[string]interface{}{
+		"orgUrl":           OrgUrl,
+		"apiToken":         ApiToken,
+		"roleAttribute":    roleAttribute,
+		"multiValuedRoles": multiValued,
+	})
+	return key
+}
+
+func (f *FakeOktaClient) Do(req *http.Request) (*http.Response, error) {
+	if !strings.HasPrefix(req.URL.String(), OrgUrl+"/") {
+		return nil, fmt.Errorf("unexpected request url %s", req.URL.String())
+	}
+	recorder := httptest.NewRecorder()
+	if req.Header.Get("Authorization") != "SSWS "+ApiToken {
+		writeJson(recorder, http.StatusUnauthorized, map[string]string{"errorCode": "E0000011", "errorSummary": "Invalid token provided"})
+		return recorder.Result(), nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Requests = append(f.Requests, req.Method+" "+req.URL.Path)
+	f.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
+
+// AddApp adds an active app with the sign on mode (e.g. OPENID_CONNECT, SAML_2_0 or BOOKMARK)
+func (f *FakeOktaClient) AddApp(label string, signOnMode string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	app := &fakeApp{Id: f.newId("0oa"), Name: strings.ToLower(strings.ReplaceAll(label, " ", "_")), Label: label, Status: "ACTIVE", SignOnMode: signOnMode}
+	f.apps = append(f.apps, app)
+	return app.Id
+}
+
+func (f *FakeOktaClient) AddGroup(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	group := &fakeGroup{Id: f.newId("00g"), Profile: map[string]string{"name": name}}
+	f.groups = append(f.groups, group)
+	return group.Id
+}
+
+func (f *FakeOktaClient) AddUser(login string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user := &fakeUser{Id: f.newId("00u"), Status: "ACTIVE", Profile: map[string]string{"login": login, "email": login}}
+	f.users = append(f.users, user)
+	return user.Id
+}
+
+func (f *FakeOktaClient) AssignGroup(appId string, groupId string, profile map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.groupAssignments[appId] = append(f.groupAssignments[appId], &fakeAssignment{id: groupId, scope: "GROUP", profile: profile})
+}
+
+// AssignUser adds a user assignment with scope USER (direct) or GROUP (inherited)
+func (f *FakeOktaClient) AssignUser(appId string, userId string, scope string, profile map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.userAssignments[appId] = append(f.userAssignments[appId], &fakeAssignment{id: userId, scope: scope, profile: profile})
+}
+
+// GroupAssignments returns the assignment profiles of an app by group name
+func (f *FakeOktaClient) GroupAssignments(appId string) map[string]map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := map[string]map[string]interface{}{}
+	for _, assignment := range f.groupAssignments[appId] {
+		result[f.group(assignment.id).Profile["name"]] = assignment.profile
+	}
+	return result
+}
+
+// UserAssignments returns the assignment profiles of an app by user login (including the scope as "_scope")
+func (f *FakeOktaClient) UserAssignments(appId string) map[string]map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := map[string]map[string]interface{}{}
+	for _, assignment := range f.userAssignments[appId] {
+		profile := map[string]interface{}{"_scope": assignment.scope}
+		for k, v := range assignment.profile {
+			profile[k] = v
+		}
+		result[f.user(assignment.id).Profile["login"]] = profile
+	}
+	return result
+}
+
+// RequestCount returns the number of requests with the method and a path ending with suffix
+func (f *FakeOktaClient) RequestCount(method string, suffix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, request := range f.Requests {
+		if strings.HasPrefix(request, method+" ") && strings.HasSuffix(request, suffix) {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *FakeOktaClient) newId(prefix string) string {
+	f.nextId++
+	return fmt.Sprintf("%s%017d", prefix, f.nextId)
+}
+
+func (f *FakeOktaClient) group(id string) *fakeGroup {
+	for _, group := range f.groups {
+		if group.Id == id {
+			return group
+		}
+	}
+	return nil
+}
+
+func (f *FakeOktaClient) user(idOrLogin string) *fakeUser {
+	for _, user := range f.users {
+		if user.Id == idOrLogin || strings.EqualFold(user.Profile["login"], idOrLogin) {
+			return user
+		}
+	}
+	return nil
+}
+
+func (f *FakeOktaClient) app(w http.ResponseWriter, r *http.Request) *fakeApp {
+	for _, app := range f.apps {
+		if app.Id == r.PathValue("app") {
+			return app
+		}
+	}
+	writeJson(w, http.StatusNotFound, map[string]string{"errorCode": "E0000007", "errorSummary": "Not found: Resource not found: " + r.PathValue("app") + " (AppInstance)"})
+	return nil
+}
+
+func (f *FakeOktaClient) listApps(w http.ResponseWriter, r *http.Request) {
+	var items []interface{}
+	for _, app := range f.apps {
+		if filter := r.URL.Query().Get("filter"); filter == `status eq "ACTIVE"` && app.Status != "ACTIVE" {
+			continue
+		}
+		items = append(items, app)
+	}
+	writePage(w, r, items)
+}
+
+func (f *FakeOktaClient) listGroupAssignments(w http.ResponseWriter, r *http.Request) {
+	if f.app(w, r) == nil {
+		return
+	}
+	var items []interface{}
+	for _, assignment := range f.groupAssignments[r.PathValue("app")] {
+		item := map[string]interface{}{"id": assignment.id, "priority": 0, "profile": assignment.profile}
+		if r.URL.Query().Get("expand") == "group" {
+			item["_embedded"] = map[string]interface{}{"group": f.group(assignment.id)}
+		}
+		items = append(items, item)
+	}
+	writePage(w, r, items)
+}
+
+func (f *FakeOktaClient) listUserAssignments(w http.ResponseWriter, r *http.Request) {
+	if f.app(w, r) == nil {
+		return
+	}
+	var items []interface{}
+	for _, assignment := range f.userAssignments[r.PathValue("app")] {
+		user := f.user(assignment.id)
+		item := map[string]interface{}{
+			"id":          assignment.id,
+			"scope":       assignment.scope,
+			"profile":     assignment.profile,
+			"credentials": map[string]string{"userName": user.Profile["login"]},
+		}
+		if r.URL.Query().Get("expand") == "user" {
+			item["_embedded"] = map[string]interface{}{"user": user}
+		}
+		items = append(items, item)
+	}
+	writePage(w, r, items)
+}
+
+func (f *FakeOktaClient) assignGroup(w http.ResponseWriter, r *http.Request) {
+	appId := r.PathValue("app")
+	if f.app(w, r) == nil {
+		return
+	}
+	group := f.group(r.PathValue("id"))
+	if group == nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"errorCode": "E0000007", "errorSummary": "Not found: Resource not found: " + r.PathValue("id") + " (UserGroup)"})
+		return
+	}
+	var body struct {
+		Profile map[string]interface{} `json:"profile"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	profile := withoutNulls(body.Profile)
+	for _, assignment := range f.groupAssignments[appId] {
+		if assignment.id == group.Id {
+			assignment.profile = profile
+			writeJson(w, http.StatusOK, map[string]interface{}{"id": group.Id, "profile": profile})
+			return
+		}
+	}
+	f.groupAssignments[appId] = append(f.groupAssignments[appId], &fakeAssignment{id: group.Id, scope: "GROUP", profile: profile})
+	writeJson(w, http.StatusOK, map[string]interface{}{"id": group.Id, "profile": profile})
+}
+
+func (f *FakeOktaClient) unassignGroup(w http.ResponseWriter, r *http.Request) {
+	f.unassign(w, r, f.groupAssignments)
+}
+
+func (f *FakeOktaClient) assignUser(w http.ResponseWriter, r *http.Request) {
+	appId := r.PathValue("app")
+	if f.app(w, r) == nil {
+		return
+	}
+	var body struct {
+		Id      string                 `json:"id"`
+		Scope   string                 `json:"scope"`
+		Profile map[string]interface{} `json:"profile"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	user := f.user(body.Id)
+	if user == nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"errorCode": "E0000007", "errorSummary": "Not found: Resource not found: " + body.Id + " (User)"})
+		return
+	}
+	profile := withoutNulls(body.Profile)
+	for _, assignment := range f.userAssignments[appId] {
+		if assignment.id == user.Id {
+			assignment.scope, assignment.profile = "USER", profile
+			writeJson(w, http.StatusOK, map[string]interface{}{"id": user.Id, "scope": "USER", "profile": profile})
+			return
+		}
+	}
+	f.userAssignments[appId] = append(f.userAssignments[appId], &fakeAssignment{id: user.Id, scope: "USER", profile: profile})
+	writeJson(w, http.StatusOK, map[string]interface{}{"id": user.Id, "scope": "USER", "profile": profile})
+}
+
+func (f *FakeOktaClient) updateUser(w http.ResponseWriter, r *http.Request) {
+	if f.app(w, r) == nil {
+		return
+	}
+	var body struct {
+		Profile map[string]interface{} `json:"profile"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	for _, assignment := range f.userAssignments[r.PathValue("app")] {
+		if assignment.id == r.PathValue("id") {
+			assignment.profile = withoutNulls(body.Profile)
+			writeJson(w, http.StatusOK, map[string]interface{}{"id": assignment.id, "scope": assignment.scope, "profile": assignment.profile})
+			return
+		}
+	}
+	writeJson(w, http.StatusNotFound, map[string]string{"errorCode": "E0000007", "errorSummary": "Not found: Resource not found: " + r.PathValue("id") + " (AppUser)"})
+}
+
+func (f *FakeOktaClient) unassignUser(w http.ResponseWriter, r *http.Request) {
+	f.unassign(w, r, f.userAssignments)
+}
+
+func (f *FakeOktaClient) unassign(w http.ResponseWriter, r *http.Request, assignments map[string][]*fakeAssignment) {
+	appId := r.PathValue("app")
+	if f.app(w, r) == nil {
+		return
+	}
+	index := slices.IndexFunc(assignments[appId], func(assignment *fakeAssignment) bool { return assignment.id == r.PathValue("id") })
+	if index < 0 {
+		writeJson(w, http.StatusNotFound, map[string]string{"errorCode": "E0000007", "errorSummary": "Not found: Resource not found: " + r.PathValue("id")})
+		return
+	}
+	assignments[appId] = slices.Delete(assignments[appId], index, index+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// searchGroups supports the search expression: profile.name eq "<name>"
+func (f *FakeOktaClient) searchGroups(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	name, found := strings.CutPrefix(search, `profile.name eq "`)
+	if !found || !strings.HasSuffix(name, `"`) {
+		writeJson(w, http.StatusBadRequest, map[string]string{"errorCode": "E0000031", "errorSummary": "Invalid search criteria."})
+		return
+	}
+	name = strings.ReplaceAll(strings.TrimSuffix(name, `"`), `\"`, `"`)
+	groups := []*fakeGroup{}
+	for _, group := range f.groups {
+		if group.Profile["name"] == name {
+			groups = append(groups, group)
+		}
+	}
+	writeJson(w, http.StatusOK, groups)
+}
+
+func (f *FakeOktaClient) getUser(w http.ResponseWriter, r *http.Request) {
+	user := f.user(r.PathValue("id"))
+	if user == nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"errorCode": "E0000007", "errorSummary": "Not found: Resource not found: " + r.PathValue("id") + " (User)"})
+		return
+	}
+	writeJson(w, http.StatusOK, user)
+}
+
+// writePage writes the page of items selected by the limit and after (offset) parameters with a next link
+func writePage(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	after, _ := strconv.Atoi(query.Get("after"))
+	end := min(after+limit, len(items))
+	page := []interface{}{}
+	if after < len(items) {
+		page = items[after:end]
+	}
+
+	self := url.URL{Scheme: "https", Host: strings.TrimPrefix(OrgUrl, "https://"), Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="self"`, self.String()))
+	if end < len(items) {
+		query.Set("after", strconv.Itoa(end))
+		self.RawQuery = query.Encode()
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, self.String()))
+	}
+	writeJson(w, http.StatusOK, page)
+}
+
+func withoutNulls(profile map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range profile {
+		if v != nil {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
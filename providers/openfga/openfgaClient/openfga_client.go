@@ -0,0 +1,251 @@
+/*
+Package openfgaClient is a minimal client for the OpenFGA HTTP API (https://openfga.dev/api/service) covering stores,
+authorization models and relationship tuples. List operations follow the OpenFGA continuation tokens.
+*/
+package openfgaClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/models/formats/openfga"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	DefaultPageSize   = 100
+	MaxTuplesPerWrite = 100 // MaxTuplesPerWrite is the OpenFGA default limit of writes and deletes in one request
+)
+
+/*
+Credentials is the integration key for an OpenFGA server, for example:
+
+	{"apiUrl": "https://openfga.example.com", "apiToken": "preshared-key", "namespace": "PhotoApp"}
+
+apiToken is an OpenFGA pre-shared key. Alternatively clientId, clientSecret, tokenUrl and audience obtain an access
+token with a client credentials grant. When neither is set, requests are not authenticated. The optional namespace
+qualifies the IDQL entities mapped from tuples (e.g. PhotoApp:User:"alice").
+*/
+type Credentials struct {
+	ApiUrl       string `json:"apiUrl"`
+	ApiToken     string `json:"apiToken,omitempty"`
+	ClientId     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	TokenUrl     string `json:"tokenUrl,omitempty"`
+	Audience     string `json:"audience,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+}
+
+type Store struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// AuthorizationModel is a stored OpenFGA authorization model
+type AuthorizationModel struct {
+	Id string `json:"id"`
+	openfga.AuthorizationModel
+}
+
+type Tuple struct {
+	Key       openfga.TupleKey `json:"key"`
+	Timestamp string           `json:"timestamp,omitempty"`
+}
+
+// tupleKeyWithoutCondition is the tuple key used for deletes
+type tupleKeyWithoutCondition struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+type OpenFgaClient struct {
+	Creds      Credentials
+	PageSize   int
+	httpClient *http.Client
+}
+
+// NewOpenFgaClient parses the integration key. If httpClient is nil, http.DefaultClient is used.
+func NewOpenFgaClient(key []byte, httpClient *http.Client) (*OpenFgaClient, error) {
+	var creds Credentials
+	if err := json.Unmarshal(key, &creds); err != nil {
+		return nil, fmt.Errorf("invalid openfga integration key: %w", err)
+	}
+	if creds.ApiUrl == "" {
+		return nil, errors.New("invalid openfga integration key: apiUrl is required")
+	}
+	creds.ApiUrl = strings.TrimSuffix(creds.ApiUrl, "/")
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if creds.ClientId != "" {
+		if creds.TokenUrl == "" {
+			return nil, errors.New("invalid openfga integration key: tokenUrl is required with clientId")
+		}
+		config := clientcredentials.Config{
+			ClientID:     creds.ClientId,
+			ClientSecret: creds.ClientSecret,
+			TokenURL:     creds.TokenUrl,
+		}
+		if creds.Audience != "" {
+			config.EndpointParams = url.Values{"audience": {creds.Audience}}
+		}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+		httpClient = config.Client(ctx)
+	}
+	return &OpenFgaClient{Creds: creds, PageSize: DefaultPageSize, httpClient: httpClient}, nil
+}
+
+func (c *OpenFgaClient) ListStores() ([]Store, error) {
+	var stores []Store
+	token := ""
+	for {
+		var page struct {
+			Stores            []Store `json:"stores"`
+			ContinuationToken string  `json:"continuation_token"`
+		}
+		query := url.Values{"page_size": {fmt.Sprint(c.PageSize)}}
+		if token != "" {
+			query.Set("continuation_token", token)
+		}
+		if err := c.doJson(http.MethodGet, c.apiUrl("stores")+"?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		stores = append(stores, page.Stores...)
+		if token = page.ContinuationToken; token == "" {
+			return stores, nil
+		}
+	}
+}
+
+// GetLatestModel returns the most recent authorization model of the store or nil if the store has no model
+func (c *OpenFgaClient) GetLatestModel(storeId string) (*AuthorizationModel, error) {
+	var page struct {
+		AuthorizationModels []AuthorizationModel `json:"authorization_models"`
+	}
+	reqUrl := c.apiUrl("stores", storeId, "authorization-models") + "?page_size=1"
+	if err := c.doJson(http.MethodGet, reqUrl, nil, &page); err != nil {
+		return nil, err
+	}
+	if len(page.AuthorizationModels) == 0 {
+		return nil, nil
+	}
+	return &page.AuthorizationModels[0], nil
+}
+
+// ReadTuples returns all the tuples of the store
+func (c *OpenFgaClient) ReadTuples(storeId string) ([]openfga.TupleKey, error) {
+	var tuples []openfga.TupleKey
+	token := ""
+	for {
+		body := map[string]interface{}{"page_size": c.PageSize}
+		if token != "" {
+			body["continuation_token"] = token
+		}
+		var page struct {
+			Tuples            []Tuple `json:"tuples"`
+			ContinuationToken string  `json:"continuation_token"`
+		}
+		if err := c.doJson(http.MethodPost, c.apiUrl("stores", storeId, "read"), body, &page); err != nil {
+			return nil, err
+		}
+		for _, tuple := range page.Tuples {
+			tuples = append(tuples, tuple.Key)
+		}
+		if token = page.ContinuationToken; token == "" {
+			return tuples, nil
+		}
+	}
+}
+
+// Write writes and deletes tuples in a single (atomic) request. The total may not exceed MaxTuplesPerWrite.
+func (c *OpenFgaClient) Write(storeId string, modelId string, writes []openfga.TupleKey, deletes []openfga.TupleKey) error {
+	body := map[string]interface{}{}
+	if modelId != "" {
+		body["authorization_model_id"] = modelId
+	}
+	if len(writes) > 0 {
+		body["writes"] = map[string]interface{}{"tuple_keys": writes}
+	}
+	if len(deletes) > 0 {
+		keys := make([]tupleKeyWithoutCondition, len(deletes))
+		for i, tuple := range deletes {
+			keys[i] = tupleKeyWithoutCondition{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object}
+		}
+		body["deletes"] = map[string]interface{}{"tuple_keys": keys}
+	}
+	return c.doJson(http.MethodPost, c.apiUrl("stores", storeId, "write"), body, nil)
+}
+
+func (c *OpenFgaClient) apiUrl(elements ...string) string {
+	for i, element := range elements {
+		elements[i] = url.PathEscape(element)
+	}
+	return c.Creds.ApiUrl + "/" + strings.Join(elements, "/")
+}
+
+func (c *OpenFgaClient) doJson(method string, reqUrl string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, reqUrl, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Creds.ApiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Creds.ApiToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &HttpError{Method: method, Url: reqUrl, StatusCode: resp.StatusCode, Body: string(respBytes)}
+	}
+	if result == nil || len(respBytes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBytes, result)
+}
+
+// HttpError is returned when the OpenFGA API returns an error status
+type HttpError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("openfga %s %s failed (%d): %s", e.Method, e.Url, e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+func IsNotFound(err error) bool {
+	var httpErr *HttpError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
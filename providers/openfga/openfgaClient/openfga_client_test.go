@@ -0,0 +1,94 @@
+package openfgaClient
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/models/formats/openfga"
+	"github.com/hexa-org/policy-mapper/providers/openfga/openfgatestsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOpenFgaClient(t *testing.T) {
+	_, err := NewOpenFgaClient([]byte("bad"), nil)
+	assert.ErrorContains(t, err, "invalid openfga integration key")
+
+	_, err = NewOpenFgaClient([]byte(`{"apiToken":"key"}`), nil)
+	assert.ErrorContains(t, err, "apiUrl is required")
+
+	_, err = NewOpenFgaClient([]byte(`{"apiUrl":"https://fga.example.com","clientId":"hexa"}`), nil)
+	assert.ErrorContains(t, err, "tokenUrl is required")
+
+	client, err := NewOpenFgaClient([]byte(`{"apiUrl":"https://fga.example.com/","namespace":"PhotoApp"}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "PhotoApp", client.Creds.Namespace)
+	assert.Equal(t, "https://fga.example.com/stores/a%2Fb/read", client.apiUrl("stores", "a/b", "read"))
+}
+
+func TestOpenFgaClient(t *testing.T) {
+	fake := openfgatestsupport.NewFakeOpenFga()
+	defer fake.Close()
+	storeId := fake.AddStore("photos")
+	fake.AddStore("albums")
+	fake.AddStore("accounts")
+	model := openfga.AuthorizationModel{
+		SchemaVersion: openfga.SchemaVersion,
+		TypeDefinitions: []openfga.TypeDefinition{
+			{Type: "User"},
+			{Type: "Photo", Relations: map[string]openfga.Userset{"viewer": {This: &struct{}{}}}},
+		},
+	}
+	fake.AddModel(storeId, openfga.AuthorizationModel{SchemaVersion: openfga.SchemaVersion})
+	modelId := fake.AddModel(storeId, model)
+	for i := 0; i < 5; i++ {
+		fake.AddTuples(storeId, openfga.TupleKey{User: fmt.Sprintf("User:u%d", i), Relation: "viewer", Object: "Photo:vacation.jpg"})
+	}
+
+	client, err := NewOpenFgaClient(fake.Key(""), fake.Server.Client())
+	assert.NoError(t, err)
+	client.PageSize = 2
+
+	stores, err := client.ListStores()
+	assert.NoError(t, err)
+	assert.Len(t, stores, 3)
+	assert.Equal(t, "photos", stores[0].Name)
+	assert.Equal(t, 2, fake.RequestCount("GET", "/stores"))
+
+	latest, err := client.GetLatestModel(storeId)
+	assert.NoError(t, err)
+	assert.Equal(t, modelId, latest.Id)
+	assert.NotNil(t, latest.TypeDefinition("Photo"))
+
+	empty, err := client.GetLatestModel(stores[1].Id)
+	assert.NoError(t, err)
+	assert.Nil(t, empty)
+
+	tuples, err := client.ReadTuples(storeId)
+	assert.NoError(t, err)
+	assert.Len(t, tuples, 5)
+	assert.Equal(t, 3, fake.RequestCount("POST", "/read"))
+
+	err = client.Write(storeId, modelId,
+		[]openfga.TupleKey{{User: "User:alice", Relation: "viewer", Object: "Photo:vacation.jpg"}},
+		[]openfga.TupleKey{tuples[0]})
+	assert.NoError(t, err)
+	tuples = fake.Tuples(storeId)
+	assert.Len(t, tuples, 5)
+	assert.Equal(t, "User:alice", tuples[4].User)
+
+	err = client.Write(storeId, modelId, []openfga.TupleKey{{User: "User:alice", Relation: "editor", Object: "Photo:vacation.jpg"}}, nil)
+	assert.ErrorContains(t, err, "relation 'Photo#editor' not found")
+
+	_, err = client.ReadTuples("missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestOpenFgaClient_Unauthorized(t *testing.T) {
+	fake := openfgatestsupport.NewFakeOpenFga()
+	defer fake.Close()
+
+	client, err := NewOpenFgaClient([]byte(`{"apiUrl":"`+fake.Server.URL+`","apiToken":"wrong"}`), fake.Server.Client())
+	assert.NoError(t, err)
+	_, err = client.ListStores()
+	assert.ErrorContains(t, err, "unauthenticated")
+}
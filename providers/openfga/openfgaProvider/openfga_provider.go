@@ -0,0 +1,340 @@
+/*
+Package openfgaProvider maps the relationship tuples of OpenFGA stores to and from IDQL using the OpenFGA mapper
+(models/formats/openfga). Each store is a policy application point. Tuples are read into RBAC-style IDQL policies and
+IDQL policies are written back as the minimal set of tuple deletes and writes against the latest authorization model.
+*/
+package openfgaProvider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/models/formats/openfga"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/types"
+	"github.com/hexa-org/policy-mapper/providers/openfga/openfgaClient"
+)
+
+const (
+	ProviderTypeOpenFga string = "openfga"
+)
+
+type OpenFgaProvider struct {
+	httpClient *http.Client
+}
+
+type ProviderOpt func(provider *OpenFgaProvider)
+
+func WithHttpClient(client *http.Client) ProviderOpt {
+	return func(provider *OpenFgaProvider) {
+		provider.httpClient = client
+	}
+}
+
+func NewOpenFgaProvider(opts ...ProviderOpt) *OpenFgaProvider {
+	provider := &OpenFgaProvider{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(provider)
+		}
+	}
+	return provider
+}
+
+func (o *OpenFgaProvider) Name() string {
+	return ProviderTypeOpenFga
+}
+
+// DiscoverApplications returns the stores of the OpenFGA server
+func (o *OpenFgaProvider) DiscoverApplications(info policyprovider.IntegrationInfo) ([]policyprovider.ApplicationInfo, error) {
+	client, err := openfgaClient.NewOpenFgaClient(info.Key, o.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := client.ListStores()
+	if err != nil {
+		return nil, err
+	}
+	var apps []policyprovider.ApplicationInfo
+	for _, store := range stores {
+		apps = append(apps, policyprovider.ApplicationInfo{
+			ObjectID:    store.Id,
+			Name:        store.Name,
+			Description: "OpenFGA store",
+			Service:     "OpenFGA",
+		})
+	}
+	return apps, nil
+}
+
+// GetPolicyInfo maps the tuples of the store into IDQL. Tuples that cannot be expressed in IDQL (e.g. usersets other
+// than member or conditions with context) are skipped.
+func (o *OpenFgaProvider) GetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) ([]hexapolicy.PolicyInfo, error) {
+	store, err := o.openStore(info, app)
+	if err != nil {
+		return nil, err
+	}
+	var tuples []openfga.TupleKey
+	for _, tuple := range store.tuples {
+		if store.mappable(tuple) {
+			tuples = append(tuples, tuple)
+		}
+	}
+	policies, err := store.mapper.MapTuplesToPolicies(store.fgaModel(), tuples)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]int{}
+	for i := range policies {
+		policyId := stablePolicyId(policies[i])
+		if used[policyId]++; used[policyId] > 1 {
+			policyId = fmt.Sprintf("%s-%d", policyId, used[policyId])
+		}
+		papId := app.ObjectID
+		policies[i].Meta.PolicyId = &policyId
+		policies[i].Meta.PapId = &papId
+		policies[i].Meta.ProviderType = ProviderTypeOpenFga
+	}
+	return policies, nil
+}
+
+/*
+SetPolicyInfo maps the policies into tuples using the latest authorization model of the store and applies the
+difference with the existing tuples. When the difference fits in one request (openfgaClient.MaxTuplesPerWrite) and no
+tuple changes only its condition, it is applied atomically. Otherwise new tuples are written first, tuples with a changed
+condition are deleted and re-written, and then tuples no longer present are deleted, so that access is not lost part way
+through. Existing tuples that cannot be expressed in IDQL are retained. Policy conditions must match a condition already
+defined in the authorization model.
+*/
+func (o *OpenFgaProvider) SetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, policies []hexapolicy.PolicyInfo) (int, error) {
+	store, err := o.openStore(info, app)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if store.model == nil {
+		return http.StatusBadRequest, fmt.Errorf("openfga store %s has no authorization model", app.ObjectID)
+	}
+	desired, err := store.desiredTuples(policies)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	desiredKeys := map[string]bool{}
+	for _, tuple := range desired {
+		desiredKeys[tupleKey(tuple)] = true
+	}
+	existingKeys := map[string]bool{}
+	var deletes []openfga.TupleKey
+	for _, tuple := range store.tuples {
+		existingKeys[tupleKey(tuple)] = true
+		if !desiredKeys[tupleKey(tuple)] && store.mappable(tuple) {
+			deletes = append(deletes, tuple)
+		}
+	}
+	var writes []openfga.TupleKey
+	for _, tuple := range desired {
+		if !existingKeys[tupleKey(tuple)] {
+			writes = append(writes, tuple)
+		}
+	}
+
+	// a tuple with a changed condition has the same relation key in deletes and writes
+	deleteKeys := map[string]bool{}
+	for _, tuple := range deletes {
+		deleteKeys[relationKey(tuple)] = true
+	}
+	changed := map[string]bool{}
+	for _, tuple := range writes {
+		if deleteKeys[relationKey(tuple)] {
+			changed[relationKey(tuple)] = true
+		}
+	}
+
+	if len(changed) == 0 && len(writes)+len(deletes) <= openfgaClient.MaxTuplesPerWrite {
+		if len(writes)+len(deletes) > 0 {
+			if err = store.client.Write(app.ObjectID, store.model.Id, writes, deletes); err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+		return http.StatusOK, nil
+	}
+
+	var added, rewritten, changedDeletes, removed []openfga.TupleKey
+	for _, tuple := range writes {
+		if changed[relationKey(tuple)] {
+			rewritten = append(rewritten, tuple)
+		} else {
+			added = append(added, tuple)
+		}
+	}
+	for _, tuple := range deletes {
+		if changed[relationKey(tuple)] {
+			changedDeletes = append(changedDeletes, tuple)
+		} else {
+			removed = append(removed, tuple)
+		}
+	}
+	if err = store.writeBatches(app.ObjectID, added, nil); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	// a tuple cannot be written while a tuple with the same relation key exists
+	if err = store.writeBatches(app.ObjectID, nil, changedDeletes); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err = store.writeBatches(app.ObjectID, rewritten, nil); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err = store.writeBatches(app.ObjectID, nil, removed); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func (o *OpenFgaProvider) Reconcile(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, comparePolicies []hexapolicy.PolicyInfo, diffsOnly bool) ([]hexapolicy.PolicyDif, error) {
+	existing, err := o.GetPolicyInfo(info, app)
+	if err != nil {
+		return nil, err
+	}
+	existingPolicies := hexapolicy.Policies{Policies: existing, App: &app.ObjectID}
+	return existingPolicies.ReconcilePolicies(comparePolicies, diffsOnly), nil
+}
+
+// fgaStore holds the latest authorization model and the tuples of a store
+type fgaStore struct {
+	client *openfgaClient.OpenFgaClient
+	mapper *openfga.OpenFgaMapper
+	model  *openfgaClient.AuthorizationModel
+	tuples []openfga.TupleKey
+}
+
+func (o *OpenFgaProvider) openStore(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) (*fgaStore, error) {
+	if app.ObjectID == "" {
+		return nil, errors.New("missing openfga store id (ApplicationInfo.ObjectID)")
+	}
+	client, err := openfgaClient.NewOpenFgaClient(info.Key, o.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := openfga.NewOpenFgaMapper(nil, client.Creds.Namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+	model, err := client.GetLatestModel(app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	tuples, err := client.ReadTuples(app.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	return &fgaStore{client: client, mapper: mapper, model: model, tuples: tuples}, nil
+}
+
+func (s *fgaStore) fgaModel() *openfga.AuthorizationModel {
+	if s.model == nil {
+		return nil
+	}
+	return &s.model.AuthorizationModel
+}
+
+// mappable returns true if the tuple can be expressed in IDQL
+func (s *fgaStore) mappable(tuple openfga.TupleKey) bool {
+	_, err := s.mapper.MapTuplesToPolicies(s.fgaModel(), []openfga.TupleKey{tuple})
+	return err == nil
+}
+
+// desiredTuples maps the policies into tuples. The mapper names a condition after its policy, so each generated
+// condition is replaced by the model condition with the same expression.
+func (s *fgaStore) desiredTuples(policies []hexapolicy.PolicyInfo) ([]openfga.TupleKey, error) {
+	// map against a copy as the mapper adds policy conditions to the model
+	var scratch openfga.AuthorizationModel
+	modelBytes, err := json.Marshal(s.model.AuthorizationModel)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(modelBytes, &scratch); err != nil {
+		return nil, err
+	}
+
+	var desired []openfga.TupleKey
+	seen := map[string]bool{}
+	for i, policy := range policies {
+		tuples, err := s.mapper.MapPolicyToTuples(&scratch, policy, i)
+		if err != nil {
+			return nil, err
+		}
+		for _, tuple := range tuples {
+			if tuple.Condition != nil {
+				name, err := s.modelCondition(scratch.Conditions[tuple.Condition.Name])
+				if err != nil {
+					return nil, err
+				}
+				tuple.Condition = &openfga.RelationshipCondition{Name: name}
+			}
+			if key := tupleKey(tuple); !seen[key] {
+				seen[key] = true
+				desired = append(desired, tuple)
+			}
+		}
+	}
+	return desired, nil
+}
+
+func (s *fgaStore) modelCondition(condition openfga.Condition) (string, error) {
+	if existing, exists := s.model.Conditions[condition.Name]; exists && existing.Expression == condition.Expression {
+		return condition.Name, nil
+	}
+	for name, existing := range s.model.Conditions {
+		if existing.Expression == condition.Expression {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("condition '%s' is not defined in the OpenFGA authorization model %s", condition.Expression, s.model.Id)
+}
+
+// writeBatches writes (or deletes) tuples in batches of openfgaClient.MaxTuplesPerWrite
+func (s *fgaStore) writeBatches(storeId string, writes []openfga.TupleKey, deletes []openfga.TupleKey) error {
+	for start := 0; start < len(writes); start += openfgaClient.MaxTuplesPerWrite {
+		batch := writes[start:min(start+openfgaClient.MaxTuplesPerWrite, len(writes))]
+		if err := s.client.Write(storeId, s.model.Id, batch, nil); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(deletes); start += openfgaClient.MaxTuplesPerWrite {
+		batch := deletes[start:min(start+openfgaClient.MaxTuplesPerWrite, len(deletes))]
+		if err := s.client.Write(storeId, s.model.Id, nil, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relationKey identifies a tuple by user, relation and object (OpenFGA allows one tuple per relation key)
+func relationKey(tuple openfga.TupleKey) string {
+	return tuple.User + " " + tuple.Relation + " " + tuple.Object
+}
+
+// tupleKey identifies a tuple by user, relation, object and condition name
+func tupleKey(tuple openfga.TupleKey) string {
+	key := tuple.User + " " + tuple.Relation + " " + tuple.Object
+	if tuple.Condition != nil {
+		key += " " + tuple.Condition.Name
+	}
+	return key
+}
+
+// stablePolicyId returns an id derived from the policy object and relations (e.g. Photo:vacation.jpg#viewPhoto,listPhotos)
+// so that policies can be reconciled regardless of the order tuples are read in
+func stablePolicyId(policy hexapolicy.PolicyInfo) string {
+	object := types.ParseEntity(policy.Object.String())
+	relations := make([]string, len(policy.Actions))
+	for i, action := range policy.Actions {
+		relations[i] = types.ParseEntity(string(action)).GetId()
+	}
+	return object.GetType() + ":" + object.GetId() + "#" + strings.Join(relations, ",")
+}
@@ -0,0 +1,229 @@
+package openfgaProvider
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/models/formats/openfga"
+	"github.com/hexa-org/policy-mapper/models/policyInfoModel"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/hexa-org/policy-mapper/providers/openfga/openfgatestsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+type testServer struct {
+	fake     *openfgatestsupport.FakeOpenFga
+	provider *OpenFgaProvider
+	info     policyprovider.IntegrationInfo
+	app      policyprovider.ApplicationInfo
+	empty    policyprovider.ApplicationInfo
+}
+
+// setupServer creates a photos store with a model generated from the photo schema and tuples for a few policies, and
+// an empty store without a model
+func setupServer(t *testing.T) *testServer {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	schemaBytes, err := os.ReadFile(filepath.Join(filepath.Dir(file), "../../../examples/policyInfoModels/photoSchema.json"))
+	assert.NoError(t, err)
+	namespaces, err := policyInfoModel.ParseSchemaFile(schemaBytes)
+	assert.NoError(t, err)
+	mapper, err := openfga.NewOpenFgaMapper(namespaces, "", nil)
+	assert.NoError(t, err)
+
+	adults := "adults"
+	store, err := mapper.MapPoliciesToStore([]hexapolicy.PolicyInfo{
+		{
+			Subjects: []string{"PhotoApp:User:\"alice\"", "[PhotoApp:UserGroup:\"friends\"]"},
+			Actions:  []hexapolicy.ActionInfo{"PhotoApp:Action:\"viewPhoto\"", "PhotoApp:Action:\"listPhotos\""},
+			Object:   "PhotoApp:Photo:\"vacation.jpg\"",
+		},
+		{
+			Meta:      hexapolicy.MetaInfo{PolicyId: &adults},
+			Subjects:  []string{"PhotoApp:User:"},
+			Actions:   []hexapolicy.ActionInfo{"PhotoApp:Action:\"viewPhoto\""},
+			Object:    "PhotoApp:Photo:\"public.jpg\"",
+			Condition: &conditions.ConditionInfo{Rule: "subject.age gt 18", Action: conditions.AAllow},
+		},
+	})
+	assert.NoError(t, err)
+
+	fake := openfgatestsupport.NewFakeOpenFga()
+	t.Cleanup(fake.Close)
+	storeId := fake.AddStore("photos")
+	emptyId := fake.AddStore("empty")
+	fake.AddModel(storeId, store.Model)
+	fake.AddTuples(storeId, store.Tuples...)
+	fake.AddTuples(storeId, openfga.TupleKey{User: "UserGroup:friends#owner", Relation: "viewPhoto", Object: "Photo:vacation.jpg"})
+	return &testServer{
+		fake:     fake,
+		provider: NewOpenFgaProvider(WithHttpClient(fake.Server.Client())),
+		info:     policyprovider.IntegrationInfo{Name: ProviderTypeOpenFga, Key: fake.Key("PhotoApp")},
+		app:      policyprovider.ApplicationInfo{ObjectID: storeId, Name: "photos"},
+		empty:    policyprovider.ApplicationInfo{ObjectID: emptyId, Name: "empty"},
+	}
+}
+
+func policyMap(policies []hexapolicy.PolicyInfo) map[string]hexapolicy.PolicyInfo {
+	result := map[string]hexapolicy.PolicyInfo{}
+	for _, policy := range policies {
+		result[*policy.Meta.PolicyId] = policy
+	}
+	return result
+}
+
+func TestOpenFgaProvider_DiscoverApplications(t *testing.T) {
+	server := setupServer(t)
+	assert.Equal(t, ProviderTypeOpenFga, server.provider.Name())
+
+	apps, err := server.provider.DiscoverApplications(server.info)
+	assert.NoError(t, err)
+	assert.Len(t, apps, 2)
+	assert.Equal(t, server.app.ObjectID, apps[0].ObjectID)
+	assert.Equal(t, "photos", apps[0].Name)
+	assert.Equal(t, "OpenFGA", apps[0].Service)
+
+	_, err = server.provider.DiscoverApplications(policyprovider.IntegrationInfo{Name: ProviderTypeOpenFga, Key: []byte(`{"apiUrl":"` + server.fake.Server.URL + `"}`)})
+	assert.ErrorContains(t, err, "unauthenticated")
+}
+
+func TestOpenFgaProvider_GetPolicyInfo(t *testing.T) {
+	server := setupServer(t)
+
+	policies, err := server.provider.GetPolicyInfo(server.info, server.app)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 2)
+	byId := policyMap(policies)
+
+	vacation := byId["Photo:vacation.jpg#viewPhoto,listPhotos"]
+	assert.Equal(t, hexapolicy.SubjectInfo{"PhotoApp:User:\"alice\"", "[PhotoApp:UserGroup:\"friends\"]"}, vacation.Subjects)
+	assert.Equal(t, hexapolicy.ObjectInfo("PhotoApp:Photo:\"vacation.jpg\""), vacation.Object)
+	assert.Equal(t, ProviderTypeOpenFga, vacation.Meta.ProviderType)
+	assert.Equal(t, server.app.ObjectID, *vacation.Meta.PapId)
+
+	public := byId["Photo:public.jpg#viewPhoto"]
+	assert.Equal(t, hexapolicy.SubjectInfo{"PhotoApp:User:"}, public.Subjects)
+	assert.Equal(t, "subject.age gt 18", public.Condition.Rule)
+
+	policies, err = server.provider.GetPolicyInfo(server.info, server.empty)
+	assert.NoError(t, err)
+	assert.Empty(t, policies)
+
+	_, err = server.provider.GetPolicyInfo(server.info, policyprovider.ApplicationInfo{})
+	assert.ErrorContains(t, err, "missing openfga store id")
+}
+
+func TestOpenFgaProvider_SetPolicyInfo(t *testing.T) {
+	server := setupServer(t)
+
+	policies, err := server.provider.GetPolicyInfo(server.info, server.app)
+	assert.NoError(t, err)
+	byId := policyMap(policies)
+	vacation := byId["Photo:vacation.jpg#viewPhoto,listPhotos"]
+	vacation.Subjects = hexapolicy.SubjectInfo{"PhotoApp:User:\"bob\"", "[PhotoApp:UserGroup:\"friends\"]"}
+	public := byId["Photo:public.jpg#viewPhoto"]
+
+	status, err := server.provider.SetPolicyInfo(server.info, server.app, []hexapolicy.PolicyInfo{vacation, public})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	// the writes and deletes are applied in one (atomic) request
+	assert.Equal(t, 1, server.fake.RequestCount("POST", "/write"))
+	assert.Len(t, server.fake.Writes[0].Writes, 2)
+	assert.Len(t, server.fake.Writes[0].Deletes, 2)
+
+	tuples := server.fake.Tuples(server.app.ObjectID)
+	assert.Len(t, tuples, 6)
+	assert.Contains(t, tuples, openfga.TupleKey{User: "User:bob", Relation: "listPhotos", Object: "Photo:vacation.jpg"})
+	assert.NotContains(t, tuples, openfga.TupleKey{User: "User:alice", Relation: "viewPhoto", Object: "Photo:vacation.jpg"})
+	assert.Contains(t, tuples, openfga.TupleKey{User: "UserGroup:friends#owner", Relation: "viewPhoto", Object: "Photo:vacation.jpg"})
+	assert.Contains(t, tuples, openfga.TupleKey{User: "User:*", Relation: "viewPhoto", Object: "Photo:public.jpg", Condition: &openfga.RelationshipCondition{Name: "adults"}})
+
+	// no changes are written when the policies are unchanged
+	_, err = server.provider.SetPolicyInfo(server.info, server.app, []hexapolicy.PolicyInfo{vacation, public})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, server.fake.RequestCount("POST", "/write"))
+
+	difs, err := server.provider.Reconcile(server.info, server.app, []hexapolicy.PolicyInfo{vacation, public}, true)
+	assert.NoError(t, err)
+	assert.Empty(t, difs)
+
+	public.Subjects = hexapolicy.SubjectInfo{"PhotoApp:User:\"carol\""}
+	difs, err = server.provider.Reconcile(server.info, server.app, []hexapolicy.PolicyInfo{vacation, public}, true)
+	assert.NoError(t, err)
+	assert.Len(t, difs, 1)
+	assert.Equal(t, hexapolicy.ChangeTypeUpdate, difs[0].Type)
+}
+
+func TestOpenFgaProvider_SetPolicyInfo_Batches(t *testing.T) {
+	server := setupServer(t)
+
+	subjects := hexapolicy.SubjectInfo{}
+	for i := 0; i < 150; i++ {
+		subjects = append(subjects, fmt.Sprintf("PhotoApp:User:\"user%d\"", i))
+	}
+	policy := hexapolicy.PolicyInfo{
+		Subjects: subjects,
+		Actions:  []hexapolicy.ActionInfo{"PhotoApp:Action:\"viewPhoto\""},
+		Object:   "PhotoApp:Photo:\"popular.jpg\"",
+	}
+	_, err := server.provider.SetPolicyInfo(server.info, server.app, []hexapolicy.PolicyInfo{policy})
+	assert.NoError(t, err)
+	// two write requests and then one delete request
+	assert.Equal(t, 3, server.fake.RequestCount("POST", "/write"))
+	assert.Len(t, server.fake.Writes[0].Writes, 100)
+	assert.Len(t, server.fake.Writes[1].Writes, 50)
+	assert.Empty(t, server.fake.Writes[1].Deletes)
+	assert.Empty(t, server.fake.Writes[2].Writes)
+	assert.NotEmpty(t, server.fake.Writes[2].Deletes)
+	assert.Len(t, server.fake.Tuples(server.app.ObjectID), 151)
+}
+
+func TestOpenFgaProvider_SetPolicyInfo_ChangedCondition(t *testing.T) {
+	server := setupServer(t)
+
+	policies, err := server.provider.GetPolicyInfo(server.info, server.app)
+	assert.NoError(t, err)
+	byId := policyMap(policies)
+	vacation := byId["Photo:vacation.jpg#viewPhoto,listPhotos"]
+	public := byId["Photo:public.jpg#viewPhoto"]
+	public.Condition = nil
+
+	// the conditional tuple is deleted before the tuple without the condition is written
+	_, err = server.provider.SetPolicyInfo(server.info, server.app, []hexapolicy.PolicyInfo{vacation, public})
+	assert.NoError(t, err)
+	unconditional := openfga.TupleKey{User: "User:*", Relation: "viewPhoto", Object: "Photo:public.jpg"}
+	assert.Equal(t, []openfgatestsupport.WriteRequest{
+		{Deletes: []openfga.TupleKey{unconditional}}, // deletes are keyed by user, relation and object
+		{Writes: []openfga.TupleKey{unconditional}},
+	}, server.fake.Writes)
+	assert.Contains(t, server.fake.Tuples(server.app.ObjectID), unconditional)
+}
+
+func TestOpenFgaProvider_SetPolicyInfo_Errors(t *testing.T) {
+	server := setupServer(t)
+
+	policy := hexapolicy.PolicyInfo{
+		Subjects:  []string{"PhotoApp:User:\"alice\""},
+		Actions:   []hexapolicy.ActionInfo{"PhotoApp:Action:\"viewPhoto\""},
+		Object:    "PhotoApp:Photo:\"vacation.jpg\"",
+		Condition: &conditions.ConditionInfo{Rule: "subject.age lt 13", Action: conditions.AAllow},
+	}
+	status, err := server.provider.SetPolicyInfo(server.info, server.app, []hexapolicy.PolicyInfo{policy})
+	assert.ErrorContains(t, err, "is not defined in the OpenFGA authorization model")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	policy.Condition = nil
+	policy.Actions = []hexapolicy.ActionInfo{"PhotoApp:Action:\"deletePhoto\""}
+	_, err = server.provider.SetPolicyInfo(server.info, server.app, []hexapolicy.PolicyInfo{policy})
+	assert.ErrorContains(t, err, "relation deletePhoto is not defined")
+
+	_, err = server.provider.SetPolicyInfo(server.info, server.empty, []hexapolicy.PolicyInfo{policy})
+	assert.ErrorContains(t, err, "has no authorization model")
+	assert.Equal(t, 0, server.fake.RequestCount("POST", "/write"))
+}
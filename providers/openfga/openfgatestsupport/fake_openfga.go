@@ -0,0 +1,323 @@
+/*
+Package openfgatestsupport provides an in-memory stand-in for the OpenFGA HTTP API (stores, authorization models and
+tuples) served by httptest. Used by the openfgaClient and openfgaProvider tests.
+*/
+package openfgatestsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hexa-org/policy-mapper/models/formats/openfga"
+)
+
+const (
+	FakeApiToken      = "fake-openfga-key"
+	maxTuplesPerWrite = 100
+	defaultPageSize   = 50
+)
+
+type fakeModel struct {
+	Id string `json:"id"`
+	openfga.AuthorizationModel
+}
+
+type fakeStore struct {
+	Id        string      `json:"id"`
+	Name      string      `json:"name"`
+	CreatedAt string      `json:"created_at"`
+	UpdatedAt string      `json:"updated_at"`
+	models    []fakeModel // models holds the authorization models, latest first
+	tuples    []openfga.TupleKey
+}
+
+// WriteRequest holds the tuples of a successful write request
+type WriteRequest struct {
+	Writes  []openfga.TupleKey
+	Deletes []openfga.TupleKey
+}
+
+// FakeOpenFga is an httptest server implementing the OpenFGA stores, authorization-models, read and write APIs
+type FakeOpenFga struct {
+	Server   *httptest.Server
+	Requests []string       // Requests is the list of "METHOD path" requests received
+	Writes   []WriteRequest // Writes is the list of successful write requests, in order
+
+	mu     sync.Mutex
+	nextId int
+	stores []*fakeStore
+}
+
+func NewFakeOpenFga() *FakeOpenFga {
+	fake := &FakeOpenFga{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stores", fake.listStores)
+	mux.HandleFunc("GET /stores/{store}/authorization-models", fake.listModels)
+	mux.HandleFunc("POST /stores/{store}/read", fake.read)
+	mux.HandleFunc("POST /stores/{store}/write", fake.write)
+	fake.Server = httptest.NewServer(fake.authenticate(mux))
+	return fake
+}
+
+func (f *FakeOpenFga) Close() {
+	f.Server.Close()
+}
+
+// Key returns an integration key for the fake server
+func (f *FakeOpenFga) Key(namespace string) []byte {
+	key, _ := json.Marshal(map[string]string{"apiUrl": f.Server.URL, "apiToken": FakeApiToken, "namespace": namespace})
+	return key
+}
+
+func (f *FakeOpenFga) AddStore(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	store := &fakeStore{Id: f.newId(), Name: name, CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-01T00:00:00Z"}
+	f.stores = append(f.stores, store)
+	return store.Id
+}
+
+// AddModel adds an authorization model to the store which becomes its latest model
+func (f *FakeOpenFga) AddModel(storeId string, model openfga.AuthorizationModel) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	store := f.store(storeId)
+	id := f.newId()
+	store.models = append([]fakeModel{{Id: id, AuthorizationModel: model}}, store.models...)
+	return id
+}
+
+func (f *FakeOpenFga) AddTuples(storeId string, tuples ...openfga.TupleKey) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	store := f.store(storeId)
+	store.tuples = append(store.tuples, tuples...)
+}
+
+// Tuples returns a copy of the tuples of the store
+func (f *FakeOpenFga) Tuples(storeId string) []openfga.TupleKey {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]openfga.TupleKey{}, f.store(storeId).tuples...)
+}
+
+// RequestCount returns the number of requests with the method and a path ending with suffix
+func (f *FakeOpenFga) RequestCount(method string, suffix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, request := range f.Requests {
+		if strings.HasPrefix(request, method+" ") && strings.HasSuffix(request, suffix) {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *FakeOpenFga) newId() string {
+	f.nextId++
+	return fmt.Sprintf("01HFAKE%019d", f.nextId)
+}
+
+func (f *FakeOpenFga) store(id string) *fakeStore {
+	for _, store := range f.stores {
+		if store.Id == id {
+			return store
+		}
+	}
+	return nil
+}
+
+func (f *FakeOpenFga) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+FakeApiToken {
+			writeError(w, http.StatusUnauthorized, "unauthenticated", "unauthenticated")
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.Requests = append(f.Requests, r.Method+" "+r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// storeOf returns the store of the request path or writes a not found error
+func (f *FakeOpenFga) storeOf(w http.ResponseWriter, r *http.Request) *fakeStore {
+	store := f.store(r.PathValue("store"))
+	if store == nil {
+		writeError(w, http.StatusNotFound, "store_id_not_found", "store_id_not_found")
+	}
+	return store
+}
+
+func (f *FakeOpenFga) listStores(w http.ResponseWriter, r *http.Request) {
+	items := make([]interface{}, len(f.stores))
+	for i, store := range f.stores {
+		items[i] = store
+	}
+	page, token := paginate(r.URL.Query().Get("page_size"), r.URL.Query().Get("continuation_token"), items)
+	writeJson(w, http.StatusOK, map[string]interface{}{"stores": page, "continuation_token": token})
+}
+
+func (f *FakeOpenFga) listModels(w http.ResponseWriter, r *http.Request) {
+	store := f.storeOf(w, r)
+	if store == nil {
+		return
+	}
+	items := make([]interface{}, len(store.models))
+	for i, model := range store.models {
+		items[i] = model
+	}
+	page, token := paginate(r.URL.Query().Get("page_size"), r.URL.Query().Get("continuation_token"), items)
+	writeJson(w, http.StatusOK, map[string]interface{}{"authorization_models": page, "continuation_token": token})
+}
+
+func (f *FakeOpenFga) read(w http.ResponseWriter, r *http.Request) {
+	store := f.storeOf(w, r)
+	if store == nil {
+		return
+	}
+	var request struct {
+		PageSize          int    `json:"page_size"`
+		ContinuationToken string `json:"continuation_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	items := make([]interface{}, len(store.tuples))
+	for i, tuple := range store.tuples {
+		items[i] = map[string]interface{}{"key": tuple, "timestamp": "2024-01-01T00:00:00Z"}
+	}
+	page, token := paginate(strconv.Itoa(request.PageSize), request.ContinuationToken, items)
+	writeJson(w, http.StatusOK, map[string]interface{}{"tuples": page, "continuation_token": token})
+}
+
+// write applies deletes and then writes, rejecting the whole request when a tuple is invalid, already exists (writes)
+// or does not exist (deletes)
+func (f *FakeOpenFga) write(w http.ResponseWriter, r *http.Request) {
+	store := f.storeOf(w, r)
+	if store == nil {
+		return
+	}
+	var request struct {
+		AuthorizationModelId string `json:"authorization_model_id"`
+		Writes               *struct {
+			TupleKeys []openfga.TupleKey `json:"tuple_keys"`
+		} `json:"writes"`
+		Deletes *struct {
+			TupleKeys []openfga.TupleKey `json:"tuple_keys"`
+		} `json:"deletes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	var writes, deletes []openfga.TupleKey
+	if request.Writes != nil {
+		writes = request.Writes.TupleKeys
+	}
+	if request.Deletes != nil {
+		deletes = request.Deletes.TupleKeys
+	}
+	if len(writes)+len(deletes) > maxTuplesPerWrite {
+		writeError(w, http.StatusBadRequest, "exceeded_entity_limit", fmt.Sprintf("number of write operations exceeds the allowed limit of %d", maxTuplesPerWrite))
+		return
+	}
+	var model *fakeModel
+	for i := range store.models {
+		if request.AuthorizationModelId == "" || store.models[i].Id == request.AuthorizationModelId {
+			model = &store.models[i]
+			break
+		}
+	}
+	if model == nil {
+		writeError(w, http.StatusBadRequest, "latest_authorization_model_not_found", "authorization model not found")
+		return
+	}
+
+	for _, tuple := range writes {
+		if indexOf(deletes, tuple) >= 0 {
+			writeError(w, http.StatusBadRequest, "cannot_allow_duplicate_tuples_in_one_request", fmt.Sprintf("duplicate tuple in write: user: '%s', relation: '%s', object: '%s'", tuple.User, tuple.Relation, tuple.Object))
+			return
+		}
+	}
+
+	tuples := append([]openfga.TupleKey{}, store.tuples...)
+	for _, tuple := range deletes {
+		index := indexOf(tuples, tuple)
+		if index < 0 {
+			writeError(w, http.StatusBadRequest, "write_failed_due_to_invalid_input", fmt.Sprintf("cannot delete a tuple which does not exist: user: '%s', relation: '%s', object: '%s'", tuple.User, tuple.Relation, tuple.Object))
+			return
+		}
+		tuples = append(tuples[:index], tuples[index+1:]...)
+	}
+	for _, tuple := range writes {
+		if indexOf(tuples, tuple) >= 0 {
+			writeError(w, http.StatusBadRequest, "write_failed_due_to_invalid_input", fmt.Sprintf("cannot write a tuple which already exists: user: '%s', relation: '%s', object: '%s'", tuple.User, tuple.Relation, tuple.Object))
+			return
+		}
+		objectType, _, _ := strings.Cut(tuple.Object, ":")
+		definition := model.TypeDefinition(objectType)
+		if definition == nil {
+			writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("type '%s' not found", objectType))
+			return
+		}
+		if _, exists := definition.Relations[tuple.Relation]; !exists {
+			writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("relation '%s#%s' not found", objectType, tuple.Relation))
+			return
+		}
+		if tuple.Condition != nil {
+			if _, exists := model.Conditions[tuple.Condition.Name]; !exists {
+				writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("condition '%s' is undefined", tuple.Condition.Name))
+				return
+			}
+		}
+		tuples = append(tuples, tuple)
+	}
+	store.tuples = tuples
+	f.Writes = append(f.Writes, WriteRequest{Writes: writes, Deletes: deletes})
+	writeJson(w, http.StatusOK, map[string]interface{}{})
+}
+
+// indexOf returns the index of the tuple with the same user, relation and object or -1
+func indexOf(tuples []openfga.TupleKey, tuple openfga.TupleKey) int {
+	for i, existing := range tuples {
+		if existing.User == tuple.User && existing.Relation == tuple.Relation && existing.Object == tuple.Object {
+			return i
+		}
+	}
+	return -1
+}
+
+// paginate returns the page of items selected by the page size and continuation token (an offset) and the next token
+func paginate(pageSize string, continuationToken string, items []interface{}) ([]interface{}, string) {
+	size, err := strconv.Atoi(pageSize)
+	if err != nil || size <= 0 {
+		size = defaultPageSize
+	}
+	offset, _ := strconv.Atoi(continuationToken)
+	if offset >= len(items) {
+		return []interface{}{}, ""
+	}
+	end := min(offset+size, len(items))
+	if end < len(items) {
+		return items[offset:end], strconv.Itoa(end)
+	}
+	return items[offset:end], ""
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJson(w, status, map[string]string{"code": code, "message": message})
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,281 @@
+package restProvider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/pkg/oauth2support"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	defaultPageSize = 100
+	maxPages        = 10000 // maxPages stops pagination that does not end
+)
+
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// restRequest is a rendered API request
+type restRequest struct {
+	method string
+	url    string
+	body   []byte
+}
+
+type restClient struct {
+	config     *Config
+	httpClient *http.Client
+}
+
+func newRestClient(config *Config, httpClient *http.Client) *restClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	if config.Auth != nil && config.Auth.Type == AuthOAuth2 {
+		credentials := &clientcredentials.Config{
+			ClientID:     config.Auth.ClientId,
+			ClientSecret: config.Auth.ClientSecret,
+			TokenURL:     config.Auth.TokenUrl,
+			Scopes:       config.Auth.Scopes,
+			AuthStyle:    oauth2.AuthStyleAutoDetect,
+		}
+		if config.Auth.Audience != "" {
+			credentials.EndpointParams = url.Values{"audience": {config.Auth.Audience}}
+		}
+		httpClient = oauth2support.NewJwtClientHandlerWithConfig(credentials, httpClient).GetHttpClient()
+	}
+	return &restClient{config: config, httpClient: httpClient}
+}
+
+// newRequest renders the endpoint path and body. The body must be valid JSON.
+func (c *restClient) newRequest(name string, endpoint Endpoint, defaultMethod string, data TemplateData) (*restRequest, error) {
+	path, err := render(name+".path", endpoint.Path, data)
+	if err != nil {
+		return nil, err
+	}
+	request := &restRequest{method: defaultMethod, url: c.resolve(path)}
+	if endpoint.Method != "" {
+		request.method = strings.ToUpper(endpoint.Method)
+	}
+	if endpoint.Body != "" {
+		body, err := render(name+".body", endpoint.Body, data)
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(body)) {
+			return nil, fmt.Errorf("%s.body template produced invalid JSON: %s", name, body)
+		}
+		request.body = []byte(body)
+	}
+	return request, nil
+}
+
+// list returns the records of all pages of a list endpoint
+func (c *restClient) list(name string, endpoint Endpoint, data TemplateData) ([]interface{}, error) {
+	request, err := c.newRequest(name, endpoint, http.MethodGet, data)
+	if err != nil {
+		return nil, err
+	}
+	pagination := Pagination{}
+	if endpoint.Pagination != nil {
+		pagination = *endpoint.Pagination
+	}
+	size := pagination.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	page := pagination.Start
+	if page == 0 {
+		page = 1
+	}
+	offset := 0
+	cursor := ""
+
+	// next page links are only followed on the origin of the list request so that credentials are not sent elsewhere
+	origin, err := url.Parse(request.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []interface{}
+	nextUrl := request.url
+	for pages := 0; pages < maxPages; pages++ {
+		pageUrl, err := url.Parse(nextUrl)
+		if err != nil {
+			return nil, err
+		}
+		query := pageUrl.Query()
+		switch pagination.Type {
+		case PageCursor:
+			if cursor != "" {
+				query.Set(defaultString(pagination.Param, "cursor"), cursor)
+			}
+		case PageOffset:
+			query.Set(defaultString(pagination.Param, "offset"), strconv.Itoa(offset))
+			query.Set(defaultString(pagination.SizeParam, "limit"), strconv.Itoa(size))
+		case PageNumber:
+			query.Set(defaultString(pagination.Param, "page"), strconv.Itoa(page))
+			query.Set(defaultString(pagination.SizeParam, "size"), strconv.Itoa(size))
+		}
+		pageUrl.RawQuery = query.Encode()
+
+		resp, body, err := c.do(request.method, pageUrl.String(), request.body)
+		if err != nil {
+			return nil, err
+		}
+		items, err := lookup(body, endpoint.Items)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 1 {
+			if array, ok := items[0].([]interface{}); ok {
+				items = array
+			}
+		}
+		records = append(records, items...)
+
+		nextUrl = ""
+		switch pagination.Type {
+		case PageLink:
+			for _, link := range resp.Header.Values("Link") {
+				if match := nextLinkRegex.FindStringSubmatch(link); match != nil {
+					if nextUrl, err = resolveLink(name, origin, pageUrl, match[1]); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case PageNext:
+			next, err := mapValue(name+".pagination.next", pagination.Next, body)
+			if err != nil {
+				return nil, err
+			}
+			if next != "" {
+				if nextUrl, err = resolveLink(name, origin, pageUrl, next); err != nil {
+					return nil, err
+				}
+			}
+		case PageCursor:
+			if cursor, err = mapValue(name+".pagination.next", pagination.Next, body); err != nil {
+				return nil, err
+			}
+			if cursor != "" {
+				nextUrl = request.url
+			}
+		case PageOffset, PageNumber:
+			if len(items) >= size {
+				offset += len(items)
+				page++
+				nextUrl = request.url
+			}
+		}
+		if nextUrl == "" {
+			return records, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: more than %d pages", name, maxPages)
+}
+
+func (c *restClient) send(request *restRequest) error {
+	_, _, err := c.do(request.method, request.url, request.body)
+	return err
+}
+
+// do sends a request and decodes the JSON response (numbers are decoded as json.Number)
+func (c *restClient) do(method string, reqUrl string, body []byte) (*http.Response, interface{}, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, reqUrl, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if auth := c.config.Auth; auth != nil {
+		switch auth.Type {
+		case AuthBearer:
+			req.Header.Set("Authorization", "Bearer "+auth.Token)
+		case AuthHeader:
+			req.Header.Set(auth.Header, auth.Value)
+		case AuthBasic:
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, nil, &HttpError{Method: method, Url: reqUrl, StatusCode: resp.StatusCode, Body: string(respBytes)}
+	}
+	var result interface{}
+	if len(bytes.TrimSpace(respBytes)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(respBytes))
+		decoder.UseNumber()
+		if err = decoder.Decode(&result); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON response from %s %s: %w", method, reqUrl, err)
+		}
+	}
+	return resp, result, nil
+}
+
+// resolve returns an absolute url for an endpoint path relative to the base url
+func (c *restClient) resolve(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return strings.TrimSuffix(c.config.BaseUrl, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// resolveLink returns the absolute url of a next page link relative to the page url. The link must have the scheme and
+// host of origin.
+func resolveLink(name string, origin *url.URL, pageUrl *url.URL, link string) (string, error) {
+	linkUrl, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	nextUrl := pageUrl.ResolveReference(linkUrl)
+	if !strings.EqualFold(nextUrl.Scheme, origin.Scheme) || !strings.EqualFold(nextUrl.Host, origin.Host) {
+		return "", fmt.Errorf("%s: next page link %s is not on %s://%s", name, nextUrl.Redacted(), origin.Scheme, origin.Host)
+	}
+	return nextUrl.String(), nil
+}
+
+func defaultString(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// HttpError is returned when the API returns an error status
+type HttpError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("rest %s %s failed (%d): %s", e.Method, e.Url, e.StatusCode, strings.TrimSpace(e.Body))
+}
@@ -0,0 +1,305 @@
+package restProvider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+)
+
+const (
+	AuthBearer = "bearer" // AuthBearer sends a static bearer token
+	AuthHeader = "header" // AuthHeader sends a static header (e.g. X-Api-Key)
+	AuthBasic  = "basic"  // AuthBasic uses HTTP basic authentication
+	AuthOAuth2 = "oauth2" // AuthOAuth2 obtains tokens with an OAuth2 client credentials grant
+
+	PageLink   = "link"   // PageLink follows the Link: <url>; rel="next" response header
+	PageNext   = "next"   // PageNext follows a next page url found in the response body
+	PageCursor = "cursor" // PageCursor sends a cursor found in the response body as a query parameter
+	PageOffset = "offset" // PageOffset sends offset and limit query parameters
+	PageNumber = "page"   // PageNumber sends page number and page size query parameters
+)
+
+/*
+Config is the integration key of the REST provider. It describes the endpoints of an application permissions API and
+how its permission records map to IDQL, for example:
+
+	{
+	  "baseUrl": "https://app.example.com/api",
+	  "auth": {"type": "bearer", "token": "..."},
+	  "apps": [{"id": "photos", "name": "Photo App"}],
+	  "list": {"path": "/apps/{{.App.ObjectID}}/permissions", "items": "items",
+	           "pagination": {"type": "cursor", "next": "next_cursor", "param": "cursor"}},
+	  "create": {"path": "/apps/{{.App.ObjectID}}/permissions",
+	             "body": "{\"id\": {{json .Id}}, \"members\": {{json .Subjects}}, \"scopes\": {{json .Actions}}, \"resource\": {{json .Object}}}"},
+	  "update": {"path": "/apps/{{.App.ObjectID}}/permissions/{{pathEscape .Id}}", "body": "..."},
+	  "delete": {"path": "/apps/{{.App.ObjectID}}/permissions/{{pathEscape .Id}}"},
+	  "mapping": {"id": "id", "subjects": "members[*].email", "subjectPrefix": "user:", "actions": "scopes", "object": "resource"}
+	}
+
+Applications are either listed (apps) or discovered (discover). Endpoint paths and bodies are Go templates (see
+TemplateData).
+*/
+type Config struct {
+	BaseUrl  string            `json:"baseUrl"`
+	Headers  map[string]string `json:"headers,omitempty"` // Headers are sent with every request
+	Auth     *AuthConfig       `json:"auth,omitempty"`
+	Apps     []AppConfig       `json:"apps,omitempty"`
+	Discover *DiscoverEndpoint `json:"discover,omitempty"`
+	List     Endpoint          `json:"list"`
+	Create   *Endpoint         `json:"create,omitempty"`
+	Update   *Endpoint         `json:"update,omitempty"`
+	Delete   *Endpoint         `json:"delete,omitempty"`
+	Mapping  Mapping           `json:"mapping"`
+}
+
+type AuthConfig struct {
+	Type         string   `json:"type"`
+	Token        string   `json:"token,omitempty"`        // Token is the bearer token
+	Header       string   `json:"header,omitempty"`       // Header is the name of the header for AuthHeader
+	Value        string   `json:"value,omitempty"`        // Value is the header value for AuthHeader
+	Username     string   `json:"username,omitempty"`     // Username is the AuthBasic user
+	Password     string   `json:"password,omitempty"`     // Password is the AuthBasic password
+	ClientId     string   `json:"clientId,omitempty"`     // ClientId is the AuthOAuth2 client
+	ClientSecret string   `json:"clientSecret,omitempty"` // ClientSecret is the AuthOAuth2 client secret
+	TokenUrl     string   `json:"tokenUrl,omitempty"`     // TokenUrl is the AuthOAuth2 token endpoint
+	Scopes       []string `json:"scopes,omitempty"`
+	Audience     string   `json:"audience,omitempty"`
+}
+
+type AppConfig struct {
+	Id          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Endpoint is an API operation. Path (relative to baseUrl or absolute) and Body are templates.
+type Endpoint struct {
+	Method     string      `json:"method,omitempty"` // Method defaults to GET (list), POST (create), PUT (update) or DELETE (delete)
+	Path       string      `json:"path"`
+	Body       string      `json:"body,omitempty"`  // Body is a template producing the JSON request body
+	Items      string      `json:"items,omitempty"` // Items is the path of the records in a list response (default the response)
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// DiscoverEndpoint lists applications. Id, Name and Description are paths or templates evaluated for each record.
+type DiscoverEndpoint struct {
+	Endpoint
+	Id          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Pagination struct {
+	Type      string `json:"type"`
+	Next      string `json:"next,omitempty"`      // Next is the path of the next url (PageNext) or cursor (PageCursor)
+	Param     string `json:"param,omitempty"`     // Param is the cursor, offset or page query parameter
+	SizeParam string `json:"sizeParam,omitempty"` // SizeParam is the page size query parameter (PageOffset and PageNumber)
+	Size      int    `json:"size,omitempty"`      // Size is the requested page size (default 100)
+	Start     int    `json:"start,omitempty"`     // Start is the first page number for PageNumber (default 1)
+}
+
+/*
+Mapping maps permission records to IDQL. Each value is either a path (e.g. `members[*].email`) or, when it contains
+`{{`, a template evaluated with the record. A template for a list (subjects or actions) may produce a JSON array.
+Prefixes are added to values read and removed from values written.
+*/
+type Mapping struct {
+	Id            string `json:"id"`
+	Subjects      string `json:"subjects"`
+	SubjectPrefix string `json:"subjectPrefix,omitempty"`
+	Actions       string `json:"actions"`
+	ActionPrefix  string `json:"actionPrefix,omitempty"`
+	Object        string `json:"object,omitempty"`
+	Condition     string `json:"condition,omitempty"` // Condition is the IDQL condition rule of the record (optional)
+}
+
+/*
+TemplateData is the data of endpoint path and body templates. Policy values are those of the IDQL policy being
+created or updated with the Mapping prefixes removed. Record is the existing record (update and delete).
+*/
+type TemplateData struct {
+	App       policyprovider.ApplicationInfo
+	Id        string
+	Subjects  []string
+	Actions   []string
+	Object    string
+	Condition string
+	Policy    hexapolicy.PolicyInfo
+	Record    interface{}
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		valueBytes, err := json.Marshal(value)
+		return string(valueBytes), err
+	},
+	"join":        strings.Join,
+	"lower":       strings.ToLower,
+	"trimPrefix":  func(prefix string, value string) string { return strings.TrimPrefix(value, prefix) },
+	"pathEscape":  url.PathEscape,
+	"queryEscape": url.QueryEscape,
+}
+
+func parseConfig(key []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(key, &config); err != nil {
+		return nil, fmt.Errorf("invalid rest integration key: %w", err)
+	}
+	switch {
+	case config.List.Path == "":
+		return nil, errors.New("invalid rest integration key: list.path is required")
+	case config.Mapping.Id == "" || config.Mapping.Subjects == "" || config.Mapping.Actions == "":
+		return nil, errors.New("invalid rest integration key: mapping id, subjects and actions are required")
+	case len(config.Apps) == 0 && config.Discover == nil:
+		return nil, errors.New("invalid rest integration key: apps or discover is required")
+	case config.Discover != nil && config.Discover.Id == "":
+		return nil, errors.New("invalid rest integration key: discover.id is required")
+	}
+	if config.Auth != nil {
+		switch config.Auth.Type {
+		case AuthBearer, AuthHeader, AuthBasic:
+		case AuthOAuth2:
+			if config.Auth.TokenUrl == "" || config.Auth.ClientId == "" {
+				return nil, errors.New("invalid rest integration key: oauth2 auth requires clientId and tokenUrl")
+			}
+		default:
+			return nil, fmt.Errorf("invalid rest integration key: unsupported auth type '%s'", config.Auth.Type)
+		}
+	}
+	return &config, nil
+}
+
+// render executes a template (a path or body) with data
+func render(name string, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	var out bytes.Buffer
+	if err = tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("%s template: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+/*
+lookup returns the values at a path in a decoded JSON value. A path is a list of object keys separated by `.` where
+each key may be followed by array selectors: an index (`[0]`) or all elements (`[*]`). An empty path or `.` selects
+the value itself, for example `members[*].email` or `data[0].id`.
+*/
+func lookup(value interface{}, path string) ([]interface{}, error) {
+	values := []interface{}{value}
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return values, nil
+	}
+	for _, element := range strings.Split(path, ".") {
+		key, selectors, _ := strings.Cut(element, "[")
+		if selectors != "" {
+			selectors = "[" + selectors
+		}
+		if key != "" {
+			var next []interface{}
+			for _, v := range values {
+				if object, ok := v.(map[string]interface{}); ok {
+					if child, exists := object[key]; exists {
+						next = append(next, child)
+					}
+				}
+			}
+			values = next
+		}
+		for selectors != "" {
+			end := strings.Index(selectors, "]")
+			if !strings.HasPrefix(selectors, "[") || end < 0 {
+				return nil, fmt.Errorf("invalid path '%s'", path)
+			}
+			selector := selectors[1:end]
+			selectors = selectors[end+1:]
+			var next []interface{}
+			for _, v := range values {
+				array, ok := v.([]interface{})
+				if !ok {
+					continue
+				}
+				if selector == "*" {
+					next = append(next, array...)
+					continue
+				}
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path '%s'", path)
+				}
+				if index >= 0 && index < len(array) {
+					next = append(next, array[index])
+				}
+			}
+			values = next
+		}
+	}
+	return values, nil
+}
+
+// mapValues returns the string values of a mapping (a path or template) for the record. Arrays are flattened.
+func mapValues(name string, mapping string, record interface{}) ([]string, error) {
+	if strings.Contains(mapping, "{{") {
+		out, err := render(name, mapping, record)
+		if err != nil {
+			return nil, err
+		}
+		out = strings.TrimSpace(out)
+		if strings.HasPrefix(out, "[") {
+			var values []string
+			if err = json.Unmarshal([]byte(out), &values); err != nil {
+				return nil, fmt.Errorf("%s template must produce a JSON array of strings: %w", name, err)
+			}
+			return values, nil
+		}
+		if out == "" {
+			return nil, nil
+		}
+		return []string{out}, nil
+	}
+
+	found, err := lookup(record, mapping)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for len(found) > 0 {
+		value := found[0]
+		found = found[1:]
+		switch v := value.(type) {
+		case nil:
+		case string:
+			values = append(values, v)
+		case json.Number, bool, float64:
+			values = append(values, fmt.Sprint(v))
+		case []interface{}:
+			found = append(v, found...)
+		default:
+			return nil, fmt.Errorf("%s value at '%s' is not a string", name, mapping)
+		}
+	}
+	return values, nil
+}
+
+// mapValue returns the first value of a mapping or "" (an empty mapping has no value)
+func mapValue(name string, mapping string, record interface{}) (string, error) {
+	if mapping == "" {
+		return "", nil
+	}
+	values, err := mapValues(name, mapping, record)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[0], nil
+}
@@ -0,0 +1,107 @@
+package restProvider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	_, err := parseConfig([]byte("bad"))
+	assert.ErrorContains(t, err, "invalid rest integration key")
+
+	tests := []struct {
+		name string
+		key  string
+		err  string
+	}{
+		{"list", `{"apps":[{"id":"a"}],"mapping":{"id":"id","subjects":"s","actions":"a"}}`, "list.path is required"},
+		{"mapping", `{"apps":[{"id":"a"}],"list":{"path":"/p"},"mapping":{"id":"id"}}`, "mapping id, subjects and actions are required"},
+		{"apps", `{"list":{"path":"/p"},"mapping":{"id":"id","subjects":"s","actions":"a"}}`, "apps or discover is required"},
+		{"discover", `{"discover":{"path":"/apps"},"list":{"path":"/p"},"mapping":{"id":"id","subjects":"s","actions":"a"}}`, "discover.id is required"},
+		{"auth", `{"apps":[{"id":"a"}],"auth":{"type":"digest"},"list":{"path":"/p"},"mapping":{"id":"id","subjects":"s","actions":"a"}}`, "unsupported auth type 'digest'"},
+		{"oauth2", `{"apps":[{"id":"a"}],"auth":{"type":"oauth2"},"list":{"path":"/p"},"mapping":{"id":"id","subjects":"s","actions":"a"}}`, "requires clientId and tokenUrl"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseConfig([]byte(test.key))
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+
+	config, err := parseConfig([]byte(`{"discover":{"path":"/apps","items":"data","id":"id"},"list":{"path":"/p"},"mapping":{"id":"id","subjects":"s","actions":"a"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", config.Discover.Items)
+}
+
+func TestLookup(t *testing.T) {
+	var record interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":"p1","members":[{"email":"alice@example.com"},{"email":"bob@example.com"}],
+		"scopes":["read","write"],"owner":{"name":"carol"},"matrix":[[1,2],[3]]}`), &record))
+
+	values, err := lookup(record, "members[*].email")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"alice@example.com", "bob@example.com"}, values)
+
+	values, err = lookup(record, "members[1].email")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"bob@example.com"}, values)
+
+	values, err = lookup(record, "owner.name")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"carol"}, values)
+
+	values, err = lookup(record, "matrix[*][0]")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{float64(1), float64(3)}, values)
+
+	values, err = lookup(record, ".")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{record}, values)
+
+	values, err = lookup(record, "missing.value")
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = lookup(record, "members[x]")
+	assert.ErrorContains(t, err, "invalid path")
+	_, err = lookup(record, "members[0")
+	assert.ErrorContains(t, err, "invalid path")
+}
+
+func TestMapValues(t *testing.T) {
+	var record interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":42,"scopes":["read","write"],"owner":{"name":"carol"},"role":"admin"}`), &record))
+
+	values, err := mapValues("actions", "scopes", record)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read", "write"}, values)
+
+	_, err = mapValues("subjects", `{{.owner.name`, record)
+	assert.ErrorContains(t, err, "invalid subjects template")
+
+	values, err = mapValues("subjects", `["user:{{.owner.name}}", "role:{{.role}}"]`, record)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user:carol", "role:admin"}, values)
+
+	values, err = mapValues("subjects", `{{if .missing}}x{{end}}`, record)
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	id, err := mapValue("id", "id", record)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", id)
+
+	object, err := mapValue("object", `/owners/{{lower .owner.name}}`, record)
+	assert.NoError(t, err)
+	assert.Equal(t, "/owners/carol", object)
+
+	_, err = mapValues("subjects", "owner", record)
+	assert.ErrorContains(t, err, "is not a string")
+
+	body, err := render("body", `{"members": {{json .Subjects}}, "path": "{{pathEscape .Id}}", "scopes": "{{join .Actions ","}}"}`,
+		TemplateData{Id: "a/b", Subjects: []string{"alice"}, Actions: []string{"read", "write"}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"members":["alice"],"path":"a%2Fb","scopes":"read,write"}`, body)
+}
@@ -0,0 +1,336 @@
+/*
+Package restProvider is a generic provider for applications that expose their permissions through a JSON REST API.
+The integration key (see Config) describes the API endpoints, authentication, pagination and how permission records
+map to IDQL policies, so that simple applications can be onboarded without writing a provider.
+
+Each permission record is one IDQL policy identified by the record id. Setting policies lists the existing records and
+creates, updates or deletes records so that they match the policies.
+*/
+package restProvider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+)
+
+const (
+	ProviderTypeRest string = "rest"
+)
+
+type RestProvider struct {
+	httpClient *http.Client
+}
+
+type ProviderOpt func(provider *RestProvider)
+
+func WithHttpClient(client *http.Client) ProviderOpt {
+	return func(provider *RestProvider) {
+		provider.httpClient = client
+	}
+}
+
+func NewRestProvider(opts ...ProviderOpt) *RestProvider {
+	provider := &RestProvider{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(provider)
+		}
+	}
+	return provider
+}
+
+func (r *RestProvider) Name() string {
+	return ProviderTypeRest
+}
+
+// DiscoverApplications returns the configured apps or the applications returned by the discover endpoint
+func (r *RestProvider) DiscoverApplications(info policyprovider.IntegrationInfo) ([]policyprovider.ApplicationInfo, error) {
+	config, err := parseConfig(info.Key)
+	if err != nil {
+		return nil, err
+	}
+	var apps []policyprovider.ApplicationInfo
+	for _, app := range config.Apps {
+		apps = append(apps, policyprovider.ApplicationInfo{
+			ObjectID:    app.Id,
+			Name:        defaultString(app.Name, app.Id),
+			Description: app.Description,
+			Service:     ProviderTypeRest,
+		})
+	}
+	if config.Discover == nil {
+		return apps, nil
+	}
+
+	client := newRestClient(config, r.httpClient)
+	records, err := client.list("discover", config.Discover.Endpoint, TemplateData{})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		app := policyprovider.ApplicationInfo{Service: ProviderTypeRest}
+		if app.ObjectID, err = mapValue("discover.id", config.Discover.Id, record); err != nil {
+			return nil, err
+		}
+		if app.ObjectID == "" {
+			continue
+		}
+		if app.Name, err = mapValue("discover.name", config.Discover.Name, record); err != nil {
+			return nil, err
+		}
+		if app.Description, err = mapValue("discover.description", config.Discover.Description, record); err != nil {
+			return nil, err
+		}
+		app.Name = defaultString(app.Name, app.ObjectID)
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func (r *RestProvider) GetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo) ([]hexapolicy.PolicyInfo, error) {
+	config, err := parseConfig(info.Key)
+	if err != nil {
+		return nil, err
+	}
+	records, err := listPolicies(newRestClient(config, r.httpClient), config, app)
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]hexapolicy.PolicyInfo, len(records))
+	for i, record := range records {
+		policies[i] = record.policy
+	}
+	return policies, nil
+}
+
+/*
+SetPolicyInfo creates a record for each policy without a matching record id, updates the records of policies that
+changed and deletes records that have no policy. All requests are rendered before any change is made. A policy that
+changed is deleted and re-created when there is no update endpoint.
+*/
+func (r *RestProvider) SetPolicyInfo(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, policies []hexapolicy.PolicyInfo) (int, error) {
+	config, err := parseConfig(info.Key)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	client := newRestClient(config, r.httpClient)
+	existing, err := listPolicies(client, config, app)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	existingById := map[string]restPolicy{}
+	for _, record := range existing {
+		existingById[record.id] = record
+	}
+
+	var requests []*restRequest
+	handled := map[string]bool{}
+	for _, policy := range policies {
+		data, err := templateData(config, app, policy)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if data.Id != "" {
+			if handled[data.Id] {
+				return http.StatusBadRequest, fmt.Errorf("duplicate policy id %s", data.Id)
+			}
+			handled[data.Id] = true
+		}
+
+		current, exists := existingById[data.Id]
+		switch {
+		case exists && !policyChanged(policy, current.policy):
+			continue
+		case exists && config.Update != nil:
+			data.Record = current.record
+			request, err := client.newRequest("update", *config.Update, http.MethodPut, data)
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			requests = append(requests, request)
+			continue
+		case exists:
+			if config.Delete == nil {
+				return http.StatusBadRequest, fmt.Errorf("policy %s changed but the update and delete endpoints are not configured", data.Id)
+			}
+			deleteData := data
+			deleteData.Record = current.record
+			request, err := client.newRequest("delete", *config.Delete, http.MethodDelete, deleteData)
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			requests = append(requests, request)
+		}
+		if config.Create == nil {
+			return http.StatusBadRequest, fmt.Errorf("policy %s cannot be created: the create endpoint is not configured", data.Id)
+		}
+		request, err := client.newRequest("create", *config.Create, http.MethodPost, data)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		requests = append(requests, request)
+	}
+
+	for _, record := range existing {
+		if handled[record.id] {
+			continue
+		}
+		if config.Delete == nil {
+			return http.StatusBadRequest, fmt.Errorf("policy %s cannot be removed: the delete endpoint is not configured", record.id)
+		}
+		data := TemplateData{App: app, Id: record.id, Policy: record.policy, Record: record.record}
+		request, err := client.newRequest("delete", *config.Delete, http.MethodDelete, data)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		requests = append(requests, request)
+	}
+
+	for _, request := range requests {
+		if err = client.send(request); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (r *RestProvider) Reconcile(info policyprovider.IntegrationInfo, app policyprovider.ApplicationInfo, comparePolicies []hexapolicy.PolicyInfo, diffsOnly bool) ([]hexapolicy.PolicyDif, error) {
+	existing, err := r.GetPolicyInfo(info, app)
+	if err != nil {
+		return nil, err
+	}
+	existingPolicies := hexapolicy.Policies{Policies: existing, App: &app.ObjectID}
+	return existingPolicies.ReconcilePolicies(comparePolicies, diffsOnly), nil
+}
+
+// restPolicy is a permission record and the policy it maps to
+type restPolicy struct {
+	id     string
+	record interface{}
+	policy hexapolicy.PolicyInfo
+}
+
+func listPolicies(client *restClient, config *Config, app policyprovider.ApplicationInfo) ([]restPolicy, error) {
+	if app.ObjectID == "" {
+		return nil, errors.New("missing application id (ApplicationInfo.ObjectID)")
+	}
+	records, err := client.list("list", config.List, TemplateData{App: app})
+	if err != nil {
+		return nil, err
+	}
+	var policies []restPolicy
+	for _, record := range records {
+		policy, err := mapRecord(config.Mapping, app, record)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, restPolicy{id: *policy.Meta.PolicyId, record: record, policy: policy})
+	}
+	return policies, nil
+}
+
+// mapRecord maps a permission record into an IDQL policy
+func mapRecord(mapping Mapping, app policyprovider.ApplicationInfo, record interface{}) (hexapolicy.PolicyInfo, error) {
+	policyId, err := mapValue("mapping.id", mapping.Id, record)
+	if err != nil {
+		return hexapolicy.PolicyInfo{}, err
+	}
+	if policyId == "" {
+		return hexapolicy.PolicyInfo{}, fmt.Errorf("record has no id at '%s'", mapping.Id)
+	}
+	subjects, err := mapValues("mapping.subjects", mapping.Subjects, record)
+	if err != nil {
+		return hexapolicy.PolicyInfo{}, err
+	}
+	actions, err := mapValues("mapping.actions", mapping.Actions, record)
+	if err != nil {
+		return hexapolicy.PolicyInfo{}, err
+	}
+	object, err := mapValue("mapping.object", mapping.Object, record)
+	if err != nil {
+		return hexapolicy.PolicyInfo{}, err
+	}
+	rule, err := mapValue("mapping.condition", mapping.Condition, record)
+	if err != nil {
+		return hexapolicy.PolicyInfo{}, err
+	}
+
+	papId := app.ObjectID
+	policy := hexapolicy.PolicyInfo{
+		Meta: hexapolicy.MetaInfo{
+			Version:      hexapolicy.IdqlVersion,
+			PolicyId:     &policyId,
+			PapId:        &papId,
+			ProviderType: ProviderTypeRest,
+		},
+		Subjects: hexapolicy.SubjectInfo{},
+		Actions:  []hexapolicy.ActionInfo{},
+		Object:   hexapolicy.ObjectInfo(object),
+	}
+	for _, subject := range subjects {
+		policy.Subjects = append(policy.Subjects, mapping.SubjectPrefix+subject)
+	}
+	for _, action := range actions {
+		policy.Actions = append(policy.Actions, hexapolicy.ActionInfo(mapping.ActionPrefix+action))
+	}
+	if rule != "" {
+		policy.Condition = &conditions.ConditionInfo{Rule: rule, Action: conditions.AAllow}
+	}
+	return policy, nil
+}
+
+// templateData returns the template data for a policy with the mapping prefixes removed
+func templateData(config *Config, app policyprovider.ApplicationInfo, policy hexapolicy.PolicyInfo) (TemplateData, error) {
+	data := TemplateData{App: app, Policy: policy, Object: policy.Object.String(), Subjects: []string{}, Actions: []string{}}
+	if policy.Meta.PolicyId != nil {
+		data.Id = *policy.Meta.PolicyId
+	}
+	for _, subject := range policy.Subjects {
+		value, found := strings.CutPrefix(subject, config.Mapping.SubjectPrefix)
+		if !found {
+			return data, fmt.Errorf("policy %s: subject %s does not start with %s", data.Id, subject, config.Mapping.SubjectPrefix)
+		}
+		data.Subjects = append(data.Subjects, value)
+	}
+	for _, action := range policy.Actions {
+		value, found := strings.CutPrefix(action.String(), config.Mapping.ActionPrefix)
+		if !found {
+			return data, fmt.Errorf("policy %s: action %s does not start with %s", data.Id, action, config.Mapping.ActionPrefix)
+		}
+		data.Actions = append(data.Actions, value)
+	}
+	if policy.Condition != nil {
+		if config.Mapping.Condition == "" {
+			return data, fmt.Errorf("policy %s: conditions are not supported (no condition mapping)", data.Id)
+		}
+		// only the rule is mapped and conditions are read as allow, so a deny would be stored as a grant
+		if action := policy.Condition.Action; action != "" && !strings.EqualFold(action, conditions.AAllow) {
+			return data, fmt.Errorf("policy %s: condition action %s is not supported (only allow conditions can be mapped)", data.Id, action)
+		}
+		data.Condition = policy.Condition.Rule
+	}
+	return data, nil
+}
+
+// policyChanged returns true if the subjects, actions, object or condition of the policies differ
+func policyChanged(policy hexapolicy.PolicyInfo, existing hexapolicy.PolicyInfo) bool {
+	if !policy.Subjects.Equals(existing.Subjects) || policy.Object != existing.Object || len(policy.Actions) != len(existing.Actions) {
+		return true
+	}
+	for _, action := range policy.Actions {
+		if !slices.Contains(existing.Actions, action) {
+			return true
+		}
+	}
+	if (policy.Condition == nil) != (existing.Condition == nil) {
+		return true
+	}
+	return policy.Condition != nil && !policy.Condition.Equals(existing.Condition)
+}
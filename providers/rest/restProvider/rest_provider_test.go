@@ -0,0 +1,427 @@
+package restProvider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hexa-org/policy-mapper/api/policyprovider"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
+	"github.com/hexa-org/policy-mapper/pkg/hexapolicy/conditions"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testToken       = "test-api-token"
+	testOAuthToken  = "test-oauth-token"
+	testClientId    = "hexa"
+	testSecret      = "hexa-secret"
+	testPermissions = 2 // testPermissions is the permissions page size
+)
+
+type permission struct {
+	Id       string              `json:"id"`
+	Members  []map[string]string `json:"members"`
+	Scopes   []string            `json:"scopes"`
+	Resource string              `json:"resource"`
+	Rule     string              `json:"rule,omitempty"`
+}
+
+// permissionsApi is an in-memory application permissions API with cursor (and Link) paginated permissions and
+// page numbered applications
+type permissionsApi struct {
+	server      *httptest.Server
+	mu          sync.Mutex
+	apps        []map[string]string
+	permissions map[string][]permission
+	requests    []string
+	tokens      int
+	linkBase    string // linkBase is prefixed to Link header urls (e.g. to return absolute links)
+}
+
+func newPermissionsApi(t *testing.T) *permissionsApi {
+	t.Helper()
+	api := &permissionsApi{
+		apps: []map[string]string{
+			{"key": "photos", "title": "Photo App", "about": "Photo sharing"},
+			{"key": "payroll", "title": "Payroll"},
+			{"key": "crm", "title": "CRM"},
+		},
+		permissions: map[string][]permission{
+			"photos": {
+				{Id: "p1", Members: []map[string]string{{"email": "alice@example.com"}}, Scopes: []string{"read", "write"}, Resource: "/albums"},
+				{Id: "p2", Members: []map[string]string{{"email": "bob@example.com"}, {"email": "carol@example.com"}}, Scopes: []string{"read"}, Resource: "/photos"},
+				{Id: "p3", Members: []map[string]string{{"email": "dave@example.com"}}, Scopes: []string{"admin"}, Resource: "/", Rule: "subject.department eq \"it\""},
+			},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", api.token)
+	mux.HandleFunc("GET /api/apps", api.listApps)
+	mux.HandleFunc("GET /api/apps/{app}/permissions", api.listPermissions)
+	mux.HandleFunc("POST /api/apps/{app}/permissions", api.createPermission)
+	mux.HandleFunc("PUT /api/apps/{app}/permissions/{id}", api.updatePermission)
+	mux.HandleFunc("DELETE /api/apps/{app}/permissions/{id}", api.deletePermission)
+	api.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.URL.Path != "/oauth/token" && auth != "Bearer "+testToken && auth != "Bearer "+testOAuthToken {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		if r.URL.Path == "/oauth/token" {
+			api.tokens++
+		} else {
+			api.requests = append(api.requests, r.Method+" "+r.URL.Path)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+func (a *permissionsApi) token(w http.ResponseWriter, r *http.Request) {
+	clientId, secret, ok := r.BasicAuth()
+	if !ok {
+		_ = r.ParseForm()
+		clientId, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != testClientId || secret != testSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	writeTestJson(w, http.StatusOK, map[string]interface{}{"access_token": testOAuthToken, "token_type": "Bearer", "expires_in": 3600})
+}
+
+func (a *permissionsApi) listApps(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	start := min((page-1)*size, len(a.apps))
+	writeTestJson(w, http.StatusOK, map[string]interface{}{"data": a.apps[start:min(start+size, len(a.apps))]})
+}
+
+func (a *permissionsApi) listPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions := a.permissions[r.PathValue("app")]
+	start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+	end := min(start+testPermissions, len(permissions))
+	next, nextLink := "", ""
+	if end < len(permissions) {
+		next = strconv.Itoa(end)
+		nextLink = fmt.Sprintf("%s%s?cursor=%s", a.linkBase, r.URL.Path, next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextLink))
+	}
+	writeTestJson(w, http.StatusOK, map[string]interface{}{"items": permissions[start:end], "next_cursor": next, "next_link": nextLink})
+}
+
+func (a *permissionsApi) createPermission(w http.ResponseWriter, r *http.Request) {
+	var p permission
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	app := r.PathValue("app")
+	if p.Id == "" {
+		p.Id = fmt.Sprintf("p%d", len(a.permissions[app])+100)
+	}
+	a.permissions[app] = append(a.permissions[app], p)
+	writeTestJson(w, http.StatusCreated, p)
+}
+
+func (a *permissionsApi) updatePermission(w http.ResponseWriter, r *http.Request) {
+	app := r.PathValue("app")
+	index := slices.IndexFunc(a.permissions[app], func(p permission) bool { return p.Id == r.PathValue("id") })
+	if index < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	var p permission
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.Id = r.PathValue("id")
+	a.permissions[app][index] = p
+	writeTestJson(w, http.StatusOK, p)
+}
+
+func (a *permissionsApi) deletePermission(w http.ResponseWriter, r *http.Request) {
+	app := r.PathValue("app")
+	index := slices.IndexFunc(a.permissions[app], func(p permission) bool { return p.Id == r.PathValue("id") })
+	if index < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	a.permissions[app] = slices.Delete(a.permissions[app], index, index+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *permissionsApi) requestCount(method string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	count := 0
+	for _, request := range a.requests {
+		if strings.HasPrefix(request, method+" ") {
+			count++
+		}
+	}
+	return count
+}
+
+func writeTestJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+const permissionBody = `{"id": {{json .Id}}, "members": [{{range $i, $s := .Subjects}}{{if $i}}, {{end}}{"email": {{json $s}}}{{end}}], "scopes": {{json .Actions}}, "resource": {{json .Object}}, "rule": {{json .Condition}}}`
+
+// testConfig returns a configuration for the permissions API with the auth
+func testConfig(api *permissionsApi, auth *AuthConfig) Config {
+	return Config{
+		BaseUrl: api.server.URL + "/api",
+		Auth:    auth,
+		Discover: &DiscoverEndpoint{
+			Endpoint:    Endpoint{Path: "/apps", Items: "data", Pagination: &Pagination{Type: PageNumber, Size: 2}},
+			Id:          "key",
+			Name:        "title",
+			Description: "about",
+		},
+		List: Endpoint{
+			Path:       "/apps/{{pathEscape .App.ObjectID}}/permissions",
+			Items:      "items",
+			Pagination: &Pagination{Type: PageCursor, Next: "next_cursor"},
+		},
+		Create: &Endpoint{Path: "/apps/{{pathEscape .App.ObjectID}}/permissions", Body: permissionBody},
+		Update: &Endpoint{Path: "/apps/{{pathEscape .App.ObjectID}}/permissions/{{pathEscape .Id}}", Body: permissionBody},
+		Delete: &Endpoint{Path: "/apps/{{pathEscape .App.ObjectID}}/permissions/{{pathEscape .Id}}"},
+		Mapping: Mapping{
+			Id:            "id",
+			Subjects:      "members[*].email",
+			SubjectPrefix: "user:",
+			Actions:       "scopes",
+			ActionPrefix:  "photos:",
+			Object:        "resource",
+			Condition:     "rule",
+		},
+	}
+}
+
+func testInfo(t *testing.T, config Config) policyprovider.IntegrationInfo {
+	t.Helper()
+	key, err := json.Marshal(config)
+	assert.NoError(t, err)
+	return policyprovider.IntegrationInfo{Name: ProviderTypeRest, Key: key}
+}
+
+func TestRestProvider_DiscoverApplications(t *testing.T) {
+	api := newPermissionsApi(t)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	assert.Equal(t, ProviderTypeRest, provider.Name())
+
+	config := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	config.Apps = []AppConfig{{Id: "static"}}
+	apps, err := provider.DiscoverApplications(testInfo(t, config))
+	assert.NoError(t, err)
+	assert.Len(t, apps, 4)
+	assert.Equal(t, "static", apps[0].Name)
+	assert.Equal(t, policyprovider.ApplicationInfo{ObjectID: "photos", Name: "Photo App", Description: "Photo sharing", Service: ProviderTypeRest}, apps[1])
+	assert.Equal(t, "CRM", apps[3].Name)
+	assert.Equal(t, 2, api.requestCount("GET"))
+
+	config.Auth.Token = "wrong"
+	_, err = provider.DiscoverApplications(testInfo(t, config))
+	assert.ErrorContains(t, err, "(401)")
+}
+
+func TestRestProvider_GetPolicyInfo(t *testing.T) {
+	api := newPermissionsApi(t)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	config := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	app := policyprovider.ApplicationInfo{ObjectID: "photos"}
+
+	policies, err := provider.GetPolicyInfo(testInfo(t, config), app)
+	assert.NoError(t, err)
+	assert.Len(t, policies, 3)
+	assert.Equal(t, "p2", *policies[1].Meta.PolicyId)
+	assert.Equal(t, "photos", *policies[1].Meta.PapId)
+	assert.Equal(t, ProviderTypeRest, policies[1].Meta.ProviderType)
+	assert.Equal(t, hexapolicy.SubjectInfo{"user:bob@example.com", "user:carol@example.com"}, policies[1].Subjects)
+	assert.Equal(t, []hexapolicy.ActionInfo{"photos:read"}, policies[1].Actions)
+	assert.Equal(t, hexapolicy.ObjectInfo("/photos"), policies[1].Object)
+	assert.Nil(t, policies[1].Condition)
+	assert.Equal(t, "subject.department eq \"it\"", policies[2].Condition.Rule)
+	assert.Equal(t, 2, api.requestCount("GET"))
+
+	// the same records using Link header pagination
+	config.List.Pagination = &Pagination{Type: PageLink}
+	linked, err := provider.GetPolicyInfo(testInfo(t, config), app)
+	assert.NoError(t, err)
+	assert.Equal(t, policies, linked)
+
+	_, err = provider.GetPolicyInfo(testInfo(t, config), policyprovider.ApplicationInfo{})
+	assert.ErrorContains(t, err, "missing application id")
+}
+
+func TestRestProvider_GetPolicyInfo_NextLinkOrigin(t *testing.T) {
+	api := newPermissionsApi(t)
+	var leaked []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = append(leaked, r.Header.Get("Authorization"))
+		writeTestJson(w, http.StatusOK, map[string]interface{}{"items": []interface{}{}})
+	}))
+	t.Cleanup(other.Close)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	config := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	app := policyprovider.ApplicationInfo{ObjectID: "photos"}
+
+	for _, pagination := range []*Pagination{{Type: PageLink}, {Type: PageNext, Next: "next_link"}} {
+		config.List.Pagination = pagination
+
+		// absolute links to the api host are followed
+		api.linkBase = api.server.URL
+		policies, err := provider.GetPolicyInfo(testInfo(t, config), app)
+		assert.NoError(t, err, pagination.Type)
+		assert.Len(t, policies, 3, pagination.Type)
+
+		// links to another host are rejected before any credentials are sent
+		api.linkBase = other.URL
+		_, err = provider.GetPolicyInfo(testInfo(t, config), app)
+		assert.ErrorContains(t, err, "next page link "+other.URL+"/api/apps/photos/permissions?cursor=2 is not on "+api.server.URL, pagination.Type)
+	}
+	assert.Empty(t, leaked)
+}
+
+func TestRestProvider_SetPolicyInfo(t *testing.T) {
+	api := newPermissionsApi(t)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	config := testConfig(api, &AuthConfig{Type: AuthOAuth2, ClientId: testClientId, ClientSecret: testSecret, TokenUrl: api.server.URL + "/oauth/token"})
+	info := testInfo(t, config)
+	app := policyprovider.ApplicationInfo{ObjectID: "photos"}
+
+	policies, err := provider.GetPolicyInfo(info, app)
+	assert.NoError(t, err)
+
+	// p1 unchanged, p2 updated, p3 removed and a new policy created
+	policies[1].Subjects = append(policies[1].Subjects, "user:erin@example.com")
+	newId := "p9"
+	policies = append(policies[:2], hexapolicy.PolicyInfo{
+		Meta:      hexapolicy.MetaInfo{PolicyId: &newId},
+		Subjects:  []string{"user:frank@example.com"},
+		Actions:   []hexapolicy.ActionInfo{"photos:read"},
+		Object:    "/shared",
+		Condition: &conditions.ConditionInfo{Rule: "subject.age gt 18", Action: conditions.AAllow},
+	})
+	status, err := provider.SetPolicyInfo(info, app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, api.requestCount("PUT"))
+	assert.Equal(t, 1, api.requestCount("POST"))
+	assert.Equal(t, 2, api.tokens)
+	assert.Equal(t, 1, api.requestCount("DELETE"))
+
+	permissions := api.permissions["photos"]
+	assert.Len(t, permissions, 3)
+	assert.Equal(t, []map[string]string{{"email": "bob@example.com"}, {"email": "carol@example.com"}, {"email": "erin@example.com"}}, permissions[1].Members)
+	assert.Equal(t, permission{Id: "p9", Members: []map[string]string{{"email": "frank@example.com"}}, Scopes: []string{"read"}, Resource: "/shared", Rule: "subject.age gt 18"}, permissions[2])
+
+	difs, err := provider.Reconcile(info, app, policies, true)
+	assert.NoError(t, err)
+	assert.Empty(t, difs)
+
+	// setting the same policies again makes no changes
+	_, err = provider.SetPolicyInfo(info, app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, api.requestCount("PUT"))
+	assert.Equal(t, 1, api.requestCount("DELETE"))
+}
+
+func TestRestProvider_SetPolicyInfo_DeleteAndCreate(t *testing.T) {
+	api := newPermissionsApi(t)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	config := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	config.Update = nil
+	info := testInfo(t, config)
+	app := policyprovider.ApplicationInfo{ObjectID: "photos"}
+
+	policies, err := provider.GetPolicyInfo(info, app)
+	assert.NoError(t, err)
+	policies[0].Actions = []hexapolicy.ActionInfo{"photos:read"}
+	_, err = provider.SetPolicyInfo(info, app, policies)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, api.requestCount("DELETE"))
+	assert.Equal(t, 1, api.requestCount("POST"))
+	assert.Equal(t, []string{"read"}, api.permissions["photos"][2].Scopes)
+	assert.Equal(t, "p1", api.permissions["photos"][2].Id)
+}
+
+func TestRestProvider_ConditionRoundTrip(t *testing.T) {
+	api := newPermissionsApi(t)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	info := testInfo(t, testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken}))
+	app := policyprovider.ApplicationInfo{ObjectID: "photos"}
+	policies, err := provider.GetPolicyInfo(info, app)
+	assert.NoError(t, err)
+
+	// a deny condition cannot be stored (it would be read back as allow)
+	denied := append([]hexapolicy.PolicyInfo{}, policies...)
+	denied[0].Condition = &conditions.ConditionInfo{Rule: "subject.department eq \"sales\"", Action: conditions.ADeny}
+	status, err := provider.SetPolicyInfo(info, app, denied)
+	assert.ErrorContains(t, err, "policy p1: condition action deny is not supported")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, 0, api.requestCount("PUT")+api.requestCount("POST")+api.requestCount("DELETE"))
+
+	// an allow condition is stored as the rule and read back unchanged
+	allowed := append([]hexapolicy.PolicyInfo{}, policies...)
+	allowed[0].Condition = &conditions.ConditionInfo{Rule: "subject.department eq \"sales\"", Action: conditions.AAllow}
+	_, err = provider.SetPolicyInfo(info, app, allowed)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, api.requestCount("PUT"))
+	read, err := provider.GetPolicyInfo(info, app)
+	assert.NoError(t, err)
+	assert.Equal(t, allowed, read)
+}
+
+func TestRestProvider_SetPolicyInfo_Errors(t *testing.T) {
+	api := newPermissionsApi(t)
+	provider := NewRestProvider(WithHttpClient(api.server.Client()))
+	config := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	app := policyprovider.ApplicationInfo{ObjectID: "photos"}
+	policies, err := provider.GetPolicyInfo(testInfo(t, config), app)
+	assert.NoError(t, err)
+
+	subject := append([]hexapolicy.PolicyInfo{}, policies...)
+	subject[0].Subjects = []string{"group:admins"}
+	_, err = provider.SetPolicyInfo(testInfo(t, config), app, subject)
+	assert.ErrorContains(t, err, "subject group:admins does not start with user:")
+
+	_, err = provider.SetPolicyInfo(testInfo(t, config), app, append(policies, policies[0]))
+	assert.ErrorContains(t, err, "duplicate policy id p1")
+
+	noDelete := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	noDelete.Delete = nil
+	_, err = provider.SetPolicyInfo(testInfo(t, noDelete), app, policies[:2])
+	assert.ErrorContains(t, err, "the delete endpoint is not configured")
+
+	noCondition := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	noCondition.Mapping.Condition = ""
+	_, err = provider.SetPolicyInfo(testInfo(t, noCondition), app, policies)
+	assert.ErrorContains(t, err, "conditions are not supported")
+
+	badBody := testConfig(api, &AuthConfig{Type: AuthBearer, Token: testToken})
+	badBody.Update.Body = `{"members": {{.Subjects}}}`
+	changed := append([]hexapolicy.PolicyInfo{}, policies...)
+	changed[0].Object = "/other"
+	status, err := provider.SetPolicyInfo(testInfo(t, badBody), app, changed)
+	assert.ErrorContains(t, err, "update.body template produced invalid JSON")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	assert.Equal(t, 0, api.requestCount("PUT")+api.requestCount("POST")+api.requestCount("DELETE"))
+	assert.Len(t, api.permissions["photos"], 3)
+}
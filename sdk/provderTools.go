@@ -9,11 +9,16 @@ import (
 	"github.com/hexa-org/policy-mapper/pkg/hexapolicy"
 	"github.com/hexa-org/policy-mapper/providers/aws/avpProvider"
 	"github.com/hexa-org/policy-mapper/providers/azure/azureProvider"
+	"github.com/hexa-org/policy-mapper/providers/fileProvider"
 	"github.com/hexa-org/policy-mapper/providers/openpolicyagent"
 
 	"github.com/hexa-org/policy-mapper/providers/aws/awsapigwProvider"
 	"github.com/hexa-org/policy-mapper/providers/aws/cognitoProvider"
 	"github.com/hexa-org/policy-mapper/providers/googlecloud/iapProvider"
+	"github.com/hexa-org/policy-mapper/providers/keycloak/keycloakProvider"
+	"github.com/hexa-org/policy-mapper/providers/okta/oktaProvider"
+	"github.com/hexa-org/policy-mapper/providers/openfga/openfgaProvider"
+	"github.com/hexa-org/policy-mapper/providers/rest/restProvider"
 	"github.com/hexa-org/policy-mapper/providers/test"
 )
 
@@ -26,6 +31,11 @@ const (
 	ProviderTypeAwsApiGW          string = awsapigwProvider.ProviderTypeAwsApiGW
 	ProviderTypeAzure             string = azureProvider.ProviderTypeAzure
 	ProviderTypeOpa                      = openpolicyagent.ProviderTypeOpa
+	ProviderTypeFile                     = fileProvider.ProviderTypeFile
+	ProviderTypeKeycloak                 = keycloakProvider.ProviderTypeKeycloak
+	ProviderTypeOkta                     = oktaProvider.ProviderTypeOkta
+	ProviderTypeOpenFga                  = openfgaProvider.ProviderTypeOpenFga
+	ProviderTypeRest                     = restProvider.ProviderTypeRest
 	EnvTestProvider               string = "HEXA_TEST_PROVIDER" // EnvTestProvider overrides whatever provider is requested and uses the specified provider instead (by name)
 )
 
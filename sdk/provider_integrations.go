@@ -14,8 +14,14 @@ import (
     "github.com/hexa-org/policy-mapper/providers/aws/awscommon"
     "github.com/hexa-org/policy-mapper/providers/aws/cognitoProvider"
     "github.com/hexa-org/policy-mapper/providers/azure/azureProvider"
+    "github.com/hexa-org/policy-mapper/providers/fileProvider"
     "github.com/hexa-org/policy-mapper/providers/googlecloud/iapProvider"
+    "github.com/hexa-org/policy-mapper/providers/keycloak/keycloakProvider"
+    "github.com/hexa-org/policy-mapper/providers/okta/oktaClient"
+    "github.com/hexa-org/policy-mapper/providers/okta/oktaProvider"
+    "github.com/hexa-org/policy-mapper/providers/openfga/openfgaProvider"
     "github.com/hexa-org/policy-mapper/providers/openpolicyagent"
+    "github.com/hexa-org/policy-mapper/providers/rest/restProvider"
     "github.com/hexa-org/policy-mapper/providers/test"
 )
 
@@ -64,6 +70,26 @@ func (i *Integration) open() error {
         i.provider, err = newOpaProvider(i.Opts)
         return err
 
+    case ProviderTypeKeycloak:
+        i.provider, err = newKeycloakProvider(i.Opts)
+        return err
+
+    case ProviderTypeOkta:
+        i.provider, err = newOktaProvider(i.Opts)
+        return err
+
+    case ProviderTypeOpenFga:
+        i.provider, err = newOpenFgaProvider(i.Opts)
+        return err
+
+    case ProviderTypeRest:
+        i.provider, err = newRestProvider(i.Opts)
+        return err
+
+    case ProviderTypeFile:
+        i.provider = &fileProvider.FileProvider{}
+        return nil
+
     case ProviderTypeMock:
         i.provider = &test.MockProvider{
             Info: *i.Opts.Info,
@@ -226,3 +252,101 @@ func newOpaProvider(options Options) (policyprovider.Provider, error) {
 
     return &openpolicyagent.OpaProvider{}, nil
 }
+
+func newKeycloakProvider(options Options) (policyprovider.Provider, error) {
+    var opts []keycloakProvider.ProviderOpt
+    if options.ProviderOpts != nil {
+        switch v := options.ProviderOpts.(type) {
+        case keycloakProvider.ProviderOpt:
+            opts = append(opts, v)
+        default:
+            return nil, errors.New("unexpected ProviderOpts for " + ProviderTypeKeycloak + " (use keycloakProvider.ProviderOpt)")
+        }
+    }
+
+    if options.HTTPClient != nil {
+        switch client := options.HTTPClient.(type) {
+        case http.Client:
+            opts = append(opts, keycloakProvider.WithHttpClient(&client))
+        case *http.Client:
+            opts = append(opts, keycloakProvider.WithHttpClient(client))
+        default:
+            return nil, errors.New("HTTPClient type supported, use WithHttpClient(http.Client{})")
+        }
+    }
+    return keycloakProvider.NewKeycloakProvider(opts...), nil
+}
+
+func newOktaProvider(options Options) (policyprovider.Provider, error) {
+    var opts []oktaProvider.ProviderOpt
+    if options.ProviderOpts != nil {
+        switch v := options.ProviderOpts.(type) {
+        case oktaProvider.ProviderOpt:
+            opts = append(opts, v)
+        default:
+            return nil, errors.New("unexpected ProviderOpts for " + ProviderTypeOkta + " (use oktaProvider.ProviderOpt)")
+        }
+    }
+
+    if options.HTTPClient != nil {
+        switch client := options.HTTPClient.(type) {
+        case http.Client:
+            opts = append(opts, oktaProvider.WithHttpClient(&client))
+        case *http.Client:
+            opts = append(opts, oktaProvider.WithHttpClient(client))
+        case oktaClient.HTTPClient:
+            opts = append(opts, oktaProvider.WithHttpClient(client))
+        default:
+            return nil, errors.New("HTTPClient type supported, use WithHttpClient(http.Client{})")
+        }
+    }
+    return oktaProvider.NewOktaProvider(opts...), nil
+}
+
+func newOpenFgaProvider(options Options) (policyprovider.Provider, error) {
+    var opts []openfgaProvider.ProviderOpt
+    if options.ProviderOpts != nil {
+        switch v := options.ProviderOpts.(type) {
+        case openfgaProvider.ProviderOpt:
+            opts = append(opts, v)
+        default:
+            return nil, errors.New("unexpected ProviderOpts for " + ProviderTypeOpenFga + " (use openfgaProvider.ProviderOpt)")
+        }
+    }
+
+    if options.HTTPClient != nil {
+        switch client := options.HTTPClient.(type) {
+        case http.Client:
+            opts = append(opts, openfgaProvider.WithHttpClient(&client))
+        case *http.Client:
+            opts = append(opts, openfgaProvider.WithHttpClient(client))
+        default:
+            return nil, errors.New("HTTPClient type supported, use WithHttpClient(http.Client{})")
+        }
+    }
+    return openfgaProvider.NewOpenFgaProvider(opts...), nil
+}
+
+func newRestProvider(options Options) (policyprovider.Provider, error) {
+    var opts []restProvider.ProviderOpt
+    if options.ProviderOpts != nil {
+        switch v := options.ProviderOpts.(type) {
+        case restProvider.ProviderOpt:
+            opts = append(opts, v)
+        default:
+            return nil, errors.New("unexpected ProviderOpts for " + ProviderTypeRest + " (use restProvider.ProviderOpt)")
+        }
+    }
+
+    if options.HTTPClient != nil {
+        switch client := options.HTTPClient.(type) {
+        case http.Client:
+            opts = append(opts, restProvider.WithHttpClient(&client))
+        case *http.Client:
+            opts = append(opts, restProvider.WithHttpClient(client))
+        default:
+            return nil, errors.New("HTTPClient type supported, use WithHttpClient(http.Client{})")
+        }
+    }
+    return restProvider.NewRestProvider(opts...), nil
+}